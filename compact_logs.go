@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/logger"
+)
+
+// compactLogsCommand is the subcommand name for one-time log database maintenance
+const compactLogsCommand = "compact-logs"
+
+// runCompactLogs finishes any pending log storage migration and switches an existing log database
+// to incremental vacuum. The switch rewrites the whole database, so run it while the server is stopped.
+func runCompactLogs(args []string) int {
+	fs := flag.NewFlagSet(compactLogsCommand, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [options]\n\nMigrate inline log bodies, build the full-text index and switch the log database\nto incremental vacuum. Run once while the server is stopped.\n\nOptions:\n", os.Args[0], compactLogsCommand)
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", "config.yaml", "Configuration file path")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	storage, err := logger.NewGORMStorageWithOptions(cfg.Logging.LogDirectory, logger.GORMStorageOptions{
+		BodyCompression: cfg.Logging.BodyCompression,
+		DisableCleanup:  true,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open log storage: %v\n", err)
+		return 1
+	}
+	defer storage.Close()
+
+	fmt.Println("Compacting log database, this may take a while for large databases...")
+	if err := storage.Compact(); err != nil {
+		fmt.Fprintf(os.Stderr, "Compact failed: %v\n", err)
+		return 1
+	}
+	fmt.Println("Log database compacted")
+	return 0
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/logexport"
+	"claude-code-codex-companion/internal/logger"
+)
+
+// exportLogsCommand is the subcommand name for bulk log export
+const exportLogsCommand = "export-logs"
+
+// runExportLogs exports request logs matching the given filters as HAR or batch-replay JSONL.
+// It reads the log database directly, so it works whether or not the server is running.
+func runExportLogs(args []string) int {
+	fs := flag.NewFlagSet(exportLogsCommand, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [options]\n\nExport request logs as HAR 1.2 or Anthropic/OpenAI batch JSONL.\n\nOptions:\n", os.Args[0], exportLogsCommand)
+		fs.PrintDefaults()
+	}
+
+	configPath := fs.String("config", "config.yaml", "Configuration file path")
+	format := fs.String("format", logexport.FormatHAR, "Export format: har, anthropic or openai")
+	output := fs.String("o", "", "Output file (default: stdout)")
+	limit := fs.Int("limit", logexport.DefaultLimit, fmt.Sprintf("Maximum number of logs to read (up to %d)", logexport.MaxLimit))
+	endpoint := fs.String("endpoint", "", "Endpoint name or URL")
+	model := fs.String("model", "", "Requested or rewritten model")
+	clientType := fs.String("client-type", "", "Client type: claude-code, codex or unknown")
+	session := fs.String("session", "", "Session ID")
+	tag := fs.String("tag", "", "Tag")
+	status := fs.String("status", "", "Status code: 404, 4xx or 400-499")
+	from := fs.String("from", "", "Start time (RFC3339, 2006-01-02T15:04 or 2006-01-02)")
+	to := fs.String("to", "", "End time (same formats as -from)")
+	text := fs.String("q", "", "Full-text search in bodies and errors")
+	failedOnly := fs.Bool("failed-only", false, "Only export failed requests")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	*format = strings.ToLower(*format)
+	if !logexport.ValidFormat(*format) {
+		fmt.Fprintf(os.Stderr, "Invalid format %q: must be har, anthropic or openai\n", *format)
+		return 2
+	}
+	if *limit <= 0 || *limit > logexport.MaxLimit {
+		fmt.Fprintf(os.Stderr, "Invalid limit %d: must be between 1 and %d\n", *limit, logexport.MaxLimit)
+		return 2
+	}
+
+	// LoadConfig writes a default config when the file is missing; export must not create files
+	if _, err := os.Stat(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	query := logger.LogQuery{
+		Endpoint:   *endpoint,
+		Model:      *model,
+		ClientType: *clientType,
+		SessionID:  *session,
+		Tag:        *tag,
+		Text:       *text,
+		FailedOnly: *failedOnly,
+	}
+	// Logs record the endpoint URL, so resolve endpoint names the same way the admin API does
+	for _, ep := range cfg.Endpoints {
+		if ep.Name == query.Endpoint {
+			query.Endpoint = ep.URL
+			break
+		}
+	}
+	if query.Status, err = logger.ParseStatusRange(*status); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *from != "" {
+		if query.From, err = logger.ParseLogTime(*from); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if *to != "" {
+		if query.To, err = logger.ParseLogTimeEnd(*to); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	redactor, err := logger.NewRedactor(cfg.Logging.Redaction)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid redaction configuration: %v\n", err)
+		return 1
+	}
+
+	// Open the log database read-only: no migrations, maintenance, cleanup or VACUUM run during export
+	storage, err := logger.NewReadOnlyGORMStorage(cfg.Logging.LogDirectory)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open log storage: %v\n", err)
+		return 1
+	}
+	defer storage.Close()
+
+	out := os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create output file: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+
+	result, err := logexport.Export(out, storage, logexport.Options{
+		Format:         *format,
+		Query:          query,
+		Limit:          *limit,
+		Redact:         redactor.RedactLog,
+		CreatorVersion: Version,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d log entries", result.Exported)
+	if result.Skipped > 0 {
+		fmt.Fprintf(os.Stderr, " (%d skipped: no replayable %s request)", result.Skipped, *format)
+	}
+	fmt.Fprintln(os.Stderr)
+	return 0
+}
@@ -0,0 +1,351 @@
+package alerting
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/statistics"
+)
+
+// 告警规则类型
+const (
+	RuleEndpointBlacklisted = "endpoint_blacklisted"
+	RuleAllEndpointsDown    = "all_endpoints_down"
+	RuleErrorRate           = "error_rate"
+	RuleOAuthRefreshFailed  = "oauth_refresh_failed"
+	RuleSpendCap            = "spend_cap"
+	RuleRateLimitRejected   = "rate_limit_rejected"
+)
+
+// alertQueueSize 待发送告警队列长度，队列满时丢弃新告警
+const alertQueueSize = 100
+
+// Alert 一条告警，webhook 和 command sink 收到的 JSON 即为该结构
+type Alert struct {
+	Rule     string                 `json:"rule"`
+	Type     string                 `json:"type"`
+	Endpoint string                 `json:"endpoint,omitempty"`
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// EndpointState 轮询类规则检查的端点状态
+type EndpointState struct {
+	Name            string
+	OAuthFailures   int    // OAuth token 连续刷新失败次数
+	OAuthError      string // 最近一次刷新失败的原因
+	RateLimitStatus string // 上游返回的 Anthropic-Ratelimit-Unified-Status
+	RateLimitReset  int64  // 限流重置时间（Unix 秒），未知时为 0
+}
+
+// Source 提供轮询类规则需要的端点状态和统计数据
+type Source interface {
+	Endpoints() []EndpointState
+	TimeSeries(query statistics.TimeSeriesQuery) (*statistics.TimeSeriesResult, error)
+}
+
+// delivery 一条待发送的告警及其目标 sink
+type delivery struct {
+	alert Alert
+	sinks []namedSink
+}
+
+// Manager 告警管理器：接收端点事件、定期检查轮询类规则，按规则 + 端点去重并在冷却时间内抑制重复告警
+type Manager struct {
+	mutex    sync.Mutex
+	config   config.AlertingConfig
+	pricing  []config.ModelPricingConfig
+	source   Source
+	sinks    []namedSink
+	cooldown time.Duration
+	interval time.Duration
+
+	lastSent   map[string]time.Time // 规则 + 端点 -> 最近一次发送时间
+	suppressed map[string]int       // 规则 + 端点 -> 冷却期内被抑制的次数
+
+	queue    chan delivery
+	stop     chan struct{}
+	done     sync.WaitGroup
+	stopOnce sync.Once
+	started  bool
+}
+
+// NewManager 创建告警管理器，调用 Start 后才会发送告警和检查轮询类规则
+func NewManager(cfg config.AlertingConfig, pricing []config.ModelPricingConfig, source Source) *Manager {
+	m := &Manager{
+		source:     source,
+		lastSent:   make(map[string]time.Time),
+		suppressed: make(map[string]int),
+		queue:      make(chan delivery, alertQueueSize),
+		stop:       make(chan struct{}),
+	}
+	m.UpdateConfig(cfg, pricing)
+	return m
+}
+
+// UpdateConfig 热更新告警配置，已有的冷却状态保留
+func (m *Manager) UpdateConfig(cfg config.AlertingConfig, pricing []config.ModelPricingConfig) {
+	if m == nil {
+		return
+	}
+	sinks := make([]namedSink, 0, len(cfg.Sinks))
+	for _, sinkConfig := range cfg.Sinks {
+		sinks = append(sinks, newSink(sinkConfig))
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.config = cfg
+	m.pricing = pricing
+	m.sinks = sinks
+	m.cooldown = parseDuration(cfg.Cooldown, 15*time.Minute)
+	m.interval = parseDuration(cfg.EvaluationInterval, time.Minute)
+}
+
+// Start 启动告警发送和轮询类规则检查
+func (m *Manager) Start() {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	if m.started {
+		m.mutex.Unlock()
+		return
+	}
+	m.started = true
+	m.mutex.Unlock()
+
+	m.done.Add(2)
+	go m.deliverLoop()
+	go m.evaluateLoop()
+}
+
+// Stop 停止后台任务，队列中尚未发送的告警会被发送完
+func (m *Manager) Stop() {
+	if m == nil {
+		return
+	}
+	m.stopOnce.Do(func() {
+		close(m.stop)
+		m.done.Wait()
+	})
+}
+
+// EndpointBlacklisted 端点被拉黑时调用，不会阻塞
+func (m *Manager) EndpointBlacklisted(endpoint, reason string) {
+	if m == nil {
+		return
+	}
+	now := time.Now()
+	for _, rule := range m.rulesOfType(RuleEndpointBlacklisted) {
+		if !matchesEndpoint(rule, endpoint) {
+			continue
+		}
+		m.fire(rule, Alert{
+			Endpoint: endpoint,
+			Title:    "Endpoint blacklisted: " + endpoint,
+			Message:  reason,
+			Time:     now,
+		})
+	}
+}
+
+// NoAvailableEndpoints 请求因没有可用端点而失败时调用，不会阻塞
+func (m *Manager) NoAvailableEndpoints(tags []string, message string) {
+	if m == nil {
+		return
+	}
+	now := time.Now()
+	for _, rule := range m.rulesOfType(RuleAllEndpointsDown) {
+		if rule.Tag != "" && !containsString(tags, rule.Tag) {
+			continue
+		}
+		title := "All endpoints are down"
+		if rule.Tag != "" {
+			title += " for tag " + rule.Tag
+		}
+		alert := Alert{Title: title, Message: message, Time: now}
+		if len(tags) > 0 {
+			alert.Details = map[string]interface{}{"tags": tags}
+		}
+		m.fire(rule, alert)
+	}
+}
+
+// Evaluate 检查轮询类规则（错误率、OAuth 刷新、费用上限、限流）
+func (m *Manager) Evaluate(now time.Time) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	enabled := m.config.Enabled
+	rules := m.config.Rules
+	pricing := m.pricing
+	m.mutex.Unlock()
+	if !enabled || m.source == nil {
+		return
+	}
+
+	var states []EndpointState
+	for _, rule := range rules {
+		var alerts []Alert
+		switch rule.Type {
+		case RuleOAuthRefreshFailed, RuleRateLimitRejected:
+			if states == nil {
+				states = m.source.Endpoints()
+			}
+			alerts = evaluateEndpointStates(rule, states, now)
+		case RuleErrorRate:
+			alerts = evaluateErrorRate(rule, m.source, now)
+		case RuleSpendCap:
+			alerts = evaluateSpendCap(rule, pricing, m.source, now)
+		}
+		for _, alert := range alerts {
+			m.fire(rule, alert)
+		}
+	}
+}
+
+// rulesOfType 返回启用状态下指定类型的规则
+func (m *Manager) rulesOfType(ruleType string) []config.AlertRuleConfig {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !m.config.Enabled {
+		return nil
+	}
+	var rules []config.AlertRuleConfig
+	for _, rule := range m.config.Rules {
+		if rule.Type == ruleType {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// fire 按规则 + 端点去重，冷却时间外的告警放入发送队列
+func (m *Manager) fire(rule config.AlertRuleConfig, alert Alert) {
+	alert.Rule = rule.Name
+	alert.Type = rule.Type
+	key := rule.Name + "|" + alert.Endpoint
+
+	m.mutex.Lock()
+	cooldown := m.cooldown
+	if rule.Cooldown != "" {
+		cooldown = parseDuration(rule.Cooldown, cooldown)
+	}
+	if last, ok := m.lastSent[key]; ok && alert.Time.Sub(last) < cooldown {
+		m.suppressed[key]++
+		m.mutex.Unlock()
+		return
+	}
+	m.lastSent[key] = alert.Time
+	if count := m.suppressed[key]; count > 0 {
+		if alert.Details == nil {
+			alert.Details = make(map[string]interface{})
+		}
+		alert.Details["suppressed"] = count
+		delete(m.suppressed, key)
+	}
+	sinks := m.sinksForRule(rule)
+	m.mutex.Unlock()
+
+	if len(sinks) == 0 {
+		return
+	}
+	select {
+	case m.queue <- delivery{alert: alert, sinks: sinks}:
+	default:
+		log.Printf("Alert queue is full, dropping alert %s: %s", alert.Rule, alert.Title)
+	}
+}
+
+// sinksForRule 返回规则的发送目标，调用方需持有 m.mutex
+func (m *Manager) sinksForRule(rule config.AlertRuleConfig) []namedSink {
+	if len(rule.Sinks) == 0 {
+		return m.sinks
+	}
+	var sinks []namedSink
+	for _, sink := range m.sinks {
+		if containsString(rule.Sinks, sink.name) {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+// deliverLoop 逐条发送队列中的告警
+func (m *Manager) deliverLoop() {
+	defer m.done.Done()
+	for {
+		select {
+		case d := <-m.queue:
+			m.deliver(d)
+		case <-m.stop:
+			for {
+				select {
+				case d := <-m.queue:
+					m.deliver(d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver 将告警发送到所有目标 sink，失败只记录日志
+func (m *Manager) deliver(d delivery) {
+	for _, sink := range d.sinks {
+		if err := sink.send(d.alert); err != nil {
+			log.Printf("Failed to send alert %s to sink %s: %v", d.alert.Rule, sink.name, err)
+		}
+	}
+}
+
+// evaluateLoop 按 evaluation_interval 定期检查轮询类规则
+func (m *Manager) evaluateLoop() {
+	defer m.done.Done()
+	timer := time.NewTimer(m.evaluationInterval())
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			m.Evaluate(time.Now())
+			timer.Reset(m.evaluationInterval())
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *Manager) evaluationInterval() time.Duration {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.interval
+}
+
+// matchesEndpoint 判断规则是否适用于端点，未配置 endpoints 时适用于全部端点
+func matchesEndpoint(rule config.AlertRuleConfig, endpoint string) bool {
+	return len(rule.Endpoints) == 0 || containsString(rule.Endpoints, endpoint)
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDuration 解析 duration，无效时返回默认值（配置已在加载时验证）
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return duration
+	}
+	return fallback
+}
@@ -0,0 +1,325 @@
+package alerting
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/statistics"
+)
+
+// receiver 记录收到的 webhook 请求体
+type receiver struct {
+	mutex    sync.Mutex
+	bodies   []map[string]interface{}
+	headers  []http.Header
+	response string
+	status   int
+}
+
+func newReceiver(t *testing.T) (*receiver, *httptest.Server) {
+	t.Helper()
+	r := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, _ := io.ReadAll(req.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("Invalid JSON body: %s", data)
+		}
+		r.mutex.Lock()
+		r.bodies = append(r.bodies, body)
+		r.headers = append(r.headers, req.Header.Clone())
+		status, response := r.status, r.response
+		r.mutex.Unlock()
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *receiver) received() []map[string]interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]map[string]interface{}(nil), r.bodies...)
+}
+
+// fakeSource 返回固定的端点状态和统计数据
+type fakeSource struct {
+	states []EndpointState
+	series map[string][]statistics.TimeSeries // group_by + "|" + endpoint -> series
+}
+
+func (f *fakeSource) Endpoints() []EndpointState {
+	return f.states
+}
+
+func (f *fakeSource) TimeSeries(query statistics.TimeSeriesQuery) (*statistics.TimeSeriesResult, error) {
+	if f.series == nil {
+		return nil, statistics.ErrTimeSeriesUnavailable
+	}
+	return &statistics.TimeSeriesResult{Series: f.series[query.GroupBy+"|"+query.Endpoint]}, nil
+}
+
+func testAlert() Alert {
+	return Alert{
+		Rule:     "blacklist",
+		Type:     RuleEndpointBlacklisted,
+		Endpoint: "primary",
+		Title:    "Endpoint blacklisted: primary",
+		Message:  "3 consecutive failures",
+		Time:     time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookSinks_Payloads(t *testing.T) {
+	r, server := newReceiver(t)
+
+	for _, sinkType := range []string{"webhook", "slack", "feishu", "dingtalk"} {
+		sink := newSink(config.AlertSinkConfig{
+			Name:    sinkType,
+			Type:    sinkType,
+			URL:     server.URL,
+			Headers: map[string]string{"X-Token": "secret"},
+		})
+		if err := sink.send(testAlert()); err != nil {
+			t.Fatalf("%s sink failed: %v", sinkType, err)
+		}
+	}
+
+	bodies := r.received()
+	if len(bodies) != 4 {
+		t.Fatalf("Expected 4 requests, got %d", len(bodies))
+	}
+	if bodies[0]["rule"] != "blacklist" || bodies[0]["endpoint"] != "primary" || bodies[0]["type"] != RuleEndpointBlacklisted {
+		t.Errorf("Unexpected webhook payload: %v", bodies[0])
+	}
+	if text, _ := bodies[1]["text"].(string); !strings.Contains(text, "Endpoint blacklisted: primary") {
+		t.Errorf("Unexpected slack payload: %v", bodies[1])
+	}
+	content, _ := bodies[2]["content"].(map[string]interface{})
+	if bodies[2]["msg_type"] != "text" || !strings.Contains(content["text"].(string), "3 consecutive failures") {
+		t.Errorf("Unexpected feishu payload: %v", bodies[2])
+	}
+	text, _ := bodies[3]["text"].(map[string]interface{})
+	if bodies[3]["msgtype"] != "text" || !strings.Contains(text["content"].(string), "[blacklist]") {
+		t.Errorf("Unexpected dingtalk payload: %v", bodies[3])
+	}
+	if r.headers[0].Get("X-Token") != "secret" || r.headers[0].Get("Content-Type") != "application/json" {
+		t.Errorf("Expected custom headers, got %v", r.headers[0])
+	}
+}
+
+func TestWebhookSinks_Errors(t *testing.T) {
+	r, server := newReceiver(t)
+
+	r.status = http.StatusInternalServerError
+	if err := newSink(config.AlertSinkConfig{Name: "w", Type: "webhook", URL: server.URL}).send(testAlert()); err == nil {
+		t.Error("Expected error for non-2xx status")
+	}
+
+	r.status = http.StatusOK
+	r.response = `{"code":19001,"msg":"param invalid"}`
+	if err := newSink(config.AlertSinkConfig{Name: "f", Type: "feishu", URL: server.URL}).send(testAlert()); err == nil || !strings.Contains(err.Error(), "19001") {
+		t.Errorf("Expected feishu error code, got %v", err)
+	}
+
+	r.response = `{"errcode":310000,"errmsg":"sign not match"}`
+	if err := newSink(config.AlertSinkConfig{Name: "d", Type: "dingtalk", URL: server.URL}).send(testAlert()); err == nil || !strings.Contains(err.Error(), "310000") {
+		t.Errorf("Expected dingtalk errcode, got %v", err)
+	}
+
+	r.response = `{"errcode":0,"errmsg":"ok"}`
+	if err := newSink(config.AlertSinkConfig{Name: "d", Type: "dingtalk", URL: server.URL}).send(testAlert()); err != nil {
+		t.Errorf("Expected success, got %v", err)
+	}
+}
+
+func TestCommandSink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	output := filepath.Join(t.TempDir(), "alert.txt")
+	sink := newSink(config.AlertSinkConfig{
+		Name:    "script",
+		Type:    "command",
+		Command: "sh",
+		Args:    []string{"-c", `cat > "$0"; echo "$CCCC_ALERT_RULE $CCCC_ALERT_ENDPOINT" >> "$0"`, output},
+	})
+	if err := sink.send(testAlert()); err != nil {
+		t.Fatalf("Command sink failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read command output: %v", err)
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	var alert Alert
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[0]), &alert) != nil || alert.Title != testAlert().Title {
+		t.Fatalf("Expected alert JSON on stdin, got %q", data)
+	}
+	if lines[1] != "blacklist primary" {
+		t.Errorf("Expected alert environment variables, got %q", lines[1])
+	}
+
+	failing := newSink(config.AlertSinkConfig{Name: "fail", Type: "command", Command: "sh", Args: []string{"-c", "echo boom >&2; exit 3"}})
+	if err := failing.send(testAlert()); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected command failure with output, got %v", err)
+	}
+}
+
+func TestManager_CooldownAndDedupe(t *testing.T) {
+	r, server := newReceiver(t)
+	m := NewManager(config.AlertingConfig{
+		Enabled:  true,
+		Cooldown: "1h",
+		Sinks:    []config.AlertSinkConfig{{Name: "hook", Type: "webhook", URL: server.URL}},
+		Rules:    []config.AlertRuleConfig{{Name: "blacklist", Type: RuleEndpointBlacklisted}},
+	}, nil, nil)
+	m.Start()
+
+	rule := m.config.Rules[0]
+	base := time.Now()
+	m.fire(rule, Alert{Endpoint: "primary", Title: "first", Time: base})
+	m.fire(rule, Alert{Endpoint: "primary", Title: "suppressed", Time: base.Add(time.Minute)})
+	m.fire(rule, Alert{Endpoint: "primary", Title: "suppressed", Time: base.Add(2 * time.Minute)})
+	m.fire(rule, Alert{Endpoint: "backup", Title: "other endpoint", Time: base.Add(2 * time.Minute)})
+	m.fire(rule, Alert{Endpoint: "primary", Title: "after cooldown", Time: base.Add(61 * time.Minute)})
+	m.Stop()
+
+	bodies := r.received()
+	if len(bodies) != 3 {
+		t.Fatalf("Expected 3 alerts after dedupe, got %v", bodies)
+	}
+	if bodies[0]["title"] != "first" || bodies[1]["title"] != "other endpoint" || bodies[2]["title"] != "after cooldown" {
+		t.Errorf("Unexpected alert order: %v", bodies)
+	}
+	details, _ := bodies[2]["details"].(map[string]interface{})
+	if details["suppressed"] != float64(2) {
+		t.Errorf("Expected suppressed count in details, got %v", bodies[2])
+	}
+}
+
+func TestManager_EventRules(t *testing.T) {
+	r, server := newReceiver(t)
+	_, other := newReceiver(t)
+	m := NewManager(config.AlertingConfig{
+		Enabled: true,
+		Sinks: []config.AlertSinkConfig{
+			{Name: "hook", Type: "webhook", URL: server.URL},
+			{Name: "other", Type: "webhook", URL: other.URL},
+		},
+		Rules: []config.AlertRuleConfig{
+			{Name: "primary-down", Type: RuleEndpointBlacklisted, Endpoints: []string{"primary"}, Sinks: []string{"hook"}},
+			{Name: "prod-down", Type: RuleAllEndpointsDown, Tag: "prod", Sinks: []string{"hook"}},
+		},
+	}, nil, nil)
+	m.Start()
+
+	m.EndpointBlacklisted("backup", "ignored endpoint")
+	m.EndpointBlacklisted("primary", "timeout")
+	m.NoAvailableEndpoints([]string{"dev"}, "ignored tag")
+	m.NoAvailableEndpoints([]string{"prod", "dev"}, "no endpoints for prod")
+	m.Stop()
+
+	bodies := r.received()
+	if len(bodies) != 2 {
+		t.Fatalf("Expected 2 alerts, got %v", bodies)
+	}
+	if bodies[0]["rule"] != "primary-down" || bodies[0]["message"] != "timeout" {
+		t.Errorf("Unexpected blacklist alert: %v", bodies[0])
+	}
+	if bodies[1]["rule"] != "prod-down" || bodies[1]["message"] != "no endpoints for prod" {
+		t.Errorf("Unexpected all-down alert: %v", bodies[1])
+	}
+
+	// 关闭后不再发送
+	disabled := NewManager(config.AlertingConfig{
+		Sinks: []config.AlertSinkConfig{{Name: "hook", Type: "webhook", URL: server.URL}},
+		Rules: []config.AlertRuleConfig{{Name: "any", Type: RuleEndpointBlacklisted}},
+	}, nil, nil)
+	disabled.Start()
+	disabled.EndpointBlacklisted("primary", "timeout")
+	disabled.Stop()
+	if len(r.received()) != 2 {
+		t.Errorf("Expected no alerts when disabled, got %v", r.received())
+	}
+}
+
+func TestManager_Evaluate(t *testing.T) {
+	r, server := newReceiver(t)
+	source := &fakeSource{
+		states: []EndpointState{
+			{Name: "oauth", OAuthFailures: 3, OAuthError: "invalid_grant"},
+			{Name: "healthy", OAuthFailures: 1, RateLimitStatus: "allowed"},
+			{Name: "limited", RateLimitStatus: "rejected", RateLimitReset: 1760800000},
+		},
+		series: map[string][]statistics.TimeSeries{
+			"endpoint|": {
+				{Key: "flaky", Total: statistics.TimeSeriesPoint{Requests: 20, Errors: 10, ErrorRate: 50}},
+				{Key: "few", Total: statistics.TimeSeriesPoint{Requests: 2, Errors: 2, ErrorRate: 100}},
+				{Key: "stable", Total: statistics.TimeSeriesPoint{Requests: 100, Errors: 1, ErrorRate: 1}},
+			},
+			"model|": {
+				{Key: "claude-sonnet-4", Total: statistics.TimeSeriesPoint{InputTokens: 2000000, OutputTokens: 1000000}},
+				{Key: "unpriced", Total: statistics.TimeSeriesPoint{InputTokens: 1000000000}},
+			},
+		},
+	}
+	pricing := []config.ModelPricingConfig{{Model: "claude-sonnet-*", Input: 3, Output: 15}}
+	m := NewManager(config.AlertingConfig{
+		Enabled:            true,
+		Cooldown:           "1h",
+		EvaluationInterval: "1h",
+		Sinks:              []config.AlertSinkConfig{{Name: "hook", Type: "webhook", URL: server.URL}},
+		Rules: []config.AlertRuleConfig{
+			{Name: "errors", Type: RuleErrorRate, Threshold: 20, Window: "5m", MinRequests: 10},
+			{Name: "oauth", Type: RuleOAuthRefreshFailed, Failures: 3},
+			{Name: "limits", Type: RuleRateLimitRejected},
+			{Name: "spend", Type: RuleSpendCap, Threshold: 20, Window: "24h"},
+			{Name: "spend-high", Type: RuleSpendCap, Threshold: 100, Window: "24h"},
+		},
+	}, pricing, source)
+	m.Start()
+
+	now := time.Now()
+	m.Evaluate(now)
+	m.Evaluate(now.Add(time.Minute)) // 冷却期内重复命中不再发送
+	m.Stop()
+
+	got := make(map[string]map[string]interface{})
+	for _, body := range r.received() {
+		endpoint, _ := body["endpoint"].(string)
+		got[body["rule"].(string)+"|"+endpoint] = body
+	}
+	if len(got) != 4 || len(r.received()) != 4 {
+		t.Fatalf("Expected 4 distinct alerts, got %v", r.received())
+	}
+	for _, key := range []string{"errors|flaky", "oauth|oauth", "limits|limited", "spend|"} {
+		if got[key] == nil {
+			t.Errorf("Missing alert %s in %v", key, got)
+		}
+	}
+	if details, _ := got["spend|"]["details"].(map[string]interface{}); details["spend"] != float64(21) {
+		t.Errorf("Expected spend of $21, got %v", got["spend|"])
+	}
+
+	// 统计不可用时跳过依赖统计的规则
+	source.series = nil
+	if alerts := evaluateErrorRate(m.config.Rules[0], source, now); alerts != nil {
+		t.Errorf("Expected no alerts without statistics, got %v", alerts)
+	}
+	if alerts := evaluateSpendCap(m.config.Rules[3], pricing, source, now); alerts != nil {
+		t.Errorf("Expected no alerts without statistics, got %v", alerts)
+	}
+}
@@ -0,0 +1,149 @@
+package alerting
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/statistics"
+	"claude-code-codex-companion/internal/transcript"
+)
+
+// evaluateEndpointStates 检查 OAuth 刷新失败和限流被拒规则
+func evaluateEndpointStates(rule config.AlertRuleConfig, states []EndpointState, now time.Time) []Alert {
+	var alerts []Alert
+	for _, state := range states {
+		if !matchesEndpoint(rule, state.Name) {
+			continue
+		}
+		switch rule.Type {
+		case RuleOAuthRefreshFailed:
+			if rule.Failures <= 0 || state.OAuthFailures < rule.Failures {
+				continue
+			}
+			alerts = append(alerts, Alert{
+				Endpoint: state.Name,
+				Title:    "OAuth token refresh failing: " + state.Name,
+				Message:  fmt.Sprintf("%d consecutive refresh failures, last error: %s", state.OAuthFailures, state.OAuthError),
+				Details:  map[string]interface{}{"consecutive_failures": state.OAuthFailures},
+				Time:     now,
+			})
+		case RuleRateLimitRejected:
+			if state.RateLimitStatus != "rejected" {
+				continue
+			}
+			alert := Alert{
+				Endpoint: state.Name,
+				Title:    "Rate limit rejected: " + state.Name,
+				Message:  "Upstream reported unified rate limit status 'rejected'",
+				Time:     now,
+			}
+			if state.RateLimitReset > 0 {
+				reset := time.Unix(state.RateLimitReset, 0)
+				alert.Message += ", resets at " + reset.Format(time.RFC3339)
+				alert.Details = map[string]interface{}{"reset_at": reset}
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// evaluateErrorRate 按端点统计窗口内的错误率，请求数达到 min_requests 且错误率不低于阈值时告警
+func evaluateErrorRate(rule config.AlertRuleConfig, source Source, now time.Time) []Alert {
+	window := parseDuration(rule.Window, 5*time.Minute)
+	result, err := queryTimeSeries(source, statistics.TimeSeriesQuery{From: now.Add(-window), To: now, GroupBy: "endpoint"})
+	if err != nil || result == nil {
+		return nil
+	}
+
+	var alerts []Alert
+	for _, series := range result.Series {
+		total := series.Total
+		if !matchesEndpoint(rule, series.Key) || total.Requests < int64(rule.MinRequests) || total.Requests == 0 {
+			continue
+		}
+		if total.ErrorRate < rule.Threshold {
+			continue
+		}
+		alerts = append(alerts, Alert{
+			Endpoint: series.Key,
+			Title:    fmt.Sprintf("Error rate %.1f%% on %s", total.ErrorRate, series.Key),
+			Message: fmt.Sprintf("%d of %d requests failed in the last %v (threshold %.1f%%)",
+				total.Errors, total.Requests, window, rule.Threshold),
+			Details: map[string]interface{}{
+				"error_rate":       total.ErrorRate,
+				"requests":         total.Requests,
+				"errors":           total.Errors,
+				"error_categories": total.ErrorCategories,
+				"window":           window.String(),
+			},
+			Time: now,
+		})
+	}
+	return alerts
+}
+
+// evaluateSpendCap 按模型价格估算窗口内的费用，达到上限时告警
+// 配置了 endpoints 时分别统计每个端点，否则统计全部端点的总费用
+func evaluateSpendCap(rule config.AlertRuleConfig, pricing []config.ModelPricingConfig, source Source, now time.Time) []Alert {
+	window := parseDuration(rule.Window, 24*time.Hour)
+	scopes := rule.Endpoints
+	if len(scopes) == 0 {
+		scopes = []string{""}
+	}
+
+	var alerts []Alert
+	for _, endpoint := range scopes {
+		result, err := queryTimeSeries(source, statistics.TimeSeriesQuery{
+			From: now.Add(-window), To: now, Endpoint: endpoint, GroupBy: "model",
+		})
+		if err != nil || result == nil {
+			continue
+		}
+
+		var spend float64
+		for _, series := range result.Series {
+			usage := transcript.Usage{
+				InputTokens:      int(series.Total.InputTokens),
+				OutputTokens:     int(series.Total.OutputTokens),
+				CacheReadTokens:  int(series.Total.CacheReadTokens),
+				CacheWriteTokens: int(series.Total.CacheWriteTokens),
+			}
+			if cost, ok := transcript.Cost(pricing, series.Key, usage); ok {
+				spend += cost
+			}
+		}
+		if spend < rule.Threshold {
+			continue
+		}
+
+		title := fmt.Sprintf("Spend cap reached: $%.2f", spend)
+		if endpoint != "" {
+			title += " on " + endpoint
+		}
+		alerts = append(alerts, Alert{
+			Endpoint: endpoint,
+			Title:    title,
+			Message:  fmt.Sprintf("Estimated spend in the last %v is $%.2f (cap $%.2f)", window, spend, rule.Threshold),
+			Details: map[string]interface{}{
+				"spend":  spend,
+				"cap":    rule.Threshold,
+				"window": window.String(),
+			},
+			Time: now,
+		})
+	}
+	return alerts
+}
+
+// queryTimeSeries 查询统计数据，统计不可用（只有内存统计）时不记录日志
+func queryTimeSeries(source Source, query statistics.TimeSeriesQuery) (*statistics.TimeSeriesResult, error) {
+	result, err := source.TimeSeries(query)
+	if err != nil && !errors.Is(err, statistics.ErrTimeSeriesUnavailable) {
+		log.Printf("Failed to query statistics for alerting: %v", err)
+	}
+	return result, err
+}
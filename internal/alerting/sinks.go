@@ -0,0 +1,153 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+// maxSinkResponseSize 读取 webhook 响应体的上限，只用于错误信息和返回码检查
+const maxSinkResponseSize = 64 * 1024
+
+// namedSink 一个告警发送目标
+type namedSink struct {
+	name string
+	send func(alert Alert) error
+}
+
+// newSink 根据配置创建 sink
+func newSink(cfg config.AlertSinkConfig) namedSink {
+	timeout := parseDuration(cfg.Timeout, 10*time.Second)
+	if cfg.Type == "command" {
+		return namedSink{name: cfg.Name, send: func(alert Alert) error {
+			return runCommand(cfg, timeout, alert)
+		}}
+	}
+
+	client := &http.Client{Timeout: timeout}
+	return namedSink{name: cfg.Name, send: func(alert Alert) error {
+		return postWebhook(client, cfg, alert)
+	}}
+}
+
+// webhookPayload 按 sink 类型生成请求体
+// webhook 直接发送 Alert JSON，slack / feishu / dingtalk 使用各自的文本消息格式
+func webhookPayload(sinkType string, alert Alert) interface{} {
+	text := formatText(alert)
+	switch sinkType {
+	case "slack":
+		return map[string]interface{}{"text": text}
+	case "feishu":
+		return map[string]interface{}{
+			"msg_type": "text",
+			"content":  map[string]string{"text": text},
+		}
+	case "dingtalk":
+		return map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text},
+		}
+	default:
+		return alert
+	}
+}
+
+// formatText 生成聊天工具中显示的告警文本
+func formatText(alert Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", alert.Rule, alert.Title)
+	if alert.Message != "" {
+		b.WriteString("\n")
+		b.WriteString(alert.Message)
+	}
+	if count, ok := alert.Details["suppressed"]; ok {
+		fmt.Fprintf(&b, "\n(%v similar alerts suppressed during cooldown)", count)
+	}
+	fmt.Fprintf(&b, "\n%s", alert.Time.Format(time.RFC3339))
+	return b.String()
+}
+
+// postWebhook 发送告警到 webhook，非 2xx 状态码或 feishu / dingtalk 返回非 0 错误码时返回错误
+func postWebhook(client *http.Client, cfg config.AlertSinkConfig, alert Alert) error {
+	body, err := json.Marshal(webhookPayload(cfg.Type, alert))
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxSinkResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	// 飞书、钉钉在 HTTP 200 的响应体中用错误码表示失败
+	if cfg.Type == "feishu" || cfg.Type == "dingtalk" {
+		var result struct {
+			Code    *int   `json:"code"`
+			Msg     string `json:"msg"`
+			ErrCode *int   `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+		}
+		if json.Unmarshal(respBody, &result) == nil {
+			if result.Code != nil && *result.Code != 0 {
+				return fmt.Errorf("webhook returned code %d: %s", *result.Code, result.Msg)
+			}
+			if result.ErrCode != nil && *result.ErrCode != 0 {
+				return fmt.Errorf("webhook returned errcode %d: %s", *result.ErrCode, result.ErrMsg)
+			}
+		}
+	}
+	return nil
+}
+
+// runCommand 执行本地命令，告警 JSON 从 stdin 传入，主要字段同时以 CCCC_ALERT_* 环境变量提供
+func runCommand(cfg config.AlertSinkConfig, timeout time.Duration, alert Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, cfg.Command, cfg.Args...)
+	cmd.Stdin = bytes.NewReader(append(payload, '\n'))
+	cmd.Env = append(os.Environ(),
+		"CCCC_ALERT_RULE="+alert.Rule,
+		"CCCC_ALERT_TYPE="+alert.Type,
+		"CCCC_ALERT_ENDPOINT="+alert.Endpoint,
+		"CCCC_ALERT_TITLE="+alert.Title,
+		"CCCC_ALERT_MESSAGE="+alert.Message,
+	)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("command timed out after %v", timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("command failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
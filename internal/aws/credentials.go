@@ -0,0 +1,138 @@
+package aws
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+// Credentials AWS 访问凭证
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// LoadCredentials 按顺序解析凭证：配置中的静态密钥 -> 配置指定的 profile -> 环境变量 -> AWS_PROFILE / default profile
+func LoadCredentials(cfg *config.AWSConfig) (Credentials, error) {
+	if cfg != nil && cfg.AccessKeyID != "" {
+		if cfg.SecretAccessKey == "" {
+			return Credentials{}, fmt.Errorf("aws_config.secret_access_key is required when access_key_id is set")
+		}
+		return Credentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			SessionToken:    cfg.SessionToken,
+		}, nil
+	}
+
+	if cfg != nil && cfg.Profile != "" {
+		return loadProfileCredentials(cfg.Profile)
+	}
+
+	if accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); accessKey != "" && secretKey != "" {
+		return Credentials{
+			AccessKeyID:     accessKey,
+			SecretAccessKey: secretKey,
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	creds, err := loadProfileCredentials(profile)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("no aws credentials found in config, environment or shared files: %v", err)
+	}
+	return creds, nil
+}
+
+// ResolveRegion 配置中的 region 优先，其次为 AWS_REGION / AWS_DEFAULT_REGION 环境变量
+func ResolveRegion(cfg *config.AWSConfig) string {
+	if cfg != nil && cfg.Region != "" {
+		return cfg.Region
+	}
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// loadProfileCredentials 从共享凭证文件（~/.aws/credentials）或配置文件（~/.aws/config）读取 profile
+func loadProfileCredentials(profile string) (Credentials, error) {
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	configFile := os.Getenv("AWS_CONFIG_FILE")
+	if home, err := os.UserHomeDir(); err == nil {
+		if credentialsFile == "" {
+			credentialsFile = filepath.Join(home, ".aws", "credentials")
+		}
+		if configFile == "" {
+			configFile = filepath.Join(home, ".aws", "config")
+		}
+	}
+
+	// ~/.aws/config 中非 default 的 profile 使用 [profile name] 作为节名
+	configSection := "profile " + profile
+	if profile == "default" {
+		configSection = "default"
+	}
+
+	for _, source := range []struct{ file, section string }{
+		{credentialsFile, profile},
+		{configFile, configSection},
+	} {
+		if source.file == "" {
+			continue
+		}
+		values, err := readINISection(source.file, source.section)
+		if err != nil || values["aws_access_key_id"] == "" {
+			continue
+		}
+		if values["aws_secret_access_key"] == "" {
+			return Credentials{}, fmt.Errorf("profile '%s' in %s is missing aws_secret_access_key", profile, source.file)
+		}
+		return Credentials{
+			AccessKeyID:     values["aws_access_key_id"],
+			SecretAccessKey: values["aws_secret_access_key"],
+			SessionToken:    values["aws_session_token"],
+		}, nil
+	}
+
+	return Credentials{}, fmt.Errorf("aws profile '%s' not found", profile)
+}
+
+// readINISection 读取 INI 文件中指定节的键值
+func readINISection(path, section string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	inSection := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.TrimSpace(line[1:len(line)-1]) == section
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if key, value, found := strings.Cut(line, "="); found {
+			values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,125 @@
+package aws
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// EventStreamMessage application/vnd.amazon.eventstream 中的一条消息
+type EventStreamMessage struct {
+	Headers map[string]string // 仅保留字符串类型的头部（:message-type、:event-type 等）
+	Payload []byte
+}
+
+// 消息帧结构：total_len(4) | headers_len(4) | prelude_crc(4) | headers | payload | message_crc(4)
+const (
+	eventStreamPreludeLen = 12
+	eventStreamTrailerLen = 4
+)
+
+// DecodeEventStream 解码完整的 event-stream 响应体，校验每一帧的 CRC
+func DecodeEventStream(data []byte) ([]EventStreamMessage, error) {
+	var messages []EventStreamMessage
+	for offset := 0; offset < len(data); {
+		if len(data)-offset < eventStreamPreludeLen+eventStreamTrailerLen {
+			return messages, fmt.Errorf("truncated event-stream frame at offset %d", offset)
+		}
+
+		prelude := data[offset : offset+eventStreamPreludeLen]
+		totalLen := int(binary.BigEndian.Uint32(prelude[0:4]))
+		headersLen := int(binary.BigEndian.Uint32(prelude[4:8]))
+		if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+			return messages, fmt.Errorf("event-stream prelude checksum mismatch at offset %d", offset)
+		}
+		if totalLen < eventStreamPreludeLen+eventStreamTrailerLen+headersLen || offset+totalLen > len(data) {
+			return messages, fmt.Errorf("invalid event-stream frame length %d at offset %d", totalLen, offset)
+		}
+
+		frame := data[offset : offset+totalLen]
+		if crc32.ChecksumIEEE(frame[:totalLen-eventStreamTrailerLen]) != binary.BigEndian.Uint32(frame[totalLen-eventStreamTrailerLen:]) {
+			return messages, fmt.Errorf("event-stream message checksum mismatch at offset %d", offset)
+		}
+
+		headers, err := decodeEventStreamHeaders(frame[eventStreamPreludeLen : eventStreamPreludeLen+headersLen])
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, EventStreamMessage{
+			Headers: headers,
+			Payload: frame[eventStreamPreludeLen+headersLen : totalLen-eventStreamTrailerLen],
+		})
+		offset += totalLen
+	}
+	return messages, nil
+}
+
+// EncodeEventStreamMessage 编码一条只包含字符串头部的消息（用于测试与本地模拟上游）
+func EncodeEventStreamMessage(headers map[string]string, payload []byte) []byte {
+	var headerBytes []byte
+	for name, value := range headers {
+		headerBytes = append(headerBytes, byte(len(name)))
+		headerBytes = append(headerBytes, name...)
+		headerBytes = append(headerBytes, 7)
+		headerBytes = binary.BigEndian.AppendUint16(headerBytes, uint16(len(value)))
+		headerBytes = append(headerBytes, value...)
+	}
+
+	totalLen := eventStreamPreludeLen + len(headerBytes) + len(payload) + eventStreamTrailerLen
+	frame := make([]byte, 0, totalLen)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(totalLen))
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(headerBytes)))
+	frame = binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(frame))
+	frame = append(frame, headerBytes...)
+	frame = append(frame, payload...)
+	return binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(frame))
+}
+
+// decodeEventStreamHeaders 解析头部；非字符串类型的值按长度跳过
+func decodeEventStreamHeaders(data []byte) (map[string]string, error) {
+	headers := map[string]string{}
+	for i := 0; i < len(data); {
+		nameLen := int(data[i])
+		i++
+		if i+nameLen+1 > len(data) {
+			return nil, fmt.Errorf("truncated event-stream header name")
+		}
+		name := string(data[i : i+nameLen])
+		i += nameLen
+		valueType := data[i]
+		i++
+
+		var valueLen int
+		switch valueType {
+		case 0, 1: // bool true / false
+			valueLen = 0
+		case 2: // byte
+			valueLen = 1
+		case 3: // int16
+			valueLen = 2
+		case 4: // int32
+			valueLen = 4
+		case 5, 8: // int64 / timestamp
+			valueLen = 8
+		case 9: // uuid
+			valueLen = 16
+		case 6, 7: // bytes / string
+			if i+2 > len(data) {
+				return nil, fmt.Errorf("truncated event-stream header value length")
+			}
+			valueLen = int(binary.BigEndian.Uint16(data[i : i+2]))
+			i += 2
+		default:
+			return nil, fmt.Errorf("unknown event-stream header type %d", valueType)
+		}
+
+		if i+valueLen > len(data) {
+			return nil, fmt.Errorf("truncated event-stream header value")
+		}
+		if valueType == 7 {
+			headers[name] = string(data[i : i+valueLen])
+		}
+		i += valueLen
+	}
+	return headers, nil
+}
@@ -0,0 +1,167 @@
+package aws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat    = "20060102T150405Z"
+	shortDateFormat  = "20060102"
+)
+
+// SignRequest 使用 AWS Signature Version 4 为请求签名，设置 X-Amz-Date、X-Amz-Security-Token 与 Authorization 头部
+// 只对 host、content-type 与 x-amz-* 头部签名：代理透传的客户端头部可能被中间层改写，签入后会导致签名失效
+func SignRequest(req *http.Request, body []byte, creds Credentials, region, service string, signTime time.Time) error {
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return fmt.Errorf("aws credentials are incomplete")
+	}
+	if region == "" {
+		return fmt.Errorf("aws region is required for signing")
+	}
+
+	signTime = signTime.UTC()
+	amzDate := signTime.Format(amzDateFormat)
+	shortDate := signTime.Format(shortDateFormat)
+
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	canonicalHeaders, signedHeaders := canonicalHeaders(req)
+	payloadHash := sha256Hex(body)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{shortDate, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		signingAlgorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(SigningKey(creds.SecretAccessKey, shortDate, region, service), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signingAlgorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// SigningKey 派生签名密钥：HMAC(HMAC(HMAC(HMAC("AWS4"+secret, date), region), service), "aws4_request")
+func SigningKey(secretAccessKey, shortDate, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secretAccessKey), shortDate)
+	kRegion := hmacSHA256(kDate, region)
+	kService := hmacSHA256(kRegion, service)
+	return hmacSHA256(kService, "aws4_request")
+}
+
+// canonicalURI 非 S3 服务需要对已转义的路径再按段编码一次
+func canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery 按键、值排序并重新编码查询参数
+func canonicalQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		values = u.Query()
+	}
+
+	var pairs []string
+	for key, vals := range values {
+		for _, val := range vals {
+			pairs = append(pairs, uriEncode(key)+"="+uriEncode(val))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func canonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for key, values := range req.Header {
+		name := strings.ToLower(key)
+		if name != "content-type" && !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[name] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteString(":")
+		sb.WriteString(headers[name])
+		sb.WriteString("\n")
+	}
+	return sb.String(), strings.Join(names, ";")
+}
+
+// uriEncode 按 SigV4 规则编码：只保留 A-Z a-z 0-9 - _ . ~，其余字节编码为大写 %XX
+func uriEncode(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
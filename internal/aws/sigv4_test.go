@@ -0,0 +1,160 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 来自 AWS 官方 aws-sig-v4-test-suite 的测试向量（region us-east-1，service "service"）
+var testSuiteCredentials = Credentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+var testSuiteTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+func TestSigningKey(t *testing.T) {
+	// AWS 文档中派生签名密钥的示例
+	key := SigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	expected := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if hex.EncodeToString(key) != expected {
+		t.Errorf("Expected signing key %s, got %s", expected, hex.EncodeToString(key))
+	}
+}
+
+func TestSignRequest_TestSuite(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		headers       map[string]string
+		body          string
+		signedHeaders string
+		signature     string
+	}{
+		{
+			name:          "get-vanilla",
+			method:        "GET",
+			url:           "https://example.amazonaws.com/",
+			signedHeaders: "host;x-amz-date",
+			signature:     "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "get-vanilla-query-order-key-case",
+			method:        "GET",
+			url:           "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			signedHeaders: "host;x-amz-date",
+			signature:     "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:          "get-vanilla-empty-query-key",
+			method:        "GET",
+			url:           "https://example.amazonaws.com/?Param1=value1",
+			signedHeaders: "host;x-amz-date",
+			signature:     "a67d582fa61cc504c4bae71f336f98b97f1ea3c7a6bfe1b6e45aec72011b9aeb",
+		},
+		{
+			name:          "post-vanilla",
+			method:        "POST",
+			url:           "https://example.amazonaws.com/",
+			signedHeaders: "host;x-amz-date",
+			signature:     "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:          "post-vanilla-query",
+			method:        "POST",
+			url:           "https://example.amazonaws.com/?Param1=value1",
+			signedHeaders: "host;x-amz-date",
+			signature:     "28038455d6de14eafc1f9222cf5aa6f1a96197d7deb8263271d420d138af7f11",
+		},
+		{
+			name:          "post-x-www-form-urlencoded",
+			method:        "POST",
+			url:           "https://example.amazonaws.com/",
+			headers:       map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:          "Param1=value1",
+			signedHeaders: "content-type;host;x-amz-date",
+			signature:     "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, tc.url, bytes.NewReader([]byte(tc.body)))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			// 透传的客户端头部不参与签名
+			req.Header.Set("User-Agent", "test-client")
+
+			if err := SignRequest(req, []byte(tc.body), testSuiteCredentials, "us-east-1", "service", testSuiteTime); err != nil {
+				t.Fatalf("SignRequest failed: %v", err)
+			}
+
+			expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=" +
+				tc.signedHeaders + ", Signature=" + tc.signature
+			if got := req.Header.Get("Authorization"); got != expected {
+				t.Errorf("Unexpected Authorization header\nexpected: %s\ngot:      %s", expected, got)
+			}
+			if req.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+				t.Errorf("Unexpected X-Amz-Date: %s", req.Header.Get("X-Amz-Date"))
+			}
+		})
+	}
+}
+
+func TestSignRequest_SessionTokenAndEscapedPath(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-3-5-sonnet-20241022-v2%3A0/invoke", nil)
+	creds := testSuiteCredentials
+	creds.SessionToken = "session-token"
+
+	if err := SignRequest(req, []byte(`{}`), creds, "us-east-1", "bedrock", testSuiteTime); err != nil {
+		t.Fatalf("SignRequest failed: %v", err)
+	}
+
+	if req.Header.Get("X-Amz-Security-Token") != "session-token" {
+		t.Errorf("Expected session token header to be set")
+	}
+	if uri := canonicalURI(req.URL); uri != "/model/anthropic.claude-3-5-sonnet-20241022-v2%253A0/invoke" {
+		t.Errorf("Expected double-encoded canonical URI, got %s", uri)
+	}
+	if !bytes.Contains([]byte(req.Header.Get("Authorization")), []byte("SignedHeaders=host;x-amz-date;x-amz-security-token,")) {
+		t.Errorf("Expected session token to be signed: %s", req.Header.Get("Authorization"))
+	}
+}
+
+func TestEventStreamRoundTrip(t *testing.T) {
+	frames := append(
+		EncodeEventStreamMessage(map[string]string{":message-type": "event", ":event-type": "chunk"}, []byte(`{"bytes":"e30="}`)),
+		EncodeEventStreamMessage(map[string]string{":message-type": "exception", ":exception-type": "throttlingException"}, []byte(`{"message":"slow down"}`))...,
+	)
+
+	messages, err := DecodeEventStream(frames)
+	if err != nil {
+		t.Fatalf("DecodeEventStream failed: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].Headers[":event-type"] != "chunk" || string(messages[0].Payload) != `{"bytes":"e30="}` {
+		t.Errorf("Unexpected first message: %+v", messages[0])
+	}
+	if messages[1].Headers[":exception-type"] != "throttlingException" {
+		t.Errorf("Unexpected second message: %+v", messages[1])
+	}
+
+	corrupted := append([]byte{}, frames...)
+	corrupted[20] ^= 0xff
+	if _, err := DecodeEventStream(corrupted); err == nil {
+		t.Error("Expected checksum error for corrupted frame")
+	}
+	if _, err := DecodeEventStream(frames[:len(frames)-3]); err == nil {
+		t.Error("Expected error for truncated stream")
+	}
+}
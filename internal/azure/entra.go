@@ -0,0 +1,101 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"claude-code-codex-companion/internal/common/tokencache"
+	"claude-code-codex-companion/internal/config"
+)
+
+// DefaultScope Azure OpenAI（Cognitive Services）所需的权限范围
+const DefaultScope = "https://cognitiveservices.azure.com/.default"
+
+// TokenURL 返回 Entra ID token 端点：配置优先，否则按租户生成
+func TokenURL(cfg *config.AzureConfig) string {
+	if cfg.TokenURL != "" {
+		return cfg.TokenURL
+	}
+	return "https://login.microsoftonline.com/" + url.PathEscape(cfg.TenantID) + "/oauth2/v2.0/token"
+}
+
+// TokenSource 通过客户端凭证授权换取 Entra ID 访问令牌，并在过期前复用缓存的令牌
+type TokenSource struct {
+	*tokencache.Cache
+
+	cfg *config.AzureConfig
+	now func() time.Time
+}
+
+// NewTokenSource 创建 Entra ID 令牌源
+func NewTokenSource(cfg *config.AzureConfig) *TokenSource {
+	s := &TokenSource{cfg: cfg, now: time.Now}
+	s.Cache = tokencache.New(s.mint, func() time.Time { return s.now() })
+	return s
+}
+
+// mint 校验凭证配置后换取新的访问令牌
+func (s *TokenSource) mint(httpClient *http.Client) (string, time.Duration, error) {
+	if s.cfg == nil || s.cfg.TenantID == "" || s.cfg.ClientID == "" || s.cfg.ClientSecret == "" {
+		return "", 0, fmt.Errorf("azure_config.tenant_id, client_id and client_secret are required for entra_id authentication")
+	}
+	return s.exchange(httpClient)
+}
+
+// exchange 使用 client_credentials 授权换取访问令牌
+func (s *TokenSource) exchange(httpClient *http.Client) (string, time.Duration, error) {
+	scope := s.cfg.Scope
+	if scope == "" {
+		scope = DefaultScope
+	}
+
+	formData := url.Values{}
+	formData.Set("grant_type", "client_credentials")
+	formData.Set("client_id", s.cfg.ClientID)
+	formData.Set("client_secret", s.cfg.ClientSecret)
+	formData.Set("scope", scope)
+
+	req, err := http.NewRequest("POST", TokenURL(s.cfg), strings.NewReader(formData.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create entra id token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to send entra id token request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read entra id token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("entra id token request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(respBody, &tokenResp); err != nil {
+		return "", 0, fmt.Errorf("failed to unmarshal entra id token response: %v", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", 0, fmt.Errorf("entra id token response missing access_token")
+	}
+
+	expiresIn := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		// 如果没有返回过期时间，按一小时处理
+		expiresIn = time.Hour
+	}
+	return tokenResp.AccessToken, expiresIn, nil
+}
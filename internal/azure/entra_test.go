@@ -0,0 +1,71 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestTokenSource_ClientCredentials(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "app-id" ||
+			r.Form.Get("client_secret") != "secret" || r.Form.Get("scope") != DefaultScope {
+			t.Errorf("Unexpected token request: %v", r.Form)
+		}
+		fmt.Fprintf(w, `{"token_type":"Bearer","expires_in":3599,"access_token":"entra-token-%d"}`, requests)
+	}))
+	defer server.Close()
+
+	source := NewTokenSource(&config.AzureConfig{
+		TenantID:     "tenant",
+		ClientID:     "app-id",
+		ClientSecret: "secret",
+		TokenURL:     server.URL,
+	})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	source.now = func() time.Time { return now }
+
+	if token, err := source.Token(server.Client()); err != nil || token != "entra-token-1" {
+		t.Fatalf("Unexpected token %s (%v)", token, err)
+	}
+	if token, ok := source.Cached(); !ok || token != "entra-token-1" {
+		t.Errorf("Expected cached token, got %s", token)
+	}
+
+	// 进入提前刷新窗口后重新换取
+	now = now.Add(56 * time.Minute)
+	if token, _ := source.Token(server.Client()); token != "entra-token-2" || requests != 2 {
+		t.Errorf("Expected refreshed token, got %s after %d requests", token, requests)
+	}
+}
+
+func TestTokenSource_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_client","error_description":"AADSTS7000215: Invalid client secret provided."}`))
+	}))
+	defer server.Close()
+
+	source := NewTokenSource(&config.AzureConfig{TenantID: "tenant", ClientID: "app-id", ClientSecret: "wrong", TokenURL: server.URL})
+	if _, err := source.Token(server.Client()); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("Expected invalid_client error, got %v", err)
+	}
+
+	if _, err := NewTokenSource(nil).Token(server.Client()); err == nil {
+		t.Error("Expected error when azure_config is missing")
+	}
+
+	if got := TokenURL(&config.AzureConfig{TenantID: "contoso.onmicrosoft.com"}); got != "https://login.microsoftonline.com/contoso.onmicrosoft.com/oauth2/v2.0/token" {
+		t.Errorf("Unexpected default token URL: %s", got)
+	}
+}
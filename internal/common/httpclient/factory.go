@@ -1,7 +1,6 @@
 package httpclient
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
@@ -35,6 +34,8 @@ type ClientConfig struct {
 	MaxIdlePerHost  int
 	DisableKeepAlive bool
 	InsecureSkipVerify bool
+	TLSConfig       *config.TLSConfig // 端点 TLS 配置（自定义 CA、mTLS、证书固定）
+	GlobalProxy     *config.GlobalProxyConfig // 全局代理配置，端点未配置代理时使用
 }
 
 // Factory HTTP客户端工厂
@@ -90,6 +91,14 @@ func (f *Factory) CreateClient(config ClientConfig) (*http.Client, error) {
 		config = f.mergeConfigs(defaultConfig, config)
 	}
 
+	tlsConfig, err := BuildTLSConfig(config.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tls config: %v", err)
+	}
+	if config.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
 	transport := &http.Transport{
 		TLSHandshakeTimeout:   config.Timeouts.TLSHandshake,
 		ResponseHeaderTimeout: config.Timeouts.ResponseHeader,
@@ -97,17 +106,15 @@ func (f *Factory) CreateClient(config ClientConfig) (*http.Client, error) {
 		DisableKeepAlives:     config.DisableKeepAlive,
 		MaxIdleConns:          config.MaxIdleConns,
 		MaxIdleConnsPerHost:   config.MaxIdlePerHost,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: config.InsecureSkipVerify,
-		},
+		TLSClientConfig:       tlsConfig,
 	}
 
-	// 如果配置了代理，设置代理拨号器
-	if config.ProxyConfig != nil {
-		dialer, err := f.createProxyDialer(config.ProxyConfig)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create proxy dialer: %v", err)
-		}
+	// 如果配置了代理（端点代理、全局代理或环境变量），设置代理拨号器
+	dialer, err := f.createRoutingDialer(config.ProxyConfig, config.GlobalProxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proxy dialer: %v", err)
+	}
+	if dialer != nil {
 		transport.DialContext = dialer.DialContext
 	}
 
@@ -175,6 +182,12 @@ func (f *Factory) mergeConfigs(defaultConfig, userConfig ClientConfig) ClientCon
 	if userConfig.ProxyConfig != nil {
 		result.ProxyConfig = userConfig.ProxyConfig
 	}
+	if userConfig.TLSConfig != nil {
+		result.TLSConfig = userConfig.TLSConfig
+	}
+	if userConfig.GlobalProxy != nil {
+		result.GlobalProxy = userConfig.GlobalProxy
+	}
 	
 	result.DisableKeepAlive = userConfig.DisableKeepAlive
 	result.InsecureSkipVerify = userConfig.InsecureSkipVerify
@@ -3,14 +3,18 @@ package httpclient
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"claude-code-codex-companion/internal/config"
 
+	"golang.org/x/net/http/httpproxy"
 	"golang.org/x/net/proxy"
 )
 
@@ -19,22 +23,168 @@ type ProxyDialer interface {
 	DialContext(ctx context.Context, network, address string) (net.Conn, error)
 }
 
-// createProxyDialer 根据代理配置创建代理拨号器
+// ProxyError 连接代理本身失败（代理不可达、TLS 握手失败、认证失败等），与上游端点的错误区分
+type ProxyError struct {
+	Proxy string // 代理地址
+	Err   error
+}
+
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("proxy error (%s): %v", e.Proxy, e.Err)
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// IsProxyError 判断错误是否由代理本身引起
+func IsProxyError(err error) bool {
+	var proxyErr *ProxyError
+	return errors.As(err, &proxyErr)
+}
+
+// createRoutingDialer 根据端点代理与全局代理配置创建拨号器，返回 nil 表示直连
+// 优先级：端点 proxy（type 为 direct 时直连）> 全局 default（no_proxy 中的主机直连）> 环境变量（use_environment）
+func (f *Factory) createRoutingDialer(proxyConfig *config.ProxyConfig, globalProxy *config.GlobalProxyConfig) (ProxyDialer, error) {
+	if proxyConfig != nil {
+		if proxyConfig.Type == "direct" {
+			return nil, nil
+		}
+		return f.createProxyDialer(proxyConfig)
+	}
+
+	if globalProxy == nil {
+		return nil, nil
+	}
+
+	if globalProxy.Default != nil {
+		proxyDialer, err := f.createProxyDialer(globalProxy.Default)
+		if err != nil {
+			return nil, err
+		}
+		// 仅用 httpproxy 的 NO_PROXY 匹配规则判断是否直连，代理地址本身不会被使用
+		matcher := (&httpproxy.Config{
+			HTTPProxy:  "http://" + globalProxy.Default.Address,
+			HTTPSProxy: "http://" + globalProxy.Default.Address,
+			NoProxy:    strings.Join(globalProxy.NoProxy, ","),
+		}).ProxyFunc()
+		return &routingProxyDialer{
+			direct: newDirectDialer(),
+			resolve: func(address string) (ProxyDialer, error) {
+				if proxyURL, _ := matcher(targetURL(address)); proxyURL == nil {
+					return nil, nil
+				}
+				return proxyDialer, nil
+			},
+		}, nil
+	}
+
+	if globalProxy.UseEnvironment {
+		proxyFunc := httpproxy.FromEnvironment().ProxyFunc()
+		return &routingProxyDialer{
+			direct: newDirectDialer(),
+			resolve: func(address string) (ProxyDialer, error) {
+				proxyURL, err := proxyFunc(targetURL(address))
+				if err != nil || proxyURL == nil {
+					return nil, err
+				}
+				envProxy, err := ProxyConfigFromURL(proxyURL)
+				if err != nil {
+					return nil, err
+				}
+				return f.createProxyDialer(envProxy)
+			},
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// ProxyConfigFromURL 将代理 URL（如环境变量 HTTPS_PROXY 的值）转换为代理配置
+func ProxyConfigFromURL(proxyURL *url.URL) (*config.ProxyConfig, error) {
+	proxyConfig := &config.ProxyConfig{Address: proxyURL.Host}
+	switch proxyURL.Scheme {
+	case "", "http":
+		proxyConfig.Type = "http"
+	case "https":
+		proxyConfig.Type = "https"
+	case "socks5", "socks5h":
+		proxyConfig.Type = "socks5"
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", proxyURL.Scheme)
+	}
+
+	if proxyURL.Port() == "" {
+		defaultPort := map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxyConfig.Type]
+		proxyConfig.Address = net.JoinHostPort(proxyURL.Hostname(), defaultPort)
+	}
+	if proxyURL.User != nil {
+		proxyConfig.Username = proxyURL.User.Username()
+		proxyConfig.Password, _ = proxyURL.User.Password()
+	}
+	return proxyConfig, nil
+}
+
+// targetURL 由拨号地址构造用于代理匹配的 URL
+// 拨号时无法得知请求协议，按端口推断：80 端口视为 http，其余视为 https（上游 API 基本都是 https）
+func targetURL(address string) *url.URL {
+	scheme := "https"
+	if _, port, err := net.SplitHostPort(address); err == nil && port == "80" {
+		scheme = "http"
+	}
+	return &url.URL{Scheme: scheme, Host: address}
+}
+
+// newDirectDialer 创建直连拨号器
+func newDirectDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   config.Default.ProxyDialer.Timeout,
+		KeepAlive: config.Default.ProxyDialer.KeepAlive,
+	}
+}
+
+// routingProxyDialer 按目标地址选择代理或直连
+type routingProxyDialer struct {
+	direct  *net.Dialer
+	resolve func(address string) (ProxyDialer, error) // 返回 nil 表示直连
+}
+
+func (r *routingProxyDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	proxyDialer, err := r.resolve(address)
+	if err != nil {
+		return nil, &ProxyError{Proxy: "environment", Err: err}
+	}
+	if proxyDialer == nil {
+		return r.direct.DialContext(ctx, network, address)
+	}
+	return proxyDialer.DialContext(ctx, network, address)
+}
+
+// createProxyDialer 根据代理配置创建代理拨号器，配置了 via 时经由上一级代理连接本代理
 func (f *Factory) createProxyDialer(proxyConfig *config.ProxyConfig) (ProxyDialer, error) {
+	var forward ProxyDialer = newDirectDialer()
+	if proxyConfig.Via != nil {
+		viaDialer, err := f.createProxyDialer(proxyConfig.Via)
+		if err != nil {
+			return nil, err
+		}
+		forward = viaDialer
+	}
+
 	switch proxyConfig.Type {
-	case "http":
-		return f.createHTTPProxyDialer(proxyConfig)
+	case "http", "https":
+		return f.createHTTPProxyDialer(proxyConfig, forward)
 	case "socks5":
-		return f.createSOCKS5ProxyDialer(proxyConfig)
+		return f.createSOCKS5ProxyDialer(proxyConfig, forward)
 	default:
 		return nil, fmt.Errorf("unsupported proxy type: %s", proxyConfig.Type)
 	}
 }
 
-// createHTTPProxyDialer 创建HTTP代理拨号器
-func (f *Factory) createHTTPProxyDialer(proxyConfig *config.ProxyConfig) (ProxyDialer, error) {
+// createHTTPProxyDialer 创建HTTP代理拨号器（https 类型与代理之间使用 TLS）
+func (f *Factory) createHTTPProxyDialer(proxyConfig *config.ProxyConfig, forward ProxyDialer) (ProxyDialer, error) {
 	proxyURL := &url.URL{
-		Scheme: "http",
+		Scheme: proxyConfig.Type,
 		Host:   proxyConfig.Address,
 	}
 
@@ -42,17 +192,26 @@ func (f *Factory) createHTTPProxyDialer(proxyConfig *config.ProxyConfig) (ProxyD
 		proxyURL.User = url.UserPassword(proxyConfig.Username, proxyConfig.Password)
 	}
 
-	return &httpProxyDialer{
+	dialer := &httpProxyDialer{
 		proxyURL: proxyURL,
-		dialer: &net.Dialer{
-			Timeout:   config.Default.ProxyDialer.Timeout,
-			KeepAlive: config.Default.ProxyDialer.KeepAlive,
-		},
-	}, nil
+		forward:  forward,
+	}
+	if proxyConfig.Type == "https" {
+		tlsConfig, err := BuildTLSConfig(proxyConfig.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build https proxy tls config: %v", err)
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = proxyURL.Hostname()
+		}
+		dialer.tlsConfig = tlsConfig
+	}
+
+	return dialer, nil
 }
 
 // createSOCKS5ProxyDialer 创建SOCKS5代理拨号器
-func (f *Factory) createSOCKS5ProxyDialer(proxyConfig *config.ProxyConfig) (ProxyDialer, error) {
+func (f *Factory) createSOCKS5ProxyDialer(proxyConfig *config.ProxyConfig, forward ProxyDialer) (ProxyDialer, error) {
 	var auth *proxy.Auth
 	if proxyConfig.Username != "" && proxyConfig.Password != "" {
 		auth = &proxy.Auth{
@@ -61,28 +220,61 @@ func (f *Factory) createSOCKS5ProxyDialer(proxyConfig *config.ProxyConfig) (Prox
 		}
 	}
 
-	dialer, err := proxy.SOCKS5("tcp", proxyConfig.Address, auth, proxy.Direct)
+	// 连接 SOCKS5 服务器本身失败属于代理错误
+	forwardDialer := &proxyForwardDialer{address: proxyConfig.Address, forward: forward}
+	dialer, err := proxy.SOCKS5("tcp", proxyConfig.Address, auth, forwardDialer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SOCKS5 proxy: %v", err)
 	}
 
 	if contextDialer, ok := dialer.(proxy.ContextDialer); ok {
-		return &socks5ProxyDialer{contextDialer: contextDialer}, nil
+		return &socks5ProxyDialer{address: proxyConfig.Address, contextDialer: contextDialer}, nil
 	}
 
-	return &socks5ProxyDialer{dialer: dialer}, nil
+	return &socks5ProxyDialer{address: proxyConfig.Address, dialer: dialer}, nil
+}
+
+// proxyForwardDialer 将 ProxyDialer 适配为 x/net/proxy 的 Dialer，连接失败时包装为 ProxyError
+type proxyForwardDialer struct {
+	address string
+	forward ProxyDialer
+}
+
+func (p *proxyForwardDialer) Dial(network, address string) (net.Conn, error) {
+	return p.DialContext(context.Background(), network, address)
+}
+
+func (p *proxyForwardDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := p.forward.DialContext(ctx, network, address)
+	if err != nil && !IsProxyError(err) {
+		return nil, &ProxyError{Proxy: p.address, Err: err}
+	}
+	return conn, err
 }
 
 // httpProxyDialer HTTP代理拨号器实现
 type httpProxyDialer struct {
-	proxyURL *url.URL
-	dialer   *net.Dialer
+	proxyURL  *url.URL
+	forward   ProxyDialer
+	tlsConfig *tls.Config // https 代理的 TLS 配置
 }
 
 func (h *httpProxyDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
-	proxyConn, err := h.dialer.DialContext(ctx, "tcp", h.proxyURL.Host)
+	proxyConn, err := h.forward.DialContext(ctx, "tcp", h.proxyURL.Host)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to HTTP proxy %s: %v", h.proxyURL.Host, err)
+		if IsProxyError(err) {
+			return nil, err
+		}
+		return nil, &ProxyError{Proxy: h.proxyURL.Host, Err: fmt.Errorf("failed to connect to HTTP proxy: %v", err)}
+	}
+
+	if h.tlsConfig != nil {
+		tlsConn := tls.Client(proxyConn, h.tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			proxyConn.Close()
+			return nil, &ProxyError{Proxy: h.proxyURL.Host, Err: fmt.Errorf("TLS handshake with HTTPS proxy failed: %v", err)}
+		}
+		proxyConn = tlsConn
 	}
 
 	connectReq := &http.Request{
@@ -93,24 +285,32 @@ func (h *httpProxyDialer) DialContext(ctx context.Context, network, address stri
 	}
 
 	if h.proxyURL.User != nil {
-		connectReq.Header.Set("Proxy-Authorization", "Basic "+basicAuth(h.proxyURL.User.String()))
+		password, _ := h.proxyURL.User.Password()
+		connectReq.Header.Set("Proxy-Authorization", "Basic "+basicAuth(h.proxyURL.User.Username()+":"+password))
 	}
 
 	if err := connectReq.Write(proxyConn); err != nil {
 		proxyConn.Close()
-		return nil, fmt.Errorf("failed to send CONNECT request: %v", err)
+		return nil, &ProxyError{Proxy: h.proxyURL.Host, Err: fmt.Errorf("failed to send CONNECT request: %v", err)}
 	}
 
 	resp, err := http.ReadResponse(bufio.NewReader(proxyConn), connectReq)
 	if err != nil {
 		proxyConn.Close()
-		return nil, fmt.Errorf("failed to read CONNECT response: %v", err)
+		return nil, &ProxyError{Proxy: h.proxyURL.Host, Err: fmt.Errorf("failed to read CONNECT response: %v", err)}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		proxyConn.Close()
-		return nil, fmt.Errorf("proxy returned non-200 status: %s", resp.Status)
+		// 502/503/504 表示代理正常但无法连接目标，属于上游错误；其余（如 407 认证失败、403 拒绝）属于代理错误
+		connectErr := fmt.Errorf("proxy returned non-200 status: %s", resp.Status)
+		switch resp.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return nil, fmt.Errorf("upstream %s unreachable via proxy %s: %v", address, h.proxyURL.Host, connectErr)
+		default:
+			return nil, &ProxyError{Proxy: h.proxyURL.Host, Err: connectErr}
+		}
 	}
 
 	return proxyConn, nil
@@ -118,11 +318,31 @@ func (h *httpProxyDialer) DialContext(ctx context.Context, network, address stri
 
 // socks5ProxyDialer SOCKS5代理拨号器实现
 type socks5ProxyDialer struct {
+	address       string
 	contextDialer proxy.ContextDialer
 	dialer        proxy.Dialer
 }
 
 func (s *socks5ProxyDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := s.dial(ctx, network, address)
+	if err != nil && !IsProxyError(err) && !isSOCKS5TargetError(err) {
+		return nil, &ProxyError{Proxy: s.address, Err: err}
+	}
+	return conn, err
+}
+
+// isSOCKS5TargetError 判断 SOCKS5 错误是否为代理报告的目标不可达（上游错误）
+func isSOCKS5TargetError(err error) bool {
+	errStr := err.Error()
+	for _, reply := range []string{"network unreachable", "host unreachable", "connection refused", "TTL expired"} {
+		if strings.Contains(errStr, reply) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *socks5ProxyDialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
 	if s.contextDialer != nil {
 		return s.contextDialer.DialContext(ctx, network, address)
 	}
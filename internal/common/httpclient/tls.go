@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+// BuildTLSConfig 根据端点 TLS 配置构建 tls.Config
+// 自定义 CA 与系统信任库合并；配置了证书固定时，验证通过的证书链（含根证书）中任一证书的 SPKI 摘要匹配即通过
+func BuildTLSConfig(tlsConfig *config.TLSConfig) (*tls.Config, error) {
+	result := &tls.Config{}
+	if tlsConfig == nil {
+		return result, nil
+	}
+
+	minVersion, err := config.ParseTLSVersion(tlsConfig.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	result.MinVersion = minVersion
+	result.ServerName = tlsConfig.ServerName
+	result.InsecureSkipVerify = tlsConfig.InsecureSkipVerify
+
+	if tlsConfig.CAFile != "" {
+		caPEM, err := os.ReadFile(tlsConfig.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca_file: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificates found in tls ca_file: %s", tlsConfig.CAFile)
+		}
+		result.RootCAs = pool
+	}
+
+	if tlsConfig.CertFile != "" || tlsConfig.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(tlsConfig.CertFile, tlsConfig.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls client certificate: %v", err)
+		}
+		result.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(tlsConfig.PinnedSPKISHA256) > 0 {
+		pins := make([][]byte, 0, len(tlsConfig.PinnedSPKISHA256))
+		for _, pin := range tlsConfig.PinnedSPKISHA256 {
+			digest, err := config.DecodeSPKIPin(pin)
+			if err != nil {
+				return nil, err
+			}
+			pins = append(pins, digest)
+		}
+		// VerifyConnection 在 InsecureSkipVerify 时同样会被调用，固定校验不会被跳过
+		// 服务端可以在 PeerCertificates 中附带任意未经验证的证书，因此只匹配验证通过的证书链；
+		// 跳过校验时没有验证链，只匹配叶子证书
+		insecure := result.InsecureSkipVerify
+		result.VerifyConnection = func(state tls.ConnectionState) error {
+			var certs []*x509.Certificate
+			if insecure {
+				if len(state.PeerCertificates) > 0 {
+					certs = state.PeerCertificates[:1]
+				}
+			} else {
+				for _, chain := range state.VerifiedChains {
+					certs = append(certs, chain...)
+				}
+			}
+			return verifySPKIPins(certs, pins)
+		}
+	}
+
+	return result, nil
+}
+
+// SPKIPin 计算证书公钥（SPKI）的 SHA-256 固定值（base64）
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// verifySPKIPins 检查证书链中是否有证书的 SPKI 摘要与固定值匹配
+func verifySPKIPins(certs []*x509.Certificate, pins [][]byte) error {
+	for _, cert := range certs {
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if bytes.Equal(sum[:], pin) {
+				return nil
+			}
+		}
+	}
+	if len(certs) > 0 {
+		return fmt.Errorf("tls certificate pinning failed: no certificate in chain matches pinned_spki_sha256 (server leaf pin: sha256/%s)", SPKIPin(certs[0]))
+	}
+	return fmt.Errorf("tls certificate pinning failed: server presented no certificates")
+}
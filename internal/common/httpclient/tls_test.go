@@ -0,0 +1,279 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+// testCA 测试用自签名 CA，可签发服务端与客户端证书
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue 签发证书，返回 tls.Certificate 以及 PEM 格式的证书与私钥
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) (tls.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load issued key pair: %v", err)
+	}
+	return cert, certPEM, keyPEM
+}
+
+// newTLSServer 使用指定 CA 签发的证书启动 TLS 服务（证书对 api.example.com 与 127.0.0.1 有效）
+func newTLSServer(t *testing.T, ca *testCA, configure func(*tls.Config)) *httptest.Server {
+	serverCert, _, _ := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "api.example.com"},
+		DNSNames:    []string{"api.example.com"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	})
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Header().Set("X-Client-CN", r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // 握手失败是预期行为
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	if configure != nil {
+		configure(server.TLS)
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func writeTempFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func doTLSRequest(t *testing.T, tlsConfig *config.TLSConfig, url string) (*http.Response, error) {
+	client, err := NewFactory().CreateClient(ClientConfig{Type: ClientTypeEndpoint, TLSConfig: tlsConfig})
+	if err != nil {
+		t.Fatalf("CreateClient failed: %v", err)
+	}
+	resp, err := client.Get(url)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestTLS_CustomCA(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTLSServer(t, ca, nil)
+
+	if _, err := doTLSRequest(t, nil, server.URL); err == nil {
+		t.Fatalf("Expected unknown CA to be rejected without ca_file")
+	}
+
+	caFile := writeTempFile(t, "ca.pem", ca.pem)
+	if _, err := doTLSRequest(t, &config.TLSConfig{CAFile: caFile}, server.URL); err != nil {
+		t.Errorf("Expected request to succeed with custom CA, got %v", err)
+	}
+}
+
+func TestTLS_ClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	server := newTLSServer(t, ca, func(c *tls.Config) {
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+		c.ClientCAs = pool
+	})
+
+	caFile := writeTempFile(t, "ca.pem", ca.pem)
+	if _, err := doTLSRequest(t, &config.TLSConfig{CAFile: caFile}, server.URL); err == nil {
+		t.Fatalf("Expected server to require a client certificate")
+	}
+
+	_, certPEM, keyPEM := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "proxy-client"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	})
+	tlsConfig := &config.TLSConfig{
+		CAFile:   caFile,
+		CertFile: writeTempFile(t, "client.pem", certPEM),
+		KeyFile:  writeTempFile(t, "client-key.pem", keyPEM),
+	}
+	resp, err := doTLSRequest(t, tlsConfig, server.URL)
+	if err != nil {
+		t.Fatalf("Expected mTLS request to succeed, got %v", err)
+	}
+	if cn := resp.Header.Get("X-Client-CN"); cn != "proxy-client" {
+		t.Errorf("Expected client certificate to be presented, got CN %q", cn)
+	}
+}
+
+func TestTLS_SPKIPinning(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTLSServer(t, ca, nil)
+	caFile := writeTempFile(t, "ca.pem", ca.pem)
+
+	leafPin := SPKIPin(server.Certificate())
+	caPin := SPKIPin(ca.cert)
+	otherPin := SPKIPin(newTestCA(t).cert)
+
+	// 固定叶子证书或 CA 证书均可
+	for _, pin := range []string{leafPin, "sha256/" + caPin} {
+		if _, err := doTLSRequest(t, &config.TLSConfig{CAFile: caFile, PinnedSPKISHA256: []string{otherPin, pin}}, server.URL); err != nil {
+			t.Errorf("Expected pin %s to match, got %v", pin, err)
+		}
+	}
+
+	_, err := doTLSRequest(t, &config.TLSConfig{CAFile: caFile, PinnedSPKISHA256: []string{otherPin}}, server.URL)
+	if err == nil || !strings.Contains(err.Error(), "pinning failed") || !strings.Contains(err.Error(), leafPin) {
+		t.Errorf("Expected pin mismatch to be rejected with the server pin in the error, got %v", err)
+	}
+
+	// 跳过证书校验时仍然执行证书固定
+	if _, err := doTLSRequest(t, &config.TLSConfig{InsecureSkipVerify: true, PinnedSPKISHA256: []string{otherPin}}, server.URL); err == nil {
+		t.Errorf("Expected pinning to be enforced with insecure_skip_verify")
+	}
+}
+
+func TestTLS_SPKIPinningIgnoresUnverifiedExtraCertificates(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := writeTempFile(t, "ca.pem", ca.pem)
+
+	// 被固定的证书来自另一个 CA，攻击者只需把它附加在自己合法证书链的末尾
+	pinnedCA := newTestCA(t)
+	pinnedCert, _, _ := pinnedCA.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "pinned"}})
+	pin := SPKIPin(pinnedCert.Leaf)
+	server := newTLSServer(t, ca, func(c *tls.Config) {
+		c.Certificates[0].Certificate = append(c.Certificates[0].Certificate, pinnedCert.Certificate[0])
+	})
+
+	_, err := doTLSRequest(t, &config.TLSConfig{CAFile: caFile, PinnedSPKISHA256: []string{pin}}, server.URL)
+	if err == nil || !strings.Contains(err.Error(), "pinning failed") {
+		t.Errorf("Expected appended pinned certificate to be ignored, got %v", err)
+	}
+
+	_, err = doTLSRequest(t, &config.TLSConfig{InsecureSkipVerify: true, PinnedSPKISHA256: []string{pin}}, server.URL)
+	if err == nil || !strings.Contains(err.Error(), "pinning failed") {
+		t.Errorf("Expected only the leaf certificate to be pinned with insecure_skip_verify, got %v", err)
+	}
+}
+
+func TestTLS_InsecureSkipVerify(t *testing.T) {
+	server := newTLSServer(t, newTestCA(t), nil)
+
+	if _, err := doTLSRequest(t, &config.TLSConfig{InsecureSkipVerify: true}, server.URL); err != nil {
+		t.Errorf("Expected insecure_skip_verify to accept unknown CA, got %v", err)
+	}
+}
+
+func TestTLS_ServerNameAndMinVersion(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := writeTempFile(t, "ca.pem", ca.pem)
+	var sni string
+	server := newTLSServer(t, ca, func(c *tls.Config) {
+		c.MaxVersion = tls.VersionTLS12
+		c.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			sni = hello.ServerName
+			return nil, nil
+		}
+	})
+
+	if _, err := doTLSRequest(t, &config.TLSConfig{CAFile: caFile, ServerName: "api.example.com"}, server.URL); err != nil {
+		t.Fatalf("Expected server_name override to succeed, got %v", err)
+	}
+	if sni != "api.example.com" {
+		t.Errorf("Expected SNI api.example.com, got %q", sni)
+	}
+
+	if _, err := doTLSRequest(t, &config.TLSConfig{CAFile: caFile, ServerName: "other.example.com"}, server.URL); err == nil {
+		t.Errorf("Expected certificate name mismatch to be rejected")
+	}
+
+	if _, err := doTLSRequest(t, &config.TLSConfig{CAFile: caFile, MinVersion: "1.3"}, server.URL); err == nil {
+		t.Errorf("Expected min_version 1.3 to reject a TLS 1.2 server")
+	}
+}
+
+func TestBuildTLSConfig_InvalidConfig(t *testing.T) {
+	testCases := []struct {
+		name      string
+		tlsConfig *config.TLSConfig
+	}{
+		{"missing ca file", &config.TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		{"ca file without certificates", &config.TLSConfig{CAFile: writeTempFile(t, "empty.pem", []byte("not a certificate"))}},
+		{"invalid min version", &config.TLSConfig{MinVersion: "1.4"}},
+		{"invalid pin", &config.TLSConfig{PinnedSPKISHA256: []string{"c2hvcnQ="}}},
+		{"missing key file", &config.TLSConfig{CertFile: "client.pem"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := BuildTLSConfig(tc.tlsConfig); err == nil {
+				t.Errorf("Expected error for %s", tc.name)
+			}
+			if _, err := NewFactory().CreateClient(ClientConfig{Type: ClientTypeProxy, TLSConfig: tc.tlsConfig}); err == nil {
+				t.Errorf("Expected CreateClient to fail for %s", tc.name)
+			}
+		})
+	}
+}
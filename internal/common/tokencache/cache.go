@@ -0,0 +1,65 @@
+package tokencache
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RefreshBuffer 提前5分钟刷新，避免在请求过程中过期（与 oauth 包保持一致）
+const RefreshBuffer = 5 * time.Minute
+
+// MintFunc 换取新的访问令牌，返回令牌及其有效期
+type MintFunc func(httpClient *http.Client) (string, time.Duration, error)
+
+// Cache 缓存 mint 换取的访问令牌，在过期前复用；并发调用在换取期间串行等待
+type Cache struct {
+	mint MintFunc
+	now  func() time.Time
+
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// New 创建令牌缓存；now 为 nil 时使用 time.Now
+func New(mint MintFunc, now func() time.Time) *Cache {
+	if now == nil {
+		now = time.Now
+	}
+	return &Cache{mint: mint, now: now}
+}
+
+// Token 返回有效的访问令牌，缓存即将过期时使用 httpClient 重新换取
+func (c *Cache) Token(httpClient *http.Client) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.validLocked() {
+		return c.token, nil
+	}
+
+	token, expiresIn, err := c.mint(httpClient)
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.expiresAt = c.now().Add(expiresIn)
+	return c.token, nil
+}
+
+// Cached 返回未过期的缓存令牌，不发起网络请求
+func (c *Cache) Cached() (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.validLocked() {
+		return "", false
+	}
+	return c.token, true
+}
+
+// validLocked 缓存令牌在刷新提前量之外仍有效（调用方需持有锁）
+func (c *Cache) validLocked() bool {
+	return c.token != "" && c.now().Add(RefreshBuffer).Before(c.expiresAt)
+}
@@ -0,0 +1,55 @@
+package tokencache
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCache_ReusesTokenUntilRefreshBuffer(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mints := 0
+	cache := New(func(*http.Client) (string, time.Duration, error) {
+		mints++
+		return fmt.Sprintf("token-%d", mints), time.Hour, nil
+	}, func() time.Time { return now })
+
+	if _, ok := cache.Cached(); ok {
+		t.Fatalf("expected empty cache before the first mint")
+	}
+	if token, err := cache.Token(nil); err != nil || token != "token-1" {
+		t.Fatalf("expected token-1, got %q (%v)", token, err)
+	}
+
+	now = now.Add(54 * time.Minute)
+	if token, ok := cache.Cached(); !ok || token != "token-1" {
+		t.Fatalf("expected cached token-1, got %q (%v)", token, ok)
+	}
+	if token, _ := cache.Token(nil); token != "token-1" || mints != 1 {
+		t.Fatalf("expected reuse of token-1, got %q after %d mints", token, mints)
+	}
+
+	// 进入刷新提前量后重新换取
+	now = now.Add(time.Minute)
+	if _, ok := cache.Cached(); ok {
+		t.Fatalf("expected cached token to expire within the refresh buffer")
+	}
+	if token, _ := cache.Token(nil); token != "token-2" || mints != 2 {
+		t.Fatalf("expected token-2, got %q after %d mints", token, mints)
+	}
+}
+
+func TestCache_MintErrorKeepsCacheEmpty(t *testing.T) {
+	cache := New(func(*http.Client) (string, time.Duration, error) {
+		return "", 0, errors.New("mint failed")
+	}, nil)
+
+	if _, err := cache.Token(nil); err == nil || err.Error() != "mint failed" {
+		t.Fatalf("expected mint error, got %v", err)
+	}
+	if _, ok := cache.Cached(); ok {
+		t.Fatalf("expected no cached token after a failed mint")
+	}
+}
@@ -4,4 +4,5 @@ package config
 func (e EndpointConfig) GetName() string     { return e.Name }
 func (e EndpointConfig) GetURL() string      { return e.URL }
 func (e EndpointConfig) GetAuthType() string { return e.AuthType }
-func (e EndpointConfig) GetAuthValue() string { return e.AuthValue }
\ No newline at end of file
+func (e EndpointConfig) GetAuthValue() string { return e.AuthValue }
+func (e EndpointConfig) GetEndpointType() string { return e.EndpointType }
\ No newline at end of file
@@ -20,6 +20,7 @@ type DefaultValues struct {
 		HealthCheckTimeout string
 		CheckInterval      string
 		RecoveryThreshold  int
+		OAuthRefreshMargin string
 	}
 
 	// HTTP客户端配置默认值（统一配置）
@@ -122,6 +123,7 @@ var Default = DefaultValues{
 		HealthCheckTimeout string
 		CheckInterval      string
 		RecoveryThreshold  int
+		OAuthRefreshMargin string
 	}{
 		TLSHandshake:       "10s",
 		ResponseHeader:     "60s",
@@ -129,6 +131,7 @@ var Default = DefaultValues{
 		HealthCheckTimeout: "30s",
 		CheckInterval:      "30s",
 		RecoveryThreshold:  1,
+		OAuthRefreshMargin: "10m",
 	},
 
 	HTTPClient: struct {
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 	"claude-code-codex-companion/internal/i18n"
@@ -104,6 +105,7 @@ func generateDefaultConfig(filename string) error {
 			IdleConnection:     "90s",
 			HealthCheckTimeout: "30s",
 			CheckInterval:      "30s",
+			OAuthRefreshMargin: "10m",
 		},
 	}
 
@@ -145,18 +147,44 @@ func SaveConfig(config *Config, filename string) error {
 		return fmt.Errorf("failed to marshal config: %v", err)
 	}
 
-	// 创建备份文件
-	if _, err := os.Stat(filename); err == nil {
-		backupFilename := filename + ".backup"
-		if err := os.Rename(filename, backupFilename); err != nil {
+	// 创建备份文件（复制而不是重命名，保证任意时刻配置文件都存在）
+	if oldData, err := os.ReadFile(filename); err == nil {
+		if err := os.WriteFile(filename+".backup", oldData, 0644); err != nil {
 			return fmt.Errorf("failed to create backup: %v", err)
 		}
 	}
 
-	// 写入新配置
-	if err := os.WriteFile(filename, data, 0644); err != nil {
+	// 先写入同目录下的临时文件并落盘，再原子替换，避免写入中途失败（如后台刷新 OAuth token 时进程退出）导致配置文件损坏
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %v", err)
 	}
 
 	return nil
+}
+
+// writeFileAtomic 通过临时文件 + rename 原子地写入文件
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmpFile.Name()
+	defer os.Remove(tmpName) // rename 成功后临时文件已不存在，删除失败可以忽略
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpName, filename)
 }
\ No newline at end of file
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+
+	if err := writeFileAtomic(filename, []byte("first"), 0644); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+	if err := writeFileAtomic(filename, []byte("second"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic overwrite failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil || string(data) != "second" {
+		t.Fatalf("Expected replaced content, got %q (%v)", data, err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+
+	// 临时文件在替换后不残留
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the target file to remain, got %d entries", len(entries))
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "config.yaml"), []byte("x"), 0644); err == nil {
+		t.Errorf("Expected error when the directory does not exist")
+	}
+}
@@ -8,6 +8,76 @@ type Config struct {
 	Tagging     TaggingConfig     `yaml:"tagging"`     // 标签系统配置（永远启用）
 	Timeouts    TimeoutConfig     `yaml:"timeouts"`    // 超时配置
 	I18n        I18nConfig        `yaml:"i18n"`        // 国际化配置
+	Proxy       GlobalProxyConfig `yaml:"proxy,omitempty"` // 全局代理配置（作用于未单独配置代理的端点）
+	Metrics     MetricsConfig     `yaml:"metrics,omitempty"` // Prometheus 指标端点配置
+	Tracing     TracingConfig     `yaml:"tracing,omitempty"` // OpenTelemetry 链路追踪配置
+	Pricing     []ModelPricingConfig `yaml:"pricing,omitempty"` // 模型价格，用于会话视图中的费用估算
+	Alerting    AlertingConfig    `yaml:"alerting,omitempty"` // 端点状态告警
+}
+
+// AlertingConfig 告警配置：规则命中时发送到 sink，同一告警在冷却时间内只发送一次
+type AlertingConfig struct {
+	Enabled            bool              `yaml:"enabled,omitempty"`
+	Cooldown           string            `yaml:"cooldown,omitempty"`            // 同一告警（规则 + 端点）的最短发送间隔，默认 15m
+	EvaluationInterval string            `yaml:"evaluation_interval,omitempty"` // 轮询类规则的检查间隔，默认 1m
+	Sinks              []AlertSinkConfig `yaml:"sinks,omitempty"`
+	Rules              []AlertRuleConfig `yaml:"rules,omitempty"`
+}
+
+// AlertSinkConfig 告警发送目标
+type AlertSinkConfig struct {
+	Name    string            `yaml:"name"`
+	Type    string            `yaml:"type"`              // webhook | slack | feishu | dingtalk | command
+	URL     string            `yaml:"url,omitempty"`     // webhook 类 sink 的地址
+	Headers map[string]string `yaml:"headers,omitempty"` // webhook 类 sink 附加的请求头
+	Command string            `yaml:"command,omitempty"` // command：可执行文件路径，告警 JSON 从 stdin 传入
+	Args    []string          `yaml:"args,omitempty"`    // command：参数
+	Timeout string            `yaml:"timeout,omitempty"` // 单次发送超时，默认 10s
+}
+
+// AlertRuleConfig 告警规则
+type AlertRuleConfig struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`                   // endpoint_blacklisted | all_endpoints_down | error_rate | oauth_refresh_failed | spend_cap | rate_limit_rejected
+	Endpoints   []string `yaml:"endpoints,omitempty"`    // 只对这些端点告警，为空表示全部
+	Tag         string   `yaml:"tag,omitempty"`          // all_endpoints_down：只在请求带有该标签时告警
+	Threshold   float64  `yaml:"threshold,omitempty"`    // error_rate：错误率百分比；spend_cap：费用上限（美元）
+	Window      string   `yaml:"window,omitempty"`       // error_rate 默认 5m，spend_cap 默认 24h
+	MinRequests int      `yaml:"min_requests,omitempty"` // error_rate：窗口内最少请求数，默认 10
+	Failures    int      `yaml:"failures,omitempty"`     // oauth_refresh_failed：连续刷新失败次数，默认 3
+	Cooldown    string   `yaml:"cooldown,omitempty"`     // 覆盖全局 cooldown
+	Sinks       []string `yaml:"sinks,omitempty"`        // 发送到的 sink 名称，为空表示全部
+}
+
+// ModelPricingConfig 模型价格配置，单位为美元 / 百万 token
+// model 支持通配符（*），按配置顺序匹配第一条；cache_read / cache_write 未配置时按 input 价格计算
+type ModelPricingConfig struct {
+	Model      string   `yaml:"model"`                 // 模型名或通配符，如 claude-sonnet-*
+	Input      float64  `yaml:"input"`                 // 输入 token 价格
+	Output     float64  `yaml:"output"`                // 输出 token 价格
+	CacheRead  *float64 `yaml:"cache_read,omitempty"`  // 缓存读取 token 价格
+	CacheWrite *float64 `yaml:"cache_write,omitempty"` // 缓存写入 token 价格
+}
+
+// TracingConfig OpenTelemetry 链路追踪配置
+// 未启用时仍会解析入站 traceparent，请求日志中记录其 trace ID，但不导出 span
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled,omitempty"`      // 是否导出 span
+	Exporter    string            `yaml:"exporter,omitempty"`     // "otlp"（OTLP/HTTP，默认）| "file"（每行一个 span 的 JSON）
+	Endpoint    string            `yaml:"endpoint,omitempty"`     // OTLP/HTTP 地址，默认 http://localhost:4318/v1/traces
+	Headers     map[string]string `yaml:"headers,omitempty"`      // OTLP 请求附加头部（如认证）
+	FilePath    string            `yaml:"file_path,omitempty"`    // file 导出器的输出文件，默认 ./logs/traces.jsonl
+	ServiceName string            `yaml:"service_name,omitempty"` // service.name，默认 claude-code-codex-companion
+	SampleRatio float64           `yaml:"sample_ratio,omitempty"` // 根 span 采样比例（0-1），0 表示全部采样；有入站 traceparent 时沿用其采样决定
+}
+
+// MetricsConfig Prometheus 指标端点配置
+// 默认在 /metrics 暴露；bearer_token 与 allowed_ips 均为可选的访问限制，同时配置时两者都需满足
+type MetricsConfig struct {
+	Disabled    bool     `yaml:"disabled,omitempty"`     // 关闭指标端点
+	Path        string   `yaml:"path,omitempty"`         // 指标路径，默认 /metrics
+	BearerToken string   `yaml:"bearer_token,omitempty"` // 要求 Authorization: Bearer <token>
+	AllowedIPs  []string `yaml:"allowed_ips,omitempty"`  // 允许访问的来源 IP 或 CIDR
 }
 
 // I18nConfig 国际化配置
@@ -25,7 +95,7 @@ type ServerConfig struct {
 type EndpointConfig struct {
 	Name              string              `yaml:"name"`
 	URL               string              `yaml:"url"`
-	EndpointType      string              `yaml:"endpoint_type"` // "anthropic" | "openai" 等
+	EndpointType      string              `yaml:"endpoint_type"` // "anthropic" | "openai" | "gemini" | "bedrock" | "vertex-anthropic" | "azure-openai" | "ollama"
 	PathPrefix        string              `yaml:"path_prefix,omitempty"` // OpenAI端点的路径前缀，如 "/v1/chat/completions"
 	AuthType          string              `yaml:"auth_type"`
 	AuthValue         string              `yaml:"auth_value"`
@@ -35,15 +105,36 @@ type EndpointConfig struct {
 	ModelRewrite      *ModelRewriteConfig `yaml:"model_rewrite,omitempty"` // 新增：模型重写配置
 	Proxy             *ProxyConfig        `yaml:"proxy,omitempty"`         // 新增：代理配置
 	OAuthConfig       *OAuthConfig        `yaml:"oauth_config,omitempty"`  // 新增：OAuth配置
+	AWSConfig         *AWSConfig          `yaml:"aws_config,omitempty" json:"aws_config,omitempty"` // bedrock 端点的 SigV4 签名配置
+	VertexConfig      *VertexConfig       `yaml:"vertex_config,omitempty" json:"vertex_config,omitempty"` // vertex-anthropic 端点的项目、区域与服务账号配置
+	AzureConfig       *AzureConfig        `yaml:"azure_config,omitempty" json:"azure_config,omitempty"`   // azure-openai 端点的部署映射、API 版本与 Entra ID 配置
+	OllamaConfig      *OllamaConfig       `yaml:"ollama_config,omitempty" json:"ollama_config,omitempty"` // ollama 端点的 num_ctx、keep_alive 等 options
+	TLS               *TLSConfig          `yaml:"tls,omitempty" json:"tls,omitempty"`                     // 自定义 CA、客户端证书（mTLS）与证书固定
 	HeaderOverrides     map[string]string `yaml:"header_overrides,omitempty" json:"header_overrides,omitempty"`         // 新增：HTTP Header覆盖配置
 	ParameterOverrides  map[string]string `yaml:"parameter_overrides,omitempty" json:"parameter_overrides,omitempty"` // 新增：Request Parameters覆盖配置
 	MaxTokensFieldName  string            `yaml:"max_tokens_field_name,omitempty" json:"max_tokens_field_name,omitempty"` // max_tokens 参数名转换选项
+	ToolSchemaProfile   string            `yaml:"tool_schema_profile,omitempty" json:"tool_schema_profile,omitempty"`     // 工具 JSON Schema 规范化档位: "lenient"(默认) | "openai_strict" | "gemini" | "none"
+	ToolArgumentValidation string         `yaml:"tool_argument_validation,omitempty" json:"tool_argument_validation,omitempty"` // 工具参数校验策略: "repair"(默认) | "retry" | "failover" | "off"
+	EnforceSingleToolCall  bool           `yaml:"enforce_single_tool_call,omitempty" json:"enforce_single_tool_call,omitempty"` // 端点忽略 parallel_tool_calls 时，客户端禁用并行工具调用则只保留第一个工具调用
+	GeminiSafetySettings map[string]string `yaml:"gemini_safety_settings,omitempty" json:"gemini_safety_settings,omitempty"` // gemini 端点的安全过滤阈值，如 HARM_CATEGORY_HARASSMENT: BLOCK_NONE
+	OpenAIAPI           string            `yaml:"openai_api,omitempty" json:"openai_api,omitempty"`                   // openai 端点的上游协议: "chat_completions"(默认) | "responses"
 	RateLimitReset      *int64            `yaml:"rate_limit_reset,omitempty" json:"rate_limit_reset,omitempty"`       // Anthropic-Ratelimit-Unified-Reset
 	RateLimitStatus     *string           `yaml:"rate_limit_status,omitempty" json:"rate_limit_status,omitempty"`     // Anthropic-Ratelimit-Unified-Status
 	EnhancedProtection  bool              `yaml:"enhanced_protection,omitempty" json:"enhanced_protection,omitempty"` // 官方帐号增强保护：allowed_warning时即禁用端点
 	SSEConfig         *SSEConfig        `yaml:"sse_config,omitempty" json:"sse_config,omitempty"` // SSE行为配置
 }
 
+// GetEndpointFormat 返回端点类型对应的内部请求格式："anthropic" 或 "openai"
+// gemini 等原生协议端点按 OpenAI 格式转换后，再由上游适配器转换为原生协议
+func GetEndpointFormat(endpointType string) string {
+	switch endpointType {
+	case "openai", "gemini", "azure-openai", "ollama":
+		return "openai"
+	default:
+		return "anthropic"
+	}
+}
+
 // 新增：SSE行为配置结构
 type SSEConfig struct {
 	RequireDoneMarker bool `yaml:"require_done_marker" json:"require_done_marker"` // 是否要求[DONE]标记
@@ -51,10 +142,20 @@ type SSEConfig struct {
 
 // 新增：代理配置结构
 type ProxyConfig struct {
-	Type     string `yaml:"type" json:"type"`         // "http" | "socks5"
+	Type     string `yaml:"type" json:"type"`         // "http" | "https" | "socks5" | "direct"（仅端点级，绕过全局代理）
 	Address  string `yaml:"address" json:"address"`   // 代理服务器地址，如 "127.0.0.1:1080"
 	Username string `yaml:"username,omitempty" json:"username,omitempty"` // 代理认证用户名（可选）
 	Password string `yaml:"password,omitempty" json:"password,omitempty"` // 代理认证密码（可选）
+	Via      *ProxyConfig `yaml:"via,omitempty" json:"via,omitempty"` // 经由另一个代理连接本代理（代理链），如 SOCKS5 经 HTTP 代理
+	TLS      *TLSConfig   `yaml:"tls,omitempty" json:"tls,omitempty"` // 仅 https 类型：与代理之间的 TLS 设置（自定义 CA 等）
+}
+
+// GlobalProxyConfig 全局代理配置
+// 端点未配置 proxy 时使用 default（no_proxy 中的主机直连）；未配置 default 且开启 use_environment 时读取环境变量
+type GlobalProxyConfig struct {
+	Default        *ProxyConfig `yaml:"default,omitempty" json:"default,omitempty"`                 // 全局默认代理
+	NoProxy        []string     `yaml:"no_proxy,omitempty" json:"no_proxy,omitempty"`               // 不经过全局代理的主机，语法同 NO_PROXY（域名后缀、IP、CIDR）
+	UseEnvironment bool         `yaml:"use_environment,omitempty" json:"use_environment,omitempty"` // 读取 HTTP_PROXY / HTTPS_PROXY / NO_PROXY 环境变量
 }
 
 // 新增：OAuth 配置结构
@@ -68,6 +169,58 @@ type OAuthConfig struct {
 	AutoRefresh  bool     `yaml:"auto_refresh" json:"auto_refresh"`                     // 是否自动刷新
 }
 
+// AWSConfig AWS SigV4 签名配置（auth_type: aws_sigv4）
+// 未配置静态密钥时依次使用 profile、环境变量与默认 profile 中的凭证
+type AWSConfig struct {
+	Region          string `yaml:"region,omitempty" json:"region,omitempty"`                       // 区域，为空时使用 AWS_REGION / AWS_DEFAULT_REGION
+	AccessKeyID     string `yaml:"access_key_id,omitempty" json:"access_key_id,omitempty"`         // 静态访问密钥 ID
+	SecretAccessKey string `yaml:"secret_access_key,omitempty" json:"secret_access_key,omitempty"` // 静态访问密钥
+	SessionToken    string `yaml:"session_token,omitempty" json:"session_token,omitempty"`         // 临时凭证的会话令牌（可选）
+	Profile         string `yaml:"profile,omitempty" json:"profile,omitempty"`                     // ~/.aws/credentials 中的 profile 名称
+}
+
+// VertexConfig Google Vertex AI 配置（endpoint_type: vertex-anthropic）
+// 未配置服务账号密钥时使用 GOOGLE_APPLICATION_CREDENTIALS 指向的密钥文件
+type VertexConfig struct {
+	ProjectID          string   `yaml:"project_id" json:"project_id"`                                         // GCP 项目 ID
+	Region             string   `yaml:"region" json:"region"`                                                 // 区域，如 us-east5、europe-west1 或 global
+	ServiceAccountFile string   `yaml:"service_account_file,omitempty" json:"service_account_file,omitempty"` // 服务账号 JSON 密钥文件路径
+	ServiceAccountJSON string   `yaml:"service_account_json,omitempty" json:"service_account_json,omitempty"` // 内联的服务账号 JSON 密钥
+	TokenURL           string   `yaml:"token_url,omitempty" json:"token_url,omitempty"`                       // Token 端点，默认使用密钥中的 token_uri
+	Scopes             []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`                             // 权限范围，默认 cloud-platform
+}
+
+// AzureConfig Azure OpenAI 配置（endpoint_type: azure-openai）
+// auth_type 为 entra_id 时使用 tenant_id / client_id / client_secret 通过客户端凭证授权换取 Entra ID 令牌
+type AzureConfig struct {
+	APIVersion   string            `yaml:"api_version,omitempty" json:"api_version,omitempty"`     // api-version 查询参数，默认 2025-04-01-preview
+	Deployments  map[string]string `yaml:"deployments,omitempty" json:"deployments,omitempty"`     // 模型名 -> 部署名，未匹配时使用模型名
+	TenantID     string            `yaml:"tenant_id,omitempty" json:"tenant_id,omitempty"`         // Entra ID 租户 ID
+	ClientID     string            `yaml:"client_id,omitempty" json:"client_id,omitempty"`         // 应用（客户端）ID
+	ClientSecret string            `yaml:"client_secret,omitempty" json:"client_secret,omitempty"` // 客户端密码
+	TokenURL     string            `yaml:"token_url,omitempty" json:"token_url,omitempty"`         // Token 端点，默认 https://login.microsoftonline.com/{tenant_id}/oauth2/v2.0/token
+	Scope        string            `yaml:"scope,omitempty" json:"scope,omitempty"`                 // 权限范围，默认 https://cognitiveservices.azure.com/.default
+}
+
+// OllamaConfig Ollama 配置（endpoint_type: ollama）
+// OpenAI 兼容接口无法设置的 options 与 keep_alive 通过原生 /api/chat 接口传递
+type OllamaConfig struct {
+	NumCtx    int                    `yaml:"num_ctx,omitempty" json:"num_ctx,omitempty"`       // 上下文窗口大小（options.num_ctx）
+	KeepAlive string                 `yaml:"keep_alive,omitempty" json:"keep_alive,omitempty"` // 模型在内存中保留的时间，如 "10m"；纯数字按秒计算，-1 表示常驻
+	Options   map[string]interface{} `yaml:"options,omitempty" json:"options,omitempty"`       // 其它 options 默认值，如 num_gpu、repeat_penalty；请求中的采样参数优先
+}
+
+// TLSConfig 端点 TLS 配置，同时作用于代理请求与健康检查
+type TLSConfig struct {
+	CAFile             string   `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`                             // 额外信任的 CA 证书（PEM），与系统信任库合并
+	CertFile           string   `yaml:"cert_file,omitempty" json:"cert_file,omitempty"`                         // 客户端证书（PEM，mTLS）
+	KeyFile            string   `yaml:"key_file,omitempty" json:"key_file,omitempty"`                           // 客户端私钥（PEM，mTLS）
+	ServerName         string   `yaml:"server_name,omitempty" json:"server_name,omitempty"`                     // 覆盖 SNI 与证书校验使用的主机名
+	MinVersion         string   `yaml:"min_version,omitempty" json:"min_version,omitempty"`                     // 最低 TLS 版本："1.0" | "1.1" | "1.2" | "1.3"
+	PinnedSPKISHA256   []string `yaml:"pinned_spki_sha256,omitempty" json:"pinned_spki_sha256,omitempty"`       // 证书链中任一证书公钥（SPKI）的 SHA-256（base64），可带 "sha256/" 前缀
+	InsecureSkipVerify bool     `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"` // 跳过证书校验（仅用于实验环境）
+}
+
 // 新增：模型重写配置结构
 type ModelRewriteConfig struct {
 	Enabled bool               `yaml:"enabled" json:"enabled"` // 是否启用模型重写
@@ -86,6 +239,41 @@ type LoggingConfig struct {
 	LogRequestBody  string `yaml:"log_request_body"`
 	LogResponseBody string `yaml:"log_response_body"`
 	LogDirectory    string `yaml:"log_directory"`
+	BodyCompression string `yaml:"body_compression,omitempty"` // 请求/响应体数据块压缩方式：zstd（默认）| gzip | none
+	MaxStorageMB    int    `yaml:"max_storage_mb,omitempty"`   // 日志数据库与数据块总大小上限（MB），0 表示不限制
+
+	Retention LogRetentionConfig `yaml:"retention,omitempty"` // 保留策略，成功与失败请求分别配置
+	Sampling  []LogSamplingRule  `yaml:"sampling,omitempty"`  // 成功请求的采样规则，按顺序匹配第一条
+	Redaction LogRedactionConfig `yaml:"redaction,omitempty"` // 入库与导出前的敏感信息脱敏
+}
+
+// LogRetentionConfig 日志保留策略
+type LogRetentionConfig struct {
+	Interval string           `yaml:"interval,omitempty"` // 检查间隔，默认 1h
+	Success  LogRetentionRule `yaml:"success,omitempty"`  // 成功请求（状态码 < 400 且无错误）
+	Failed   LogRetentionRule `yaml:"failed,omitempty"`   // 失败请求
+}
+
+// LogRetentionRule 单类请求的保留上限，任一条件超出即删除最旧的日志
+type LogRetentionRule struct {
+	MaxAge    string `yaml:"max_age,omitempty"`     // 最长保留时间，默认 720h（30 天），"0" 表示不限制
+	MaxRows   int    `yaml:"max_rows,omitempty"`    // 最多保留条数，0 表示不限制
+	MaxSizeMB int    `yaml:"max_size_mb,omitempty"` // 请求体与响应体原始大小之和上限（MB），0 表示不限制
+}
+
+// LogSamplingRule 按端点、客户端类型或标签设置记录比例，多个匹配条件需同时满足
+type LogSamplingRule struct {
+	Endpoint   string  `yaml:"endpoint,omitempty"`    // 端点名称
+	ClientType string  `yaml:"client_type,omitempty"` // claude-code | codex
+	Tag        string  `yaml:"tag,omitempty"`
+	Rate       float64 `yaml:"rate"` // 记录比例，0-1
+}
+
+// LogRedactionConfig 脱敏规则；内置规则覆盖常见认证头和 API key 格式
+type LogRedactionConfig struct {
+	DisableDefaults bool     `yaml:"disable_defaults,omitempty"` // 关闭内置规则
+	Headers         []string `yaml:"headers,omitempty"`          // 额外需要脱敏的请求/响应头（不区分大小写）
+	Patterns        []string `yaml:"patterns,omitempty"`         // 正文中需要脱敏的正则；含捕获组时只替换第一个捕获组
 }
 
 type ValidationConfig struct {
@@ -110,6 +298,8 @@ type TimeoutConfig struct {
 	HealthCheckTimeout string `yaml:"health_check_timeout" json:"health_check_timeout"` // 健康检查整体响应超时，默认30s
 	CheckInterval      string `yaml:"check_interval" json:"check_interval"`             // 健康检查间隔，默认30s
 	RecoveryThreshold  int    `yaml:"recovery_threshold" json:"recovery_threshold"`     // 连续成功多少次后恢复端点，默认1
+	// OAuth 后台刷新配置
+	OAuthRefreshMargin string `yaml:"oauth_refresh_margin" json:"oauth_refresh_margin"` // 在 token 过期前多久主动刷新，默认10m
 }
 
 // 代理客户端超时配置（内部使用，从TimeoutConfig转换）
@@ -1,9 +1,15 @@
 package config
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
 	"fmt"
 	"net"
+	"net/url"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -82,6 +88,31 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("invalid log_response_body '%s', must be one of: none, truncated, full", config.Logging.LogResponseBody)
 	}
 
+	// Validate body_compression
+	if config.Logging.BodyCompression == "" {
+		config.Logging.BodyCompression = "zstd"
+	}
+	switch config.Logging.BodyCompression {
+	case "zstd", "gzip", "none":
+	default:
+		return fmt.Errorf("invalid body_compression '%s', must be one of: zstd, gzip, none", config.Logging.BodyCompression)
+	}
+
+	if config.Logging.MaxStorageMB < 0 {
+		return fmt.Errorf("max_storage_mb cannot be negative")
+	}
+
+	// 验证日志保留、采样与脱敏配置
+	if err := validateLogRetentionConfig(&config.Logging.Retention); err != nil {
+		return fmt.Errorf("logging retention configuration error: %v", err)
+	}
+	if err := validateLogSamplingRules(config.Logging.Sampling); err != nil {
+		return fmt.Errorf("logging sampling configuration error: %v", err)
+	}
+	if err := validateLogRedactionConfig(&config.Logging.Redaction); err != nil {
+		return fmt.Errorf("logging redaction configuration error: %v", err)
+	}
+
 	// 验证Tagging配置
 	if err := validateTaggingConfig(&config.Tagging); err != nil {
 		return fmt.Errorf("tagging configuration error: %v", err)
@@ -106,6 +137,34 @@ func validateConfig(config *Config) error {
 	if err := validateProxyConfigs(config.Endpoints); err != nil {
 		return fmt.Errorf("proxy configuration error: %v", err)
 	}
+	if err := validateGlobalProxyConfig(&config.Proxy); err != nil {
+		return fmt.Errorf("proxy configuration error: %v", err)
+	}
+
+	// 验证指标端点配置
+	if err := validateMetricsConfig(&config.Metrics); err != nil {
+		return fmt.Errorf("metrics configuration error: %v", err)
+	}
+
+	// 验证链路追踪配置
+	if err := validateTracingConfig(&config.Tracing); err != nil {
+		return fmt.Errorf("tracing configuration error: %v", err)
+	}
+
+	// 验证模型价格配置
+	if err := validatePricingConfig(config.Pricing); err != nil {
+		return fmt.Errorf("pricing configuration error: %v", err)
+	}
+
+	// 验证告警配置
+	if err := validateAlertingConfig(&config.Alerting); err != nil {
+		return fmt.Errorf("alerting configuration error: %v", err)
+	}
+
+	// 验证TLS配置
+	if err := validateTLSConfigs(config.Endpoints); err != nil {
+		return fmt.Errorf("tls configuration error: %v", err)
+	}
 
 	// 验证OAuth配置
 	if err := validateOAuthConfigs(config.Endpoints); err != nil {
@@ -200,6 +259,9 @@ func validateTimeoutConfig(config *TimeoutConfig) error {
 	if config.CheckInterval == "" {
 		config.CheckInterval = "30s"
 	}
+	if config.OAuthRefreshMargin == "" {
+		config.OAuthRefreshMargin = "10m"
+	}
 
 	// 验证所有非空超时时间格式
 	timeoutFields := map[string]string{
@@ -208,6 +270,7 @@ func validateTimeoutConfig(config *TimeoutConfig) error {
 		"idle_connection":        config.IdleConnection,
 		"health_check_timeout":   config.HealthCheckTimeout,
 		"check_interval":         config.CheckInterval,
+		"oauth_refresh_margin":   config.OAuthRefreshMargin,
 	}
 
 	for fieldName, value := range timeoutFields {
@@ -394,14 +457,296 @@ func ValidateProxyConfig(config *ProxyConfig, context string) error {
 	return validateProxyConfig(config, context)
 }
 
+// validateGlobalProxyConfig 验证全局代理配置
+func validateGlobalProxyConfig(config *GlobalProxyConfig) error {
+	if config.Default != nil {
+		if config.Default.Type == "direct" {
+			return fmt.Errorf("proxy.default: type 'direct' is only allowed on endpoints")
+		}
+		if err := validateProxyConfig(config.Default, "proxy.default"); err != nil {
+			return err
+		}
+	}
+
+	for _, host := range config.NoProxy {
+		if strings.TrimSpace(host) == "" {
+			return fmt.Errorf("proxy.no_proxy: empty host entry")
+		}
+	}
+
+	return nil
+}
+
+// metricsReservedPaths 指标路径不能与之冲突的已有路由前缀
+var metricsReservedPaths = []string{"/v1", "/admin", "/static", "/responses", "/chat/completions"}
+
+// validateMetricsConfig 验证指标端点配置并设置默认路径
+func validateMetricsConfig(config *MetricsConfig) error {
+	if config.Path == "" {
+		config.Path = "/metrics"
+	}
+	if !strings.HasPrefix(config.Path, "/") || config.Path == "/" || strings.ContainsAny(config.Path, ":*") {
+		return fmt.Errorf("metrics.path must be an absolute path other than '/' without wildcards, got '%s'", config.Path)
+	}
+	for _, reserved := range metricsReservedPaths {
+		if config.Path == reserved || strings.HasPrefix(config.Path, reserved+"/") {
+			return fmt.Errorf("metrics.path '%s' conflicts with the built-in route %s", config.Path, reserved)
+		}
+	}
+
+	for _, entry := range config.AllowedIPs {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return fmt.Errorf("metrics.allowed_ips: invalid CIDR '%s'", entry)
+			}
+		} else if net.ParseIP(entry) == nil {
+			return fmt.Errorf("metrics.allowed_ips: invalid IP address '%s'", entry)
+		}
+	}
+
+	return nil
+}
+
+// validateTracingConfig 验证链路追踪配置并设置默认值
+func validateTracingConfig(config *TracingConfig) error {
+	if config.Exporter == "" {
+		config.Exporter = "otlp"
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "claude-code-codex-companion"
+	}
+
+	switch config.Exporter {
+	case "otlp":
+		if config.Endpoint == "" {
+			config.Endpoint = "http://localhost:4318/v1/traces"
+		}
+		parsed, err := url.Parse(config.Endpoint)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("tracing.endpoint must be an http(s) URL, got '%s'", config.Endpoint)
+		}
+	case "file":
+		if config.FilePath == "" {
+			config.FilePath = "./logs/traces.jsonl"
+		}
+	default:
+		return fmt.Errorf("tracing.exporter must be 'otlp' or 'file', got '%s'", config.Exporter)
+	}
+
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %v", config.SampleRatio)
+	}
+
+	return nil
+}
+
+// validatePricingConfig 验证模型价格配置
+func validatePricingConfig(pricing []ModelPricingConfig) error {
+	for i, price := range pricing {
+		if price.Model == "" {
+			return fmt.Errorf("pricing[%d]: model is required", i)
+		}
+		if _, err := filepath.Match(price.Model, "test-model"); err != nil {
+			return fmt.Errorf("pricing[%d]: invalid model pattern '%s': %v", i, price.Model, err)
+		}
+		if price.Input < 0 || price.Output < 0 {
+			return fmt.Errorf("pricing[%d]: prices must not be negative", i)
+		}
+		if (price.CacheRead != nil && *price.CacheRead < 0) || (price.CacheWrite != nil && *price.CacheWrite < 0) {
+			return fmt.Errorf("pricing[%d]: prices must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// alertSinkTypes 支持的告警 sink 类型
+var alertSinkTypes = map[string]bool{"webhook": true, "slack": true, "feishu": true, "dingtalk": true, "command": true}
+
+// alertRuleWindows 各类告警规则的默认窗口，不在其中的规则不使用窗口
+var alertRuleWindows = map[string]string{
+	"endpoint_blacklisted": "",
+	"all_endpoints_down":   "",
+	"error_rate":           "5m",
+	"oauth_refresh_failed": "",
+	"spend_cap":            "24h",
+	"rate_limit_rejected":  "",
+}
+
+// validateAlertingConfig 验证告警配置并设置默认值
+func validateAlertingConfig(config *AlertingConfig) error {
+	if config.Cooldown == "" {
+		config.Cooldown = "15m"
+	}
+	if config.EvaluationInterval == "" {
+		config.EvaluationInterval = "1m"
+	}
+	if err := validatePositiveDuration(config.Cooldown, "cooldown"); err != nil {
+		return err
+	}
+	if err := validatePositiveDuration(config.EvaluationInterval, "evaluation_interval"); err != nil {
+		return err
+	}
+
+	sinkNames := make(map[string]bool)
+	for i := range config.Sinks {
+		sink := &config.Sinks[i]
+		if sink.Name == "" {
+			return fmt.Errorf("sinks[%d]: name is required", i)
+		}
+		if sinkNames[sink.Name] {
+			return fmt.Errorf("sinks[%d]: duplicate name '%s'", i, sink.Name)
+		}
+		sinkNames[sink.Name] = true
+
+		if !alertSinkTypes[sink.Type] {
+			return fmt.Errorf("sink '%s': type must be one of webhook, slack, feishu, dingtalk, command, got '%s'", sink.Name, sink.Type)
+		}
+		if sink.Type == "command" {
+			if sink.Command == "" {
+				return fmt.Errorf("sink '%s': command is required", sink.Name)
+			}
+		} else {
+			parsed, err := url.Parse(sink.URL)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("sink '%s': url must be an http(s) URL, got '%s'", sink.Name, sink.URL)
+			}
+		}
+		if sink.Timeout == "" {
+			sink.Timeout = "10s"
+		}
+		if err := validatePositiveDuration(sink.Timeout, fmt.Sprintf("sink '%s' timeout", sink.Name)); err != nil {
+			return err
+		}
+	}
+
+	ruleNames := make(map[string]bool)
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		defaultWindow, known := alertRuleWindows[rule.Type]
+		if !known {
+			return fmt.Errorf("rules[%d]: unknown type '%s'", i, rule.Type)
+		}
+		if rule.Name == "" {
+			rule.Name = rule.Type
+		}
+		if ruleNames[rule.Name] {
+			return fmt.Errorf("rules[%d]: duplicate name '%s'", i, rule.Name)
+		}
+		ruleNames[rule.Name] = true
+
+		if defaultWindow != "" {
+			if rule.Window == "" {
+				rule.Window = defaultWindow
+			}
+			if err := validatePositiveDuration(rule.Window, fmt.Sprintf("rule '%s' window", rule.Name)); err != nil {
+				return err
+			}
+		}
+		if rule.Cooldown != "" {
+			if err := validatePositiveDuration(rule.Cooldown, fmt.Sprintf("rule '%s' cooldown", rule.Name)); err != nil {
+				return err
+			}
+		}
+
+		switch rule.Type {
+		case "error_rate":
+			if rule.Threshold <= 0 || rule.Threshold > 100 {
+				return fmt.Errorf("rule '%s': threshold must be a percentage between 0 and 100", rule.Name)
+			}
+			if rule.MinRequests == 0 {
+				rule.MinRequests = 10
+			}
+		case "spend_cap":
+			if rule.Threshold <= 0 {
+				return fmt.Errorf("rule '%s': threshold must be a positive amount in USD", rule.Name)
+			}
+		case "oauth_refresh_failed":
+			if rule.Failures == 0 {
+				rule.Failures = 3
+			}
+		}
+		if rule.MinRequests < 0 || rule.Failures < 0 {
+			return fmt.Errorf("rule '%s': min_requests and failures must not be negative", rule.Name)
+		}
+
+		for _, name := range rule.Sinks {
+			if !sinkNames[name] {
+				return fmt.Errorf("rule '%s': unknown sink '%s'", rule.Name, name)
+			}
+		}
+	}
+
+	if config.Enabled && len(config.Rules) > 0 && len(config.Sinks) == 0 {
+		return fmt.Errorf("at least one sink is required when rules are configured")
+	}
+	return nil
+}
+
+// ValidateCommandSinksUnchanged 验证新配置没有新增或修改 command 类型的告警 sink
+// command sink 会在本机执行任意程序，只允许在启动时从配置文件加载，热更新和管理 API 不能写入
+func ValidateCommandSinksUnchanged(current, updated AlertingConfig) error {
+	existing := make(map[string]AlertSinkConfig)
+	for _, sink := range current.Sinks {
+		if sink.Type == "command" {
+			existing[sink.Name] = sink
+		}
+	}
+	for _, sink := range updated.Sinks {
+		if sink.Type != "command" {
+			continue
+		}
+		old, ok := existing[sink.Name]
+		if !ok {
+			return fmt.Errorf("alerting sink '%s': command sinks cannot be added via hot update, edit the config file and restart", sink.Name)
+		}
+		if old.Command != sink.Command || !reflect.DeepEqual(old.Args, sink.Args) {
+			return fmt.Errorf("alerting sink '%s': command sinks cannot be changed via hot update, edit the config file and restart", sink.Name)
+		}
+	}
+	return nil
+}
+
+// validatePositiveDuration 验证 duration 字符串大于 0
+func validatePositiveDuration(value, field string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("%s must be a positive duration, got '%s'", field, value)
+	}
+	return nil
+}
+
 // validateProxyConfig 验证单个代理配置
 func validateProxyConfig(config *ProxyConfig, context string) error {
+	return validateProxyChain(config, context, 0)
+}
+
+// maxProxyChainLength 代理链（via）的最大长度
+const maxProxyChainLength = 3
+
+// validateProxyChain 验证代理配置及其 via 代理链
+func validateProxyChain(config *ProxyConfig, context string, depth int) error {
+	if depth >= maxProxyChainLength {
+		return fmt.Errorf("%s: proxy chain is too long (max %d proxies)", context, maxProxyChainLength)
+	}
+
 	if config.Type == "" {
 		return fmt.Errorf("%s: proxy type is required", context)
 	}
+
+	// direct 表示该端点绕过全局代理直连，不需要地址
+	if config.Type == "direct" {
+		if depth > 0 {
+			return fmt.Errorf("%s: type 'direct' cannot be used in a proxy chain", context)
+		}
+		if config.Via != nil {
+			return fmt.Errorf("%s: type 'direct' cannot have a via proxy", context)
+		}
+		return nil
+	}
 	
 	// 验证代理类型
-	validTypes := []string{"http", "socks5"}
+	validTypes := []string{"http", "https", "socks5", "direct"}
 	validType := false
 	for _, vt := range validTypes {
 		if config.Type == vt {
@@ -426,10 +771,91 @@ func validateProxyConfig(config *ProxyConfig, context string) error {
 	if (config.Username != "" && config.Password == "") || (config.Username == "" && config.Password != "") {
 		return fmt.Errorf("%s: proxy username and password must both be provided or both be empty", context)
 	}
+
+	if config.TLS != nil {
+		if config.Type != "https" {
+			return fmt.Errorf("%s: proxy tls is only supported for type 'https'", context)
+		}
+		if err := validateTLSConfig(config.TLS, context); err != nil {
+			return err
+		}
+	}
+
+	if config.Via != nil {
+		return validateProxyChain(config.Via, context+" via", depth+1)
+	}
 	
 	return nil
 }
 
+// validateTLSConfigs 验证端点的 TLS 配置
+func validateTLSConfigs(endpoints []EndpointConfig) error {
+	for i, endpoint := range endpoints {
+		if endpoint.TLS == nil {
+			continue
+		}
+
+		if err := validateTLSConfig(endpoint.TLS, fmt.Sprintf("endpoint[%d] '%s'", i, endpoint.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateTLSConfig 验证单个 TLS 配置（导出函数）
+func ValidateTLSConfig(config *TLSConfig, context string) error {
+	return validateTLSConfig(config, context)
+}
+
+// validateTLSConfig 验证单个 TLS 配置的格式（证书文件在创建客户端时才加载）
+func validateTLSConfig(config *TLSConfig, context string) error {
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return fmt.Errorf("%s: tls cert_file and key_file must both be provided or both be empty", context)
+	}
+
+	if _, err := ParseTLSVersion(config.MinVersion); err != nil {
+		return fmt.Errorf("%s: %v", context, err)
+	}
+
+	for _, pin := range config.PinnedSPKISHA256 {
+		if _, err := DecodeSPKIPin(pin); err != nil {
+			return fmt.Errorf("%s: %v", context, err)
+		}
+	}
+
+	return nil
+}
+
+// ParseTLSVersion 解析 min_version 配置，空字符串返回 0（使用 Go 默认值）
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("invalid tls min_version '%s', must be one of: 1.0, 1.1, 1.2, 1.3", version)
+	}
+}
+
+// DecodeSPKIPin 解析证书固定值（base64 编码的 SPKI SHA-256，可带 "sha256/" 前缀）
+func DecodeSPKIPin(pin string) ([]byte, error) {
+	digest, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(pin), "sha256/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pinned_spki_sha256 '%s': %v", pin, err)
+	}
+	if len(digest) != sha256.Size {
+		return nil, fmt.Errorf("invalid pinned_spki_sha256 '%s': expected %d bytes, got %d", pin, sha256.Size, len(digest))
+	}
+	return digest, nil
+}
+
 // validateServerConfig validates server configuration
 func validateServerConfig(host string, port int) error {
 	if port <= 0 || port > 65535 {
@@ -464,14 +890,126 @@ func validateEndpoint(endpoint EndpointConfig, index int) error {
 		return fmt.Errorf("endpoint %d: url cannot be empty", index)
 	}
 	
-	if endpoint.AuthType != "api_key" && endpoint.AuthType != "auth_token" && endpoint.AuthType != "oauth" {
-		return fmt.Errorf("endpoint %d: invalid auth_type '%s', must be 'api_key', 'auth_token', or 'oauth'", index, endpoint.AuthType)
+	if endpoint.AuthType != "api_key" && endpoint.AuthType != "auth_token" && endpoint.AuthType != "oauth" && endpoint.AuthType != "aws_sigv4" && endpoint.AuthType != "gcp_service_account" && endpoint.AuthType != "entra_id" {
+		return fmt.Errorf("endpoint %d: invalid auth_type '%s', must be 'api_key', 'auth_token', 'oauth', 'aws_sigv4', 'gcp_service_account', or 'entra_id'", index, endpoint.AuthType)
 	}
 	
-	// OAuth 认证不需要 auth_value，其他认证类型需要
-	if endpoint.AuthType != "oauth" && endpoint.AuthValue == "" {
+	// OAuth、SigV4、服务账号与 Entra ID 认证不需要 auth_value，其他认证类型需要（本地 Ollama 通常不需要认证）
+	if endpoint.AuthType != "oauth" && endpoint.AuthType != "aws_sigv4" && endpoint.AuthType != "gcp_service_account" && endpoint.AuthType != "entra_id" && endpoint.EndpointType != "ollama" && endpoint.AuthValue == "" {
 		return fmt.Errorf("endpoint %d: auth_value cannot be empty for non-oauth authentication", index)
 	}
+
+	// Bedrock 端点只能使用 SigV4 签名，SigV4 也只用于 Bedrock 端点
+	if (endpoint.EndpointType == "bedrock") != (endpoint.AuthType == "aws_sigv4") {
+		return fmt.Errorf("endpoint %d: bedrock endpoints require auth_type 'aws_sigv4', which is only supported by bedrock endpoints", index)
+	}
+
+	// 服务账号认证只用于 Vertex AI 端点
+	if endpoint.AuthType == "gcp_service_account" && endpoint.EndpointType != "vertex-anthropic" {
+		return fmt.Errorf("endpoint %d: auth_type 'gcp_service_account' is only supported by vertex-anthropic endpoints", index)
+	}
+
+	// Entra ID 客户端凭证只用于 Azure OpenAI 端点
+	if endpoint.AuthType == "entra_id" {
+		if endpoint.EndpointType != "azure-openai" {
+			return fmt.Errorf("endpoint %d: auth_type 'entra_id' is only supported by azure-openai endpoints", index)
+		}
+		if endpoint.AzureConfig == nil || endpoint.AzureConfig.TenantID == "" || endpoint.AzureConfig.ClientID == "" || endpoint.AzureConfig.ClientSecret == "" {
+			return fmt.Errorf("endpoint %d: auth_type 'entra_id' requires azure_config.tenant_id, client_id and client_secret", index)
+		}
+	}
+
+	switch endpoint.EndpointType {
+	case "", "anthropic", "openai", "gemini", "bedrock", "azure-openai", "ollama":
+	case "vertex-anthropic":
+		// Vertex AI 端点可使用服务账号换取令牌，或直接使用 auth_token（如 gcloud auth print-access-token）
+		if endpoint.AuthType != "gcp_service_account" && endpoint.AuthType != "auth_token" {
+			return fmt.Errorf("endpoint %d: vertex-anthropic endpoints require auth_type 'gcp_service_account' or 'auth_token'", index)
+		}
+		if endpoint.VertexConfig == nil || endpoint.VertexConfig.ProjectID == "" || endpoint.VertexConfig.Region == "" {
+			return fmt.Errorf("endpoint %d: vertex-anthropic endpoints require vertex_config.project_id and vertex_config.region", index)
+		}
+	default:
+		return fmt.Errorf("endpoint %d: invalid endpoint_type '%s', must be 'anthropic', 'openai', 'gemini', 'bedrock', 'vertex-anthropic', 'azure-openai', or 'ollama'", index, endpoint.EndpointType)
+	}
+
+	switch endpoint.OpenAIAPI {
+	case "", "chat_completions":
+	case "responses":
+		if endpoint.EndpointType != "openai" {
+			return fmt.Errorf("endpoint %d: openai_api 'responses' is only supported for openai endpoints", index)
+		}
+	default:
+		return fmt.Errorf("endpoint %d: invalid openai_api '%s', must be 'chat_completions' or 'responses'", index, endpoint.OpenAIAPI)
+	}
+
+	switch endpoint.ToolSchemaProfile {
+	case "", "lenient", "openai_strict", "gemini", "none":
+	default:
+		return fmt.Errorf("endpoint %d: invalid tool_schema_profile '%s', must be 'lenient', 'openai_strict', 'gemini', or 'none'", index, endpoint.ToolSchemaProfile)
+	}
+
+	switch endpoint.ToolArgumentValidation {
+	case "", "repair", "retry", "failover", "off":
+	default:
+		return fmt.Errorf("endpoint %d: invalid tool_argument_validation '%s', must be 'repair', 'retry', 'failover', or 'off'", index, endpoint.ToolArgumentValidation)
+	}
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// validateLogRetentionConfig 填充保留策略默认值并校验
+func validateLogRetentionConfig(config *LogRetentionConfig) error {
+	if config.Interval == "" {
+		config.Interval = "1h"
+	}
+	interval, err := time.ParseDuration(config.Interval)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("interval must be a positive duration, got '%s'", config.Interval)
+	}
+
+	rules := map[string]*LogRetentionRule{"success": &config.Success, "failed": &config.Failed}
+	for name, rule := range rules {
+		if rule.MaxAge == "" {
+			rule.MaxAge = "720h"
+		}
+		if maxAge, err := time.ParseDuration(rule.MaxAge); err != nil || maxAge < 0 {
+			return fmt.Errorf("%s.max_age must be a non-negative duration, got '%s'", name, rule.MaxAge)
+		}
+		if rule.MaxRows < 0 {
+			return fmt.Errorf("%s.max_rows cannot be negative", name)
+		}
+		if rule.MaxSizeMB < 0 {
+			return fmt.Errorf("%s.max_size_mb cannot be negative", name)
+		}
+	}
+	return nil
+}
+
+// validateLogSamplingRules 校验采样规则
+func validateLogSamplingRules(rules []LogSamplingRule) error {
+	for i, rule := range rules {
+		if rule.Endpoint == "" && rule.ClientType == "" && rule.Tag == "" {
+			return fmt.Errorf("rule %d: at least one of endpoint, client_type or tag is required", i)
+		}
+		if rule.Rate < 0 || rule.Rate > 1 {
+			return fmt.Errorf("rule %d: rate must be between 0 and 1, got %v", i, rule.Rate)
+		}
+	}
+	return nil
+}
+
+// validateLogRedactionConfig 校验脱敏规则中的正则
+func validateLogRedactionConfig(config *LogRedactionConfig) error {
+	for _, header := range config.Headers {
+		if strings.TrimSpace(header) == "" {
+			return fmt.Errorf("header name cannot be empty")
+		}
+	}
+	for _, pattern := range config.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %v", pattern, err)
+		}
+	}
+	return nil
+}
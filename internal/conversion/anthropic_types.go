@@ -58,6 +58,10 @@ type AnthropicContentBlock struct {
 	Content   interface{}        `json:"content,omitempty"`
 	IsError   *bool              `json:"is_error,omitempty"`
 
+	// thinking（由 assistant 发出）
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+
 	// 用于流式事件的增量字段
 	PartialJSON string `json:"partial_json,omitempty"` // 用于 input_json_delta
 }
@@ -80,6 +84,8 @@ type AnthropicTool struct {
 type AnthropicToolChoice struct {
 	Type string `json:"type"`           // "auto"|"any"|"tool"
 	Name string `json:"name,omitempty"` // 当 Type=="tool" 时指定工具名
+
+	DisableParallelToolUse *bool `json:"disable_parallel_tool_use,omitempty"` // 新版写法：禁用并行工具调用
 }
 
 // AnthropicResponse Anthropic 响应（精简）
@@ -115,8 +121,9 @@ type AnthropicMessageStart struct {
 // AnthropicContentBlockForStart 专门用于 content_block_start 事件的结构体
 // 确保 text 字段始终被序列化，即使为空
 type AnthropicContentBlockForStart struct {
-	Type string `json:"type"` // "text" | "tool_use"
-	Text string `json:"-"`    // 使用自定义序列化
+	Type     string `json:"type"` // "text" | "thinking" | "tool_use"
+	Text     string `json:"-"`    // 使用自定义序列化
+	Thinking string `json:"-"`    // 使用自定义序列化
 
 	// tool_use 字段（当 Type 为 "tool_use" 时使用）
 	ID    string          `json:"id,omitempty"`
@@ -125,11 +132,14 @@ type AnthropicContentBlockForStart struct {
 }
 
 // MarshalJSON 自定义 JSON 序列化
-// 对于 "text" 类型，始终包含 text 字段；对于 "tool_use" 类型，省略 text 字段
+// 对于 "text" 类型，始终包含 text 字段；对于 "thinking" 类型，始终包含 thinking 与 signature 字段；
+// 对于 "tool_use" 类型，省略这些字段
 func (c AnthropicContentBlockForStart) MarshalJSON() ([]byte, error) {
 	type Alias AnthropicContentBlockForStart
 	aux := &struct {
-		Text *string `json:"text,omitempty"`
+		Text      *string `json:"text,omitempty"`
+		Thinking  *string `json:"thinking,omitempty"`
+		Signature *string `json:"signature,omitempty"`
 		*Alias
 	}{
 		Alias: (*Alias)(&c),
@@ -139,6 +149,11 @@ func (c AnthropicContentBlockForStart) MarshalJSON() ([]byte, error) {
 	if c.Type == "text" {
 		aux.Text = &c.Text
 	}
+	if c.Type == "thinking" {
+		signature := ""
+		aux.Thinking = &c.Thinking
+		aux.Signature = &signature
+	}
 	
 	return json.Marshal(aux)
 }
@@ -0,0 +1,82 @@
+package conversion
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+// AzureOpenAIDefaultAPIVersion 未配置 api_version 时使用的版本（同时支持 Chat Completions 与 Responses API）
+const AzureOpenAIDefaultAPIVersion = "2025-04-01-preview"
+
+// AzureOpenAIAdapter 将 OpenAI 请求转换为 Azure OpenAI 部署风格的请求
+// Chat Completions 发送到 /openai/deployments/{deployment}/chat/completions，
+// Responses API（原生 Codex 格式）发送到 /openai/responses，部署名放在 model 字段中；
+// 两者都附加 api-version 查询参数，响应格式与 OpenAI 一致
+type AzureOpenAIAdapter struct {
+	logger      *logger.Logger
+	apiVersion  string
+	deployments map[string]string
+}
+
+// NewAzureOpenAIAdapter 创建 Azure OpenAI 上游适配器
+func NewAzureOpenAIAdapter(endpointInfo *EndpointInfo, logger *logger.Logger) *AzureOpenAIAdapter {
+	apiVersion := endpointInfo.AzureAPIVersion
+	if apiVersion == "" {
+		apiVersion = AzureOpenAIDefaultAPIVersion
+	}
+	return &AzureOpenAIAdapter{
+		logger:      logger,
+		apiVersion:  apiVersion,
+		deployments: endpointInfo.AzureDeployments,
+	}
+}
+
+// AdaptRequest 将模型名映射为部署名，并根据请求体格式选择 Chat Completions 或 Responses 路径
+func (a *AzureOpenAIAdapter) AdaptRequest(body []byte) (*UpstreamRequest, error) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse request for Azure OpenAI conversion", err)
+	}
+
+	model, _ := req["model"].(string)
+	deployment := model
+	if mapped, ok := a.deployments[model]; ok && mapped != "" {
+		deployment = mapped
+	}
+	if deployment == "" {
+		return nil, NewConversionError("unsupported_feature", "Azure OpenAI request requires a model or deployment", nil)
+	}
+	streaming, _ := req["stream"].(bool)
+
+	// Responses API 请求使用 input 字段，Chat Completions 使用 messages
+	_, hasMessages := req["messages"]
+	_, hasInput := req["input"]
+	isResponses := hasInput && !hasMessages
+
+	var path string
+	if isResponses {
+		path = "/openai/responses"
+	} else {
+		path = "/openai/deployments/" + url.PathEscape(deployment) + "/chat/completions"
+	}
+	req["model"] = deployment
+
+	converted, err := json.Marshal(req)
+	if err != nil {
+		return nil, NewConversionError("marshal_error", "Failed to marshal Azure OpenAI request", err)
+	}
+
+	return &UpstreamRequest{
+		Path:      path,
+		Query:     url.Values{"api-version": []string{a.apiVersion}},
+		Body:      converted,
+		Streaming: streaming,
+	}, nil
+}
+
+// AdaptResponse Azure OpenAI 的响应与 OpenAI 格式一致，原样返回
+func (a *AzureOpenAIAdapter) AdaptResponse(body []byte, isStreaming bool) ([]byte, error) {
+	return body, nil
+}
@@ -0,0 +1,78 @@
+package conversion
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAzureOpenAIAdapter_AdaptRequest(t *testing.T) {
+	adapter := NewUpstreamAdapter(&EndpointInfo{
+		Type:             "azure-openai",
+		AzureDeployments: map[string]string{"gpt-4o": "prod-gpt4o", "gpt-5": "codex-gpt5"},
+	}, getTestLogger())
+
+	testCases := []struct {
+		name               string
+		request            string
+		expectedPath       string
+		expectedDeployment string
+		streaming          bool
+	}{
+		{
+			name:               "chat completions with mapped deployment",
+			request:            `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+			expectedPath:       "/openai/deployments/prod-gpt4o/chat/completions",
+			expectedDeployment: "prod-gpt4o",
+			streaming:          true,
+		},
+		{
+			name:               "chat completions with unmapped model",
+			request:            `{"model":"gpt-4.1-mini","messages":[{"role":"user","content":"hi"}]}`,
+			expectedPath:       "/openai/deployments/gpt-4.1-mini/chat/completions",
+			expectedDeployment: "gpt-4.1-mini",
+		},
+		{
+			name:               "native responses request",
+			request:            `{"model":"gpt-5","stream":true,"input":[{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"}]}]}`,
+			expectedPath:       "/openai/responses",
+			expectedDeployment: "codex-gpt5",
+			streaming:          true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			upstream, err := adapter.AdaptRequest([]byte(tc.request))
+			if err != nil {
+				t.Fatalf("AdaptRequest failed: %v", err)
+			}
+			if upstream.Path != tc.expectedPath {
+				t.Errorf("Expected path %s, got %s", tc.expectedPath, upstream.Path)
+			}
+			if upstream.Streaming != tc.streaming {
+				t.Errorf("Expected streaming=%v, got %v", tc.streaming, upstream.Streaming)
+			}
+			if got := upstream.Query.Get("api-version"); got != AzureOpenAIDefaultAPIVersion {
+				t.Errorf("Expected default api-version %s, got %s", AzureOpenAIDefaultAPIVersion, got)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(upstream.Body, &body); err != nil {
+				t.Fatalf("Failed to unmarshal Azure request: %v", err)
+			}
+			if body["model"] != tc.expectedDeployment {
+				t.Errorf("Expected model to be deployment %s, got %v", tc.expectedDeployment, body["model"])
+			}
+		})
+	}
+
+	// 配置的 api_version 优先
+	versioned := NewUpstreamAdapter(&EndpointInfo{Type: "azure-openai", AzureAPIVersion: "2024-10-21"}, getTestLogger())
+	upstream, err := versioned.AdaptRequest([]byte(`{"model":"gpt-4o","messages":[]}`))
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+	if upstream.Query.Encode() != "api-version=2024-10-21" {
+		t.Errorf("Unexpected query: %s", upstream.Query.Encode())
+	}
+}
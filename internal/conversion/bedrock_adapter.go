@@ -0,0 +1,125 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"claude-code-codex-companion/internal/aws"
+	"claude-code-codex-companion/internal/logger"
+)
+
+// BedrockAnthropicVersion Bedrock 上 Anthropic Messages API 的固定版本号
+const BedrockAnthropicVersion = "bedrock-2023-05-31"
+
+// BedrockAdapter 将 Anthropic Messages 请求转换为 Bedrock InvokeModel / InvokeModelWithResponseStream
+// 请求体基本不变：模型 ID 移到路径中（经过 model_rewrite 后的模型名即 Bedrock 模型 ID），
+// 流式响应的 event-stream 二进制帧解码回 Anthropic SSE
+type BedrockAdapter struct {
+	logger *logger.Logger
+}
+
+// NewBedrockAdapter 创建 Bedrock 上游适配器
+func NewBedrockAdapter(logger *logger.Logger) *BedrockAdapter {
+	return &BedrockAdapter{logger: logger}
+}
+
+// AdaptRequest 移除 model/stream 字段，设置 anthropic_version，并生成 invoke 路径
+func (a *BedrockAdapter) AdaptRequest(body []byte) (*UpstreamRequest, error) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse request for Bedrock conversion", err)
+	}
+
+	modelID, _ := req["model"].(string)
+	if modelID == "" {
+		return nil, NewConversionError("unsupported_feature", "Bedrock request requires a model", nil)
+	}
+	streaming, _ := req["stream"].(bool)
+
+	delete(req, "model")
+	delete(req, "stream")
+	if _, ok := req["anthropic_version"]; !ok {
+		req["anthropic_version"] = BedrockAnthropicVersion
+	}
+
+	converted, err := json.Marshal(req)
+	if err != nil {
+		return nil, NewConversionError("marshal_error", "Failed to marshal Bedrock request", err)
+	}
+
+	// 模型 ID 可能包含 ":"（版本号）或是推理配置文件 ARN，需要作为单个路径段转义
+	path := "/model/" + strings.ReplaceAll(url.PathEscape(modelID), ":", "%3A") + "/invoke"
+	if streaming {
+		path += "-with-response-stream"
+	}
+
+	return &UpstreamRequest{
+		Path:      path,
+		Query:     url.Values{},
+		Body:      converted,
+		Streaming: streaming,
+	}, nil
+}
+
+// AdaptResponse 非流式响应本身就是 Anthropic 消息；流式响应解码 event-stream 后输出 Anthropic SSE
+func (a *BedrockAdapter) AdaptResponse(body []byte, isStreaming bool) ([]byte, error) {
+	if !isStreaming {
+		return body, nil
+	}
+
+	messages, err := aws.DecodeEventStream(body)
+	if err != nil && len(messages) == 0 {
+		return nil, NewConversionError("sse_parse_error", "Failed to decode Bedrock event stream", err)
+	}
+	if err != nil && a.logger != nil {
+		// 截断的流保留已解码部分，由响应校验判断完整性
+		a.logger.Debug(fmt.Sprintf("Bedrock event stream decoding stopped early: %v", err))
+	}
+
+	var out bytes.Buffer
+	for _, message := range messages {
+		if messageType := message.Headers[":message-type"]; messageType == "exception" || messageType == "error" {
+			exceptionType := message.Headers[":exception-type"]
+			if exceptionType == "" {
+				exceptionType = message.Headers[":error-code"]
+			}
+			return nil, NewConversionError("upstream_error", fmt.Sprintf("Bedrock stream %s: %s", exceptionType, string(message.Payload)), nil)
+		}
+		if message.Headers[":event-type"] != "chunk" {
+			continue
+		}
+
+		var chunk struct {
+			Bytes string `json:"bytes"`
+		}
+		if err := json.Unmarshal(message.Payload, &chunk); err != nil {
+			return nil, NewConversionError("sse_parse_error", "Failed to parse Bedrock chunk payload", err)
+		}
+		event, err := base64.StdEncoding.DecodeString(chunk.Bytes)
+		if err != nil {
+			return nil, NewConversionError("sse_parse_error", "Failed to decode Bedrock chunk bytes", err)
+		}
+
+		var eventType struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(event, &eventType); err != nil || eventType.Type == "" {
+			return nil, NewConversionError("sse_parse_error", "Bedrock chunk is not an Anthropic stream event", err)
+		}
+
+		out.WriteString("event: ")
+		out.WriteString(eventType.Type)
+		out.WriteString("\ndata: ")
+		out.Write(event)
+		out.WriteString("\n\n")
+	}
+
+	if out.Len() == 0 {
+		return nil, NewConversionError("empty_stream", "No chunk events found in Bedrock event stream", nil)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,129 @@
+package conversion
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"claude-code-codex-companion/internal/aws"
+)
+
+func bedrockChunk(event string) []byte {
+	payload, _ := json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString([]byte(event))})
+	return aws.EncodeEventStreamMessage(map[string]string{
+		":message-type": "event",
+		":event-type":   "chunk",
+		":content-type": "application/json",
+	}, payload)
+}
+
+func TestBedrockAdapter_AdaptRequest(t *testing.T) {
+	adapter := NewBedrockAdapter(getTestLogger())
+
+	testCases := []struct {
+		name         string
+		request      string
+		expectedPath string
+		streaming    bool
+	}{
+		{
+			name:         "streaming with versioned model id",
+			request:      `{"model":"anthropic.claude-3-5-sonnet-20241022-v2:0","max_tokens":100,"stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+			expectedPath: "/model/anthropic.claude-3-5-sonnet-20241022-v2%3A0/invoke-with-response-stream",
+			streaming:    true,
+		},
+		{
+			name:         "non-streaming with inference profile arn",
+			request:      `{"model":"arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-sonnet-4-20250514-v1:0","max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`,
+			expectedPath: "/model/arn%3Aaws%3Abedrock%3Aus-east-1%3A123456789012%3Ainference-profile%2Fus.anthropic.claude-sonnet-4-20250514-v1%3A0/invoke",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			upstream, err := adapter.AdaptRequest([]byte(tc.request))
+			if err != nil {
+				t.Fatalf("AdaptRequest failed: %v", err)
+			}
+			if upstream.Path != tc.expectedPath {
+				t.Errorf("Expected path %s, got %s", tc.expectedPath, upstream.Path)
+			}
+			if upstream.Streaming != tc.streaming {
+				t.Errorf("Expected streaming=%v, got %v", tc.streaming, upstream.Streaming)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(upstream.Body, &body); err != nil {
+				t.Fatalf("Failed to unmarshal Bedrock request: %v", err)
+			}
+			if _, ok := body["model"]; ok {
+				t.Error("Expected model to be removed from the body")
+			}
+			if _, ok := body["stream"]; ok {
+				t.Error("Expected stream to be removed from the body")
+			}
+			if body["anthropic_version"] != BedrockAnthropicVersion {
+				t.Errorf("Expected anthropic_version %s, got %v", BedrockAnthropicVersion, body["anthropic_version"])
+			}
+		})
+	}
+}
+
+func TestBedrockAdapter_StreamingResponse(t *testing.T) {
+	adapter := NewBedrockAdapter(getTestLogger())
+
+	var stream []byte
+	for _, event := range []string{
+		`{"type":"message_start","message":{"id":"msg_bdrk_01","type":"message","role":"assistant","model":"claude-3-5-sonnet-20241022","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+		`{"type":"message_stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":10,"outputTokenCount":5}}`,
+	} {
+		stream = append(stream, bedrockChunk(event)...)
+	}
+
+	result, err := adapter.AdaptResponse(stream, true)
+	if err != nil {
+		t.Fatalf("AdaptResponse failed: %v", err)
+	}
+
+	sse := string(result)
+	for _, expected := range []string{
+		"event: message_start\ndata: {\"type\":\"message_start\"",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n",
+		"event: message_stop\n",
+	} {
+		if !strings.Contains(sse, expected) {
+			t.Errorf("Expected %q in SSE:\n%s", expected, sse)
+		}
+	}
+	if strings.Count(sse, "event: ") != 6 {
+		t.Errorf("Expected 6 SSE events, got:\n%s", sse)
+	}
+
+	// 非流式响应原样返回
+	body := []byte(`{"id":"msg_bdrk_01","type":"message","content":[]}`)
+	if passthrough, err := adapter.AdaptResponse(body, false); err != nil || string(passthrough) != string(body) {
+		t.Errorf("Expected non-streaming response to pass through, got %s (%v)", string(passthrough), err)
+	}
+}
+
+func TestBedrockAdapter_StreamException(t *testing.T) {
+	adapter := NewBedrockAdapter(getTestLogger())
+
+	stream := append(
+		bedrockChunk(`{"type":"message_start","message":{"id":"msg_bdrk_01","type":"message","role":"assistant","content":[]}}`),
+		aws.EncodeEventStreamMessage(map[string]string{
+			":message-type":   "exception",
+			":exception-type": "throttlingException",
+		}, []byte(`{"message":"Too many requests"}`))...,
+	)
+
+	_, err := adapter.AdaptResponse(stream, true)
+	if err == nil || !strings.Contains(err.Error(), "throttlingException") {
+		t.Errorf("Expected throttling exception error, got %v", err)
+	}
+}
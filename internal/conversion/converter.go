@@ -1,6 +1,7 @@
 package conversion
 
 import (
+	"claude-code-codex-companion/internal/config"
 	"claude-code-codex-companion/internal/logger"
 )
 
@@ -20,9 +21,9 @@ func NewConverter(logger *logger.Logger) Converter {
 	}
 }
 
-// ShouldConvert 检查是否需要转换
+// ShouldConvert 检查是否需要转换（gemini 等原生协议端点先转换为 OpenAI 格式）
 func (c *DefaultConverter) ShouldConvert(endpointType string) bool {
-	return endpointType == "openai"
+	return config.GetEndpointFormat(endpointType) == "openai"
 }
 
 // ConvertRequest 转换请求
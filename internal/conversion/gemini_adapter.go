@@ -0,0 +1,567 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+// Gemini 推理强度对应的默认思考预算（请求中只有 reasoning_effort 时使用）
+var geminiThinkingBudgets = map[string]int{
+	"low":    1024,
+	"medium": 8192,
+	"high":   24576,
+}
+
+// geminiThoughtSignatureSeparator 分隔工具调用 ID 与编码后的思考签名
+// 签名以 base64url 编码附加在 ID 之后，随客户端的 tool_use 原样回传，无需在代理中保存状态
+const geminiThoughtSignatureSeparator = "__thought__"
+
+// GeminiAdapter 在 OpenAI Chat Completions 与 Gemini generateContent 之间转换
+type GeminiAdapter struct {
+	logger         *logger.Logger
+	schemaProfile  string
+	safetySettings map[string]string
+
+	model string // 请求的模型名，用于拼接路径与填充响应
+}
+
+// NewGeminiAdapter 创建 Gemini 上游适配器
+func NewGeminiAdapter(endpointInfo *EndpointInfo, logger *logger.Logger) *GeminiAdapter {
+	// 未显式配置时按 Gemini 支持的 Schema 子集规范化工具参数
+	schemaProfile := endpointInfo.ToolSchemaProfile
+	if schemaProfile == "" {
+		schemaProfile = ToolSchemaProfileGemini
+	}
+	return &GeminiAdapter{
+		logger:         logger,
+		schemaProfile:  schemaProfile,
+		safetySettings: endpointInfo.GeminiSafetySettings,
+	}
+}
+
+// AdaptRequest 将 Chat Completions 请求转换为 Gemini generateContent 请求
+func (a *GeminiAdapter) AdaptRequest(body []byte) (*UpstreamRequest, error) {
+	var chatReq compatChatRequest
+	if err := json.Unmarshal(body, &chatReq); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse request for Gemini conversion", err)
+	}
+
+	a.model = strings.TrimPrefix(chatReq.Model, "models/")
+	if a.model == "" {
+		return nil, NewConversionError("unsupported_feature", "Gemini request requires a model", nil)
+	}
+
+	out := GeminiRequest{}
+
+	// 消息映射：system -> systemInstruction，assistant -> model，tool -> functionResponse
+	toolNameByID := map[string]string{}
+	var systemParts []GeminiPart
+	for _, m := range chatReq.Messages {
+		switch m.Role {
+		case "system", "developer":
+			if text := chatContentText(m.Content); text != "" {
+				systemParts = append(systemParts, GeminiPart{Text: text})
+			}
+		case "assistant":
+			var parts []GeminiPart
+			if text := chatContentText(m.Content); text != "" {
+				parts = append(parts, GeminiPart{Text: text})
+			}
+			for _, tc := range m.ToolCalls {
+				toolNameByID[tc.ID] = tc.Function.Name
+				parts = append(parts, GeminiPart{
+					FunctionCall: &GeminiFunctionCall{
+						Name: tc.Function.Name,
+						Args: parseFunctionArguments(tc.Function.Arguments),
+					},
+					ThoughtSignature: thoughtSignatureFromToolCallID(tc.ID),
+				})
+			}
+			out.Contents = appendGeminiContent(out.Contents, "model", parts)
+		case "tool":
+			name := toolNameByID[m.ToolCallID]
+			if name == "" {
+				name = m.Name
+			}
+			out.Contents = appendGeminiContent(out.Contents, "user", []GeminiPart{{
+				FunctionResponse: &GeminiFunctionResponse{
+					Name:     name,
+					Response: functionResponsePayload(chatContentText(m.Content)),
+				},
+			}})
+		default:
+			out.Contents = appendGeminiContent(out.Contents, "user", chatContentParts(m.Content))
+		}
+	}
+	if len(systemParts) > 0 {
+		out.SystemInstruction = &GeminiContent{Parts: systemParts}
+	}
+	if len(out.Contents) == 0 {
+		out.Contents = []GeminiContent{{Role: "user", Parts: []GeminiPart{{Text: "Hello"}}}}
+	}
+
+	// 工具声明
+	var declarations []GeminiFunctionDeclaration
+	for _, t := range chatReq.Tools {
+		decl := GeminiFunctionDeclaration{Name: t.Name, Description: t.Description, Parameters: t.Parameters}
+		if t.Function != nil {
+			decl = GeminiFunctionDeclaration{Name: t.Function.Name, Description: t.Function.Description, Parameters: t.Function.Parameters}
+		}
+		if decl.Name == "" {
+			continue // 非函数工具（如 web_search）Gemini 无法声明
+		}
+		if decl.Parameters != nil {
+			decl.Parameters = SanitizeToolSchema(decl.Parameters, a.schemaProfile)
+		}
+		declarations = append(declarations, decl)
+	}
+	if len(declarations) > 0 {
+		out.Tools = []GeminiTool{{FunctionDeclarations: declarations}}
+		out.ToolConfig = geminiToolConfig(chatReq.ToolChoice)
+	}
+
+	// 生成参数
+	gen := &GeminiGenerationConfig{
+		Temperature:   chatReq.Temperature,
+		TopP:          chatReq.TopP,
+		TopK:          chatReq.TopK,
+		StopSequences: stopSequencesFromChat(chatReq.Stop),
+	}
+	for _, maxTokens := range []*int{chatReq.MaxOutputTokens, chatReq.MaxCompletionTokens, chatReq.MaxTokens} {
+		if maxTokens != nil {
+			gen.MaxOutputTokens = maxTokens
+			break
+		}
+	}
+
+	// 思考预算：优先使用 max_reasoning_tokens（由 Anthropic thinking.budget_tokens 转换而来）
+	effort := ""
+	if chatReq.ReasoningEffort != nil {
+		effort = *chatReq.ReasoningEffort
+	} else if chatReq.Reasoning != nil {
+		effort = chatReq.Reasoning.Effort
+	}
+	if chatReq.MaxReasoningTokens != nil {
+		gen.ThinkingConfig = &GeminiThinkingConfig{ThinkingBudget: chatReq.MaxReasoningTokens}
+	} else if budget, ok := geminiThinkingBudgets[effort]; ok {
+		gen.ThinkingConfig = &GeminiThinkingConfig{ThinkingBudget: &budget}
+	}
+	if gen.Temperature != nil || gen.TopP != nil || gen.TopK != nil || gen.MaxOutputTokens != nil ||
+		len(gen.StopSequences) > 0 || gen.ThinkingConfig != nil {
+		out.GenerationConfig = gen
+	}
+
+	// 安全设置按类别排序，保证请求体稳定
+	categories := make([]string, 0, len(a.safetySettings))
+	for category := range a.safetySettings {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		out.SafetySettings = append(out.SafetySettings, GeminiSafetySetting{Category: category, Threshold: a.safetySettings[category]})
+	}
+
+	converted, err := json.Marshal(out)
+	if err != nil {
+		return nil, NewConversionError("marshal_error", "Failed to marshal Gemini request", err)
+	}
+
+	streaming := chatReq.Stream != nil && *chatReq.Stream
+	req := &UpstreamRequest{
+		Path:      "/models/" + a.model + ":generateContent",
+		Query:     url.Values{},
+		Body:      converted,
+		Streaming: streaming,
+	}
+	if streaming {
+		req.Path = "/models/" + a.model + ":streamGenerateContent"
+		req.Query.Set("alt", "sse")
+	}
+	return req, nil
+}
+
+// AdaptResponse 将 Gemini 响应转换为 Chat Completions 响应（流式时为 chat.completion.chunk SSE）
+func (a *GeminiAdapter) AdaptResponse(body []byte, isStreaming bool) ([]byte, error) {
+	if !isStreaming {
+		var resp GeminiResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, NewConversionError("parse_error", "Failed to parse Gemini response", err)
+		}
+		return a.convertResponse(&resp)
+	}
+	return a.convertStream(body)
+}
+
+func (a *GeminiAdapter) convertResponse(resp *GeminiResponse) ([]byte, error) {
+	message := map[string]interface{}{"role": "assistant", "content": ""}
+	finishReason := "stop"
+
+	var text strings.Builder
+	var toolCalls []OpenAIToolCall
+	if len(resp.Candidates) > 0 {
+		candidate := resp.Candidates[0]
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.Thought:
+				// 思考内容不作为回复文本返回
+			case part.FunctionCall != nil:
+				toolCalls = append(toolCalls, geminiFunctionCallToToolCall(part, len(toolCalls)))
+			case part.Text != "":
+				text.WriteString(part.Text)
+			}
+		}
+		finishReason = mapGeminiFinishReason(candidate.FinishReason, len(toolCalls) > 0)
+	} else if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		finishReason = "content_filter"
+	}
+
+	message["content"] = text.String()
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+
+	out := map[string]interface{}{
+		"id":      a.responseID(resp),
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   a.responseModel(resp),
+		"choices": []interface{}{map[string]interface{}{
+			"index":         0,
+			"message":       message,
+			"finish_reason": finishReason,
+		}},
+	}
+	if usage := geminiUsageToOpenAI(resp.UsageMetadata); usage != nil {
+		out["usage"] = usage
+	}
+	return json.Marshal(out)
+}
+
+func (a *GeminiAdapter) convertStream(body []byte) ([]byte, error) {
+	var out bytes.Buffer
+	var id, model string
+	var usage *OpenAIUsage
+	finishReason := ""
+	toolCallCount := 0
+	created := time.Now().Unix()
+	started := false
+
+	writeChunk := func(delta map[string]interface{}, finish interface{}, usage *OpenAIUsage) {
+		chunk := map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []interface{}{map[string]interface{}{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finish,
+			}},
+		}
+		if usage != nil {
+			chunk["usage"] = usage
+		}
+		data, _ := json.Marshal(chunk)
+		out.WriteString("data: ")
+		out.Write(data)
+		out.WriteString("\n\n")
+	}
+
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		var resp GeminiResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			return nil, NewConversionError("sse_parse_error", "Failed to parse Gemini SSE event", err)
+		}
+
+		if !started {
+			started = true
+			id = a.responseID(&resp)
+			model = a.responseModel(&resp)
+			writeChunk(map[string]interface{}{"role": "assistant", "content": ""}, nil, nil)
+		}
+
+		// usageMetadata 在每个事件中都是累计值，只保留最后一个
+		if u := geminiUsageToOpenAI(resp.UsageMetadata); u != nil {
+			usage = u
+		}
+
+		if len(resp.Candidates) == 0 {
+			if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+				finishReason = "content_filter"
+			}
+			continue
+		}
+
+		candidate := resp.Candidates[0]
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.Thought:
+			case part.FunctionCall != nil:
+				toolCall := geminiFunctionCallToToolCall(part, toolCallCount)
+				toolCallCount++
+				writeChunk(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+					"index":    toolCall.Index,
+					"id":       toolCall.ID,
+					"type":     "function",
+					"function": map[string]interface{}{"name": toolCall.Function.Name, "arguments": toolCall.Function.Arguments},
+				}}}, nil, nil)
+			case part.Text != "":
+				writeChunk(map[string]interface{}{"content": part.Text}, nil, nil)
+			}
+		}
+		if candidate.FinishReason != "" {
+			finishReason = mapGeminiFinishReason(candidate.FinishReason, toolCallCount > 0)
+		}
+	}
+
+	if !started {
+		return nil, NewConversionError("empty_stream", "No valid events found in Gemini SSE stream", nil)
+	}
+
+	// 没有 finishReason 说明流被截断，不补 finish_reason/[DONE]，交给响应校验触发重试
+	if finishReason != "" {
+		writeChunk(map[string]interface{}{}, finishReason, usage)
+		out.WriteString("data: [DONE]\n\n")
+	} else if a.logger != nil {
+		a.logger.Debug("Gemini SSE stream ended without finishReason")
+	}
+
+	return out.Bytes(), nil
+}
+
+func (a *GeminiAdapter) responseID(resp *GeminiResponse) string {
+	if resp.ResponseID != "" {
+		return "chatcmpl-" + resp.ResponseID
+	}
+	return "chatcmpl-" + strings.TrimPrefix(newToolCallID(), "call_")
+}
+
+func (a *GeminiAdapter) responseModel(resp *GeminiResponse) string {
+	if a.model != "" {
+		return a.model
+	}
+	return resp.ModelVersion
+}
+
+// appendGeminiContent 追加一轮内容，相邻的同角色内容合并（Gemini 要求 user/model 交替）
+func appendGeminiContent(contents []GeminiContent, role string, parts []GeminiPart) []GeminiContent {
+	if len(parts) == 0 {
+		return contents
+	}
+	if n := len(contents); n > 0 && contents[n-1].Role == role {
+		contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+		return contents
+	}
+	return append(contents, GeminiContent{Role: role, Parts: parts})
+}
+
+// chatContentText 提取消息中的纯文本（string 或 text 片段数组）
+func chatContentText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var sb strings.Builder
+		for _, item := range v {
+			if part, ok := item.(map[string]interface{}); ok {
+				if text, ok := part["text"].(string); ok {
+					sb.WriteString(text)
+				}
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
+
+// chatContentParts 将用户消息内容转换为 Gemini parts，图片转换为 inlineData / fileData
+func chatContentParts(content interface{}) []GeminiPart {
+	items, ok := content.([]interface{})
+	if !ok {
+		if text := chatContentText(content); text != "" {
+			return []GeminiPart{{Text: text}}
+		}
+		return nil
+	}
+
+	var parts []GeminiPart
+	for _, item := range items {
+		part, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if text, ok := part["text"].(string); ok && text != "" {
+			parts = append(parts, GeminiPart{Text: text})
+			continue
+		}
+		imageURL := ""
+		switch v := part["image_url"].(type) {
+		case string:
+			imageURL = v
+		case map[string]interface{}:
+			imageURL, _ = v["url"].(string)
+		}
+		if imageURL != "" {
+			parts = append(parts, imageURLToGeminiPart(imageURL))
+		}
+	}
+	return parts
+}
+
+// imageURLToGeminiPart data URL 转换为 inlineData，其它 URL 转换为 fileData
+func imageURLToGeminiPart(imageURL string) GeminiPart {
+	if strings.HasPrefix(imageURL, "data:") {
+		header, data, found := strings.Cut(strings.TrimPrefix(imageURL, "data:"), ",")
+		if found {
+			mimeType := strings.TrimSuffix(header, ";base64")
+			return GeminiPart{InlineData: &GeminiBlob{MimeType: mimeType, Data: data}}
+		}
+	}
+	fileData := &GeminiFileData{FileURI: imageURL}
+	if u, err := url.Parse(imageURL); err == nil {
+		fileData.MimeType = mime.TypeByExtension(path.Ext(u.Path))
+	}
+	return GeminiPart{FileData: fileData}
+}
+
+// parseFunctionArguments 将 JSON 字符串参数解析为对象，无法解析时包一层
+func parseFunctionArguments(arguments string) map[string]interface{} {
+	args := map[string]interface{}{}
+	if strings.TrimSpace(arguments) == "" {
+		return args
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil || args == nil {
+		return map[string]interface{}{"arguments": arguments}
+	}
+	return args
+}
+
+// functionResponsePayload functionResponse.response 必须是对象：JSON 对象原样使用，其它内容放在 content 字段
+func functionResponsePayload(content string) map[string]interface{} {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]interface{}{"content": content}
+}
+
+// geminiToolConfig 将 tool_choice 映射为 functionCallingConfig
+func geminiToolConfig(toolChoice interface{}) *GeminiToolConfig {
+	config := &GeminiFunctionCallingConfig{Mode: "AUTO"}
+	switch v := toolChoice.(type) {
+	case string:
+		switch v {
+		case "required":
+			config.Mode = "ANY"
+		case "none":
+			config.Mode = "NONE"
+		}
+	case map[string]interface{}:
+		name, _ := v["name"].(string)
+		if function, ok := v["function"].(map[string]interface{}); ok {
+			name, _ = function["name"].(string)
+		}
+		if name != "" {
+			config.Mode = "ANY"
+			config.AllowedFunctionNames = []string{name}
+		}
+	}
+	return &GeminiToolConfig{FunctionCallingConfig: config}
+}
+
+func stopSequencesFromChat(stop interface{}) []string {
+	switch v := stop.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []interface{}:
+		return toStringSlice(v)
+	}
+	return nil
+}
+
+// geminiFunctionCallToToolCall 转换 functionCall 片段，片段上的思考签名编码进工具调用 ID
+func geminiFunctionCallToToolCall(part GeminiPart, index int) OpenAIToolCall {
+	fc := part.FunctionCall
+	id := fc.ID
+	if id == "" {
+		id = newToolCallID()
+	}
+	if part.ThoughtSignature != "" {
+		id += geminiThoughtSignatureSeparator + base64.RawURLEncoding.EncodeToString([]byte(part.ThoughtSignature))
+	}
+	args := fc.Args
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	arguments, _ := json.Marshal(args)
+	return OpenAIToolCall{
+		Index:    index,
+		ID:       id,
+		Type:     "function",
+		Function: OpenAIToolCallDetail{Name: fc.Name, Arguments: string(arguments)},
+	}
+}
+
+// thoughtSignatureFromToolCallID 从工具调用 ID 中还原思考签名，没有或无法解码时返回空字符串
+func thoughtSignatureFromToolCallID(id string) string {
+	_, encoded, found := strings.Cut(id, geminiThoughtSignatureSeparator)
+	if !found {
+		return ""
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	return string(signature)
+}
+
+// mapGeminiFinishReason 将 Gemini finishReason 映射为 OpenAI finish_reason
+func mapGeminiFinishReason(reason string, hasToolCalls bool) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// geminiUsageToOpenAI 思考 token 计入 completion_tokens
+func geminiUsageToOpenAI(usage *GeminiUsageMetadata) *OpenAIUsage {
+	if usage == nil {
+		return nil
+	}
+	completion := usage.CandidatesTokenCount + usage.ThoughtsTokenCount
+	total := usage.TotalTokenCount
+	if total == 0 {
+		total = usage.PromptTokenCount + completion
+	}
+	return &OpenAIUsage{
+		PromptTokens:     usage.PromptTokenCount,
+		CompletionTokens: completion,
+		TotalTokens:      total,
+	}
+}
@@ -0,0 +1,355 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 录制的 Gemini streamGenerateContent?alt=sse 响应（事件以 \r\n\r\n 分隔）
+const recordedGeminiSSE = "data: {\"candidates\": [{\"content\": {\"parts\": [{\"text\": \"Checking the file\",\"thought\": true}],\"role\": \"model\"},\"index\": 0}],\"usageMetadata\": {\"promptTokenCount\": 42,\"totalTokenCount\": 60,\"thoughtsTokenCount\": 18},\"modelVersion\": \"gemini-2.5-pro\",\"responseId\": \"resp-gemini-1\"}\r\n\r\n" +
+	"data: {\"candidates\": [{\"content\": {\"parts\": [{\"text\": \"Let me read \"}],\"role\": \"model\"},\"index\": 0}],\"usageMetadata\": {\"promptTokenCount\": 42,\"candidatesTokenCount\": 3,\"totalTokenCount\": 63,\"thoughtsTokenCount\": 18},\"modelVersion\": \"gemini-2.5-pro\",\"responseId\": \"resp-gemini-1\"}\r\n\r\n" +
+	"data: {\"candidates\": [{\"content\": {\"parts\": [{\"text\": \"the file.\"},{\"functionCall\": {\"name\": \"Read\",\"args\": {\"file_path\": \"/tmp/a.go\"}}}],\"role\": \"model\"},\"finishReason\": \"STOP\",\"index\": 0}],\"usageMetadata\": {\"promptTokenCount\": 42,\"candidatesTokenCount\": 15,\"totalTokenCount\": 75,\"thoughtsTokenCount\": 18},\"modelVersion\": \"gemini-2.5-pro\",\"responseId\": \"resp-gemini-1\"}\r\n\r\n"
+
+const geminiAnthropicRequest = `{
+	"model": "gemini-2.5-pro",
+	"max_tokens": 2048,
+	"stream": true,
+	"system": "You are a coding assistant.",
+	"thinking": {"type": "enabled", "budget_tokens": 4096},
+	"tools": [{
+		"name": "Read",
+		"description": "Read a file",
+		"input_schema": {"$schema": "http://json-schema.org/draft-07/schema#", "type": "object", "properties": {"file_path": {"type": "string"}}, "required": ["file_path"], "additionalProperties": false}
+	}],
+	"messages": [
+		{"role": "user", "content": [
+			{"type": "text", "text": "What is in this image?"},
+			{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+		]},
+		{"role": "assistant", "content": [
+			{"type": "tool_use", "id": "toolu_01", "name": "Read", "input": {"file_path": "/tmp/b.go"}}
+		]},
+		{"role": "user", "content": [
+			{"type": "tool_result", "tool_use_id": "toolu_01", "content": "package main"}
+		]}
+	]
+}`
+
+func convertForGemini(t *testing.T, anthropicRequest string) ([]byte, *ConversionContext, *GeminiAdapter) {
+	t.Helper()
+	info := &EndpointInfo{Type: "gemini"}
+	chatBody, ctx, err := NewRequestConverter(getTestLogger()).Convert([]byte(anthropicRequest), info)
+	if err != nil {
+		t.Fatalf("Anthropic -> OpenAI conversion failed: %v", err)
+	}
+	ctx.EndpointType = info.Type
+	return chatBody, ctx, NewGeminiAdapter(info, getTestLogger())
+}
+
+func TestGeminiAdapter_AdaptRequest(t *testing.T) {
+	chatBody, _, adapter := convertForGemini(t, geminiAnthropicRequest)
+
+	upstream, err := adapter.AdaptRequest(chatBody)
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+
+	if upstream.Path != "/models/gemini-2.5-pro:streamGenerateContent" {
+		t.Errorf("Unexpected path: %s", upstream.Path)
+	}
+	if !upstream.Streaming || upstream.Query.Get("alt") != "sse" {
+		t.Errorf("Expected streaming request with alt=sse, got streaming=%v query=%v", upstream.Streaming, upstream.Query)
+	}
+
+	var req GeminiRequest
+	if err := json.Unmarshal(upstream.Body, &req); err != nil {
+		t.Fatalf("Failed to unmarshal Gemini request: %v", err)
+	}
+
+	if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "You are a coding assistant." {
+		t.Errorf("Expected system instruction, got %+v", req.SystemInstruction)
+	}
+
+	if len(req.Contents) != 3 {
+		t.Fatalf("Expected user/model/user contents, got %d: %s", len(req.Contents), string(upstream.Body))
+	}
+	for i, role := range []string{"user", "model", "user"} {
+		if req.Contents[i].Role != role {
+			t.Errorf("Content %d: expected role %s, got %s", i, role, req.Contents[i].Role)
+		}
+	}
+
+	var image *GeminiBlob
+	for _, part := range req.Contents[0].Parts {
+		if part.InlineData != nil {
+			image = part.InlineData
+		}
+	}
+	if image == nil || image.MimeType != "image/png" || image.Data != "iVBORw0KGgo=" {
+		t.Errorf("Expected inline image data, got %+v", req.Contents[0].Parts)
+	}
+
+	call := req.Contents[1].Parts[0].FunctionCall
+	if call == nil || call.Name != "Read" || call.Args["file_path"] != "/tmp/b.go" {
+		t.Errorf("Expected functionCall for Read, got %+v", req.Contents[1].Parts[0])
+	}
+	response := req.Contents[2].Parts[0].FunctionResponse
+	if response == nil || response.Name != "Read" || response.Response["content"] != "package main" {
+		t.Errorf("Expected functionResponse for Read, got %+v", req.Contents[2].Parts[0])
+	}
+
+	if len(req.Tools) != 1 || len(req.Tools[0].FunctionDeclarations) != 1 {
+		t.Fatalf("Expected one function declaration, got %+v", req.Tools)
+	}
+	params := req.Tools[0].FunctionDeclarations[0].Parameters
+	if _, ok := params["additionalProperties"]; ok {
+		t.Errorf("Expected additionalProperties to be removed by the gemini schema profile: %v", params)
+	}
+	if _, ok := params["$schema"]; ok {
+		t.Errorf("Expected $schema to be removed: %v", params)
+	}
+
+	gen := req.GenerationConfig
+	if gen == nil || gen.MaxOutputTokens == nil || *gen.MaxOutputTokens != 2048 {
+		t.Errorf("Expected maxOutputTokens 2048, got %+v", gen)
+	}
+	if gen == nil || gen.ThinkingConfig == nil || gen.ThinkingConfig.ThinkingBudget == nil || *gen.ThinkingConfig.ThinkingBudget != 4096 {
+		t.Errorf("Expected thinkingBudget 4096, got %+v", gen)
+	}
+}
+
+func TestGeminiAdapter_ToolChoiceAndSafetySettings(t *testing.T) {
+	adapter := NewGeminiAdapter(&EndpointInfo{
+		Type:                 "gemini",
+		GeminiSafetySettings: map[string]string{"HARM_CATEGORY_HARASSMENT": "BLOCK_NONE", "HARM_CATEGORY_DANGEROUS_CONTENT": "BLOCK_ONLY_HIGH"},
+	}, getTestLogger())
+
+	upstream, err := adapter.AdaptRequest([]byte(`{
+		"model": "models/gemini-2.5-flash",
+		"messages": [{"role": "user", "content": "hi"}],
+		"tools": [{"type": "function", "name": "Grep", "parameters": {"type": "object", "properties": {}}}],
+		"tool_choice": {"type": "function", "function": {"name": "Grep"}},
+		"reasoning": {"effort": "low"},
+		"stop": "END"
+	}`))
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+
+	if upstream.Path != "/models/gemini-2.5-flash:generateContent" || upstream.Streaming || len(upstream.Query) != 0 {
+		t.Errorf("Unexpected non-streaming request: path=%s streaming=%v query=%v", upstream.Path, upstream.Streaming, upstream.Query)
+	}
+
+	var req GeminiRequest
+	if err := json.Unmarshal(upstream.Body, &req); err != nil {
+		t.Fatalf("Failed to unmarshal Gemini request: %v", err)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].FunctionDeclarations[0].Name != "Grep" {
+		t.Errorf("Expected flat Responses-style tool to be declared, got %+v", req.Tools)
+	}
+	fc := req.ToolConfig.FunctionCallingConfig
+	if fc.Mode != "ANY" || len(fc.AllowedFunctionNames) != 1 || fc.AllowedFunctionNames[0] != "Grep" {
+		t.Errorf("Expected ANY mode restricted to Grep, got %+v", fc)
+	}
+	if budget := req.GenerationConfig.ThinkingConfig.ThinkingBudget; budget == nil || *budget != 1024 {
+		t.Errorf("Expected low effort thinking budget 1024, got %v", budget)
+	}
+	if len(req.GenerationConfig.StopSequences) != 1 || req.GenerationConfig.StopSequences[0] != "END" {
+		t.Errorf("Expected stop sequence END, got %v", req.GenerationConfig.StopSequences)
+	}
+	if len(req.SafetySettings) != 2 || req.SafetySettings[0].Category != "HARM_CATEGORY_DANGEROUS_CONTENT" {
+		t.Errorf("Expected sorted safety settings, got %+v", req.SafetySettings)
+	}
+}
+
+func TestGeminiAdapter_StreamingAgainstStubServer(t *testing.T) {
+	chatBody, ctx, adapter := convertForGemini(t, geminiAnthropicRequest)
+	upstream, err := adapter.AdaptRequest(chatBody)
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.5-pro:streamGenerateContent" {
+			t.Errorf("Unexpected upstream path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" || r.URL.Query().Get("alt") != "sse" || r.URL.Query().Has("key") {
+			t.Errorf("Expected x-goog-api-key header and alt query parameter, got %s", r.URL.RawQuery)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req GeminiRequest
+		if err := json.Unmarshal(body, &req); err != nil || len(req.Contents) == 0 {
+			t.Errorf("Stub received invalid Gemini request: %v %s", err, string(body))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, recordedGeminiSSE)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1beta"+upstream.Path+"?"+upstream.Query.Encode(), bytes.NewReader(upstream.Body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", "test-key")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request to stub server failed: %v", err)
+	}
+	defer resp.Body.Close()
+	geminiBody, _ := io.ReadAll(resp.Body)
+
+	chatSSE, err := adapter.AdaptResponse(geminiBody, upstream.Streaming)
+	if err != nil {
+		t.Fatalf("AdaptResponse failed: %v", err)
+	}
+	chat := string(chatSSE)
+	if strings.Contains(chat, "Checking the file") {
+		t.Errorf("Thought parts should not be returned as text: %s", chat)
+	}
+	if !strings.Contains(chat, `"finish_reason":"tool_calls"`) || !strings.HasSuffix(chat, "data: [DONE]\n\n") {
+		t.Errorf("Expected tool_calls finish and [DONE]: %s", chat)
+	}
+	if strings.Count(chat, `"usage"`) != 1 || !strings.Contains(chat, `"completion_tokens":33`) {
+		t.Errorf("Expected a single usage with thoughts counted as completion tokens: %s", chat)
+	}
+
+	anthropicSSE, err := NewResponseConverter(getTestLogger()).Convert(chatSSE, ctx, true)
+	if err != nil {
+		t.Fatalf("OpenAI -> Anthropic conversion failed: %v", err)
+	}
+	result := string(anthropicSSE)
+	for _, expected := range []string{
+		`"text":"Let me read the file."`,
+		`"type":"tool_use"`,
+		`"name":"Read"`,
+		`"stop_reason":"tool_use"`,
+		`"output_tokens":33`,
+		"event: message_stop",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %s in Anthropic SSE:\n%s", expected, result)
+		}
+	}
+}
+
+func TestGeminiAdapter_NonStreamingResponse(t *testing.T) {
+	adapter := NewGeminiAdapter(&EndpointInfo{Type: "gemini"}, getTestLogger())
+	if _, err := adapter.AdaptRequest([]byte(`{"model":"gemini-2.5-flash","messages":[{"role":"user","content":"hi"}]}`)); err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+
+	testCases := []struct {
+		name           string
+		response       string
+		expectedFinish string
+		expectedText   string
+	}{
+		{
+			name:           "max tokens",
+			response:       `{"candidates":[{"content":{"role":"model","parts":[{"text":"partial"}]},"finishReason":"MAX_TOKENS","index":0}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":7,"totalTokenCount":12},"responseId":"r1"}`,
+			expectedFinish: "length",
+			expectedText:   "partial",
+		},
+		{
+			name:           "safety",
+			response:       `{"candidates":[{"content":{"role":"model","parts":[]},"finishReason":"SAFETY","index":0}]}`,
+			expectedFinish: "content_filter",
+		},
+		{
+			name:           "blocked prompt",
+			response:       `{"promptFeedback":{"blockReason":"PROHIBITED_CONTENT"},"usageMetadata":{"promptTokenCount":5,"totalTokenCount":5}}`,
+			expectedFinish: "content_filter",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := adapter.AdaptResponse([]byte(tc.response), false)
+			if err != nil {
+				t.Fatalf("AdaptResponse failed: %v", err)
+			}
+
+			var resp OpenAIResponse
+			if err := json.Unmarshal(result, &resp); err != nil {
+				t.Fatalf("Failed to unmarshal chat completion: %v", err)
+			}
+			if !strings.Contains(string(result), `"object":"chat.completion"`) || resp.Model != "gemini-2.5-flash" {
+				t.Errorf("Unexpected chat completion: %s", string(result))
+			}
+			if resp.Choices[0].FinishReason != tc.expectedFinish {
+				t.Errorf("Expected finish_reason %s, got %s", tc.expectedFinish, resp.Choices[0].FinishReason)
+			}
+			if text, _ := resp.Choices[0].Message.Content.(string); text != tc.expectedText {
+				t.Errorf("Expected text %q, got %q", tc.expectedText, text)
+			}
+		})
+	}
+}
+
+func TestGeminiAdapter_TruncatedStream(t *testing.T) {
+	adapter := NewGeminiAdapter(&EndpointInfo{Type: "gemini"}, getTestLogger())
+	truncated := strings.SplitAfterN(recordedGeminiSSE, "\r\n\r\n", 3)
+	result, err := adapter.AdaptResponse([]byte(truncated[0]+truncated[1]), true)
+	if err != nil {
+		t.Fatalf("AdaptResponse failed: %v", err)
+	}
+
+	// 没有 finishReason 时不补齐结束标记，交给响应校验识别为不完整的流
+	if strings.Contains(string(result), "[DONE]") || strings.Contains(string(result), `"finish_reason":"`) {
+		t.Errorf("Truncated stream should not be completed: %s", string(result))
+	}
+}
+
+func TestGeminiAdapter_ThoughtSignatureRoundTrip(t *testing.T) {
+	const signature = "CiQB0e2Kb+/signature=="
+	response := `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"Read","args":{"file_path":"/tmp/a.go"}},"thoughtSignature":"` + signature + `"}]},"finishReason":"STOP","index":0}]}`
+
+	for _, isStreaming := range []bool{false, true} {
+		adapter := NewGeminiAdapter(&EndpointInfo{Type: "gemini"}, getTestLogger())
+		body := []byte(response)
+		if isStreaming {
+			body = []byte("data: " + response + "\r\n\r\n")
+		}
+		result, err := adapter.AdaptResponse(body, isStreaming)
+		if err != nil {
+			t.Fatalf("AdaptResponse failed: %v", err)
+		}
+
+		// 签名编码进工具调用 ID，ID 只包含 Anthropic tool_use ID 允许的字符
+		var id string
+		for _, match := range strings.Split(string(result), `"id":"call_`)[1:] {
+			id = "call_" + match[:strings.Index(match, `"`)]
+		}
+		if !strings.Contains(id, geminiThoughtSignatureSeparator) || strings.ContainsAny(id, "+/=") {
+			t.Fatalf("Expected tool call ID to carry the encoded signature, got %q: %s", id, string(result))
+		}
+
+		// 客户端回传该工具调用后，签名原样带回 functionCall 片段
+		chatRequest := `{"model":"gemini-3-pro-preview","messages":[` +
+			`{"role":"user","content":"read a.go"},` +
+			`{"role":"assistant","content":"","tool_calls":[{"id":"` + id + `","type":"function","function":{"name":"Read","arguments":"{\"file_path\":\"/tmp/a.go\"}"}}]},` +
+			`{"role":"tool","tool_call_id":"` + id + `","content":"package main"}]}`
+		upstream, err := adapter.AdaptRequest([]byte(chatRequest))
+		if err != nil {
+			t.Fatalf("AdaptRequest failed: %v", err)
+		}
+		var req GeminiRequest
+		if err := json.Unmarshal(upstream.Body, &req); err != nil {
+			t.Fatalf("Failed to unmarshal Gemini request: %v", err)
+		}
+		part := req.Contents[1].Parts[0]
+		if part.FunctionCall == nil || part.ThoughtSignature != signature {
+			t.Errorf("Expected thoughtSignature %q on the functionCall part, got %+v", signature, part)
+		}
+		if response := req.Contents[2].Parts[0].FunctionResponse; response == nil || response.Name != "Read" {
+			t.Errorf("Expected functionResponse for Read, got %+v", req.Contents[2].Parts[0])
+		}
+	}
+
+	if got := thoughtSignatureFromToolCallID("toolu_01"); got != "" {
+		t.Errorf("Expected no signature for IDs from other endpoints, got %q", got)
+	}
+}
@@ -60,6 +60,11 @@ func (a *MessageAggregator) processChunk(chunk OpenAIStreamChunk, aggregated *Ag
 			}
 		}
 
+		// Process reasoning content
+		if choice.Delta.ReasoningContent != "" {
+			aggregated.ThinkingContent += choice.Delta.ReasoningContent
+		}
+
 		// Process tool calls
 		if len(choice.Delta.ToolCalls) > 0 {
 			for _, toolCall := range choice.Delta.ToolCalls {
@@ -206,8 +211,13 @@ func (a *MessageAggregator) applyContentFixes(aggregated *AggregatedMessage) {
 	// Trim whitespace from text content
 	aggregated.TextContent = strings.TrimSpace(aggregated.TextContent)
 
-	// Apply finish reason mapping
-	aggregated.FinishReason = a.mapFinishReason(aggregated.FinishReason)
+	// Apply finish reason mapping (including stop sequence detection on the text tail)
+	if aggregated.FinishReason == "stop" && len(a.stopSequences) > 0 {
+		aggregated.FinishReason, aggregated.StopSequence, aggregated.TextContent =
+			resolveStopReason(aggregated.FinishReason, aggregated.TextContent, a.stopSequences)
+	} else {
+		aggregated.FinishReason = a.mapFinishReason(aggregated.FinishReason)
+	}
 
 	// Apply Python JSON fixes to tool call arguments, especially for TodoWrite
 	for i := range aggregated.ToolCalls {
@@ -239,12 +249,5 @@ func (a *MessageAggregator) applyContentFixes(aggregated *AggregatedMessage) {
 
 // mapFinishReason maps OpenAI finish reasons to Anthropic format
 func (a *MessageAggregator) mapFinishReason(openaiReason string) string {
-	switch openaiReason {
-	case "tool_calls":
-		return "tool_use"
-	case "length":
-		return "max_tokens"
-	default: // including "stop" and others
-		return "end_turn"
-	}
+	return MapFinishReasonToStopReason(openaiReason)
 }
\ No newline at end of file
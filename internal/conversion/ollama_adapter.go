@@ -0,0 +1,392 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+// OllamaAdapter 在 OpenAI Chat Completions 与 Ollama /api/chat 之间转换
+// 相比 Ollama 的 OpenAI 兼容接口，原生接口支持流式工具调用、keep_alive 以及 num_ctx、top_k 等 options
+type OllamaAdapter struct {
+	logger    *logger.Logger
+	options   map[string]interface{}
+	keepAlive string
+
+	model string // 请求的模型名，用于填充响应
+}
+
+// NewOllamaAdapter 创建 Ollama 上游适配器
+func NewOllamaAdapter(endpointInfo *EndpointInfo, logger *logger.Logger) *OllamaAdapter {
+	return &OllamaAdapter{
+		logger:    logger,
+		options:   endpointInfo.OllamaOptions,
+		keepAlive: endpointInfo.OllamaKeepAlive,
+	}
+}
+
+// AdaptRequest 将 Chat Completions 请求转换为 Ollama /api/chat 请求
+func (a *OllamaAdapter) AdaptRequest(body []byte) (*UpstreamRequest, error) {
+	var chatReq compatChatRequest
+	if err := json.Unmarshal(body, &chatReq); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse request for Ollama conversion", err)
+	}
+
+	a.model = chatReq.Model
+	if a.model == "" {
+		return nil, NewConversionError("unsupported_feature", "Ollama request requires a model", nil)
+	}
+
+	out := OllamaChatRequest{
+		Model:    a.model,
+		Messages: []OllamaMessage{},
+		Stream:   chatReq.Stream != nil && *chatReq.Stream,
+	}
+
+	// 消息映射：tool 消息需要函数名，工具调用参数为 JSON 对象
+	toolNameByID := map[string]string{}
+	for _, m := range chatReq.Messages {
+		switch m.Role {
+		case "system", "developer":
+			if text := chatContentText(m.Content); text != "" {
+				out.Messages = append(out.Messages, OllamaMessage{Role: "system", Content: text})
+			}
+		case "assistant":
+			msg := OllamaMessage{Role: "assistant", Content: chatContentText(m.Content)}
+			for _, tc := range m.ToolCalls {
+				toolNameByID[tc.ID] = tc.Function.Name
+				msg.ToolCalls = append(msg.ToolCalls, OllamaToolCall{Function: OllamaToolCallFunction{
+					Name:      tc.Function.Name,
+					Arguments: parseFunctionArguments(tc.Function.Arguments),
+				}})
+			}
+			out.Messages = append(out.Messages, msg)
+		case "tool":
+			name := toolNameByID[m.ToolCallID]
+			if name == "" {
+				name = m.Name
+			}
+			out.Messages = append(out.Messages, OllamaMessage{Role: "tool", Content: chatContentText(m.Content), ToolName: name})
+		default:
+			out.Messages = append(out.Messages, a.userMessage(m.Content))
+		}
+	}
+
+	// 工具声明（Ollama 不支持 tool_choice，none 时不声明工具）
+	if choice, _ := chatReq.ToolChoice.(string); choice != "none" {
+		for _, t := range chatReq.Tools {
+			def := OpenAIFunctionDef{Name: t.Name, Description: t.Description, Parameters: t.Parameters}
+			if t.Function != nil {
+				def = *t.Function
+			}
+			if def.Name == "" {
+				continue
+			}
+			out.Tools = append(out.Tools, OpenAITool{Type: "function", Function: def})
+		}
+	}
+
+	// options：端点配置作为默认值，请求中的采样参数优先
+	options := map[string]interface{}{}
+	for key, value := range a.options {
+		options[key] = value
+	}
+	if chatReq.Temperature != nil {
+		options["temperature"] = *chatReq.Temperature
+	}
+	if chatReq.TopP != nil {
+		options["top_p"] = *chatReq.TopP
+	}
+	if chatReq.TopK != nil {
+		options["top_k"] = *chatReq.TopK
+	}
+	for _, maxTokens := range []*int{chatReq.MaxOutputTokens, chatReq.MaxCompletionTokens, chatReq.MaxTokens} {
+		if maxTokens != nil {
+			options["num_predict"] = *maxTokens
+			break
+		}
+	}
+	if stop := stopSequencesFromChat(chatReq.Stop); len(stop) > 0 {
+		options["stop"] = stop
+	}
+	if len(options) > 0 {
+		out.Options = options
+	}
+
+	// 请求了推理时开启思考，思考内容通过 message.thinking 返回
+	if chatReq.ReasoningEffort != nil || chatReq.MaxReasoningTokens != nil || (chatReq.Reasoning != nil && chatReq.Reasoning.Effort != "") {
+		think := true
+		out.Think = &think
+	}
+
+	if a.keepAlive != "" {
+		out.KeepAlive = ollamaKeepAlive(a.keepAlive)
+	}
+
+	converted, err := json.Marshal(out)
+	if err != nil {
+		return nil, NewConversionError("marshal_error", "Failed to marshal Ollama request", err)
+	}
+
+	return &UpstreamRequest{
+		Path:      "/api/chat",
+		Query:     url.Values{},
+		Body:      converted,
+		Streaming: out.Stream,
+	}, nil
+}
+
+// userMessage 用户消息：文本合并为 content，data URL 图片转换为 base64（Ollama 不支持远程图片 URL）
+func (a *OllamaAdapter) userMessage(content interface{}) OllamaMessage {
+	msg := OllamaMessage{Role: "user", Content: chatContentText(content)}
+	items, ok := content.([]interface{})
+	if !ok {
+		return msg
+	}
+	for _, item := range items {
+		part, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		imageURL := ""
+		switch v := part["image_url"].(type) {
+		case string:
+			imageURL = v
+		case map[string]interface{}:
+			imageURL, _ = v["url"].(string)
+		}
+		if imageURL == "" {
+			continue
+		}
+		if _, data, found := strings.Cut(strings.TrimPrefix(imageURL, "data:"), ","); found && strings.HasPrefix(imageURL, "data:") {
+			msg.Images = append(msg.Images, data)
+		} else if a.logger != nil {
+			a.logger.Debug("Dropping remote image URL not supported by Ollama", map[string]interface{}{"url": imageURL})
+		}
+	}
+	return msg
+}
+
+// AdaptResponse 将 Ollama 响应转换为 Chat Completions 响应（流式时将 NDJSON 转换为 chat.completion.chunk SSE）
+func (a *OllamaAdapter) AdaptResponse(body []byte, isStreaming bool) ([]byte, error) {
+	if !isStreaming {
+		var resp OllamaChatResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, NewConversionError("parse_error", "Failed to parse Ollama response", err)
+		}
+		if resp.Error != "" {
+			return nil, NewConversionError("upstream_error", "Ollama request failed: "+resp.Error, nil)
+		}
+		return a.convertResponse(&resp)
+	}
+	return a.convertStream(body)
+}
+
+func (a *OllamaAdapter) convertResponse(resp *OllamaChatResponse) ([]byte, error) {
+	message := map[string]interface{}{"role": "assistant", "content": ""}
+	var toolCalls []OpenAIToolCall
+	if resp.Message != nil {
+		message["content"] = resp.Message.Content
+		if resp.Message.Thinking != "" {
+			message["reasoning_content"] = resp.Message.Thinking
+		}
+		for _, tc := range resp.Message.ToolCalls {
+			toolCalls = append(toolCalls, ollamaToolCallToToolCall(tc, len(toolCalls)))
+		}
+	}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+
+	out := map[string]interface{}{
+		"id":      newChatCompletionID(),
+		"object":  "chat.completion",
+		"created": ollamaCreatedAt(resp.CreatedAt),
+		"model":   a.responseModel(resp.Model),
+		"choices": []interface{}{map[string]interface{}{
+			"index":         0,
+			"message":       message,
+			"finish_reason": mapOllamaDoneReason(resp.DoneReason, len(toolCalls) > 0),
+		}},
+		"usage": ollamaUsageToOpenAI(resp),
+	}
+	return json.Marshal(out)
+}
+
+func (a *OllamaAdapter) convertStream(body []byte) ([]byte, error) {
+	var out bytes.Buffer
+	id := newChatCompletionID()
+	model := a.model
+	created := time.Now().Unix()
+	toolCallCount := 0
+	started := false
+	done := false
+
+	writeChunk := func(delta map[string]interface{}, finish interface{}, usage *OpenAIUsage) {
+		chunk := map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []interface{}{map[string]interface{}{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finish,
+			}},
+		}
+		if usage != nil {
+			chunk["usage"] = usage
+		}
+		data, _ := json.Marshal(chunk)
+		out.WriteString("data: ")
+		out.Write(data)
+		out.WriteString("\n\n")
+	}
+
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var resp OllamaChatResponse
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			return nil, NewConversionError("ndjson_parse_error", "Failed to parse Ollama NDJSON line", err)
+		}
+		if resp.Error != "" {
+			return nil, NewConversionError("upstream_error", "Ollama request failed: "+resp.Error, nil)
+		}
+
+		if !started {
+			started = true
+			model = a.responseModel(resp.Model)
+			created = ollamaCreatedAt(resp.CreatedAt)
+			writeChunk(map[string]interface{}{"role": "assistant", "content": ""}, nil, nil)
+		}
+
+		if resp.Message != nil {
+			if resp.Message.Thinking != "" {
+				writeChunk(map[string]interface{}{"reasoning_content": resp.Message.Thinking}, nil, nil)
+			}
+			if resp.Message.Content != "" {
+				writeChunk(map[string]interface{}{"content": resp.Message.Content}, nil, nil)
+			}
+			// Ollama 的工具调用在一行中完整返回
+			for _, tc := range resp.Message.ToolCalls {
+				toolCall := ollamaToolCallToToolCall(tc, toolCallCount)
+				toolCallCount++
+				writeChunk(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+					"index":    toolCall.Index,
+					"id":       toolCall.ID,
+					"type":     "function",
+					"function": map[string]interface{}{"name": toolCall.Function.Name, "arguments": toolCall.Function.Arguments},
+				}}}, nil, nil)
+			}
+		}
+
+		if resp.Done {
+			done = true
+			writeChunk(map[string]interface{}{}, mapOllamaDoneReason(resp.DoneReason, toolCallCount > 0), ollamaUsageToOpenAI(&resp))
+			break
+		}
+	}
+
+	if !started {
+		return nil, NewConversionError("empty_stream", "No valid lines found in Ollama NDJSON stream", nil)
+	}
+
+	// 没有 done 说明流被截断，不补 finish_reason/[DONE]，交给响应校验触发重试
+	if done {
+		out.WriteString("data: [DONE]\n\n")
+	} else if a.logger != nil {
+		a.logger.Debug("Ollama NDJSON stream ended without done")
+	}
+
+	return out.Bytes(), nil
+}
+
+func (a *OllamaAdapter) responseModel(responseModel string) string {
+	if responseModel != "" {
+		return responseModel
+	}
+	return a.model
+}
+
+// ParseOllamaModels 解析 /api/tags 响应，返回排序后的模型名
+func ParseOllamaModels(body []byte) ([]string, error) {
+	var tags OllamaTagsResponse
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse Ollama tags response", err)
+	}
+	models := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		name := m.Name
+		if name == "" {
+			name = m.Model
+		}
+		if name != "" {
+			models = append(models, name)
+		}
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// ollamaKeepAlive 纯数字按秒数发送，其它按时长字符串（如 "10m"）发送
+func ollamaKeepAlive(keepAlive string) json.RawMessage {
+	if seconds, err := strconv.Atoi(keepAlive); err == nil {
+		return json.RawMessage(strconv.Itoa(seconds))
+	}
+	encoded, _ := json.Marshal(keepAlive)
+	return encoded
+}
+
+func ollamaToolCallToToolCall(tc OllamaToolCall, index int) OpenAIToolCall {
+	args := tc.Function.Arguments
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	arguments, _ := json.Marshal(args)
+	return OpenAIToolCall{
+		Index:    index,
+		ID:       newToolCallID(),
+		Type:     "function",
+		Function: OpenAIToolCallDetail{Name: tc.Function.Name, Arguments: string(arguments)},
+	}
+}
+
+// mapOllamaDoneReason 将 Ollama done_reason 映射为 OpenAI finish_reason
+func mapOllamaDoneReason(reason string, hasToolCalls bool) string {
+	if reason == "length" {
+		return "length"
+	}
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func ollamaUsageToOpenAI(resp *OllamaChatResponse) *OpenAIUsage {
+	return &OpenAIUsage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+	}
+}
+
+func ollamaCreatedAt(createdAt string) int64 {
+	if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
+		return t.Unix()
+	}
+	return time.Now().Unix()
+}
+
+func newChatCompletionID() string {
+	return "chatcmpl-" + strings.TrimPrefix(newToolCallID(), "call_")
+}
@@ -0,0 +1,238 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// 录制的 Ollama /api/chat NDJSON 流：思考 -> 文本 -> 工具调用 -> done
+const recordedOllamaNDJSON = `{"model":"qwen3:14b","created_at":"2025-06-01T10:00:00.000Z","message":{"role":"assistant","content":"","thinking":"Need the file."},"done":false}
+{"model":"qwen3:14b","created_at":"2025-06-01T10:00:00.100Z","message":{"role":"assistant","content":"Let me read "},"done":false}
+{"model":"qwen3:14b","created_at":"2025-06-01T10:00:00.200Z","message":{"role":"assistant","content":"the file."},"done":false}
+{"model":"qwen3:14b","created_at":"2025-06-01T10:00:00.300Z","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"Read","arguments":{"file_path":"/tmp/a.go"}}}]},"done":false}
+{"model":"qwen3:14b","created_at":"2025-06-01T10:00:00.400Z","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","total_duration":1200000000,"prompt_eval_count":42,"eval_count":17}
+`
+
+const ollamaAnthropicRequest = `{
+	"model": "qwen3:14b",
+	"max_tokens": 1024,
+	"stream": true,
+	"temperature": 0.2,
+	"top_k": 40,
+	"system": "You are a coding assistant.",
+	"thinking": {"type": "enabled", "budget_tokens": 2048},
+	"tools": [{
+		"name": "Read",
+		"description": "Read a file",
+		"input_schema": {"type": "object", "properties": {"file_path": {"type": "string"}}, "required": ["file_path"]}
+	}],
+	"messages": [
+		{"role": "user", "content": [
+			{"type": "text", "text": "What is in this image?"},
+			{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+		]},
+		{"role": "assistant", "content": [
+			{"type": "tool_use", "id": "toolu_01", "name": "Read", "input": {"file_path": "/tmp/b.go"}}
+		]},
+		{"role": "user", "content": [
+			{"type": "tool_result", "tool_use_id": "toolu_01", "content": "package main"}
+		]}
+	]
+}`
+
+func convertForOllama(t *testing.T, anthropicRequest string) ([]byte, *ConversionContext, UpstreamAdapter) {
+	t.Helper()
+	info := &EndpointInfo{
+		Type:            "ollama",
+		OllamaOptions:   map[string]interface{}{"num_ctx": 32768, "temperature": 0.8},
+		OllamaKeepAlive: "30m",
+	}
+	chatBody, ctx, err := NewRequestConverter(getTestLogger()).Convert([]byte(anthropicRequest), info)
+	if err != nil {
+		t.Fatalf("Anthropic -> OpenAI conversion failed: %v", err)
+	}
+	ctx.EndpointType = info.Type
+	adapter := NewUpstreamAdapter(info, getTestLogger())
+	if _, ok := adapter.(*OllamaAdapter); !ok {
+		t.Fatalf("Expected OllamaAdapter, got %T", adapter)
+	}
+	return chatBody, ctx, adapter
+}
+
+func TestOllamaAdapter_AdaptRequest(t *testing.T) {
+	chatBody, _, adapter := convertForOllama(t, ollamaAnthropicRequest)
+
+	upstream, err := adapter.AdaptRequest(chatBody)
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+	if upstream.Path != "/api/chat" || !upstream.Streaming {
+		t.Errorf("Unexpected upstream request: path=%s streaming=%v", upstream.Path, upstream.Streaming)
+	}
+
+	var req OllamaChatRequest
+	if err := json.Unmarshal(upstream.Body, &req); err != nil {
+		t.Fatalf("Failed to unmarshal Ollama request: %v", err)
+	}
+
+	roles := make([]string, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		roles = append(roles, m.Role)
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,tool" {
+		t.Fatalf("Unexpected message roles: %v\n%s", roles, string(upstream.Body))
+	}
+	if user := req.Messages[1]; user.Content != "What is in this image?" || len(user.Images) != 1 || user.Images[0] != "iVBORw0KGgo=" {
+		t.Errorf("Expected text and base64 image without data URL prefix, got %+v", user)
+	}
+	call := req.Messages[2].ToolCalls
+	if len(call) != 1 || call[0].Function.Name != "Read" || call[0].Function.Arguments["file_path"] != "/tmp/b.go" {
+		t.Errorf("Expected tool call arguments as an object, got %+v", call)
+	}
+	if tool := req.Messages[3]; tool.ToolName != "Read" || tool.Content != "package main" {
+		t.Errorf("Expected tool result with tool_name, got %+v", tool)
+	}
+
+	if len(req.Tools) != 1 || req.Tools[0].Function.Name != "Read" {
+		t.Errorf("Expected Read tool, got %+v", req.Tools)
+	}
+	if req.Options["num_ctx"] != float64(32768) || req.Options["temperature"] != 0.2 ||
+		req.Options["top_k"] != float64(40) || req.Options["num_predict"] != float64(1024) {
+		t.Errorf("Unexpected options (request sampling parameters should override endpoint defaults): %v", req.Options)
+	}
+	if req.Think == nil || !*req.Think {
+		t.Errorf("Expected think to be enabled for thinking requests")
+	}
+	if string(req.KeepAlive) != `"30m"` {
+		t.Errorf("Expected keep_alive 30m, got %s", string(req.KeepAlive))
+	}
+}
+
+func TestOllamaAdapter_NonStreamingDefaults(t *testing.T) {
+	adapter := NewOllamaAdapter(&EndpointInfo{Type: "ollama", OllamaKeepAlive: "-1"}, getTestLogger())
+	upstream, err := adapter.AdaptRequest([]byte(`{"model":"llama3.2","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function","function":{"name":"Grep","parameters":{}}}],"tool_choice":"none"}`))
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+
+	// Ollama 默认流式响应，非流式请求必须显式发送 stream:false
+	body := string(upstream.Body)
+	if upstream.Streaming || !strings.Contains(body, `"stream":false`) {
+		t.Errorf("Expected explicit stream:false, got %s", body)
+	}
+	if !strings.Contains(body, `"keep_alive":-1`) || strings.Contains(body, `"tools"`) || strings.Contains(body, `"options"`) {
+		t.Errorf("Expected numeric keep_alive, no tools for tool_choice none and no options: %s", body)
+	}
+
+	result, err := adapter.AdaptResponse([]byte(`{"model":"llama3.2","created_at":"2025-06-01T10:00:00Z","message":{"role":"assistant","content":"partial"},"done":true,"done_reason":"length","prompt_eval_count":5,"eval_count":7}`), false)
+	if err != nil {
+		t.Fatalf("AdaptResponse failed: %v", err)
+	}
+	var resp OpenAIResponse
+	if err := json.Unmarshal(result, &resp); err != nil {
+		t.Fatalf("Failed to unmarshal chat completion: %v", err)
+	}
+	if resp.Choices[0].FinishReason != "length" || resp.Usage == nil || resp.Usage.TotalTokens != 12 {
+		t.Errorf("Unexpected chat completion: %s", string(result))
+	}
+
+	if _, err := adapter.AdaptResponse([]byte(`{"error":"model 'llama3.2' not found"}`), false); err == nil {
+		t.Errorf("Expected Ollama error response to return an error")
+	}
+}
+
+func TestOllamaAdapter_StreamingAgainstStubServer(t *testing.T) {
+	chatBody, ctx, adapter := convertForOllama(t, ollamaAnthropicRequest)
+	upstream, err := adapter.AdaptRequest(chatBody)
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Unexpected upstream path: %s", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req OllamaChatRequest
+		if err := json.Unmarshal(body, &req); err != nil || !req.Stream {
+			t.Errorf("Stub received invalid Ollama request: %v %s", err, string(body))
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for _, line := range strings.SplitAfter(recordedOllamaNDJSON, "\n") {
+			io.WriteString(w, line)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+upstream.Path, "application/json", bytes.NewReader(upstream.Body))
+	if err != nil {
+		t.Fatalf("Request to stub server failed: %v", err)
+	}
+	defer resp.Body.Close()
+	ollamaBody, _ := io.ReadAll(resp.Body)
+
+	chatSSE, err := adapter.AdaptResponse(ollamaBody, upstream.Streaming)
+	if err != nil {
+		t.Fatalf("AdaptResponse failed: %v", err)
+	}
+	chat := string(chatSSE)
+	if !strings.Contains(chat, `"finish_reason":"tool_calls"`) || !strings.HasSuffix(chat, "data: [DONE]\n\n") {
+		t.Errorf("Expected tool_calls finish and [DONE]: %s", chat)
+	}
+	if !strings.Contains(chat, `"prompt_tokens":42`) || !strings.Contains(chat, `"completion_tokens":17`) {
+		t.Errorf("Expected usage from prompt_eval_count/eval_count: %s", chat)
+	}
+
+	anthropicSSE, err := NewResponseConverter(getTestLogger()).Convert(chatSSE, ctx, true)
+	if err != nil {
+		t.Fatalf("OpenAI -> Anthropic conversion failed: %v", err)
+	}
+	result := string(anthropicSSE)
+	for _, expected := range []string{
+		`"thinking":"Need the file."`,
+		`"text":"Let me read the file."`,
+		`"type":"tool_use"`,
+		`"name":"Read"`,
+		`"stop_reason":"tool_use"`,
+		`"output_tokens":17`,
+		"event: message_stop",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %s in Anthropic SSE:\n%s", expected, result)
+		}
+	}
+}
+
+func TestOllamaAdapter_TruncatedStream(t *testing.T) {
+	adapter := NewOllamaAdapter(&EndpointInfo{Type: "ollama"}, getTestLogger())
+	lines := strings.SplitAfter(recordedOllamaNDJSON, "\n")
+	result, err := adapter.AdaptResponse([]byte(strings.Join(lines[:3], "")), true)
+	if err != nil {
+		t.Fatalf("AdaptResponse failed: %v", err)
+	}
+
+	// 没有 done 时不补齐结束标记，交给响应校验识别为不完整的流
+	if strings.Contains(string(result), "[DONE]") || strings.Contains(string(result), `"finish_reason":"`) {
+		t.Errorf("Truncated stream should not be completed: %s", string(result))
+	}
+
+	if _, err := adapter.AdaptResponse([]byte("{\"error\":\"out of memory\"}\n"), true); err == nil {
+		t.Errorf("Expected error line to return an error")
+	}
+}
+
+func TestParseOllamaModels(t *testing.T) {
+	models, err := ParseOllamaModels([]byte(`{"models":[{"name":"qwen3:14b","model":"qwen3:14b","size":9276198565},{"name":"llama3.2:latest","model":"llama3.2:latest"}]}`))
+	if err != nil {
+		t.Fatalf("ParseOllamaModels failed: %v", err)
+	}
+	if strings.Join(models, ",") != "llama3.2:latest,qwen3:14b" {
+		t.Errorf("Unexpected models: %v", models)
+	}
+}
@@ -0,0 +1,57 @@
+package conversion
+
+import "encoding/json"
+
+// Ollama /api/chat 与 /api/tags 结构定义（仅包含代理用到的字段）
+
+// OllamaChatRequest /api/chat 请求
+type OllamaChatRequest struct {
+	Model     string                 `json:"model"`
+	Messages  []OllamaMessage        `json:"messages"`
+	Tools     []OpenAITool           `json:"tools,omitempty"`
+	Stream    bool                   `json:"stream"` // Ollama 默认流式，必须显式设置
+	Think     *bool                  `json:"think,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	KeepAlive json.RawMessage        `json:"keep_alive,omitempty"` // "5m" 形式的时长或秒数
+}
+
+// OllamaMessage 对话消息，图片为不带 data URL 前缀的 base64
+type OllamaMessage struct {
+	Role      string           `json:"role"` // "system" | "user" | "assistant" | "tool"
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // tool 消息对应的函数名
+}
+
+// OllamaToolCall 工具调用，参数为 JSON 对象而不是字符串
+type OllamaToolCall struct {
+	Function OllamaToolCallFunction `json:"function"`
+}
+
+// OllamaToolCallFunction 工具调用的函数名与参数
+type OllamaToolCallFunction struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// OllamaChatResponse /api/chat 响应（非流式响应体，或 NDJSON 流中的一行）
+type OllamaChatResponse struct {
+	Model           string         `json:"model"`
+	CreatedAt       string         `json:"created_at"`
+	Message         *OllamaMessage `json:"message,omitempty"`
+	Done            bool           `json:"done"`
+	DoneReason      string         `json:"done_reason,omitempty"` // stop | length | load
+	PromptEvalCount int            `json:"prompt_eval_count,omitempty"`
+	EvalCount       int            `json:"eval_count,omitempty"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// OllamaTagsResponse /api/tags 响应
+type OllamaTagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+}
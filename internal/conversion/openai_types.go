@@ -18,6 +18,7 @@ type OpenAIRequest struct {
 	Stop                []string    `json:"stop,omitempty"`
 	User                string      `json:"user,omitempty"`
 	ParallelToolCalls   *bool       `json:"parallel_tool_calls,omitempty"`
+	TopK                *int        `json:"top_k,omitempty"` // 仅 gemini、ollama 等支持 top_k 的原生协议端点保留
 	// 推理相关字段 (o1 模型)
 	ReasoningEffort     *string     `json:"reasoning_effort,omitempty"`     // "low"|"medium"|"high" 推理强度
 	MaxReasoningTokens  *int        `json:"max_reasoning_tokens,omitempty"` // 推理阶段的最大 token 数
@@ -31,6 +32,7 @@ type OpenAIMessage struct {
 	ToolCallID string `json:"tool_call_id,omitempty"`
 	// 仅 assistant 会用到
 	ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"` // 推理内容（Responses API 推理摘要等），转换为 Anthropic thinking 块
 }
 
 // OpenAIMessageContent 复合内容：text / image_url
@@ -0,0 +1,135 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParallelToolCallsMapping(t *testing.T) {
+	converter := NewRequestConverter(getTestLogger())
+	tools := `"tools":[{"name":"Read","input_schema":{"type":"object","properties":{}}}]`
+
+	testCases := []struct {
+		name     string
+		request  string
+		expected *bool
+	}{
+		{
+			name:     "not specified",
+			request:  `{"model":"m","max_tokens":10,` + tools + `,"messages":[{"role":"user","content":"hi"}]}`,
+			expected: nil,
+		},
+		{
+			name:     "top-level disable_parallel_tool_use",
+			request:  `{"model":"m","max_tokens":10,` + tools + `,"disable_parallel_tool_use":true,"messages":[{"role":"user","content":"hi"}]}`,
+			expected: boolPtr(false),
+		},
+		{
+			name:     "tool_choice disable_parallel_tool_use",
+			request:  `{"model":"m","max_tokens":10,` + tools + `,"tool_choice":{"type":"auto","disable_parallel_tool_use":true},"messages":[{"role":"user","content":"hi"}]}`,
+			expected: boolPtr(false),
+		},
+		{
+			name:     "tool_choice explicitly allows parallel calls",
+			request:  `{"model":"m","max_tokens":10,` + tools + `,"disable_parallel_tool_use":true,"tool_choice":{"type":"any","disable_parallel_tool_use":false},"messages":[{"role":"user","content":"hi"}]}`,
+			expected: boolPtr(true),
+		},
+		{
+			name:     "ignored without tools",
+			request:  `{"model":"m","max_tokens":10,"disable_parallel_tool_use":true,"messages":[{"role":"user","content":"hi"}]}`,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _, err := converter.Convert([]byte(tc.request), &EndpointInfo{Type: "openai"})
+			if err != nil {
+				t.Fatalf("Conversion failed: %v", err)
+			}
+
+			var oaReq OpenAIRequest
+			if err := json.Unmarshal(result, &oaReq); err != nil {
+				t.Fatalf("Failed to unmarshal result: %v", err)
+			}
+
+			switch {
+			case tc.expected == nil && oaReq.ParallelToolCalls != nil:
+				t.Errorf("Expected parallel_tool_calls to be omitted, got %v", *oaReq.ParallelToolCalls)
+			case tc.expected != nil && (oaReq.ParallelToolCalls == nil || *oaReq.ParallelToolCalls != *tc.expected):
+				t.Errorf("Expected parallel_tool_calls=%v, got %v", *tc.expected, oaReq.ParallelToolCalls)
+			}
+		})
+	}
+}
+
+func TestEnforceSingleToolCall(t *testing.T) {
+	converter := NewResponseConverter(getTestLogger())
+
+	nonStreaming, _ := json.Marshal(OpenAIResponse{
+		ID:    "chatcmpl-parallel",
+		Model: "gpt-4",
+		Choices: []OpenAIChoice{{
+			FinishReason: "tool_calls",
+			Message: OpenAIMessage{
+				Role: "assistant",
+				ToolCalls: []OpenAIToolCall{
+					{ID: "call_1", Type: "function", Function: OpenAIToolCallDetail{Name: "Read", Arguments: `{}`}},
+					{ID: "call_2", Type: "function", Function: OpenAIToolCallDetail{Name: "Grep", Arguments: `{}`}},
+				},
+			},
+		}},
+	})
+	streaming := []byte(`data: {"id":"chatcmpl-parallel","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"Read","arguments":"{}"}}]}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-parallel","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"Grep","arguments":"{}"}}]}}]}` + "\n\n" +
+		`data: {"id":"chatcmpl-parallel","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n" +
+		"data: [DONE]\n\n")
+
+	testCases := []struct {
+		name          string
+		disabled      bool
+		enforce       bool
+		expectedCalls int
+	}{
+		{"parallel allowed", false, true, 2},
+		{"disabled but not enforced", true, false, 2},
+		{"disabled and enforced", true, true, 1},
+	}
+
+	for _, tc := range testCases {
+		for _, isStreaming := range []bool{false, true} {
+			body := nonStreaming
+			name := tc.name + "/non-streaming"
+			if isStreaming {
+				body = streaming
+				name = tc.name + "/streaming"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				ctx := &ConversionContext{DisableParallelToolUse: tc.disabled, EnforceSingleToolCall: tc.enforce}
+				result, err := converter.Convert(body, ctx, isStreaming)
+				if err != nil {
+					t.Fatalf("Conversion failed: %v", err)
+				}
+
+				calls := strings.Count(string(result), `"type":"tool_use"`)
+				if calls != tc.expectedCalls {
+					t.Errorf("Expected %d tool_use blocks, got %d: %s", tc.expectedCalls, calls, string(result))
+				}
+				if !strings.Contains(string(result), `"id":"call_1"`) {
+					t.Errorf("Expected the first tool call to be kept: %s", string(result))
+				}
+
+				// 被丢弃的工具调用需要记录下来，不能静默截断
+				if tc.expectedCalls == 1 {
+					if len(ctx.ToolArgumentRepairs) != 1 || !strings.HasPrefix(ctx.ToolArgumentRepairs[0], "Grep(call_2) dropped") {
+						t.Errorf("Expected the dropped tool call to be recorded, got %v", ctx.ToolArgumentRepairs)
+					}
+				} else if len(ctx.ToolArgumentRepairs) != 0 {
+					t.Errorf("Expected no records when nothing is dropped, got %v", ctx.ToolArgumentRepairs)
+				}
+			})
+		}
+	}
+}
@@ -36,6 +36,11 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 		IsStreaming:    anthReq.Stream != nil && *anthReq.Stream,
 		RequestHeaders: make(map[string]string),
 		StopSequences:  anthReq.StopSequences,
+		ToolSchemas:    make(map[string]map[string]interface{}),
+		ToolNames:      NewToolNameMapper(),
+	}
+	if endpointInfo != nil {
+		ctx.ToolArgumentValidation = endpointInfo.ToolArgumentValidation
 	}
 
 	// 构建 OpenAI 请求
@@ -46,6 +51,10 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 	// 温控映射
 	out.Temperature = anthReq.Temperature
 	out.TopP = anthReq.TopP
+	// top_k 只有原生协议适配器能传递给上游，OpenAI 兼容端点会拒绝该字段
+	if endpointInfo != nil && (endpointInfo.Type == "gemini" || endpointInfo.Type == "ollama") {
+		out.TopK = anthReq.TopK
+	}
 	
 	// 根据端点配置处理 max_tokens 字段名转换
 	if endpointInfo != nil && endpointInfo.MaxTokensFieldName != "" {
@@ -74,13 +83,19 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 	}
 
 	// 工具映射
+	schemaProfile := ""
+	if endpointInfo != nil {
+		schemaProfile = endpointInfo.ToolSchemaProfile
+	}
 	for _, t := range anthReq.Tools {
+		ctx.ToolSchemas[t.Name] = t.InputSchema
 		out.Tools = append(out.Tools, OpenAITool{
 			Type: "function",
 			Function: OpenAIFunctionDef{
-				Name:        t.Name,
+				Name:        ctx.ToolNames.Map(t.Name),
 				Description: t.Description,
-				Parameters:  t.InputSchema, // JSON Schema 原样给到 parameters
+				// JSON Schema 按端点档位规范化后给到 parameters；工具调用的 arguments 原样返回
+				Parameters: SanitizeToolSchema(t.InputSchema, schemaProfile),
 			},
 		})
 	}
@@ -101,7 +116,7 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 				out.ToolChoice = map[string]interface{}{
 					"type": "function",
 					"function": map[string]interface{}{
-						"name": anthReq.ToolChoice.Name,
+						"name": ctx.ToolNames.Map(anthReq.ToolChoice.Name),
 					},
 				}
 			default:
@@ -240,6 +255,7 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 
 		case "assistant":
 			// assistant 可以包含：text + tool_use（一个或多个）
+			// thinking / redacted_thinking 块不回传：Chat Completions 没有对应的输入字段，签名也只对 Anthropic 有效
 			// 使用新的 GetContentBlocks 方法获取内容块
 			contentBlocks := m.GetContentBlocks()
 			var textParts []string
@@ -276,7 +292,7 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 					ID:   tu.ID, // 用原始 id，便于和后续 tool_result 对齐
 					Type: "function",
 					Function: OpenAIToolCallDetail{
-						Name:      tu.Name,
+						Name:      ctx.ToolNames.Map(tu.Name),
 						Arguments: args,
 					},
 				}
@@ -292,9 +308,14 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 		}
 	}
 
-	// 处理并行工具调用设置
-	if anthReq.DisableParallelToolUse != nil && *anthReq.DisableParallelToolUse {
-		out.ParallelToolCalls = boolPtr(false)
+	// 处理并行工具调用设置：兼容顶层 disable_parallel_tool_use 和新版 tool_choice.disable_parallel_tool_use
+	// parallel_tool_calls 只在有工具时才有意义，部分端点在没有 tools 时会拒绝该参数
+	if disable := anthropicDisableParallelToolUse(&anthReq); disable != nil && len(anthReq.Tools) > 0 {
+		out.ParallelToolCalls = boolPtr(!*disable)
+		ctx.DisableParallelToolUse = *disable
+		if endpointInfo != nil {
+			ctx.EnforceSingleToolCall = endpointInfo.EnforceSingleToolCall
+		}
 	}
 
 	// 处理 thinking 模式转换为 OpenAI 推理模式
@@ -326,9 +347,14 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 
 	// 记录忽略的字段
 	if c.logger != nil {
-		if anthReq.TopK != nil {
+		if anthReq.TopK != nil && out.TopK == nil {
 			c.logger.Debug("Ignoring top_k field (not supported by OpenAI)")
 		}
+		if mappings := ctx.ToolNames.Mappings(); len(mappings) > 0 {
+			c.logger.Debug("Mapped tool names for upstream function-name rules", map[string]interface{}{
+				"mappings": mappings,
+			})
+		}
 	}
 
 	// 序列化结果
@@ -344,6 +370,14 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 	return result, ctx, nil
 }
 
+// anthropicDisableParallelToolUse 读取禁用并行工具调用的设置，tool_choice 中的新版写法优先
+func anthropicDisableParallelToolUse(req *AnthropicRequest) *bool {
+	if req.ToolChoice != nil && req.ToolChoice.DisableParallelToolUse != nil {
+		return req.ToolChoice.DisableParallelToolUse
+	}
+	return req.DisableParallelToolUse
+}
+
 // boolPtr 返回bool指针
 func boolPtr(b bool) *bool {
 	return &b
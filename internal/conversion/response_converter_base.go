@@ -1,6 +1,8 @@
 package conversion
 
 import (
+	"fmt"
+
 	"claude-code-codex-companion/internal/logger"
 )
 
@@ -16,4 +18,30 @@ func NewResponseConverter(logger *logger.Logger) *ResponseConverter {
 		logger:    logger,
 		sseParser: NewSSEParser(logger),
 	}
-}
\ No newline at end of file
+}
+
+// enforceSingleToolCall 客户端禁用并行工具调用但端点忽略该参数时，只保留第一个工具调用
+// 被丢弃的工具调用逐个写入 ctx.ToolArgumentRepairs，随请求日志展示；toolCallAt 返回第 i 个工具调用的名称和 ID
+// 返回应保留的工具调用数量
+func (c *ResponseConverter) enforceSingleToolCall(ctx *ConversionContext, toolCallCount int, toolCallAt func(i int) (string, string)) int {
+	if ctx == nil || !ctx.DisableParallelToolUse || !ctx.EnforceSingleToolCall || toolCallCount <= 1 {
+		return toolCallCount
+	}
+
+	dropped := make([]string, 0, toolCallCount-1)
+	for i := 1; i < toolCallCount; i++ {
+		name, id := toolCallAt(i)
+		desc := fmt.Sprintf("%s(%s)", ctx.restoreToolName(name), id)
+		dropped = append(dropped, desc)
+		ctx.ToolArgumentRepairs = append(ctx.ToolArgumentRepairs, desc+" dropped to enforce disable_parallel_tool_use")
+	}
+
+	if c.logger != nil {
+		c.logger.Info("Dropping extra tool calls to enforce disable_parallel_tool_use", map[string]interface{}{
+			"returned_tool_calls": toolCallCount,
+			"kept_tool_calls":     1,
+			"dropped_tool_calls":  dropped,
+		})
+	}
+	return 1
+}
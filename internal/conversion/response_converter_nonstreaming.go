@@ -24,15 +24,11 @@ func (c *ResponseConverter) convertNonStreamingResponse(openaiResp []byte, ctx *
 	msg := choice.Message
 	var blocks []AnthropicContentBlock
 
-	// 文本
+	// 提取文本
+	var text string
 	switch ct := msg.Content.(type) {
 	case string:
-		if strings.TrimSpace(ct) != "" {
-			blocks = append(blocks, AnthropicContentBlock{
-				Type: "text",
-				Text: ct,
-			})
-		}
+		text = ct
 	case []interface{}:
 		// 如果上游返回了多模态数组（少见），这里只抽取 text
 		b, _ := json.Marshal(ct)
@@ -44,41 +40,61 @@ func (c *ResponseConverter) convertNonStreamingResponse(openaiResp []byte, ctx *
 					sb.WriteString(p.Text)
 				}
 			}
-			if s := strings.TrimSpace(sb.String()); s != "" {
-				blocks = append(blocks, AnthropicContentBlock{
-					Type: "text",
-					Text: s,
-				})
-			}
+			text = strings.TrimSpace(sb.String())
 		}
 	}
 
+	// 转换 OpenAI finish_reason 到 Anthropic stop_reason（含停止序列识别）
+	var stopSequences []string
+	if ctx != nil {
+		stopSequences = ctx.StopSequences
+	}
+	stopReason, stopSequence, text := resolveStopReason(choice.FinishReason, text, stopSequences)
+
+	// 推理内容（位于文本之前）
+	if strings.TrimSpace(msg.ReasoningContent) != "" {
+		blocks = append(blocks, AnthropicContentBlock{
+			Type:     "thinking",
+			Thinking: msg.ReasoningContent,
+		})
+	}
+
+	// 文本
+	if strings.TrimSpace(text) != "" {
+		blocks = append(blocks, AnthropicContentBlock{
+			Type: "text",
+			Text: text,
+		})
+	}
+
 	// 工具调用
-	for _, tc := range msg.ToolCalls {
+	toolCalls := msg.ToolCalls[:c.enforceSingleToolCall(ctx, len(msg.ToolCalls), func(i int) (string, string) {
+		return msg.ToolCalls[i].Function.Name, msg.ToolCalls[i].ID
+	})]
+	for _, tc := range toolCalls {
+		// 还原请求时映射过的工具名
+		name := ctx.restoreToolName(tc.Function.Name)
+		// 按工具声明的 input_schema 校验并修复参数
+		arguments, err := c.validateToolArguments(ctx, name, tc.ID, tc.Function.Arguments)
+		if err != nil {
+			return nil, err
+		}
 		blocks = append(blocks, AnthropicContentBlock{
 			Type: "tool_use",
 			ID:   tc.ID,
-			Name: tc.Function.Name,
+			Name: name,
 			// OpenAI.arguments 是 JSON 字符串；Anthropic.input 是原生 JSON
-			Input: json.RawMessage(tc.Function.Arguments),
+			Input: json.RawMessage(arguments),
 		})
 	}
 
-	// 转换 OpenAI finish_reason 到 Anthropic stop_reason
-	stopReason := "end_turn" // 默认值
-	if choice.FinishReason == "tool_calls" {
-		stopReason = "tool_use"
-	} else if choice.FinishReason == "length" {
-		stopReason = "max_tokens"
-	}
-	// 其他情况（包括 "stop"）都映射为 "end_turn"
-
 	out := AnthropicResponse{
 		Type:       "message",
 		Role:       "assistant",
 		Model:      in.Model,
 		Content:    blocks,
-		StopReason: stopReason,
+		StopReason:   stopReason,
+		StopSequence: stopSequence,
 	}
 	if in.Usage != nil {
 		out.Usage = &AnthropicUsage{
@@ -14,19 +14,38 @@ func (c *ResponseConverter) convertStreamingResponseRefactored(openaiResp []byte
 
 	// 2. Aggregate message
 	aggregator := NewMessageAggregator(c.logger)
+	if ctx != nil {
+		aggregator.SetStopSequences(ctx.StopSequences)
+	}
 	aggregatedMsg, err := aggregator.AggregateChunks(chunks)
 	if err != nil {
 		return nil, NewConversionError("aggregation_error", "Failed to aggregate chunks", err)
 	}
 
-	// 3. Unified conversion
+	// 3. Enforce single tool call when parallel tool use is disabled but ignored by the endpoint
+	aggregatedMsg.ToolCalls = aggregatedMsg.ToolCalls[:c.enforceSingleToolCall(ctx, len(aggregatedMsg.ToolCalls), func(i int) (string, string) {
+		return aggregatedMsg.ToolCalls[i].Name, aggregatedMsg.ToolCalls[i].ID
+	})]
+
+	// 4. Restore mapped tool names, then validate and repair tool arguments against the declared input_schema
+	for i := range aggregatedMsg.ToolCalls {
+		toolCall := &aggregatedMsg.ToolCalls[i]
+		toolCall.Name = ctx.restoreToolName(toolCall.Name)
+		arguments, err := c.validateToolArguments(ctx, toolCall.Name, toolCall.ID, toolCall.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		toolCall.Arguments = arguments
+	}
+
+	// 5. Unified conversion
 	converter := NewUnifiedConverter(c.logger)
 	result, err := converter.ConvertAggregatedMessage(aggregatedMsg)
 	if err != nil {
 		return nil, NewConversionError("conversion_error", "Failed to convert aggregated message", err)
 	}
 
-	// 4. Build SSE output
+	// 6. Build SSE output
 	sseOutput := c.sseParser.BuildAnthropicSSEFromEvents(result.Events)
 
 	if c.logger != nil {
@@ -0,0 +1,457 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+// OpenAIAPIResponses openai 端点的 openai_api 取值：使用 Responses API 作为上游协议
+const OpenAIAPIResponses = "responses"
+
+// ResponsesAdapter 在 OpenAI Chat Completions 与 Responses API 之间转换
+// 用于只开放 /responses 的 OpenAI 端点（如仅支持 Responses API 的模型），
+// Claude Code 请求先按 OpenAI 端点转换为 Chat Completions，再由适配器转换为 Responses 请求；
+// 原生 Codex 请求本身就是 Responses 格式，请求与响应均原样透传
+type ResponsesAdapter struct {
+	logger *logger.Logger
+
+	model       string // 请求的模型名，用于填充响应
+	passthrough bool   // 请求已是 Responses 格式
+}
+
+// NewResponsesAdapter 创建 Responses API 上游适配器
+func NewResponsesAdapter(endpointInfo *EndpointInfo, logger *logger.Logger) *ResponsesAdapter {
+	return &ResponsesAdapter{logger: logger}
+}
+
+// AdaptRequest 将 Chat Completions 请求转换为 Responses API 请求
+func (a *ResponsesAdapter) AdaptRequest(body []byte) (*UpstreamRequest, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse request for Responses API conversion", err)
+	}
+
+	// Responses API 请求使用 input 字段，Chat Completions 使用 messages
+	_, hasMessages := raw["messages"]
+	_, hasInput := raw["input"]
+	if hasInput && !hasMessages {
+		a.passthrough = true
+		var streaming bool
+		if stream, ok := raw["stream"]; ok {
+			json.Unmarshal(stream, &streaming)
+		}
+		return &UpstreamRequest{Path: "/responses", Query: url.Values{}, Body: body, Streaming: streaming}, nil
+	}
+
+	var chatReq compatChatRequest
+	if err := json.Unmarshal(body, &chatReq); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse request for Responses API conversion", err)
+	}
+	a.model = chatReq.Model
+
+	store := false
+	out := ResponsesRequest{
+		Model:             chatReq.Model,
+		Input:             []ResponsesInputItem{},
+		ParallelToolCalls: chatReq.ParallelToolCalls,
+		Temperature:       chatReq.Temperature,
+		TopP:              chatReq.TopP,
+		Stream:            chatReq.Stream,
+		Store:             &store, // 代理每次都发送完整上下文，不需要上游保存会话
+		User:              chatReq.User,
+	}
+
+	// 消息映射：system/developer -> instructions，assistant 工具调用 -> function_call，tool -> function_call_output
+	var instructions []string
+	for _, m := range chatReq.Messages {
+		switch m.Role {
+		case "system", "developer":
+			if text := chatContentText(m.Content); text != "" {
+				instructions = append(instructions, text)
+			}
+		case "assistant":
+			if text := chatContentText(m.Content); text != "" {
+				out.Input = append(out.Input, ResponsesInputItem{
+					Type:    "message",
+					Role:    "assistant",
+					Content: []ResponsesContentPart{{Type: "output_text", Text: text}},
+				})
+			}
+			for _, tc := range m.ToolCalls {
+				arguments := tc.Function.Arguments
+				if strings.TrimSpace(arguments) == "" {
+					arguments = "{}"
+				}
+				out.Input = append(out.Input, ResponsesInputItem{
+					Type:      "function_call",
+					CallID:    tc.ID,
+					Name:      tc.Function.Name,
+					Arguments: arguments,
+				})
+			}
+		case "tool":
+			output := chatContentText(m.Content)
+			out.Input = append(out.Input, ResponsesInputItem{
+				Type:   "function_call_output",
+				CallID: m.ToolCallID,
+				Output: &output,
+			})
+		default:
+			if parts := responsesInputParts(m.Content); len(parts) > 0 {
+				out.Input = append(out.Input, ResponsesInputItem{Type: "message", Role: "user", Content: parts})
+			}
+		}
+	}
+	out.Instructions = strings.Join(instructions, "\n\n")
+
+	// 工具声明：Responses API 使用扁平结构
+	for _, t := range chatReq.Tools {
+		tool := ResponsesTool{Type: "function", Name: t.Name, Description: t.Description, Parameters: t.Parameters}
+		if t.Function != nil {
+			tool = ResponsesTool{Type: "function", Name: t.Function.Name, Description: t.Function.Description, Parameters: t.Function.Parameters}
+		}
+		if tool.Name == "" {
+			continue
+		}
+		out.Tools = append(out.Tools, tool)
+	}
+	if len(out.Tools) > 0 {
+		out.ToolChoice = responsesToolChoice(chatReq.ToolChoice)
+	}
+
+	for _, maxTokens := range []*int{chatReq.MaxOutputTokens, chatReq.MaxCompletionTokens, chatReq.MaxTokens} {
+		if maxTokens != nil {
+			out.MaxOutputTokens = maxTokens
+			break
+		}
+	}
+
+	// 请求推理摘要，以便转换为 Anthropic thinking 块
+	effort := ""
+	if chatReq.ReasoningEffort != nil {
+		effort = *chatReq.ReasoningEffort
+	} else if chatReq.Reasoning != nil {
+		effort = chatReq.Reasoning.Effort
+	}
+	if effort != "" {
+		out.Reasoning = &ResponsesReasoning{Effort: effort, Summary: "auto"}
+	}
+
+	converted, err := json.Marshal(out)
+	if err != nil {
+		return nil, NewConversionError("marshal_error", "Failed to marshal Responses API request", err)
+	}
+
+	return &UpstreamRequest{
+		Path:      "/responses",
+		Query:     url.Values{},
+		Body:      converted,
+		Streaming: chatReq.Stream != nil && *chatReq.Stream,
+	}, nil
+}
+
+// AdaptResponse 将 Responses API 响应转换为 Chat Completions 响应（流式时为 chat.completion.chunk SSE）
+func (a *ResponsesAdapter) AdaptResponse(body []byte, isStreaming bool) ([]byte, error) {
+	if a.passthrough {
+		return body, nil
+	}
+	if !isStreaming {
+		var resp ResponsesResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, NewConversionError("parse_error", "Failed to parse Responses API response", err)
+		}
+		return a.convertResponse(&resp)
+	}
+	return a.convertStream(body)
+}
+
+func (a *ResponsesAdapter) convertResponse(resp *ResponsesResponse) ([]byte, error) {
+	if resp.Status == "failed" {
+		return nil, responsesUpstreamError(resp.Error)
+	}
+
+	var text, reasoning strings.Builder
+	var toolCalls []OpenAIToolCall
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "reasoning":
+			for _, part := range item.Summary {
+				reasoning.WriteString(part.Text)
+			}
+		case "message":
+			for _, part := range item.Content {
+				if part.Type == "output_text" {
+					text.WriteString(part.Text)
+				}
+			}
+		case "function_call":
+			toolCalls = append(toolCalls, responsesFunctionCallToToolCall(&item, len(toolCalls)))
+		}
+	}
+
+	message := map[string]interface{}{"role": "assistant", "content": text.String()}
+	if reasoning.Len() > 0 {
+		message["reasoning_content"] = reasoning.String()
+	}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+	}
+
+	out := map[string]interface{}{
+		"id":      a.responseID(resp.ID),
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   a.responseModel(resp.Model),
+		"choices": []interface{}{map[string]interface{}{
+			"index":         0,
+			"message":       message,
+			"finish_reason": MapStopReasonToFinishReason(resp.stopReason(len(toolCalls) > 0)),
+		}},
+	}
+	if usage := responsesUsageToOpenAI(resp.Usage); usage != nil {
+		out["usage"] = usage
+	}
+	return json.Marshal(out)
+}
+
+func (a *ResponsesAdapter) convertStream(body []byte) ([]byte, error) {
+	var out bytes.Buffer
+	var id, model string
+	created := time.Now().Unix()
+	started := false
+	finished := false
+
+	// output_index -> 工具调用序号（Chat Completions 的 tool_calls[].index 从 0 连续编号）
+	toolCallIndexes := map[int]int{}
+
+	writeChunk := func(delta map[string]interface{}, finish interface{}, usage *OpenAIUsage) {
+		chunk := map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []interface{}{map[string]interface{}{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finish,
+			}},
+		}
+		if usage != nil {
+			chunk["usage"] = usage
+		}
+		data, _ := json.Marshal(chunk)
+		out.WriteString("data: ")
+		out.Write(data)
+		out.WriteString("\n\n")
+	}
+	start := func(resp *ResponsesResponse) {
+		if started {
+			return
+		}
+		started = true
+		responseID, responseModel := "", ""
+		if resp != nil {
+			responseID, responseModel = resp.ID, resp.Model
+		}
+		id = a.responseID(responseID)
+		model = a.responseModel(responseModel)
+		writeChunk(map[string]interface{}{"role": "assistant", "content": ""}, nil, nil)
+	}
+
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+
+		var event ResponsesStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, NewConversionError("sse_parse_error", "Failed to parse Responses API SSE event", err)
+		}
+
+		switch event.Type {
+		case "response.created", "response.in_progress":
+			start(event.Response)
+		case "response.output_item.added":
+			start(nil)
+			if event.Item == nil || event.Item.Type != "function_call" {
+				continue
+			}
+			index := len(toolCallIndexes)
+			toolCallIndexes[event.OutputIndex] = index
+			callID := event.Item.CallID
+			if callID == "" {
+				callID = newToolCallID()
+			}
+			writeChunk(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+				"index":    index,
+				"id":       callID,
+				"type":     "function",
+				"function": map[string]interface{}{"name": event.Item.Name, "arguments": event.Item.Arguments},
+			}}}, nil, nil)
+		case "response.function_call_arguments.delta":
+			index, ok := toolCallIndexes[event.OutputIndex]
+			if !ok || event.Delta == "" {
+				continue
+			}
+			writeChunk(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+				"index":    index,
+				"function": map[string]interface{}{"arguments": event.Delta},
+			}}}, nil, nil)
+		case "response.output_text.delta":
+			start(nil)
+			if event.Delta != "" {
+				writeChunk(map[string]interface{}{"content": event.Delta}, nil, nil)
+			}
+		case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+			start(nil)
+			if event.Delta != "" {
+				writeChunk(map[string]interface{}{"reasoning_content": event.Delta}, nil, nil)
+			}
+		case "response.completed", "response.incomplete":
+			start(event.Response)
+			if event.Response == nil {
+				continue
+			}
+			finished = true
+			writeChunk(map[string]interface{}{}, MapStopReasonToFinishReason(event.Response.stopReason(len(toolCallIndexes) > 0)), responsesUsageToOpenAI(event.Response.Usage))
+		case "response.failed":
+			var respErr *ResponsesError
+			if event.Response != nil {
+				respErr = event.Response.Error
+			}
+			return nil, responsesUpstreamError(respErr)
+		case "error":
+			return nil, responsesUpstreamError(&ResponsesError{Code: event.Code, Message: event.Message})
+		}
+	}
+
+	if !started {
+		return nil, NewConversionError("empty_stream", "No valid events found in Responses API SSE stream", nil)
+	}
+
+	// 没有 response.completed 说明流被截断，不补 finish_reason/[DONE]，交给响应校验触发重试
+	if finished {
+		out.WriteString("data: [DONE]\n\n")
+	} else if a.logger != nil {
+		a.logger.Debug("Responses API SSE stream ended without response.completed")
+	}
+
+	return out.Bytes(), nil
+}
+
+func (a *ResponsesAdapter) responseID(responseID string) string {
+	if responseID != "" {
+		return "chatcmpl-" + strings.TrimPrefix(responseID, "resp_")
+	}
+	return "chatcmpl-" + strings.TrimPrefix(newToolCallID(), "call_")
+}
+
+func (a *ResponsesAdapter) responseModel(responseModel string) string {
+	if responseModel != "" {
+		return responseModel
+	}
+	return a.model
+}
+
+// responsesInputParts 将用户消息内容转换为 input_text / input_image 片段
+func responsesInputParts(content interface{}) []ResponsesContentPart {
+	items, ok := content.([]interface{})
+	if !ok {
+		if text := chatContentText(content); text != "" {
+			return []ResponsesContentPart{{Type: "input_text", Text: text}}
+		}
+		return nil
+	}
+
+	var parts []ResponsesContentPart
+	for _, item := range items {
+		part, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if text, ok := part["text"].(string); ok && text != "" {
+			parts = append(parts, ResponsesContentPart{Type: "input_text", Text: text})
+			continue
+		}
+		imageURL := ""
+		switch v := part["image_url"].(type) {
+		case string:
+			imageURL = v
+		case map[string]interface{}:
+			imageURL, _ = v["url"].(string)
+		}
+		if imageURL != "" {
+			parts = append(parts, ResponsesContentPart{Type: "input_image", ImageURL: imageURL})
+		}
+	}
+	return parts
+}
+
+// responsesToolChoice 将 Chat Completions 的 tool_choice 转换为 Responses 格式（指定函数时为扁平结构）
+func responsesToolChoice(toolChoice interface{}) interface{} {
+	v, ok := toolChoice.(map[string]interface{})
+	if !ok {
+		return toolChoice
+	}
+	name, _ := v["name"].(string)
+	if function, ok := v["function"].(map[string]interface{}); ok {
+		name, _ = function["name"].(string)
+	}
+	if name == "" {
+		return toolChoice
+	}
+	return map[string]interface{}{"type": "function", "name": name}
+}
+
+func responsesFunctionCallToToolCall(item *ResponsesOutputItem, index int) OpenAIToolCall {
+	id := item.CallID
+	if id == "" {
+		id = newToolCallID()
+	}
+	arguments := item.Arguments
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	return OpenAIToolCall{
+		Index:    index,
+		ID:       id,
+		Type:     "function",
+		Function: OpenAIToolCallDetail{Name: item.Name, Arguments: arguments},
+	}
+}
+
+func responsesUsageToOpenAI(usage *ResponsesUsage) *OpenAIUsage {
+	if usage == nil {
+		return nil
+	}
+	total := usage.TotalTokens
+	if total == 0 {
+		total = usage.InputTokens + usage.OutputTokens
+	}
+	return &OpenAIUsage{
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      total,
+	}
+}
+
+func responsesUpstreamError(respErr *ResponsesError) error {
+	message := "Responses API request failed"
+	if respErr != nil && respErr.Message != "" {
+		message += ": " + respErr.Message
+		if respErr.Code != "" {
+			message += " (" + respErr.Code + ")"
+		}
+	}
+	return NewConversionError("upstream_error", message, nil)
+}
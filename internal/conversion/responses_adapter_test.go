@@ -0,0 +1,260 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// 录制的 Responses API 流式响应：推理摘要 -> 文本 -> 工具调用
+const recordedResponsesSSE = "event: response.created\n" +
+	"data: {\"type\":\"response.created\",\"sequence_number\":0,\"response\":{\"id\":\"resp_abc123\",\"object\":\"response\",\"status\":\"in_progress\",\"model\":\"gpt-5\",\"output\":[]}}\n\n" +
+	"event: response.output_item.added\n" +
+	"data: {\"type\":\"response.output_item.added\",\"sequence_number\":1,\"output_index\":0,\"item\":{\"id\":\"rs_1\",\"type\":\"reasoning\",\"summary\":[]}}\n\n" +
+	"event: response.reasoning_summary_text.delta\n" +
+	"data: {\"type\":\"response.reasoning_summary_text.delta\",\"sequence_number\":2,\"item_id\":\"rs_1\",\"output_index\":0,\"summary_index\":0,\"delta\":\"Need to read \"}\n\n" +
+	"event: response.reasoning_summary_text.delta\n" +
+	"data: {\"type\":\"response.reasoning_summary_text.delta\",\"sequence_number\":3,\"item_id\":\"rs_1\",\"output_index\":0,\"summary_index\":0,\"delta\":\"the file first.\"}\n\n" +
+	"event: response.output_item.added\n" +
+	"data: {\"type\":\"response.output_item.added\",\"sequence_number\":4,\"output_index\":1,\"item\":{\"id\":\"msg_1\",\"type\":\"message\",\"role\":\"assistant\",\"content\":[]}}\n\n" +
+	"event: response.output_text.delta\n" +
+	"data: {\"type\":\"response.output_text.delta\",\"sequence_number\":5,\"item_id\":\"msg_1\",\"output_index\":1,\"content_index\":0,\"delta\":\"Let me read \"}\n\n" +
+	"event: response.output_text.delta\n" +
+	"data: {\"type\":\"response.output_text.delta\",\"sequence_number\":6,\"item_id\":\"msg_1\",\"output_index\":1,\"content_index\":0,\"delta\":\"the file.\"}\n\n" +
+	"event: response.output_item.added\n" +
+	"data: {\"type\":\"response.output_item.added\",\"sequence_number\":7,\"output_index\":2,\"item\":{\"id\":\"fc_1\",\"type\":\"function_call\",\"call_id\":\"call_read_1\",\"name\":\"Read\",\"arguments\":\"\"}}\n\n" +
+	"event: response.function_call_arguments.delta\n" +
+	"data: {\"type\":\"response.function_call_arguments.delta\",\"sequence_number\":8,\"item_id\":\"fc_1\",\"output_index\":2,\"delta\":\"{\\\"file_path\\\":\"}\n\n" +
+	"event: response.function_call_arguments.delta\n" +
+	"data: {\"type\":\"response.function_call_arguments.delta\",\"sequence_number\":9,\"item_id\":\"fc_1\",\"output_index\":2,\"delta\":\"\\\"/tmp/a.go\\\"}\"}\n\n" +
+	"event: response.completed\n" +
+	"data: {\"type\":\"response.completed\",\"sequence_number\":10,\"response\":{\"id\":\"resp_abc123\",\"object\":\"response\",\"status\":\"completed\",\"model\":\"gpt-5\",\"output\":[],\"usage\":{\"input_tokens\":42,\"output_tokens\":31,\"total_tokens\":73}}}\n\n"
+
+const responsesAnthropicRequest = `{
+	"model": "gpt-5",
+	"max_tokens": 2048,
+	"stream": true,
+	"system": "You are a coding assistant.",
+	"thinking": {"type": "enabled", "budget_tokens": 4096},
+	"tools": [{
+		"name": "Read",
+		"description": "Read a file",
+		"input_schema": {"type": "object", "properties": {"file_path": {"type": "string"}}, "required": ["file_path"]}
+	}],
+	"tool_choice": {"type": "tool", "name": "Read"},
+	"messages": [
+		{"role": "user", "content": [
+			{"type": "text", "text": "What is in this image?"},
+			{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+		]},
+		{"role": "assistant", "content": [
+			{"type": "text", "text": "Reading it."},
+			{"type": "tool_use", "id": "toolu_01", "name": "Read", "input": {"file_path": "/tmp/b.go"}}
+		]},
+		{"role": "user", "content": [
+			{"type": "tool_result", "tool_use_id": "toolu_01", "content": "package main"}
+		]}
+	]
+}`
+
+func convertForResponses(t *testing.T, anthropicRequest string) ([]byte, *ConversionContext, UpstreamAdapter) {
+	t.Helper()
+	info := &EndpointInfo{Type: "openai", OpenAIAPI: OpenAIAPIResponses}
+	chatBody, ctx, err := NewRequestConverter(getTestLogger()).Convert([]byte(anthropicRequest), info)
+	if err != nil {
+		t.Fatalf("Anthropic -> OpenAI conversion failed: %v", err)
+	}
+	ctx.EndpointType = info.Type
+	adapter := NewUpstreamAdapter(info, getTestLogger())
+	if _, ok := adapter.(*ResponsesAdapter); !ok {
+		t.Fatalf("Expected ResponsesAdapter for openai_api responses, got %T", adapter)
+	}
+	return chatBody, ctx, adapter
+}
+
+func TestNewUpstreamAdapter_OpenAIChatCompletions(t *testing.T) {
+	for _, openaiAPI := range []string{"", "chat_completions"} {
+		if adapter := NewUpstreamAdapter(&EndpointInfo{Type: "openai", OpenAIAPI: openaiAPI}, nil); adapter != nil {
+			t.Errorf("Expected no adapter for openai_api %q, got %T", openaiAPI, adapter)
+		}
+	}
+}
+
+func TestResponsesAdapter_AdaptRequest(t *testing.T) {
+	chatBody, _, adapter := convertForResponses(t, responsesAnthropicRequest)
+
+	upstream, err := adapter.AdaptRequest(chatBody)
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+	if upstream.Path != "/responses" || !upstream.Streaming {
+		t.Errorf("Unexpected upstream request: path=%s streaming=%v", upstream.Path, upstream.Streaming)
+	}
+
+	var req ResponsesRequest
+	if err := json.Unmarshal(upstream.Body, &req); err != nil {
+		t.Fatalf("Failed to unmarshal Responses request: %v", err)
+	}
+
+	if req.Model != "gpt-5" || req.Instructions != "You are a coding assistant." {
+		t.Errorf("Unexpected model/instructions: %s %q", req.Model, req.Instructions)
+	}
+	if req.Store == nil || *req.Store {
+		t.Errorf("Expected store=false, got %v", req.Store)
+	}
+	if req.MaxOutputTokens == nil || *req.MaxOutputTokens != 2048 {
+		t.Errorf("Expected max_output_tokens 2048, got %v", req.MaxOutputTokens)
+	}
+	if req.Reasoning == nil || req.Reasoning.Effort == "" || req.Reasoning.Summary != "auto" {
+		t.Errorf("Expected reasoning effort with summary, got %+v", req.Reasoning)
+	}
+
+	types := make([]string, 0, len(req.Input))
+	for _, item := range req.Input {
+		types = append(types, item.Type)
+	}
+	if strings.Join(types, ",") != "message,message,function_call,function_call_output" {
+		t.Fatalf("Unexpected input items: %v\n%s", types, string(upstream.Body))
+	}
+
+	user := req.Input[0]
+	partTypes := map[string]ResponsesContentPart{}
+	for _, part := range user.Content {
+		partTypes[part.Type] = part
+	}
+	if user.Role != "user" || partTypes["input_text"].Text != "What is in this image?" ||
+		partTypes["input_image"].ImageURL != "data:image/png;base64,iVBORw0KGgo=" {
+		t.Errorf("Unexpected user message: %+v", user)
+	}
+	if assistant := req.Input[1]; assistant.Role != "assistant" || assistant.Content[0].Type != "output_text" || assistant.Content[0].Text != "Reading it." {
+		t.Errorf("Unexpected assistant message: %+v", assistant)
+	}
+	call := req.Input[2]
+	if call.CallID != "toolu_01" || call.Name != "Read" || !strings.Contains(call.Arguments, "/tmp/b.go") {
+		t.Errorf("Unexpected function_call: %+v", call)
+	}
+	output := req.Input[3]
+	if output.CallID != "toolu_01" || output.Output == nil || *output.Output != "package main" {
+		t.Errorf("Unexpected function_call_output: %+v", output)
+	}
+
+	if len(req.Tools) != 1 || req.Tools[0].Type != "function" || req.Tools[0].Name != "Read" || req.Tools[0].Parameters == nil {
+		t.Errorf("Expected flat function tool, got %+v", req.Tools)
+	}
+	choice, _ := req.ToolChoice.(map[string]interface{})
+	if choice["type"] != "function" || choice["name"] != "Read" {
+		t.Errorf("Expected flat tool_choice for Read, got %v", req.ToolChoice)
+	}
+}
+
+func TestResponsesAdapter_Streaming(t *testing.T) {
+	chatBody, ctx, adapter := convertForResponses(t, responsesAnthropicRequest)
+	upstream, err := adapter.AdaptRequest(chatBody)
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+
+	chatSSE, err := adapter.AdaptResponse([]byte(recordedResponsesSSE), upstream.Streaming)
+	if err != nil {
+		t.Fatalf("AdaptResponse failed: %v", err)
+	}
+	chat := string(chatSSE)
+	if !strings.Contains(chat, `"finish_reason":"tool_calls"`) || !strings.HasSuffix(chat, "data: [DONE]\n\n") {
+		t.Errorf("Expected tool_calls finish and [DONE]: %s", chat)
+	}
+	if !strings.Contains(chat, `"id":"call_read_1"`) || !strings.Contains(chat, `"prompt_tokens":42`) {
+		t.Errorf("Expected tool call id and usage: %s", chat)
+	}
+
+	anthropicSSE, err := NewResponseConverter(getTestLogger()).Convert(chatSSE, ctx, true)
+	if err != nil {
+		t.Fatalf("OpenAI -> Anthropic conversion failed: %v", err)
+	}
+	result := string(anthropicSSE)
+	for _, expected := range []string{
+		`"type":"thinking"`,
+		`"thinking":"Need to read the file first."`,
+		`"text":"Let me read the file."`,
+		`"type":"tool_use"`,
+		`"id":"call_read_1"`,
+		`"name":"Read"`,
+		`"stop_reason":"tool_use"`,
+		`"output_tokens":31`,
+		"event: message_stop",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %s in Anthropic SSE:\n%s", expected, result)
+		}
+	}
+	if strings.Index(result, `"type":"thinking"`) > strings.Index(result, `"type":"text"`) {
+		t.Errorf("Expected thinking block before text block:\n%s", result)
+	}
+}
+
+func TestResponsesAdapter_NonStreamingResponse(t *testing.T) {
+	adapter := NewResponsesAdapter(&EndpointInfo{Type: "openai", OpenAIAPI: OpenAIAPIResponses}, getTestLogger())
+	if _, err := adapter.AdaptRequest([]byte(`{"model":"gpt-5","messages":[{"role":"user","content":"hi"}]}`)); err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+
+	result, err := adapter.AdaptResponse([]byte(`{
+		"id": "resp_1", "status": "incomplete", "model": "gpt-5",
+		"incomplete_details": {"reason": "max_output_tokens"},
+		"output": [
+			{"type": "reasoning", "id": "rs_1", "summary": [{"type": "summary_text", "text": "Thinking."}]},
+			{"type": "message", "id": "msg_1", "role": "assistant", "content": [{"type": "output_text", "text": "partial"}]}
+		],
+		"usage": {"input_tokens": 5, "output_tokens": 7, "total_tokens": 12}
+	}`), false)
+	if err != nil {
+		t.Fatalf("AdaptResponse failed: %v", err)
+	}
+
+	var resp OpenAIResponse
+	if err := json.Unmarshal(result, &resp); err != nil {
+		t.Fatalf("Failed to unmarshal chat completion: %v", err)
+	}
+	if resp.Choices[0].FinishReason != "length" {
+		t.Errorf("Expected finish_reason length, got %s", resp.Choices[0].FinishReason)
+	}
+	if text, _ := resp.Choices[0].Message.Content.(string); text != "partial" || resp.Choices[0].Message.ReasoningContent != "Thinking." {
+		t.Errorf("Unexpected message: %+v", resp.Choices[0].Message)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 12 {
+		t.Errorf("Unexpected usage: %+v", resp.Usage)
+	}
+
+	if _, err := adapter.AdaptResponse([]byte(`{"id":"resp_2","status":"failed","error":{"code":"server_error","message":"boom"}}`), false); err == nil {
+		t.Errorf("Expected failed response to return an error")
+	}
+}
+
+func TestResponsesAdapter_PassthroughAndTruncatedStream(t *testing.T) {
+	adapter := NewResponsesAdapter(&EndpointInfo{Type: "openai", OpenAIAPI: OpenAIAPIResponses}, getTestLogger())
+	codexBody := []byte(`{"model":"gpt-5","input":[{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"}]}],"stream":true}`)
+	upstream, err := adapter.AdaptRequest(codexBody)
+	if err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+	if upstream.Path != "/responses" || !upstream.Streaming || string(upstream.Body) != string(codexBody) {
+		t.Errorf("Expected native Responses request to pass through, got %+v", upstream)
+	}
+	if result, _ := adapter.AdaptResponse([]byte(recordedResponsesSSE), true); string(result) != recordedResponsesSSE {
+		t.Errorf("Expected native Responses response to pass through")
+	}
+
+	adapter = NewResponsesAdapter(&EndpointInfo{Type: "openai", OpenAIAPI: OpenAIAPIResponses}, getTestLogger())
+	if _, err := adapter.AdaptRequest([]byte(`{"model":"gpt-5","messages":[{"role":"user","content":"hi"}],"stream":true}`)); err != nil {
+		t.Fatalf("AdaptRequest failed: %v", err)
+	}
+	truncated := recordedResponsesSSE[:strings.Index(recordedResponsesSSE, "event: response.completed")]
+	result, err := adapter.AdaptResponse([]byte(truncated), true)
+	if err != nil {
+		t.Fatalf("AdaptResponse failed: %v", err)
+	}
+
+	// 没有 response.completed 时不补齐结束标记，交给响应校验识别为不完整的流
+	if strings.Contains(string(result), "[DONE]") || strings.Contains(string(result), `"finish_reason":"`) {
+		t.Errorf("Truncated stream should not be completed: %s", string(result))
+	}
+}
@@ -0,0 +1,113 @@
+package conversion
+
+import "encoding/json"
+
+// OpenAI Responses API 结构定义（仅包含代理用到的字段）
+
+// ResponsesRequest /responses 请求
+type ResponsesRequest struct {
+	Model             string               `json:"model"`
+	Instructions      string               `json:"instructions,omitempty"`
+	Input             []ResponsesInputItem `json:"input"`
+	Tools             []ResponsesTool      `json:"tools,omitempty"`
+	ToolChoice        interface{}          `json:"tool_choice,omitempty"` // "auto"|"none"|"required"|{"type":"function","name":...}
+	ParallelToolCalls *bool                `json:"parallel_tool_calls,omitempty"`
+	Temperature       *float64             `json:"temperature,omitempty"`
+	TopP              *float64             `json:"top_p,omitempty"`
+	MaxOutputTokens   *int                 `json:"max_output_tokens,omitempty"`
+	Reasoning         *ResponsesReasoning  `json:"reasoning,omitempty"`
+	Stream            *bool                `json:"stream,omitempty"`
+	Store             *bool                `json:"store,omitempty"`
+	User              string               `json:"user,omitempty"`
+}
+
+// ResponsesInputItem 输入项：message / function_call / function_call_output
+type ResponsesInputItem struct {
+	Type      string                 `json:"type"`
+	Role      string                 `json:"role,omitempty"`
+	Content   []ResponsesContentPart `json:"content,omitempty"`
+	CallID    string                 `json:"call_id,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Arguments string                 `json:"arguments,omitempty"`
+	Output    *string                `json:"output,omitempty"` // function_call_output 的输出允许为空字符串
+}
+
+// ResponsesContentPart 消息内容片段：input_text / input_image / output_text
+type ResponsesContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// ResponsesTool 函数工具（Responses API 使用扁平结构）
+type ResponsesTool struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ResponsesReasoning 推理配置
+type ResponsesReasoning struct {
+	Effort  string `json:"effort,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// ResponsesResponse /responses 响应（非流式响应体，或流式事件中的 response 字段）
+type ResponsesResponse struct {
+	ID                string                `json:"id"`
+	Model             string                `json:"model"`
+	Status            string                `json:"status"` // completed | incomplete | failed | in_progress
+	Output            []ResponsesOutputItem `json:"output"`
+	Usage             *ResponsesUsage       `json:"usage,omitempty"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"` // max_output_tokens | content_filter
+	} `json:"incomplete_details,omitempty"`
+	Error *ResponsesError `json:"error,omitempty"`
+}
+
+// stopReason 根据响应状态与 incomplete_details 得出 Anthropic stop_reason
+func (r *ResponsesResponse) stopReason(hasToolCalls bool) string {
+	incompleteReason := ""
+	if r.IncompleteDetails != nil {
+		incompleteReason = r.IncompleteDetails.Reason
+	}
+	return MapResponsesStatusToStopReason(r.Status, incompleteReason, hasToolCalls)
+}
+
+// ResponsesOutputItem 输出项：message / function_call / reasoning
+type ResponsesOutputItem struct {
+	Type      string                 `json:"type"`
+	ID        string                 `json:"id,omitempty"`
+	Role      string                 `json:"role,omitempty"`
+	Content   []ResponsesContentPart `json:"content,omitempty"`
+	Summary   []ResponsesContentPart `json:"summary,omitempty"` // reasoning 的摘要（summary_text）
+	CallID    string                 `json:"call_id,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Arguments string                 `json:"arguments,omitempty"`
+}
+
+// ResponsesUsage token 用量
+type ResponsesUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// ResponsesError 上游返回的错误
+type ResponsesError struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// ResponsesStreamEvent 流式事件，不同事件类型只使用其中的部分字段
+type ResponsesStreamEvent struct {
+	Type        string               `json:"type"`
+	Response    *ResponsesResponse   `json:"response,omitempty"`
+	OutputIndex int                  `json:"output_index"`
+	Item        *ResponsesOutputItem `json:"item,omitempty"`
+	Delta       string               `json:"delta,omitempty"`
+	Code        string               `json:"code,omitempty"`
+	Message     string               `json:"message,omitempty"`
+	Error       json.RawMessage      `json:"error,omitempty"`
+}
@@ -9,8 +9,10 @@ type AggregatedMessage struct {
 	ID           string                  `json:"id"`
 	Model        string                  `json:"model"`
 	TextContent  string                  `json:"text_content"`
+	ThinkingContent string               `json:"thinking_content,omitempty"` // 推理内容（reasoning_content 增量合并结果）
 	ToolCalls    []AggregatedToolCall    `json:"tool_calls"`
 	FinishReason string                  `json:"finish_reason"`
+	StopSequence string                  `json:"stop_sequence,omitempty"` // 命中的停止序列（仅 stop_sequence 时有值）
 	Usage        *OpenAIUsage            `json:"usage,omitempty"`
 }
 
@@ -41,8 +43,9 @@ type ConversionMetadata struct {
 
 // MessageAggregator aggregates OpenAI chunks into a complete message
 type MessageAggregator struct {
-	logger      *logger.Logger
-	pythonFixer *PythonJSONFixer
+	logger        *logger.Logger
+	pythonFixer   *PythonJSONFixer
+	stopSequences []string // 请求中的停止序列，用于识别 stop_sequence
 }
 
 // NewMessageAggregator creates a new MessageAggregator
@@ -53,6 +56,11 @@ func NewMessageAggregator(logger *logger.Logger) *MessageAggregator {
 	}
 }
 
+// SetStopSequences sets the stop sequences used to detect stop_sequence stop reasons
+func (a *MessageAggregator) SetStopSequences(stopSequences []string) {
+	a.stopSequences = stopSequences
+}
+
 // UnifiedConverter converts aggregated messages to Anthropic event sequences
 type UnifiedConverter struct {
 	logger *logger.Logger
@@ -0,0 +1,124 @@
+package conversion
+
+import "strings"
+
+// Anthropic stop_reason 取值
+const (
+	StopReasonEndTurn      = "end_turn"
+	StopReasonMaxTokens    = "max_tokens"
+	StopReasonStopSequence = "stop_sequence"
+	StopReasonToolUse      = "tool_use"
+	StopReasonPauseTurn    = "pause_turn"
+	StopReasonRefusal      = "refusal"
+)
+
+// MapFinishReasonToStopReason 将 OpenAI finish_reason 映射为 Anthropic stop_reason
+// stop_sequence 需要结合生成文本判断，见 resolveStopReason
+func MapFinishReasonToStopReason(finishReason string) string {
+	switch finishReason {
+	case "tool_calls", "function_call":
+		return StopReasonToolUse
+	case "length":
+		return StopReasonMaxTokens
+	case "content_filter":
+		return StopReasonRefusal
+	default: // 包括 "stop" 以及未知值
+		return StopReasonEndTurn
+	}
+}
+
+// MapStopReasonToFinishReason 将 Anthropic stop_reason 映射为 OpenAI finish_reason
+func MapStopReasonToFinishReason(stopReason string) string {
+	switch stopReason {
+	case StopReasonToolUse:
+		return "tool_calls"
+	case StopReasonMaxTokens:
+		return "length"
+	case StopReasonRefusal:
+		return "content_filter"
+	default: // end_turn / stop_sequence / pause_turn
+		return "stop"
+	}
+}
+
+// MapResponsesStatusToStopReason 将 Responses API 的 status 与 incomplete_details.reason 映射为 Anthropic stop_reason
+// hasToolCalls 表示输出中是否包含 function_call
+func MapResponsesStatusToStopReason(status, incompleteReason string, hasToolCalls bool) string {
+	switch status {
+	case "incomplete":
+		switch incompleteReason {
+		case "max_output_tokens":
+			return StopReasonMaxTokens
+		case "content_filter":
+			return StopReasonRefusal
+		default:
+			return StopReasonEndTurn
+		}
+	case "in_progress", "queued":
+		// 后台模式下响应尚未结束，对应 Anthropic 的暂停回合
+		return StopReasonPauseTurn
+	default:
+		if hasToolCalls {
+			return StopReasonToolUse
+		}
+		return StopReasonEndTurn
+	}
+}
+
+// MapFinishReasonToResponsesStatus 将 OpenAI finish_reason 映射为 Responses API 的 status 与 incomplete_details.reason
+// incompleteReason 为空表示不需要 incomplete_details
+func MapFinishReasonToResponsesStatus(finishReason string) (status string, incompleteReason string) {
+	switch finishReason {
+	case "length":
+		return "incomplete", "max_output_tokens"
+	case "content_filter":
+		return "incomplete", "content_filter"
+	default:
+		return "completed", ""
+	}
+}
+
+// matchStopSequence 检查生成文本的结尾是否命中请求中的停止序列
+// 部分上游（如 vLLM 开启 include_stop_str_in_output）会把停止序列保留在输出里，
+// 命中时返回匹配到的序列以及去掉该序列（及其前导空白）后的文本；多个序列同时命中时取最长的一个
+func matchStopSequence(text string, stopSequences []string) (matched string, trimmed string, ok bool) {
+	if len(stopSequences) == 0 || text == "" {
+		return "", text, false
+	}
+
+	candidates := []string{text}
+	if tail := strings.TrimRight(text, " \t\r\n"); tail != text {
+		candidates = append(candidates, tail)
+	}
+
+	for _, candidate := range candidates {
+		for _, seq := range stopSequences {
+			if seq == "" || !strings.HasSuffix(candidate, seq) {
+				continue
+			}
+			if len(seq) > len(matched) {
+				matched = seq
+				trimmed = strings.TrimRight(strings.TrimSuffix(candidate, seq), " \t\r\n")
+			}
+		}
+		if matched != "" {
+			return matched, trimmed, true
+		}
+	}
+
+	return "", text, false
+}
+
+// resolveStopReason 综合 finish_reason、生成文本和停止序列得出最终的 stop_reason
+// 仅当上游以 "stop" 结束时才尝试识别停止序列；命中时返回的文本已去掉停止序列
+func resolveStopReason(finishReason, text string, stopSequences []string) (stopReason, stopSequence, finalText string) {
+	stopReason = MapFinishReasonToStopReason(finishReason)
+	if finishReason != "stop" {
+		return stopReason, "", text
+	}
+
+	if matched, trimmed, ok := matchStopSequence(text, stopSequences); ok {
+		return StopReasonStopSequence, matched, trimmed
+	}
+	return stopReason, "", text
+}
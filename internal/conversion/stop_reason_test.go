@@ -0,0 +1,187 @@
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type stopReasonCase struct {
+	name                 string
+	finishReason         string
+	text                 string
+	stopSequences        []string
+	expectedStopReason   string
+	expectedStopSequence string
+	expectedText         string
+}
+
+var stopReasonCases = []stopReasonCase{
+	{"stop", "stop", "Hello world", nil, "end_turn", "", "Hello world"},
+	{"length", "length", "Hello wor", nil, "max_tokens", "", "Hello wor"},
+	{"tool_calls", "tool_calls", "", nil, "tool_use", "", ""},
+	{"content_filter", "content_filter", "I can", nil, "refusal", "", "I can"},
+	{"unknown reason", "weird", "Hi", nil, "end_turn", "", "Hi"},
+	{"stop sequence at tail", "stop", "Answer: 42\n###", []string{"###"}, "stop_sequence", "###", "Answer: 42"},
+	{"stop sequence not at tail", "stop", "### Answer: 42", []string{"###"}, "end_turn", "", "### Answer: 42"},
+	{"longest stop sequence wins", "stop", "done END_OF_TEXT", []string{"TEXT", "END_OF_TEXT"}, "stop_sequence", "END_OF_TEXT", "done"},
+	{"stop sequence ignored on length", "length", "Answer ###", []string{"###"}, "max_tokens", "", "Answer ###"},
+}
+
+func TestStopReasonMapping_NonStreaming(t *testing.T) {
+	converter := NewResponseConverter(getTestLogger())
+
+	for _, tc := range stopReasonCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oaResp := OpenAIResponse{
+				ID:    "chatcmpl-stop",
+				Model: "gpt-4",
+				Choices: []OpenAIChoice{
+					{
+						Index:        0,
+						FinishReason: tc.finishReason,
+						Message: OpenAIMessage{
+							Role:    "assistant",
+							Content: tc.text,
+						},
+					},
+				},
+			}
+
+			respBytes, _ := json.Marshal(oaResp)
+			ctx := &ConversionContext{StopSequences: tc.stopSequences}
+			result, err := converter.convertNonStreamingResponse(respBytes, ctx)
+			if err != nil {
+				t.Fatalf("Conversion failed: %v", err)
+			}
+
+			var anthResp AnthropicResponse
+			if err := json.Unmarshal(result, &anthResp); err != nil {
+				t.Fatalf("Failed to unmarshal result: %v", err)
+			}
+
+			if anthResp.StopReason != tc.expectedStopReason {
+				t.Errorf("Expected stop_reason '%s', got '%s'", tc.expectedStopReason, anthResp.StopReason)
+			}
+			if anthResp.StopSequence != tc.expectedStopSequence {
+				t.Errorf("Expected stop_sequence '%s', got '%s'", tc.expectedStopSequence, anthResp.StopSequence)
+			}
+
+			text := ""
+			for _, block := range anthResp.Content {
+				if block.Type == "text" {
+					text += block.Text
+				}
+			}
+			if text != tc.expectedText {
+				t.Errorf("Expected text '%s', got '%s'", tc.expectedText, text)
+			}
+		})
+	}
+}
+
+func TestStopReasonMapping_Streaming(t *testing.T) {
+	converter := NewResponseConverter(getTestLogger())
+
+	for _, tc := range stopReasonCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			if tc.text != "" {
+				content, _ := json.Marshal(tc.text)
+				sb.WriteString(fmt.Sprintf(`data: {"id":"chatcmpl-stop","object":"chat.completion.chunk","model":"gpt-4","choices":[{"index":0,"delta":{"role":"assistant","content":%s}}]}`+"\n\n", content))
+			} else {
+				sb.WriteString(`data: {"id":"chatcmpl-stop","object":"chat.completion.chunk","model":"gpt-4","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{}"}}]}}]}` + "\n\n")
+			}
+			sb.WriteString(fmt.Sprintf(`data: {"id":"chatcmpl-stop","object":"chat.completion.chunk","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"%s"}]}`+"\n\n", tc.finishReason))
+			sb.WriteString("data: [DONE]\n\n")
+
+			ctx := &ConversionContext{StopSequences: tc.stopSequences}
+			result, err := converter.convertStreamingResponseRefactored([]byte(sb.String()), ctx)
+			if err != nil {
+				t.Fatalf("Conversion failed: %v", err)
+			}
+
+			var delta *AnthropicMessageDelta
+			text := ""
+			for _, line := range strings.Split(string(result), "\n") {
+				if !strings.HasPrefix(line, "data: ") {
+					continue
+				}
+				data := strings.TrimPrefix(line, "data: ")
+
+				var event map[string]interface{}
+				if err := json.Unmarshal([]byte(data), &event); err != nil {
+					continue
+				}
+				switch event["type"] {
+				case "message_delta":
+					delta = &AnthropicMessageDelta{}
+					if err := json.Unmarshal([]byte(data), delta); err != nil {
+						t.Fatalf("Failed to parse message_delta: %v", err)
+					}
+				case "content_block_delta":
+					if d, ok := event["delta"].(map[string]interface{}); ok && d["type"] == "text_delta" {
+						text += d["text"].(string)
+					}
+				}
+			}
+
+			if delta == nil || delta.Delta == nil {
+				t.Fatalf("message_delta event not found in output: %s", string(result))
+			}
+			if delta.Delta.StopReason != tc.expectedStopReason {
+				t.Errorf("Expected stop_reason '%s', got '%s'", tc.expectedStopReason, delta.Delta.StopReason)
+			}
+			if delta.Delta.StopSequence != tc.expectedStopSequence {
+				t.Errorf("Expected stop_sequence '%s', got '%s'", tc.expectedStopSequence, delta.Delta.StopSequence)
+			}
+			if text != tc.expectedText {
+				t.Errorf("Expected text '%s', got '%s'", tc.expectedText, text)
+			}
+		})
+	}
+}
+
+func TestMapStopReasonToFinishReason(t *testing.T) {
+	testCases := []struct {
+		stopReason string
+		expected   string
+	}{
+		{"end_turn", "stop"},
+		{"stop_sequence", "stop"},
+		{"pause_turn", "stop"},
+		{"tool_use", "tool_calls"},
+		{"max_tokens", "length"},
+		{"refusal", "content_filter"},
+	}
+
+	for _, tc := range testCases {
+		if got := MapStopReasonToFinishReason(tc.stopReason); got != tc.expected {
+			t.Errorf("MapStopReasonToFinishReason(%q) = %q, want %q", tc.stopReason, got, tc.expected)
+		}
+	}
+}
+
+func TestResponsesStatusMapping(t *testing.T) {
+	toAnthropic := []struct {
+		status           string
+		incompleteReason string
+		hasToolCalls     bool
+		expected         string
+	}{
+		{"completed", "", false, "end_turn"},
+		{"completed", "", true, "tool_use"},
+		{"incomplete", "max_output_tokens", false, "max_tokens"},
+		{"incomplete", "content_filter", false, "refusal"},
+		{"in_progress", "", false, "pause_turn"},
+		{"queued", "", false, "pause_turn"},
+	}
+
+	for _, tc := range toAnthropic {
+		if got := MapResponsesStatusToStopReason(tc.status, tc.incompleteReason, tc.hasToolCalls); got != tc.expected {
+			t.Errorf("MapResponsesStatusToStopReason(%q, %q, %v) = %q, want %q",
+				tc.status, tc.incompleteReason, tc.hasToolCalls, got, tc.expected)
+		}
+	}
+}
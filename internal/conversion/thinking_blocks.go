@@ -0,0 +1,165 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// StripUnsignedThinkingBlocks 移除 Anthropic Messages 请求历史中没有签名的 thinking 块
+// OpenAI / Responses 等上游的推理内容会被转换为不带签名的 thinking 块，客户端会在后续请求中原样回传；
+// 请求发往原生 Anthropic 端点（如故障转移后）时，这类块会被拒绝（400），因此在发送前移除
+// 只含未签名 thinking 块的 assistant 消息整条移除（上游拒绝空文本块），相邻的同角色消息随之合并
+// 移除后若最后一条 assistant 消息含工具调用但不再以 thinking 块开头，同时关闭本次请求的 thinking，
+// 否则上游会要求该消息以 thinking 块开头
+// 返回 false 表示请求无需修改
+func StripUnsignedThinkingBlocks(body []byte) ([]byte, bool) {
+	if !bytes.Contains(body, []byte(`"thinking"`)) {
+		return body, false
+	}
+
+	var request map[string]json.RawMessage
+	if err := json.Unmarshal(body, &request); err != nil {
+		return body, false
+	}
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(request["messages"], &messages); err != nil {
+		return body, false
+	}
+
+	stripped := false
+	dropped := map[int]bool{}       // 移除后没有剩余内容的 assistant 消息
+	needsThinking := map[int]bool{} // 移除后含工具调用但不再以 thinking 块开头的 assistant 消息
+	for i, message := range messages {
+		var role string
+		json.Unmarshal(message["role"], &role)
+		if role != "assistant" {
+			continue
+		}
+		var blocks []json.RawMessage
+		if err := json.Unmarshal(message["content"], &blocks); err != nil {
+			continue
+		}
+
+		kept := make([]json.RawMessage, 0, len(blocks))
+		var keptTypes []string
+		for _, block := range blocks {
+			var header struct {
+				Type      string `json:"type"`
+				Signature string `json:"signature"`
+			}
+			json.Unmarshal(block, &header)
+			if header.Type == "thinking" && header.Signature == "" {
+				continue
+			}
+			kept = append(kept, block)
+			keptTypes = append(keptTypes, header.Type)
+		}
+		if len(kept) == len(blocks) {
+			continue
+		}
+
+		stripped = true
+		if len(kept) == 0 {
+			dropped[i] = true
+			continue
+		}
+		content, err := json.Marshal(kept)
+		if err != nil {
+			return body, false
+		}
+		messages[i]["content"] = content
+		needsThinking[i] = containsString(keptTypes, "tool_use") &&
+			keptTypes[0] != "thinking" && keptTypes[0] != "redacted_thinking"
+	}
+	if !stripped {
+		return body, false
+	}
+
+	// 最后一条 assistant 消息按移除前的下标判断
+	lastAssistantNeedsThinking := false
+	for i := len(messages) - 1; i >= 0; i-- {
+		var role string
+		json.Unmarshal(messages[i]["role"], &role)
+		if role == "assistant" && !dropped[i] {
+			lastAssistantNeedsThinking = needsThinking[i]
+			break
+		}
+	}
+
+	if len(dropped) > 0 {
+		compacted, err := dropAndMergeMessages(messages, dropped)
+		if err != nil {
+			return body, false
+		}
+		messages = compacted
+	}
+
+	encoded, err := json.Marshal(messages)
+	if err != nil {
+		return body, false
+	}
+	request["messages"] = encoded
+	if lastAssistantNeedsThinking {
+		delete(request, "thinking")
+	}
+
+	result, err := json.Marshal(request)
+	if err != nil {
+		return body, false
+	}
+	return result, true
+}
+
+// dropAndMergeMessages 移除指定下标的消息，并合并因此相邻的同角色消息（Anthropic 要求 user/assistant 交替）
+func dropAndMergeMessages(messages []map[string]json.RawMessage, dropped map[int]bool) ([]map[string]json.RawMessage, error) {
+	result := make([]map[string]json.RawMessage, 0, len(messages))
+	lastRole := ""
+	for i, message := range messages {
+		if dropped[i] {
+			continue
+		}
+		var role string
+		json.Unmarshal(message["role"], &role)
+		if len(result) > 0 && role == lastRole {
+			merged, err := mergeMessageContent(result[len(result)-1]["content"], message["content"])
+			if err != nil {
+				return nil, err
+			}
+			result[len(result)-1]["content"] = merged
+			continue
+		}
+		result = append(result, message)
+		lastRole = role
+	}
+	return result, nil
+}
+
+// mergeMessageContent 合并两条消息的 content，字符串内容转换为文本块
+func mergeMessageContent(first, second json.RawMessage) (json.RawMessage, error) {
+	var blocks []json.RawMessage
+	for _, content := range []json.RawMessage{first, second} {
+		contentBlocks, err := contentAsBlocks(content)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, contentBlocks...)
+	}
+	return json.Marshal(blocks)
+}
+
+// contentAsBlocks 将 content（字符串或块数组）转换为块数组，空字符串不产生文本块
+func contentAsBlocks(content json.RawMessage) ([]json.RawMessage, error) {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		if text == "" {
+			return nil, nil
+		}
+		block, err := json.Marshal(map[string]string{"type": "text", "text": text})
+		return []json.RawMessage{block}, err
+	}
+	var blocks []json.RawMessage
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
@@ -0,0 +1,123 @@
+package conversion
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStripUnsignedThinkingBlocks(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		wantStripped   bool
+		wantContent    []string // 各 assistant 消息剩余块的类型
+		wantNoThinking bool     // 请求中的 thinking 参数被移除
+		wantMessages   int      // 剩余消息数，0 表示不检查
+	}{
+		{
+			name:         "no thinking blocks",
+			body:         `{"model":"claude-sonnet-4","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"text","text":"hello"}]}]}`,
+			wantStripped: false,
+		},
+		{
+			name:         "signed thinking is kept",
+			body:         `{"messages":[{"role":"assistant","content":[{"type":"thinking","thinking":"plan","signature":"sig"},{"type":"text","text":"hello"}]}]}`,
+			wantStripped: false,
+		},
+		{
+			name:         "unsigned thinking in history",
+			body:         `{"thinking":{"type":"enabled","budget_tokens":1024},"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"thinking","thinking":"plan"},{"type":"text","text":"hello"}]},{"role":"user","content":"next"}]}`,
+			wantStripped: true,
+			wantContent:  []string{"text"},
+		},
+		{
+			name:         "only unsigned thinking",
+			body:         `{"thinking":{"type":"enabled","budget_tokens":1024},"messages":[{"role":"assistant","content":[{"type":"thinking","thinking":"plan","signature":""}]},{"role":"user","content":"next"}]}`,
+			wantStripped: true,
+			wantMessages: 1,
+		},
+		{
+			name: "emptied assistant turn merges surrounding user turns",
+			body: `{"thinking":{"type":"enabled","budget_tokens":1024},"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":[{"type":"thinking","thinking":"plan"}]},` +
+				`{"role":"user","content":[{"type":"text","text":"next"}]},{"role":"assistant","content":[{"type":"text","text":"done"}]}]}`,
+			wantStripped: true,
+			wantContent:  []string{"text"},
+			wantMessages: 2,
+		},
+		{
+			name: "tool loop in progress disables thinking",
+			body: `{"thinking":{"type":"enabled","budget_tokens":1024},"messages":[{"role":"user","content":"hi"},` +
+				`{"role":"assistant","content":[{"type":"thinking","thinking":"plan"},{"type":"tool_use","id":"t1","name":"Read","input":{}}]},` +
+				`{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}]}`,
+			wantStripped:   true,
+			wantContent:    []string{"tool_use"},
+			wantNoThinking: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, stripped := StripUnsignedThinkingBlocks([]byte(tc.body))
+			if stripped != tc.wantStripped {
+				t.Fatalf("stripped = %v, want %v", stripped, tc.wantStripped)
+			}
+			if !stripped {
+				if string(result) != tc.body {
+					t.Errorf("Expected body to be unchanged, got %s", result)
+				}
+				return
+			}
+
+			var request struct {
+				Thinking json.RawMessage `json:"thinking"`
+				Messages []struct {
+					Role    string          `json:"role"`
+					Content json.RawMessage `json:"content"`
+				} `json:"messages"`
+			}
+			if err := json.Unmarshal(result, &request); err != nil {
+				t.Fatalf("Invalid result JSON: %v", err)
+			}
+			if tc.wantMessages > 0 && len(request.Messages) != tc.wantMessages {
+				t.Errorf("Expected %d messages, got %d: %s", tc.wantMessages, len(request.Messages), result)
+			}
+
+			// 结果需满足上游约束：角色交替、消息非空、文本块非空
+			var types []string
+			for i, message := range request.Messages {
+				if i > 0 && message.Role == request.Messages[i-1].Role {
+					t.Errorf("Messages %d and %d share role %s: %s", i-1, i, message.Role, result)
+				}
+				var text string
+				if json.Unmarshal(message.Content, &text) == nil {
+					if text == "" {
+						t.Errorf("Message %d has empty content: %s", i, result)
+					}
+					continue
+				}
+				var blocks []struct {
+					Type string  `json:"type"`
+					Text *string `json:"text"`
+				}
+				json.Unmarshal(message.Content, &blocks)
+				if len(blocks) == 0 {
+					t.Errorf("Message %d has no content blocks: %s", i, result)
+				}
+				for _, block := range blocks {
+					if block.Type == "text" && (block.Text == nil || *block.Text == "") {
+						t.Errorf("Message %d has an empty text block: %s", i, result)
+					}
+					if message.Role == "assistant" {
+						types = append(types, block.Type)
+					}
+				}
+			}
+			if len(types) != len(tc.wantContent) || (len(types) > 0 && types[0] != tc.wantContent[0]) {
+				t.Errorf("assistant content = %v, want %v", types, tc.wantContent)
+			}
+			if tc.wantNoThinking != (request.Thinking == nil) {
+				t.Errorf("thinking parameter = %s, wantRemoved %v", request.Thinking, tc.wantNoThinking)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 工具参数校验策略（对应端点配置 tool_argument_validation）
+const (
+	ToolArgumentValidationOff      = "off"      // 不校验
+	ToolArgumentValidationRepair   = "repair"   // 校验并修复，无法修复时原样透传（默认）
+	ToolArgumentValidationRetry    = "retry"    // 无法修复时在当前端点重试
+	ToolArgumentValidationFailover = "failover" // 无法修复时切换到下一个端点
+)
+
+// ToolArgumentsInvalidErrorType 无法修复的工具参数对应的 ConversionError 类型
+const ToolArgumentsInvalidErrorType = "tool_arguments_invalid"
+
+// ToolArgumentValidator 按工具声明的 input_schema 校验并修复 tool_use 参数
+// 只做安全的类型转换：字符串与数字/布尔互转、单值包装为数组、JSON 字符串展开为数组/对象、
+// enum 大小写纠正、补齐带 default 的必填字段、移除可选字段上的 null
+type ToolArgumentValidator struct{}
+
+// NewToolArgumentValidator 创建工具参数校验器
+func NewToolArgumentValidator() *ToolArgumentValidator {
+	return &ToolArgumentValidator{}
+}
+
+// ValidateAndRepair 校验并修复一次工具调用的参数
+// 返回修复后的参数（未修复时与输入相同）、已执行的修复列表以及仍无法满足 schema 的问题列表
+func (v *ToolArgumentValidator) ValidateAndRepair(arguments string, schema map[string]interface{}) (string, []string, []string) {
+	if schema == nil {
+		return arguments, nil, nil
+	}
+
+	raw := strings.TrimSpace(arguments)
+	if raw == "" {
+		raw = "{}"
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return arguments, nil, []string{fmt.Sprintf("$: arguments are not valid JSON: %v", err)}
+	}
+
+	r := &argumentRepairer{}
+	repaired := r.repair(value, schema, "$")
+	if len(r.repairs) == 0 {
+		return arguments, nil, r.problems
+	}
+
+	out, err := json.Marshal(repaired)
+	if err != nil {
+		return arguments, nil, append(r.problems, fmt.Sprintf("$: failed to marshal repaired arguments: %v", err))
+	}
+	return string(out), r.repairs, r.problems
+}
+
+type argumentRepairer struct {
+	repairs  []string
+	problems []string
+}
+
+func (r *argumentRepairer) repaired(path, format string, args ...interface{}) {
+	r.repairs = append(r.repairs, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (r *argumentRepairer) problem(path, format string, args ...interface{}) {
+	r.problems = append(r.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// repair 递归修复单个值
+func (r *argumentRepairer) repair(value interface{}, schema map[string]interface{}, path string) interface{} {
+	if schema == nil {
+		return value
+	}
+
+	types := schemaTypeList(schema)
+
+	// 未声明 type 的组合 schema：选第一个无需报错即可满足的分支
+	if len(types) == 0 {
+		for _, combinator := range []string{"anyOf", "oneOf"} {
+			if branches, ok := schema[combinator].([]interface{}); ok {
+				return r.repairWithBranches(value, branches, path)
+			}
+		}
+		return r.checkEnum(value, schema, path)
+	}
+
+	if value == nil {
+		if containsString(types, "null") || schema["nullable"] == true {
+			return nil
+		}
+		r.problem(path, "expected %s, got null", strings.Join(types, "|"))
+		return nil
+	}
+
+	if !valueMatchesTypes(value, types) {
+		coerced, ok := coerceValue(value, types)
+		if !ok {
+			r.problem(path, "expected %s, got %s", strings.Join(types, "|"), jsonTypeName(value))
+			return value
+		}
+		r.repaired(path, "coerced %s to %s", jsonTypeName(value), jsonTypeName(coerced))
+		value = coerced
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		return r.checkEnum(r.repairObject(val, schema, path), schema, path)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i := range val {
+				val[i] = r.repair(val[i], items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+		return r.checkEnum(val, schema, path)
+	default:
+		return r.checkEnum(value, schema, path)
+	}
+}
+
+// repairWithBranches 依次尝试 anyOf/oneOf 分支，采用第一个没有遗留问题的结果
+func (r *argumentRepairer) repairWithBranches(value interface{}, branches []interface{}, path string) interface{} {
+	for _, b := range branches {
+		branch, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		trial := &argumentRepairer{}
+		result := trial.repair(deepCopySchemaValue(value), branch, path)
+		if len(trial.problems) == 0 {
+			r.repairs = append(r.repairs, trial.repairs...)
+			return result
+		}
+	}
+	r.problem(path, "value does not match any of the allowed schemas")
+	return value
+}
+
+// repairObject 修复对象：递归属性、补齐必填字段、移除可选字段上的 null
+func (r *argumentRepairer) repairObject(obj map[string]interface{}, schema map[string]interface{}, path string) interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := toStringSlice(schema["required"])
+	requiredSet := map[string]bool{}
+	for _, name := range required {
+		requiredSet[name] = true
+	}
+
+	for name, propValue := range obj {
+		propSchema, ok := properties[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		propPath := path + "." + name
+		if propValue == nil && !requiredSet[name] && !containsString(schemaTypeList(propSchema), "null") && propSchema["nullable"] != true {
+			delete(obj, name)
+			r.repaired(propPath, "removed null optional field")
+			continue
+		}
+		obj[name] = r.repair(propValue, propSchema, propPath)
+	}
+
+	for _, name := range required {
+		if _, exists := obj[name]; exists {
+			continue
+		}
+		propSchema, _ := properties[name].(map[string]interface{})
+		if def, hasDefault := propSchema["default"]; hasDefault {
+			obj[name] = deepCopySchemaValue(def)
+			r.repaired(path+"."+name, "filled missing required field with schema default")
+			continue
+		}
+		r.problem(path+"."+name, "missing required field")
+	}
+
+	return obj
+}
+
+// checkEnum 校验 enum（数组和对象按深度比较），字符串大小写不一致时纠正
+func (r *argumentRepairer) checkEnum(value interface{}, schema map[string]interface{}, path string) interface{} {
+	enum, ok := schema["enum"].([]interface{})
+	if !ok || len(enum) == 0 {
+		return value
+	}
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) {
+			return value
+		}
+	}
+	if s, ok := value.(string); ok {
+		for _, allowed := range enum {
+			if as, ok := allowed.(string); ok && strings.EqualFold(as, strings.TrimSpace(s)) {
+				r.repaired(path, "normalized enum value %q to %q", s, as)
+				return as
+			}
+		}
+	}
+	r.problem(path, "value %v is not one of the allowed enum values", value)
+	return value
+}
+
+// coerceValue 尝试将值转换为目标类型之一
+// 只有标量会被包装为单元素数组；以 [ 开头但不是合法 JSON 数组的字符串不做转换，由调用方报告问题
+func coerceValue(value interface{}, types []string) (interface{}, bool) {
+	for _, t := range types {
+		switch t {
+		case "array":
+			switch val := value.(type) {
+			case string:
+				trimmed := strings.TrimSpace(val)
+				if strings.HasPrefix(trimmed, "[") {
+					var arr []interface{}
+					if err := json.Unmarshal([]byte(trimmed), &arr); err == nil {
+						return arr, true
+					}
+					continue
+				}
+				return []interface{}{value}, true
+			case float64, bool:
+				return []interface{}{value}, true
+			}
+		case "object":
+			if s, ok := value.(string); ok {
+				var obj map[string]interface{}
+				if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &obj); err == nil && obj != nil {
+					return obj, true
+				}
+			}
+		case "integer":
+			if s, ok := value.(string); ok {
+				if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+					return float64(i), true
+				}
+			}
+		case "number":
+			if s, ok := value.(string); ok {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+					return f, true
+				}
+			}
+		case "boolean":
+			if s, ok := value.(string); ok {
+				switch strings.ToLower(strings.TrimSpace(s)) {
+				case "true":
+					return true, true
+				case "false":
+					return false, true
+				}
+			}
+		case "string":
+			switch val := value.(type) {
+			case float64:
+				return strconv.FormatFloat(val, 'f', -1, 64), true
+			case bool:
+				return strconv.FormatBool(val), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func schemaTypeList(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		return toStringSlice(t)
+	}
+	return nil
+}
+
+func valueMatchesTypes(value interface{}, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := value.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// validateToolArguments 在响应转换时校验单个 tool_use 的参数，并把修复记录写入转换上下文
+// 无法修复且策略为 retry/failover 时返回 ToolArgumentsInvalidErrorType 类型的错误
+func (c *ResponseConverter) validateToolArguments(ctx *ConversionContext, toolName, toolID, arguments string) (string, error) {
+	if ctx == nil || ctx.ToolArgumentValidation == ToolArgumentValidationOff {
+		return arguments, nil
+	}
+	schema, ok := ctx.ToolSchemas[toolName]
+	if !ok {
+		return arguments, nil
+	}
+
+	repaired, repairs, problems := NewToolArgumentValidator().ValidateAndRepair(arguments, schema)
+	for _, repair := range repairs {
+		ctx.ToolArgumentRepairs = append(ctx.ToolArgumentRepairs, fmt.Sprintf("%s(%s) %s", toolName, toolID, repair))
+	}
+
+	if len(repairs) > 0 && c.logger != nil {
+		c.logger.Debug("Repaired tool arguments against input_schema", map[string]interface{}{
+			"tool_name": toolName,
+			"tool_id":   toolID,
+			"repairs":   repairs,
+		})
+	}
+
+	if len(problems) > 0 {
+		if c.logger != nil {
+			c.logger.Info("Tool arguments do not match input_schema", map[string]interface{}{
+				"tool_name": toolName,
+				"tool_id":   toolID,
+				"problems":  problems,
+				"policy":    ctx.ToolArgumentValidation,
+			})
+		}
+		if ctx.ToolArgumentValidation == ToolArgumentValidationRetry || ctx.ToolArgumentValidation == ToolArgumentValidationFailover {
+			return arguments, NewConversionError(ToolArgumentsInvalidErrorType,
+				fmt.Sprintf("Tool %s arguments cannot be repaired: %s", toolName, strings.Join(problems, "; ")), nil)
+		}
+	}
+
+	return repaired, nil
+}
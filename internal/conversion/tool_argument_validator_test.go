@@ -0,0 +1,243 @@
+package conversion
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testEditSchema = `{
+	"type": "object",
+	"properties": {
+		"file_path": {"type": "string"},
+		"edits": {"type": "array", "items": {"type": "string"}},
+		"limit": {"type": "integer"},
+		"ratio": {"type": "number"},
+		"replace_all": {"type": "boolean", "default": false},
+		"mode": {"type": "string", "enum": ["append", "overwrite"]},
+		"options": {"type": "object", "properties": {"depth": {"type": "integer"}}},
+		"note": {"type": "string"}
+	},
+	"required": ["file_path", "replace_all"]
+}`
+
+func TestToolArgumentValidator_ValidateAndRepair(t *testing.T) {
+	schema := mustSchema(t, testEditSchema)
+	validator := NewToolArgumentValidator()
+
+	testCases := []struct {
+		name           string
+		arguments      string
+		expected       string // 为空表示参数保持不变
+		expectRepairs  int
+		expectProblems bool
+	}{
+		{
+			name:      "valid arguments unchanged",
+			arguments: `{"file_path":"a.go","replace_all":true}`,
+		},
+		{
+			name:          "string to array",
+			arguments:     `{"file_path":"a.go","replace_all":true,"edits":"one"}`,
+			expected:      `{"file_path":"a.go","replace_all":true,"edits":["one"]}`,
+			expectRepairs: 1,
+		},
+		{
+			name:          "json string to array",
+			arguments:     `{"file_path":"a.go","replace_all":true,"edits":"[\"a\",\"b\"]"}`,
+			expected:      `{"file_path":"a.go","replace_all":true,"edits":["a","b"]}`,
+			expectRepairs: 1,
+		},
+		{
+			name:          "numbers and booleans as strings",
+			arguments:     `{"file_path":"a.go","replace_all":"TRUE","limit":"10","ratio":"0.5"}`,
+			expected:      `{"file_path":"a.go","replace_all":true,"limit":10,"ratio":0.5}`,
+			expectRepairs: 3,
+		},
+		{
+			name:          "number to string",
+			arguments:     `{"file_path":42,"replace_all":false}`,
+			expected:      `{"file_path":"42","replace_all":false}`,
+			expectRepairs: 1,
+		},
+		{
+			name:          "missing required field with default",
+			arguments:     `{"file_path":"a.go"}`,
+			expected:      `{"file_path":"a.go","replace_all":false}`,
+			expectRepairs: 1,
+		},
+		{
+			name:          "enum case and null optional field",
+			arguments:     `{"file_path":"a.go","replace_all":true,"mode":"Append","note":null}`,
+			expected:      `{"file_path":"a.go","replace_all":true,"mode":"append"}`,
+			expectRepairs: 2,
+		},
+		{
+			name:          "nested object from json string",
+			arguments:     `{"file_path":"a.go","replace_all":true,"options":"{\"depth\":\"3\"}"}`,
+			expected:      `{"file_path":"a.go","replace_all":true,"options":{"depth":3}}`,
+			expectRepairs: 2,
+		},
+		{
+			name:           "missing required field without default",
+			arguments:      `{"replace_all":true}`,
+			expectProblems: true,
+		},
+		{
+			name:           "uncoercible value",
+			arguments:      `{"file_path":"a.go","replace_all":true,"limit":"ten"}`,
+			expectProblems: true,
+		},
+		{
+			name:           "invalid json",
+			arguments:      `{"file_path":`,
+			expectProblems: true,
+		},
+		{
+			name:           "malformed json array string not wrapped",
+			arguments:      `{"file_path":"a.go","replace_all":true,"edits":"[\"a\","}`,
+			expectProblems: true,
+		},
+		{
+			name:           "object not wrapped into array",
+			arguments:      `{"file_path":"a.go","replace_all":true,"edits":{"a":"b"}}`,
+			expectProblems: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, repairs, problems := validator.ValidateAndRepair(tc.arguments, schema)
+
+			if len(repairs) != tc.expectRepairs {
+				t.Errorf("Expected %d repairs, got %d: %v", tc.expectRepairs, len(repairs), repairs)
+			}
+			if tc.expectProblems != (len(problems) > 0) {
+				t.Errorf("Expected problems=%v, got %v", tc.expectProblems, problems)
+			}
+
+			if tc.expected == "" {
+				if tc.expectRepairs == 0 && result != tc.arguments {
+					t.Errorf("Expected arguments to be unchanged, got %s", result)
+				}
+				return
+			}
+
+			var got, want interface{}
+			json.Unmarshal([]byte(result), &got)
+			json.Unmarshal([]byte(tc.expected), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Unexpected repaired arguments\n got: %s\nwant: %s", result, tc.expected)
+			}
+		})
+	}
+}
+
+// enum 中的数组和对象值按深度比较，不会因不可比较类型 panic
+func TestToolArgumentValidator_CompositeEnumValues(t *testing.T) {
+	schema := mustSchema(t, `{
+		"type": "object",
+		"properties": {
+			"range": {"type": "array", "enum": [[1, 2], [3, 4]]},
+			"filter": {"type": "object", "enum": [{"kind": "file"}]}
+		}
+	}`)
+	validator := NewToolArgumentValidator()
+
+	if _, _, problems := validator.ValidateAndRepair(`{"range":[3,4],"filter":{"kind":"file"}}`, schema); len(problems) > 0 {
+		t.Errorf("Expected composite enum values to match, got %v", problems)
+	}
+	if _, _, problems := validator.ValidateAndRepair(`{"range":[5],"filter":{"kind":"dir"}}`, schema); len(problems) != 2 {
+		t.Errorf("Expected 2 enum problems, got %v", problems)
+	}
+}
+
+func TestToolArgumentValidation_ResponsePaths(t *testing.T) {
+	converter := NewResponseConverter(getTestLogger())
+	schema := mustSchema(t, testEditSchema)
+
+	nonStreaming := func(args string) []byte {
+		resp := OpenAIResponse{
+			ID:    "chatcmpl-args",
+			Model: "gpt-4",
+			Choices: []OpenAIChoice{{
+				FinishReason: "tool_calls",
+				Message: OpenAIMessage{
+					Role: "assistant",
+					ToolCalls: []OpenAIToolCall{{
+						ID:       "call_1",
+						Type:     "function",
+						Function: OpenAIToolCallDetail{Name: "Edit", Arguments: args},
+					}},
+				},
+			}},
+		}
+		b, _ := json.Marshal(resp)
+		return b
+	}
+	streaming := func(args string) []byte {
+		encoded, _ := json.Marshal(args)
+		return []byte(`data: {"id":"chatcmpl-args","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"Edit","arguments":` + string(encoded) + `}}]}}]}` + "\n\n" +
+			`data: {"id":"chatcmpl-args","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n" +
+			"data: [DONE]\n\n")
+	}
+
+	testCases := []struct {
+		name          string
+		policy        string
+		arguments     string
+		expectError   bool
+		expectRepairs int
+	}{
+		{"repair policy fixes arguments", ToolArgumentValidationRepair, `{"file_path":"a.go","edits":"x"}`, false, 2},
+		{"default policy passes unrepairable through", "", `{"edits":"x"}`, false, 2},
+		{"off policy skips validation", ToolArgumentValidationOff, `{"edits":"x"}`, false, 0},
+		{"failover policy rejects unrepairable", ToolArgumentValidationFailover, `{"edits":"x"}`, true, 2},
+		{"retry policy rejects unrepairable", ToolArgumentValidationRetry, `{"limit":"ten","file_path":"a","replace_all":true}`, true, 0},
+	}
+
+	for _, tc := range testCases {
+		for _, isStreaming := range []bool{false, true} {
+			name := tc.name + "/non-streaming"
+			body := nonStreaming(tc.arguments)
+			if isStreaming {
+				name = tc.name + "/streaming"
+				body = streaming(tc.arguments)
+			}
+
+			t.Run(name, func(t *testing.T) {
+				ctx := &ConversionContext{
+					ToolSchemas:            map[string]map[string]interface{}{"Edit": schema},
+					ToolArgumentValidation: tc.policy,
+				}
+
+				result, err := converter.Convert(body, ctx, isStreaming)
+				if tc.expectError {
+					var convErr *ConversionError
+					if !errors.As(err, &convErr) || convErr.Type != ToolArgumentsInvalidErrorType {
+						t.Fatalf("Expected %s error, got %v", ToolArgumentsInvalidErrorType, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Conversion failed: %v", err)
+				}
+
+				if len(ctx.ToolArgumentRepairs) != tc.expectRepairs {
+					t.Errorf("Expected %d recorded repairs, got %v", tc.expectRepairs, ctx.ToolArgumentRepairs)
+				}
+				for _, repair := range ctx.ToolArgumentRepairs {
+					if !strings.HasPrefix(repair, "Edit(call_1) ") {
+						t.Errorf("Repair record should identify the tool call, got %q", repair)
+					}
+				}
+				// 流式输出的 input_json_delta 会被拆分，只在非流式响应中直接检查修复结果
+				if !isStreaming && tc.expectRepairs > 0 && !strings.Contains(string(result), `["x"]`) {
+					t.Errorf("Expected repaired edits array in output, got %s", string(result))
+				}
+			})
+		}
+	}
+}
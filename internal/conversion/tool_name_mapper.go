@@ -0,0 +1,106 @@
+package conversion
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// MaxToolNameLength OpenAI 兼容端点对函数名的长度限制
+const MaxToolNameLength = 64
+
+// toolNameHashLength 截断或冲突时追加的哈希后缀长度
+const toolNameHashLength = 8
+
+// ToolNameMapper 工具名可逆映射
+// 部分 OpenAI 兼容端点要求函数名匹配 ^[a-zA-Z0-9_-]{1,64}$，而 MCP 工具名（mcp__server__tool）可能超长或包含其它字符。
+// 请求时将工具名替换为合法名称，响应时再还原为原始名称；本身合法的名称保持不变。
+type ToolNameMapper struct {
+	toUpstream map[string]string // 原始名称 -> 上游名称
+	toOriginal map[string]string // 上游名称 -> 原始名称
+}
+
+// NewToolNameMapper 创建工具名映射器
+func NewToolNameMapper() *ToolNameMapper {
+	return &ToolNameMapper{
+		toUpstream: make(map[string]string),
+		toOriginal: make(map[string]string),
+	}
+}
+
+// Map 返回原始工具名对应的上游名称，首次出现时分配并记录映射
+func (m *ToolNameMapper) Map(name string) string {
+	if mapped, ok := m.toUpstream[name]; ok {
+		return mapped
+	}
+
+	mapped := sanitizeToolName(name)
+	if owner, taken := m.toOriginal[mapped]; taken && owner != name {
+		// 不同原始名称规范化后冲突，追加哈希区分
+		mapped = appendToolNameHash(mapped, name)
+	}
+
+	m.toUpstream[name] = mapped
+	m.toOriginal[mapped] = name
+	return mapped
+}
+
+// Restore 将上游返回的工具名还原为原始名称，未知名称原样返回
+func (m *ToolNameMapper) Restore(name string) string {
+	if m == nil {
+		return name
+	}
+	if original, ok := m.toOriginal[name]; ok {
+		return original
+	}
+	return name
+}
+
+// Mappings 返回发生了变化的映射（上游名称 -> 原始名称），用于日志
+func (m *ToolNameMapper) Mappings() map[string]string {
+	changed := make(map[string]string)
+	for mapped, original := range m.toOriginal {
+		if mapped != original {
+			changed[mapped] = original
+		}
+	}
+	return changed
+}
+
+// sanitizeToolName 替换非法字符并在超长时截断，截断后追加原始名称的哈希保证唯一
+func sanitizeToolName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	sanitized := b.String()
+	if sanitized == "" {
+		sanitized = "tool"
+	}
+	if len(sanitized) > MaxToolNameLength {
+		return appendToolNameHash(sanitized, name)
+	}
+	return sanitized
+}
+
+// appendToolNameHash 在名称末尾追加原始名称的短哈希，总长度不超过 MaxToolNameLength
+func appendToolNameHash(name, original string) string {
+	sum := sha1.Sum([]byte(original))
+	suffix := "_" + hex.EncodeToString(sum[:])[:toolNameHashLength]
+	if len(name)+len(suffix) > MaxToolNameLength {
+		name = name[:MaxToolNameLength-len(suffix)]
+	}
+	return name + suffix
+}
+
+// restoreToolName 按转换上下文中的映射还原工具名
+func (ctx *ConversionContext) restoreToolName(name string) string {
+	if ctx == nil {
+		return name
+	}
+	return ctx.ToolNames.Restore(name)
+}
@@ -0,0 +1,176 @@
+package conversion
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var validUpstreamToolName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+func TestToolNameMapper(t *testing.T) {
+	longName := "mcp__" + strings.Repeat("very_long_server_name_", 3) + "__" + strings.Repeat("tool_", 6)
+
+	testCases := []struct {
+		name      string
+		input     string
+		unchanged bool
+	}{
+		{"valid name unchanged", "Read", true},
+		{"mcp name unchanged", "mcp__github__create_issue", true},
+		{"dots replaced", "mcp__server.v2__search", false},
+		{"non ascii replaced", "mcp__服务__查询", false},
+		{"long name shortened", longName, false},
+		{"empty name", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mapper := NewToolNameMapper()
+			mapped := mapper.Map(tc.input)
+
+			if !validUpstreamToolName.MatchString(mapped) {
+				t.Errorf("Mapped name %q does not satisfy upstream rules", mapped)
+			}
+			if tc.unchanged && mapped != tc.input {
+				t.Errorf("Expected %q to stay unchanged, got %q", tc.input, mapped)
+			}
+			if restored := mapper.Restore(mapped); restored != tc.input {
+				t.Errorf("Expected %q to be restored, got %q", tc.input, restored)
+			}
+			if again := mapper.Map(tc.input); again != mapped {
+				t.Errorf("Mapping is not stable: %q then %q", mapped, again)
+			}
+		})
+	}
+
+	t.Run("collisions stay distinct", func(t *testing.T) {
+		mapper := NewToolNameMapper()
+		a := mapper.Map("mcp__a.b")
+		b := mapper.Map("mcp__a_b")
+		c := mapper.Map("mcp__a b")
+		if a == b || b == c || a == c {
+			t.Fatalf("Expected distinct names, got %q %q %q", a, b, c)
+		}
+		for original, mapped := range map[string]string{"mcp__a.b": a, "mcp__a_b": b, "mcp__a b": c} {
+			if restored := mapper.Restore(mapped); restored != original {
+				t.Errorf("Expected %q, got %q", original, restored)
+			}
+		}
+	})
+
+	t.Run("unknown names pass through", func(t *testing.T) {
+		var mapper *ToolNameMapper
+		if got := mapper.Restore("Bash"); got != "Bash" {
+			t.Errorf("Expected Bash, got %q", got)
+		}
+	})
+}
+
+func TestToolNameMapping_RoundTrip(t *testing.T) {
+	original := "mcp__" + strings.Repeat("x", 40) + ".server__" + strings.Repeat("y", 30)
+
+	anthReq := map[string]interface{}{
+		"model":      "claude-3-5-sonnet",
+		"max_tokens": 100,
+		"tools": []interface{}{
+			map[string]interface{}{"name": original, "input_schema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"q": map[string]interface{}{"type": "string"}}}},
+		},
+		"tool_choice": map[string]interface{}{"type": "tool", "name": original},
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": "search"},
+			map[string]interface{}{"role": "assistant", "content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": original, "input": map[string]interface{}{"q": "a"}},
+			}},
+			map[string]interface{}{"role": "user", "content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": "ok"},
+			}},
+		},
+	}
+	body, _ := json.Marshal(anthReq)
+
+	result, ctx, err := NewRequestConverter(getTestLogger()).Convert(body, &EndpointInfo{Type: "openai"})
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	var oaReq OpenAIRequest
+	if err := json.Unmarshal(result, &oaReq); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+
+	mapped := oaReq.Tools[0].Function.Name
+	if mapped == original || !validUpstreamToolName.MatchString(mapped) {
+		t.Fatalf("Expected a shortened, sanitized tool name, got %q", mapped)
+	}
+
+	choice, _ := oaReq.ToolChoice.(map[string]interface{})
+	function, _ := choice["function"].(map[string]interface{})
+	if function["name"] != mapped {
+		t.Errorf("Expected tool_choice to reference %q, got %v", mapped, oaReq.ToolChoice)
+	}
+
+	historyMapped := false
+	for _, m := range oaReq.Messages {
+		for _, tc := range m.ToolCalls {
+			if tc.Function.Name == mapped {
+				historyMapped = true
+			}
+		}
+	}
+	if !historyMapped {
+		t.Errorf("Expected history tool_use to use the mapped name")
+	}
+
+	responseConverter := NewResponseConverter(getTestLogger())
+
+	t.Run("non-streaming", func(t *testing.T) {
+		resp := OpenAIResponse{
+			ID:    "chatcmpl-names",
+			Model: "gpt-4",
+			Choices: []OpenAIChoice{{
+				FinishReason: "tool_calls",
+				Message: OpenAIMessage{
+					Role: "assistant",
+					ToolCalls: []OpenAIToolCall{{
+						ID:       "call_1",
+						Type:     "function",
+						Function: OpenAIToolCallDetail{Name: mapped, Arguments: `{"q":"b"}`},
+					}},
+				},
+			}},
+		}
+		respBody, _ := json.Marshal(resp)
+
+		out, err := responseConverter.Convert(respBody, ctx, false)
+		if err != nil {
+			t.Fatalf("Conversion failed: %v", err)
+		}
+		var anthResp AnthropicResponse
+		if err := json.Unmarshal(out, &anthResp); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(anthResp.Content) != 1 || anthResp.Content[0].Name != original {
+			t.Errorf("Expected restored tool name %q, got %+v", original, anthResp.Content)
+		}
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		stream := `data: {"id":"chatcmpl-names","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"` + mapped + `","arguments":""}}]}}]}` + "\n\n" +
+			`data: {"id":"chatcmpl-names","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"q\":\"b\"}"}}]}}]}` + "\n\n" +
+			`data: {"id":"chatcmpl-names","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n" +
+			"data: [DONE]\n\n"
+
+		out, err := responseConverter.Convert([]byte(stream), ctx, true)
+		if err != nil {
+			t.Fatalf("Conversion failed: %v", err)
+		}
+		if strings.Contains(string(out), mapped) {
+			t.Errorf("Mapped tool name leaked into the stream: %s", string(out))
+		}
+		if !strings.Contains(string(out), `"name":"`+original+`"`) {
+			t.Errorf("Expected restored tool name in content_block_start, got %s", string(out))
+		}
+	})
+}
@@ -0,0 +1,480 @@
+package conversion
+
+import (
+	"sort"
+	"strings"
+)
+
+// 工具 JSON Schema 规范化档位（对应端点配置 tool_schema_profile）
+const (
+	ToolSchemaProfileNone         = "none"          // 原样透传
+	ToolSchemaProfileLenient      = "lenient"       // 仅移除元数据关键字并保证根节点为 object（默认）
+	ToolSchemaProfileOpenAIStrict = "openai_strict" // OpenAI function parameters 严格校验可接受的子集
+	ToolSchemaProfileGemini       = "gemini"        // Gemini / OpenAPI 3.0 子集
+)
+
+// 各档位都会移除的元数据关键字
+var schemaMetadataKeywords = []string{"$schema", "$id", "$comment"}
+
+// OpenAI 严格模式支持的 string format
+var openAIStrictFormats = map[string]bool{
+	"date-time": true, "time": true, "date": true, "duration": true,
+	"email": true, "hostname": true, "ipv4": true, "ipv6": true, "uuid": true,
+}
+
+// Gemini 支持的 format（按类型区分）
+var geminiFormats = map[string]map[string]bool{
+	"string":  {"enum": true, "date-time": true},
+	"integer": {"int32": true, "int64": true},
+	"number":  {"float": true, "double": true},
+}
+
+// Gemini 可识别的 schema 字段，其余字段一律丢弃
+var geminiAllowedKeywords = map[string]bool{
+	"type": true, "format": true, "title": true, "description": true, "nullable": true,
+	"enum": true, "properties": true, "required": true, "items": true,
+	"minItems": true, "maxItems": true, "minimum": true, "maximum": true,
+	"minLength": true, "maxLength": true, "pattern": true, "anyOf": true,
+	"propertyOrdering": true,
+}
+
+// SanitizeToolSchema 按档位将工具的 input_schema 改写为上游可接受的形式
+// 输入不会被修改，返回值为深拷贝后的结果；未知档位按 lenient 处理
+func SanitizeToolSchema(schema map[string]interface{}, profile string) map[string]interface{} {
+	if profile == ToolSchemaProfileNone {
+		return schema
+	}
+
+	s := &schemaSanitizer{profile: profile}
+	if s.profile == "" {
+		s.profile = ToolSchemaProfileLenient
+	}
+
+	copied, _ := deepCopySchemaValue(schema).(map[string]interface{})
+	if copied == nil {
+		copied = map[string]interface{}{}
+	}
+
+	// 先展开本地 $ref（lenient 保持原样）
+	if s.profile != ToolSchemaProfileLenient {
+		s.defs = collectSchemaDefinitions(copied)
+		delete(copied, "$defs")
+		delete(copied, "definitions")
+	}
+
+	root := s.sanitizeRoot(copied)
+	return root
+}
+
+type schemaSanitizer struct {
+	profile string
+	defs    map[string]interface{}
+}
+
+// sanitizeRoot 处理根节点：根节点必须是带 properties 的 object
+func (s *schemaSanitizer) sanitizeRoot(root map[string]interface{}) map[string]interface{} {
+	for _, kw := range schemaMetadataKeywords {
+		delete(root, kw)
+	}
+
+	if s.profile != ToolSchemaProfileLenient {
+		root = s.flattenRootCombinators(root)
+		// 根节点不允许 enum / not
+		delete(root, "enum")
+		delete(root, "not")
+	}
+
+	if _, hasType := root["type"]; !hasType {
+		root["type"] = "object"
+	}
+	if root["type"] == "object" {
+		if _, hasProps := root["properties"]; !hasProps {
+			root["properties"] = map[string]interface{}{}
+		}
+	}
+
+	if s.profile == ToolSchemaProfileLenient {
+		return root
+	}
+	return s.sanitizeNode(root, 0)
+}
+
+// flattenRootCombinators 将根节点的 anyOf/oneOf/allOf 合并成单个 object
+// 合并后 required 取各分支的交集（allOf 取并集），保证任一分支的合法参数仍能通过
+func (s *schemaSanitizer) flattenRootCombinators(root map[string]interface{}) map[string]interface{} {
+	for _, combinator := range []string{"allOf", "anyOf", "oneOf"} {
+		branches, ok := root[combinator].([]interface{})
+		if !ok {
+			continue
+		}
+		delete(root, combinator)
+
+		properties, _ := root["properties"].(map[string]interface{})
+		if properties == nil {
+			properties = map[string]interface{}{}
+		}
+
+		var required []string
+		for i, b := range branches {
+			branch, ok := s.resolveRef(b).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if props, ok := branch["properties"].(map[string]interface{}); ok {
+				for name, prop := range props {
+					if _, exists := properties[name]; !exists {
+						properties[name] = prop
+					}
+				}
+			}
+			branchRequired := toStringSlice(branch["required"])
+			switch {
+			case combinator == "allOf":
+				required = unionStrings(required, branchRequired)
+			case i == 0:
+				required = branchRequired
+			default:
+				required = intersectStrings(required, branchRequired)
+			}
+		}
+
+		root["properties"] = properties
+		root["required"] = unionStrings(toStringSlice(root["required"]), required)
+		if len(toStringSlice(root["required"])) == 0 {
+			delete(root, "required")
+		}
+		root["type"] = "object"
+	}
+	return root
+}
+
+// sanitizeNode 递归处理子 schema
+func (s *schemaSanitizer) sanitizeNode(node map[string]interface{}, depth int) map[string]interface{} {
+	// 防御循环引用（自引用的 $ref 会无限展开）
+	if depth > 10 {
+		return map[string]interface{}{"type": "object"}
+	}
+
+	if ref, ok := node["$ref"].(string); ok {
+		resolved, ok := s.lookupRef(ref)
+		delete(node, "$ref")
+		if ok {
+			for k, v := range deepCopySchemaValue(resolved).(map[string]interface{}) {
+				if _, exists := node[k]; !exists {
+					node[k] = v
+				}
+			}
+		} else if _, hasType := node["type"]; !hasType {
+			node["type"] = "object"
+		}
+	}
+
+	for _, kw := range schemaMetadataKeywords {
+		delete(node, kw)
+	}
+
+	switch s.profile {
+	case ToolSchemaProfileOpenAIStrict:
+		s.applyOpenAIStrictRules(node)
+	case ToolSchemaProfileGemini:
+		s.applyGeminiRules(node)
+	}
+
+	// 递归子节点
+	if props, ok := node["properties"].(map[string]interface{}); ok {
+		for name, prop := range props {
+			if child, ok := prop.(map[string]interface{}); ok {
+				props[name] = s.sanitizeNode(child, depth+1)
+			}
+		}
+	}
+	if items, ok := node["items"].(map[string]interface{}); ok {
+		node["items"] = s.sanitizeNode(items, depth+1)
+	}
+	if ap, ok := node["additionalProperties"].(map[string]interface{}); ok {
+		node["additionalProperties"] = s.sanitizeNode(ap, depth+1)
+	}
+	for _, combinator := range []string{"anyOf", "oneOf", "allOf"} {
+		if branches, ok := node[combinator].([]interface{}); ok {
+			for i, b := range branches {
+				if child, ok := b.(map[string]interface{}); ok {
+					branches[i] = s.sanitizeNode(child, depth+1)
+				}
+			}
+		}
+	}
+
+	// required 中只能出现存在的属性
+	if props, ok := node["properties"].(map[string]interface{}); ok {
+		if required := toStringSlice(node["required"]); len(required) > 0 {
+			var kept []string
+			for _, name := range required {
+				if _, exists := props[name]; exists {
+					kept = append(kept, name)
+				}
+			}
+			if len(kept) > 0 {
+				node["required"] = stringsToInterfaces(kept)
+			} else {
+				delete(node, "required")
+			}
+		}
+	}
+
+	return node
+}
+
+// applyOpenAIStrictRules OpenAI 严格档位：不支持的 format 移除，enum 值类型统一
+func (s *schemaSanitizer) applyOpenAIStrictRules(node map[string]interface{}) {
+	delete(node, "examples")
+
+	if format, ok := node["format"].(string); ok && !openAIStrictFormats[format] {
+		delete(node, "format")
+	}
+
+	normalizeMixedEnum(node)
+}
+
+// applyGeminiRules Gemini 档位：只保留 OpenAPI 3.0 子集
+func (s *schemaSanitizer) applyGeminiRules(node map[string]interface{}) {
+	// const → 单值 enum
+	if c, ok := node["const"]; ok {
+		if _, hasEnum := node["enum"]; !hasEnum {
+			node["enum"] = []interface{}{c}
+		}
+		delete(node, "const")
+	}
+
+	// oneOf → anyOf（语义上更宽松，Gemini 仅支持 anyOf）
+	if oneOf, ok := node["oneOf"]; ok {
+		if _, hasAnyOf := node["anyOf"]; !hasAnyOf {
+			node["anyOf"] = oneOf
+		}
+		delete(node, "oneOf")
+	}
+
+	// type: ["string", "null"] → type: "string", nullable: true
+	if types, ok := node["type"].([]interface{}); ok {
+		var nonNull []string
+		for _, t := range types {
+			if ts, ok := t.(string); ok {
+				if ts == "null" {
+					node["nullable"] = true
+				} else {
+					nonNull = append(nonNull, ts)
+				}
+			}
+		}
+		switch len(nonNull) {
+		case 0:
+			node["type"] = "string"
+		case 1:
+			node["type"] = nonNull[0]
+		default:
+			// 多类型改写为 anyOf
+			delete(node, "type")
+			var branches []interface{}
+			for _, t := range nonNull {
+				branches = append(branches, map[string]interface{}{"type": t})
+			}
+			node["anyOf"] = branches
+		}
+	}
+
+	for key := range node {
+		if !geminiAllowedKeywords[key] {
+			delete(node, key)
+		}
+	}
+
+	if format, ok := node["format"].(string); ok {
+		typ, _ := node["type"].(string)
+		if !geminiFormats[typ][format] {
+			delete(node, "format")
+		}
+	}
+
+	// Gemini 的 enum 只能是字符串；含数值或布尔值时去掉 enum 并保留原类型，
+	// 否则模型会按字符串返回参数，客户端收到的类型与其 schema 不符
+	if enum, ok := node["enum"].([]interface{}); ok {
+		strs := make([]interface{}, 0, len(enum))
+		var values []interface{}
+		for _, v := range enum {
+			if v == nil {
+				node["nullable"] = true
+				continue
+			}
+			if sv, ok := v.(string); ok {
+				strs = append(strs, sv)
+			}
+			values = append(values, v)
+		}
+		if len(strs) == len(values) {
+			node["enum"] = strs
+			node["type"] = "string"
+		} else {
+			delete(node, "enum")
+			if _, hasType := node["type"]; !hasType {
+				if typ := commonJSONType(values); typ != "" {
+					node["type"] = typ
+				}
+			}
+		}
+	}
+}
+
+// commonJSONType 所有值的 JSON 类型相同时返回该类型，否则返回空字符串
+func commonJSONType(values []interface{}) string {
+	typ := ""
+	for _, v := range values {
+		t := jsonTypeName(v)
+		if typ != "" && t != typ {
+			return ""
+		}
+		typ = t
+	}
+	return typ
+}
+
+// normalizeMixedEnum 当 enum 中混合了多种类型时，按类型拆分为 anyOf 分支，每个成员保留原始类型
+// 例如 enum: [1, "high", true] → anyOf: [{type: number, enum: [1]}, {type: string, enum: ["high"]}, {type: boolean, enum: [true]}]
+// 节点已有 anyOf 时无法合并，保持原样
+func normalizeMixedEnum(node map[string]interface{}) {
+	enum, ok := node["enum"].([]interface{})
+	if !ok || len(enum) == 0 {
+		return
+	}
+	if _, hasAnyOf := node["anyOf"]; hasAnyOf {
+		return
+	}
+
+	var kinds []string
+	members := map[string][]interface{}{}
+	for _, v := range enum {
+		kind := jsonTypeName(v)
+		if _, seen := members[kind]; !seen {
+			kinds = append(kinds, kind)
+		}
+		members[kind] = append(members[kind], v)
+	}
+	if len(kinds) <= 1 {
+		return
+	}
+
+	branches := make([]interface{}, 0, len(kinds))
+	for _, kind := range kinds {
+		branch := map[string]interface{}{"type": kind}
+		if kind != "null" {
+			branch["enum"] = members[kind]
+		}
+		branches = append(branches, branch)
+	}
+	delete(node, "enum")
+	delete(node, "type")
+	node["anyOf"] = branches
+}
+
+// collectSchemaDefinitions 收集 $defs / definitions 供 $ref 展开
+func collectSchemaDefinitions(root map[string]interface{}) map[string]interface{} {
+	defs := map[string]interface{}{}
+	for _, key := range []string{"definitions", "$defs"} {
+		if d, ok := root[key].(map[string]interface{}); ok {
+			for name, v := range d {
+				defs["#/"+key+"/"+name] = v
+			}
+		}
+	}
+	return defs
+}
+
+func (s *schemaSanitizer) lookupRef(ref string) (map[string]interface{}, bool) {
+	if s.defs == nil || !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	def, ok := s.defs[ref].(map[string]interface{})
+	return def, ok
+}
+
+func (s *schemaSanitizer) resolveRef(v interface{}) interface{} {
+	node, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	if ref, ok := node["$ref"].(string); ok {
+		if resolved, ok := s.lookupRef(ref); ok {
+			return resolved
+		}
+	}
+	return node
+}
+
+// deepCopySchemaValue 深拷贝 JSON 值（map / slice / 标量）
+func deepCopySchemaValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = deepCopySchemaValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = deepCopySchemaValue(item)
+		}
+		return out
+	default:
+		return val
+	}
+}
+
+func toStringSlice(v interface{}) []string {
+	switch val := v.(type) {
+	case []string:
+		return val
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func unionStrings(a, b []string) []string {
+	set := map[string]bool{}
+	var out []string
+	for _, v := range append(append([]string{}, a...), b...) {
+		if !set[v] {
+			set[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func intersectStrings(a, b []string) []string {
+	set := map[string]bool{}
+	for _, v := range b {
+		set[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if set[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
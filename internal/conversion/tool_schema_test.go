@@ -0,0 +1,147 @@
+package conversion
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func mustSchema(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		t.Fatalf("invalid schema fixture: %v", err)
+	}
+	return m
+}
+
+func TestSanitizeToolSchema(t *testing.T) {
+	testCases := []struct {
+		name     string
+		profile  string
+		input    string
+		expected string
+	}{
+		{
+			name:     "none keeps schema untouched",
+			profile:  ToolSchemaProfileNone,
+			input:    `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","properties":{"url":{"type":"string","format":"uri"}}}`,
+			expected: `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","properties":{"url":{"type":"string","format":"uri"}}}`,
+		},
+		{
+			name:     "lenient strips metadata only",
+			profile:  ToolSchemaProfileLenient,
+			input:    `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","additionalProperties":false,"properties":{"url":{"type":"string","format":"uri"}}}`,
+			expected: `{"type":"object","additionalProperties":false,"properties":{"url":{"type":"string","format":"uri"}}}`,
+		},
+		{
+			name:     "empty profile defaults to lenient",
+			profile:  "",
+			input:    `{"$schema":"x"}`,
+			expected: `{"type":"object","properties":{}}`,
+		},
+		{
+			name:     "openai strict drops unsupported format and normalizes mixed enum",
+			profile:  ToolSchemaProfileOpenAIStrict,
+			input:    `{"type":"object","properties":{"url":{"type":"string","format":"uri"},"when":{"type":"string","format":"date-time"},"level":{"enum":[1,"high",true]}},"required":["url","missing"]}`,
+			expected: `{"type":"object","properties":{"url":{"type":"string"},"when":{"type":"string","format":"date-time"},"level":{"anyOf":[{"type":"number","enum":[1]},{"type":"string","enum":["high"]},{"type":"boolean","enum":[true]}]}},"required":["url"]}`,
+		},
+		{
+			name:     "openai strict flattens root anyOf",
+			profile:  ToolSchemaProfileOpenAIStrict,
+			input:    `{"anyOf":[{"type":"object","properties":{"a":{"type":"string"},"b":{"type":"number"}},"required":["a","b"]},{"type":"object","properties":{"a":{"type":"string"},"c":{"type":"boolean"}},"required":["a"]}]}`,
+			expected: `{"type":"object","properties":{"a":{"type":"string"},"b":{"type":"number"},"c":{"type":"boolean"}},"required":["a"]}`,
+		},
+		{
+			name:     "openai strict inlines local refs",
+			profile:  ToolSchemaProfileOpenAIStrict,
+			input:    `{"type":"object","$defs":{"Item":{"type":"object","properties":{"id":{"type":"string"}}}},"properties":{"items":{"type":"array","items":{"$ref":"#/$defs/Item"}}}}`,
+			expected: `{"type":"object","properties":{"items":{"type":"array","items":{"type":"object","properties":{"id":{"type":"string"}}}}}}`,
+		},
+		{
+			name:     "gemini keeps OpenAPI subset",
+			profile:  ToolSchemaProfileGemini,
+			input:    `{"$schema":"x","type":"object","additionalProperties":false,"properties":{"url":{"type":"string","format":"uri","default":"a"},"name":{"type":["string","null"]},"mode":{"const":"fast"},"count":{"type":"integer","enum":[1,2]},"flag":{"const":true},"choice":{"oneOf":[{"type":"string"},{"type":"number"}]}}}`,
+			expected: `{"type":"object","properties":{"url":{"type":"string"},"name":{"type":"string","nullable":true},"mode":{"type":"string","enum":["fast"]},"count":{"type":"integer"},"flag":{"type":"boolean"},"choice":{"anyOf":[{"type":"string"},{"type":"number"}]}}}`,
+		},
+		{
+			name:     "self referencing ref terminates",
+			profile:  ToolSchemaProfileGemini,
+			input:    `{"type":"object","definitions":{"Node":{"type":"object","properties":{"child":{"$ref":"#/definitions/Node"}}}},"properties":{"root":{"$ref":"#/definitions/Node"}}}`,
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := mustSchema(t, tc.input)
+			original := mustSchema(t, tc.input)
+
+			result := SanitizeToolSchema(input, tc.profile)
+
+			if !reflect.DeepEqual(input, original) {
+				t.Errorf("input schema was modified in place")
+			}
+			if tc.expected == "" {
+				if result["type"] != "object" {
+					t.Errorf("expected object root, got %v", result)
+				}
+				return
+			}
+
+			// 通过 JSON 往返比较，避免 []string / []interface{} 的差异
+			resultJSON, _ := json.Marshal(result)
+			var got, want interface{}
+			json.Unmarshal(resultJSON, &got)
+			json.Unmarshal([]byte(tc.expected), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected schema\n got: %s\nwant: %s", resultJSON, tc.expected)
+			}
+		})
+	}
+}
+
+func TestConvertAnthropicRequestToOpenAI_ToolSchemaProfile(t *testing.T) {
+	converter := NewRequestConverter(getTestLogger())
+
+	anthReq := `{
+		"model": "claude-3-5-sonnet",
+		"max_tokens": 100,
+		"messages": [{"role": "user", "content": "fetch it"}],
+		"tools": [{
+			"name": "WebFetch",
+			"description": "Fetch a URL",
+			"input_schema": {
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type": "object",
+				"additionalProperties": false,
+				"properties": {"url": {"type": "string", "format": "uri"}},
+				"required": ["url"]
+			}
+		}]
+	}`
+
+	result, _, err := converter.Convert([]byte(anthReq), &EndpointInfo{Type: "openai", ToolSchemaProfile: ToolSchemaProfileGemini})
+	if err != nil {
+		t.Fatalf("Conversion failed: %v", err)
+	}
+
+	var oaReq OpenAIRequest
+	if err := json.Unmarshal(result, &oaReq); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if len(oaReq.Tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %d", len(oaReq.Tools))
+	}
+
+	params := oaReq.Tools[0].Function.Parameters
+	for _, key := range []string{"$schema", "additionalProperties"} {
+		if _, exists := params[key]; exists {
+			t.Errorf("Expected %s to be removed, got %v", key, params)
+		}
+	}
+	url := params["properties"].(map[string]interface{})["url"].(map[string]interface{})
+	if _, exists := url["format"]; exists {
+		t.Errorf("Expected format: uri to be removed, got %v", url)
+	}
+}
@@ -4,6 +4,17 @@ package conversion
 type EndpointInfo struct {
 	Type               string
 	MaxTokensFieldName string
+	ToolSchemaProfile  string // 工具 JSON Schema 规范化档位，见 ToolSchemaProfile* 常量
+	ToolArgumentValidation string // 工具参数校验策略，见 ToolArgumentValidation* 常量
+	EnforceSingleToolCall  bool   // 端点忽略 parallel_tool_calls 时，是否在响应中只保留第一个工具调用
+	GeminiSafetySettings   map[string]string // gemini 端点的安全过滤阈值（category -> threshold）
+	VertexProjectID        string            // vertex-anthropic 端点的 GCP 项目 ID
+	VertexRegion           string            // vertex-anthropic 端点的区域
+	AzureAPIVersion        string            // azure-openai 端点的 api-version
+	AzureDeployments       map[string]string // azure-openai 端点的模型名 -> 部署名映射
+	OpenAIAPI              string            // openai 端点的上游协议："chat_completions"（默认）或 "responses"
+	OllamaOptions          map[string]interface{} // ollama 端点的默认 options（含 num_ctx）
+	OllamaKeepAlive        string                 // ollama 端点的 keep_alive
 }
 
 // Converter 定义转换器接口
@@ -25,6 +36,12 @@ type ConversionContext struct {
 	IsStreaming     bool                   // 是否为流式请求
 	RequestHeaders  map[string]string      // 原始请求头
 	StopSequences   []string               // 请求中的停止序列，用于响应时检测
+	ToolSchemas     map[string]map[string]interface{} // 工具名 -> 原始 input_schema，用于校验 tool_use 参数
+	ToolArgumentValidation string           // 工具参数校验策略
+	ToolArgumentRepairs    []string         // 响应转换时对工具调用所做的修改记录（参数修复、丢弃多余的并行调用）
+	ToolNames       *ToolNameMapper        // 工具名映射（上游名称 <-> 原始名称），响应时还原
+	DisableParallelToolUse bool             // 客户端是否禁用了并行工具调用
+	EnforceSingleToolCall  bool             // 禁用并行工具调用时，响应中多余的工具调用是否丢弃
 	// 注意：不包含模型映射，因为转换发生在模型重写之后
 }
 
@@ -88,6 +88,11 @@ func (c *UnifiedConverter) generateMessageStartEvent(msg *AggregatedMessage) (An
 // generateContentEvents creates content block events for text and tool calls
 func (c *UnifiedConverter) generateContentEvents(msg *AggregatedMessage, blockIndex *int) ([]AnthropicSSEEvent, error) {
 	var events []AnthropicSSEEvent
+
+	// Generate thinking events first, matching Anthropic's block order
+	if len(msg.ThinkingContent) > 0 {
+		events = append(events, c.generateThinkingEvents(msg.ThinkingContent, blockIndex)...)
+	}
 	
 	// Generate text content events if present
 	if len(msg.TextContent) > 0 {
@@ -122,6 +127,41 @@ func (c *UnifiedConverter) generateContentEvents(msg *AggregatedMessage, blockIn
 	return events, nil
 }
 
+// generateThinkingEvents creates events for a thinking block (no signature is available from OpenAI upstreams)
+func (c *UnifiedConverter) generateThinkingEvents(thinkingContent string, blockIndex *int) []AnthropicSSEEvent {
+	currentIndex := *blockIndex
+	*blockIndex++
+
+	return []AnthropicSSEEvent{
+		{
+			Type: "content_block_start",
+			Data: &AnthropicContentBlockStart{
+				Type:         "content_block_start",
+				Index:        currentIndex,
+				ContentBlock: &AnthropicContentBlockForStart{Type: "thinking"},
+			},
+		},
+		{
+			Type: "content_block_delta",
+			Data: &AnthropicContentBlockDelta{
+				Type:  "content_block_delta",
+				Index: currentIndex,
+				Delta: &AnthropicContentBlock{
+					Type:     "thinking_delta",
+					Thinking: thinkingContent,
+				},
+			},
+		},
+		{
+			Type: "content_block_stop",
+			Data: &AnthropicContentBlockStop{
+				Type:  "content_block_stop",
+				Index: currentIndex,
+			},
+		},
+	}
+}
+
 // generateTextEvents creates events for text content
 func (c *UnifiedConverter) generateTextEvents(textContent string, blockIndex *int) ([]AnthropicSSEEvent, error) {
 	var events []AnthropicSSEEvent
@@ -247,8 +287,8 @@ func (c *UnifiedConverter) generateMessageEndEvents(msg *AggregatedMessage) ([]A
 	messageDelta := &AnthropicMessageDelta{
 		Type: "message_delta",
 		Delta: &AnthropicMessageDeltaContent{
-			StopReason: msg.FinishReason,
-			// StopSequence is omitted (nil) as it's not used in OpenAI responses
+			StopReason:   msg.FinishReason,
+			StopSequence: msg.StopSequence, // Only set when a stop sequence was matched
 		},
 	}
 
@@ -0,0 +1,83 @@
+package conversion
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+// UpstreamRequest 适配后发往上游的请求
+type UpstreamRequest struct {
+	Path      string     // 相对于端点 URL 的请求路径
+	Query     url.Values // 需要附加的查询参数
+	Body      []byte
+	Streaming bool // 是否向上游请求了流式响应
+}
+
+// UpstreamAdapter 在代理内部使用的请求格式与上游原生协议之间转换
+// 代理内部只处理 Anthropic Messages 与 OpenAI Chat Completions 两种格式，
+// gemini、bedrock、vertex-anthropic、ollama 等原生协议端点（以及使用 Responses API 的 openai 端点）按对应格式走完转换流程后，由适配器在收发时做最后一步转换
+type UpstreamAdapter interface {
+	// AdaptRequest 将内部格式的请求体转换为上游请求
+	AdaptRequest(body []byte) (*UpstreamRequest, error)
+
+	// AdaptResponse 将上游响应转换回内部格式（流式响应转换为对应格式的 SSE）
+	AdaptResponse(body []byte, isStreaming bool) ([]byte, error)
+}
+
+// NewUpstreamAdapter 按端点类型创建上游适配器，上游协议即内部格式时返回 nil
+// 适配器可能在请求与响应之间保存状态，每次请求都需要创建新的实例
+func NewUpstreamAdapter(endpointInfo *EndpointInfo, logger *logger.Logger) UpstreamAdapter {
+	if endpointInfo == nil {
+		return nil
+	}
+
+	switch endpointInfo.Type {
+	case "gemini":
+		return NewGeminiAdapter(endpointInfo, logger)
+	case "bedrock":
+		return NewBedrockAdapter(logger)
+	case "vertex-anthropic":
+		return NewVertexAnthropicAdapter(endpointInfo, logger)
+	case "azure-openai":
+		return NewAzureOpenAIAdapter(endpointInfo, logger)
+	case "ollama":
+		return NewOllamaAdapter(endpointInfo, logger)
+	case "openai":
+		// openai 端点默认直接使用 Chat Completions，配置 openai_api: responses 时改用 Responses API
+		if endpointInfo.OpenAIAPI == OpenAIAPIResponses {
+			return NewResponsesAdapter(endpointInfo, logger)
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// compatChatRequest 解析 Chat Completions 请求；兼容 Codex 转换后保留的 Responses 风格字段
+type compatChatRequest struct {
+	OpenAIRequest
+	Tools     []compatChatTool `json:"tools,omitempty"`
+	Stop      interface{}      `json:"stop,omitempty"` // string | []string
+	Reasoning *struct {
+		Effort string `json:"effort,omitempty"`
+	} `json:"reasoning,omitempty"`
+}
+
+// compatChatTool 同时兼容 {"type":"function","function":{...}} 与 Responses 风格的扁平结构
+type compatChatTool struct {
+	Type        string                 `json:"type"`
+	Function    *OpenAIFunctionDef     `json:"function,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// newToolCallID 为上游未提供 ID 的工具调用生成唯一 ID
+func newToolCallID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
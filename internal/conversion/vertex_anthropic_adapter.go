@@ -0,0 +1,78 @@
+package conversion
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+// VertexAnthropicVersion Vertex AI 上 Anthropic Messages API 的固定版本号
+const VertexAnthropicVersion = "vertex-2023-10-16"
+
+// VertexAnthropicAdapter 将 Anthropic Messages 请求转换为 Vertex AI rawPredict / streamRawPredict 请求
+// 模型名移到路径中（经过 model_rewrite 后的模型名即 Vertex 模型 ID，如 claude-sonnet-4@20250514），
+// 响应（包括 SSE）与 Anthropic 原生格式一致，无需转换
+type VertexAnthropicAdapter struct {
+	logger    *logger.Logger
+	projectID string
+	region    string
+}
+
+// NewVertexAnthropicAdapter 创建 Vertex AI Anthropic 上游适配器
+func NewVertexAnthropicAdapter(endpointInfo *EndpointInfo, logger *logger.Logger) *VertexAnthropicAdapter {
+	return &VertexAnthropicAdapter{
+		logger:    logger,
+		projectID: endpointInfo.VertexProjectID,
+		region:    endpointInfo.VertexRegion,
+	}
+}
+
+// AdaptRequest 移除 model 字段，设置 anthropic_version，并生成 rawPredict 路径
+func (a *VertexAnthropicAdapter) AdaptRequest(body []byte) (*UpstreamRequest, error) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, NewConversionError("parse_error", "Failed to parse request for Vertex AI conversion", err)
+	}
+
+	model, _ := req["model"].(string)
+	if model == "" {
+		return nil, NewConversionError("unsupported_feature", "Vertex AI request requires a model", nil)
+	}
+	if a.projectID == "" || a.region == "" {
+		return nil, NewConversionError("unsupported_feature", "Vertex AI endpoint requires project_id and region", nil)
+	}
+	streaming, _ := req["stream"].(bool)
+
+	// stream 字段保留：streamRawPredict 依据它返回 SSE
+	delete(req, "model")
+	if _, ok := req["anthropic_version"]; !ok {
+		req["anthropic_version"] = VertexAnthropicVersion
+	}
+
+	converted, err := json.Marshal(req)
+	if err != nil {
+		return nil, NewConversionError("marshal_error", "Failed to marshal Vertex AI request", err)
+	}
+
+	path := "/projects/" + url.PathEscape(a.projectID) +
+		"/locations/" + url.PathEscape(a.region) +
+		"/publishers/anthropic/models/" + url.PathEscape(model)
+	if streaming {
+		path += ":streamRawPredict"
+	} else {
+		path += ":rawPredict"
+	}
+
+	return &UpstreamRequest{
+		Path:      path,
+		Query:     url.Values{},
+		Body:      converted,
+		Streaming: streaming,
+	}, nil
+}
+
+// AdaptResponse Vertex AI 返回的即是 Anthropic 消息与 SSE，原样返回
+func (a *VertexAnthropicAdapter) AdaptResponse(body []byte, isStreaming bool) ([]byte, error) {
+	return body, nil
+}
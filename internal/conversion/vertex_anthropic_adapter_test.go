@@ -0,0 +1,68 @@
+package conversion
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVertexAnthropicAdapter_AdaptRequest(t *testing.T) {
+	adapter := NewUpstreamAdapter(&EndpointInfo{
+		Type:            "vertex-anthropic",
+		VertexProjectID: "my-project",
+		VertexRegion:    "us-east5",
+	}, getTestLogger())
+
+	testCases := []struct {
+		name         string
+		request      string
+		expectedPath string
+		streaming    bool
+	}{
+		{
+			name:         "streaming",
+			request:      `{"model":"claude-sonnet-4@20250514","max_tokens":100,"stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+			expectedPath: "/projects/my-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4@20250514:streamRawPredict",
+			streaming:    true,
+		},
+		{
+			name:         "non-streaming",
+			request:      `{"model":"claude-3-5-haiku@20241022","max_tokens":100,"messages":[{"role":"user","content":"hi"}]}`,
+			expectedPath: "/projects/my-project/locations/us-east5/publishers/anthropic/models/claude-3-5-haiku@20241022:rawPredict",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			upstream, err := adapter.AdaptRequest([]byte(tc.request))
+			if err != nil {
+				t.Fatalf("AdaptRequest failed: %v", err)
+			}
+			if upstream.Path != tc.expectedPath {
+				t.Errorf("Expected path %s, got %s", tc.expectedPath, upstream.Path)
+			}
+			if upstream.Streaming != tc.streaming {
+				t.Errorf("Expected streaming=%v, got %v", tc.streaming, upstream.Streaming)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(upstream.Body, &body); err != nil {
+				t.Fatalf("Failed to unmarshal Vertex request: %v", err)
+			}
+			if _, ok := body["model"]; ok {
+				t.Error("Expected model to be removed from the body")
+			}
+			if stream, _ := body["stream"].(bool); stream != tc.streaming {
+				t.Errorf("Expected stream=%v to be kept in the body", tc.streaming)
+			}
+			if body["anthropic_version"] != VertexAnthropicVersion {
+				t.Errorf("Expected anthropic_version %s, got %v", VertexAnthropicVersion, body["anthropic_version"])
+			}
+		})
+	}
+
+	// 未配置项目或区域时报错
+	missing := NewUpstreamAdapter(&EndpointInfo{Type: "vertex-anthropic"}, getTestLogger())
+	if _, err := missing.AdaptRequest([]byte(`{"model":"claude-sonnet-4@20250514","messages":[]}`)); err == nil {
+		t.Error("Expected error when project and region are missing")
+	}
+}
@@ -2,14 +2,20 @@ package endpoint
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"claude-code-codex-companion/internal/aws"
+	"claude-code-codex-companion/internal/azure"
 	"claude-code-codex-companion/internal/common/httpclient"
 	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/conversion"
+	"claude-code-codex-companion/internal/gcp"
 	"claude-code-codex-companion/internal/interfaces"
+	"claude-code-codex-companion/internal/metrics"
 	"claude-code-codex-companion/internal/oauth"
 	"claude-code-codex-companion/internal/statistics"
 	"claude-code-codex-companion/internal/utils"
@@ -41,7 +47,7 @@ type Endpoint struct {
 	ID                string                   `json:"id"`
 	Name              string                   `json:"name"`
 	URL               string                   `json:"url"`
-	EndpointType      string                   `json:"endpoint_type"` // "anthropic" | "openai" 等
+	EndpointType      string                   `json:"endpoint_type"` // "anthropic" | "openai" | "gemini" | "bedrock" | "vertex-anthropic" | "azure-openai" | "ollama"
 	PathPrefix        string                   `json:"path_prefix,omitempty"` // OpenAI端点的路径前缀
 	AuthType          string                   `json:"auth_type"`
 	AuthValue         string                   `json:"auth_value"`
@@ -51,9 +57,19 @@ type Endpoint struct {
 	ModelRewrite      *config.ModelRewriteConfig `json:"model_rewrite,omitempty"` // 新增：模型重写配置
 	Proxy             *config.ProxyConfig      `json:"proxy,omitempty"` // 新增：代理配置
 	OAuthConfig       *config.OAuthConfig      `json:"oauth_config,omitempty"` // 新增：OAuth配置
+	AWSConfig         *config.AWSConfig        `json:"aws_config,omitempty"`   // bedrock 端点的 SigV4 签名配置
+	VertexConfig      *config.VertexConfig     `json:"vertex_config,omitempty"` // vertex-anthropic 端点的项目、区域与服务账号配置
+	AzureConfig       *config.AzureConfig      `json:"azure_config,omitempty"`  // azure-openai 端点的部署映射、API 版本与 Entra ID 配置
+	OllamaConfig      *config.OllamaConfig     `json:"ollama_config,omitempty"` // ollama 端点的 num_ctx、keep_alive 等 options
+	TLS               *config.TLSConfig        `json:"tls,omitempty"`           // 自定义 CA、客户端证书（mTLS）与证书固定
 	HeaderOverrides     map[string]string      `json:"header_overrides,omitempty"`     // 新增：HTTP Header覆盖配置
 	ParameterOverrides  map[string]string      `json:"parameter_overrides,omitempty"` // 新增：Request Parameters覆盖配置
 	MaxTokensFieldName  string                 `json:"max_tokens_field_name,omitempty"` // max_tokens 参数名转换选项
+	ToolSchemaProfile   string                 `json:"tool_schema_profile,omitempty"`   // 工具 JSON Schema 规范化档位
+	ToolArgumentValidation string              `json:"tool_argument_validation,omitempty"` // 工具参数校验策略
+	EnforceSingleToolCall  bool                `json:"enforce_single_tool_call,omitempty"` // 禁用并行工具调用时强制单工具调用
+	GeminiSafetySettings map[string]string     `json:"gemini_safety_settings,omitempty"` // gemini 端点的安全过滤阈值
+	OpenAIAPI            string                `json:"openai_api,omitempty"`             // openai 端点的上游协议（chat_completions / responses）
 	RateLimitReset      *int64                 `json:"rate_limit_reset,omitempty"`      // Anthropic-Ratelimit-Unified-Reset
 	RateLimitStatus     *string                `json:"rate_limit_status,omitempty"`     // Anthropic-Ratelimit-Unified-Status
 	EnhancedProtection  bool                   `json:"enhanced_protection,omitempty"`   // 官方帐号增强保护：allowed_warning时即禁用端点
@@ -88,9 +104,31 @@ type Endpoint struct {
 	// 新增：保护 LearnedUnsupportedParams 的互斥锁
 	learnedParamsMutex sync.RWMutex
 
+	// 服务账号访问令牌缓存（gcp_service_account 认证，运行时获取，不持久化）
+	gcpTokenSource *gcp.TokenSource
+
+	// Entra ID 访问令牌缓存（entra_id 认证，运行时获取，不持久化）
+	entraTokenSource *azure.TokenSource
+
+	// OAuth token 刷新状态与历史（运行时记录，不持久化，由 e.mutex 保护，每次更新替换为新的快照）
+	OAuthRefresh *OAuthRefreshStatus `json:"oauth_refresh,omitempty"`
+
+	// 串行化 OAuth token 刷新：换取 token 期间不持有 e.mutex，避免阻塞读取认证头部的请求
+	oauthRefreshMutex sync.Mutex
+
+	// 全局代理配置（端点未配置 proxy 时使用，由 Manager 设置）
+	globalProxy *config.GlobalProxyConfig
+
 	mutex               sync.RWMutex
 }
 
+// SetGlobalProxy 设置全局代理配置，端点未配置 proxy 时所有出站请求使用全局代理
+func (e *Endpoint) SetGlobalProxy(globalProxy *config.GlobalProxyConfig) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.globalProxy = globalProxy
+}
+
 func NewEndpoint(cfg config.EndpointConfig) *Endpoint {
 	// 如果没有指定 endpoint_type，使用统一默认值
 	endpointType := config.GetStringWithDefault(cfg.EndpointType, config.Default.Endpoint.Type)
@@ -109,9 +147,19 @@ func NewEndpoint(cfg config.EndpointConfig) *Endpoint {
 		ModelRewrite:      cfg.ModelRewrite, // 新增：从配置中复制模型重写配置
 		Proxy:             cfg.Proxy,      // 新增：从配置中复制代理配置
 		OAuthConfig:       cfg.OAuthConfig, // 新增：从配置中复制OAuth配置
+		AWSConfig:         cfg.AWSConfig,   // 新增：从配置中复制AWS签名配置
+		VertexConfig:      cfg.VertexConfig, // 新增：从配置中复制Vertex AI配置
+		AzureConfig:       cfg.AzureConfig,  // 新增：从配置中复制Azure OpenAI配置
+		OllamaConfig:      cfg.OllamaConfig, // 新增：从配置中复制Ollama配置
+		TLS:               cfg.TLS,          // 从配置中复制TLS配置
 		HeaderOverrides:     cfg.HeaderOverrides,     // 新增：从配置中复制HTTP Header覆盖配置
 		ParameterOverrides:  cfg.ParameterOverrides,  // 新增：从配置中复制Request Parameters覆盖配置
 		MaxTokensFieldName:  cfg.MaxTokensFieldName,  // 新增：从配置中复制max_tokens参数名转换选项
+		ToolSchemaProfile:   cfg.ToolSchemaProfile,   // 新增：从配置中复制工具Schema规范化档位
+		ToolArgumentValidation: cfg.ToolArgumentValidation, // 新增：从配置中复制工具参数校验策略
+		EnforceSingleToolCall:  cfg.EnforceSingleToolCall,  // 新增：从配置中复制单工具调用强制选项
+		GeminiSafetySettings: cfg.GeminiSafetySettings,   // 新增：从配置中复制Gemini安全设置
+		OpenAIAPI:            cfg.OpenAIAPI,              // 新增：从配置中复制OpenAI上游协议
 		RateLimitReset:      cfg.RateLimitReset,      // 新增：从配置加载rate limit reset状态
 		RateLimitStatus:     cfg.RateLimitStatus,     // 新增：从配置加载rate limit status状态
 		EnhancedProtection:  cfg.EnhancedProtection,  // 新增：从配置加载官方帐号增强保护设置
@@ -119,6 +167,8 @@ func NewEndpoint(cfg config.EndpointConfig) *Endpoint {
 		Status:            StatusActive,
 		LastCheck:         time.Now(),
 		RequestHistory:    utils.NewCircularBuffer(100, 140*time.Second), // 100个记录，140秒窗口
+		gcpTokenSource:    gcp.NewTokenSource(cfg.VertexConfig),
+		entraTokenSource:  azure.NewTokenSource(cfg.AzureConfig),
 	}
 }
 
@@ -155,11 +205,62 @@ func (e *Endpoint) GetAuthHeader() (string, error) {
 		}
 		
 		return oauth.GetAuthorizationHeader(e.OAuthConfig), nil
+	case "aws_sigv4":
+		return "", fmt.Errorf("aws_sigv4 requests must be signed with SignAWSRequest")
+	case "gcp_service_account":
+		token, ok := e.gcpTokenSource.Cached()
+		if !ok {
+			return "", fmt.Errorf("service account token expired, refresh required")
+		}
+		return "Bearer " + token, nil
+	case "entra_id":
+		token, ok := e.entraTokenSource.Cached()
+		if !ok {
+			return "", fmt.Errorf("entra id token expired, refresh required")
+		}
+		return "Bearer " + token, nil
 	default:
 		return e.AuthValue, nil
 	}
 }
 
+// GetFormat 返回端点使用的内部请求格式："anthropic" 或 "openai"
+func (e *Endpoint) GetFormat() string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return config.GetEndpointFormat(e.EndpointType)
+}
+
+// SetAPIKey 为 api_key 认证类型设置认证信息：gemini 端点使用 x-goog-api-key 头部，azure-openai 端点使用 api-key 头部，
+// 其它端点使用 x-api-key 头部；密钥不放入 URL，避免出现在传输错误、链路追踪和告警中
+func (e *Endpoint) SetAPIKey(req *http.Request) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	switch e.EndpointType {
+	case "gemini":
+		req.Header.Set("x-goog-api-key", e.AuthValue)
+	case "azure-openai":
+		req.Header.Set("api-key", e.AuthValue)
+	default:
+		req.Header.Set("x-api-key", e.AuthValue)
+	}
+}
+
+// SignAWSRequest 为 aws_sigv4 认证类型的请求计算 SigV4 签名
+// 签名覆盖 URL 与请求体，必须在所有头部和查询参数设置完成之后调用
+func (e *Endpoint) SignAWSRequest(req *http.Request, body []byte) error {
+	e.mutex.RLock()
+	awsConfig := e.AWSConfig
+	e.mutex.RUnlock()
+
+	creds, err := aws.LoadCredentials(awsConfig)
+	if err != nil {
+		return err
+	}
+	return aws.SignRequest(req, body, creds, aws.ResolveRegion(awsConfig), "bedrock", time.Now())
+}
+
 func (e *Endpoint) GetTags() []string {
 	e.mutex.RLock()
 	defer e.mutex.RUnlock()
@@ -170,6 +271,86 @@ func (e *Endpoint) GetTags() []string {
 	return tags
 }
 
+// GetAzureDeployments 返回 azure-openai 端点的 API 版本与模型到部署名的映射
+func (e *Endpoint) GetAzureDeployments() (string, map[string]string) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.AzureConfig == nil {
+		return "", nil
+	}
+	return e.AzureConfig.APIVersion, e.AzureConfig.Deployments
+}
+
+// GetOllamaOptions 返回 ollama 端点的默认 options（num_ctx 合并到 options 中）与 keep_alive
+func (e *Endpoint) GetOllamaOptions() (map[string]interface{}, string) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.OllamaConfig == nil {
+		return nil, ""
+	}
+	options := make(map[string]interface{}, len(e.OllamaConfig.Options)+1)
+	for key, value := range e.OllamaConfig.Options {
+		options[key] = value
+	}
+	if e.OllamaConfig.NumCtx > 0 {
+		options["num_ctx"] = e.OllamaConfig.NumCtx
+	}
+	return options, e.OllamaConfig.KeepAlive
+}
+
+// ListOllamaModels 通过 /api/tags 获取 ollama 端点上可用的模型，供模型重写配置选择
+func (e *Endpoint) ListOllamaModels(timeoutConfig config.HealthCheckTimeoutConfig) ([]string, error) {
+	if e.EndpointType != "ollama" {
+		return nil, fmt.Errorf("model discovery is only supported for ollama endpoints")
+	}
+
+	client, err := e.CreateHealthClient(timeoutConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client for model discovery: %v", err)
+	}
+
+	req, err := http.NewRequest("GET", e.GetFullURL("/api/tags"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create model discovery request: %v", err)
+	}
+	switch e.AuthType {
+	case "api_key":
+		e.SetAPIKey(req)
+	case "auth_token":
+		if e.AuthValue != "" {
+			req.Header.Set("Authorization", "Bearer "+e.AuthValue)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send model discovery request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model discovery response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model discovery failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return conversion.ParseOllamaModels(body)
+}
+
+// GetVertexTarget 返回 vertex-anthropic 端点的项目 ID 与区域，未配置时返回空字符串
+func (e *Endpoint) GetVertexTarget() (string, string) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.VertexConfig == nil {
+		return "", ""
+	}
+	return e.VertexConfig.ProjectID, e.VertexConfig.Region
+}
+
 // GetHeaderOverrides 安全地获取Header覆盖配置的副本
 func (e *Endpoint) GetHeaderOverrides() map[string]string {
 	e.mutex.RLock()
@@ -245,6 +426,25 @@ func (e *Endpoint) GetFullURL(path string) string {
         }
 
         return fullURL
+	case "bedrock":
+		// Bedrock 端点：请求路径由上游适配器生成（/model/{modelId}/invoke）
+		return baseURL + path
+	case "azure-openai":
+		// Azure OpenAI 端点：请求路径由上游适配器生成（/openai/deployments/{deployment}/chat/completions 或 /openai/responses）
+		return baseURL + path
+	case "ollama":
+		// Ollama 端点：请求路径由上游适配器生成（/api/chat）
+		return baseURL + path
+	case "vertex-anthropic":
+		// Vertex AI 端点：请求路径由上游适配器生成（/projects/{project}/locations/{region}/publishers/anthropic/models/{model}:rawPredict）
+		return baseURL + "/v1" + path
+	case "gemini":
+		// Gemini 端点：PathPrefix 默认为 /v1beta，请求路径由上游适配器生成（如 /models/{model}:generateContent）
+		prefix := e.PathPrefix
+		if prefix == "" {
+			prefix = "/v1beta"
+		}
+		return baseURL + prefix + path
 	default:
 		// 向后兼容：默认使用 anthropic 格式，需要添加 /v1 前缀
 		return baseURL + "/v1" + path
@@ -304,35 +504,60 @@ func (e *Endpoint) RecordRequest(success bool, requestID string) {
 
 func (e *Endpoint) MarkInactive() {
 	e.mutex.Lock()
-	defer e.mutex.Unlock()
+	changed := e.Status != StatusInactive
+	if changed {
+		metrics.BlacklistTransitions.WithLabelValues(e.Name, "blacklisted").Inc()
+	}
 	e.Status = StatusInactive
+	e.mutex.Unlock()
+
+	if changed {
+		e.notifyStatusChange(StatusChange{Change: ChangeBlacklisted})
+	}
 }
 
 // MarkInactiveWithReason 标记端点为失效并记录原因
 func (e *Endpoint) MarkInactiveWithReason() {
 	e.mutex.Lock()
-	defer e.mutex.Unlock()
+	var reason *BlacklistReason
+	defer func() {
+		e.mutex.Unlock()
+		if reason != nil {
+			e.notifyStatusChange(StatusChange{Change: ChangeBlacklisted, Reason: reason.ErrorSummary})
+		}
+	}()
 	
 	if e.Status == StatusActive {
 		e.Status = StatusInactive
+		metrics.BlacklistTransitions.WithLabelValues(e.Name, "blacklisted").Inc()
 		
 		// 从循环缓冲区获取导致失效的请求ID
 		failedRequestIDs := e.RequestHistory.GetRecentFailureRequestIDs(time.Now())
 		
 		// 构建失效原因记录
 		e.blacklistMutex.Lock()
-		e.BlacklistReason = &BlacklistReason{
+		reason = &BlacklistReason{
 			BlacklistedAt:     time.Now(),
 			CausingRequestIDs: failedRequestIDs,
 			ErrorSummary:      fmt.Sprintf("Endpoint failed due to %d consecutive failures", len(failedRequestIDs)),
 		}
+		e.BlacklistReason = reason
 		e.blacklistMutex.Unlock()
 	}
 }
 
 func (e *Endpoint) MarkActive() {
 	e.mutex.Lock()
-	defer e.mutex.Unlock()
+	recovered := e.Status == StatusInactive
+	defer func() {
+		e.mutex.Unlock()
+		if recovered {
+			e.notifyStatusChange(StatusChange{Change: ChangeRecovered})
+		}
+	}()
+	if recovered {
+		metrics.BlacklistTransitions.WithLabelValues(e.Name, "recovered").Inc()
+	}
 	e.Status = StatusActive
 	e.FailureCount = 0
 	e.SuccessiveSuccesses = 0 // 重置连续成功次数
@@ -355,6 +580,23 @@ func (e *Endpoint) GetSuccessiveSuccesses() int {
 	return e.SuccessiveSuccesses
 }
 
+// GetMonitoringStatus 返回用于监控的端点状态：
+// disabled（未启用）、cooling（失效且在等待 rate limit 重置）、或 active / inactive / checking
+func (e *Endpoint) GetMonitoringStatus() string {
+	e.mutex.RLock()
+	enabled := e.Enabled
+	status := e.Status
+	e.mutex.RUnlock()
+
+	if !enabled {
+		return "disabled"
+	}
+	if status == StatusInactive && e.ShouldSkipHealthCheckUntilReset() {
+		return "cooling"
+	}
+	return string(status)
+}
+
 
 func generateID(name string) string {
 	// Use stable ID based on endpoint name hash for statistics persistence
@@ -376,6 +618,8 @@ func parseDuration(durationStr string, defaultDuration time.Duration) time.Durat
 func (e *Endpoint) CreateProxyClient(timeoutConfig config.ProxyTimeoutConfig) (*http.Client, error) {
 	e.mutex.RLock()
 	proxyConfig := e.Proxy
+	tlsConfig := e.TLS
+	globalProxy := e.globalProxy
 	e.mutex.RUnlock()
 	
 	factory := httpclient.NewFactory()
@@ -388,6 +632,8 @@ func (e *Endpoint) CreateProxyClient(timeoutConfig config.ProxyTimeoutConfig) (*
 			OverallRequest: parseDuration(timeoutConfig.OverallRequest, 0),
 		},
 		ProxyConfig: proxyConfig,
+		TLSConfig:   tlsConfig,
+		GlobalProxy: globalProxy,
 	}
 	
 	return factory.CreateClient(clientConfig)
@@ -397,6 +643,8 @@ func (e *Endpoint) CreateProxyClient(timeoutConfig config.ProxyTimeoutConfig) (*
 func (e *Endpoint) CreateHealthClient(timeoutConfig config.HealthCheckTimeoutConfig) (*http.Client, error) {
 	e.mutex.RLock()
 	proxyConfig := e.Proxy
+	tlsConfig := e.TLS
+	globalProxy := e.globalProxy
 	e.mutex.RUnlock()
 	
 	factory := httpclient.NewFactory()
@@ -409,6 +657,8 @@ func (e *Endpoint) CreateHealthClient(timeoutConfig config.HealthCheckTimeoutCon
 			OverallRequest: parseDuration(timeoutConfig.OverallRequest, 30*time.Second),
 		},
 		ProxyConfig: proxyConfig,
+		TLSConfig:   tlsConfig,
+		GlobalProxy: globalProxy,
 	}
 	
 	return factory.CreateClient(clientConfig)
@@ -421,20 +671,58 @@ func (e *Endpoint) RefreshOAuthToken(timeoutConfig config.ProxyTimeoutConfig) er
 
 // RefreshOAuthTokenWithCallback 刷新 OAuth token 并可选地调用回调函数
 func (e *Endpoint) RefreshOAuthTokenWithCallback(timeoutConfig config.ProxyTimeoutConfig, onTokenRefreshed func(*Endpoint) error) error {
-	e.mutex.Lock()
-	defer e.mutex.Unlock()
-	
+	_, err := e.refreshOAuthToken(timeoutConfig, onTokenRefreshed, OAuthRefreshTriggerRequest, nil)
+	return err
+}
+
+// refreshOAuthToken 刷新 OAuth token 并记录刷新历史
+// due 不为空时在取得刷新锁后再次确认是否仍需刷新，避免与请求触发的刷新重复；返回值表示是否实际发起了刷新
+// 同一端点的刷新由 oauthRefreshMutex 串行执行，向 token 端点的请求在 e.mutex 之外进行
+func (e *Endpoint) refreshOAuthToken(timeoutConfig config.ProxyTimeoutConfig, onTokenRefreshed func(*Endpoint) error, trigger string, due func(*config.OAuthConfig) bool) (bool, error) {
+	e.oauthRefreshMutex.Lock()
+	defer e.oauthRefreshMutex.Unlock()
+
+	e.mutex.RLock()
 	if e.AuthType != "oauth" {
-		return fmt.Errorf("endpoint is not configured for oauth authentication")
+		e.mutex.RUnlock()
+		return false, fmt.Errorf("endpoint is not configured for oauth authentication")
 	}
-	
 	if e.OAuthConfig == nil {
-		return fmt.Errorf("oauth config is nil")
+		e.mutex.RUnlock()
+		return false, fmt.Errorf("oauth config is nil")
 	}
-	
-	// 创建HTTP客户端用于刷新请求
-	factory := httpclient.NewFactory()
-	clientConfig := httpclient.ClientConfig{
+	if due != nil && !due(e.OAuthConfig) {
+		e.mutex.RUnlock()
+		return false, nil
+	}
+	current := *e.OAuthConfig
+	clientConfig := e.oauthRefreshClientConfig(timeoutConfig)
+	e.mutex.RUnlock()
+
+	newOAuthConfig, err := exchangeOAuthRefreshToken(&current, clientConfig)
+	if err == nil {
+		e.mutex.Lock()
+		e.OAuthConfig = newOAuthConfig
+		e.mutex.Unlock()
+
+		// 持久化回调读取 e.OAuthConfig；OAuthConfig 只在持有刷新锁时被替换，此处无需持有 e.mutex
+		if onTokenRefreshed != nil {
+			if persistErr := onTokenRefreshed(e); persistErr != nil {
+				// 回调失败，但token已经刷新成功，只记录错误
+				err = fmt.Errorf("oauth token refreshed successfully but failed to persist to config file: %v", persistErr)
+			}
+		}
+	}
+
+	e.mutex.Lock()
+	e.recordOAuthRefresh(trigger, err)
+	e.mutex.Unlock()
+	return true, err
+}
+
+// oauthRefreshClientConfig 刷新请求使用的 HTTP 客户端配置，调用方需持有 e.mutex
+func (e *Endpoint) oauthRefreshClientConfig(timeoutConfig config.ProxyTimeoutConfig) httpclient.ClientConfig {
+	return httpclient.ClientConfig{
 		Type: httpclient.ClientTypeProxy,
 		Timeouts: httpclient.TimeoutConfig{
 			TLSHandshake:   parseDuration(timeoutConfig.TLSHandshake, 10*time.Second),
@@ -443,31 +731,63 @@ func (e *Endpoint) RefreshOAuthTokenWithCallback(timeoutConfig config.ProxyTimeo
 			OverallRequest: parseDuration(timeoutConfig.OverallRequest, 30*time.Second),
 		},
 		ProxyConfig: e.Proxy,
+		GlobalProxy: e.globalProxy,
 	}
-	
-	client, err := factory.CreateClient(clientConfig)
+}
+
+// exchangeOAuthRefreshToken 在 token 端点使用 refresh token 换取新 token
+func exchangeOAuthRefreshToken(current *config.OAuthConfig, clientConfig httpclient.ClientConfig) (*config.OAuthConfig, error) {
+	client, err := httpclient.NewFactory().CreateClient(clientConfig)
 	if err != nil {
-		return fmt.Errorf("failed to create http client for token refresh: %v", err)
+		return nil, fmt.Errorf("failed to create http client for token refresh: %v", err)
 	}
-	
-	// 刷新token
-	newOAuthConfig, err := oauth.RefreshToken(e.OAuthConfig, client)
+
+	newOAuthConfig, err := oauth.RefreshToken(current, client)
 	if err != nil {
-		return fmt.Errorf("failed to refresh oauth token: %v", err)
+		return nil, fmt.Errorf("failed to refresh oauth token: %v", err)
 	}
-	
-	// 更新配置
-	e.OAuthConfig = newOAuthConfig
-	
-	// 如果提供了回调函数，调用它来处理配置持久化
-	if onTokenRefreshed != nil {
-		if err := onTokenRefreshed(e); err != nil {
-			// 回调失败，但token已经刷新成功，只记录错误
-			return fmt.Errorf("oauth token refreshed successfully but failed to persist to config file: %v", err)
-		}
+	return newOAuthConfig, nil
+}
+
+// MintAccessToken 为 gcp_service_account / entra_id 认证换取访问令牌，令牌未过期时直接返回缓存
+func (e *Endpoint) MintAccessToken(timeoutConfig config.ProxyTimeoutConfig) (string, error) {
+	var source interface {
+		Cached() (string, bool)
+		Token(httpClient *http.Client) (string, error)
 	}
-	
-	return nil
+	switch e.AuthType {
+	case "gcp_service_account":
+		source = e.gcpTokenSource
+	case "entra_id":
+		source = e.entraTokenSource
+	default:
+		return "", fmt.Errorf("endpoint auth_type '%s' does not mint access tokens", e.AuthType)
+	}
+
+	if token, ok := source.Cached(); ok {
+		return token, nil
+	}
+
+	// 创建HTTP客户端用于换取令牌
+	factory := httpclient.NewFactory()
+	clientConfig := httpclient.ClientConfig{
+		Type: httpclient.ClientTypeProxy,
+		Timeouts: httpclient.TimeoutConfig{
+			TLSHandshake:   parseDuration(timeoutConfig.TLSHandshake, 10*time.Second),
+			ResponseHeader: parseDuration(timeoutConfig.ResponseHeader, 60*time.Second),
+			IdleConnection: parseDuration(timeoutConfig.IdleConnection, 90*time.Second),
+			OverallRequest: parseDuration(timeoutConfig.OverallRequest, 30*time.Second),
+		},
+		ProxyConfig: e.Proxy,
+		GlobalProxy: e.globalProxy,
+	}
+
+	client, err := factory.CreateClient(clientConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to create http client for access token: %v", err)
+	}
+
+	return source.Token(client)
 }
 
 // GetAuthHeaderWithRefresh 获取认证头部，如果需要会自动刷新OAuth token
@@ -477,6 +797,15 @@ func (e *Endpoint) GetAuthHeaderWithRefresh(timeoutConfig config.ProxyTimeoutCon
 
 // GetAuthHeaderWithRefreshCallback 获取认证头部，如果需要会自动刷新OAuth token，支持回调
 func (e *Endpoint) GetAuthHeaderWithRefreshCallback(timeoutConfig config.ProxyTimeoutConfig, onTokenRefreshed func(*Endpoint) error) (string, error) {
+	// 服务账号与 Entra ID 令牌由端点自行换取与缓存，无需持久化
+	if e.AuthType == "gcp_service_account" || e.AuthType == "entra_id" {
+		token, err := e.MintAccessToken(timeoutConfig)
+		if err != nil {
+			return "", fmt.Errorf("failed to mint access token: %v", err)
+		}
+		return "Bearer " + token, nil
+	}
+
 	// 首先尝试获取认证头部
 	authHeader, err := e.GetAuthHeader()
 	
@@ -672,6 +1001,11 @@ func (e *Endpoint) ShouldDisableOnAllowedWarning() bool {
 	return true
 }
 
+// ProbesNativeCodexFormat 端点是否需要对 /responses 请求自动探测原生 Codex 格式支持
+func (e *Endpoint) ProbesNativeCodexFormat() bool {
+	return e.EndpointType == "openai" || e.EndpointType == "azure-openai"
+}
+
 // UpdateNativeCodexSupport 动态更新端点的Codex支持状态
 func (e *Endpoint) UpdateNativeCodexSupport(supported bool) {
 	e.mutex.Lock()
@@ -688,16 +1022,19 @@ func (e *Endpoint) UpdateNativeCodexSupport(supported bool) {
 // LearnUnsupportedParam 记录一个不支持的参数
 func (e *Endpoint) LearnUnsupportedParam(param string) {
 	e.learnedParamsMutex.Lock()
-	defer e.learnedParamsMutex.Unlock()
 	
 	// 检查是否已经记录
 	for _, p := range e.LearnedUnsupportedParams {
 		if p == param {
+			e.learnedParamsMutex.Unlock()
 			return // 已存在
 		}
 	}
 	
 	e.LearnedUnsupportedParams = append(e.LearnedUnsupportedParams, param)
+	e.learnedParamsMutex.Unlock()
+
+	e.notifyStatusChange(StatusChange{Change: ChangeLearnedParam, Param: param})
 }
 
 // IsParamUnsupported 检查参数是否已被学习为不支持
@@ -0,0 +1,35 @@
+package endpoint
+
+import (
+	"net/http"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestSetAPIKey(t *testing.T) {
+	testCases := []struct {
+		endpointType string
+		header       string
+	}{
+		{"gemini", "x-goog-api-key"},
+		{"azure-openai", "api-key"},
+		{"anthropic", "x-api-key"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.endpointType, func(t *testing.T) {
+			ep := NewEndpoint(config.EndpointConfig{Name: tc.endpointType, URL: "https://api.example.com", EndpointType: tc.endpointType, AuthType: "api_key", AuthValue: "secret-key"})
+			req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1beta/models/gemini:generateContent?alt=sse", nil)
+			ep.SetAPIKey(req)
+
+			if req.Header.Get(tc.header) != "secret-key" {
+				t.Errorf("Expected key in %s header, got headers %v", tc.header, req.Header)
+			}
+			// 密钥不能出现在 URL 中，否则会随传输错误写入日志、链路追踪和告警
+			if req.URL.RawQuery != "alt=sse" {
+				t.Errorf("Expected URL query to be unchanged, got %s", req.URL.RawQuery)
+			}
+		})
+	}
+}
@@ -41,6 +41,7 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 	endpoints := make([]*Endpoint, 0, len(cfg.Endpoints))
 	for _, endpointConfig := range cfg.Endpoints {
 		endpoint := NewEndpoint(endpointConfig)
+		endpoint.SetGlobalProxy(&cfg.Proxy)
 		
 		// Initialize or inherit statistics data
 		if err := initializeEndpointStatistics(endpoint, statisticsManager); err != nil {
@@ -121,6 +122,14 @@ func (m *Manager) RecordRequest(endpointID string, success bool, requestID strin
 	}
 }
 
+// GetTimeSeries 返回时间序列统计存储，统计仅保存在内存时为 nil
+func (m *Manager) GetTimeSeries() *statistics.TimeSeriesStore {
+	if m.statisticsManager == nil {
+		return nil
+	}
+	return m.statisticsManager.TimeSeries()
+}
+
 func (m *Manager) UpdateEndpoints(endpointConfigs []config.EndpointConfig) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -141,6 +150,7 @@ func (m *Manager) UpdateEndpoints(endpointConfigs []config.EndpointConfig) {
 		} else {
 			// New endpoint - create fresh with inherited statistics from database
 			endpoint := NewEndpoint(cfg)
+			endpoint.SetGlobalProxy(&m.config.Proxy)
 			if m.statisticsManager != nil {
 				if err := initializeEndpointStatistics(endpoint, m.statisticsManager); err != nil {
 					log.Printf("WARNING: Failed to load statistics for new endpoint %s: %v", 
@@ -290,6 +300,7 @@ func initializeEndpointStatistics(endpoint *Endpoint, statisticsManager statisti
 func (m *Manager) updateExistingEndpoint(existingEndpoint *Endpoint, newConfig config.EndpointConfig) *Endpoint {
 	// Create new endpoint with updated configuration but preserve statistics
 	newEndpoint := NewEndpoint(newConfig)
+	newEndpoint.SetGlobalProxy(&m.config.Proxy)
 	
 	// Copy statistics from existing endpoint to preserve accumulated data
 	existingEndpoint.mutex.RLock()
@@ -304,6 +315,9 @@ func (m *Manager) updateExistingEndpoint(existingEndpoint *Endpoint, newConfig c
 	
 	// Preserve request history for health checking
 	newEndpoint.RequestHistory = existingEndpoint.RequestHistory
+
+	// Preserve oauth refresh history and backoff state
+	newEndpoint.OAuthRefresh = existingEndpoint.OAuthRefresh
 	newEndpoint.mutex.Unlock()
 	existingEndpoint.mutex.RUnlock()
 
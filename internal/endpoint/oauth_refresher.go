@@ -0,0 +1,179 @@
+package endpoint
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/oauth"
+)
+
+// OAuth token 刷新的触发来源
+const (
+	OAuthRefreshTriggerBackground = "background" // 后台刷新器在过期前主动刷新
+	OAuthRefreshTriggerRequest    = "request"    // 请求时发现 token 过期或认证失败
+)
+
+const (
+	// oauthRefreshCheckInterval 后台刷新器的检查间隔
+	oauthRefreshCheckInterval = 30 * time.Second
+	// oauthRefreshDegradedThreshold 连续失败多少次后将端点标记为降级
+	oauthRefreshDegradedThreshold = 3
+	// oauthRefreshHistorySize 保留的刷新历史条数
+	oauthRefreshHistorySize = 20
+)
+
+// OAuthRefreshRecord 一次 OAuth token 刷新的记录
+type OAuthRefreshRecord struct {
+	Time      time.Time `json:"time"`
+	Trigger   string    `json:"trigger"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	ExpiresAt int64     `json:"expires_at,omitempty"` // 刷新后 token 的过期时间（毫秒）
+}
+
+// OAuthRefreshStatus OAuth token 刷新状态
+type OAuthRefreshStatus struct {
+	ConsecutiveFailures int                  `json:"consecutive_failures"`
+	NextAttemptAt       time.Time            `json:"next_attempt_at,omitempty"` // 退避结束前后台刷新器不会重试
+	Degraded            bool                 `json:"degraded"`
+	DegradedReason      string               `json:"degraded_reason,omitempty"`
+	History             []OAuthRefreshRecord `json:"history"` // 最新的记录在前
+}
+
+// recordOAuthRefresh 记录一次刷新结果并更新退避与降级状态，调用方需持有 e.mutex
+func (e *Endpoint) recordOAuthRefresh(trigger string, refreshErr error) {
+	now := time.Now()
+	record := OAuthRefreshRecord{
+		Time:    now,
+		Trigger: trigger,
+		Success: refreshErr == nil,
+	}
+
+	next := &OAuthRefreshStatus{}
+	if e.OAuthRefresh != nil {
+		*next = *e.OAuthRefresh
+	}
+
+	if refreshErr == nil {
+		if e.OAuthConfig != nil {
+			record.ExpiresAt = e.OAuthConfig.ExpiresAt
+		}
+		if next.Degraded {
+			log.Printf("[OAuth] Endpoint %s recovered from degraded state after successful token refresh", e.Name)
+		}
+		next.ConsecutiveFailures = 0
+		next.NextAttemptAt = time.Time{}
+		next.Degraded = false
+		next.DegradedReason = ""
+	} else {
+		record.Error = refreshErr.Error()
+		next.ConsecutiveFailures++
+		next.NextAttemptAt = now.Add(oauth.RefreshBackoff(next.ConsecutiveFailures))
+		if next.ConsecutiveFailures >= oauthRefreshDegradedThreshold {
+			if !next.Degraded {
+				log.Printf("[OAuth] Endpoint %s marked as degraded after %d consecutive token refresh failures", e.Name, next.ConsecutiveFailures)
+			}
+			next.Degraded = true
+			next.DegradedReason = fmt.Sprintf("OAuth token refresh failed %d times in a row: %v", next.ConsecutiveFailures, refreshErr)
+		}
+	}
+
+	// 新记录放在最前面，超出上限时丢弃最旧的记录
+	history := make([]OAuthRefreshRecord, 0, oauthRefreshHistorySize)
+	history = append(history, record)
+	for _, r := range next.History {
+		if len(history) >= oauthRefreshHistorySize {
+			break
+		}
+		history = append(history, r)
+	}
+	next.History = history
+
+	e.OAuthRefresh = next
+}
+
+// GetOAuthRefreshStatus 获取 OAuth token 刷新状态快照
+func (e *Endpoint) GetOAuthRefreshStatus() *OAuthRefreshStatus {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.OAuthRefresh
+}
+
+// OAuthRefresher 后台 OAuth token 刷新器：在 token 过期前 margin 时间内主动刷新，失败时指数退避重试
+type OAuthRefresher struct {
+	manager          *Manager
+	settings         func() config.TimeoutConfig // 每次检查时读取最新的超时与刷新提前量配置（支持热更新）
+	onTokenRefreshed func(*Endpoint) error       // 刷新成功后持久化新 token
+	interval         time.Duration
+	stopCh           chan struct{}
+	stopOnce         sync.Once
+}
+
+// NewOAuthRefresher 创建后台 OAuth token 刷新器
+func NewOAuthRefresher(manager *Manager, settings func() config.TimeoutConfig, onTokenRefreshed func(*Endpoint) error) *OAuthRefresher {
+	return &OAuthRefresher{
+		manager:          manager,
+		settings:         settings,
+		onTokenRefreshed: onTokenRefreshed,
+		interval:         oauthRefreshCheckInterval,
+		stopCh:           make(chan struct{}),
+	}
+}
+
+// Start 启动后台刷新，启动时立即检查一次
+func (r *OAuthRefresher) Start() {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		r.RefreshDue()
+		for {
+			select {
+			case <-ticker.C:
+				r.RefreshDue()
+			case <-r.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台刷新
+func (r *OAuthRefresher) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+}
+
+// RefreshDue 检查所有启用的 OAuth 端点，刷新即将过期且不在退避期内的 token
+func (r *OAuthRefresher) RefreshDue() {
+	timeouts := r.settings()
+	margin := config.GetTimeoutDuration(timeouts.OAuthRefreshMargin, config.GetTimeoutDuration(config.Default.Timeouts.OAuthRefreshMargin, 10*time.Minute))
+
+	for _, ep := range r.manager.GetAllEndpoints() {
+		if !ep.Enabled || ep.AuthType != "oauth" {
+			continue
+		}
+
+		now := time.Now()
+		if status := ep.GetOAuthRefreshStatus(); status != nil && now.Before(status.NextAttemptAt) {
+			continue
+		}
+
+		due := func(oauthConfig *config.OAuthConfig) bool {
+			return oauth.IsRefreshDue(oauthConfig, margin, time.Now())
+		}
+		refreshed, err := ep.refreshOAuthToken(timeouts.ToProxyTimeoutConfig(), r.onTokenRefreshed, OAuthRefreshTriggerBackground, due)
+		if !refreshed {
+			continue
+		}
+		if err != nil {
+			log.Printf("[OAuth] Background token refresh failed for endpoint %s: %v", ep.Name, err)
+		} else {
+			log.Printf("[OAuth] Background token refresh succeeded for endpoint %s", ep.Name)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package endpoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+// fakeTokenServer 模拟 token 端点：release 为空时立即响应，否则等待 release 关闭后再响应
+type fakeTokenServer struct {
+	*httptest.Server
+	requests atomic.Int32
+	fail     atomic.Bool
+	release  chan struct{}
+}
+
+func newFakeTokenServer(t *testing.T) *fakeTokenServer {
+	t.Helper()
+	server := &fakeTokenServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.requests.Add(1)
+		if server.release != nil {
+			<-server.release
+		}
+		if server.fail.Load() {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "sk-ant-oat01-refreshed",
+			"refresh_token": "sk-ant-ort01-refreshed",
+			"expires_in":    3600,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newOAuthTestEndpoint(name, tokenURL string, expiresIn time.Duration) *Endpoint {
+	return NewEndpoint(config.EndpointConfig{
+		Name:         name,
+		URL:          "https://api.anthropic.com",
+		EndpointType: "anthropic",
+		AuthType:     "oauth",
+		Enabled:      true,
+		OAuthConfig: &config.OAuthConfig{
+			AccessToken:  "sk-ant-oat01-" + name,
+			RefreshToken: "sk-ant-ort01-" + name,
+			ExpiresAt:    time.Now().Add(expiresIn).UnixMilli(),
+			TokenURL:     tokenURL,
+			AutoRefresh:  true,
+		},
+	})
+}
+
+func newTestRefresher(endpoints []*Endpoint, onTokenRefreshed func(*Endpoint) error) *OAuthRefresher {
+	manager := &Manager{selector: NewSelector(endpoints), endpoints: endpoints}
+	return NewOAuthRefresher(manager, func() config.TimeoutConfig { return config.TimeoutConfig{} }, onTokenRefreshed)
+}
+
+func TestOAuthRefresher_RefreshesOnlyDueEndpoints(t *testing.T) {
+	server := newFakeTokenServer(t)
+	server.release = make(chan struct{})
+
+	due := newOAuthTestEndpoint("due", server.URL, 8*time.Minute)
+	fresh := newOAuthTestEndpoint("fresh", server.URL, time.Hour)
+	disabled := newOAuthTestEndpoint("disabled", server.URL, time.Minute)
+	disabled.Enabled = false
+
+	persisted := make(chan string, 3)
+	refresher := newTestRefresher([]*Endpoint{due, fresh, disabled}, func(ep *Endpoint) error {
+		persisted <- ep.Name + ":" + ep.OAuthConfig.AccessToken
+		return nil
+	})
+	refresher.interval = time.Hour
+	refresher.Start()
+	defer refresher.Stop()
+
+	// 换取 token 期间不持有 e.mutex，请求仍可读取当前认证头部
+	deadline := time.Now().Add(2 * time.Second)
+	for server.requests.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	headerRead := make(chan string, 1)
+	go func() {
+		header, _ := due.GetAuthHeader()
+		headerRead <- header
+	}()
+	select {
+	case header := <-headerRead:
+		if header != "Bearer sk-ant-oat01-due" {
+			t.Errorf("Expected current token during refresh, got %q", header)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("GetAuthHeader blocked while the token exchange was in flight")
+	}
+	close(server.release)
+
+	select {
+	case got := <-persisted:
+		if got != "due:sk-ant-oat01-refreshed" {
+			t.Errorf("Expected refreshed token to be persisted for the due endpoint, got %s", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected background refresh on start")
+	}
+
+	// 已刷新的端点不再到期，再次检查不会发起请求
+	refresher.RefreshDue()
+	if n := server.requests.Load(); n != 1 {
+		t.Errorf("Expected exactly one token request, got %d", n)
+	}
+	if len(persisted) != 0 {
+		t.Errorf("Expected no other endpoint to be refreshed, got %s", <-persisted)
+	}
+
+	status := due.GetOAuthRefreshStatus()
+	if status == nil || len(status.History) != 1 || !status.History[0].Success || status.History[0].Trigger != OAuthRefreshTriggerBackground {
+		t.Fatalf("Expected one successful background refresh record, got %+v", status)
+	}
+	if status.History[0].ExpiresAt != due.OAuthConfig.ExpiresAt {
+		t.Errorf("Expected record to carry the new expiry")
+	}
+	if fresh.GetOAuthRefreshStatus() != nil || disabled.GetOAuthRefreshStatus() != nil {
+		t.Errorf("Expected endpoints that are not due to have no refresh history")
+	}
+}
+
+func TestOAuthRefresher_DegradesAfterConsecutiveFailuresAndRecovers(t *testing.T) {
+	server := newFakeTokenServer(t)
+	server.fail.Store(true)
+
+	ep := newOAuthTestEndpoint("flaky", server.URL, time.Minute)
+	refresher := newTestRefresher([]*Endpoint{ep}, nil)
+
+	// 退避期内不重试；测试中手动结束退避以模拟时间流逝
+	endBackoff := func() {
+		ep.mutex.Lock()
+		status := *ep.OAuthRefresh
+		status.NextAttemptAt = time.Time{}
+		ep.OAuthRefresh = &status
+		ep.mutex.Unlock()
+	}
+
+	refresher.RefreshDue()
+	requests := server.requests.Load()
+	refresher.RefreshDue()
+	if server.requests.Load() != requests {
+		t.Errorf("Expected no retry during backoff")
+	}
+	status := ep.GetOAuthRefreshStatus()
+	if status.ConsecutiveFailures != 1 || status.Degraded || !status.NextAttemptAt.After(time.Now()) {
+		t.Errorf("Expected one failure with backoff and no degradation, got %+v", status)
+	}
+
+	for i := 1; i < oauthRefreshDegradedThreshold; i++ {
+		endBackoff()
+		refresher.RefreshDue()
+	}
+	status = ep.GetOAuthRefreshStatus()
+	if !status.Degraded || status.ConsecutiveFailures != oauthRefreshDegradedThreshold ||
+		!strings.Contains(status.DegradedReason, "3 times in a row") {
+		t.Errorf("Expected endpoint to be degraded after %d failures, got %+v", oauthRefreshDegradedThreshold, status)
+	}
+	if ep.OAuthConfig.AccessToken != "sk-ant-oat01-flaky" {
+		t.Errorf("Expected failed refreshes to keep the current token")
+	}
+
+	server.fail.Store(false)
+	endBackoff()
+	refresher.RefreshDue()
+	status = ep.GetOAuthRefreshStatus()
+	if status.Degraded || status.DegradedReason != "" || status.ConsecutiveFailures != 0 || !status.NextAttemptAt.IsZero() {
+		t.Errorf("Expected endpoint to recover after a successful refresh, got %+v", status)
+	}
+	if len(status.History) != oauthRefreshDegradedThreshold+1 || !status.History[0].Success || status.History[1].Success {
+		t.Errorf("Expected newest record first, got %+v", status.History)
+	}
+}
+
+func TestRecordOAuthRefresh_KeepsBoundedHistory(t *testing.T) {
+	ep := newOAuthTestEndpoint("history", "http://127.0.0.1", time.Hour)
+	for i := 0; i < oauthRefreshHistorySize+5; i++ {
+		ep.recordOAuthRefresh(OAuthRefreshTriggerRequest, nil)
+	}
+	if n := len(ep.GetOAuthRefreshStatus().History); n != oauthRefreshHistorySize {
+		t.Errorf("Expected history to be capped at %d, got %d", oauthRefreshHistorySize, n)
+	}
+}
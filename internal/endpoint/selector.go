@@ -204,8 +204,8 @@ func (s *Selector) isEndpointCompatible(ep *Endpoint, requestFormat string) bool
 	// 2. Anthropic 请求 → 优先 Anthropic 端点，也可以选择 OpenAI 端点（支持 Anthropic → OpenAI 转换）
 
 	if requestFormat == "openai" {
-		// OpenAI 请求只能发到 OpenAI 端点
-		return ep.EndpointType == "openai"
+		// OpenAI 请求只能发到 OpenAI 格式端点（含 gemini 等经适配器转换的端点）
+		return ep.GetFormat() == "openai"
 	}
 
 	if requestFormat == "anthropic" {
@@ -0,0 +1,39 @@
+package endpoint
+
+import "sync/atomic"
+
+// 端点状态变化类型
+const (
+	ChangeBlacklisted  = "blacklisted"
+	ChangeRecovered    = "recovered"
+	ChangeLearnedParam = "learned_param"
+)
+
+// StatusChange 端点状态变化通知
+type StatusChange struct {
+	Change string // blacklisted | recovered | learned_param
+	Status string // 变化后的监控状态，见 GetMonitoringStatus
+	Reason string // 拉黑原因摘要
+	Param  string // 学习到的不支持参数
+}
+
+// statusListener 状态变化监听器（实时日志流）
+var statusListener atomic.Pointer[func(*Endpoint, StatusChange)]
+
+// SetStatusChangeListener 设置端点状态变化监听器，传入 nil 取消监听
+// 监听器在端点锁释放后同步调用，不应阻塞
+func SetStatusChangeListener(listener func(*Endpoint, StatusChange)) {
+	if listener == nil {
+		statusListener.Store(nil)
+		return
+	}
+	statusListener.Store(&listener)
+}
+
+// notifyStatusChange 通知监听器，调用方不能持有端点锁
+func (e *Endpoint) notifyStatusChange(change StatusChange) {
+	if listener := statusListener.Load(); listener != nil {
+		change.Status = e.GetMonitoringStatus()
+		(*listener)(e, change)
+	}
+}
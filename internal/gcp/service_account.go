@@ -0,0 +1,130 @@
+package gcp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+// DefaultTokenURL Google OAuth2 token 端点，配置与密钥中均未指定时使用
+const DefaultTokenURL = "https://oauth2.googleapis.com/token"
+
+// DefaultScope Vertex AI 所需的权限范围
+const DefaultScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// jwtBearerGrantType RFC 7523 JWT bearer 授权类型
+const jwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// ServiceAccountKey 服务账号 JSON 密钥中用到的字段
+type ServiceAccountKey struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// LoadServiceAccountKey 按顺序读取密钥：配置中的内联 JSON -> 配置指定的文件 -> GOOGLE_APPLICATION_CREDENTIALS
+func LoadServiceAccountKey(cfg *config.VertexConfig) (*ServiceAccountKey, error) {
+	var data []byte
+	switch {
+	case cfg != nil && cfg.ServiceAccountJSON != "":
+		data = []byte(cfg.ServiceAccountJSON)
+	case cfg != nil && cfg.ServiceAccountFile != "":
+		content, err := os.ReadFile(cfg.ServiceAccountFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service account file: %v", err)
+		}
+		data = content
+	case os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "":
+		content, err := os.ReadFile(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GOOGLE_APPLICATION_CREDENTIALS: %v", err)
+		}
+		data = content
+	default:
+		return nil, fmt.Errorf("no service account key configured")
+	}
+
+	var key ServiceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %v", err)
+	}
+	if key.Type != "" && key.Type != "service_account" {
+		return nil, fmt.Errorf("unsupported credentials type '%s', expected 'service_account'", key.Type)
+	}
+	if key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, fmt.Errorf("service account key is missing client_email or private_key")
+	}
+	return &key, nil
+}
+
+// SignJWT 生成用于 JWT bearer 授权的 RS256 断言，有效期一小时
+func (k *ServiceAccountKey) SignJWT(audience string, scopes []string, issuedAt time.Time) (string, error) {
+	privateKey, err := parseRSAPrivateKey(k.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if k.PrivateKeyID != "" {
+		header["kid"] = k.PrivateKeyID
+	}
+	claims := map[string]interface{}{
+		"iss":   k.ClientEmail,
+		"scope": strings.Join(scopes, " "),
+		"aud":   audience,
+		"iat":   issuedAt.Unix(),
+		"exp":   issuedAt.Add(time.Hour).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwt: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey 解析 PEM 格式私钥，支持 PKCS#8（服务账号密钥的默认格式）与 PKCS#1
+func parseRSAPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("service account private_key is not valid PEM")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("service account private_key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private_key: %v", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,110 @@
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"claude-code-codex-companion/internal/common/tokencache"
+	"claude-code-codex-companion/internal/config"
+)
+
+// TokenSource 使用服务账号密钥换取访问令牌，并在过期前复用缓存的令牌
+type TokenSource struct {
+	*tokencache.Cache
+
+	cfg *config.VertexConfig
+	key *ServiceAccountKey // 仅在 mint 中读写，由缓存的锁保护
+	now func() time.Time
+}
+
+// NewTokenSource 创建令牌源；密钥在第一次获取令牌时才读取
+func NewTokenSource(cfg *config.VertexConfig) *TokenSource {
+	s := &TokenSource{cfg: cfg, now: time.Now}
+	s.Cache = tokencache.New(s.mint, func() time.Time { return s.now() })
+	return s
+}
+
+// mint 按需读取服务账号密钥后换取新的访问令牌
+func (s *TokenSource) mint(httpClient *http.Client) (string, time.Duration, error) {
+	if s.key == nil {
+		key, err := LoadServiceAccountKey(s.cfg)
+		if err != nil {
+			return "", 0, err
+		}
+		s.key = key
+	}
+	return s.exchange(httpClient)
+}
+
+// tokenURL token 端点：配置优先，其次为密钥中的 token_uri
+func (s *TokenSource) tokenURL() string {
+	if s.cfg != nil && s.cfg.TokenURL != "" {
+		return s.cfg.TokenURL
+	}
+	if s.key != nil && s.key.TokenURI != "" {
+		return s.key.TokenURI
+	}
+	return DefaultTokenURL
+}
+
+// exchange 通过 JWT bearer 授权换取访问令牌
+func (s *TokenSource) exchange(httpClient *http.Client) (string, time.Duration, error) {
+	scopes := []string{DefaultScope}
+	if s.cfg != nil && len(s.cfg.Scopes) > 0 {
+		scopes = s.cfg.Scopes
+	}
+
+	tokenURL := s.tokenURL()
+	assertion, err := s.key.SignJWT(tokenURL, scopes, s.now())
+	if err != nil {
+		return "", 0, err
+	}
+
+	formData := url.Values{}
+	formData.Set("grant_type", jwtBearerGrantType)
+	formData.Set("assertion", assertion)
+
+	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(formData.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to send token request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("service account token request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(respBody, &tokenResp); err != nil {
+		return "", 0, fmt.Errorf("failed to unmarshal token response: %v", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response missing access_token")
+	}
+
+	expiresIn := time.Duration(tokenResp.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		// 如果没有返回过期时间，按一小时处理
+		expiresIn = time.Hour
+	}
+	return tokenResp.AccessToken, expiresIn, nil
+}
@@ -0,0 +1,155 @@
+package gcp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func newTestServiceAccount(t *testing.T, tokenURI string) (*rsa.PrivateKey, string) {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	keyJSON, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test-project",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "proxy@test-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURI,
+	})
+	return privateKey, string(keyJSON)
+}
+
+// verifyAssertion 校验 JWT 签名并返回 claims
+func verifyAssertion(publicKey *rsa.PublicKey, assertion string) (map[string]interface{}, error) {
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expected 3 jwt segments, got %d", len(parts))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid signature: %v", err)
+	}
+
+	var header map[string]string
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header["alg"] != "RS256" || header["kid"] != "key-1" {
+		return nil, fmt.Errorf("unexpected jwt header: %s", headerJSON)
+	}
+
+	var claims map[string]interface{}
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func TestTokenSource_MintAndCache(t *testing.T) {
+	var privateKey *rsa.PrivateKey
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		if r.Form.Get("grant_type") != jwtBearerGrantType {
+			t.Errorf("Unexpected grant_type: %s", r.Form.Get("grant_type"))
+		}
+		claims, err := verifyAssertion(&privateKey.PublicKey, r.Form.Get("assertion"))
+		if err != nil {
+			t.Errorf("Assertion verification failed: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if claims["iss"] != "proxy@test-project.iam.gserviceaccount.com" || claims["scope"] != DefaultScope {
+			t.Errorf("Unexpected claims: %v", claims)
+		}
+		if claims["aud"] != "http://"+r.Host+"/token" {
+			t.Errorf("Expected audience to be the token URL, got %v", claims["aud"])
+		}
+		if exp, iat := claims["exp"].(float64), claims["iat"].(float64); exp-iat != 3600 {
+			t.Errorf("Expected one hour lifetime, got %v", exp-iat)
+		}
+		fmt.Fprintf(w, `{"access_token":"ya29.token-%d","expires_in":3599,"token_type":"Bearer"}`, requests)
+	}))
+	defer server.Close()
+
+	var keyJSON string
+	privateKey, keyJSON = newTestServiceAccount(t, server.URL+"/token")
+	source := NewTokenSource(&config.VertexConfig{ServiceAccountJSON: keyJSON})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	source.now = func() time.Time { return now }
+
+	if _, ok := source.Cached(); ok {
+		t.Error("Expected no cached token before first mint")
+	}
+
+	token, err := source.Token(server.Client())
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if token != "ya29.token-1" {
+		t.Errorf("Unexpected token: %s", token)
+	}
+
+	// 有效期内复用缓存
+	now = now.Add(50 * time.Minute)
+	if token, _ := source.Token(server.Client()); token != "ya29.token-1" || requests != 1 {
+		t.Errorf("Expected cached token, got %s after %d requests", token, requests)
+	}
+
+	// 进入提前刷新窗口后重新换取
+	now = now.Add(5 * time.Minute)
+	if _, ok := source.Cached(); ok {
+		t.Error("Expected cached token to be considered expired")
+	}
+	if token, _ := source.Token(server.Client()); token != "ya29.token-2" || requests != 2 {
+		t.Errorf("Expected refreshed token, got %s after %d requests", token, requests)
+	}
+}
+
+func TestTokenSource_ConfiguredTokenURLAndErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/custom" {
+			t.Errorf("Expected configured token URL to be used, got %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant","error_description":"Invalid JWT Signature."}`))
+	}))
+	defer server.Close()
+
+	_, keyJSON := newTestServiceAccount(t, "https://unused.example.com/token")
+	source := NewTokenSource(&config.VertexConfig{ServiceAccountJSON: keyJSON, TokenURL: server.URL + "/custom"})
+	if _, err := source.Token(server.Client()); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Expected invalid_grant error, got %v", err)
+	}
+
+	if _, err := NewTokenSource(&config.VertexConfig{ServiceAccountJSON: `{"type":"authorized_user"}`}).Token(server.Client()); err == nil {
+		t.Error("Expected error for non service account credentials")
+	}
+}
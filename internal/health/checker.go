@@ -98,6 +98,9 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 		endpointInfo := &conversion.EndpointInfo{
 			Type:               ep.EndpointType,
 			MaxTokensFieldName: ep.MaxTokensFieldName,
+			ToolSchemaProfile:  ep.ToolSchemaProfile,
+			ToolArgumentValidation: ep.ToolArgumentValidation,
+			EnforceSingleToolCall:  ep.EnforceSingleToolCall,
 		}
 		
 		convertedBody, _, err := c.converter.ConvertRequest(finalRequestBody, endpointInfo)
@@ -110,6 +113,35 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 		targetURL = ep.GetFullURL("/chat/completions")
 	}
 
+	// 原生协议端点（如 gemini、bedrock、vertex-anthropic、azure-openai、ollama）再由上游适配器转换
+	var upstreamRequest *conversion.UpstreamRequest
+	vertexProjectID, vertexRegion := ep.GetVertexTarget()
+	azureAPIVersion, azureDeployments := ep.GetAzureDeployments()
+	ollamaOptions, ollamaKeepAlive := ep.GetOllamaOptions()
+	adapter := conversion.NewUpstreamAdapter(&conversion.EndpointInfo{
+		Type:                 ep.EndpointType,
+		ToolSchemaProfile:    ep.ToolSchemaProfile,
+		GeminiSafetySettings: ep.GeminiSafetySettings,
+		VertexProjectID:      vertexProjectID,
+		VertexRegion:         vertexRegion,
+		AzureAPIVersion:      azureAPIVersion,
+		AzureDeployments:     azureDeployments,
+		OpenAIAPI:            ep.OpenAIAPI,
+		OllamaOptions:        ollamaOptions,
+		OllamaKeepAlive:      ollamaKeepAlive,
+	}, nil)
+	if adapter != nil {
+		upstreamRequest, err = adapter.AdaptRequest(finalRequestBody)
+		if err != nil {
+			return fmt.Errorf("upstream request adaptation failed during health check: %v", err)
+		}
+		finalRequestBody = upstreamRequest.Body
+		targetURL = ep.GetFullURL(upstreamRequest.Path)
+		if len(upstreamRequest.Query) > 0 {
+			targetURL += "?" + upstreamRequest.Query.Encode()
+		}
+	}
+
 	// 构造最终的HTTP请求
 	req, err := http.NewRequest("POST", targetURL, bytes.NewReader(finalRequestBody))
 	if err != nil {
@@ -123,7 +155,23 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 
 	// 单独设置认证头部（不包含在默认headers中）
 	if ep.AuthType == "api_key" {
-		req.Header.Set("x-api-key", ep.AuthValue)
+		ep.SetAPIKey(req)
+	} else if ep.AuthType == "aws_sigv4" {
+		if err := ep.SignAWSRequest(req, finalRequestBody); err != nil {
+			return fmt.Errorf("failed to sign aws request: %v", err)
+		}
+	} else if ep.AuthType == "gcp_service_account" || ep.AuthType == "entra_id" {
+		// 服务账号与 Entra ID 令牌可能尚未换取或已过期，健康检查同样需要换取
+		authHeader, err := ep.GetAuthHeaderWithRefresh(config.ProxyTimeoutConfig{
+			TLSHandshake:   c.healthTimeouts.TLSHandshake,
+			ResponseHeader: c.healthTimeouts.ResponseHeader,
+			IdleConnection: c.healthTimeouts.IdleConnection,
+			OverallRequest: c.healthTimeouts.OverallRequest,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get auth header: %v", err)
+		}
+		req.Header.Set("Authorization", authHeader)
 	} else {
 		authHeader, err := ep.GetAuthHeader()
 		if err != nil {
@@ -156,6 +204,13 @@ func (c *Checker) CheckEndpoint(ep *endpoint.Endpoint) error {
 		return fmt.Errorf("failed to read health check response: %v", err)
 	}
 
+	// 将上游原生协议响应转换回内部格式再验证
+	if adapter != nil {
+		if body, err = adapter.AdaptResponse(body, upstreamRequest.Streaming); err != nil {
+			return fmt.Errorf("upstream response adaptation failed during health check: %v", err)
+		}
+	}
+
 	// 简单验证：检查是否包含SSE格式的流式响应
 	if !bytes.Contains(body, []byte("event:")) && !bytes.Contains(body, []byte("data:")) {
 		// 如果不是流式响应，检查是否为有效的JSON响应
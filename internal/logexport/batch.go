@@ -0,0 +1,127 @@
+package logexport
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+// invalidCustomIDChars custom_id 只允许字母、数字、下划线和连字符，最长 64 个字符
+var invalidCustomIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// anthropicBatchLine Anthropic Message Batches 的一行请求
+type anthropicBatchLine struct {
+	CustomID string                     `json:"custom_id"`
+	Params   map[string]json.RawMessage `json:"params"`
+}
+
+// openAIBatchLine OpenAI Batch API 的一行请求
+type openAIBatchLine struct {
+	CustomID string                     `json:"custom_id"`
+	Method   string                     `json:"method"`
+	URL      string                     `json:"url"`
+	Body     map[string]json.RawMessage `json:"body"`
+}
+
+// batchWriter 将日志中的请求体写出为批量重放 JSONL，每个请求 ID 只写出一条
+type batchWriter struct {
+	encoder *json.Encoder
+	format  string
+	seen    map[string]bool
+}
+
+func newBatchWriter(w io.Writer, format string) *batchWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &batchWriter{encoder: encoder, format: format, seen: make(map[string]bool)}
+}
+
+func (b *batchWriter) write(log *logger.RequestLog) (bool, error) {
+	if b.seen[log.RequestID] {
+		return false, nil
+	}
+
+	body, path, ok := nativeRequest(log, b.format)
+	if !ok {
+		return false, nil
+	}
+	params := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(body), &params); err != nil {
+		// 正文被截断或未记录时无法重放
+		return false, nil
+	}
+	// 批量接口不支持流式输出
+	delete(params, "stream")
+	delete(params, "stream_options")
+
+	var line interface{}
+	switch b.format {
+	case FormatAnthropic:
+		if !strings.Contains(path, "/messages") || strings.Contains(path, "count_tokens") {
+			return false, nil
+		}
+		line = anthropicBatchLine{CustomID: customID(log), Params: params}
+	case FormatOpenAI:
+		batchURL := openAIBatchURL(path)
+		if batchURL == "" {
+			return false, nil
+		}
+		line = openAIBatchLine{CustomID: customID(log), Method: "POST", URL: batchURL, Body: params}
+	}
+
+	b.seen[log.RequestID] = true
+	return true, b.encoder.Encode(line)
+}
+
+func (b *batchWriter) close() error {
+	return nil
+}
+
+// nativeRequest 返回日志中指定格式的请求体及其路径：
+// 客户端请求本身是该格式时使用原始请求，否则在目标端点为该格式时使用发往上游的请求
+func nativeRequest(log *logger.RequestLog, format string) (string, string, bool) {
+	if log.RequestFormat == format {
+		if body := firstNonEmpty(log.OriginalRequestBody, log.RequestBody); body != "" {
+			return body, urlPath(firstNonEmpty(log.OriginalRequestURL, log.Path)), true
+		}
+	}
+	if log.TargetFormat == format && log.FinalRequestBody != "" {
+		return log.FinalRequestBody, urlPath(log.FinalRequestURL), true
+	}
+	return "", "", false
+}
+
+// openAIBatchURL 将请求路径映射为 Batch API 支持的接口
+func openAIBatchURL(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/responses"):
+		return "/v1/responses"
+	case strings.HasSuffix(path, "/chat/completions"):
+		return "/v1/chat/completions"
+	default:
+		return ""
+	}
+}
+
+// customID 由请求 ID 生成 custom_id
+func customID(log *logger.RequestLog) string {
+	id := invalidCustomIDChars.ReplaceAllString(log.RequestID, "_")
+	if id == "" {
+		id = "request"
+	}
+	if len(id) > 64 {
+		id = id[:64]
+	}
+	return id
+}
+
+func urlPath(rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil {
+		return parsed.Path
+	}
+	return rawURL
+}
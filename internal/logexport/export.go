@@ -0,0 +1,140 @@
+package logexport
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+// 导出格式
+const (
+	FormatHAR       = "har"       // HAR 1.2，可在浏览器开发者工具和 HAR 查看器中打开
+	FormatAnthropic = "anthropic" // Anthropic Message Batches 格式的 JSONL
+	FormatOpenAI    = "openai"    // OpenAI Batch API 格式的 JSONL
+)
+
+const (
+	// DefaultLimit 未指定数量时最多导出的日志条数
+	DefaultLimit = 10000
+	// MaxLimit 单次导出的日志条数上限
+	MaxLimit = 100000
+	// exportPageSize 分页读取日志时每页的条数
+	exportPageSize = 500
+)
+
+// ErrUnknownFormat 不支持的导出格式
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Searcher 按条件分页检索日志，*logger.Logger 和 *logger.GORMStorage 均实现该接口
+type Searcher interface {
+	SearchLogs(query logger.LogQuery) (*logger.LogPage, error)
+}
+
+// Options 导出选项
+type Options struct {
+	Format         string
+	Query          logger.LogQuery              // 过滤条件，Cursor 和 Limit 会被忽略
+	Limit          int                          // 最多读取的日志条数，0 表示 DefaultLimit，不超过 MaxLimit
+	Redact         func(log *logger.RequestLog) // 写出前就地脱敏，通常为日志配置的脱敏规则
+	CreatorVersion string                       // 写入 HAR creator.version
+}
+
+// Result 导出结果
+type Result struct {
+	Exported int `json:"exported"`
+	Skipped  int `json:"skipped"` // 批量重放格式中无法还原为对应格式请求的日志
+}
+
+// entryWriter 将日志逐条写出为某种格式
+type entryWriter interface {
+	// write 写出一条日志，返回 false 表示该日志不适用于此格式而被跳过
+	write(log *logger.RequestLog) (bool, error)
+	close() error
+}
+
+// ValidFormat 判断导出格式是否受支持
+func ValidFormat(format string) bool {
+	return format == FormatHAR || format == FormatAnthropic || format == FormatOpenAI
+}
+
+// FileName 返回导出文件的默认文件名（不含目录）
+func FileName(format, suffix string) string {
+	if format == FormatHAR {
+		return fmt.Sprintf("logs_%s.har", suffix)
+	}
+	return fmt.Sprintf("logs_%s_batch_%s.jsonl", format, suffix)
+}
+
+// ContentType 返回导出内容的 MIME 类型
+func ContentType(format string) string {
+	if format == FormatHAR {
+		return "application/json; charset=utf-8"
+	}
+	return "application/x-ndjson; charset=utf-8"
+}
+
+// Export 按条件读取日志（时间倒序）并以指定格式写出
+func Export(w io.Writer, searcher Searcher, options Options) (*Result, error) {
+	buffered := bufio.NewWriter(w)
+
+	var writer entryWriter
+	switch options.Format {
+	case FormatHAR:
+		writer = newHARWriter(buffered, options.CreatorVersion)
+	case FormatAnthropic, FormatOpenAI:
+		writer = newBatchWriter(buffered, options.Format)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, options.Format)
+	}
+
+	limit := options.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	} else if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	result := &Result{}
+	query := options.Query
+	query.Cursor = ""
+	read := 0
+	for read < limit {
+		query.Limit = exportPageSize
+		if remaining := limit - read; remaining < query.Limit {
+			query.Limit = remaining
+		}
+
+		page, err := searcher.SearchLogs(query)
+		if err != nil {
+			return result, err
+		}
+		for _, log := range page.Logs {
+			if options.Redact != nil {
+				options.Redact(log)
+			}
+			written, err := writer.write(log)
+			if err != nil {
+				return result, err
+			}
+			if written {
+				result.Exported++
+			} else {
+				result.Skipped++
+			}
+		}
+		read += len(page.Logs)
+
+		if page.NextCursor == "" || len(page.Logs) == 0 {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	if err := writer.close(); err != nil {
+		return result, err
+	}
+	return result, buffered.Flush()
+}
@@ -0,0 +1,245 @@
+package logexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/logger"
+)
+
+// fakeSearcher 按游标分页返回固定的日志，游标为下一页的起始位置
+type fakeSearcher struct {
+	logs    []*logger.RequestLog
+	queries []logger.LogQuery
+}
+
+func (f *fakeSearcher) SearchLogs(query logger.LogQuery) (*logger.LogPage, error) {
+	f.queries = append(f.queries, query)
+	start, _ := strconv.Atoi(query.Cursor)
+	end := start + query.Limit
+	if end > len(f.logs) {
+		end = len(f.logs)
+	}
+	page := &logger.LogPage{Logs: f.logs[start:end], Total: len(f.logs)}
+	if end < len(f.logs) {
+		page.NextCursor = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+func sampleLogs() []*logger.RequestLog {
+	timestamp := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	return []*logger.RequestLog{
+		{
+			// Anthropic 客户端经转换发往 OpenAI 端点，重试两次
+			Timestamp:          timestamp,
+			RequestID:          "req-2",
+			Endpoint:           "https://api.openai.example",
+			Method:             "POST",
+			Path:               "/v1/messages",
+			StatusCode:         200,
+			DurationMs:         1500,
+			AttemptNumber:      2,
+			RequestFormat:      "anthropic",
+			TargetFormat:       "openai",
+			OriginalRequestURL: "/v1/messages?beta=true",
+			OriginalRequestBody: `{"model":"claude-sonnet-4","max_tokens":100,"stream":true,` +
+				`"messages":[{"role":"user","content":"hi"}]}`,
+			FinalRequestURL:         "https://api.openai.example/v1/chat/completions",
+			FinalRequestHeaders:     map[string]string{"Authorization": "Bearer sk-live-secret", "Content-Type": "application/json"},
+			FinalRequestBody:        `{"model":"gpt-4o","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hi"}]}`,
+			OriginalResponseHeaders: map[string]string{"Content-Type": "text/event-stream"},
+			OriginalResponseBody:    "data: {}\n\ndata: [DONE]\n\n",
+		},
+		{
+			Timestamp:          timestamp.Add(-time.Second),
+			RequestID:          "req-2",
+			Endpoint:           "https://api.anthropic.example",
+			Path:               "/v1/messages",
+			StatusCode:         529,
+			AttemptNumber:      1,
+			RequestFormat:      "anthropic",
+			TargetFormat:       "anthropic",
+			OriginalRequestURL: "/v1/messages?beta=true",
+			OriginalRequestBody: `{"model":"claude-sonnet-4","max_tokens":100,"stream":true,` +
+				`"messages":[{"role":"user","content":"hi"}]}`,
+			FinalRequestURL:  "https://api.anthropic.example/v1/messages",
+			FinalRequestBody: `{"model":"claude-sonnet-4","max_tokens":100,"stream":true,"messages":[{"role":"user","content":"hi"}]}`,
+			Error:            "overloaded",
+		},
+		{
+			// 请求体被截断，无法重放
+			Timestamp:           timestamp.Add(-2 * time.Second),
+			RequestID:           "req-1",
+			Path:                "/v1/messages",
+			RequestFormat:       "anthropic",
+			TargetFormat:        "anthropic",
+			OriginalRequestBody: `{"model":"claude-sonnet-4","messa... (truncated)`,
+		},
+		{
+			// count_tokens 不是消息请求
+			Timestamp:           timestamp.Add(-3 * time.Second),
+			RequestID:           "req-0",
+			Path:                "/v1/messages/count_tokens",
+			RequestFormat:       "anthropic",
+			TargetFormat:        "anthropic",
+			OriginalRequestBody: `{"model":"claude-sonnet-4","messages":[]}`,
+		},
+	}
+}
+
+func TestExport_HAR(t *testing.T) {
+	redactor, err := logger.NewRedactor(config.LogRedactionConfig{})
+	if err != nil {
+		t.Fatalf("NewRedactor failed: %v", err)
+	}
+	searcher := &fakeSearcher{logs: sampleLogs()}
+
+	var buf bytes.Buffer
+	result, err := Export(&buf, searcher, Options{Format: FormatHAR, Redact: redactor.RedactLog, CreatorVersion: "1.2.3"})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if result.Exported != 4 || result.Skipped != 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	var har struct {
+		Log struct {
+			Version string     `json:"version"`
+			Creator harCreator `json:"creator"`
+			Entries []harEntry `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &har); err != nil {
+		t.Fatalf("Invalid HAR JSON: %v\n%s", err, buf.String())
+	}
+	if har.Log.Version != "1.2" || har.Log.Creator.Version != "1.2.3" || len(har.Log.Entries) != 4 {
+		t.Fatalf("Unexpected HAR log: %+v", har.Log)
+	}
+
+	entry := har.Log.Entries[0]
+	if entry.Request.URL != "https://api.openai.example/v1/chat/completions" || entry.Request.PostData == nil ||
+		!strings.Contains(entry.Request.PostData.Text, "gpt-4o") {
+		t.Errorf("Expected upstream request, got %+v", entry.Request)
+	}
+	if entry.Response.Status != 200 || entry.Response.StatusText != "OK" || entry.Response.Content.MimeType != "text/event-stream" {
+		t.Errorf("Unexpected response: %+v", entry.Response)
+	}
+	if entry.RequestID != "req-2" || entry.AttemptNumber != 2 || entry.Time != 1500 {
+		t.Errorf("Unexpected entry metadata: %+v", entry)
+	}
+	for _, header := range entry.Request.Headers {
+		if header.Name == "Authorization" && strings.Contains(header.Value, "sk-live-secret") {
+			t.Errorf("Expected Authorization header to be redacted, got %q", header.Value)
+		}
+	}
+
+	// 未记录上游 URL 时由端点地址和路径拼出
+	if got := har.Log.Entries[2].Request.URL; got != "/v1/messages" {
+		t.Errorf("Unexpected fallback URL: %s", got)
+	}
+	if har.Log.Entries[1].Response.Error != "overloaded" {
+		t.Errorf("Expected error in response, got %+v", har.Log.Entries[1].Response)
+	}
+}
+
+func TestExport_EmptyHAR(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Export(&buf, &fakeSearcher{}, Options{Format: FormatHAR}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	var har map[string]map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &har); err != nil {
+		t.Fatalf("Invalid HAR JSON: %v", err)
+	}
+	if entries, ok := har["log"]["entries"].([]interface{}); !ok || len(entries) != 0 {
+		t.Errorf("Expected empty entries, got %v", har)
+	}
+}
+
+func TestExport_AnthropicBatch(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := Export(&buf, &fakeSearcher{logs: sampleLogs()}, Options{Format: FormatAnthropic})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if result.Exported != 1 || result.Skipped != 3 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one line per request, got %q", buf.String())
+	}
+	var line struct {
+		CustomID string                 `json:"custom_id"`
+		Params   map[string]interface{} `json:"params"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("Invalid JSONL: %v", err)
+	}
+	if line.CustomID != "req-2" || line.Params["model"] != "claude-sonnet-4" {
+		t.Errorf("Unexpected batch line: %+v", line)
+	}
+	if _, ok := line.Params["stream"]; ok {
+		t.Errorf("Expected stream to be removed, got %+v", line.Params)
+	}
+}
+
+func TestExport_OpenAIBatch(t *testing.T) {
+	var buf bytes.Buffer
+	result, err := Export(&buf, &fakeSearcher{logs: sampleLogs()}, Options{Format: FormatOpenAI})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if result.Exported != 1 {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	var line struct {
+		CustomID string                 `json:"custom_id"`
+		Method   string                 `json:"method"`
+		URL      string                 `json:"url"`
+		Body     map[string]interface{} `json:"body"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &line); err != nil {
+		t.Fatalf("Invalid JSONL: %v", err)
+	}
+	if line.Method != "POST" || line.URL != "/v1/chat/completions" || line.Body["model"] != "gpt-4o" {
+		t.Errorf("Unexpected batch line: %+v", line)
+	}
+	if _, ok := line.Body["stream_options"]; ok {
+		t.Errorf("Expected stream_options to be removed, got %+v", line.Body)
+	}
+}
+
+func TestExport_LimitAndPaging(t *testing.T) {
+	var logs []*logger.RequestLog
+	for i := 0; i < 1200; i++ {
+		logs = append(logs, &logger.RequestLog{RequestID: "req", Timestamp: time.Now()})
+	}
+	searcher := &fakeSearcher{logs: logs}
+
+	var buf bytes.Buffer
+	result, err := Export(&buf, searcher, Options{Format: FormatHAR, Limit: 700, Query: logger.LogQuery{Model: "claude", Cursor: "100"}})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if result.Exported != 700 || len(searcher.queries) != 2 {
+		t.Errorf("Expected 700 entries over 2 pages, got %+v after %d queries", result, len(searcher.queries))
+	}
+	if searcher.queries[0].Cursor != "" || searcher.queries[0].Model != "claude" || searcher.queries[1].Limit != 200 {
+		t.Errorf("Unexpected queries: %+v", searcher.queries)
+	}
+
+	if _, err := Export(&buf, searcher, Options{Format: "xml"}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
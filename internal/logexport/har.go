@@ -0,0 +1,275 @@
+package logexport
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+// HAR 1.2 结构，字段含义见 http://www.softwareishard.com/blog/har-12-spec/
+// 以下划线开头的字段为自定义字段，HAR 查看器会忽略它们
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	Error       string         `json:"_error,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+
+	RequestID     string   `json:"_requestId"`
+	Endpoint      string   `json:"_endpoint,omitempty"`
+	AttemptNumber int      `json:"_attemptNumber,omitempty"`
+	Model         string   `json:"_model,omitempty"`
+	SessionID     string   `json:"_sessionId,omitempty"`
+	ClientType    string   `json:"_clientType,omitempty"`
+	Tags          []string `json:"_tags,omitempty"`
+}
+
+// harWriter 以流的方式写出 HAR 文档，entries 逐条编码，不需要一次性持有全部日志
+type harWriter struct {
+	w       io.Writer
+	version string
+	started bool
+	count   int
+}
+
+func newHARWriter(w io.Writer, version string) *harWriter {
+	if version == "" {
+		version = "dev"
+	}
+	return &harWriter{w: w, version: version}
+}
+
+func (h *harWriter) begin() error {
+	h.started = true
+	creator, err := json.Marshal(harCreator{Name: "claude-code-codex-companion", Version: h.version})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(h.w, `{"log":{"version":"1.2","creator":`+string(creator)+`,"entries":[`)
+	return err
+}
+
+func (h *harWriter) write(log *logger.RequestLog) (bool, error) {
+	if !h.started {
+		if err := h.begin(); err != nil {
+			return false, err
+		}
+	}
+	data, err := json.Marshal(newHAREntry(log))
+	if err != nil {
+		return false, err
+	}
+	if h.count > 0 {
+		if _, err := io.WriteString(h.w, ","); err != nil {
+			return false, err
+		}
+	}
+	h.count++
+	_, err = h.w.Write(data)
+	return true, err
+}
+
+func (h *harWriter) close() error {
+	if !h.started {
+		if err := h.begin(); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(h.w, "]}}\n")
+	return err
+}
+
+// newHAREntry 将一次上游尝试转为 HAR entry：请求为发往上游的请求，响应为上游返回的响应
+// 未记录上游数据的日志（如请求未发出）退回到客户端请求和返回给客户端的响应
+func newHAREntry(log *logger.RequestLog) harEntry {
+	requestURL := requestURLOf(log)
+	requestHeaders := firstHeaders(log.FinalRequestHeaders, log.RequestHeaders, log.OriginalRequestHeaders)
+	requestBody := firstNonEmpty(log.FinalRequestBody, log.RequestBody, log.OriginalRequestBody)
+	responseHeaders := firstHeaders(log.OriginalResponseHeaders, log.ResponseHeaders, log.FinalResponseHeaders)
+	responseBody := firstNonEmpty(log.OriginalResponseBody, log.ResponseBody, log.FinalResponseBody)
+
+	method := log.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	entry := harEntry{
+		StartedDateTime: log.Timestamp.Format(time.RFC3339Nano),
+		Time:            float64(log.DurationMs),
+		Request: harRequest{
+			Method:      method,
+			URL:         requestURL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(requestHeaders),
+			QueryString: harQueryString(requestURL),
+			HeadersSize: -1,
+			BodySize:    bodySize(log.RequestBodySize, requestBody),
+		},
+		Response: harResponse{
+			Status:      log.StatusCode,
+			StatusText:  http.StatusText(log.StatusCode),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(responseHeaders),
+			Content: harContent{
+				Size:     len(responseBody),
+				MimeType: headerValue(responseHeaders, "Content-Type"),
+				Text:     responseBody,
+			},
+			HeadersSize: -1,
+			BodySize:    bodySize(log.ResponseBodySize, responseBody),
+			Error:       log.Error,
+		},
+		Timings: harTimings{Send: 0, Wait: float64(log.DurationMs), Receive: 0},
+
+		RequestID:     log.RequestID,
+		Endpoint:      log.Endpoint,
+		AttemptNumber: log.AttemptNumber,
+		Model:         firstNonEmpty(log.OriginalModel, log.Model),
+		SessionID:     log.SessionID,
+		ClientType:    log.ClientType,
+		Tags:          log.Tags,
+	}
+	if requestBody != "" {
+		mimeType := headerValue(requestHeaders, "Content-Type")
+		if mimeType == "" {
+			mimeType = "application/json"
+		}
+		entry.Request.PostData = &harPostData{MimeType: mimeType, Text: requestBody}
+	}
+	return entry
+}
+
+// requestURLOf 返回上游请求的完整 URL，未记录时由端点地址和请求路径拼出
+func requestURLOf(log *logger.RequestLog) string {
+	if log.FinalRequestURL != "" {
+		return log.FinalRequestURL
+	}
+	path := firstNonEmpty(log.OriginalRequestURL, log.Path)
+	if strings.HasPrefix(log.Endpoint, "http://") || strings.HasPrefix(log.Endpoint, "https://") {
+		return strings.TrimRight(log.Endpoint, "/") + path
+	}
+	return path
+}
+
+// harHeaders 按名称排序，保证导出结果稳定
+func harHeaders(headers map[string]string) []harNameValue {
+	result := make([]harNameValue, 0, len(headers))
+	for name, value := range headers {
+		result = append(result, harNameValue{Name: name, Value: value})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func harQueryString(rawURL string) []harNameValue {
+	result := []harNameValue{}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return result
+	}
+	for name, values := range parsed.Query() {
+		for _, value := range values {
+			result = append(result, harNameValue{Name: name, Value: value})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// headerValue 不区分大小写地读取请求头
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// bodySize 优先使用记录的原始大小（正文可能被截断）
+func bodySize(recorded int, body string) int {
+	if recorded > 0 {
+		return recorded
+	}
+	return len(body)
+}
+
+func firstHeaders(candidates ...map[string]string) map[string]string {
+	for _, headers := range candidates {
+		if len(headers) > 0 {
+			return headers
+		}
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
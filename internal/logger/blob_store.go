@@ -0,0 +1,309 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 请求/响应体的内容寻址存储：正文按内容切分为数据块，以 sha256 命名并压缩后写入 blobs 目录。
+// 相同的数据块（重复的系统提示、工具定义、历史消息）只保存一份，SQLite 中仅保留数据块引用。
+
+// 正文压缩方式
+const (
+	BodyCompressionZstd = "zstd"
+	BodyCompressionGzip = "gzip"
+	BodyCompressionNone = "none"
+)
+
+// 数据块文件首字节标记压缩方式，切换压缩方式后旧数据块仍可读取
+const (
+	blobCodecRaw  byte = 'r'
+	blobCodecGzip byte = 'g'
+	blobCodecZstd byte = 'z'
+)
+
+// 内容定义分块参数：平均约 16KB。边界只取决于附近的内容，
+// 正文中间插入或末尾追加内容后，其余部分仍能切出相同的数据块
+const (
+	chunkMinSize = 4 << 10
+	chunkMaxSize = 64 << 10
+	chunkMask    = uint64(1<<14-1) << 50
+)
+
+// gearTable 分块滚动哈希使用的随机表，固定种子保证不同进程切分结果一致
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x9e3779b97f4a7c15)
+	for i := range table {
+		// splitmix64
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+var (
+	zstdDecoderOnce sync.Once
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+)
+
+// sharedZstdDecoder DecodeAll 可并发调用，整个进程共用一个解码器
+func sharedZstdDecoder() (*zstd.Decoder, error) {
+	zstdDecoderOnce.Do(func() {
+		zstdDecoder, zstdDecoderErr = zstd.NewReader(nil)
+	})
+	return zstdDecoder, zstdDecoderErr
+}
+
+// splitChunks 按内容定义的边界（gear 滚动哈希）切分数据
+func splitChunks(data []byte) [][]byte {
+	var chunks [][]byte
+	for len(data) > 0 {
+		if len(data) <= chunkMinSize {
+			chunks = append(chunks, data)
+			break
+		}
+
+		limit := len(data)
+		if limit > chunkMaxSize {
+			limit = chunkMaxSize
+		}
+
+		cut := limit
+		var hash uint64
+		for i := 0; i < limit; i++ {
+			hash = (hash << 1) + gearTable[data[i]]
+			if i >= chunkMinSize && hash&chunkMask == 0 {
+				cut = i + 1
+				break
+			}
+		}
+
+		chunks = append(chunks, data[:cut])
+		data = data[cut:]
+	}
+	return chunks
+}
+
+// blobChunk 一个已写入的数据块
+type blobChunk struct {
+	Hash       string
+	Size       int64 // 原始大小
+	StoredSize int64 // 压缩后的文件大小
+}
+
+// BlobStore 内容寻址的数据块文件存储
+type BlobStore struct {
+	dir         string
+	compression string
+	encoder     *zstd.Encoder
+}
+
+// NewBlobStore 创建数据块存储，compression 为空时使用 zstd
+func NewBlobStore(dir, compression string) (*BlobStore, error) {
+	if compression == "" {
+		compression = BodyCompressionZstd
+	}
+
+	store := &BlobStore{dir: dir, compression: compression}
+	switch compression {
+	case BodyCompressionZstd:
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %v", err)
+		}
+		store.encoder = encoder
+	case BodyCompressionGzip, BodyCompressionNone:
+	default:
+		return nil, fmt.Errorf("unsupported body compression: %s", compression)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %v", err)
+	}
+	return store, nil
+}
+
+// blobPath 数据块按哈希前两位分目录存放，避免单个目录下文件过多
+func blobPath(dir, hash string) string {
+	return filepath.Join(dir, hash[:2], hash)
+}
+
+// encode 按配置的压缩方式编码数据块，首字节为压缩方式标记
+func (b *BlobStore) encode(chunk []byte) ([]byte, error) {
+	switch b.compression {
+	case BodyCompressionZstd:
+		return b.encoder.EncodeAll(chunk, []byte{blobCodecZstd}), nil
+	case BodyCompressionGzip:
+		var buf bytes.Buffer
+		buf.WriteByte(blobCodecGzip)
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(chunk); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return append([]byte{blobCodecRaw}, chunk...), nil
+	}
+}
+
+// decodeBlob 根据首字节标记解码数据块文件
+func decodeBlob(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty blob file")
+	}
+
+	switch data[0] {
+	case blobCodecZstd:
+		decoder, err := sharedZstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+		return decoder.DecodeAll(data[1:], nil)
+	case blobCodecGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case blobCodecRaw:
+		return data[1:], nil
+	default:
+		return nil, fmt.Errorf("unknown blob codec %q", data[0])
+	}
+}
+
+// Put 写入数据块，内容相同的数据块已存在时直接复用
+func (b *BlobStore) Put(chunk []byte) (blobChunk, error) {
+	sum := sha256.Sum256(chunk)
+	hash := hex.EncodeToString(sum[:])
+	path := blobPath(b.dir, hash)
+
+	if info, err := os.Stat(path); err == nil {
+		return blobChunk{Hash: hash, Size: int64(len(chunk)), StoredSize: info.Size()}, nil
+	}
+
+	encoded, err := b.encode(chunk)
+	if err != nil {
+		return blobChunk{}, fmt.Errorf("failed to compress blob: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return blobChunk{}, fmt.Errorf("failed to create blob directory: %v", err)
+	}
+
+	// 先写临时文件再重命名，读取方不会看到写了一半的数据块
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".tmp*")
+	if err != nil {
+		return blobChunk{}, fmt.Errorf("failed to create blob file: %v", err)
+	}
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return blobChunk{}, fmt.Errorf("failed to write blob file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return blobChunk{}, fmt.Errorf("failed to write blob file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return blobChunk{}, fmt.Errorf("failed to store blob file: %v", err)
+	}
+
+	return blobChunk{Hash: hash, Size: int64(len(chunk)), StoredSize: int64(len(encoded))}, nil
+}
+
+// Get 读取并解码数据块
+func (b *BlobStore) Get(hash string) ([]byte, error) {
+	return readBlob(b.dir, hash)
+}
+
+// readBlob 读取数据块文件，不依赖当前配置的压缩方式
+func readBlob(dir, hash string) ([]byte, error) {
+	if len(hash) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid blob hash %q", hash)
+	}
+	data, err := os.ReadFile(blobPath(dir, hash))
+	if err != nil {
+		return nil, err
+	}
+	return decodeBlob(data)
+}
+
+// Remove 删除数据块文件，文件不存在不视为错误
+func (b *BlobStore) Remove(hash string) error {
+	if err := os.Remove(blobPath(b.dir, hash)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// WriteBody 切分并写入正文，返回由数据块哈希组成的引用
+func (b *BlobStore) WriteBody(body string) (string, []blobChunk, error) {
+	pieces := splitChunks([]byte(body))
+	hashes := make([]string, len(pieces))
+	chunks := make([]blobChunk, len(pieces))
+	for i, piece := range pieces {
+		chunk, err := b.Put(piece)
+		if err != nil {
+			return "", nil, err
+		}
+		hashes[i] = chunk.Hash
+		chunks[i] = chunk
+	}
+	return strings.Join(hashes, ","), chunks, nil
+}
+
+// ReadBody 按引用还原正文，cache 用于同一批日志间复用已解码的数据块（可为 nil）
+func (b *BlobStore) ReadBody(ref string, cache map[string][]byte) (string, error) {
+	return readBody(b.dir, ref, cache)
+}
+
+func readBody(dir, ref string, cache map[string][]byte) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+
+	var body strings.Builder
+	for _, hash := range strings.Split(ref, ",") {
+		chunk, ok := cache[hash]
+		if !ok {
+			data, err := readBlob(dir, hash)
+			if err != nil {
+				return "", fmt.Errorf("failed to read blob %s: %v", hash, err)
+			}
+			chunk = data
+			if cache != nil {
+				cache[hash] = chunk
+			}
+		}
+		body.Write(chunk)
+	}
+	return body.String(), nil
+}
+
+// Close 释放编码器资源
+func (b *BlobStore) Close() {
+	if b.encoder != nil {
+		b.encoder.Close()
+	}
+}
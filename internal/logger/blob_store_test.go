@@ -0,0 +1,319 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// randomText 生成不可压缩、无重复片段的测试正文
+func randomText(seed int64, size int) string {
+	rng := rand.New(rand.NewSource(seed))
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789 "
+	buf := make([]byte, size)
+	for i := range buf {
+		buf[i] = letters[rng.Intn(len(letters))]
+	}
+	return string(buf)
+}
+
+func TestSplitChunks(t *testing.T) {
+	data := []byte(randomText(1, 300<<10))
+	chunks := splitChunks(data)
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Fatalf("Chunks do not reassemble to the original data")
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > chunkMaxSize {
+			t.Errorf("Chunk %d exceeds max size: %d", i, len(chunk))
+		}
+		if i < len(chunks)-1 && len(chunk) < chunkMinSize {
+			t.Errorf("Chunk %d below min size: %d", i, len(chunk))
+		}
+	}
+
+	// 在开头插入内容后，后续数据块应重新对齐
+	shifted := splitChunks(append([]byte("inserted prefix"), data...))
+	original := make(map[string]bool)
+	for _, chunk := range chunks {
+		original[string(chunk)] = true
+	}
+	shared := 0
+	for _, chunk := range shifted {
+		if original[string(chunk)] {
+			shared++
+		}
+	}
+	if shared < len(chunks)-2 {
+		t.Errorf("Expected most chunks to be shared after an insertion, got %d of %d", shared, len(chunks))
+	}
+
+	if got := splitChunks(nil); len(got) != 0 {
+		t.Errorf("Expected no chunks for empty data, got %d", len(got))
+	}
+}
+
+func TestBlobStore_RoundTripAndCodecs(t *testing.T) {
+	dir := t.TempDir()
+	body := strings.Repeat(`{"role":"user","content":"hello"}`, 5000) + randomText(2, 50<<10)
+
+	for _, compression := range []string{BodyCompressionZstd, BodyCompressionGzip, BodyCompressionNone} {
+		store, err := NewBlobStore(t.TempDir(), compression)
+		if err != nil {
+			t.Fatalf("%s: failed to create store: %v", compression, err)
+		}
+		ref, chunks, err := store.WriteBody(body)
+		if err != nil {
+			t.Fatalf("%s: WriteBody failed: %v", compression, err)
+		}
+		got, err := store.ReadBody(ref, nil)
+		if err != nil || got != body {
+			t.Errorf("%s: round trip mismatch (err=%v)", compression, err)
+		}
+
+		var stored int64
+		for _, chunk := range chunks {
+			stored += chunk.StoredSize
+		}
+		if compression != BodyCompressionNone && stored >= int64(len(body)) {
+			t.Errorf("%s: expected compressed size below %d, got %d", compression, len(body), stored)
+		}
+		store.Close()
+	}
+
+	// 切换压缩方式后，已写入的数据块仍可读取，相同内容不会重复写入
+	gzipStore, _ := NewBlobStore(dir, BodyCompressionGzip)
+	ref, _, err := gzipStore.WriteBody(body)
+	if err != nil {
+		t.Fatalf("WriteBody failed: %v", err)
+	}
+	zstdStore, _ := NewBlobStore(dir, BodyCompressionZstd)
+	defer zstdStore.Close()
+	if got, err := zstdStore.ReadBody(ref, nil); err != nil || got != body {
+		t.Errorf("Expected gzip blobs to be readable after switching to zstd (err=%v)", err)
+	}
+	if ref2, _, _ := zstdStore.WriteBody(body); ref2 != ref {
+		t.Errorf("Expected identical content to produce the same reference")
+	}
+
+	if _, err := NewBlobStore(dir, "lz4"); err == nil {
+		t.Errorf("Expected unsupported compression to be rejected")
+	}
+}
+
+func countBlobFiles(t *testing.T, dir string) int {
+	t.Helper()
+	count := 0
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		files, _ := os.ReadDir(dir + "/" + entry.Name())
+		count += len(files)
+	}
+	return count
+}
+
+func TestGORMStorage_BodiesStoredAsDedupedBlobs(t *testing.T) {
+	storage, dir := setupQueryStorage(t)
+	systemPrompt := randomText(3, 100<<10)
+
+	for i := 0; i < 3; i++ {
+		body := fmt.Sprintf(`{"system":%q,"messages":[{"content":"question %d"}]}`, systemPrompt, i)
+		storage.SaveLog(&RequestLog{
+			Timestamp:           time.Now(),
+			RequestID:           fmt.Sprintf("req-%d", i),
+			Endpoint:            "primary",
+			RequestBody:         body,
+			OriginalRequestBody: body,
+			ResponseBody:        fmt.Sprintf(`{"content":"answer %d"}`, i),
+		})
+	}
+
+	// 数据库中只保留引用
+	var inline int64
+	storage.db.Model(&GormRequestLog{}).Where("request_body != '' OR original_request_body != '' OR response_body != ''").Count(&inline)
+	if inline != 0 {
+		t.Errorf("Expected no inline bodies in the database, got %d rows", inline)
+	}
+
+	logs, err := storage.GetAllLogsByRequestID("req-2")
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("Failed to load log: %v", err)
+	}
+	if !strings.Contains(logs[0].RequestBody, systemPrompt) || logs[0].OriginalRequestBody != logs[0].RequestBody {
+		t.Errorf("Request body was not restored from blob storage")
+	}
+	if logs[0].ResponseBody != `{"content":"answer 2"}` {
+		t.Errorf("Unexpected response body: %s", logs[0].ResponseBody)
+	}
+
+	// 重复的系统提示只存一份
+	stats, _ := storage.GetStats()
+	if blobSize := stats["blob_size_bytes"].(int64); blobSize > int64(len(systemPrompt))*2 {
+		t.Errorf("Expected repeated system prompt to be deduplicated, blob size %d", blobSize)
+	}
+	if files := countBlobFiles(t, dir+"/blobs"); int64(files) != stats["blob_count"].(int64) {
+		t.Errorf("Expected %d blob files, got %d", stats["blob_count"], files)
+	}
+}
+
+func TestGORMStorage_CleanupCollectsUnreferencedBlobs(t *testing.T) {
+	storage, dir := setupQueryStorage(t)
+	shared := randomText(4, 40<<10)
+	old := randomText(5, 40<<10)
+
+	storage.SaveLog(&RequestLog{Timestamp: time.Now().AddDate(0, 0, -10), RequestID: "old", Endpoint: "primary",
+		RequestBody: shared, ResponseBody: old})
+	storage.SaveLog(&RequestLog{Timestamp: time.Now(), RequestID: "new", Endpoint: "primary", RequestBody: shared})
+
+	before := countBlobFiles(t, dir+"/blobs")
+	if deleted, err := storage.CleanupLogsByDays(5); err != nil || deleted != 1 {
+		t.Fatalf("Expected 1 deleted log, got %d (err=%v)", deleted, err)
+	}
+	after := countBlobFiles(t, dir+"/blobs")
+	if after >= before || after == 0 {
+		t.Errorf("Expected only unreferenced blobs to be removed, before %d after %d", before, after)
+	}
+
+	logs, _ := storage.GetAllLogsByRequestID("new")
+	if len(logs) != 1 || logs[0].RequestBody != shared {
+		t.Errorf("Shared blobs of remaining logs must be kept")
+	}
+
+	var mode int
+	storage.db.Raw("PRAGMA auto_vacuum").Scan(&mode)
+	if mode != 2 {
+		t.Errorf("Expected incremental auto_vacuum, got %d", mode)
+	}
+}
+
+func TestGORMStorage_StorageLimitDeletesOldestLogs(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := NewGORMStorageWithOptions(dir, GORMStorageOptions{BodyCompression: BodyCompressionNone})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 60; i++ {
+		storage.SaveLog(&RequestLog{Timestamp: base.Add(time.Duration(i) * time.Second), RequestID: fmt.Sprintf("req-%03d", i),
+			Endpoint: "primary", RequestBody: randomText(int64(100+i), 8<<10)})
+	}
+
+	dbSize, blobSize := storage.storageSize()
+	storage.maxStorageBytes = dbSize + blobSize/2
+	deleted, err := storage.enforceStorageLimit()
+	if err != nil {
+		t.Fatalf("enforceStorageLimit failed: %v", err)
+	}
+	if deleted == 0 {
+		t.Fatalf("Expected old logs to be deleted")
+	}
+
+	dbSize, blobSize = storage.storageSize()
+	if dbSize+blobSize > storage.maxStorageBytes {
+		t.Errorf("Expected total size %d to be within limit %d", dbSize+blobSize, storage.maxStorageBytes)
+	}
+	if logs, _ := storage.GetAllLogsByRequestID("req-000"); len(logs) != 0 {
+		t.Errorf("Expected oldest log to be deleted")
+	}
+	if logs, _ := storage.GetAllLogsByRequestID("req-059"); len(logs) != 1 {
+		t.Errorf("Expected newest log to be kept")
+	}
+}
+
+func TestGORMStorage_MigratesInlineBodies(t *testing.T) {
+	storage, dir := setupQueryStorage(t)
+
+	// 新数据库直接启用增量 VACUUM
+	var mode int
+	storage.db.Raw("PRAGMA auto_vacuum").Scan(&mode)
+	if mode != 2 {
+		t.Fatalf("Expected new database to use incremental auto_vacuum, got %d", mode)
+	}
+
+	// 模拟旧版本数据库：正文内联保存，全文索引为基于正文列的外部内容表
+	statements := []string{
+		"DROP TRIGGER request_logs_fts_delete",
+		"DROP TABLE request_logs_fts",
+		`CREATE VIRTUAL TABLE request_logs_fts USING fts5(request_body, response_body, error,
+			content='request_logs', content_rowid='id', tokenize='trigram')`,
+		`CREATE TRIGGER request_logs_fts_update AFTER UPDATE OF request_body, response_body, error ON request_logs BEGIN
+			INSERT INTO request_logs_fts(request_logs_fts, rowid, request_body, response_body, error)
+			VALUES ('delete', old.id, old.request_body, old.response_body, old.error);
+			INSERT INTO request_logs_fts(rowid, request_body, response_body, error)
+			VALUES (new.id, new.request_body, new.response_body, new.error);
+		END`,
+		"PRAGMA auto_vacuum = NONE",
+		"VACUUM",
+	}
+	for _, sql := range statements {
+		if err := storage.db.Exec(sql).Error; err != nil {
+			t.Fatalf("Failed to prepare legacy schema (%s): %v", sql, err)
+		}
+	}
+	legacy := []GormRequestLog{
+		{Timestamp: time.Now(), RequestID: "legacy-1", Endpoint: "primary", Method: "POST", Path: "/v1/messages",
+			RequestBody: `{"messages":[{"content":"migrate the legacy parser"}]}`, FinalRequestBody: `{"final":true}`},
+		{Timestamp: time.Now(), RequestID: "legacy-2", Endpoint: "primary", Method: "POST", Path: "/v1/messages",
+			ResponseBody: `{"content":"ok"}`, Error: "upstream timeout"},
+	}
+	if err := storage.db.Create(&legacy).Error; err != nil {
+		t.Fatalf("Failed to insert legacy logs: %v", err)
+	}
+	storage.db.Exec("INSERT INTO request_logs_fts(request_logs_fts) VALUES ('rebuild')")
+	storage.Close()
+
+	reopened, err := NewGORMStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer reopened.Close()
+	// 迁移和索引补建在后台执行
+	<-reopened.maintenanceDone
+
+	var inline int64
+	reopened.db.Model(&GormRequestLog{}).Where("body_refs = '' AND (request_body != '' OR response_body != '' OR final_request_body != '')").Count(&inline)
+	if inline != 0 {
+		t.Errorf("Expected inline bodies to be migrated, %d rows left", inline)
+	}
+
+	logs, _ := reopened.GetAllLogsByRequestID("legacy-1")
+	if len(logs) != 1 || !strings.Contains(logs[0].RequestBody, "legacy parser") || logs[0].FinalRequestBody != `{"final":true}` {
+		t.Errorf("Migrated bodies were not restored: %+v", logs)
+	}
+
+	if reopened.ftsEnabled {
+		if !reopened.ftsReady.Load() || reopened.fullTextRebuildPending() {
+			t.Fatalf("Expected full-text index rebuild to complete")
+		}
+		page, err := reopened.SearchLogs(LogQuery{Text: "legacy parser"})
+		if err != nil {
+			t.Fatalf("SearchLogs failed: %v", err)
+		}
+		assertIDs(t, "rebuilt index", page, "legacy-1")
+	}
+	page, err := reopened.SearchLogs(LogQuery{Text: "ok"})
+	if err != nil {
+		t.Fatalf("SearchLogs failed: %v", err)
+	}
+	assertIDs(t, "like search on migrated bodies", page, "legacy-2")
+
+	// 已有数据库启动时不执行整库 VACUUM，由 Compact 显式切换
+	reopened.db.Raw("PRAGMA auto_vacuum").Scan(&mode)
+	if mode != 0 {
+		t.Errorf("Expected startup to leave auto_vacuum unchanged, got %d", mode)
+	}
+	if err := reopened.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	reopened.db.Raw("PRAGMA auto_vacuum").Scan(&mode)
+	if mode != 2 {
+		t.Errorf("Expected Compact to switch to incremental auto_vacuum, got %d", mode)
+	}
+}
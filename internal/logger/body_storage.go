@@ -0,0 +1,475 @@
+package logger
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	sqlitedriver "modernc.org/sqlite"
+)
+
+// 迁移、索引重建和数据块回收时每批处理的日志条数
+const bodyStorageBatchSize = 200
+
+// fullTextExcerptBytes 每个正文加入全文索引的最大字节数
+// 索引保存在 logs.db 中，只收录摘录，避免重复的长正文随每次请求写入索引
+const fullTextExcerptBytes = 8 << 10
+
+// GormLogBlob 数据块登记表，用于统计占用空间和回收不再被引用的数据块
+type GormLogBlob struct {
+	Hash       string    `gorm:"column:hash;primaryKey;size:64"`
+	Size       int64     `gorm:"column:size;default:0"`
+	StoredSize int64     `gorm:"column:stored_size;default:0"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (GormLogBlob) TableName() string {
+	return "log_blobs"
+}
+
+// bodyColumn 存放正文的列及其在模型中的字段
+type bodyColumn struct {
+	name  string
+	value *string
+}
+
+// bodyColumns 返回日志中所有正文列
+func (log *GormRequestLog) bodyColumns() []bodyColumn {
+	return []bodyColumn{
+		{"request_body", &log.RequestBody},
+		{"response_body", &log.ResponseBody},
+		{"original_request_body", &log.OriginalRequestBody},
+		{"original_response_body", &log.OriginalResponseBody},
+		{"final_request_body", &log.FinalRequestBody},
+		{"final_response_body", &log.FinalResponseBody},
+	}
+}
+
+func init() {
+	// log_body(blob_dir, body_refs, column) 在 SQL 中还原正文，供不走全文索引的 LIKE 检索使用
+	sqlitedriver.MustRegisterDeterministicScalarFunction("log_body", 3, func(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		dir, _ := args[0].(string)
+		refsJSON, _ := args[1].(string)
+		column, _ := args[2].(string)
+		if refsJSON == "" {
+			return "", nil
+		}
+
+		var refs map[string]string
+		if err := json.Unmarshal([]byte(refsJSON), &refs); err != nil {
+			return "", nil
+		}
+		body, err := readBody(dir, refs[column], nil)
+		if err != nil {
+			// 单条记录的数据块缺失不应中断整个检索
+			return "", nil
+		}
+		return body, nil
+	})
+}
+
+// storeBodies 将正文写入数据块存储，清空正文列并记录引用；返回需要登记的数据块
+// 写入失败时日志保持原样，正文仍以内联方式保存
+func (g *GORMStorage) storeBodies(log *GormRequestLog) ([]GormLogBlob, error) {
+	refs := make(map[string]string)
+	seen := make(map[string]bool)
+	var records []GormLogBlob
+
+	for _, column := range log.bodyColumns() {
+		if *column.value == "" {
+			continue
+		}
+		ref, chunks, err := g.blobs.WriteBody(*column.value)
+		if err != nil {
+			return nil, err
+		}
+		refs[column.name] = ref
+		for _, chunk := range chunks {
+			if seen[chunk.Hash] {
+				continue
+			}
+			seen[chunk.Hash] = true
+			records = append(records, GormLogBlob{Hash: chunk.Hash, Size: chunk.Size, StoredSize: chunk.StoredSize})
+		}
+	}
+
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return nil, err
+	}
+	log.BodyRefs = string(data)
+	for _, column := range log.bodyColumns() {
+		*column.value = ""
+	}
+	return records, nil
+}
+
+// registerBlobs 登记数据块，已登记的数据块保持不变
+func registerBlobs(tx *gorm.DB, records []GormLogBlob) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(records, 100).Error
+}
+
+// loadBodies 按引用还原一批日志的正文；数据块缺失时对应正文留空
+func (g *GORMStorage) loadBodies(logs []GormRequestLog) {
+	cache := make(map[string][]byte)
+	for i := range logs {
+		if logs[i].BodyRefs == "" {
+			continue
+		}
+
+		var refs map[string]string
+		if err := json.Unmarshal([]byte(logs[i].BodyRefs), &refs); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: invalid body references for log %d: %v\n", logs[i].ID, err)
+			continue
+		}
+		for _, column := range logs[i].bodyColumns() {
+			ref, ok := refs[column.name]
+			if !ok {
+				continue
+			}
+			body, err := g.blobs.ReadBody(ref, cache)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to load %s for log %d: %v\n", column.name, logs[i].ID, err)
+				continue
+			}
+			*column.value = body
+		}
+	}
+}
+
+// convertLogs 还原正文并转换为 RequestLog
+func (g *GORMStorage) convertLogs(gormLogs []GormRequestLog) []*RequestLog {
+	g.loadBodies(gormLogs)
+	logs := make([]*RequestLog, len(gormLogs))
+	for i := range gormLogs {
+		logs[i] = ConvertFromGormRequestLog(&gormLogs[i])
+	}
+	return logs
+}
+
+// migrateInlineBodies 将旧版本内联保存在 request_logs 中的正文迁移到数据块存储
+// 分批执行，每批一个事务；stop 关闭时在批次之间退出，已迁移的批次不会重复处理
+func (g *GORMStorage) migrateInlineBodies(stop <-chan struct{}) (int, error) {
+	inline := "body_refs = '' AND (request_body != '' OR response_body != '' OR original_request_body != '' OR " +
+		"original_response_body != '' OR final_request_body != '' OR final_response_body != '')"
+
+	migrated := 0
+	var lastID uint
+	for {
+		select {
+		case <-stop:
+			return migrated, errMaintenanceStopped
+		default:
+		}
+
+		var batch []GormRequestLog
+		err := g.db.Select("id", "body_refs", "request_body", "response_body", "original_request_body",
+			"original_response_body", "final_request_body", "final_response_body").
+			Where("id > ?", lastID).Where(inline).
+			Order("id ASC").Limit(bodyStorageBatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return migrated, fmt.Errorf("failed to read inline bodies: %v", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		if err := g.migrateBatch(batch); err != nil {
+			return migrated, fmt.Errorf("failed to migrate inline bodies: %v", err)
+		}
+
+		migrated += len(batch)
+		lastID = batch[len(batch)-1].ID
+		if migrated%(bodyStorageBatchSize*25) == 0 {
+			fmt.Printf("Log storage maintenance: migrated bodies of %d log entries so far\n", migrated)
+		}
+	}
+	return migrated, nil
+}
+
+// migrateBatch 迁移一批日志的正文；持有读锁，避免刚写入的数据块在引用入库前被回收
+func (g *GORMStorage) migrateBatch(batch []GormRequestLog) error {
+	g.blobMu.RLock()
+	defer g.blobMu.RUnlock()
+
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		for i := range batch {
+			records, err := g.storeBodies(&batch[i])
+			if err != nil {
+				return err
+			}
+			if err := registerBlobs(tx, records); err != nil {
+				return err
+			}
+			updates := map[string]interface{}{"body_refs": batch[i].BodyRefs}
+			for _, column := range batch[i].bodyColumns() {
+				updates[column.name] = ""
+			}
+			if err := tx.Model(&GormRequestLog{}).Where("id = ?", batch[i].ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// rebuildFullTextIndex 为 (next, until] 范围内的已有日志补建全文索引（无内容表，正文来自数据块存储）
+// 之后写入的日志由 SaveLog 自行索引；每批在一个事务中写入索引并推进进度，中断后从进度处继续
+func (g *GORMStorage) rebuildFullTextIndex(stop <-chan struct{}, next, until uint) error {
+	for next < until {
+		select {
+		case <-stop:
+			return errMaintenanceStopped
+		default:
+		}
+
+		var batch []GormRequestLog
+		err := g.db.Select("id", "body_refs", "request_body", "response_body", "error").
+			Where("id > ? AND id <= ?", next, until).
+			Order("id ASC").Limit(bodyStorageBatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		g.loadBodies(batch)
+		err = g.db.Transaction(func(tx *gorm.DB) error {
+			for _, log := range batch {
+				if err := indexFullText(tx, log.ID, log.RequestBody, log.ResponseBody, log.Error); err != nil {
+					return err
+				}
+			}
+			return setMaintenanceValue(tx, maintenanceFTSRebuildNext, batch[len(batch)-1].ID)
+		})
+		if err != nil {
+			return err
+		}
+		next = batch[len(batch)-1].ID
+	}
+	return nil
+}
+
+// indexFullText 将一条日志的正文摘录和错误信息加入全文索引
+func indexFullText(tx *gorm.DB, id uint, requestBody, responseBody, errorText string) error {
+	requestBody = requestExcerpt(requestBody)
+	responseBody = truncateUTF8(responseBody, fullTextExcerptBytes)
+	errorText = truncateUTF8(errorText, fullTextExcerptBytes)
+	if requestBody == "" && responseBody == "" && errorText == "" {
+		return nil
+	}
+	return tx.Exec("INSERT INTO request_logs_fts(rowid, request_body, response_body, error) VALUES (?, ?, ?, ?)",
+		id, requestBody, responseBody, errorText).Error
+}
+
+// requestExcerpt 返回请求体中需要索引的部分：只取最后一条消息，
+// 系统提示、工具定义和更早的消息在每次请求中重复出现，已在之前的请求中索引过
+// 无法解析为消息请求时取开头部分；结果不超过 fullTextExcerptBytes
+func requestExcerpt(body string) string {
+	if len(body) <= fullTextExcerptBytes {
+		return body
+	}
+
+	var request struct {
+		Messages []json.RawMessage `json:"messages"`
+		Input    json.RawMessage   `json:"input"`
+	}
+	if err := json.Unmarshal([]byte(body), &request); err == nil {
+		var inputItems []json.RawMessage
+		switch {
+		case len(request.Messages) > 0:
+			body = string(request.Messages[len(request.Messages)-1])
+		case json.Unmarshal(request.Input, &inputItems) == nil && len(inputItems) > 0:
+			body = string(inputItems[len(inputItems)-1])
+		case len(request.Input) > 0:
+			body = string(request.Input)
+		}
+	}
+	return truncateUTF8(body, fullTextExcerptBytes)
+}
+
+// truncateUTF8 截取不超过 limit 字节的前缀，不截断多字节字符
+func truncateUTF8(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit]
+}
+
+// markReferencedBlobs 收集 id 在 (afterID, upToID] 范围内的日志引用的数据块
+func (g *GORMStorage) markReferencedBlobs(referenced map[string]bool, afterID, upToID uint) error {
+	for {
+		var batch []GormRequestLog
+		err := g.db.Select("id", "body_refs").
+			Where("id > ? AND id <= ? AND body_refs != ''", afterID, upToID).
+			Order("id ASC").Limit(bodyStorageBatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, log := range batch {
+			var refs map[string]string
+			if err := json.Unmarshal([]byte(log.BodyRefs), &refs); err != nil {
+				continue
+			}
+			for _, ref := range refs {
+				for _, hash := range strings.Split(ref, ",") {
+					referenced[hash] = true
+				}
+			}
+		}
+		afterID = batch[len(batch)-1].ID
+	}
+}
+
+// collectBlobs 删除不再被任何日志引用的数据块（标记-清除），返回删除的数据块数量
+func (g *GORMStorage) collectBlobs() (int, error) {
+	// 标记阶段不阻塞日志写入；清除前加写锁，补标记期间新写入的日志
+	var maxID uint
+	if err := g.db.Model(&GormRequestLog{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+		return 0, fmt.Errorf("failed to read max log id: %v", err)
+	}
+	referenced := make(map[string]bool)
+	if err := g.markReferencedBlobs(referenced, 0, maxID); err != nil {
+		return 0, fmt.Errorf("failed to mark referenced blobs: %v", err)
+	}
+
+	g.blobMu.Lock()
+	defer g.blobMu.Unlock()
+
+	if err := g.markReferencedBlobs(referenced, maxID, math.MaxUint32); err != nil {
+		return 0, fmt.Errorf("failed to mark referenced blobs: %v", err)
+	}
+
+	var hashes []string
+	if err := g.db.Model(&GormLogBlob{}).Pluck("hash", &hashes).Error; err != nil {
+		return 0, fmt.Errorf("failed to list blobs: %v", err)
+	}
+
+	var unreferenced []string
+	for _, hash := range hashes {
+		if !referenced[hash] {
+			unreferenced = append(unreferenced, hash)
+		}
+	}
+
+	for start := 0; start < len(unreferenced); start += bodyStorageBatchSize {
+		end := start + bodyStorageBatchSize
+		if end > len(unreferenced) {
+			end = len(unreferenced)
+		}
+		batch := unreferenced[start:end]
+		for _, hash := range batch {
+			if err := g.blobs.Remove(hash); err != nil {
+				return start, fmt.Errorf("failed to remove blob %s: %v", hash, err)
+			}
+		}
+		if err := g.db.Where("hash IN ?", batch).Delete(&GormLogBlob{}).Error; err != nil {
+			return start, fmt.Errorf("failed to delete blob records: %v", err)
+		}
+	}
+	return len(unreferenced), nil
+}
+
+// reclaimSpace 回收数据块并归还数据库空闲页
+func (g *GORMStorage) reclaimSpace() {
+	if _, err := g.collectBlobs(); err != nil {
+		fmt.Printf("Failed to collect unreferenced blobs: %v\n", err)
+	}
+	if err := g.db.Exec("PRAGMA incremental_vacuum").Error; err != nil {
+		fmt.Printf("Failed to vacuum database: %v\n", err)
+	}
+}
+
+// incrementalVacuumEnabled 数据库是否已处于增量 VACUUM 模式
+func (g *GORMStorage) incrementalVacuumEnabled() (bool, error) {
+	var mode int
+	if err := g.db.Raw("PRAGMA auto_vacuum").Scan(&mode).Error; err != nil {
+		return false, err
+	}
+	return mode == 2, nil
+}
+
+// ensureIncrementalVacuum 启用增量 VACUUM；已有数据库需要执行一次完整 VACUUM 才能切换模式
+// 完整 VACUUM 会重写整个数据库并阻塞写入，只由 Compact 显式执行
+func (g *GORMStorage) ensureIncrementalVacuum() error {
+	enabled, err := g.incrementalVacuumEnabled()
+	if err != nil || enabled {
+		return err
+	}
+
+	if err := g.db.Exec("PRAGMA auto_vacuum = INCREMENTAL").Error; err != nil {
+		return err
+	}
+	return g.db.Exec("VACUUM").Error
+}
+
+// storageSize 数据库有效页与数据块文件的总大小（字节）
+func (g *GORMStorage) storageSize() (dbSize, blobSize int64) {
+	var pageCount, freelistCount, pageSize int64
+	g.db.Raw("PRAGMA page_count").Scan(&pageCount)
+	g.db.Raw("PRAGMA freelist_count").Scan(&freelistCount)
+	g.db.Raw("PRAGMA page_size").Scan(&pageSize)
+	g.db.Model(&GormLogBlob{}).Select("COALESCE(SUM(stored_size), 0)").Scan(&blobSize)
+	return (pageCount - freelistCount) * pageSize, blobSize
+}
+
+// enforceStorageLimit 总大小超过上限时从最旧的日志开始删除，返回删除的日志条数
+func (g *GORMStorage) enforceStorageLimit() (int64, error) {
+	if g.maxStorageBytes <= 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+	for {
+		dbSize, blobSize := g.storageSize()
+		if dbSize+blobSize <= g.maxStorageBytes {
+			return deleted, nil
+		}
+
+		var count int64
+		if err := g.db.Model(&GormRequestLog{}).Count(&count).Error; err != nil {
+			return deleted, fmt.Errorf("failed to count logs: %v", err)
+		}
+		if count == 0 {
+			return deleted, nil
+		}
+
+		// 每轮删除最旧的 10%（至少 10 条），数据块去重导致释放空间不确定，逐轮检查
+		batch := count / 10
+		if batch < 10 {
+			batch = 10
+		}
+		result := g.db.Where("id IN (SELECT id FROM request_logs ORDER BY timestamp ASC, id ASC LIMIT ?)", batch).
+			Delete(&GormRequestLog{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("failed to delete old logs: %v", result.Error)
+		}
+		deleted += result.RowsAffected
+		g.reclaimSpace()
+	}
+}
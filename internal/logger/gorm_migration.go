@@ -40,6 +40,12 @@ func createOptimizedIndexes(db *gorm.DB) error {
 		// 新增：组合索引优化客户端分析查询
 		"CREATE INDEX IF NOT EXISTS idx_request_logs_client_time ON request_logs(client_type, timestamp DESC)",
 		"CREATE INDEX IF NOT EXISTS idx_request_logs_format_time ON request_logs(request_format, format_converted, timestamp DESC)",
+
+		// 新增：按 trace ID 关联链路追踪
+		"CREATE INDEX IF NOT EXISTS idx_trace_id ON request_logs(trace_id)",
+
+		// 新增：会话视图按会话聚合与回放
+		"CREATE INDEX IF NOT EXISTS idx_request_logs_session_time ON request_logs(session_id, timestamp ASC)",
 	}
 	
 	for _, sql := range indexes {
@@ -87,6 +93,9 @@ func validateTableCompatibility(db *gorm.DB) error {
 		"format_converted": "format_converted BOOLEAN DEFAULT 0",
 		"detection_confidence": "detection_confidence REAL DEFAULT 0",
 		"detected_by": "detected_by VARCHAR(50) DEFAULT ''",
+		"tool_argument_repairs": "tool_argument_repairs TEXT DEFAULT '[]'",
+		"trace_id": "trace_id VARCHAR(32) DEFAULT ''",
+		"body_refs": "body_refs TEXT DEFAULT ''",
 	}
 	
 	for column, definition := range optionalColumns {
@@ -101,4 +110,73 @@ func validateTableCompatibility(db *gorm.DB) error {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+// checkReadOnlyCompatibility 检查表结构是否已是当前版本，只读打开时不能补齐缺失的表和列
+func checkReadOnlyCompatibility(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&GormRequestLog{}) {
+		return fmt.Errorf("request_logs table does not exist")
+	}
+	if !migrator.HasTable(&GormLogBlob{}) {
+		return fmt.Errorf("log_blobs table does not exist")
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&GormRequestLog{}); err != nil {
+		return err
+	}
+	for _, column := range stmt.Schema.DBNames {
+		if !migrator.HasColumn(&GormRequestLog{}, column) {
+			return fmt.Errorf("column %s does not exist", column)
+		}
+	}
+	return nil
+}
+
+// dropLegacyFullTextSearch 删除旧版基于 request_logs 正文列的外部内容索引及其触发器
+// 返回 true 表示全文索引需要重建（旧版索引或索引不存在）
+func dropLegacyFullTextSearch(db *gorm.DB) bool {
+	var ddl string
+	db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'request_logs_fts'").Scan(&ddl)
+	if ddl == "" {
+		return true
+	}
+	if !strings.Contains(ddl, "content='request_logs'") {
+		return false
+	}
+
+	statements := []string{
+		"DROP TRIGGER IF EXISTS request_logs_fts_insert",
+		"DROP TRIGGER IF EXISTS request_logs_fts_update",
+		"DROP TRIGGER IF EXISTS request_logs_fts_delete",
+		"DROP TABLE IF EXISTS request_logs_fts",
+	}
+	for _, sql := range statements {
+		if err := db.Exec(sql).Error; err != nil {
+			fmt.Printf("Warning: failed to drop legacy full-text index: %v\n", err)
+		}
+	}
+	return true
+}
+
+// setupFullTextSearch 创建覆盖请求体、响应体和错误信息的 FTS5 索引
+// 正文保存在数据块存储中，索引为无内容表：写入日志时由 SaveLog 插入，删除日志时由触发器同步删除
+// 返回 false 表示当前 SQLite 不支持 FTS5，检索退回 LIKE 扫描
+func setupFullTextSearch(db *gorm.DB) bool {
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS request_logs_fts USING fts5(
+			request_body, response_body, error,
+			content='', contentless_delete=1, tokenize='trigram'
+		)`,
+		`CREATE TRIGGER IF NOT EXISTS request_logs_fts_delete AFTER DELETE ON request_logs BEGIN
+			DELETE FROM request_logs_fts WHERE rowid = old.id;
+		END`,
+	}
+
+	for _, sql := range statements {
+		if err := db.Exec(sql).Error; err != nil {
+			fmt.Printf("Warning: full-text search unavailable, falling back to LIKE: %v\n", err)
+			return false
+		}
+	}
+	return true
+}
@@ -72,6 +72,15 @@ type GormRequestLog struct {
 	DetectionConfidence float64 `gorm:"column:detection_confidence;default:0"`
 	DetectedBy          string  `gorm:"column:detected_by;size:50;default:''"`
 
+	// 新增：工具参数修复记录
+	ToolArgumentRepairs string `gorm:"column:tool_argument_repairs;type:text;default:'[]'"` // JSON array
+
+	// 新增：OpenTelemetry trace ID
+	TraceID string `gorm:"column:trace_id;size:32;index:idx_trace_id;default:''"`
+
+	// 新增：正文数据块引用（JSON 对象，列名 -> 数据块哈希列表），正文列此时为空
+	BodyRefs string `gorm:"column:body_refs;type:text;default:''"`
+
 	// 创建时间（现有字段）
 	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
 }
@@ -121,6 +130,8 @@ func ConvertToGormRequestLog(log *RequestLog) *GormRequestLog {
 		FormatConverted:         log.FormatConverted,
 		DetectionConfidence:     log.DetectionConfidence,
 		DetectedBy:              log.DetectedBy,
+		ToolArgumentRepairs:     marshalTagsToJSON(log.ToolArgumentRepairs),
+		TraceID:                 log.TraceID,
 	}
 	
 	// 转换JSON字段
@@ -175,6 +186,8 @@ func ConvertFromGormRequestLog(gormLog *GormRequestLog) *RequestLog {
 		FormatConverted:         gormLog.FormatConverted,
 		DetectionConfidence:     gormLog.DetectionConfidence,
 		DetectedBy:              gormLog.DetectedBy,
+		ToolArgumentRepairs:     unmarshalTagsFromJSON(gormLog.ToolArgumentRepairs),
+		TraceID:                 gormLog.TraceID,
 	}
 	
 	// 转换JSON字段
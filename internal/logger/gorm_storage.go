@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -20,10 +22,40 @@ type GORMStorage struct {
 	config         *GORMConfig
 	cleanupTicker  *time.Ticker
 	stopCleanup    chan struct{}
+	cleanupDone    chan struct{} // 后台清理协程退出后关闭
+	ftsEnabled     bool // 是否可用 FTS5 全文索引，可用时新日志写入即加入索引
+	ftsReady       atomic.Bool // 已有日志已全部加入索引，检索可以使用全文索引
+
+	blobs           *BlobStore   // 请求/响应体数据块存储
+	blobMu          sync.RWMutex // 写日志持读锁，回收数据块持写锁
+	closeOnce       sync.Once
+	maintenanceMu   sync.Mutex    // 串行执行一次性维护（后台任务与 Compact）
+	maintenanceDone chan struct{} // 后台维护结束后关闭
+	maxStorageBytes int64        // 数据库与数据块总大小上限，0 表示不限制
+
+	retentionMu       sync.Mutex      // 保护 retention 与 retentionInterval，配置热更新时修改
+	retention         RetentionPolicy // 成功/失败请求的保留上限
+	retentionInterval time.Duration   // 保留策略检查间隔
+}
+
+// GORMStorageOptions 日志存储选项
+type GORMStorageOptions struct {
+	BodyCompression string // 正文压缩方式：zstd（默认）、gzip、none
+	MaxStorageBytes int64  // 数据库与数据块总大小上限，超出时删除最旧的日志；0 表示不限制
+
+	Retention         *RetentionPolicy // 保留策略，nil 时使用 DefaultRetentionPolicy
+	RetentionInterval time.Duration    // 保留策略检查间隔，0 时为 1 小时
+
+	DisableCleanup bool // 不启动后台清理和后台维护，用于 compact-logs 等自行执行维护的命令行场景
 }
 
 // NewGORMStorage 创建一个新的基于GORM的日志存储
 func NewGORMStorage(logDir string) (*GORMStorage, error) {
+	return NewGORMStorageWithOptions(logDir, GORMStorageOptions{})
+}
+
+// NewGORMStorageWithOptions 按指定选项创建日志存储
+func NewGORMStorageWithOptions(logDir string, options GORMStorageOptions) (*GORMStorage, error) {
 	// 创建日志目录
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create log directory: %v", err)
@@ -74,10 +106,32 @@ func NewGORMStorage(logDir string) (*GORMStorage, error) {
 		}
 	}
 	
+	// 新数据库在建表前直接启用增量 VACUUM；已有数据库不受影响，需通过 Compact 切换
+	if err := db.Exec("PRAGMA auto_vacuum = INCREMENTAL").Error; err != nil {
+		fmt.Printf("Warning: Failed to set auto_vacuum: %v\n", err)
+	}
+	
+	blobs, err := NewBlobStore(filepath.Join(logDir, "blobs"), options.BodyCompression)
+	if err != nil {
+		return nil, err
+	}
+	
 	storage := &GORMStorage{
-		db:          db,
-		config:      config,
-		stopCleanup: make(chan struct{}),
+		db:                db,
+		config:            config,
+		stopCleanup:       make(chan struct{}),
+		cleanupDone:       make(chan struct{}),
+		maintenanceDone:   make(chan struct{}),
+		blobs:             blobs,
+		maxStorageBytes:   options.MaxStorageBytes,
+		retention:         DefaultRetentionPolicy(),
+		retentionInterval: defaultRetentionInterval,
+	}
+	if options.Retention != nil {
+		storage.retention = *options.Retention
+	}
+	if options.RetentionInterval > 0 {
+		storage.retentionInterval = options.RetentionInterval
 	}
 	
 	// 验证表结构兼容性
@@ -87,14 +141,91 @@ func NewGORMStorage(logDir string) (*GORMStorage, error) {
 			return nil, fmt.Errorf("failed to migrate database: %v", err)
 		}
 	}
+	if err := db.AutoMigrate(&GormLogBlob{}, &GormLogMaintenance{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate blob table: %v", err)
+	}
 	
 	// 创建优化索引
 	if err := createOptimizedIndexes(db); err != nil {
 		return nil, fmt.Errorf("failed to create optimized indexes: %v", err)
 	}
 	
-	// 启动后台清理程序
-	storage.startBackgroundCleanup()
+	// 旧版全文索引依赖正文列，需在迁移正文前删除
+	rebuildIndex := dropLegacyFullTextSearch(db)
+	
+	// 创建全文检索索引；已有日志的索引由后台维护补建，补建完成前检索退回 LIKE 扫描
+	storage.ftsEnabled = setupFullTextSearch(db)
+	if storage.ftsEnabled && rebuildIndex {
+		if err := storage.scheduleFullTextRebuild(); err != nil {
+			return nil, fmt.Errorf("failed to schedule full-text index rebuild: %v", err)
+		}
+	}
+	storage.ftsReady.Store(storage.ftsEnabled && !storage.fullTextRebuildPending())
+	
+	// 已有数据库切换增量 VACUUM 需要整库重写，不在启动时执行
+	if enabled, err := storage.incrementalVacuumEnabled(); err == nil && !enabled {
+		fmt.Println("Notice: log database is not in incremental vacuum mode; run the compact-logs command once while the server is stopped to enable it")
+	}
+	
+	// 启动后台维护（迁移内联正文、补建全文索引）和后台清理程序
+	if options.DisableCleanup {
+		close(storage.maintenanceDone)
+		close(storage.cleanupDone)
+	} else {
+		storage.startMaintenance()
+		storage.startBackgroundCleanup()
+	}
+	
+	return storage, nil
+}
+
+// NewReadOnlyGORMStorage 以只读方式打开已有的日志存储，用于命令行导出等场景
+// 不创建目录和表，不执行迁移、后台维护、清理和 VACUUM，连接设置 query_only，不会修改任何数据
+// 数据库不存在或表结构需要迁移时返回错误，此时应先启动一次服务完成迁移
+func NewReadOnlyGORMStorage(logDir string) (*GORMStorage, error) {
+	dbPath := filepath.Join(logDir, "logs.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("log database not found: %v", err)
+	}
+	config := DefaultGORMConfig(dbPath)
+	
+	db, err := gorm.Open(sqlite.Dialector{
+		DriverName: "sqlite",
+		DSN:        fmt.Sprintf("%s?_pragma=query_only(1)&_pragma=busy_timeout(%d)", dbPath, appconfig.Default.Database.BusyTimeout),
+	}, &gorm.Config{
+		Logger: logger.Default.LogMode(config.LogLevel),
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %v", err)
+	}
+	
+	if err := checkReadOnlyCompatibility(db); err != nil {
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			sqlDB.Close()
+		}
+		return nil, fmt.Errorf("log database needs migration, start the server once to upgrade it: %v", err)
+	}
+	
+	storage := &GORMStorage{
+		db:              db,
+		config:          config,
+		stopCleanup:     make(chan struct{}),
+		cleanupDone:     make(chan struct{}),
+		maintenanceDone: make(chan struct{}),
+		// 数据块自带压缩格式标记，读取不依赖压缩配置
+		blobs: &BlobStore{dir: filepath.Join(logDir, "blobs"), compression: BodyCompressionNone},
+	}
+	close(storage.cleanupDone)
+	close(storage.maintenanceDone)
+	
+	// 旧版全文索引或补建未完成时检索退回 LIKE 扫描
+	var ddl string
+	db.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'request_logs_fts'").Scan(&ddl)
+	storage.ftsEnabled = ddl != "" && !strings.Contains(ddl, "content='request_logs'")
+	storage.ftsReady.Store(storage.ftsEnabled && !storage.fullTextRebuildPending())
 	
 	return storage, nil
 }
@@ -104,10 +235,31 @@ func NewGORMStorage(logDir string) (*GORMStorage, error) {
 func (g *GORMStorage) SaveLog(log *RequestLog) {
 	gormLog := ConvertToGormRequestLog(log)
 	
+	// 写入期间持有读锁，避免数据块在日志入库前被回收
+	g.blobMu.RLock()
+	defer g.blobMu.RUnlock()
+	
+	// 正文写入数据块存储；失败时正文仍内联保存在数据库中
+	blobs, err := g.storeBodies(gormLog)
+	if err != nil {
+		fmt.Printf("Failed to save log bodies to blob storage: %v\n", err)
+	}
+	
 	// 添加重试机制处理SQLite BUSY错误
 	maxRetries := appconfig.Default.Database.MaxRetries
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		err := g.db.Create(gormLog).Error
+		err := g.db.Transaction(func(tx *gorm.DB) error {
+			if err := registerBlobs(tx, blobs); err != nil {
+				return err
+			}
+			if err := tx.Create(gormLog).Error; err != nil {
+				return err
+			}
+			if g.ftsEnabled {
+				return indexFullText(tx, gormLog.ID, log.RequestBody, log.ResponseBody, log.Error)
+			}
+			return nil
+		})
 		if err == nil {
 			return // 成功保存
 		}
@@ -156,12 +308,7 @@ func (g *GORMStorage) GetLogs(limit, offset int, failedOnly bool) ([]*RequestLog
 	}
 	
 	// 转换为现有的RequestLog格式
-	logs := make([]*RequestLog, len(gormLogs))
-	for i, gormLog := range gormLogs {
-		logs[i] = ConvertFromGormRequestLog(&gormLog)
-	}
-	
-	return logs, int(total), nil
+	return g.convertLogs(gormLogs), int(total), nil
 }
 
 // GetAllLogsByRequestID 获取指定request_id的所有日志条目
@@ -177,12 +324,7 @@ func (g *GORMStorage) GetAllLogsByRequestID(requestID string) ([]*RequestLog, er
 	}
 	
 	// 转换为现有的RequestLog格式
-	logs := make([]*RequestLog, len(gormLogs))
-	for i, gormLog := range gormLogs {
-		logs[i] = ConvertFromGormRequestLog(&gormLog)
-	}
-	
-	return logs, nil
+	return g.convertLogs(gormLogs), nil
 }
 
 // CleanupLogsByDays 清理指定天数之前的日志
@@ -201,11 +343,9 @@ func (g *GORMStorage) CleanupLogsByDays(days int) (int64, error) {
 		return 0, fmt.Errorf("failed to cleanup logs: %v", result.Error)
 	}
 	
-	// VACUUM 操作（保持与现有实现一致）
+	// 回收不再被引用的数据块并增量归还空闲页，避免整库 VACUUM
 	if result.RowsAffected > 0 {
-		if err := g.db.Exec("VACUUM").Error; err != nil {
-			fmt.Printf("Failed to vacuum database: %v\n", err)
-		}
+		g.reclaimSpace()
 	}
 	
 	return result.RowsAffected, nil
@@ -213,15 +353,18 @@ func (g *GORMStorage) CleanupLogsByDays(days int) (int64, error) {
 
 // Close 关闭数据库连接和清理程序
 func (g *GORMStorage) Close() error {
-	// 停止后台清理程序
+	// 停止后台清理程序（持有 retentionMu，避免与 SetRetention 中的 Reset 交错）
+	g.retentionMu.Lock()
 	if g.cleanupTicker != nil {
 		g.cleanupTicker.Stop()
 	}
+	g.closeOnce.Do(func() { close(g.stopCleanup) })
+	g.retentionMu.Unlock()
+	// 等待进行中的清理和维护结束，避免在已关闭的连接上执行
+	<-g.cleanupDone
+	<-g.maintenanceDone
 	
-	select {
-	case g.stopCleanup <- struct{}{}:
-	default:
-	}
+	g.blobs.Close()
 	
 	// 关闭数据库连接
 	sqlDB, err := g.db.DB()
@@ -231,21 +374,20 @@ func (g *GORMStorage) Close() error {
 	return sqlDB.Close()
 }
 
-// startBackgroundCleanup 启动后台清理程序（保持与现有实现一致）
+// startBackgroundCleanup 启动后台清理程序：按间隔执行保留策略，再检查总大小上限
+// 启动时只检查总大小上限，保留策略在第一个间隔后才执行，避免重启后立即按新策略批量删除日志
 func (g *GORMStorage) startBackgroundCleanup() {
-	g.cleanupTicker = time.NewTicker(24 * time.Hour)
+	g.cleanupTicker = time.NewTicker(g.retentionInterval)
 	
 	go func() {
+		defer close(g.cleanupDone)
+		if g.maxStorageBytes > 0 {
+			g.runStorageLimit()
+		}
 		for {
 			select {
 			case <-g.cleanupTicker.C:
-				// 清理30天前的日志
-				deleted, err := g.CleanupLogsByDays(30)
-				if err != nil {
-					fmt.Printf("Background cleanup error: %v\n", err)
-				} else if deleted > 0 {
-					fmt.Printf("Background cleanup: deleted %d old log entries\n", deleted)
-				}
+				g.runRetention()
 			case <-g.stopCleanup:
 				return
 			}
@@ -253,6 +395,50 @@ func (g *GORMStorage) startBackgroundCleanup() {
 	}()
 }
 
+// SetRetention 替换保留策略和检查间隔，用于配置热更新；间隔变化时从现在起重新计时
+func (g *GORMStorage) SetRetention(policy RetentionPolicy, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultRetentionInterval
+	}
+
+	g.retentionMu.Lock()
+	defer g.retentionMu.Unlock()
+	g.retention = policy
+	select {
+	case <-g.stopCleanup:
+		// 已关闭，不再重启计时器
+	default:
+		if interval != g.retentionInterval && g.cleanupTicker != nil {
+			g.cleanupTicker.Reset(interval)
+		}
+	}
+	g.retentionInterval = interval
+}
+
+// runRetention 执行一次保留策略和总大小检查
+func (g *GORMStorage) runRetention() {
+	deleted, err := g.ApplyRetention(time.Now())
+	if err != nil {
+		fmt.Printf("Background cleanup error: %v\n", err)
+	} else if deleted > 0 {
+		fmt.Printf("Background cleanup: deleted %d old log entries\n", deleted)
+	}
+	
+	if g.maxStorageBytes > 0 {
+		g.runStorageLimit()
+	}
+}
+
+// runStorageLimit 执行一次总大小检查
+func (g *GORMStorage) runStorageLimit() {
+	deleted, err := g.enforceStorageLimit()
+	if err != nil {
+		fmt.Printf("Storage limit cleanup error: %v\n", err)
+	} else if deleted > 0 {
+		fmt.Printf("Storage limit cleanup: deleted %d old log entries\n", deleted)
+	}
+}
+
 // GetStats 获取统计信息
 func (g *GORMStorage) GetStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -273,11 +459,14 @@ func (g *GORMStorage) GetStats() (map[string]interface{}, error) {
 		stats["oldest_log"] = oldestLog.Timestamp
 	}
 	
-	// 数据库大小
-	var pageCount, pageSize int
-	g.db.Raw("PRAGMA page_count").Scan(&pageCount)
-	g.db.Raw("PRAGMA page_size").Scan(&pageSize)
-	stats["db_size_bytes"] = pageCount * pageSize
+	// 数据库与数据块大小
+	dbSize, blobSize := g.storageSize()
+	stats["db_size_bytes"] = dbSize
+	stats["blob_size_bytes"] = blobSize
+	
+	var blobCount int64
+	g.db.Model(&GormLogBlob{}).Count(&blobCount)
+	stats["blob_count"] = blobCount
 	
 	return stats, nil
 }
\ No newline at end of file
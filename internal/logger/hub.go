@@ -0,0 +1,264 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 实时日志流事件类型
+const (
+	StreamEventLog            = "log"
+	StreamEventEndpointStatus = "endpoint_status"
+)
+
+// 端点状态变化类型
+const (
+	EndpointChangeBlacklisted  = "blacklisted"
+	EndpointChangeRecovered    = "recovered"
+	EndpointChangeLearnedParam = "learned_param"
+)
+
+// subscriberBufferSize 每个订阅者的事件缓冲区大小，写满后丢弃新事件而不阻塞发布方
+// 缓冲区中只保存不含正文的日志摘要
+const subscriberBufferSize = 256
+
+// StreamEvent 实时日志流中的一条事件
+type StreamEvent struct {
+	Type     string               `json:"type"`
+	Time     time.Time            `json:"time"`
+	Log      *RequestLog          `json:"log,omitempty"`
+	Endpoint *EndpointStatusEvent `json:"endpoint,omitempty"`
+}
+
+// EndpointStatusEvent 端点状态变化（拉黑、恢复、学习到不支持的参数）
+type EndpointStatusEvent struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Change string `json:"change"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Param  string `json:"param,omitempty"`
+}
+
+// Hub 进程内的日志事件发布/订阅中心
+type Hub struct {
+	mutex       sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// Subscription 一个订阅者；慢消费者的缓冲区写满时事件被丢弃并计数
+type Subscription struct {
+	hub     *Hub
+	query   LogQuery
+	events  chan StreamEvent
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// NewHub 创建发布/订阅中心
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscribe 按日志检索条件订阅事件（游标和条数限制被忽略）
+func (h *Hub) Subscribe(query LogQuery) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		query:  query,
+		events: make(chan StreamEvent, subscriberBufferSize),
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		close(sub.events)
+		return sub
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Publish 将事件分发给条件匹配的订阅者，从不阻塞
+// 条件匹配在锁外进行，订阅者收到的是不含正文的日志摘要
+func (h *Hub) Publish(event StreamEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	h.mutex.RLock()
+	subscribers := make([]*Subscription, 0, len(h.subscribers))
+	for sub := range h.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	h.mutex.RUnlock()
+
+	matched := subscribers[:0]
+	for _, sub := range subscribers {
+		if sub.query.matchesEvent(event) {
+			matched = append(matched, sub)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	published := event
+	if event.Log != nil {
+		published.Log = event.Log.StreamSummary()
+	}
+
+	// 读锁下发送：取消订阅需要写锁，不会向已关闭的通道发送
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for _, sub := range matched {
+		if _, ok := h.subscribers[sub]; !ok {
+			continue
+		}
+		select {
+		case sub.events <- published:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// SubscriberCount 当前订阅者数量
+func (h *Hub) SubscriberCount() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.subscribers)
+}
+
+// Close 关闭所有订阅，之后的订阅立即结束
+func (h *Hub) Close() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		delete(h.subscribers, sub)
+		sub.once.Do(func() { close(sub.events) })
+	}
+}
+
+// Events 事件通道，订阅结束时关闭
+func (s *Subscription) Events() <-chan StreamEvent {
+	return s.events
+}
+
+// TakeDropped 返回自上次调用以来因缓冲区已满而丢弃的事件数
+func (s *Subscription) TakeDropped() uint64 {
+	return s.dropped.Swap(0)
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.hub.mutex.Lock()
+	defer s.hub.mutex.Unlock()
+	delete(s.hub.subscribers, s)
+	s.once.Do(func() { close(s.events) })
+}
+
+// streamLogSnapshot 复制一份日志用于发布，避免与调用方后续的修改产生竞争
+// 请求头等 map 不随事件发布；请求体、响应体和错误只保留与全文索引相同的有限片段用于文本过滤，
+// 其余正文直接去除，发布时再由 StreamSummary 去掉片段
+func streamLogSnapshot(log *RequestLog) *RequestLog {
+	snapshot := *log
+	snapshot.RequestBody = requestExcerpt(log.RequestBody)
+	snapshot.ResponseBody = truncateUTF8(log.ResponseBody, fullTextExcerptBytes)
+	snapshot.Error = truncateUTF8(log.Error, fullTextExcerptBytes)
+	snapshot.OriginalRequestBody = ""
+	snapshot.OriginalResponseBody = ""
+	snapshot.FinalRequestBody = ""
+	snapshot.FinalResponseBody = ""
+	snapshot.RequestHeaders = nil
+	snapshot.ResponseHeaders = nil
+	snapshot.OriginalRequestHeaders = nil
+	snapshot.OriginalResponseHeaders = nil
+	snapshot.FinalRequestHeaders = nil
+	snapshot.FinalResponseHeaders = nil
+	snapshot.Tags = append([]string(nil), log.Tags...)
+	snapshot.BlacklistCausingRequestIDs = append([]string(nil), log.BlacklistCausingRequestIDs...)
+	snapshot.ToolArgumentRepairs = append([]string(nil), log.ToolArgumentRepairs...)
+	return &snapshot
+}
+
+// StreamSummary 去掉请求体和响应体的日志副本，实时流只推送摘要，详情通过 request_id 查询
+func (l *RequestLog) StreamSummary() *RequestLog {
+	summary := *l
+	summary.RequestBody = ""
+	summary.ResponseBody = ""
+	summary.OriginalRequestBody = ""
+	summary.OriginalResponseBody = ""
+	summary.FinalRequestBody = ""
+	summary.FinalResponseBody = ""
+	return &summary
+}
+
+// matchesEvent 判断事件是否满足订阅条件
+// 端点状态事件只受端点过滤条件约束
+func (q LogQuery) matchesEvent(event StreamEvent) bool {
+	switch event.Type {
+	case StreamEventLog:
+		return event.Log != nil && q.Matches(event.Log)
+	case StreamEventEndpointStatus:
+		if event.Endpoint == nil {
+			return false
+		}
+		return q.Endpoint == "" || q.Endpoint == event.Endpoint.Name || q.Endpoint == event.Endpoint.URL
+	}
+	return false
+}
+
+// Matches 在内存中按与 SearchLogs 相同的语义判断日志是否满足条件（游标和条数限制除外）
+func (q LogQuery) Matches(log *RequestLog) bool {
+	if q.Endpoint != "" && log.Endpoint != q.Endpoint {
+		return false
+	}
+	if q.Model != "" && log.Model != q.Model && log.RewrittenModel != q.Model {
+		return false
+	}
+	if q.ClientType != "" && log.ClientType != q.ClientType {
+		return false
+	}
+	if q.SessionID != "" && log.SessionID != q.SessionID {
+		return false
+	}
+	if q.Tag != "" {
+		found := false
+		for _, tag := range log.Tags {
+			if tag == q.Tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Status != nil && (log.StatusCode < q.Status.Min || log.StatusCode > q.Status.Max) {
+		return false
+	}
+	if !q.From.IsZero() && log.Timestamp.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && log.Timestamp.After(q.To) {
+		return false
+	}
+	if q.FormatConverted != nil && log.FormatConverted != *q.FormatConverted {
+		return false
+	}
+	if q.FailedOnly && log.StatusCode < 400 && log.Error == "" {
+		return false
+	}
+	if text := strings.ToLower(strings.TrimSpace(q.Text)); text != "" {
+		if !strings.Contains(strings.ToLower(log.RequestBody), text) &&
+			!strings.Contains(strings.ToLower(log.ResponseBody), text) &&
+			!strings.Contains(strings.ToLower(log.Error), text) {
+			return false
+		}
+	}
+	return true
+}
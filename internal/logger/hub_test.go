@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHub_FiltersAndEndpointEvents(t *testing.T) {
+	hub := NewHub()
+	all := hub.Subscribe(LogQuery{})
+	backup := hub.Subscribe(LogQuery{Endpoint: "https://backup.example.com"})
+	failed := hub.Subscribe(LogQuery{FailedOnly: true})
+
+	hub.Publish(StreamEvent{Type: StreamEventLog, Log: &RequestLog{RequestID: "ok", Endpoint: "https://primary.example.com", StatusCode: 200}})
+	hub.Publish(StreamEvent{Type: StreamEventLog, Log: &RequestLog{RequestID: "err", Endpoint: "https://backup.example.com", StatusCode: 502}})
+	hub.Publish(StreamEvent{Type: StreamEventEndpointStatus, Endpoint: &EndpointStatusEvent{
+		Name: "backup", URL: "https://backup.example.com", Change: EndpointChangeBlacklisted, Status: "inactive",
+	}})
+
+	expect := func(name string, sub *Subscription, want ...string) {
+		t.Helper()
+		var got []string
+		for len(sub.Events()) > 0 {
+			event := <-sub.Events()
+			if event.Time.IsZero() {
+				t.Errorf("%s: expected event time to be set", name)
+			}
+			if event.Log != nil {
+				got = append(got, event.Log.RequestID)
+			} else {
+				got = append(got, event.Endpoint.Change)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+
+	expect("all", all, "ok", "err", EndpointChangeBlacklisted)
+	expect("endpoint filter", backup, "err", EndpointChangeBlacklisted)
+	// 端点状态事件只受端点条件约束
+	expect("failed only", failed, "err", EndpointChangeBlacklisted)
+
+	if hub.SubscriberCount() != 3 {
+		t.Errorf("Expected 3 subscribers, got %d", hub.SubscriberCount())
+	}
+	backup.Close()
+	backup.Close() // 重复关闭是安全的
+	if hub.SubscriberCount() != 2 {
+		t.Errorf("Expected 2 subscribers after close, got %d", hub.SubscriberCount())
+	}
+	if _, ok := <-backup.Events(); ok {
+		t.Errorf("Expected closed subscription channel")
+	}
+}
+
+func TestHub_SlowConsumerDropsInsteadOfBlocking(t *testing.T) {
+	hub := NewHub()
+	slow := hub.Subscribe(LogQuery{})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBufferSize+10; i++ {
+			hub.Publish(StreamEvent{Type: StreamEventLog, Log: &RequestLog{RequestID: fmt.Sprintf("req-%d", i)}})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Publish blocked on a slow consumer")
+	}
+
+	if dropped := slow.TakeDropped(); dropped != 10 {
+		t.Errorf("Expected 10 dropped events, got %d", dropped)
+	}
+	if dropped := slow.TakeDropped(); dropped != 0 {
+		t.Errorf("Expected dropped counter to reset, got %d", dropped)
+	}
+	if first := <-slow.Events(); first.Log.RequestID != "req-0" {
+		t.Errorf("Expected buffered events to be kept in order, got %s", first.Log.RequestID)
+	}
+}
+
+func TestHub_CloseEndsSubscriptions(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(LogQuery{})
+	hub.Close()
+
+	if _, ok := <-sub.Events(); ok {
+		t.Errorf("Expected subscription to end when hub closes")
+	}
+	sub.Close()
+
+	late := hub.Subscribe(LogQuery{})
+	if _, ok := <-late.Events(); ok {
+		t.Errorf("Expected subscription after close to end immediately")
+	}
+	hub.Publish(StreamEvent{Type: StreamEventLog, Log: &RequestLog{}})
+}
+
+func TestLogger_LogRequestPublishesSnapshot(t *testing.T) {
+	log, err := NewLogger(LogConfig{Level: "error", LogRequestTypes: "failed", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer log.Close()
+
+	sub := log.Hub().Subscribe(LogQuery{Text: "needle"})
+	requestLog := &RequestLog{
+		Timestamp:      time.Now(),
+		RequestID:      "req-1",
+		Endpoint:       "primary",
+		RequestBody:    `{"content":"find the needle"}`,
+		RequestHeaders: map[string]string{"Authorization": "secret"},
+		Tags:           []string{"vip"},
+	}
+	log.LogRequest(requestLog)
+	requestLog.Tags[0] = "mutated"
+
+	event := <-sub.Events()
+	if event.Type != StreamEventLog || event.Log.RequestID != "req-1" {
+		t.Fatalf("Expected published log event, got %+v", event)
+	}
+	if event.Log.RequestHeaders != nil {
+		t.Errorf("Expected headers not to be published")
+	}
+	if event.Log.Tags[0] != "vip" {
+		t.Errorf("Expected published log to be a snapshot, got tags %v", event.Log.Tags)
+	}
+	if event.Log.RequestBody != "" {
+		t.Errorf("Expected subscribers to receive a summary without bodies, got %+v", event.Log)
+	}
+
+	log.PublishEndpointStatus(EndpointStatusEvent{Name: "primary", Change: EndpointChangeLearnedParam, Param: "top_k"})
+	// 文本条件不影响端点状态事件
+	if event := <-sub.Events(); event.Endpoint == nil || event.Endpoint.Param != "top_k" {
+		t.Errorf("Expected endpoint status event, got %+v", event)
+	}
+}
+
+// 文本过滤只作用于与全文索引相同的有限片段
+func TestStreamLogSnapshot_KeepsBoundedExcerpts(t *testing.T) {
+	snapshot := streamLogSnapshot(&RequestLog{
+		RequestBody:       strings.Repeat("a", fullTextExcerptBytes) + "needle",
+		ResponseBody:      "needle " + strings.Repeat("b", 2*fullTextExcerptBytes),
+		FinalResponseBody: "final",
+	})
+	if len(snapshot.RequestBody) > fullTextExcerptBytes || len(snapshot.ResponseBody) > fullTextExcerptBytes {
+		t.Errorf("Expected bounded bodies, got %d and %d bytes", len(snapshot.RequestBody), len(snapshot.ResponseBody))
+	}
+	if snapshot.FinalResponseBody != "" {
+		t.Errorf("Expected bodies not used for filtering to be dropped")
+	}
+	if !(LogQuery{Text: "needle"}).Matches(snapshot) {
+		t.Errorf("Expected the response excerpt to match")
+	}
+}
+
+// Matches 与 SearchLogs 的过滤语义保持一致
+func TestLogQuery_MatchesAgreesWithSearchLogs(t *testing.T) {
+	storage, _ := setupQueryStorage(t)
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	saveQueryLogs(storage, base)
+
+	all, err := storage.SearchLogs(LogQuery{})
+	if err != nil {
+		t.Fatalf("SearchLogs failed: %v", err)
+	}
+
+	converted := false
+	queries := []LogQuery{
+		{Endpoint: "backup"},
+		{Model: "gpt-4o"},
+		{ClientType: "codex", SessionID: "sess-b"},
+		{Tag: "vip"},
+		{Status: &StatusRange{Min: 500, Max: 599}},
+		{From: base.Add(time.Minute), To: base.Add(2 * time.Minute)},
+		{FormatConverted: &converted},
+		{FailedOnly: true},
+		{Text: "Rate Limit"},
+		{Text: "暂不可用"},
+		{Text: "0%"},
+	}
+	for _, query := range queries {
+		page, err := storage.SearchLogs(query)
+		if err != nil {
+			t.Fatalf("SearchLogs(%+v) failed: %v", query, err)
+		}
+		var matched []string
+		for _, log := range all.Logs {
+			if query.Matches(log) {
+				matched = append(matched, log.RequestID)
+			}
+		}
+		if fmt.Sprint(matched) != fmt.Sprint(requestIDs(page)) {
+			t.Errorf("Query %+v: Matches selected %v, SearchLogs returned %v", query, matched, requestIDs(page))
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package logger
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor 游标无法解析
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// LogQuery 日志检索条件，零值字段表示不过滤
+type LogQuery struct {
+	Endpoint        string
+	Model           string // 匹配客户端请求的模型名或重写后的模型名
+	ClientType      string
+	SessionID       string
+	Tag             string
+	Status          *StatusRange
+	From            time.Time
+	To              time.Time
+	FormatConverted *bool
+	FailedOnly      bool
+	Text            string // 全文检索请求体、响应体和错误信息
+	Cursor          string // 上一页返回的 NextCursor，空表示从最新的记录开始
+	Limit           int
+}
+
+// LogPage 一页检索结果
+type LogPage struct {
+	Logs       []*RequestLog `json:"logs"`
+	Total      int           `json:"total"`       // 满足过滤条件的记录总数（不受游标影响）
+	NextCursor string        `json:"next_cursor"` // 为空表示没有更早的记录
+}
+
+// StatusRange 状态码范围（含两端）
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// ParseStatusRange 解析状态码过滤条件，支持 "404"、"4xx"、"400-499" 三种写法
+func ParseStatusRange(value string) (*StatusRange, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return nil, nil
+	}
+
+	if len(value) == 3 && strings.HasSuffix(value, "xx") {
+		class, err := strconv.Atoi(value[:1])
+		if err != nil || class < 1 || class > 5 {
+			return nil, fmt.Errorf("invalid status class: %s", value)
+		}
+		return &StatusRange{Min: class * 100, Max: class*100 + 99}, nil
+	}
+
+	if minStr, maxStr, found := strings.Cut(value, "-"); found {
+		low, err1 := strconv.Atoi(strings.TrimSpace(minStr))
+		high, err2 := strconv.Atoi(strings.TrimSpace(maxStr))
+		if err1 != nil || err2 != nil || low < 0 || low > high {
+			return nil, fmt.Errorf("invalid status range: %s", value)
+		}
+		return &StatusRange{Min: low, Max: high}, nil
+	}
+
+	status, err := strconv.Atoi(value)
+	if err != nil || status < 0 {
+		return nil, fmt.Errorf("invalid status code: %s", value)
+	}
+	return &StatusRange{Min: status, Max: status}, nil
+}
+
+// ParseLogTime 解析时间过滤参数，支持 RFC3339、页面 datetime-local 格式和日期（后两者按服务器本地时区）
+func ParseLogTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
+
+// ParseLogTimeEnd 解析时间范围的结束时间，只给出日期时包含当天全部记录
+func ParseLogTimeEnd(value string) (time.Time, error) {
+	t, err := ParseLogTime(value)
+	if err == nil && len(value) == len("2006-01-02") {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, err
+}
+
+// encodeLogCursor 游标记录上一页最后一条日志的 (timestamp, id)
+func encodeLogCursor(timestamp time.Time, id uint) string {
+	raw := timestamp.Format(time.RFC3339Nano) + "|" + strconv.FormatUint(uint64(id), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeLogCursor 解析 encodeLogCursor 生成的游标
+func decodeLogCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	timestampStr, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, timestampStr)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	return timestamp, uint(id), nil
+}
+
+// ftsQuery 将用户输入转为 FTS5 短语查询，避免其中的运算符被解析
+func ftsQuery(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(text)
+}
+
+// applyLogFilters 应用除游标外的所有过滤条件
+func (g *GORMStorage) applyLogFilters(db *gorm.DB, query LogQuery) *gorm.DB {
+	if query.Endpoint != "" {
+		db = db.Where("endpoint = ?", query.Endpoint)
+	}
+	if query.Model != "" {
+		db = db.Where("(model = ? OR rewritten_model = ?)", query.Model, query.Model)
+	}
+	if query.ClientType != "" {
+		db = db.Where("client_type = ?", query.ClientType)
+	}
+	if query.SessionID != "" {
+		db = db.Where("session_id = ?", query.SessionID)
+	}
+	if query.Tag != "" {
+		db = db.Where("EXISTS (SELECT 1 FROM json_each(request_logs.tags) WHERE json_each.value = ?)", query.Tag)
+	}
+	if query.Status != nil {
+		db = db.Where("status_code BETWEEN ? AND ?", query.Status.Min, query.Status.Max)
+	}
+	if !query.From.IsZero() {
+		db = db.Where("timestamp >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		db = db.Where("timestamp <= ?", query.To)
+	}
+	if query.FormatConverted != nil {
+		db = db.Where("format_converted = ?", *query.FormatConverted)
+	}
+	if query.FailedOnly {
+		// 与 GetLogs 的失败判定保持一致
+		db = db.Where("(status_code >= ? OR error != ?)", 400, "")
+	}
+
+	if text := strings.TrimSpace(query.Text); text != "" {
+		// trigram 分词器无法匹配少于 3 个字符的短语，此时或索引尚未补建完成时退回 LIKE 扫描
+		if g.ftsReady.Load() && utf8.RuneCountInString(text) >= 3 {
+			db = db.Where("id IN (SELECT rowid FROM request_logs_fts WHERE request_logs_fts MATCH ?)", ftsQuery(text))
+		} else {
+			// 正文保存在数据块存储中，通过 log_body 函数还原后匹配；旧版未迁移的记录仍匹配正文列
+			pattern := "%" + escapeLike(text) + "%"
+			db = db.Where(`(request_body LIKE ? ESCAPE '\' OR response_body LIKE ? ESCAPE '\' OR error LIKE ? ESCAPE '\' OR
+				(body_refs != '' AND (log_body(?, body_refs, 'request_body') LIKE ? ESCAPE '\' OR log_body(?, body_refs, 'response_body') LIKE ? ESCAPE '\')))`,
+				pattern, pattern, pattern, g.blobs.dir, pattern, g.blobs.dir, pattern)
+		}
+	}
+	return db
+}
+
+// SearchLogs 按条件检索日志，按时间倒序并使用游标分页
+func (g *GORMStorage) SearchLogs(query LogQuery) (*LogPage, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var total int64
+	if err := g.applyLogFilters(g.db.Model(&GormRequestLog{}), query).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to get total count: %v", err)
+	}
+
+	db := g.applyLogFilters(g.db.Model(&GormRequestLog{}), query)
+	if query.Cursor != "" {
+		timestamp, id, err := decodeLogCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("(timestamp < ? OR (timestamp = ? AND id < ?))", timestamp, timestamp, id)
+	}
+
+	// 多取一条用于判断是否还有下一页
+	var gormLogs []GormRequestLog
+	err := db.Order("timestamp DESC").Order("id DESC").
+		Limit(limit + 1).
+		Find(&gormLogs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to search logs: %v", err)
+	}
+
+	page := &LogPage{Total: int(total)}
+	if len(gormLogs) > limit {
+		gormLogs = gormLogs[:limit]
+		last := gormLogs[len(gormLogs)-1]
+		page.NextCursor = encodeLogCursor(last.Timestamp, last.ID)
+	}
+
+	page.Logs = g.convertLogs(gormLogs)
+	return page, nil
+}
@@ -0,0 +1,316 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func setupQueryStorage(t *testing.T) (*GORMStorage, string) {
+	dir := t.TempDir()
+	storage, err := NewGORMStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage, dir
+}
+
+func saveQueryLogs(storage *GORMStorage, base time.Time) {
+	logs := []*RequestLog{
+		{RequestID: "req-1", Endpoint: "primary", Model: "claude-sonnet", StatusCode: 200, ClientType: "claude-code", SessionID: "sess-a",
+			Tags: []string{"vip"}, RequestBody: `{"messages":[{"content":"refactor the parser"}]}`, ResponseBody: `{"content":"done"}`},
+		{RequestID: "req-2", Endpoint: "primary", Model: "claude-sonnet", RewrittenModel: "gpt-4o", ModelRewriteApplied: true, StatusCode: 429,
+			ClientType: "claude-code", SessionID: "sess-a", Error: "rate limit exceeded", FormatConverted: true},
+		{RequestID: "req-3", Endpoint: "backup", Model: "gpt-5", StatusCode: 502, ClientType: "codex", SessionID: "sess-b",
+			Tags: []string{"vipx", "batch"}, ResponseBody: `{"error":"上游服务暂不可用"}`},
+		{RequestID: "req-4", Endpoint: "backup", Model: "gpt-5", StatusCode: 0, ClientType: "codex", Error: "dial tcp: connection refused",
+			RequestBody: `{"input":"100% done_ok"}`},
+	}
+	for i, log := range logs {
+		log.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		storage.SaveLog(log)
+	}
+}
+
+func requestIDs(page *LogPage) []string {
+	ids := make([]string, len(page.Logs))
+	for i, log := range page.Logs {
+		ids[i] = log.RequestID
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, name string, page *LogPage, want ...string) {
+	t.Helper()
+	got := requestIDs(page)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s: expected %v, got %v", name, want, got)
+	}
+	if page.Total != len(want) {
+		t.Errorf("%s: expected total %d, got %d", name, len(want), page.Total)
+	}
+}
+
+func TestSearchLogs_Filters(t *testing.T) {
+	storage, _ := setupQueryStorage(t)
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	saveQueryLogs(storage, base)
+
+	converted := true
+	testCases := []struct {
+		name  string
+		query LogQuery
+		want  []string
+	}{
+		{"no filter", LogQuery{}, []string{"req-4", "req-3", "req-2", "req-1"}},
+		{"endpoint", LogQuery{Endpoint: "backup"}, []string{"req-4", "req-3"}},
+		{"rewritten model", LogQuery{Model: "gpt-4o"}, []string{"req-2"}},
+		{"client type and session", LogQuery{ClientType: "claude-code", SessionID: "sess-a"}, []string{"req-2", "req-1"}},
+		{"tag exact match", LogQuery{Tag: "vip"}, []string{"req-1"}},
+		{"status class", LogQuery{Status: &StatusRange{Min: 400, Max: 499}}, []string{"req-2"}},
+		{"status zero", LogQuery{Status: &StatusRange{Min: 0, Max: 0}}, []string{"req-4"}},
+		{"time range", LogQuery{From: base.Add(time.Minute), To: base.Add(2 * time.Minute)}, []string{"req-3", "req-2"}},
+		{"format converted", LogQuery{FormatConverted: &converted}, []string{"req-2"}},
+		{"failed only", LogQuery{FailedOnly: true}, []string{"req-4", "req-3", "req-2"}},
+		{"full text request body", LogQuery{Text: "refactor the"}, []string{"req-1"}},
+		{"full text is case insensitive", LogQuery{Text: "RATE LIMIT"}, []string{"req-2"}},
+		{"full text chinese", LogQuery{Text: "暂不可用"}, []string{"req-3"}},
+		{"full text operators are literal", LogQuery{Text: `connection OR "refused`}, nil},
+		{"short text falls back to like", LogQuery{Text: "ok"}, []string{"req-4"}},
+		{"like wildcards are escaped", LogQuery{Text: "0%"}, []string{"req-4"}},
+		{"combined", LogQuery{Endpoint: "backup", Text: "connection refused"}, []string{"req-4"}},
+	}
+
+	for _, tc := range testCases {
+		page, err := storage.SearchLogs(tc.query)
+		if err != nil {
+			t.Fatalf("%s: SearchLogs failed: %v", tc.name, err)
+		}
+		assertIDs(t, tc.name, page, tc.want...)
+	}
+}
+
+func TestSearchLogs_CursorPagination(t *testing.T) {
+	storage, _ := setupQueryStorage(t)
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	// 相同时间戳的记录依靠 id 决定顺序，跨页时不能重复或遗漏
+	for i := 0; i < 7; i++ {
+		storage.SaveLog(&RequestLog{
+			Timestamp: base.Add(time.Duration(i/3) * time.Second),
+			RequestID: fmt.Sprintf("req-%d", i),
+			Endpoint:  "primary",
+		})
+	}
+
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatalf("Pagination did not terminate")
+		}
+		page, err := storage.SearchLogs(LogQuery{Limit: 3, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("SearchLogs failed: %v", err)
+		}
+		if page.Total != 7 {
+			t.Errorf("Expected total 7 on every page, got %d", page.Total)
+		}
+		seen = append(seen, requestIDs(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	want := "[req-6 req-5 req-4 req-3 req-2 req-1 req-0]"
+	if fmt.Sprint(seen) != want {
+		t.Errorf("Expected %s, got %v", want, seen)
+	}
+
+	if _, err := storage.SearchLogs(LogQuery{Cursor: "not-a-cursor"}); err == nil {
+		t.Errorf("Expected invalid cursor to be rejected")
+	}
+}
+
+func TestSearchLogs_FullTextIndexMaintenance(t *testing.T) {
+	storage, dir := setupQueryStorage(t)
+	if !storage.ftsEnabled {
+		t.Skip("FTS5 not available")
+	}
+	saveQueryLogs(storage, time.Now().Add(-time.Hour))
+
+	// 模拟旧数据库：删除全文索引后重新打开，应回填已有记录
+	if err := storage.db.Exec("DROP TABLE request_logs_fts").Error; err != nil {
+		t.Fatalf("Failed to drop FTS table: %v", err)
+	}
+	storage.Close()
+
+	// 不启动后台维护时索引补建保持待执行，检索退回 LIKE 扫描
+	pending, err := NewGORMStorageWithOptions(dir, GORMStorageOptions{DisableCleanup: true})
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	if pending.ftsReady.Load() || !pending.fullTextRebuildPending() {
+		t.Errorf("Expected full-text index rebuild to be pending")
+	}
+	page, err := pending.SearchLogs(LogQuery{Text: "connection refused"})
+	if err != nil {
+		t.Fatalf("SearchLogs failed: %v", err)
+	}
+	assertIDs(t, "like search while rebuild is pending", page, "req-4")
+	pending.Close()
+
+	reopened, err := NewGORMStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer reopened.Close()
+	<-reopened.maintenanceDone
+	if !reopened.ftsReady.Load() {
+		t.Fatalf("Expected full-text index to be ready after background rebuild")
+	}
+
+	page, err = reopened.SearchLogs(LogQuery{Text: "connection refused"})
+	if err != nil {
+		t.Fatalf("SearchLogs failed: %v", err)
+	}
+	assertIDs(t, "backfilled index", page, "req-4")
+
+	// 删除记录后索引同步删除
+	if _, err := reopened.CleanupLogsByDays(0); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	var indexed int64
+	reopened.db.Raw("SELECT COUNT(*) FROM request_logs_fts WHERE request_logs_fts MATCH ?", ftsQuery("connection")).Scan(&indexed)
+	if indexed != 0 {
+		t.Errorf("Expected deleted logs to be removed from the full-text index, got %d", indexed)
+	}
+}
+
+func TestSearchLogs_FullTextIndexesBoundedExcerpt(t *testing.T) {
+	storage, _ := setupQueryStorage(t)
+	if !storage.ftsEnabled {
+		t.Skip("FTS5 not available")
+	}
+
+	// 每次请求都带着同样的长系统提示，只有最后一条消息是新的
+	system := strings.Repeat("You are a careful assistant. ", 2000)
+	for i, message := range []string{"first question about parsers", "second question about lexers"} {
+		body := fmt.Sprintf(`{"system":%q,"messages":[{"role":"user","content":"earlier turn"},{"role":"user","content":%q}]}`, system, message)
+		storage.SaveLog(&RequestLog{Timestamp: time.Now(), RequestID: fmt.Sprintf("req-%d", i), Endpoint: "primary", RequestBody: body})
+	}
+
+	page, err := storage.SearchLogs(LogQuery{Text: "question about lexers"})
+	if err != nil {
+		t.Fatalf("SearchLogs failed: %v", err)
+	}
+	assertIDs(t, "last message", page, "req-1")
+
+	var indexed int64
+	storage.db.Raw("SELECT COUNT(*) FROM request_logs_fts WHERE request_logs_fts MATCH ?", ftsQuery("careful assistant")).Scan(&indexed)
+	if indexed != 0 {
+		t.Errorf("Expected repeated system prompt to stay out of the full-text index, got %d matches", indexed)
+	}
+
+	if excerpt := requestExcerpt("{" + strings.Repeat("中", fullTextExcerptBytes)); len(excerpt) > fullTextExcerptBytes || !utf8.ValidString(excerpt) {
+		t.Errorf("Expected a valid excerpt within %d bytes, got %d bytes", fullTextExcerptBytes, len(excerpt))
+	}
+}
+
+// 只读打开不迁移、不写入，仍可检索和读取正文
+func TestNewReadOnlyGORMStorage(t *testing.T) {
+	storage, dir := setupQueryStorage(t)
+	saveQueryLogs(storage, time.Now().Add(-time.Hour))
+	<-storage.maintenanceDone
+
+	readOnly, err := NewReadOnlyGORMStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to open read-only storage: %v", err)
+	}
+	defer readOnly.Close()
+
+	page, err := readOnly.SearchLogs(LogQuery{Text: "parser"})
+	if err != nil {
+		t.Fatalf("SearchLogs failed: %v", err)
+	}
+	assertIDs(t, "read-only search", page, "req-1")
+	if !strings.Contains(page.Logs[0].RequestBody, "refactor the parser") {
+		t.Errorf("Expected request body to be loaded from blob storage, got %q", page.Logs[0].RequestBody)
+	}
+	if err := readOnly.db.Exec("DELETE FROM request_logs").Error; err == nil {
+		t.Errorf("Expected writes through read-only storage to fail")
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := NewReadOnlyGORMStorage(missing); err == nil {
+		t.Errorf("Expected error for missing log database")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("Expected read-only open not to create the log directory")
+	}
+}
+
+func TestParseStatusRange(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    *StatusRange
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"404", &StatusRange{404, 404}, false},
+		{"0", &StatusRange{0, 0}, false},
+		{"5xx", &StatusRange{500, 599}, false},
+		{"4XX", &StatusRange{400, 499}, false},
+		{"400-499", &StatusRange{400, 499}, false},
+		{"9xx", nil, true},
+		{"500-400", nil, true},
+		{"abc", nil, true},
+	}
+
+	for _, tc := range testCases {
+		got, err := ParseStatusRange(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseStatusRange(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("ParseStatusRange(%q) = %v, want %v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestParseLogTime(t *testing.T) {
+	testCases := []struct {
+		input   string
+		end     bool
+		want    time.Time
+		wantErr bool
+	}{
+		{"2026-10-18T14:30:00Z", false, time.Date(2026, 10, 18, 14, 30, 0, 0, time.UTC), false},
+		{"2026-10-18T14:30", false, time.Date(2026, 10, 18, 14, 30, 0, 0, time.Local), false},
+		{"2026-10-18", false, time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local), false},
+		{"2026-10-18", true, time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local).Add(-time.Nanosecond), false},
+		{"2026-10-18T14:30", true, time.Date(2026, 10, 18, 14, 30, 0, 0, time.Local), false},
+		{"yesterday", false, time.Time{}, true},
+	}
+
+	for _, tc := range testCases {
+		parse := ParseLogTime
+		if tc.end {
+			parse = ParseLogTimeEnd
+		}
+		got, err := parse(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parse(%q, end=%v) error = %v, wantErr %v", tc.input, tc.end, err, tc.wantErr)
+			continue
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("parse(%q, end=%v) = %v, want %v", tc.input, tc.end, got, tc.want)
+		}
+	}
+}
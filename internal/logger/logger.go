@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	appconfig "claude-code-codex-companion/internal/config"
 	"claude-code-codex-companion/internal/utils"
 
 	"github.com/sirupsen/logrus"
@@ -67,14 +69,24 @@ type RequestLog struct {
 	FormatConverted    bool    `json:"format_converted"`               // 是否进行了格式转换
 	DetectionConfidence float64 `json:"detection_confidence,omitempty"` // 格式检测置信度 (0.0-1.0)
 	DetectedBy         string  `json:"detected_by,omitempty"`          // 检测方法: "path" | "body-structure" | "default"
+
+	// 新增：响应转换时对工具参数所做的修复（按 input_schema 校验）
+	ToolArgumentRepairs []string `json:"tool_argument_repairs,omitempty"`
+
+	// 新增：OpenTelemetry trace ID（沿用入站 traceparent 或由代理生成）
+	TraceID string `json:"trace_id,omitempty"`
 }
 
 // StorageInterface defines the interface for log storage backends
 type StorageInterface interface {
 	SaveLog(log *RequestLog)
 	GetLogs(limit, offset int, failedOnly bool) ([]*RequestLog, int, error)
+	SearchLogs(query LogQuery) (*LogPage, error)
 	GetAllLogsByRequestID(requestID string) ([]*RequestLog, error)
+	ListSessions(limit, offset int) ([]*SessionSummary, int, error)
+	GetLogsBySessionID(sessionID string) ([]*RequestLog, error)
 	CleanupLogsByDays(days int) (int64, error)
+	SetRetention(policy RetentionPolicy, interval time.Duration)
 	Close() error
 }
 
@@ -82,6 +94,15 @@ type Logger struct {
 	logger  *logrus.Logger
 	storage StorageInterface
 	config  LogConfig
+	hub     *Hub // 实时日志流
+
+	policies *logPolicies // 脱敏与采样规则；以指针共享，按值复制的 Logger 也能看到热更新
+}
+
+// logPolicies 配置热更新时整体替换的日志规则
+type logPolicies struct {
+	redactor atomic.Pointer[Redactor] // 入库和推送前脱敏
+	sampler  atomic.Pointer[Sampler]  // 成功请求采样
 }
 
 type LogConfig struct {
@@ -90,6 +111,12 @@ type LogConfig struct {
 	LogRequestBody  string
 	LogResponseBody string
 	LogDirectory    string
+	BodyCompression string // 正文数据块压缩方式：zstd | gzip | none
+	MaxStorageMB    int    // 数据库与数据块总大小上限（MB），0 表示不限制
+
+	Retention appconfig.LogRetentionConfig // 保留策略
+	Sampling  []appconfig.LogSamplingRule  // 采样规则，endpoint 需已解析为端点 URL
+	Redaction appconfig.LogRedactionConfig // 脱敏规则
 }
 
 func NewLogger(config LogConfig) (*Logger, error) {
@@ -105,22 +132,72 @@ func NewLogger(config LogConfig) (*Logger, error) {
 		TimestampFormat: time.RFC3339,
 	})
 
+	retention, retentionInterval, err := NewRetentionPolicy(config.Retention)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log retention policy: %v", err)
+	}
+	redactor, err := NewRedactor(config.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
 	// Use GORM storage instead of SQLite storage
-	storage, err := NewGORMStorage(config.LogDirectory)
+	storage, err := NewGORMStorageWithOptions(config.LogDirectory, GORMStorageOptions{
+		BodyCompression:   config.BodyCompression,
+		MaxStorageBytes:   int64(config.MaxStorageMB) << 20,
+		Retention:         &retention,
+		RetentionInterval: retentionInterval,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize GORM log storage: %v", err)
 	}
 
-	return &Logger{
-		logger:  logger,
-		storage: storage,
-		config:  config,
-	}, nil
+	l := &Logger{
+		logger:   logger,
+		storage:  storage,
+		config:   config,
+		hub:      NewHub(),
+		policies: &logPolicies{},
+	}
+	l.policies.redactor.Store(redactor)
+	l.policies.sampler.Store(NewSampler(config.Sampling))
+	return l, nil
+}
+
+// UpdatePolicies 配置热更新时替换保留策略、采样规则和脱敏规则，任一配置无效时不做任何修改
+// 新规则只作用于之后写入的日志；保留策略在下一次定时检查时生效
+func (l *Logger) UpdatePolicies(retentionConfig appconfig.LogRetentionConfig, sampling []appconfig.LogSamplingRule, redaction appconfig.LogRedactionConfig) error {
+	retention, retentionInterval, err := NewRetentionPolicy(retentionConfig)
+	if err != nil {
+		return fmt.Errorf("invalid log retention policy: %v", err)
+	}
+	redactor, err := NewRedactor(redaction)
+	if err != nil {
+		return err
+	}
+
+	l.policies.redactor.Store(redactor)
+	l.policies.sampler.Store(NewSampler(sampling))
+	if l.storage != nil {
+		l.storage.SetRetention(retention, retentionInterval)
+	}
+	return nil
 }
 
 func (l *Logger) LogRequest(log *RequestLog) {
-	// 总是记录到存储，方便Web界面查看
-	l.storage.SaveLog(log)
+	// 先脱敏，密钥不会写入存储或推送给订阅者
+	l.policies.redactor.Load().RedactLog(log)
+
+	// 未被采样的成功请求既不入库也不推送
+	if l.policies.sampler.Load().Keep(log) {
+		// 记录到存储，方便Web界面查看
+		l.storage.SaveLog(log)
+
+		// 推送给实时日志流的订阅者；无订阅者时跳过快照复制
+		if l.hub != nil && l.hub.SubscriberCount() > 0 {
+			l.hub.Publish(StreamEvent{Type: StreamEventLog, Log: streamLogSnapshot(log)})
+		}
+	}
 
 	// 根据配置决定是否输出到控制台
 	shouldLog := l.shouldLogRequest(log.StatusCode)
@@ -211,6 +288,14 @@ func (l *Logger) GetLogs(limit, offset int, failedOnly bool) ([]*RequestLog, int
 	return l.storage.GetLogs(limit, offset, failedOnly)
 }
 
+// SearchLogs 按条件检索日志（游标分页）
+func (l *Logger) SearchLogs(query LogQuery) (*LogPage, error) {
+	if l.storage == nil {
+		return &LogPage{Logs: []*RequestLog{}}, nil
+	}
+	return l.storage.SearchLogs(query)
+}
+
 func (l *Logger) GetAllLogsByRequestID(requestID string) ([]*RequestLog, error) {
 	if l.storage == nil {
 		return []*RequestLog{}, nil
@@ -218,6 +303,22 @@ func (l *Logger) GetAllLogsByRequestID(requestID string) ([]*RequestLog, error)
 	return l.storage.GetAllLogsByRequestID(requestID)
 }
 
+// ListSessions 列出会话概要（按最近活动时间倒序）
+func (l *Logger) ListSessions(limit, offset int) ([]*SessionSummary, int, error) {
+	if l.storage == nil {
+		return []*SessionSummary{}, 0, nil
+	}
+	return l.storage.ListSessions(limit, offset)
+}
+
+// GetLogsBySessionID 按时间顺序获取会话的全部日志，仅每个请求的最后一次尝试带有正文
+func (l *Logger) GetLogsBySessionID(sessionID string) ([]*RequestLog, error) {
+	if l.storage == nil {
+		return []*RequestLog{}, nil
+	}
+	return l.storage.GetLogsBySessionID(sessionID)
+}
+
 func (l *Logger) CleanupLogsByDays(days int) (int64, error) {
 	if l.storage == nil {
 		return 0, fmt.Errorf("storage not available")
@@ -272,8 +373,33 @@ func (l *Logger) UpdateRequestLog(log *RequestLog, req *http.Request, resp *http
 	}
 }
 
+// Hub 返回实时日志流的发布/订阅中心
+func (l *Logger) Hub() *Hub {
+	return l.hub
+}
+
+// PublishEndpointStatus 将端点状态变化推送到实时日志流
+func (l *Logger) PublishEndpointStatus(event EndpointStatusEvent) {
+	if l.hub != nil {
+		l.hub.Publish(StreamEvent{Type: StreamEventEndpointStatus, Endpoint: &event})
+	}
+}
+
+// RedactText 按配置的脱敏规则遮盖文本中的密钥，用于日志以外的输出（如链路追踪中的错误信息）
+func (l *Logger) RedactText(text string) string {
+	return l.policies.redactor.Load().RedactText(text)
+}
+
+// RedactLog 按配置的脱敏规则就地脱敏日志，用于导出入库前（或旧版本）记录的日志
+func (l *Logger) RedactLog(log *RequestLog) {
+	l.policies.redactor.Load().RedactLog(log)
+}
+
 // Close closes the logger and its storage backend
 func (l *Logger) Close() error {
+	if l.hub != nil {
+		l.hub.Close()
+	}
 	if l.storage != nil {
 		return l.storage.Close()
 	}
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 后台维护任务在 log_maintenance 表中记录的进度
+const (
+	maintenanceFTSRebuildNext  = "fts_rebuild_next"  // 已补建索引的最大日志 ID
+	maintenanceFTSRebuildUntil = "fts_rebuild_until" // 需要补建索引的最大日志 ID，之后的日志由 SaveLog 索引
+)
+
+// errMaintenanceStopped 存储关闭时中断后台维护
+var errMaintenanceStopped = errors.New("log storage maintenance stopped")
+
+// GormLogMaintenance 后台维护任务的进度，重启后从中断处继续
+type GormLogMaintenance struct {
+	Name  string `gorm:"column:name;primaryKey;size:64"`
+	Value uint   `gorm:"column:value"`
+}
+
+func (GormLogMaintenance) TableName() string {
+	return "log_maintenance"
+}
+
+// setMaintenanceValue 写入维护进度
+func setMaintenanceValue(tx *gorm.DB, name string, value uint) error {
+	return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&GormLogMaintenance{Name: name, Value: value}).Error
+}
+
+// maintenanceValue 读取维护进度，不存在时返回 false
+func (g *GORMStorage) maintenanceValue(name string) (uint, bool) {
+	var record GormLogMaintenance
+	if err := g.db.Where("name = ?", name).Limit(1).Find(&record).Error; err != nil || record.Name == "" {
+		return 0, false
+	}
+	return record.Value, true
+}
+
+// scheduleFullTextRebuild 记录需要为已有日志补建全文索引，由后台维护执行
+func (g *GORMStorage) scheduleFullTextRebuild() error {
+	var maxID uint
+	if err := g.db.Model(&GormRequestLog{}).Select("COALESCE(MAX(id), 0)").Scan(&maxID).Error; err != nil {
+		return err
+	}
+	if maxID == 0 {
+		return nil
+	}
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if err := setMaintenanceValue(tx, maintenanceFTSRebuildNext, 0); err != nil {
+			return err
+		}
+		return setMaintenanceValue(tx, maintenanceFTSRebuildUntil, maxID)
+	})
+}
+
+// fullTextRebuildPending 是否还有已有日志未加入全文索引
+func (g *GORMStorage) fullTextRebuildPending() bool {
+	_, pending := g.maintenanceValue(maintenanceFTSRebuildUntil)
+	return pending
+}
+
+// startMaintenance 在后台执行升级后的一次性维护：迁移内联正文、为已有日志补建全文索引
+// 维护期间日志照常读写，检索退回 LIKE 扫描；中断后下次启动继续
+func (g *GORMStorage) startMaintenance() {
+	go func() {
+		defer close(g.maintenanceDone)
+		if err := g.runMaintenance(g.stopCleanup); err != nil && !errors.Is(err, errMaintenanceStopped) {
+			fmt.Printf("Log storage maintenance error: %v\n", err)
+		}
+	}()
+}
+
+// runMaintenance 执行一次性维护，已完成的步骤不会重复执行
+func (g *GORMStorage) runMaintenance(stop <-chan struct{}) error {
+	g.maintenanceMu.Lock()
+	defer g.maintenanceMu.Unlock()
+
+	migrated, err := g.migrateInlineBodies(stop)
+	if migrated > 0 {
+		fmt.Printf("Log storage maintenance: migrated bodies of %d log entries to blob storage\n", migrated)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !g.ftsEnabled {
+		return nil
+	}
+	until, pending := g.maintenanceValue(maintenanceFTSRebuildUntil)
+	if pending {
+		next, _ := g.maintenanceValue(maintenanceFTSRebuildNext)
+		fmt.Printf("Log storage maintenance: building full-text index for log entries %d-%d\n", next+1, until)
+		if err := g.rebuildFullTextIndex(stop, next, until); err != nil {
+			if errors.Is(err, errMaintenanceStopped) {
+				return err
+			}
+			return fmt.Errorf("failed to build full-text index: %v", err)
+		}
+		err := g.db.Where("name IN ?", []string{maintenanceFTSRebuildNext, maintenanceFTSRebuildUntil}).
+			Delete(&GormLogMaintenance{}).Error
+		if err != nil {
+			return err
+		}
+		fmt.Println("Log storage maintenance: full-text index is ready")
+	}
+	g.ftsReady.Store(true)
+	return nil
+}
+
+// Compact 同步完成一次性维护，并将已有数据库切换为增量 VACUUM 模式
+// 切换需要执行一次完整 VACUUM，会重写整个数据库并阻塞写入，应在服务停止时通过 compact-logs 命令执行
+func (g *GORMStorage) Compact() error {
+	if err := g.runMaintenance(nil); err != nil {
+		return err
+	}
+	if err := g.ensureIncrementalVacuum(); err != nil {
+		return fmt.Errorf("failed to enable incremental vacuum: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	appconfig "claude-code-codex-companion/internal/config"
+)
+
+// redactedValue 脱敏后的占位文本，与调试导出中端点配置的脱敏保持一致
+const redactedValue = "[REDACTED]"
+
+// defaultRedactedHeaders 内置脱敏的请求/响应头
+var defaultRedactedHeaders = []string{
+	"authorization",
+	"proxy-authorization",
+	"x-api-key",
+	"api-key",
+	"x-goog-api-key",
+	"cookie",
+	"set-cookie",
+}
+
+// defaultRedactionPatterns 内置的正文与 URL 脱敏规则，含捕获组时只替换第一个捕获组
+var defaultRedactionPatterns = []string{
+	`\bsk-[A-Za-z0-9_-]{20,}`,                  // Anthropic / OpenAI API key
+	`\bAIza[0-9A-Za-z_-]{35}`,                  // Google API key
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,            // AWS access key ID
+	`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{16,})`, // Bearer token
+	`(?i)"(?:api_key|apikey|access_token|refresh_token|client_secret|password)"\s*:\s*"([^"]+)"`,
+	`(?i)[?&](?:key|api_key|access_token)=([^&\s"]+)`, // URL 中的密钥参数
+}
+
+// Redactor 在日志入库、推送和导出前遮盖认证头与正文中的密钥
+type Redactor struct {
+	headers  map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor 根据配置创建脱敏器
+func NewRedactor(config appconfig.LogRedactionConfig) (*Redactor, error) {
+	redactor := &Redactor{headers: make(map[string]bool)}
+
+	var headers, patterns []string
+	if !config.DisableDefaults {
+		headers = append(headers, defaultRedactedHeaders...)
+		patterns = append(patterns, defaultRedactionPatterns...)
+	}
+	headers = append(headers, config.Headers...)
+	patterns = append(patterns, config.Patterns...)
+
+	for _, header := range headers {
+		redactor.headers[strings.ToLower(strings.TrimSpace(header))] = true
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern '%s': %v", pattern, err)
+		}
+		redactor.patterns = append(redactor.patterns, re)
+	}
+	return redactor, nil
+}
+
+// RedactHeaders 返回脱敏后的请求/响应头副本
+func (r *Redactor) RedactHeaders(headers map[string]string) map[string]string {
+	if r == nil || headers == nil {
+		return headers
+	}
+	result := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if r.headers[strings.ToLower(key)] {
+			result[key] = redactedValue
+		} else {
+			result[key] = r.RedactText(value)
+		}
+	}
+	return result
+}
+
+// RedactText 按正则规则遮盖文本中的密钥
+func (r *Redactor) RedactText(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	for _, re := range r.patterns {
+		text = redactPattern(re, text)
+	}
+	return text
+}
+
+// redactPattern 替换所有匹配；正则含捕获组时只替换第一个捕获组，保留其余上下文
+func redactPattern(re *regexp.Regexp, text string) string {
+	matches := re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if len(match) >= 4 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(redactedValue)
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// RedactLog 就地脱敏日志中的请求/响应头、正文、URL 和错误信息
+func (r *Redactor) RedactLog(log *RequestLog) {
+	if r == nil || log == nil {
+		return
+	}
+
+	log.RequestHeaders = r.RedactHeaders(log.RequestHeaders)
+	log.ResponseHeaders = r.RedactHeaders(log.ResponseHeaders)
+	log.OriginalRequestHeaders = r.RedactHeaders(log.OriginalRequestHeaders)
+	log.OriginalResponseHeaders = r.RedactHeaders(log.OriginalResponseHeaders)
+	log.FinalRequestHeaders = r.RedactHeaders(log.FinalRequestHeaders)
+	log.FinalResponseHeaders = r.RedactHeaders(log.FinalResponseHeaders)
+
+	// 各正文字段经常内容相同（如未转换的请求），同一条日志内复用脱敏结果
+	redacted := make(map[string]string)
+	redact := func(text string) string {
+		if text == "" {
+			return text
+		}
+		if result, ok := redacted[text]; ok {
+			return result
+		}
+		result := r.RedactText(text)
+		redacted[text] = result
+		return result
+	}
+
+	log.RequestBody = redact(log.RequestBody)
+	log.ResponseBody = redact(log.ResponseBody)
+	log.OriginalRequestBody = redact(log.OriginalRequestBody)
+	log.OriginalResponseBody = redact(log.OriginalResponseBody)
+	log.FinalRequestBody = redact(log.FinalRequestBody)
+	log.FinalResponseBody = redact(log.FinalResponseBody)
+	log.OriginalRequestURL = redact(log.OriginalRequestURL)
+	log.FinalRequestURL = redact(log.FinalRequestURL)
+	log.Error = redact(log.Error)
+}
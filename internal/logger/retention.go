@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	appconfig "claude-code-codex-companion/internal/config"
+)
+
+// 与 GetLogs 的失败判定保持一致
+const (
+	failedLogCondition  = "(status_code >= 400 OR error != '')"
+	successLogCondition = "(status_code < 400 AND error = '')"
+)
+
+// 未配置时的默认值：与以往固定清理 30 天前日志的行为一致
+const (
+	defaultRetentionMaxAge   = 30 * 24 * time.Hour
+	defaultRetentionInterval = time.Hour
+)
+
+// RetentionRule 单类日志的保留上限，零值表示不限制
+type RetentionRule struct {
+	MaxAge   time.Duration
+	MaxRows  int64
+	MaxBytes int64 // 按请求体与响应体原始大小之和计算
+}
+
+// RetentionPolicy 成功与失败请求分别适用的保留上限
+type RetentionPolicy struct {
+	Success RetentionRule
+	Failed  RetentionRule
+}
+
+// DefaultRetentionPolicy 成功与失败请求均保留 30 天
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		Success: RetentionRule{MaxAge: defaultRetentionMaxAge},
+		Failed:  RetentionRule{MaxAge: defaultRetentionMaxAge},
+	}
+}
+
+// NewRetentionPolicy 由配置生成保留策略和检查间隔，未填写的字段使用默认值
+func NewRetentionPolicy(config appconfig.LogRetentionConfig) (RetentionPolicy, time.Duration, error) {
+	interval := defaultRetentionInterval
+	if config.Interval != "" {
+		parsed, err := time.ParseDuration(config.Interval)
+		if err != nil || parsed <= 0 {
+			return RetentionPolicy{}, 0, fmt.Errorf("invalid retention interval '%s'", config.Interval)
+		}
+		interval = parsed
+	}
+
+	success, err := newRetentionRule(config.Success)
+	if err != nil {
+		return RetentionPolicy{}, 0, fmt.Errorf("success: %v", err)
+	}
+	failed, err := newRetentionRule(config.Failed)
+	if err != nil {
+		return RetentionPolicy{}, 0, fmt.Errorf("failed: %v", err)
+	}
+	return RetentionPolicy{Success: success, Failed: failed}, interval, nil
+}
+
+func newRetentionRule(config appconfig.LogRetentionRule) (RetentionRule, error) {
+	rule := RetentionRule{
+		MaxAge:   defaultRetentionMaxAge,
+		MaxRows:  int64(config.MaxRows),
+		MaxBytes: int64(config.MaxSizeMB) << 20,
+	}
+	if config.MaxAge != "" {
+		maxAge, err := time.ParseDuration(config.MaxAge)
+		if err != nil || maxAge < 0 {
+			return RetentionRule{}, fmt.Errorf("invalid max_age '%s'", config.MaxAge)
+		}
+		rule.MaxAge = maxAge
+	}
+	return rule, nil
+}
+
+// ApplyRetention 按保留策略删除超出上限的日志并回收空间，返回删除的日志条数
+func (g *GORMStorage) ApplyRetention(now time.Time) (int64, error) {
+	g.retentionMu.Lock()
+	retention := g.retention
+	g.retentionMu.Unlock()
+
+	classes := []struct {
+		condition string
+		rule      RetentionRule
+	}{
+		{successLogCondition, retention.Success},
+		{failedLogCondition, retention.Failed},
+	}
+
+	var deleted int64
+	var applyErr error
+	for _, class := range classes {
+		count, err := g.applyRetentionRule(class.condition, class.rule, now)
+		deleted += count
+		if err != nil {
+			applyErr = err
+			break
+		}
+	}
+
+	if deleted > 0 {
+		g.reclaimSpace()
+	}
+	return deleted, applyErr
+}
+
+// applyRetentionRule 依次按时间、条数、大小删除一类日志中最旧的记录
+func (g *GORMStorage) applyRetentionRule(condition string, rule RetentionRule, now time.Time) (int64, error) {
+	var deleted int64
+
+	if rule.MaxAge > 0 {
+		result := g.db.Where(condition).Where("timestamp < ?", now.Add(-rule.MaxAge)).Delete(&GormRequestLog{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("failed to delete logs by age: %v", result.Error)
+		}
+		deleted += result.RowsAffected
+	}
+
+	if rule.MaxRows > 0 {
+		result := g.db.Where("id IN (SELECT id FROM request_logs WHERE "+condition+
+			" ORDER BY timestamp DESC, id DESC LIMIT -1 OFFSET ?)", rule.MaxRows).
+			Delete(&GormRequestLog{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("failed to delete logs by count: %v", result.Error)
+		}
+		deleted += result.RowsAffected
+	}
+
+	if rule.MaxBytes > 0 {
+		// 从最新的日志开始累加正文大小，超出上限之后的记录全部删除
+		result := g.db.Where("id IN (SELECT id FROM (SELECT id, SUM(request_body_size + response_body_size) "+
+			"OVER (ORDER BY timestamp DESC, id DESC) AS running_size FROM request_logs WHERE "+condition+
+			") WHERE running_size > ?)", rule.MaxBytes).
+			Delete(&GormRequestLog{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("failed to delete logs by size: %v", result.Error)
+		}
+		deleted += result.RowsAffected
+	}
+
+	return deleted, nil
+}
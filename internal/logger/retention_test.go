@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	appconfig "claude-code-codex-companion/internal/config"
+)
+
+func TestNewRetentionPolicy(t *testing.T) {
+	policy, interval, err := NewRetentionPolicy(appconfig.LogRetentionConfig{})
+	if err != nil {
+		t.Fatalf("NewRetentionPolicy failed: %v", err)
+	}
+	if policy != DefaultRetentionPolicy() || interval != time.Hour {
+		t.Errorf("Expected defaults, got %+v every %v", policy, interval)
+	}
+
+	policy, interval, err = NewRetentionPolicy(appconfig.LogRetentionConfig{
+		Interval: "10m",
+		Success:  appconfig.LogRetentionRule{MaxAge: "24h", MaxRows: 1000},
+		Failed:   appconfig.LogRetentionRule{MaxAge: "0", MaxSizeMB: 2},
+	})
+	if err != nil {
+		t.Fatalf("NewRetentionPolicy failed: %v", err)
+	}
+	want := RetentionPolicy{
+		Success: RetentionRule{MaxAge: 24 * time.Hour, MaxRows: 1000},
+		Failed:  RetentionRule{MaxBytes: 2 << 20},
+	}
+	if policy != want || interval != 10*time.Minute {
+		t.Errorf("Expected %+v every 10m, got %+v every %v", want, policy, interval)
+	}
+
+	if _, _, err := NewRetentionPolicy(appconfig.LogRetentionConfig{Failed: appconfig.LogRetentionRule{MaxAge: "30d"}}); err == nil {
+		t.Errorf("Expected invalid max_age to be rejected")
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	storage, _ := setupQueryStorage(t)
+	now := time.Now()
+
+	// 成功请求：6 条，每条正文 100 字节；失败请求：3 条，其中 1 条超过 10 天
+	for i := 0; i < 6; i++ {
+		storage.SaveLog(&RequestLog{Timestamp: now.Add(-time.Duration(i) * time.Minute), RequestID: fmt.Sprintf("ok-%d", i),
+			Endpoint: "primary", StatusCode: 200, RequestBodySize: 60, ResponseBodySize: 40})
+	}
+	storage.SaveLog(&RequestLog{Timestamp: now, RequestID: "fail-0", Endpoint: "primary", StatusCode: 500})
+	storage.SaveLog(&RequestLog{Timestamp: now.AddDate(0, 0, -3), RequestID: "fail-1", Endpoint: "primary", Error: "connection refused"})
+	storage.SaveLog(&RequestLog{Timestamp: now.AddDate(0, 0, -11), RequestID: "fail-2", Endpoint: "primary", StatusCode: 429})
+
+	storage.SetRetention(RetentionPolicy{
+		Success: RetentionRule{MaxRows: 4, MaxBytes: 300},
+		Failed:  RetentionRule{MaxAge: 10 * 24 * time.Hour},
+	}, time.Hour)
+	deleted, err := storage.ApplyRetention(now)
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if deleted != 4 {
+		t.Errorf("Expected 4 deleted logs, got %d", deleted)
+	}
+
+	page, err := storage.SearchLogs(LogQuery{})
+	if err != nil {
+		t.Fatalf("SearchLogs failed: %v", err)
+	}
+	// 成功请求按条数保留 4 条，再按大小保留最新的 3 条；失败请求只删除超过 10 天的记录
+	assertIDs(t, "remaining logs", page, "fail-0", "ok-0", "ok-1", "ok-2", "fail-1")
+}
+
+func TestLogger_UpdatePolicies(t *testing.T) {
+	log, err := NewLogger(LogConfig{Level: "error", LogRequestTypes: "failed", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	defer log.Close()
+
+	// 无效配置整体拒绝，原有规则保持不变
+	if err := log.UpdatePolicies(appconfig.LogRetentionConfig{Interval: "soon"}, nil, appconfig.LogRedactionConfig{}); err == nil {
+		t.Fatalf("Expected invalid retention interval to be rejected")
+	}
+	if err := log.UpdatePolicies(appconfig.LogRetentionConfig{}, nil, appconfig.LogRedactionConfig{Patterns: []string{"("}}); err == nil {
+		t.Fatalf("Expected invalid redaction pattern to be rejected")
+	}
+	log.LogRequest(&RequestLog{Timestamp: time.Now(), RequestID: "before", Endpoint: "https://api.example.com", StatusCode: 200,
+		RequestBody: `{"ssn":"123-45-6789"}`})
+
+	err = log.UpdatePolicies(
+		appconfig.LogRetentionConfig{Interval: "10m", Success: appconfig.LogRetentionRule{MaxRows: 1}},
+		[]appconfig.LogSamplingRule{{Endpoint: "https://batch.example.com", Rate: 0}},
+		appconfig.LogRedactionConfig{Patterns: []string{`"ssn":"([^"]+)"`}},
+	)
+	if err != nil {
+		t.Fatalf("UpdatePolicies failed: %v", err)
+	}
+
+	log.LogRequest(&RequestLog{Timestamp: time.Now(), RequestID: "sampled-out", Endpoint: "https://batch.example.com", StatusCode: 200})
+	log.LogRequest(&RequestLog{Timestamp: time.Now(), RequestID: "after", Endpoint: "https://api.example.com", StatusCode: 200,
+		RequestBody: `{"ssn":"123-45-6789"}`})
+
+	logs, _, err := log.GetLogs(10, 0, false)
+	if err != nil {
+		t.Fatalf("GetLogs failed: %v", err)
+	}
+	bodies := map[string]string{}
+	for _, l := range logs {
+		bodies[l.RequestID] = l.RequestBody
+	}
+	if _, ok := bodies["sampled-out"]; ok || len(bodies) != 2 {
+		t.Errorf("Expected the new sampling rule to drop the batch request, got %v", bodies)
+	}
+	if !strings.Contains(bodies["before"], "123-45-6789") || strings.Contains(bodies["after"], "123-45-6789") {
+		t.Errorf("Expected the new redaction rule to apply only to later logs, got %v", bodies)
+	}
+
+	storage := log.storage.(*GORMStorage)
+	if storage.retentionInterval != 10*time.Minute || storage.retention.Success.MaxRows != 1 {
+		t.Errorf("Expected the new retention policy on the storage, got %+v every %v", storage.retention, storage.retentionInterval)
+	}
+}
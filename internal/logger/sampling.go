@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"hash/fnv"
+
+	appconfig "claude-code-codex-companion/internal/config"
+)
+
+// Sampler 按规则决定成功请求是否入库；失败请求始终记录
+type Sampler struct {
+	rules []appconfig.LogSamplingRule
+}
+
+// NewSampler 创建采样器，rules 按顺序匹配，未匹配任何规则的请求全部记录
+func NewSampler(rules []appconfig.LogSamplingRule) *Sampler {
+	return &Sampler{rules: rules}
+}
+
+// Keep 判断日志是否入库
+// 以 request ID 的哈希决定是否采样，同一请求的所有尝试结果一致
+func (s *Sampler) Keep(log *RequestLog) bool {
+	if s == nil || len(s.rules) == 0 || isFailedLog(log) {
+		return true
+	}
+
+	for _, rule := range s.rules {
+		if samplingRuleMatches(rule, log) {
+			return sampleFraction(log.RequestID) < rule.Rate
+		}
+	}
+	return true
+}
+
+// isFailedLog 与存储层的失败判定保持一致
+func isFailedLog(log *RequestLog) bool {
+	return log.StatusCode >= 400 || log.Error != ""
+}
+
+// samplingRuleMatches 规则中填写的条件需全部满足
+// 日志记录的是端点 URL，配置中的端点名称由调用方在创建 Logger 前解析为 URL
+func samplingRuleMatches(rule appconfig.LogSamplingRule, log *RequestLog) bool {
+	if rule.Endpoint != "" && rule.Endpoint != log.Endpoint {
+		return false
+	}
+	if rule.ClientType != "" && rule.ClientType != log.ClientType {
+		return false
+	}
+	if rule.Tag != "" {
+		found := false
+		for _, tag := range log.Tags {
+			if tag == rule.Tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sampleFraction 将 request ID 映射到 [0, 1)
+func sampleFraction(requestID string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(requestID))
+	return float64(h.Sum64()>>11) / float64(uint64(1)<<53)
+}
@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SessionSummary 会话概要，由同一 session_id 的请求日志聚合而来
+type SessionSummary struct {
+	SessionID      string    `json:"session_id"`
+	ClientType     string    `json:"client_type,omitempty"`
+	FirstSeen      time.Time `json:"first_seen"`
+	LastSeen       time.Time `json:"last_seen"`
+	Requests       int       `json:"requests"`        // 不同 request_id 的数量
+	Attempts       int       `json:"attempts"`        // 日志条数（含重试）
+	FailedAttempts int       `json:"failed_attempts"` // 状态码 ≥ 400 或有错误信息的日志条数
+	Models         []string  `json:"models,omitempty"`
+}
+
+// sessionSummaryRow ListSessions 的聚合查询结果
+// 聚合后的时间戳没有列类型信息，驱动按字符串返回，需自行解析
+type sessionSummaryRow struct {
+	SessionID      string
+	ClientType     string
+	FirstSeen      string
+	LastSeen       string
+	Requests       int
+	Attempts       int
+	FailedAttempts int
+	Models         string
+}
+
+// sqliteTimeLayouts modernc.org/sqlite 写入时间戳时可能使用的格式
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// parseSQLiteTime 解析聚合查询返回的时间戳字符串
+func parseSQLiteTime(value string) time.Time {
+	// time.Time.String() 可能带有单调时钟后缀
+	if idx := strings.Index(value, " m="); idx >= 0 {
+		value = value[:idx]
+	}
+	for _, layout := range sqliteTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// ListSessions 按最近活动时间倒序列出会话
+func (g *GORMStorage) ListSessions(limit, offset int) ([]*SessionSummary, int, error) {
+	var total int64
+	if err := g.db.Model(&GormRequestLog{}).
+		Where("session_id != ?", "").
+		Distinct("session_id").
+		Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count sessions: %v", err)
+	}
+
+	var rows []sessionSummaryRow
+	err := g.db.Model(&GormRequestLog{}).
+		Select(`session_id,
+			MAX(client_type) AS client_type,
+			MIN(timestamp) AS first_seen,
+			MAX(timestamp) AS last_seen,
+			COUNT(DISTINCT request_id) AS requests,
+			COUNT(*) AS attempts,
+			SUM(CASE WHEN status_code >= 400 OR error != '' THEN 1 ELSE 0 END) AS failed_attempts,
+			GROUP_CONCAT(DISTINCT NULLIF(model, '')) AS models`).
+		Where("session_id != ?", "").
+		Group("session_id").
+		Order("last_seen DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list sessions: %v", err)
+	}
+
+	sessions := make([]*SessionSummary, len(rows))
+	for i, row := range rows {
+		sessions[i] = &SessionSummary{
+			SessionID:      row.SessionID,
+			ClientType:     row.ClientType,
+			FirstSeen:      parseSQLiteTime(row.FirstSeen),
+			LastSeen:       parseSQLiteTime(row.LastSeen),
+			Requests:       row.Requests,
+			Attempts:       row.Attempts,
+			FailedAttempts: row.FailedAttempts,
+		}
+		if row.Models != "" {
+			sessions[i].Models = strings.Split(row.Models, ",")
+		}
+	}
+
+	return sessions, int(total), nil
+}
+
+// sessionBodyBatchSize 还原会话正文时每批查询的日志条数，避免超出 SQLite 参数上限
+const sessionBodyBatchSize = 500
+
+// GetLogsBySessionID 按时间顺序获取会话的全部日志（含重试）
+// 对话记录只使用每个请求最后一次尝试的正文，其余尝试只加载元数据，不读取正文
+func (g *GORMStorage) GetLogsBySessionID(sessionID string) ([]*RequestLog, error) {
+	var gormLogs []GormRequestLog
+
+	omitted := []string{"body_refs"}
+	for _, column := range (&GormRequestLog{}).bodyColumns() {
+		omitted = append(omitted, column.name)
+	}
+	err := g.db.Omit(omitted...).
+		Where("session_id = ?", sessionID).
+		Order("timestamp ASC, id ASC").
+		Find(&gormLogs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs by session ID: %v", err)
+	}
+
+	// 每个请求的最后一次尝试：attempt_number 最大，相同时取时间较晚的一条
+	finalIndex := make(map[string]int)
+	for i := range gormLogs {
+		j, ok := finalIndex[gormLogs[i].RequestID]
+		if !ok || gormLogs[i].AttemptNumber >= gormLogs[j].AttemptNumber {
+			finalIndex[gormLogs[i].RequestID] = i
+		}
+	}
+	indexByID := make(map[uint]int, len(finalIndex))
+	ids := make([]uint, 0, len(finalIndex))
+	for _, i := range finalIndex {
+		indexByID[gormLogs[i].ID] = i
+		ids = append(ids, gormLogs[i].ID)
+	}
+
+	for start := 0; start < len(ids); start += sessionBodyBatchSize {
+		end := start + sessionBodyBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		var bodies []GormRequestLog
+		err := g.db.Select(append([]string{"id"}, omitted...)).
+			Where("id IN ?", ids[start:end]).
+			Find(&bodies).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to load session log bodies: %v", err)
+		}
+		g.loadBodies(bodies)
+
+		for k := range bodies {
+			target := &gormLogs[indexByID[bodies[k].ID]]
+			source := bodies[k].bodyColumns()
+			for c, column := range target.bodyColumns() {
+				*column.value = *source[c].value
+			}
+		}
+	}
+
+	return g.convertLogs(gormLogs), nil
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestListSessionsAndGetLogsBySessionID(t *testing.T) {
+	storage, _ := setupQueryStorage(t)
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	saveQueryLogs(storage, base)
+	// sess-a 的重试记录，以及最近活动的 sess-c
+	storage.SaveLog(&RequestLog{Timestamp: base.Add(5 * time.Minute), RequestID: "req-2", AttemptNumber: 2, Endpoint: "backup",
+		Model: "claude-sonnet", StatusCode: 200, ClientType: "claude-code", SessionID: "sess-a"})
+	storage.SaveLog(&RequestLog{Timestamp: base.Add(6 * time.Minute), RequestID: "req-5", Endpoint: "primary",
+		Model: "claude-haiku", StatusCode: 200, ClientType: "claude-code", SessionID: "sess-c"})
+
+	sessions, total, err := storage.ListSessions(10, 0)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected 3 sessions, got %d", total)
+	}
+	var ids []string
+	for _, session := range sessions {
+		ids = append(ids, session.SessionID)
+	}
+	if fmt.Sprint(ids) != "[sess-c sess-a sess-b]" {
+		t.Fatalf("Expected sessions ordered by last activity, got %v", ids)
+	}
+
+	sessA := sessions[1]
+	if sessA.Requests != 2 || sessA.Attempts != 3 || sessA.FailedAttempts != 1 {
+		t.Errorf("Unexpected counters for sess-a: %+v", sessA)
+	}
+	if !sessA.FirstSeen.Equal(base) || !sessA.LastSeen.Equal(base.Add(5*time.Minute)) {
+		t.Errorf("Unexpected time range for sess-a: %v - %v", sessA.FirstSeen, sessA.LastSeen)
+	}
+	if fmt.Sprint(sessA.Models) != "[claude-sonnet]" || sessA.ClientType != "claude-code" {
+		t.Errorf("Unexpected models or client type for sess-a: %+v", sessA)
+	}
+
+	page, _, err := storage.ListSessions(1, 1)
+	if err != nil || len(page) != 1 || page[0].SessionID != "sess-a" {
+		t.Errorf("Expected second page to contain sess-a, got %v (%v)", page, err)
+	}
+
+	logs, err := storage.GetLogsBySessionID("sess-a")
+	if err != nil {
+		t.Fatalf("GetLogsBySessionID failed: %v", err)
+	}
+	var order []string
+	for _, log := range logs {
+		order = append(order, fmt.Sprintf("%s#%d", log.RequestID, log.AttemptNumber))
+	}
+	if fmt.Sprint(order) != "[req-1#1 req-2#1 req-2#2]" {
+		t.Errorf("Expected session logs in time order, got %v", order)
+	}
+}
+
+func TestGetLogsBySessionID_LoadsBodiesForFinalAttemptsOnly(t *testing.T) {
+	storage, _ := setupQueryStorage(t)
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	storage.SaveLog(&RequestLog{Timestamp: base, RequestID: "req-1", AttemptNumber: 1, StatusCode: 502, SessionID: "sess-a",
+		RequestBody: `{"attempt":1}`, ResponseBody: `{"error":"bad gateway"}`})
+	storage.SaveLog(&RequestLog{Timestamp: base.Add(time.Second), RequestID: "req-1", AttemptNumber: 2, StatusCode: 200, SessionID: "sess-a",
+		RequestBody: `{"attempt":2}`, OriginalRequestBody: `{"original":2}`, FinalResponseBody: `{"final":2}`})
+	storage.SaveLog(&RequestLog{Timestamp: base.Add(time.Minute), RequestID: "req-2", AttemptNumber: 1, StatusCode: 200, SessionID: "sess-a",
+		RequestBody: `{"turn":2}`, ResponseBody: `{"content":"ok"}`})
+
+	logs, err := storage.GetLogsBySessionID("sess-a")
+	if err != nil {
+		t.Fatalf("GetLogsBySessionID failed: %v", err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("Expected 3 logs, got %d", len(logs))
+	}
+
+	retried := logs[0]
+	if retried.RequestBody != "" || retried.ResponseBody != "" {
+		t.Errorf("Expected superseded attempt to skip bodies, got %q / %q", retried.RequestBody, retried.ResponseBody)
+	}
+	if retried.StatusCode != 502 || retried.AttemptNumber != 1 {
+		t.Errorf("Expected superseded attempt metadata to be kept, got %+v", retried)
+	}
+
+	final := logs[1]
+	if final.RequestBody != `{"attempt":2}` || final.OriginalRequestBody != `{"original":2}` || final.FinalResponseBody != `{"final":2}` {
+		t.Errorf("Expected final attempt bodies to be restored, got %+v", final)
+	}
+	if logs[2].RequestBody != `{"turn":2}` || logs[2].ResponseBody != `{"content":"ok"}` {
+		t.Errorf("Expected single attempt bodies to be restored, got %+v", logs[2])
+	}
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Default 代理使用的默认注册表，由 /metrics 输出
+var Default = NewRegistry()
+
+var (
+	// latencyBuckets 上游请求耗时桶（秒），覆盖 LLM 长请求
+	latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300}
+	// ttftBuckets 首字节耗时桶（秒）
+	ttftBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 20, 30, 60}
+	// taggerBuckets tagger 执行耗时桶（秒）
+	taggerBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+)
+
+var (
+	// Requests 每次上游尝试计一次
+	Requests = Default.NewCounterVec("cccc_requests_total",
+		"Upstream request attempts by endpoint, model, client type, status and attempt number.",
+		"endpoint", "model", "client_type", "status", "attempt")
+
+	// RequestDuration 上游尝试耗时
+	RequestDuration = Default.NewHistogramVec("cccc_request_duration_seconds",
+		"Upstream request attempt latency in seconds.", latencyBuckets,
+		"endpoint", "model", "client_type")
+
+	// TimeToFirstToken 流式响应从开始上游尝试到收到首字节的耗时
+	TimeToFirstToken = Default.NewHistogramVec("cccc_time_to_first_token_seconds",
+		"Time from the start of an upstream attempt to the first streamed response byte in seconds.", ttftBuckets,
+		"endpoint", "model")
+
+	// RequestsInFlight 正在处理的客户端请求数
+	RequestsInFlight = Default.NewGaugeVec("cccc_requests_in_flight",
+		"Client requests currently being proxied.")
+
+	// EndpointRequestsInFlight 各端点正在进行的上游请求数
+	EndpointRequestsInFlight = Default.NewGaugeVec("cccc_endpoint_requests_in_flight",
+		"Upstream requests currently in flight per endpoint.", "endpoint")
+
+	// BlacklistTransitions 端点被拉黑（blacklisted）与恢复（recovered）的次数
+	BlacklistTransitions = Default.NewCounterVec("cccc_endpoint_blacklist_transitions_total",
+		"Endpoint blacklist transitions (blacklisted or recovered).", "endpoint", "transition")
+
+	// ConversionFailures 格式转换失败次数，stage 为转换阶段，kind 为 ConversionError 类型
+	ConversionFailures = Default.NewCounterVec("cccc_conversion_failures_total",
+		"Format conversion failures by stage and kind.", "stage", "kind")
+
+	// TaggerDuration 各 tagger 的执行耗时（来自 TaggerResult.Duration）
+	TaggerDuration = Default.NewHistogramVec("cccc_tagger_duration_seconds",
+		"Tagger execution time in seconds.", taggerBuckets, "tagger", "result")
+)
+
+// EndpointState 端点状态快照，用于采集时输出端点状态相关指标
+type EndpointState struct {
+	Name                     string
+	Status                   string // active | inactive | cooling | checking | disabled
+	LearnedUnsupportedParams []string
+}
+
+// EndpointStatuses 端点状态指标输出的全部状态值
+var EndpointStatuses = []string{"active", "inactive", "cooling", "checking", "disabled"}
+
+var endpointStateSource atomic.Value // func() []EndpointState
+
+// SetEndpointStateSource 设置端点状态快照来源，每次采集时调用
+func SetEndpointStateSource(source func() []EndpointState) {
+	endpointStateSource.Store(source)
+}
+
+func endpointStates() []EndpointState {
+	source, _ := endpointStateSource.Load().(func() []EndpointState)
+	if source == nil {
+		return nil
+	}
+	return source()
+}
+
+func init() {
+	Default.NewGaugeFunc("cccc_endpoint_status",
+		"Current endpoint status; exactly one status per endpoint is 1.",
+		[]string{"endpoint", "status"},
+		func(emit func(value float64, labelValues ...string)) {
+			for _, state := range endpointStates() {
+				for _, status := range EndpointStatuses {
+					value := 0.0
+					if state.Status == status {
+						value = 1
+					}
+					emit(value, state.Name, status)
+				}
+			}
+		})
+
+	Default.NewGaugeFunc("cccc_endpoint_learned_unsupported_params",
+		"Parameters learned as unsupported per endpoint (1 per learned parameter).",
+		[]string{"endpoint", "param"},
+		func(emit func(value float64, labelValues ...string)) {
+			for _, state := range endpointStates() {
+				for _, param := range state.LearnedUnsupportedParams {
+					emit(1, state.Name, param)
+				}
+			}
+		})
+}
+
+// StatusLabel 将 HTTP 状态码转换为标签值，0（网络错误等无响应情况）记为 error
+func StatusLabel(statusCode int) string {
+	if statusCode <= 0 {
+		return "error"
+	}
+	return strconv.Itoa(statusCode)
+}
+
+// OtherModel 未在配置中出现的模型统一记为 other，避免客户端传入的模型名使标签无限增长
+const OtherModel = "other"
+
+// ValueOrUnknown 空标签值记为 unknown
+func ValueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// ParseAllowedIPs 解析 IP 或 CIDR 列表
+func ParseAllowedIPs(entries []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", entry)
+			}
+			nets = append(nets, ipNet)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", entry)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+			bits = 8 * net.IPv4len
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets, nil
+}
+
+// Protect 为 handler 增加可选的访问控制：
+// bearerToken 非空时要求 Authorization: Bearer <token>；allowedIPs 非空时只允许来自这些地址的连接
+// 来源地址取自连接本身（RemoteAddr），不信任 X-Forwarded-For
+func Protect(handler http.Handler, bearerToken string, allowedIPs []string) (http.Handler, error) {
+	nets, err := ParseAllowedIPs(allowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	if bearerToken == "" && len(nets) == 0 {
+		return handler, nil
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(nets) > 0 && !ipAllowed(r.RemoteAddr, nets) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if bearerToken != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(bearerToken)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}), nil
+}
+
+func ipAllowed(remoteAddr string, nets []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
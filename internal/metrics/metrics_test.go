@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scrape 通过 HTTP 抓取指标文本
+func scrape(t *testing.T, url string, header http.Header) (int, string) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func assertContains(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected scrape output to contain %q, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestRegistry_TextExposition(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("test_requests_total", "Requests.", "endpoint", "status")
+	inFlight := registry.NewGaugeVec("test_in_flight", "In flight.")
+	latency := registry.NewHistogramVec("test_latency_seconds", "Latency.", []float64{1, 0.1, 0.5}, "endpoint")
+	registry.NewCounterVec("test_unused_total", "Never observed.", "endpoint")
+	registry.NewGaugeFunc("test_status", "Status.", []string{"endpoint"}, func(emit func(float64, ...string)) {
+		emit(1, "b")
+		emit(0, "a")
+		emit(2) // 标签数量不匹配的样本被忽略
+	})
+
+	requests.WithLabelValues("primary", "200").Inc()
+	requests.WithLabelValues("primary", "200").Add(2)
+	requests.WithLabelValues("backup", "error").Inc()
+	requests.WithLabelValues("backup", "error").Add(-5) // 计数器不能减少
+	requests.WithLabelValues(`we"ird\`, "200").Inc()
+	inFlight.WithLabelValues().Inc()
+	inFlight.WithLabelValues().Inc()
+	inFlight.WithLabelValues().Dec()
+	for _, v := range []float64{0.05, 0.3, 0.5, 2} {
+		latency.WithLabelValues("primary").Observe(v)
+	}
+
+	server := httptest.NewServer(registry.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != ContentType {
+		t.Errorf("Expected content type %q, got %q", ContentType, contentType)
+	}
+
+	_, body := scrape(t, server.URL, nil)
+	assertContains(t, body,
+		"# HELP test_requests_total Requests.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{endpoint="backup",status="error"} 1`,
+		`test_requests_total{endpoint="primary",status="200"} 3`,
+		`test_requests_total{endpoint="we\"ird\\",status="200"} 1`,
+		"# TYPE test_in_flight gauge",
+		"test_in_flight 1",
+		"# TYPE test_latency_seconds histogram",
+		`test_latency_seconds_bucket{endpoint="primary",le="0.1"} 1`,
+		`test_latency_seconds_bucket{endpoint="primary",le="0.5"} 3`,
+		`test_latency_seconds_bucket{endpoint="primary",le="1"} 3`,
+		`test_latency_seconds_bucket{endpoint="primary",le="+Inf"} 4`,
+		`test_latency_seconds_sum{endpoint="primary"} 2.85`,
+		`test_latency_seconds_count{endpoint="primary"} 4`,
+		`test_status{endpoint="a"} 0`,
+		`test_status{endpoint="b"} 1`,
+	)
+
+	if strings.Contains(body, "test_unused_total") {
+		t.Errorf("Expected metric families without series to be omitted")
+	}
+	if strings.Index(body, `endpoint="backup"`) > strings.Index(body, `endpoint="primary"`) {
+		t.Errorf("Expected series to be sorted by label values")
+	}
+	if strings.Index(body, `test_status{endpoint="a"}`) > strings.Index(body, `test_status{endpoint="b"}`) {
+		t.Errorf("Expected gauge func samples to be sorted by label values")
+	}
+}
+
+func TestRegistry_DuplicateAndLabelMismatchPanic(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("dup_total", "Dup.", "a")
+
+	assertPanics(t, "duplicate registration", func() { registry.NewGaugeVec("dup_total", "Dup.") })
+	assertPanics(t, "label count mismatch", func() { counter.WithLabelValues("x", "y") })
+}
+
+func assertPanics(t *testing.T, name string, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic for %s", name)
+		}
+	}()
+	fn()
+}
+
+func TestDefault_EndpointStateMetrics(t *testing.T) {
+	SetEndpointStateSource(func() []EndpointState {
+		return []EndpointState{
+			{Name: "primary", Status: "active"},
+			{Name: "rate-limited", Status: "cooling", LearnedUnsupportedParams: []string{"top_k", "metadata"}},
+		}
+	})
+	defer SetEndpointStateSource(nil)
+
+	Requests.WithLabelValues("primary", "claude-3-5-sonnet", "claude-code", StatusLabel(200), "1").Inc()
+	Requests.WithLabelValues("primary", ValueOrUnknown(""), "codex", StatusLabel(0), "2").Inc()
+	BlacklistTransitions.WithLabelValues("primary", "blacklisted").Inc()
+	ConversionFailures.WithLabelValues("response", "sse_parse_error").Inc()
+
+	server := httptest.NewServer(Default.Handler())
+	defer server.Close()
+
+	_, body := scrape(t, server.URL, nil)
+	assertContains(t, body,
+		`cccc_requests_total{endpoint="primary",model="claude-3-5-sonnet",client_type="claude-code",status="200",attempt="1"} 1`,
+		`cccc_requests_total{endpoint="primary",model="unknown",client_type="codex",status="error",attempt="2"} 1`,
+		`cccc_endpoint_blacklist_transitions_total{endpoint="primary",transition="blacklisted"} 1`,
+		`cccc_conversion_failures_total{stage="response",kind="sse_parse_error"} 1`,
+		`cccc_endpoint_status{endpoint="primary",status="active"} 1`,
+		`cccc_endpoint_status{endpoint="primary",status="cooling"} 0`,
+		`cccc_endpoint_status{endpoint="rate-limited",status="active"} 0`,
+		`cccc_endpoint_status{endpoint="rate-limited",status="cooling"} 1`,
+		`cccc_endpoint_learned_unsupported_params{endpoint="rate-limited",param="metadata"} 1`,
+		`cccc_endpoint_learned_unsupported_params{endpoint="rate-limited",param="top_k"} 1`,
+	)
+}
+
+func TestProtect(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGaugeVec("protected_gauge", "Protected.").WithLabelValues().Set(1)
+
+	testCases := []struct {
+		name        string
+		bearerToken string
+		allowedIPs  []string
+		header      http.Header
+		wantStatus  int
+	}{
+		{"no restriction", "", nil, nil, http.StatusOK},
+		{"missing token", "secret", nil, nil, http.StatusUnauthorized},
+		{"wrong token", "secret", nil, http.Header{"Authorization": {"Bearer nope"}}, http.StatusUnauthorized},
+		{"valid token", "secret", nil, http.Header{"Authorization": {"Bearer secret"}}, http.StatusOK},
+		{"allowed loopback cidr", "", []string{"10.0.0.0/8", "127.0.0.0/8"}, nil, http.StatusOK},
+		{"allowed single ip", "", []string{"127.0.0.1"}, nil, http.StatusOK},
+		{"forwarded header is ignored", "", []string{"10.0.0.1"}, http.Header{"X-Forwarded-For": {"10.0.0.1"}}, http.StatusForbidden},
+		{"ip allowed but token missing", "secret", []string{"127.0.0.1"}, nil, http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, err := Protect(registry.Handler(), tc.bearerToken, tc.allowedIPs)
+			if err != nil {
+				t.Fatalf("Protect failed: %v", err)
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			status, body := scrape(t, server.URL, tc.header)
+			if status != tc.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tc.wantStatus, status)
+			}
+			if status == http.StatusOK && !strings.Contains(body, "protected_gauge 1\n") {
+				t.Errorf("Expected metrics in response, got:\n%s", body)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"not-an-ip", "10.0.0.0/40"} {
+		if _, err := Protect(registry.Handler(), "", []string{invalid}); err == nil {
+			t.Errorf("Expected invalid allowed IP %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+
+	// ContentType Prometheus 文本格式（0.0.4）
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// family 一个指标族（同名、同类型的一组时间序列）
+type family interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表，按注册顺序输出 Prometheus 文本格式
+type Registry struct {
+	mu       sync.RWMutex
+	families []family
+	names    map[string]bool
+}
+
+// NewRegistry 创建空的指标注册表
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[f.name()] {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", f.name()))
+	}
+	r.names[f.name()] = true
+	r.families = append(r.families, f)
+}
+
+// WriteText 以 Prometheus 文本格式输出所有指标；没有任何时间序列的指标族不输出
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	families := make([]family, len(r.families))
+	copy(families, r.families)
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler 返回输出 Prometheus 文本格式的 http.Handler
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(w)
+	})
+}
+
+// vec 带标签的时间序列集合，标签值按顺序拼接作为键
+type vec[T any] struct {
+	metricName string
+	help       string
+	labelNames []string
+	newChild   func() *T
+
+	mu       sync.RWMutex
+	children map[string]*child[T]
+}
+
+type child[T any] struct {
+	labelValues []string
+	metric      *T
+}
+
+func newVec[T any](metricName, help string, labelNames []string, newChild func() *T) *vec[T] {
+	return &vec[T]{
+		metricName: metricName,
+		help:       help,
+		labelNames: labelNames,
+		newChild:   newChild,
+		children:   make(map[string]*child[T]),
+	}
+}
+
+func (v *vec[T]) name() string {
+	return v.metricName
+}
+
+func (v *vec[T]) withLabelValues(labelValues ...string) *T {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.RLock()
+	c, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return c.metric
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok := v.children[key]; ok {
+		return c.metric
+	}
+	c = &child[T]{labelValues: append([]string(nil), labelValues...), metric: v.newChild()}
+	v.children[key] = c
+	return c.metric
+}
+
+// sortedChildren 按标签值排序返回所有时间序列，保证输出稳定
+func (v *vec[T]) sortedChildren() []*child[T] {
+	v.mu.RLock()
+	children := make([]*child[T], 0, len(v.children))
+	for _, c := range v.children {
+		children = append(children, c)
+	}
+	v.mu.RUnlock()
+
+	sort.Slice(children, func(i, j int) bool {
+		return lessLabelValues(children[i].labelValues, children[j].labelValues)
+	})
+	return children
+}
+
+// Counter 单调递增计数器
+type Counter struct {
+	bits uint64
+}
+
+// Inc 计数加一
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add 增加计数，负数会被忽略
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	addFloat(&c.bits, delta)
+}
+
+// Value 当前计数
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	*vec[Counter]
+}
+
+// NewCounterVec 创建并注册带标签的计数器
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	v := &CounterVec{newVec(name, help, labelNames, func() *Counter { return &Counter{} })}
+	r.register(v)
+	return v
+}
+
+// WithLabelValues 获取（必要时创建）指定标签值的计数器
+func (v *CounterVec) WithLabelValues(labelValues ...string) *Counter {
+	return v.withLabelValues(labelValues...)
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	children := v.sortedChildren()
+	if len(children) == 0 {
+		return
+	}
+	writeHeader(w, v.metricName, v.help, typeCounter)
+	for _, c := range children {
+		writeSample(w, v.metricName, v.labelNames, c.labelValues, "", "", c.metric.Value())
+	}
+}
+
+// Gauge 可增可减的数值
+type Gauge struct {
+	bits uint64
+}
+
+// Set 设置数值
+func (g *Gauge) Set(value float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(value))
+}
+
+// Inc 数值加一
+func (g *Gauge) Inc() {
+	addFloat(&g.bits, 1)
+}
+
+// Dec 数值减一
+func (g *Gauge) Dec() {
+	addFloat(&g.bits, -1)
+}
+
+// Value 当前数值
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+// GaugeVec 带标签的数值
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+// NewGaugeVec 创建并注册带标签的数值指标
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	v := &GaugeVec{newVec(name, help, labelNames, func() *Gauge { return &Gauge{} })}
+	r.register(v)
+	return v
+}
+
+// WithLabelValues 获取（必要时创建）指定标签值的数值
+func (v *GaugeVec) WithLabelValues(labelValues ...string) *Gauge {
+	return v.withLabelValues(labelValues...)
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	children := v.sortedChildren()
+	if len(children) == 0 {
+		return
+	}
+	writeHeader(w, v.metricName, v.help, typeGauge)
+	for _, c := range children {
+		writeSample(w, v.metricName, v.labelNames, c.labelValues, "", "", c.metric.Value())
+	}
+}
+
+// GaugeFunc 采集时才计算的数值指标，适合端点状态这类已有数据源的状态快照
+type GaugeFunc struct {
+	metricName string
+	help       string
+	labelNames []string
+	collect    func(emit func(value float64, labelValues ...string))
+}
+
+// NewGaugeFunc 创建并注册采集时计算的数值指标；collect 每次采集时调用，通过 emit 输出各时间序列
+func (r *Registry) NewGaugeFunc(name, help string, labelNames []string, collect func(emit func(value float64, labelValues ...string))) *GaugeFunc {
+	g := &GaugeFunc{metricName: name, help: help, labelNames: labelNames, collect: collect}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) name() string {
+	return g.metricName
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	type sample struct {
+		labelValues []string
+		value       float64
+	}
+	var samples []sample
+	g.collect(func(value float64, labelValues ...string) {
+		if len(labelValues) != len(g.labelNames) {
+			return
+		}
+		samples = append(samples, sample{labelValues: labelValues, value: value})
+	})
+	if len(samples) == 0 {
+		return
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		return lessLabelValues(samples[i].labelValues, samples[j].labelValues)
+	})
+	writeHeader(w, g.metricName, g.help, typeGauge)
+	for _, s := range samples {
+		writeSample(w, g.metricName, g.labelNames, s.labelValues, "", "", s.value)
+	}
+}
+
+// Histogram 直方图，桶为累计计数
+type Histogram struct {
+	upperBounds []float64
+
+	mu     sync.Mutex
+	counts []uint64 // 非累计，输出时再累加
+	sum    float64
+	count  uint64
+}
+
+// Observe 记录一次观测值
+func (h *Histogram) Observe(value float64) {
+	i := sort.SearchFloat64s(h.upperBounds, value)
+	h.mu.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += value
+	h.count++
+	h.mu.Unlock()
+}
+
+func (h *Histogram) snapshot() (cumulative []uint64, sum float64, count uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cumulative = make([]uint64, len(h.counts))
+	var running uint64
+	for i, n := range h.counts {
+		running += n
+		cumulative[i] = running
+	}
+	return cumulative, h.sum, h.count
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	*vec[Histogram]
+	upperBounds []float64
+}
+
+// NewHistogramVec 创建并注册带标签的直方图，buckets 为各桶上界（无需包含 +Inf）
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	upperBounds := append([]float64(nil), buckets...)
+	sort.Float64s(upperBounds)
+	v := &HistogramVec{upperBounds: upperBounds}
+	v.vec = newVec(name, help, labelNames, func() *Histogram {
+		return &Histogram{upperBounds: upperBounds, counts: make([]uint64, len(upperBounds))}
+	})
+	r.register(v)
+	return v
+}
+
+// WithLabelValues 获取（必要时创建）指定标签值的直方图
+func (v *HistogramVec) WithLabelValues(labelValues ...string) *Histogram {
+	return v.withLabelValues(labelValues...)
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	children := v.sortedChildren()
+	if len(children) == 0 {
+		return
+	}
+	writeHeader(w, v.metricName, v.help, typeHistogram)
+	for _, c := range children {
+		cumulative, sum, count := c.metric.snapshot()
+		for i, upperBound := range v.upperBounds {
+			writeSample(w, v.metricName+"_bucket", v.labelNames, c.labelValues, "le", formatFloat(upperBound), float64(cumulative[i]))
+		}
+		writeSample(w, v.metricName+"_bucket", v.labelNames, c.labelValues, "le", "+Inf", float64(count))
+		writeSample(w, v.metricName+"_sum", v.labelNames, c.labelValues, "", "", sum)
+		writeSample(w, v.metricName+"_count", v.labelNames, c.labelValues, "", "", float64(count))
+	}
+}
+
+func addFloat(bits *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(bits, old, updated) {
+			return
+		}
+	}
+}
+
+func lessLabelValues(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func writeHeader(w *bufio.Writer, name, help, metricType string) {
+	w.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	w.WriteString("# TYPE " + name + " " + metricType + "\n")
+}
+
+// writeSample 输出一行样本；extraName 非空时追加额外标签（直方图的 le）
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(labelName + `="` + escapeLabelValue(labelValues[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,260 @@
+package oauth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+// Anthropic 订阅账号（Claude Pro/Max）的默认 OAuth 参数
+const (
+	DefaultAuthorizeURL = "https://claude.ai/oauth/authorize"
+	DefaultTokenURL     = "https://console.anthropic.com/v1/oauth/token"
+	DefaultClientID     = "9d1c250a-e61b-44d9-88ed-5944d1962f5e"
+	DefaultRedirectURI  = "https://console.anthropic.com/oauth/code/callback"
+)
+
+// DefaultScopes Anthropic 订阅账号登录默认申请的权限范围
+var DefaultScopes = []string{"org:create_api_key", "user:profile", "user:inference"}
+
+// loginSessionTTL 登录会话有效期，超时后需要重新发起登录
+const loginSessionTTL = 10 * time.Minute
+
+// LoginOptions 发起登录时的可选参数，为空时使用 Anthropic 默认值
+type LoginOptions struct {
+	AuthorizeURL string   `json:"authorize_url,omitempty"`
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	RedirectURI  string   `json:"redirect_uri,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// LoginSession 一次进行中的 PKCE 授权码登录
+type LoginSession struct {
+	State        string
+	AuthorizeURL string // 完整的授权地址，由用户在浏览器中打开
+	options      LoginOptions
+	codeVerifier string
+	createdAt    time.Time
+}
+
+// AuthorizationCodeRequest 授权码换取 token 的请求结构
+type AuthorizationCodeRequest struct {
+	GrantType    string `json:"grant_type"`
+	Code         string `json:"code"`
+	State        string `json:"state,omitempty"`
+	ClientID     string `json:"client_id"`
+	RedirectURI  string `json:"redirect_uri"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// LoginManager 管理进行中的登录会话（按 state 索引，仅保存在内存中）
+type LoginManager struct {
+	mutex    sync.Mutex
+	sessions map[string]*LoginSession
+	now      func() time.Time
+}
+
+// NewLoginManager 创建登录会话管理器
+func NewLoginManager() *LoginManager {
+	return &LoginManager{
+		sessions: make(map[string]*LoginSession),
+		now:      time.Now,
+	}
+}
+
+// GeneratePKCE 生成 PKCE code_verifier 与 S256 code_challenge
+func GeneratePKCE() (verifier string, challenge string, err error) {
+	verifier, err = randomURLSafeString(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// Start 发起登录：生成 verifier、challenge 与 state，返回需要在浏览器中打开的授权地址
+func (m *LoginManager) Start(options LoginOptions) (*LoginSession, error) {
+	options = applyLoginDefaults(options)
+
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate pkce verifier: %v", err)
+	}
+	state, err := randomURLSafeString(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate state: %v", err)
+	}
+
+	authorizeURL, err := url.Parse(options.AuthorizeURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorize_url: %v", err)
+	}
+	query := authorizeURL.Query()
+	query.Set("code", "true") // 授权完成后在页面上显示授权码，供用户复制粘贴
+	query.Set("client_id", options.ClientID)
+	query.Set("response_type", "code")
+	query.Set("redirect_uri", options.RedirectURI)
+	query.Set("scope", strings.Join(options.Scopes, " "))
+	query.Set("code_challenge", challenge)
+	query.Set("code_challenge_method", "S256")
+	query.Set("state", state)
+	authorizeURL.RawQuery = query.Encode()
+
+	session := &LoginSession{
+		State:        state,
+		AuthorizeURL: authorizeURL.String(),
+		options:      options,
+		codeVerifier: verifier,
+		createdAt:    m.now(),
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.cleanupExpiredLocked()
+	m.sessions[state] = session
+
+	return session, nil
+}
+
+// Complete 使用用户粘贴的授权码完成登录，返回开启自动刷新的 OAuth 配置
+// 授权页面显示的授权码形如 "code#state"：未指定 state 时使用其中的 state，
+// 指定了 state 时两者必须一致，否则拒绝（授权码可能来自其他登录会话）
+func (m *LoginManager) Complete(state, code string, httpClient *http.Client) (*config.OAuthConfig, error) {
+	code = strings.TrimSpace(code)
+	if idx := strings.Index(code, "#"); idx >= 0 {
+		if pastedState := code[idx+1:]; pastedState != "" {
+			if state != "" && pastedState != state {
+				return nil, fmt.Errorf("authorization code does not belong to this login session, please start the login again")
+			}
+			state = pastedState
+		}
+		code = code[:idx]
+	}
+	if code == "" {
+		return nil, fmt.Errorf("authorization code is empty")
+	}
+
+	m.mutex.Lock()
+	m.cleanupExpiredLocked()
+	session, ok := m.sessions[state]
+	if ok {
+		// 授权码只能使用一次，无论换取是否成功都结束该会话
+		delete(m.sessions, state)
+	}
+	m.mutex.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("login session not found or expired, please start the login again")
+	}
+
+	return exchangeAuthorizationCode(session, code, httpClient)
+}
+
+// exchangeAuthorizationCode 在 token 端点使用授权码与 code_verifier 换取 token
+func exchangeAuthorizationCode(session *LoginSession, code string, httpClient *http.Client) (*config.OAuthConfig, error) {
+	exchangeReq := AuthorizationCodeRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		State:        session.State,
+		ClientID:     session.options.ClientID,
+		RedirectURI:  session.options.RedirectURI,
+		CodeVerifier: session.codeVerifier,
+	}
+
+	reqBody, err := json.Marshal(exchangeReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token request: %v", err)
+	}
+
+	log.Printf("[OAuth] Exchanging authorization code at token_url: %s", session.options.TokenURL)
+
+	req, err := http.NewRequest("POST", session.options.TokenURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send token request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("authorization code exchange failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	baseConfig := &config.OAuthConfig{
+		TokenURL:    session.options.TokenURL,
+		ClientID:    session.options.ClientID,
+		Scopes:      session.options.Scopes,
+		AutoRefresh: true,
+	}
+	newConfig, err := parseTokenResponse(respBody, baseConfig)
+	if err != nil {
+		return nil, err
+	}
+	if newConfig.RefreshToken == "" {
+		return nil, fmt.Errorf("token response missing refresh_token")
+	}
+
+	return newConfig, nil
+}
+
+// cleanupExpiredLocked 清理超时的登录会话，调用方需持有锁
+func (m *LoginManager) cleanupExpiredLocked() {
+	now := m.now()
+	for state, session := range m.sessions {
+		if now.Sub(session.createdAt) > loginSessionTTL {
+			delete(m.sessions, state)
+		}
+	}
+}
+
+// applyLoginDefaults 为未设置的登录参数填充 Anthropic 默认值
+func applyLoginDefaults(options LoginOptions) LoginOptions {
+	if options.AuthorizeURL == "" {
+		options.AuthorizeURL = DefaultAuthorizeURL
+	}
+	if options.TokenURL == "" {
+		options.TokenURL = DefaultTokenURL
+	}
+	if options.ClientID == "" {
+		options.ClientID = DefaultClientID
+	}
+	if options.RedirectURI == "" {
+		options.RedirectURI = DefaultRedirectURI
+	}
+	if len(options.Scopes) == 0 {
+		options.Scopes = DefaultScopes
+	}
+	return options
+}
+
+// randomURLSafeString 生成 n 字节随机数的 base64url 编码（无填充）
+func randomURLSafeString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
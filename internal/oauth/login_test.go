@@ -0,0 +1,158 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAuthServer 模拟授权服务器的 token 端点，校验 PKCE 后签发 token
+type fakeAuthServer struct {
+	*httptest.Server
+	challenges map[string]string // code -> code_challenge
+	lastReq    AuthorizationCodeRequest
+}
+
+func newFakeAuthServer(t *testing.T) *fakeAuthServer {
+	f := &fakeAuthServer{challenges: make(map[string]string)}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/oauth/token" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		var req AuthorizationCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Invalid token request: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.lastReq = req
+
+		sum := sha256.Sum256([]byte(req.CodeVerifier))
+		challenge, ok := f.challenges[req.Code]
+		if !ok || req.GrantType != "authorization_code" || challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		delete(f.challenges, req.Code)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"sk-ant-oat01-fake","refresh_token":"sk-ant-ort01-fake","expires_in":28800,"token_type":"Bearer"}`))
+	}))
+	return f
+}
+
+// authorize 模拟用户在浏览器中完成授权，返回页面上显示的 "code#state"
+func (f *fakeAuthServer) authorize(t *testing.T, authorizeURL string) string {
+	u, err := url.Parse(authorizeURL)
+	if err != nil {
+		t.Fatalf("Invalid authorize URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("response_type") != "code" {
+		t.Fatalf("Unexpected authorize parameters: %s", u.RawQuery)
+	}
+	code := "auth-code-" + q.Get("state")[:8]
+	f.challenges[code] = q.Get("code_challenge")
+	return code + "#" + q.Get("state")
+}
+
+func TestLoginManager_CompleteAgainstFakeServer(t *testing.T) {
+	server := newFakeAuthServer(t)
+	defer server.Close()
+
+	manager := NewLoginManager()
+	session, err := manager.Start(LoginOptions{
+		AuthorizeURL: server.URL + "/oauth/authorize",
+		TokenURL:     server.URL + "/v1/oauth/token",
+	})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if !strings.HasPrefix(session.AuthorizeURL, server.URL+"/oauth/authorize?") ||
+		!strings.Contains(session.AuthorizeURL, "client_id="+DefaultClientID) ||
+		!strings.Contains(session.AuthorizeURL, "scope=org%3Acreate_api_key+user%3Aprofile+user%3Ainference") {
+		t.Errorf("Unexpected authorize URL: %s", session.AuthorizeURL)
+	}
+
+	pasted := server.authorize(t, session.AuthorizeURL)
+	before := time.Now().UnixMilli()
+	oauthConfig, err := manager.Complete("", "  "+pasted+"\n", server.Client())
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	if server.lastReq.State != session.State || server.lastReq.RedirectURI != DefaultRedirectURI || strings.Contains(server.lastReq.Code, "#") {
+		t.Errorf("Unexpected token request: %+v", server.lastReq)
+	}
+	if oauthConfig.AccessToken != "sk-ant-oat01-fake" || oauthConfig.RefreshToken != "sk-ant-ort01-fake" {
+		t.Errorf("Unexpected tokens: %+v", oauthConfig)
+	}
+	if oauthConfig.TokenURL != server.URL+"/v1/oauth/token" || oauthConfig.ClientID != DefaultClientID || !oauthConfig.AutoRefresh {
+		t.Errorf("Expected token_url, client_id and auto_refresh to be set: %+v", oauthConfig)
+	}
+	if oauthConfig.ExpiresAt < before+28800*1000 {
+		t.Errorf("Expected expires_at from expires_in, got %d", oauthConfig.ExpiresAt)
+	}
+
+	// 会话只能使用一次
+	if _, err := manager.Complete(session.State, "another-code", server.Client()); err == nil {
+		t.Errorf("Expected completed session to be removed")
+	}
+}
+
+func TestLoginManager_RejectsWrongVerifierAndExpiredSession(t *testing.T) {
+	server := newFakeAuthServer(t)
+	defer server.Close()
+
+	manager := NewLoginManager()
+	options := LoginOptions{AuthorizeURL: server.URL + "/oauth/authorize", TokenURL: server.URL + "/v1/oauth/token"}
+
+	first, _ := manager.Start(options)
+	second, _ := manager.Start(options)
+	if first.State == second.State {
+		t.Fatalf("Expected unique state per session")
+	}
+
+	// 使用第一个会话的授权码完成第二个会话：verifier 不匹配
+	code := strings.SplitN(server.authorize(t, first.AuthorizeURL), "#", 2)[0]
+	if _, err := manager.Complete(second.State, code, server.Client()); err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Errorf("Expected PKCE mismatch to be rejected, got %v", err)
+	}
+
+	// 粘贴的 "code#state" 与当前会话不一致时直接拒绝，且不结束当前会话
+	pasted := server.authorize(t, first.AuthorizeURL)
+	if _, err := manager.Complete(second.State, pasted, server.Client()); err == nil || !strings.Contains(err.Error(), "does not belong") {
+		t.Errorf("Expected mismatched pasted state to be rejected, got %v", err)
+	}
+	if _, ok := manager.sessions[first.State]; !ok {
+		t.Errorf("Expected the session named in the pasted code to be kept")
+	}
+
+	manager.now = func() time.Time { return time.Now().Add(loginSessionTTL + time.Minute) }
+	if _, err := manager.Complete(first.State, code, server.Client()); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("Expected expired session error, got %v", err)
+	}
+}
+
+func TestGeneratePKCE(t *testing.T) {
+	verifier, challenge, err := GeneratePKCE()
+	if err != nil {
+		t.Fatalf("GeneratePKCE failed: %v", err)
+	}
+	// RFC 7636: verifier 长度 43-128
+	if len(verifier) < 43 || len(verifier) > 128 {
+		t.Errorf("Unexpected verifier length %d", len(verifier))
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Errorf("Challenge is not S256 of verifier")
+	}
+}
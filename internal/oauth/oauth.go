@@ -237,6 +237,42 @@ func ShouldRefreshToken(oauthConfig *config.OAuthConfig) bool {
 	return time.Now().Add(bufferTime).After(expirationTime)
 }
 
+// 后台刷新失败后的重试退避参数
+const (
+	refreshBackoffBase = 30 * time.Second
+	refreshBackoffMax  = 30 * time.Minute
+)
+
+// IsRefreshDue 检查后台刷新是否应该在 margin 内提前刷新 token
+// 未开启自动刷新或没有 refresh_token 时不刷新；过期时间未知（0）时立即刷新以获取正确的过期时间
+func IsRefreshDue(oauthConfig *config.OAuthConfig, margin time.Duration, now time.Time) bool {
+	if oauthConfig == nil || !oauthConfig.AutoRefresh || oauthConfig.RefreshToken == "" {
+		return false
+	}
+
+	if oauthConfig.ExpiresAt <= 0 {
+		return true
+	}
+
+	return !now.Add(margin).Before(time.UnixMilli(oauthConfig.ExpiresAt))
+}
+
+// RefreshBackoff 计算连续失败 failures 次后下一次重试前的等待时间（指数退避，上限30分钟）
+func RefreshBackoff(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+
+	backoff := refreshBackoffBase
+	for i := 1; i < failures && backoff < refreshBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > refreshBackoffMax {
+		backoff = refreshBackoffMax
+	}
+	return backoff
+}
+
 // GetAuthorizationHeader 获取授权头部
 func GetAuthorizationHeader(oauthConfig *config.OAuthConfig) string {
 	if oauthConfig == nil || oauthConfig.AccessToken == "" {
@@ -0,0 +1,50 @@
+package oauth
+
+import (
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestIsRefreshDue(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	margin := 10 * time.Minute
+
+	cases := []struct {
+		name   string
+		config *config.OAuthConfig
+		want   bool
+	}{
+		{"nil config", nil, false},
+		{"auto refresh disabled", &config.OAuthConfig{RefreshToken: "r", ExpiresAt: now.UnixMilli()}, false},
+		{"missing refresh token", &config.OAuthConfig{AutoRefresh: true, ExpiresAt: now.UnixMilli()}, false},
+		{"unknown expiry", &config.OAuthConfig{AutoRefresh: true, RefreshToken: "r"}, true},
+		{"outside margin", &config.OAuthConfig{AutoRefresh: true, RefreshToken: "r", ExpiresAt: now.Add(11 * time.Minute).UnixMilli()}, false},
+		{"inside margin", &config.OAuthConfig{AutoRefresh: true, RefreshToken: "r", ExpiresAt: now.Add(9 * time.Minute).UnixMilli()}, true},
+		{"already expired", &config.OAuthConfig{AutoRefresh: true, RefreshToken: "r", ExpiresAt: now.Add(-time.Minute).UnixMilli()}, true},
+	}
+
+	for _, tc := range cases {
+		if got := IsRefreshDue(tc.config, margin, now); got != tc.want {
+			t.Errorf("%s: IsRefreshDue = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRefreshBackoff(t *testing.T) {
+	expected := map[int]time.Duration{
+		0:  0,
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		6:  16 * time.Minute,
+		7:  30 * time.Minute,
+		50: 30 * time.Minute,
+	}
+	for failures, want := range expected {
+		if got := RefreshBackoff(failures); got != want {
+			t.Errorf("RefreshBackoff(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
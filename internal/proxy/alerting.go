@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"claude-code-codex-companion/internal/alerting"
+	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/statistics"
+)
+
+// alertSource 为告警规则提供端点状态和时间序列统计
+type alertSource struct {
+	endpointManager *endpoint.Manager
+}
+
+// Endpoints 返回所有端点的 OAuth 刷新和限流状态
+func (a *alertSource) Endpoints() []alerting.EndpointState {
+	endpoints := a.endpointManager.GetAllEndpoints()
+	states := make([]alerting.EndpointState, 0, len(endpoints))
+	for _, ep := range endpoints {
+		state := alerting.EndpointState{Name: ep.Name}
+		if status := ep.GetOAuthRefreshStatus(); status != nil {
+			state.OAuthFailures = status.ConsecutiveFailures
+			if len(status.History) > 0 {
+				state.OAuthError = status.History[0].Error
+			}
+		}
+		reset, status := ep.GetRateLimitState()
+		if status != nil {
+			state.RateLimitStatus = *status
+		}
+		if reset != nil {
+			state.RateLimitReset = *reset
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// TimeSeries 查询时间序列统计
+func (a *alertSource) TimeSeries(query statistics.TimeSeriesQuery) (*statistics.TimeSeriesResult, error) {
+	return a.endpointManager.GetTimeSeries().Query(query)
+}
+
+// StopAlerting 停止告警后台任务，队列中的告警会在返回前发送
+func (s *Server) StopAlerting() {
+	s.alerter.Stop()
+}
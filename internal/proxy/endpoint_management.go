@@ -6,11 +6,16 @@ import (
 	"strings"
 	"time"
 
+	"claude-code-codex-companion/internal/common/httpclient"
 	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/metrics"
 	"claude-code-codex-companion/internal/tagging"
 	"claude-code-codex-companion/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RetryBehavior 定义重试行为
@@ -44,6 +49,14 @@ func (s *Server) tryProxyRequestWithRetry(c *gin.Context, ep *endpoint.Endpoint,
 			errorMsg = "Endpoint is blacklisted (no detailed reason available)"
 		}
 		
+		// 被跳过的拉黑端点同样记录一个尝试 span，便于在链路中看到跳过原因
+		attemptSpan, endAttempt := startSpan(c, "cccc.upstream_attempt", trace.WithAttributes(endpointAttributes(ep)...))
+		attemptSpan.SetAttributes(
+			attribute.Int("cccc.attempt", globalAttemptNumber),
+			attribute.Bool("cccc.endpoint.blacklisted", true),
+		)
+		endAttempt()
+
 		// 记录被拉黑端点的虚拟请求日志
 		s.logBlacklistedEndpointRequest(requestID, ep, path, requestBody, c, duration, errorMsg, causingRequestIDs, globalAttemptNumber, taggedRequest)
 		
@@ -56,7 +69,29 @@ func (s *Server) tryProxyRequestWithRetry(c *gin.Context, ep *endpoint.Endpoint,
 		currentGlobalAttempt := globalAttemptNumber + endpointAttempt - 1
 		s.logger.Debug(fmt.Sprintf("Trying endpoint %s (endpoint attempt %d/%d, global attempt %d)", ep.Name, endpointAttempt, MaxEndpointRetries, currentGlobalAttempt))
 		
+		attemptSpan, endAttempt := startSpan(c, "cccc.upstream_attempt", trace.WithAttributes(endpointAttributes(ep)...))
+		attemptSpan.SetAttributes(
+			attribute.Int("cccc.attempt", currentGlobalAttempt),
+			attribute.Int("cccc.endpoint_attempt", endpointAttempt),
+		)
+		endpointInFlight := metrics.EndpointRequestsInFlight.WithLabelValues(ep.Name)
+		endpointInFlight.Inc()
+		attemptStart := time.Now()
 		success, shouldRetryAnywhere := s.proxyToEndpoint(c, ep, path, requestBody, requestID, startTime, taggedRequest, currentGlobalAttempt)
+		endpointInFlight.Dec()
+		s.recordAttemptMetrics(c, ep, currentGlobalAttempt, time.Since(attemptStart), success)
+		attemptSpan.SetAttributes(
+			attribute.Bool("cccc.success", success),
+			attribute.Int("http.response.status_code", c.GetInt("last_status_code")),
+		)
+		if !success {
+			if lastErr, ok := c.Value("last_error").(error); ok && lastErr != nil {
+				s.recordSpanError(attemptSpan, lastErr)
+			} else {
+				attemptSpan.SetStatus(codes.Error, fmt.Sprintf("upstream status %d", c.GetInt("last_status_code")))
+			}
+		}
+		endAttempt()
 		if success {
 			// 检查是否应该跳过健康统计记录
 			skipHealthRecord, _ := c.Get("skip_health_record")
@@ -76,10 +111,10 @@ func (s *Server) tryProxyRequestWithRetry(c *gin.Context, ep *endpoint.Endpoint,
 			return true, false
 		}
 		
-		// 记录失败，但检查是否为 count_tokens 请求，如果是则不计入健康统计
+		// 记录失败，但检查是否为 count_tokens 请求或代理故障，如果是则不计入健康统计
 		skipHealthRecord, _ := c.Get("skip_health_record")
 		isCountTokensRequest := strings.Contains(path, "/count_tokens")
-		shouldSkip := (skipHealthRecord == true) || isCountTokensRequest
+		shouldSkip := (skipHealthRecord == true) || isCountTokensRequest || s.isProxyFailure(c)
 		if !shouldSkip {
 			s.endpointManager.RecordRequest(ep.ID, false, requestID)
 		}
@@ -145,8 +180,31 @@ const (
 	ErrorCategorySSEValidationError  ErrorCategory = 4 // SSE流不完整验证错误，原地重试
 	ErrorCategoryOtherValidationError ErrorCategory = 5 // 其他验证错误，切换端点
 	ErrorCategoryResponseTimeoutError ErrorCategory = 6 // 响应超时错误，切换端点
+	ErrorCategoryToolArgumentError   ErrorCategory = 7 // 工具参数无法修复（策略为 retry），原地重试
+	ErrorCategoryProxyError          ErrorCategory = 8 // 代理本身故障，切换端点且不计入端点健康统计
 )
 
+// errorCategoryLabels 错误类别在统计中使用的名称
+var errorCategoryLabels = map[ErrorCategory]string{
+	ErrorCategoryClientError:          "client_error",
+	ErrorCategoryServerError:          "server_error",
+	ErrorCategoryNetworkError:         "network_error",
+	ErrorCategoryUsageValidationError: "usage_validation",
+	ErrorCategorySSEValidationError:   "incomplete_stream",
+	ErrorCategoryOtherValidationError: "validation_error",
+	ErrorCategoryResponseTimeoutError: "response_timeout",
+	ErrorCategoryToolArgumentError:    "tool_arguments",
+	ErrorCategoryProxyError:           "proxy_error",
+}
+
+// Label 返回错误类别名称
+func (e ErrorCategory) Label() string {
+	if label, ok := errorCategoryLabels[e]; ok {
+		return label
+	}
+	return "unknown"
+}
+
 // determineRetryBehaviorFromError 根据错误信息确定重试行为
 func (s *Server) determineRetryBehaviorFromError(err error, statusCode int, currentAttempt int) RetryBehavior {
 	if err == nil && statusCode >= 200 && statusCode < 300 {
@@ -190,6 +248,13 @@ func (s *Server) determineRetryBehaviorFromError(err error, statusCode int, curr
 		}
 		return RetryBehaviorSwitchEndpoint
 		
+	case ErrorCategoryToolArgumentError:
+		// 工具参数无法修复，原地重试让模型重新生成
+		if currentAttempt < MaxEndpointRetries {
+			return RetryBehaviorRetryEndpoint
+		}
+		return RetryBehaviorSwitchEndpoint
+		
 	case ErrorCategoryOtherValidationError:
 		// 其他验证错误，切换端点
 		return RetryBehaviorSwitchEndpoint
@@ -198,6 +263,10 @@ func (s *Server) determineRetryBehaviorFromError(err error, statusCode int, curr
 		// 响应超时错误，切换端点
 		return RetryBehaviorSwitchEndpoint
 		
+	case ErrorCategoryProxyError:
+		// 代理故障，切换到其他端点（可能使用不同的代理或直连）
+		return RetryBehaviorSwitchEndpoint
+		
 	default:
 		// 未知错误，在同一端点重试
 		if currentAttempt < MaxEndpointRetries {
@@ -221,6 +290,11 @@ func (s *Server) categorizeError(err error, statusCode int) ErrorCategory {
 	
 	errStr := err.Error()
 	
+	// 代理故障（代理不可达、TLS握手失败、代理认证失败），与上游端点错误区分
+	if httpclient.IsProxyError(err) || strings.Contains(errStr, "proxy error (") {
+		return ErrorCategoryProxyError
+	}
+	
 	// 客户端错误（基于错误字符串判断的特定错误仍然直接切换端点）
 	if strings.Contains(errStr, "Request format conversion failed") ||
 	   strings.Contains(errStr, "Authentication failed") ||
@@ -246,6 +320,11 @@ func (s *Server) categorizeError(err error, statusCode int) ErrorCategory {
 		return ErrorCategorySSEValidationError
 	}
 	
+	// 工具参数无法修复（原地重试）
+	if strings.Contains(errStr, "Invalid tool arguments, retrying endpoint") {
+		return ErrorCategoryToolArgumentError
+	}
+	
 	// 其他验证错误（切换端点）
 	if strings.Contains(errStr, "validation failed") ||
 	   strings.Contains(errStr, "Response format conversion failed") {
@@ -271,6 +350,16 @@ func (s *Server) categorizeError(err error, statusCode int) ErrorCategory {
 	return ErrorCategoryServerError
 }
 
+// isProxyFailure 判断上一次失败是否由代理本身引起，代理故障不计入端点健康统计，避免拉黑代理后面的端点
+func (s *Server) isProxyFailure(c *gin.Context) bool {
+	if errInterface, exists := c.Get("last_error"); exists {
+		if err, ok := errInterface.(error); ok && err != nil {
+			return s.categorizeError(err, 0) == ErrorCategoryProxyError
+		}
+	}
+	return false
+}
+
 // determineRetryBehavior 根据当前情况确定重试行为（保持向后兼容）
 func (s *Server) determineRetryBehavior(c *gin.Context, ep *endpoint.Endpoint, currentAttempt int) RetryBehavior {
 	// 临时实现：默认在同一端点重试，最后一次尝试时切换端点
@@ -396,8 +485,8 @@ func (s *Server) isEndpointCompatibleWithFormat(ep *endpoint.Endpoint, requestFo
 	// 2. Anthropic 请求 → 优先 Anthropic 端点，也可以选择 OpenAI 端点（支持 Anthropic → OpenAI 转换）
 
 	if requestFormat == "openai" {
-		// OpenAI 请求只能发到 OpenAI 端点
-		return ep.EndpointType == "openai"
+		// OpenAI 请求只能发到 OpenAI 格式端点（含 gemini 等经适配器转换的端点）
+		return ep.GetFormat() == "openai"
 	}
 
 	if requestFormat == "anthropic" {
@@ -413,10 +502,14 @@ func (s *Server) isEndpointCompatibleWithFormat(ep *endpoint.Endpoint, requestFo
 
 // fallbackToOtherEndpoints 当endpoint失败时，根据是否有tag决定fallback策略
 func (s *Server) fallbackToOtherEndpoints(c *gin.Context, path string, requestBody []byte, requestID string, startTime time.Time, failedEndpoint *endpoint.Endpoint, taggedRequest *tagging.TaggedRequest) {
-	// 记录失败的endpoint，但检查是否为 count_tokens 请求，如果是则不计入健康统计
+	fallbackSpan, endFallback := startSpan(c, "cccc.fallback")
+	defer endFallback()
+	fallbackSpan.SetAttributes(attribute.String("cccc.failed_endpoint", failedEndpoint.Name))
+
+	// 记录失败的endpoint，但检查是否为 count_tokens 请求或代理故障，如果是则不计入健康统计
 	skipHealthRecord, _ := c.Get("skip_health_record")
 	isCountTokensRequest := strings.Contains(path, "/count_tokens")
-	shouldSkip := (skipHealthRecord == true) || isCountTokensRequest
+	shouldSkip := (skipHealthRecord == true) || isCountTokensRequest || s.isProxyFailure(c)
 	if !shouldSkip {
 		s.endpointManager.RecordRequest(failedEndpoint.ID, false, requestID)
 	}
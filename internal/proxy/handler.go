@@ -7,12 +7,19 @@ import (
 	"time"
 
 	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/metrics"
 	"claude-code-codex-companion/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func (s *Server) handleProxy(c *gin.Context) {
+	inFlight := metrics.RequestsInFlight.WithLabelValues()
+	inFlight.Inc()
+	defer inFlight.Dec()
+
 	requestID := c.GetString("request_id")
 	startTime := c.MustGet("start_time").(time.Time)
 	path := c.Param("path")
@@ -44,6 +51,11 @@ func (s *Server) handleProxy(c *gin.Context) {
 	originalModel := s.extractModelFromRequest(requestBody)
 	// 存储到context中，供后续使用
 	c.Set("original_model", originalModel)
+	currentSpan(c).SetAttributes(
+		attribute.String("cccc.client_type", string(formatDetection.ClientType)),
+		attribute.String("cccc.request_format", string(formatDetection.Format)),
+		attribute.String("cccc.model.original", originalModel),
+	)
 
 	// 提取 thinking 信息
 	thinkingInfo, err := utils.ExtractThinkingInfo(string(requestBody))
@@ -54,7 +66,23 @@ func (s *Server) handleProxy(c *gin.Context) {
 	c.Set("thinking_info", thinkingInfo)
 
 	// 处理请求标签
+	taggingSpan, endTagging := startSpan(c, "cccc.tagging")
 	taggedRequest := s.processRequestTags(c.Request)
+	if taggedRequest != nil {
+		taggingSpan.SetAttributes(attribute.StringSlice("cccc.tags", taggedRequest.Tags))
+		for _, result := range taggedRequest.TaggerResults {
+			attrs := []attribute.KeyValue{
+				attribute.String("cccc.tagger.name", result.TaggerName),
+				attribute.Bool("cccc.tagger.matched", result.Matched),
+				attribute.Float64("cccc.tagger.duration_ms", float64(result.Duration.Microseconds())/1000),
+			}
+			if result.Error != nil {
+				attrs = append(attrs, attribute.String("cccc.tagger.error", result.Error.Error()))
+			}
+			taggingSpan.AddEvent("tagger", trace.WithAttributes(attrs...))
+		}
+	}
+	endTagging()
 
 	// count_tokens 请求将通过统一的端点尝试和回退逻辑处理
 	// OpenAI 端点不支持 count_tokens，但会自动回退到支持的端点
@@ -62,7 +90,14 @@ func (s *Server) handleProxy(c *gin.Context) {
 	// 选择端点并处理请求（根据格式、客户端类型和标签选择兼容的端点）
 	requestFormat := string(formatDetection.Format)
 	clientType := string(formatDetection.ClientType)
+	routingSpan, endRouting := startSpan(c, "cccc.routing")
 	selectedEndpoint, err := s.selectEndpointForRequest(taggedRequest, requestFormat, clientType)
+	if err != nil {
+		s.recordSpanError(routingSpan, err)
+	} else {
+		routingSpan.SetAttributes(endpointAttributes(selectedEndpoint)...)
+	}
+	endRouting()
 	if err != nil {
 		s.logger.Error("Failed to select endpoint", err)
 		// 获取tags用于日志记录
@@ -72,6 +107,7 @@ func (s *Server) handleProxy(c *gin.Context) {
 		}
 		// 生成详细的错误消息
 		errorMsg := s.generateDetailedEndpointUnavailableMessage(requestID, tags)
+		s.alerter.NoAvailableEndpoints(tags, errorMsg)
 		s.sendFailureResponse(c, requestID, startTime, requestBody, tags, 0, errorMsg, "no_available_endpoints")
 		return
 	}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func hotUpdateTestConfig(sinks ...config.AlertSinkConfig) *config.Config {
+	return &config.Config{
+		Server:    config.ServerConfig{Host: "127.0.0.1", Port: 8080},
+		Endpoints: []config.EndpointConfig{{Name: "primary", URL: "https://api.example.com", Enabled: true}},
+		Alerting:  config.AlertingConfig{Enabled: true, Sinks: sinks},
+	}
+}
+
+func TestHotUpdateConfig_RejectsCommandSinkChanges(t *testing.T) {
+	script := config.AlertSinkConfig{Name: "script", Type: "command", Command: "/usr/local/bin/notify", Args: []string{"--quiet"}}
+	hook := config.AlertSinkConfig{Name: "hook", Type: "webhook", URL: "https://hooks.example.com/alert"}
+
+	tests := []struct {
+		name    string
+		current *config.Config
+		updated *config.Config
+		reject  string
+	}{
+		{
+			name:    "add command sink",
+			current: hotUpdateTestConfig(hook),
+			updated: hotUpdateTestConfig(hook, config.AlertSinkConfig{Name: "evil", Type: "command", Command: "sh", Args: []string{"-c", "id"}}),
+			reject:  "cannot be added",
+		},
+		{
+			name:    "change command",
+			current: hotUpdateTestConfig(script),
+			updated: hotUpdateTestConfig(config.AlertSinkConfig{Name: "script", Type: "command", Command: "sh", Args: script.Args}),
+			reject:  "cannot be changed",
+		},
+		{
+			name:    "change args",
+			current: hotUpdateTestConfig(script),
+			updated: hotUpdateTestConfig(config.AlertSinkConfig{Name: "script", Type: "command", Command: script.Command, Args: []string{"-c", "id"}}),
+			reject:  "cannot be changed",
+		},
+		{
+			name:    "webhook sink turned into command",
+			current: hotUpdateTestConfig(hook),
+			updated: hotUpdateTestConfig(config.AlertSinkConfig{Name: "hook", Type: "command", Command: "sh"}),
+			reject:  "cannot be added",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{config: tt.current}
+			err := s.HotUpdateConfig(tt.updated)
+			if err == nil || !strings.Contains(err.Error(), tt.reject) {
+				t.Fatalf("Expected hot update to be rejected with %q, got %v", tt.reject, err)
+			}
+			if s.config != tt.current {
+				t.Error("Expected running config to stay unchanged")
+			}
+		})
+	}
+}
+
+func TestValidateConfigForHotUpdate_AllowsUnchangedCommandSink(t *testing.T) {
+	script := config.AlertSinkConfig{Name: "script", Type: "command", Command: "/usr/local/bin/notify", Args: []string{"--quiet"}}
+	hook := config.AlertSinkConfig{Name: "hook", Type: "webhook", URL: "https://hooks.example.com/alert"}
+	s := &Server{config: hotUpdateTestConfig(script)}
+
+	// 保留原有 command sink、新增 webhook sink 或删除 command sink 均允许
+	for _, updated := range []*config.Config{hotUpdateTestConfig(script, hook), hotUpdateTestConfig(hook)} {
+		if err := s.validateConfigForHotUpdate(updated); err != nil {
+			t.Errorf("Expected update to be allowed, got %v", err)
+		}
+	}
+}
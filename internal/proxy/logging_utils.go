@@ -18,6 +18,7 @@ import (
 func (s *Server) sendFailureResponse(c *gin.Context, requestID string, startTime time.Time, requestBody []byte, requestTags []string, attemptedCount int, errorMsg, errorType string) {
 	duration := time.Since(startTime)
 	requestLog := s.logger.CreateRequestLog(requestID, "failed", c.Request.Method, c.Param("path"))
+	requestLog.TraceID = traceIDFromContext(c)
 	requestLog.DurationMs = duration.Nanoseconds() / 1000000
 	requestLog.StatusCode = http.StatusBadGateway
 	
@@ -93,6 +94,7 @@ func (s *Server) sendFailureResponse(c *gin.Context, requestID string, startTime
 // logSimpleRequest creates and logs a simple request log entry for error cases
 func (s *Server) logSimpleRequest(requestID, endpoint, method, path string, originalRequestBody []byte, finalRequestBody []byte, c *gin.Context, req *http.Request, resp *http.Response, responseBody []byte, duration time.Duration, err error, isStreaming bool, tags []string, contentTypeOverride string, originalModel, rewrittenModel string, attemptNumber int) {
 	requestLog := s.logger.CreateRequestLog(requestID, endpoint, method, path)
+	requestLog.TraceID = traceIDFromContext(c)
 	requestLog.RequestBodySize = len(originalRequestBody)
 	requestLog.Tags = tags
 	requestLog.ContentTypeOverride = contentTypeOverride
@@ -219,6 +221,7 @@ func (s *Server) logSimpleRequest(requestID, endpoint, method, path string, orig
 // logBlacklistedEndpointRequest 记录对被拉黑端点的请求日志
 func (s *Server) logBlacklistedEndpointRequest(requestID string, ep *endpoint.Endpoint, path string, requestBody []byte, c *gin.Context, duration time.Duration, errorMsg string, causingRequestIDs []string, attemptNumber int, taggedRequest *tagging.TaggedRequest) {
 	requestLog := s.logger.CreateRequestLog(requestID, ep.URL, c.Request.Method, path)
+	requestLog.TraceID = traceIDFromContext(c)
 	requestLog.RequestBodySize = len(requestBody)
 	requestLog.AttemptNumber = attemptNumber
 	requestLog.DurationMs = duration.Nanoseconds() / 1000000
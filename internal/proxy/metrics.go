@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/conversion"
+	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/metrics"
+	"claude-code-codex-companion/internal/statistics"
+	"claude-code-codex-companion/internal/tagging"
+	"claude-code-codex-companion/internal/transcript"
+	"claude-code-codex-companion/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleMetrics 输出 Prometheus 指标；访问限制每次请求时从当前配置读取，支持热更新
+func (s *Server) handleMetrics(c *gin.Context) {
+	s.configMutex.Lock()
+	metricsConfig := s.config.Metrics
+	s.configMutex.Unlock()
+	handler, err := metrics.Protect(metrics.Default.Handler(), metricsConfig.BearerToken, metricsConfig.AllowedIPs)
+	if err != nil {
+		s.logger.Error("Invalid metrics access configuration", err)
+		c.String(http.StatusInternalServerError, "invalid metrics configuration")
+		return
+	}
+	handler.ServeHTTP(c.Writer, c.Request)
+}
+
+// endpointMetricsStates 采集时生成端点状态快照
+func (s *Server) endpointMetricsStates() []metrics.EndpointState {
+	endpoints := s.endpointManager.GetAllEndpoints()
+	states := make([]metrics.EndpointState, 0, len(endpoints))
+	for _, ep := range endpoints {
+		states = append(states, metrics.EndpointState{
+			Name:                     ep.Name,
+			Status:                   ep.GetMonitoringStatus(),
+			LearnedUnsupportedParams: ep.GetLearnedUnsupportedParams(),
+		})
+	}
+	return states
+}
+
+// recordAttemptMetrics 记录一次上游尝试的计数与耗时，状态码取自 context 中的 last_status_code
+// 同时写入时间序列统计，失败时按 categorizeError 的类别计数
+func (s *Server) recordAttemptMetrics(c *gin.Context, ep *endpoint.Endpoint, attemptNumber int, duration time.Duration, success bool) {
+	originalModel, rewrittenModel := c.GetString("original_model"), c.GetString("rewritten_model")
+	model := s.metricsModelLabel(originalModel, rewrittenModel)
+	clientType := string(utils.ClientUnknown)
+	if formatDetection, exists := c.Get("format_detection"); exists {
+		if detection, ok := formatDetection.(*utils.FormatDetectionResult); ok && detection != nil {
+			clientType = metrics.ValueOrUnknown(string(detection.ClientType))
+		}
+	}
+
+	metrics.Requests.WithLabelValues(ep.Name, model, clientType, metrics.StatusLabel(c.GetInt("last_status_code")), strconv.Itoa(attemptNumber)).Inc()
+	metrics.RequestDuration.WithLabelValues(ep.Name, model, clientType).Observe(duration.Seconds())
+
+	// 时间序列统计记录实际模型名，按模型的图表与 spend_cap 告警依赖它匹配模型价格
+	statisticsModel := rewrittenModel
+	if statisticsModel == "" {
+		statisticsModel = originalModel
+	}
+	sample := statistics.TimeSeriesSample{
+		Endpoint:   ep.Name,
+		Model:      metrics.ValueOrUnknown(statisticsModel),
+		ClientType: clientType,
+		Success:    success,
+		Latency:    duration,
+	}
+	if success {
+		if usage, ok := c.Value("response_usage").(transcript.Usage); ok {
+			sample.InputTokens = int64(usage.InputTokens)
+			sample.OutputTokens = int64(usage.OutputTokens)
+			sample.CacheReadTokens = int64(usage.CacheReadTokens)
+			sample.CacheWriteTokens = int64(usage.CacheWriteTokens)
+		}
+	} else {
+		lastErr, _ := c.Value("last_error").(error)
+		sample.ErrorCategory = s.categorizeError(lastErr, c.GetInt("last_status_code")).Label()
+	}
+	s.endpointManager.GetTimeSeries().Record(sample)
+}
+
+// metricsModelLabel Prometheus 指标使用的模型标签
+// 模型被重写时取重写后的模型；未重写时只有配置中出现过的模型名保留原值，其余记为 other
+func (s *Server) metricsModelLabel(originalModel, rewrittenModel string) string {
+	if rewrittenModel != "" {
+		return rewrittenModel
+	}
+	if originalModel == "" {
+		return metrics.ValueOrUnknown(originalModel)
+	}
+
+	s.configMutex.Lock()
+	defer s.configMutex.Unlock()
+	if isConfiguredModel(s.config, originalModel) {
+		return originalModel
+	}
+	return metrics.OtherModel
+}
+
+// isConfiguredModel 模型名是否在配置中出现：模型重写规则（不含通配符的源模型和目标模型）、
+// Azure 部署映射，以及模型价格（与 transcript.Cost 一致按通配符匹配）
+func isConfiguredModel(cfg *config.Config, model string) bool {
+	for _, ep := range cfg.Endpoints {
+		if ep.ModelRewrite != nil {
+			for _, rule := range ep.ModelRewrite.Rules {
+				if rule.TargetModel == model || rule.SourcePattern == model {
+					return true
+				}
+			}
+		}
+		if ep.AzureConfig != nil {
+			if _, ok := ep.AzureConfig.Deployments[model]; ok {
+				return true
+			}
+		}
+	}
+	for _, pricing := range cfg.Pricing {
+		if matched, err := filepath.Match(pricing.Model, model); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// recordConversionFailure 按转换阶段与 ConversionError 类型记录转换失败
+func recordConversionFailure(stage string, err error) {
+	kind := "unknown"
+	var convErr *conversion.ConversionError
+	if errors.As(err, &convErr) && convErr.Type != "" {
+		kind = convErr.Type
+	}
+	metrics.ConversionFailures.WithLabelValues(stage, kind).Inc()
+}
+
+// recordTaggerMetrics 记录各 tagger 的执行耗时
+func recordTaggerMetrics(taggedRequest *tagging.TaggedRequest) {
+	for _, result := range taggedRequest.TaggerResults {
+		outcome := "unmatched"
+		if result.Error != nil {
+			outcome = "error"
+		} else if result.Matched {
+			outcome = "matched"
+		}
+		metrics.TaggerDuration.WithLabelValues(result.TaggerName, outcome).Observe(result.Duration.Seconds())
+	}
+}
+
+// firstByteReader 记录上游响应体首个字节到达的时间，用于统计流式响应的首 token 耗时
+type firstByteReader struct {
+	io.ReadCloser
+	firstByteAt time.Time
+}
+
+func (r *firstByteReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 && r.firstByteAt.IsZero() {
+		r.firstByteAt = time.Now()
+	}
+	return n, err
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/alerting"
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/statistics"
+	"claude-code-codex-companion/internal/transcript"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsModelLabel(t *testing.T) {
+	s := &Server{config: &config.Config{
+		Endpoints: []config.EndpointConfig{
+			{
+				Name: "rewrite",
+				ModelRewrite: &config.ModelRewriteConfig{Enabled: true, Rules: []config.ModelRewriteRule{
+					{SourcePattern: "claude-*", TargetModel: "gpt-4o"},
+					{SourcePattern: "claude-3-haiku", TargetModel: "gpt-4o-mini"},
+				}},
+			},
+			{
+				Name:        "azure",
+				AzureConfig: &config.AzureConfig{Deployments: map[string]string{"gpt-4.1": "prod-gpt41"}},
+			},
+		},
+		Pricing: []config.ModelPricingConfig{{Model: "claude-sonnet-4"}, {Model: "claude-opus-*"}, {Model: "[invalid"}},
+	}}
+
+	testCases := []struct {
+		original  string
+		rewritten string
+		expected  string
+	}{
+		{original: "claude-anything", rewritten: "gpt-4o", expected: "gpt-4o"},
+		{original: "claude-3-haiku", expected: "claude-3-haiku"},
+		{original: "gpt-4o-mini", expected: "gpt-4o-mini"},
+		{original: "gpt-4.1", expected: "gpt-4.1"},
+		{original: "claude-sonnet-4", expected: "claude-sonnet-4"},
+		{original: "claude-opus-4", expected: "claude-opus-4"},
+		{original: "client-supplied-random-name", expected: "other"},
+		{original: "", expected: "unknown"},
+	}
+	for _, tc := range testCases {
+		if got := s.metricsModelLabel(tc.original, tc.rewritten); got != tc.expected {
+			t.Errorf("metricsModelLabel(%q, %q) = %q, expected %q", tc.original, tc.rewritten, got, tc.expected)
+		}
+	}
+}
+
+func TestRecordAttemptMetrics_GlobPricedModelReachesSpendCap(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		Logging:   config.LoggingConfig{LogDirectory: t.TempDir()},
+		Endpoints: []config.EndpointConfig{{Name: "direct", URL: "https://api.anthropic.com", EndpointType: "anthropic", Enabled: true}},
+		Pricing:   []config.ModelPricingConfig{{Model: "claude-sonnet-*", Input: 3, Output: 15}},
+	}
+	manager, err := endpoint.NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if manager.GetTimeSeries() == nil {
+		t.Skip("time-series statistics are unavailable")
+	}
+	s := &Server{config: cfg, endpointManager: manager}
+
+	// 未配置模型重写的直连端点：模型只由 pricing 的通配符匹配
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("original_model", "claude-sonnet-4-5-20250929")
+	c.Set("rewritten_model", "")
+	c.Set("last_status_code", 200)
+	c.Set("response_usage", transcript.Usage{InputTokens: 2000000, OutputTokens: 1000000})
+	s.recordAttemptMetrics(c, manager.GetAllEndpoints()[0], 1, time.Second, true)
+
+	result, err := manager.GetTimeSeries().Query(statistics.TimeSeriesQuery{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Minute), GroupBy: "model"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Series) != 1 || result.Series[0].Key != "claude-sonnet-4-5-20250929" {
+		t.Fatalf("Expected the real model name in the time series, got %+v", result.Series)
+	}
+
+	// $3 × 2M + $15 × 1M = $21，达到 $20 上限
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer server.Close()
+	alerter := alerting.NewManager(config.AlertingConfig{
+		Enabled:            true,
+		EvaluationInterval: "1h",
+		Sinks:              []config.AlertSinkConfig{{Name: "hook", Type: "webhook", URL: server.URL}},
+		Rules:              []config.AlertRuleConfig{{Name: "spend", Type: alerting.RuleSpendCap, Threshold: 20, Window: "24h"}},
+	}, cfg.Pricing, &alertSource{endpointManager: manager})
+	alerter.Start()
+	alerter.Evaluate(time.Now())
+	alerter.Stop()
+
+	select {
+	case body := <-received:
+		if !strings.Contains(fmt.Sprint(body), "Spend cap reached: $21.00") {
+			t.Errorf("Expected spend cap alert for $21.00, got %v", body)
+		}
+	default:
+		t.Fatalf("Expected the spend cap rule to fire for a glob-priced model")
+	}
+}
@@ -2,9 +2,15 @@ package proxy
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
+	"claude-code-codex-companion/internal/tracing"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 
@@ -15,7 +21,26 @@ func (s *Server) loggingMiddleware() gin.HandlerFunc {
 		c.Set("request_id", requestID)
 		c.Set("start_time", start)
 
+		// 根 span：沿用入站 traceparent（若有），各阶段与上游尝试的 span 挂在其下
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.Tracer().Start(ctx, "cccc.request",
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithTimestamp(start),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("cccc.request_id", requestID),
+			))
+		c.Set(traceContextKey, ctx)
+
 		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
 	}
 }
 
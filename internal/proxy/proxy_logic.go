@@ -5,9 +5,11 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -15,20 +17,30 @@ import (
 
 	"claude-code-codex-companion/internal/conversion"
 	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/metrics"
 	"claude-code-codex-companion/internal/tagging"
+	"claude-code-codex-companion/internal/tracing"
+	"claude-code-codex-companion/internal/transcript"
 	"claude-code-codex-companion/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path string, requestBody []byte, requestID string, startTime time.Time, taggedRequest *tagging.TaggedRequest, attemptNumber int) (bool, bool) {
+	// 递归重试（Codex 格式探测、token 刷新、参数学习）会嵌套在当前 span 之下
+	span, endSpan := startSpan(c, "cccc.proxy_to_endpoint", trace.WithAttributes(endpointAttributes(ep)...))
+	defer endSpan()
+	span.SetAttributes(attribute.String("url.path", path))
+
 	// 检查是否为 count_tokens 请求到 OpenAI 端点
 	isCountTokensRequest := strings.Contains(path, "/count_tokens")
-	isOpenAIEndpoint := ep.EndpointType == "openai"
+	isOpenAIEndpoint := ep.GetFormat() == "openai"
 
-	// OpenAI 端点不支持 count_tokens，立即尝试下一个端点
-	if isCountTokensRequest && isOpenAIEndpoint {
-		s.logger.Debug(fmt.Sprintf("Skipping count_tokens request on OpenAI endpoint %s", ep.Name))
+	// OpenAI 端点不支持 count_tokens，立即尝试下一个端点（Bedrock InvokeModel 与 Vertex AI rawPredict 同样没有 count_tokens）
+	if isCountTokensRequest && (isOpenAIEndpoint || ep.EndpointType == "bedrock" || ep.EndpointType == "vertex-anthropic") {
+		s.logger.Debug(fmt.Sprintf("Skipping count_tokens request on %s endpoint %s", ep.EndpointType, ep.Name))
 		// 标记这次尝试为特殊情况，不记录健康统计，不记录日志（除非所有端点都因此失败）
 		c.Set("skip_health_record", true)
 		c.Set("skip_logging", true)
@@ -72,7 +84,8 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		}
 	}
 
-	// 应用模型重写（如果配置了）
+	// 应用模型重写（如果配置了）；每次尝试的端点不同，先清除上一次尝试的重写结果
+	c.Set("rewritten_model", "")
 	originalModel, rewrittenModel, err := s.modelRewriter.RewriteRequestWithTags(tempReq, ep.ModelRewrite, ep.Tags, clientType)
 	if err != nil {
 		s.logger.Error("Model rewrite failed", err)
@@ -85,6 +98,11 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		return false, false
 	}
 
+	// 指标按重写后的模型计数
+	if originalModel != "" && rewrittenModel != "" && originalModel != rewrittenModel {
+		c.Set("rewritten_model", rewrittenModel)
+	}
+
 	// 如果进行了模型重写，获取重写后的请求体
 	var finalRequestBody []byte
 	if originalModel != "" && rewrittenModel != "" {
@@ -101,6 +119,11 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	} else {
 		finalRequestBody = requestBody // 使用原始请求体
 	}
+	span.SetAttributes(
+		attribute.String("cccc.model.original", originalModel),
+		attribute.String("cccc.model.rewritten", rewrittenModel),
+		attribute.Bool("cccc.model.rewrite_applied", originalModel != "" && rewrittenModel != "" && originalModel != rewrittenModel),
+	)
 
 	// 格式转换（在模型重写之后）
 	// 关键修复：只有当请求格式与端点格式不匹配时才需要转换
@@ -119,7 +142,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	if formatDetection != nil && formatDetection.Format != utils.FormatUnknown {
 		// 有明确的格式检测结果
 		requestIsAnthropic := (formatDetection.Format == utils.FormatAnthropic)
-		endpointIsOpenAI := (ep.EndpointType == "openai")
+		endpointIsOpenAI := (ep.GetFormat() == "openai")
 
 		// Anthropic格式请求 + OpenAI端点 = 需要转换
 		// OpenAI格式请求 + OpenAI端点 = 不需要转换（直接透传）
@@ -144,11 +167,18 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		endpointInfo := &conversion.EndpointInfo{
 			Type:               ep.EndpointType,
 			MaxTokensFieldName: ep.MaxTokensFieldName,
+			ToolSchemaProfile:  ep.ToolSchemaProfile,
+			ToolArgumentValidation: ep.ToolArgumentValidation,
+			EnforceSingleToolCall:  ep.EnforceSingleToolCall,
 		}
 
+		conversionSpan, endConversion := startSpan(c, "cccc.request_conversion", trace.WithAttributes(attribute.String("cccc.conversion.stage", "request")))
 		convertedBody, ctx, err := s.converter.ConvertRequest(finalRequestBody, endpointInfo)
+		s.recordSpanError(conversionSpan, err)
+		endConversion()
 		if err != nil {
 			s.logger.Error("Request format conversion failed", err)
+			recordConversionFailure("request", err)
 			duration := time.Since(endpointStartTime)
 			s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, nil, nil, nil, duration, err, false, tags, "", originalModel, rewrittenModel, attemptNumber)
 			// Request转换失败是请求格式问题，不应该重试其他端点，直接返回错误
@@ -166,6 +196,15 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 			"converted_size": len(convertedBody),
 		})
 	} else {
+		// 其他上游的推理内容转换出的 thinking 块没有签名，客户端回传后会被 Anthropic 端点拒绝（如故障转移时）
+		if ep.GetFormat() == "anthropic" && (formatDetection == nil || formatDetection.Format == utils.FormatAnthropic) {
+			if strippedBody, stripped := conversion.StripUnsignedThinkingBlocks(finalRequestBody); stripped {
+				finalRequestBody = strippedBody
+				s.logger.Debug("Removed unsigned thinking blocks from request history", map[string]interface{}{
+					"endpoint": ep.Name,
+				})
+			}
+		}
 		s.logger.Debug("Skipping format conversion (not needed)", map[string]interface{}{
 			"request_format": func() string {
 				if formatDetection != nil {
@@ -184,7 +223,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	// - NativeCodexFormat == false: 端点需要 OpenAI 格式，执行转换
 	
 	codexNeedsConversion := false
-    if ep.EndpointType == "openai" && inboundPath == "/responses" {
+    if ep.ProbesNativeCodexFormat() && inboundPath == "/responses" {
 		if ep.NativeCodexFormat == nil {
 			// 首次请求，使用原生格式尝试（收到400后会自动转换并重试）
 			s.logger.Info("First /responses request to endpoint, trying native Codex format", map[string]interface{}{
@@ -204,6 +243,9 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 			})
 			codexNeedsConversion = true
 		}
+	} else if (ep.EndpointType == "gemini" || ep.EndpointType == "ollama") && inboundPath == "/responses" {
+		// Gemini、Ollama 端点不支持 Codex 格式，始终先转换为 OpenAI 格式再由上游适配器处理
+		codexNeedsConversion = true
 	}
 	
     	if codexNeedsConversion {
@@ -215,6 +257,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
         	}
         	convertedBody, err := s.convertCodexToOpenAI(finalRequestBody)
 		if err != nil {
+			recordConversionFailure("codex_request", err)
 			s.logger.Debug("Failed to convert Codex format to OpenAI", map[string]interface{}{
 				"error": err.Error(),
 			})
@@ -237,7 +280,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	}
 
 	// OpenAI user 参数长度限制 hack（在格式转换之后，参数覆盖之前）
-	if ep.EndpointType == "openai" {
+	if ep.EndpointType == "openai" || ep.EndpointType == "azure-openai" {
 		hackedBody, err := s.applyOpenAIUserLengthHack(finalRequestBody)
 		if err != nil {
 			s.logger.Debug("Failed to apply OpenAI user length hack", map[string]interface{}{
@@ -308,6 +351,52 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		}
 	}
 
+	// 原生协议端点（如 gemini、bedrock、vertex-anthropic、azure-openai、ollama）：将 OpenAI 格式请求转换为上游协议，并使用适配器生成的路径
+	var upstreamRequest *conversion.UpstreamRequest
+	vertexProjectID, vertexRegion := ep.GetVertexTarget()
+	azureAPIVersion, azureDeployments := ep.GetAzureDeployments()
+	ollamaOptions, ollamaKeepAlive := ep.GetOllamaOptions()
+	upstreamAdapter := conversion.NewUpstreamAdapter(&conversion.EndpointInfo{
+		Type:                 ep.EndpointType,
+		ToolSchemaProfile:    ep.ToolSchemaProfile,
+		GeminiSafetySettings: ep.GeminiSafetySettings,
+		VertexProjectID:      vertexProjectID,
+		VertexRegion:         vertexRegion,
+		AzureAPIVersion:      azureAPIVersion,
+		AzureDeployments:     azureDeployments,
+		OpenAIAPI:            ep.OpenAIAPI,
+		OllamaOptions:        ollamaOptions,
+		OllamaKeepAlive:      ollamaKeepAlive,
+	}, s.logger)
+	if upstreamAdapter != nil {
+		conversionSpan, endConversion := startSpan(c, "cccc.request_conversion", trace.WithAttributes(attribute.String("cccc.conversion.stage", "upstream_request")))
+		upstreamRequest, err = upstreamAdapter.AdaptRequest(finalRequestBody)
+		s.recordSpanError(conversionSpan, err)
+		endConversion()
+		if err != nil {
+			s.logger.Error("Upstream request adaptation failed", err)
+			recordConversionFailure("upstream_request", err)
+			duration := time.Since(endpointStartTime)
+			s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, nil, nil, nil, duration, err, false, tags, "", originalModel, rewrittenModel, attemptNumber)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Request format conversion failed", "details": err.Error()})
+			// 设置错误信息到context中
+			c.Set("last_error", err)
+			c.Set("last_status_code", http.StatusBadRequest)
+			return false, false // 不重试，直接返回
+		}
+		finalRequestBody = upstreamRequest.Body
+		effectivePath = upstreamRequest.Path
+		targetURL = ep.GetFullURL(effectivePath)
+		if len(upstreamRequest.Query) > 0 {
+			targetURL += "?" + upstreamRequest.Query.Encode()
+		}
+		s.logger.Debug("Request adapted for upstream protocol", map[string]interface{}{
+			"endpoint_type": ep.EndpointType,
+			"path":          effectivePath,
+			"streaming":     upstreamRequest.Streaming,
+		})
+	}
+
 	// 创建最终的HTTP请求
 	req, err := http.NewRequest(c.Request.Method, targetURL, bytes.NewReader(finalRequestBody))
 	if err != nil {
@@ -333,8 +422,8 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 
 	// 根据认证类型设置不同的认证头部
 	if ep.AuthType == "api_key" {
-		req.Header.Set("x-api-key", ep.AuthValue)
-	} else {
+		ep.SetAPIKey(req)
+	} else if ep.AuthType != "aws_sigv4" { // SigV4 签名在发送前最后计算
 		authHeader, err := ep.GetAuthHeaderWithRefreshCallback(s.config.Timeouts.ToProxyTimeoutConfig(), s.createOAuthTokenRefreshCallback())
 		if err != nil {
 			s.logger.Error(fmt.Sprintf("Failed to get auth header: %v", err), err)
@@ -373,8 +462,26 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		}
 	}
 
+	// 透传客户端查询参数；有适配器时合并到适配器生成的查询参数中，同名参数以已有值为准
 	if c.Request.URL.RawQuery != "" {
-		req.URL.RawQuery = c.Request.URL.RawQuery
+		if upstreamAdapter == nil {
+			req.URL.RawQuery = c.Request.URL.RawQuery
+		} else {
+			mergeClientQuery(req.URL, c.Request.URL.Query())
+		}
+	}
+
+	// SigV4 签名覆盖最终的 URL、头部与请求体，必须在所有修改之后进行
+	if ep.AuthType == "aws_sigv4" {
+		if err := ep.SignAWSRequest(req, finalRequestBody); err != nil {
+			s.logger.Error(fmt.Sprintf("Failed to sign AWS request: %v", err), err)
+			duration := time.Since(endpointStartTime)
+			s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, nil, nil, duration, err, s.isRequestExpectingStream(req), tags, "", originalModel, rewrittenModel, attemptNumber)
+			// 设置错误信息到context中
+			c.Set("last_error", err)
+			c.Set("last_status_code", http.StatusUnauthorized)
+			return false, true // 凭证问题只影响当前端点，尝试下一个端点
+		}
 	}
 
 	// 为这个端点创建支持代理的HTTP客户端
@@ -389,10 +496,23 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		return false, true
 	}
 
+	upstreamSpan, endUpstream := startSpan(c, "cccc.upstream_http", trace.WithSpanKind(trace.SpanKindClient))
+	upstreamSpan.SetAttributes(
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+		attribute.String("url.path", req.URL.Path),
+	)
+	tracing.Inject(traceContext(c), req.Header)
         resp, err := client.Do(req)
+	if err != nil {
+		s.recordSpanError(upstreamSpan, err)
+	} else {
+		upstreamSpan.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+	endUpstream()
         if err != nil {
             // 如果是首次对 OpenAI 端点的 /responses 请求发生网络级错误（如 EOF），视作不支持 responses，转换并改用 /chat/completions 重试
-            if ep.EndpointType == "openai" && inboundPath == "/responses" && ep.NativeCodexFormat == nil {
+            if ep.ProbesNativeCodexFormat() && inboundPath == "/responses" && ep.NativeCodexFormat == nil {
                 s.logger.Info("Network error on first /responses request - converting to OpenAI format and retrying /chat/completions", map[string]interface{}{
                     "endpoint": ep.Name,
                     "error":    err.Error(),
@@ -400,6 +520,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
                 falseValue := false
                 ep.NativeCodexFormat = &falseValue
                 if convertedBody, convertErr := s.convertCodexToOpenAI(requestBody); convertErr == nil && convertedBody != nil {
+                    span.AddEvent("retry: /responses network error, falling back to /chat/completions")
                     // 递归重试到 /chat/completions
                     return s.proxyToEndpoint(c, ep, "/chat/completions", convertedBody, requestID, startTime, taggedRequest, attemptNumber)
                 }
@@ -454,6 +575,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 				resp.Body.Close()
 
 				// Token刷新成功，递归重试相同的endpoint（重新走完整的请求流程）
+				span.AddEvent("retry: oauth token refreshed")
 				return s.proxyToEndpoint(c, ep, path, requestBody, requestID, startTime, taggedRequest, attemptNumber)
 			}
 		} else {
@@ -494,6 +616,8 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 				if wasModified {
 					// 使用清理后的请求体递归重试当前端点
 					s.logger.Debug("Retrying request after removing learned unsupported parameters")
+					span.AddEvent("retry: removed learned unsupported parameters", trace.WithAttributes(
+						attribute.StringSlice("cccc.learned_unsupported_params", ep.GetLearnedUnsupportedParams())))
 					return s.proxyToEndpoint(c, ep, path, cleanedBody, requestID, startTime, taggedRequest, attemptNumber)
 				}
 			}
@@ -503,7 +627,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
             // 如果是首个 /responses 请求且返回 4xx/5xx（排除 401/403 认证类），
             // 视为端点不支持原生 Codex /responses：转换为 OpenAI 格式并改走 /chat/completions 重试
             if (resp.StatusCode >= 400 && resp.StatusCode < 600 && resp.StatusCode != 401 && resp.StatusCode != 403) &&
-               ep.EndpointType == "openai" &&
+               ep.ProbesNativeCodexFormat() &&
                inboundPath == "/responses" &&
                ep.NativeCodexFormat == nil {
 			
@@ -519,6 +643,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 			convertedBody, convertErr := s.convertCodexToOpenAI(requestBody)
 			if convertErr != nil {
 				s.logger.Error("Failed to convert Codex format to OpenAI for retry", convertErr)
+				recordConversionFailure("codex_request", convertErr)
 				// 转换失败，记录日志并尝试下一个端点
 				s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, resp, decompressedBody, duration, nil, s.isRequestExpectingStream(req), tags, "", originalModel, rewrittenModel, attemptNumber)
 				c.Set("last_error", fmt.Errorf("format conversion failed: %v", convertErr))
@@ -533,6 +658,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 			// 关闭原响应
 			resp.Body.Close()
 			
+                span.AddEvent("retry: endpoint requires OpenAI format, falling back to /chat/completions")
                 // 用转换后的请求体重试（递归调用，会使用新的 NativeCodexFormat 配置）
                 // 同时切换到 /chat/completions 路径
                 return s.proxyToEndpoint(c, ep, "/chat/completions", convertedBody, requestID, startTime, taggedRequest, attemptNumber)
@@ -546,7 +672,9 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		return false, true
 	}
 
-	responseBody, err := io.ReadAll(resp.Body)
+	// 记录首字节到达时间，用于流式响应的首 token 耗时指标
+	bodyReader := &firstByteReader{ReadCloser: resp.Body}
+	responseBody, err := io.ReadAll(bodyReader)
 	if err != nil {
 		s.logger.Error("Failed to read response body", err)
 		// 记录读取响应体失败的日志
@@ -574,6 +702,30 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		return false, false
 	}
 
+	// 将上游原生协议响应转换回 OpenAI 格式，后续校验与转换按 OpenAI 端点处理
+	if upstreamAdapter != nil {
+		conversionSpan, endConversion := startSpan(c, "cccc.response_conversion", trace.WithAttributes(attribute.String("cccc.conversion.stage", "upstream_response")))
+		adaptedBody, err := upstreamAdapter.AdaptResponse(decompressedBody, upstreamRequest.Streaming)
+		s.recordSpanError(conversionSpan, err)
+		endConversion()
+		if err != nil {
+			s.logger.Error("Upstream response adaptation failed", err)
+			recordConversionFailure("upstream_response", err)
+			duration := time.Since(endpointStartTime)
+			adaptError := fmt.Sprintf("Upstream response adaptation failed: %v", err)
+			s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, resp, decompressedBody, duration, fmt.Errorf(adaptError), upstreamRequest.Streaming, tags, "", originalModel, rewrittenModel, attemptNumber)
+			// 设置错误信息到context中
+			c.Set("last_error", fmt.Errorf(adaptError))
+			c.Set("last_status_code", resp.StatusCode)
+			return false, true
+		}
+		decompressedBody = adaptedBody
+		// 适配后的流式响应统一为 SSE（Bedrock 原始为 application/vnd.amazon.eventstream）
+		if upstreamRequest.Streaming {
+			resp.Header.Set("Content-Type", "text/event-stream")
+		}
+	}
+
 	// 智能检测内容类型并自动覆盖
 	currentContentType := resp.Header.Get("Content-Type")
 	newContentType, overrideInfo := s.validator.SmartDetectContentType(decompressedBody, currentContentType, resp.StatusCode)
@@ -587,6 +739,10 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 
 	// 判断是否为流式响应（基于最终的Content-Type）
 	isStreaming := strings.Contains(strings.ToLower(finalContentType), "text/event-stream")
+	if isStreaming && !bodyReader.firstByteAt.IsZero() {
+		span.AddEvent("first_byte", trace.WithTimestamp(bodyReader.firstByteAt))
+		metrics.TimeToFirstToken.WithLabelValues(ep.Name, metrics.ValueOrUnknown(originalModel)).Observe(bodyReader.firstByteAt.Sub(endpointStartTime).Seconds())
+	}
 
 	// 添加调试日志
 	if len(decompressedBody) > 0 && len(decompressedBody) < 500 {
@@ -620,7 +776,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	}
 
 	// 严格 Anthropic 格式验证已永久启用
-	if err := s.validator.ValidateResponseWithPath(decompressedBody, isStreaming, ep.EndpointType, path, ep.URL); err != nil {
+	if err := s.validator.ValidateResponseWithPath(decompressedBody, isStreaming, ep.GetFormat(), path, ep.URL); err != nil {
 		// 如果是usage统计验证失败，尝试下一个endpoint
 		if strings.Contains(err.Error(), "invalid usage stats") {
 			s.logger.Info(fmt.Sprintf("Usage validation failed for endpoint %s: %v", ep.Name, err))
@@ -662,12 +818,22 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	convertedResponseBody := decompressedBody
 	if conversionContext != nil {
 		s.logger.Info(fmt.Sprintf("Starting response conversion. Streaming: %v, OriginalSize: %d", isStreaming, len(decompressedBody)))
+		conversionSpan, endConversion := startSpan(c, "cccc.response_conversion", trace.WithAttributes(attribute.String("cccc.conversion.stage", "response")))
 		convertedResp, err := s.converter.ConvertResponse(decompressedBody, conversionContext, isStreaming)
+		s.recordSpanError(conversionSpan, err)
+		endConversion()
 		if err != nil {
 			s.logger.Error("Response format conversion failed", err)
+			recordConversionFailure("response", err)
 			// Response转换失败，记录错误并尝试下一个端点
 			duration := time.Since(endpointStartTime)
 			conversionError := fmt.Sprintf("Response format conversion failed: %v", err)
+			// 工具参数无法修复且策略为 retry 时，原地重试当前端点（failover 按普通转换失败切换端点）
+			var convErr *conversion.ConversionError
+			if errors.As(err, &convErr) && convErr.Type == conversion.ToolArgumentsInvalidErrorType &&
+				conversionContext.ToolArgumentValidation == conversion.ToolArgumentValidationRetry {
+				conversionError = fmt.Sprintf("Invalid tool arguments, retrying endpoint: %v", err)
+			}
 			s.logSimpleRequest(requestID, ep.URL, c.Request.Method, path, requestBody, finalRequestBody, c, req, resp, decompressedBody, duration, fmt.Errorf(conversionError), isStreaming, tags, "", originalModel, rewrittenModel, attemptNumber)
 			// 设置错误信息到context中
 			c.Set("last_error", fmt.Errorf(conversionError))
@@ -733,7 +899,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 			isCodexClient = (fd.ClientType == utils.ClientCodex)
 		}
 
-		if ep.EndpointType == "openai" && isCodexClient {
+		if ep.GetFormat() == "openai" && isCodexClient {
 			s.logger.Info("Converting chat completions SSE to Responses API format for Codex", map[string]interface{}{
 				"endpoint_type": ep.EndpointType,
 				"client_type":   "codex",
@@ -750,9 +916,15 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	c.Set("last_error", nil)
 	c.Set("last_status_code", resp.StatusCode)
 
+	// 记录 token 用量，供时间序列统计使用
+	if usage, ok := transcript.ParseUsage(finalResponseBody); ok {
+		c.Set("response_usage", usage)
+	}
+
 	duration := time.Since(endpointStartTime)
 	// 创建日志条目，记录修改前后的完整数据
 	requestLog := s.logger.CreateRequestLog(requestID, ep.URL, c.Request.Method, path)
+	requestLog.TraceID = traceIDFromContext(c)
 	requestLog.RequestBodySize = len(requestBody)
 	requestLog.Tags = tags
 	requestLog.ContentTypeOverride = overrideInfo
@@ -766,6 +938,11 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 		}
 	}
 
+	// 记录工具参数修复
+	if conversionContext != nil && len(conversionContext.ToolArgumentRepairs) > 0 {
+		requestLog.ToolArgumentRepairs = conversionContext.ToolArgumentRepairs
+	}
+
 	// 设置格式检测信息
 	if formatDetection, exists := c.Get("format_detection"); exists {
 		if detection, ok := formatDetection.(*utils.FormatDetectionResult); ok && detection != nil {
@@ -836,7 +1013,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	}
 
 	// 动态API格式学习 - 根据成功响应更新端点格式偏好
-	if formatDetection != nil && formatDetection.ClientType == utils.ClientCodex && ep.EndpointType == "openai" {
+	if formatDetection != nil && formatDetection.ClientType == utils.ClientCodex && ep.ProbesNativeCodexFormat() {
 		// 只有当 /responses 路径成功时，才标记端点支持原生 Codex 格式
 		// /chat/completions 成功不代表支持 /responses
 		if inboundPath == "/responses" {
@@ -879,7 +1056,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 	s.logger.LogRequest(requestLog)
 
         // 🔍 自动探测成功：如果是首次 /responses 请求且成功，标记为支持原生 Codex 格式
-        if ep.EndpointType == "openai" && inboundPath == "/responses" && ep.NativeCodexFormat == nil {
+        if ep.ProbesNativeCodexFormat() && inboundPath == "/responses" && ep.NativeCodexFormat == nil {
             trueValue := true
             ep.NativeCodexFormat = &trueValue
             s.logger.Info("Auto-detected: endpoint natively supports Codex format", map[string]interface{}{
@@ -1282,16 +1459,26 @@ func (s *Server) convertChatCompletionsToResponsesSSE(body []byte) []byte {
 
 		// 结束事件：response.completed
 		if finishReason != "" {
+			status, incompleteReason := conversion.MapFinishReasonToResponsesStatus(finishReason)
+			response := map[string]interface{}{
+				"id":            responseID,
+				"object":        "response",
+				"created":       created,
+				"model":         model,
+				"status":        status,
+				"finish_reason": finishReason,
+			}
+			eventType := "response.completed"
+			if incompleteReason != "" {
+				// 因长度或内容过滤截断时按 Responses API 规范输出 incomplete_details
+				eventType = "response.incomplete"
+				response["incomplete_details"] = map[string]interface{}{
+					"reason": incompleteReason,
+				}
+			}
 			event := map[string]interface{}{
-				"type": "response.completed",
-				"response": map[string]interface{}{
-					"id":            responseID,
-					"object":        "response",
-					"created":       created,
-					"model":         model,
-					"status":        "completed",
-					"finish_reason": finishReason,
-				},
+				"type":     eventType,
+				"response": response,
 			}
 			eventJSON, _ := json.Marshal(event)
 			convertedLines = append(convertedLines, "data: "+string(eventJSON))
@@ -1436,6 +1623,42 @@ func (s *Server) updateEndpointCodexSupport(ep *endpoint.Endpoint, isCodex bool)
 	s.logger.Info(fmt.Sprintf("Updated endpoint %s native_codex_support to %v", ep.Name, isCodex))
 }
 
+// toolSchemaErrorMessages 上游拒绝工具 JSON Schema 时的错误消息特征（已转小写）
+var toolSchemaErrorMessages = []string{
+	"invalid schema for function", // OpenAI
+	"invalid_function_parameters", // OpenAI 错误码出现在消息中
+	"json schema is invalid",      // Anthropic: tools.N.custom.input_schema
+	"function_declarations",       // Gemini: tools[0].function_declarations[0].parameters
+}
+
+// mergeClientQuery 将客户端查询参数合并到上游 URL，不覆盖上游 URL 中已有的参数
+// 客户端传入的 key 不透传：上游认证由端点配置决定，密钥也不应出现在上游 URL 中
+func mergeClientQuery(target *url.URL, clientQuery url.Values) {
+	query := target.Query()
+	for name, values := range clientQuery {
+		if _, exists := query[name]; exists || name == "key" {
+			continue
+		}
+		query[name] = values
+	}
+	target.RawQuery = query.Encode()
+}
+
+// isToolSchemaError 判断错误是否为工具 JSON Schema 不合法：优先匹配错误码，其次匹配已知的错误消息
+func isToolSchemaError(errorData map[string]interface{}, errorMsgLower string) bool {
+	if errObj, ok := errorData["error"].(map[string]interface{}); ok {
+		if code, ok := errObj["code"].(string); ok && code == "invalid_function_parameters" {
+			return true
+		}
+	}
+	for _, message := range toolSchemaErrorMessages {
+		if strings.Contains(errorMsgLower, message) {
+			return true
+		}
+	}
+	return false
+}
+
 // 🎓 从400错误响应中学习不支持的参数
 func (s *Server) learnUnsupportedParamsFromError(errorBody []byte, ep *endpoint.Endpoint, requestBody []byte) {
 	if ep == nil || len(errorBody) == 0 {
@@ -1487,6 +1710,17 @@ func (s *Server) learnUnsupportedParamsFromError(errorBody []byte, ep *endpoint.
 
 	errorMsgLower := strings.ToLower(errorMsg)
 
+	// 工具 JSON Schema 不合法说明端点支持工具，只是 schema 需要规范化（见 tool_schema_profile），
+	// 不能学习为不支持 tools 或其他参数
+	if isToolSchemaError(errorData, errorMsgLower) {
+		s.logger.Info("Tool schema rejected by endpoint, consider adjusting tool_schema_profile", map[string]interface{}{
+			"endpoint":            ep.Name,
+			"tool_schema_profile": ep.ToolSchemaProfile,
+			"error_msg":           errorMsg,
+		})
+		return
+	}
+
 	// 检查每个模式
 	for _, pattern := range unsupportedPatterns {
 		matched := false
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestIsToolSchemaError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		expected bool
+	}{
+		{
+			name:     "openai invalid schema",
+			body:     `{"error":{"message":"Invalid schema for function 'edit': 'format' is not permitted.","type":"invalid_request_error"}}`,
+			expected: true,
+		},
+		{
+			name:     "openai error code",
+			body:     `{"error":{"message":"Invalid parameters","code":"invalid_function_parameters"}}`,
+			expected: true,
+		},
+		{
+			name:     "anthropic input schema",
+			body:     `{"type":"error","error":{"type":"invalid_request_error","message":"tools.0.custom.input_schema: JSON schema is invalid"}}`,
+			expected: true,
+		},
+		{
+			name:     "gemini function declarations",
+			body:     `{"error":{"code":400,"message":"Invalid JSON payload received. Unknown name \"additionalProperties\" at 'tools[0].function_declarations[0].parameters'"}}`,
+			expected: true,
+		},
+		{
+			name:     "unsupported tools",
+			body:     `{"error":{"message":"tools is not supported by this model"}}`,
+			expected: false,
+		},
+		{
+			name:     "unrelated schema mention",
+			body:     `{"error":{"message":"response_format json_schema is not supported"}}`,
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var errorData map[string]interface{}
+			if err := json.Unmarshal([]byte(tc.body), &errorData); err != nil {
+				t.Fatalf("Invalid test body: %v", err)
+			}
+			message := errorData["error"].(map[string]interface{})["message"].(string)
+			if got := isToolSchemaError(errorData, strings.ToLower(message)); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMergeClientQuery(t *testing.T) {
+	target, _ := url.Parse("https://generativelanguage.googleapis.com/v1beta/models/gemini:streamGenerateContent?alt=sse")
+	mergeClientQuery(target, url.Values{"key": {"client-key"}, "alt": {"json"}, "trace": {"1"}})
+
+	query := target.Query()
+	if query.Get("alt") != "sse" {
+		t.Errorf("Expected upstream parameters to take precedence, got %s", target.RawQuery)
+	}
+	if query.Has("key") {
+		t.Errorf("Expected client key parameter not to be forwarded, got %s", target.RawQuery)
+	}
+	if query.Get("trace") != "1" {
+		t.Errorf("Expected client parameter to be forwarded, got %s", target.RawQuery)
+	}
+}
@@ -40,6 +40,8 @@ func (s *Server) processRequestTags(req *http.Request) *tagging.TaggedRequest {
 	}
 	
 	if taggedRequest != nil {
+		recordTaggerMetrics(taggedRequest)
+
 		// 记录详细的tagging结果
 		s.logger.Debug(fmt.Sprintf("Tagging completed: found %d tags: %v", len(taggedRequest.Tags), taggedRequest.Tags))
 		for _, result := range taggedRequest.TaggerResults {
@@ -1,18 +1,22 @@
 package proxy
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
+	"claude-code-codex-companion/internal/alerting"
 	"claude-code-codex-companion/internal/config"
 	"claude-code-codex-companion/internal/conversion"
 	"claude-code-codex-companion/internal/endpoint"
 	"claude-code-codex-companion/internal/health"
 	"claude-code-codex-companion/internal/i18n"
 	"claude-code-codex-companion/internal/logger"
+	"claude-code-codex-companion/internal/metrics"
 	"claude-code-codex-companion/internal/modelrewrite"
 	"claude-code-codex-companion/internal/statistics"
 	"claude-code-codex-companion/internal/tagging"
+	"claude-code-codex-companion/internal/tracing"
 	"claude-code-codex-companion/internal/validator"
 	"claude-code-codex-companion/internal/web"
 
@@ -25,6 +29,7 @@ type Server struct {
 	logger          *logger.Logger
 	validator       *validator.ResponseValidator
 	healthChecker   *health.Checker
+	oauthRefresher  *endpoint.OAuthRefresher // 新增：后台 OAuth token 刷新器
 	adminServer     *web.AdminServer
 	taggingManager  *tagging.Manager       // 新增：tagging系统管理器
 	modelRewriter   *modelrewrite.Rewriter // 新增：模型重写器
@@ -33,6 +38,23 @@ type Server struct {
 	router          *gin.Engine
 	configFilePath  string
 	configMutex     sync.Mutex             // 新增：保护配置文件操作的互斥锁
+	shutdownTracing func(context.Context) error // 新增：刷新并关闭 span 导出器
+	alerter         *alerting.Manager           // 端点状态告警
+}
+
+// resolveSamplingEndpoints 日志中记录的是端点 URL，将采样规则中的端点名称替换为对应 URL
+func resolveSamplingEndpoints(rules []config.LogSamplingRule, endpoints []config.EndpointConfig) []config.LogSamplingRule {
+	resolved := make([]config.LogSamplingRule, len(rules))
+	for i, rule := range rules {
+		for _, ep := range endpoints {
+			if ep.Name == rule.Endpoint {
+				rule.Endpoint = ep.URL
+				break
+			}
+		}
+		resolved[i] = rule
+	}
+	return resolved
 }
 
 func NewServer(cfg *config.Config, configFilePath string, version string) (*Server, error) {
@@ -42,6 +64,11 @@ func NewServer(cfg *config.Config, configFilePath string, version string) (*Serv
 		LogRequestBody:  cfg.Logging.LogRequestBody,
 		LogResponseBody: cfg.Logging.LogResponseBody,
 		LogDirectory:    cfg.Logging.LogDirectory,
+		BodyCompression: cfg.Logging.BodyCompression,
+		MaxStorageMB:    cfg.Logging.MaxStorageMB,
+		Retention:       cfg.Logging.Retention,
+		Sampling:        resolveSamplingEndpoints(cfg.Logging.Sampling, cfg.Endpoints),
+		Redaction:       cfg.Logging.Redaction,
 	}
 
 	log, err := logger.NewLogger(logConfig)
@@ -55,6 +82,12 @@ func NewServer(cfg *config.Config, configFilePath string, version string) (*Serv
 	}
 	responseValidator := validator.NewResponseValidator()
 
+	// 初始化链路追踪（未启用时只解析入站 traceparent）
+	shutdownTracing, err := tracing.Init(cfg.Tracing, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %v", err)
+	}
+
 	// 初始化tagging系统
 	taggingManager := tagging.NewManager()
 	if err := taggingManager.Initialize(&cfg.Tagging); err != nil {
@@ -101,6 +134,7 @@ func NewServer(cfg *config.Config, configFilePath string, version string) (*Serv
 		converter:       converter,      // 新增：设置格式转换器
 		i18nManager:     i18nManager,    // 新增：设置国际化管理器
 		configFilePath:  configFilePath,
+		shutdownTracing: shutdownTracing,
 	}
 
 	// 设置热更新处理器
@@ -109,6 +143,32 @@ func NewServer(cfg *config.Config, configFilePath string, version string) (*Serv
 	// 让端点管理器使用同一个健康检查器
 	endpointManager.SetHealthChecker(healthChecker)
 
+	// 启动后台 OAuth token 刷新，刷新后的 token 通过 saveConfigToFile 原子地写回配置文件
+	server.oauthRefresher = endpoint.NewOAuthRefresher(endpointManager, server.currentTimeouts, server.createOAuthTokenRefreshCallback())
+	server.oauthRefresher.Start()
+
+	// 指标采集时从端点管理器读取端点状态
+	metrics.SetEndpointStateSource(server.endpointMetricsStates)
+
+	// 启动告警，拉黑事件由下方的状态监听器触发，其余规则定期检查
+	server.alerter = alerting.NewManager(cfg.Alerting, cfg.Pricing, &alertSource{endpointManager: endpointManager})
+	server.alerter.Start()
+
+	// 端点状态变化推送到实时日志流
+	endpoint.SetStatusChangeListener(func(ep *endpoint.Endpoint, change endpoint.StatusChange) {
+		log.PublishEndpointStatus(logger.EndpointStatusEvent{
+			Name:   ep.Name,
+			URL:    ep.URL,
+			Change: change.Change,
+			Status: change.Status,
+			Reason: change.Reason,
+			Param:  change.Param,
+		})
+		if change.Change == endpoint.ChangeBlacklisted {
+			server.alerter.EndpointBlacklisted(ep.Name, change.Reason)
+		}
+	})
+
 	server.setupRoutes()
 	return server, nil
 }
@@ -134,6 +194,15 @@ func (s *Server) setupRoutes() {
 	// 支持 Codex 的 /responses 路径
 	s.router.Any("/responses", s.loggingMiddleware(), s.handleProxy)
 	s.router.Any("/chat/completions", s.loggingMiddleware(), s.handleProxy)
+
+	// Prometheus 指标端点（路径在启动时确定，访问限制支持热更新）
+	if !s.config.Metrics.Disabled {
+		metricsPath := s.config.Metrics.Path
+		if metricsPath == "" {
+			metricsPath = "/metrics"
+		}
+		s.router.GET(metricsPath, s.handleMetrics)
+	}
 }
 
 func (s *Server) Start() error {
@@ -158,6 +227,23 @@ func (s *Server) GetHealthChecker() *health.Checker {
 	return s.healthChecker
 }
 
+// ShutdownTracing 刷新尚未导出的 span 并关闭导出器
+func (s *Server) ShutdownTracing(ctx context.Context) error {
+	return s.shutdownTracing(ctx)
+}
+
+// StopOAuthRefresher 停止后台 OAuth token 刷新
+func (s *Server) StopOAuthRefresher() {
+	if s.oauthRefresher != nil {
+		s.oauthRefresher.Stop()
+	}
+}
+
+// FlushStatistics 将内存中尚未写入的时间序列统计写入数据库
+func (s *Server) FlushStatistics() error {
+	return s.endpointManager.GetTimeSeries().Flush()
+}
+
 // HotUpdateConfig safely updates configuration without restarting the server
 func (s *Server) HotUpdateConfig(newConfig *config.Config) error {
 	// 验证新配置
@@ -173,13 +259,16 @@ func (s *Server) HotUpdateConfig(newConfig *config.Config) error {
 	}
 
 	// 更新日志配置（如果可能）
-	if err := s.updateLoggingConfig(newConfig.Logging); err != nil {
+	if err := s.updateLoggingConfig(newConfig.Logging, newConfig.Endpoints); err != nil {
 		s.logger.Error("Failed to update logging config, continuing with endpoint updates", err)
 	}
 
 	// 更新验证器配置
 	s.updateValidatorConfig(newConfig.Validation)
 
+	// 更新告警配置
+	s.alerter.UpdateConfig(newConfig.Alerting, newConfig.Pricing)
+
 	// 更新内存中的配置（需要锁保护，因为可能与其他配置更新并发）
 	s.configMutex.Lock()
 	s.config = newConfig
@@ -204,6 +293,11 @@ func (s *Server) validateConfigForHotUpdate(newConfig *config.Config) error {
 		return fmt.Errorf("at least one endpoint must be configured")
 	}
 
+	// command 告警 sink 只能在启动时从配置文件加载
+	if err := config.ValidateCommandSinksUnchanged(s.config.Alerting, newConfig.Alerting); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -214,17 +308,27 @@ func (s *Server) updateEndpoints(newEndpoints []config.EndpointConfig) error {
 }
 
 // updateLoggingConfig updates logging configuration if possible
-func (s *Server) updateLoggingConfig(newLogging config.LoggingConfig) error {
-	// 目前只能更新日志级别和记录策略，不能更换日志目录
+func (s *Server) updateLoggingConfig(newLogging config.LoggingConfig, endpoints []config.EndpointConfig) error {
+	// 目前只能更新日志级别、记录策略以及保留/采样/脱敏规则，不能更换日志目录
 	if newLogging.LogDirectory != s.config.Logging.LogDirectory {
 		return fmt.Errorf("log directory cannot be changed via hot update")
 	}
 
+	// 保留、采样、脱敏规则应用到运行中的 Logger；采样规则中的端点名称按新的端点配置解析
+	if s.logger != nil {
+		if err := s.logger.UpdatePolicies(newLogging.Retention, resolveSamplingEndpoints(newLogging.Sampling, endpoints), newLogging.Redaction); err != nil {
+			return err
+		}
+	}
+
 	// 可以安全更新的日志配置
 	s.config.Logging.Level = newLogging.Level
 	s.config.Logging.LogRequestTypes = newLogging.LogRequestTypes
 	s.config.Logging.LogRequestBody = newLogging.LogRequestBody
 	s.config.Logging.LogResponseBody = newLogging.LogResponseBody
+	s.config.Logging.Retention = newLogging.Retention
+	s.config.Logging.Sampling = newLogging.Sampling
+	s.config.Logging.Redaction = newLogging.Redaction
 
 	return nil
 }
@@ -262,6 +366,13 @@ func (s *Server) updateEndpointConfig(endpointName string, updateFunc func(*conf
 	return fmt.Errorf("endpoint not found: %s", endpointName)
 }
 
+// currentTimeouts 获取当前（可能已热更新的）超时配置
+func (s *Server) currentTimeouts() config.TimeoutConfig {
+	s.configMutex.Lock()
+	defer s.configMutex.Unlock()
+	return s.config.Timeouts
+}
+
 // createOAuthTokenRefreshCallback 创建 OAuth token 刷新后的回调函数
 func (s *Server) createOAuthTokenRefreshCallback() func(*endpoint.Endpoint) error {
 	return func(ep *endpoint.Endpoint) error {
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceContextKey gin context 中保存当前活跃 span 的 context
+// 不替换 c.Request，避免与重建请求体等对 c.Request 的修改互相覆盖
+const traceContextKey = "trace_context"
+
+// traceContext 返回当前活跃 span 的 context
+func traceContext(c *gin.Context) context.Context {
+	if value, exists := c.Get(traceContextKey); exists {
+		if ctx, ok := value.(context.Context); ok {
+			return ctx
+		}
+	}
+	return c.Request.Context()
+}
+
+// currentSpan 返回当前活跃的 span
+func currentSpan(c *gin.Context) trace.Span {
+	return trace.SpanFromContext(traceContext(c))
+}
+
+// startSpan 以当前活跃 span 为父创建子 span 并设为活跃；返回的 end 结束该 span 并恢复父 span
+func startSpan(c *gin.Context, name string, opts ...trace.SpanStartOption) (trace.Span, func()) {
+	parent := traceContext(c)
+	ctx, span := tracing.Tracer().Start(parent, name, opts...)
+	c.Set(traceContextKey, ctx)
+	return span, func() {
+		span.End()
+		c.Set(traceContextKey, parent)
+	}
+}
+
+// traceIDFromContext 返回当前请求的 trace ID，写入请求日志
+func traceIDFromContext(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	return tracing.TraceID(traceContext(c))
+}
+
+// endpointAttributes 端点相关的 span 属性
+func endpointAttributes(ep *endpoint.Endpoint) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("cccc.endpoint.name", ep.Name),
+		attribute.String("cccc.endpoint.type", ep.EndpointType),
+	}
+}
+
+// recordSpanError 将 span 标记为失败
+// 错误信息可能包含上游 URL 和密钥，按日志脱敏规则处理后再导出
+func (s *Server) recordSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	message := err.Error()
+	if s.logger != nil {
+		message = s.logger.RedactText(message)
+	}
+	// 与 span.RecordError 记录的 exception 事件一致，只是消息经过脱敏
+	span.AddEvent("exception", trace.WithAttributes(
+		attribute.String("exception.type", fmt.Sprintf("%T", err)),
+		attribute.String("exception.message", message),
+	))
+	span.SetStatus(codes.Error, message)
+}
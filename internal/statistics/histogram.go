@@ -0,0 +1,98 @@
+package statistics
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// histogramGrowth is the ratio between adjacent bucket bounds; percentiles
+// read from the histogram are accurate to within about 5%.
+const histogramGrowth = 1.05
+
+var logHistogramGrowth = math.Log(histogramGrowth)
+
+// LatencyHistogram is a mergeable log-bucketed histogram of latencies in
+// milliseconds, similar to an HDR histogram with fixed relative precision.
+// Keys are bucket indexes; bucket i covers (growth^(i-1), growth^i].
+type LatencyHistogram map[int]int64
+
+// bucketIndex returns the bucket index for a latency in milliseconds
+func bucketIndex(ms int64) int {
+	if ms <= 1 {
+		return 0
+	}
+	return int(math.Ceil(math.Log(float64(ms)) / logHistogramGrowth))
+}
+
+// bucketUpperBound returns the upper bound of a bucket in milliseconds
+func bucketUpperBound(index int) float64 {
+	return math.Pow(histogramGrowth, float64(index))
+}
+
+// Observe records one latency in milliseconds
+func (h LatencyHistogram) Observe(ms int64) {
+	h[bucketIndex(ms)]++
+}
+
+// Merge adds all counts from another histogram
+func (h LatencyHistogram) Merge(other LatencyHistogram) {
+	for index, count := range other {
+		h[index] += count
+	}
+}
+
+// Count returns the total number of observations
+func (h LatencyHistogram) Count() int64 {
+	var total int64
+	for _, count := range h {
+		total += count
+	}
+	return total
+}
+
+// Quantile returns the latency in milliseconds at quantile q (0-1),
+// reported as the upper bound of the bucket containing it
+func (h LatencyHistogram) Quantile(q float64) float64 {
+	total := h.Count()
+	if total == 0 {
+		return 0
+	}
+
+	indexes := make([]int, 0, len(h))
+	for index := range h {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	rank := int64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for _, index := range indexes {
+		seen += h[index]
+		if seen >= rank {
+			return bucketUpperBound(index)
+		}
+	}
+	return bucketUpperBound(indexes[len(indexes)-1])
+}
+
+// encodeHistogram serializes a histogram for storage
+func encodeHistogram(h LatencyHistogram) string {
+	if len(h) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(h)
+	return string(data)
+}
+
+// decodeHistogram parses a stored histogram; invalid data yields an empty histogram
+func decodeHistogram(data string) LatencyHistogram {
+	h := make(LatencyHistogram)
+	if data != "" {
+		json.Unmarshal([]byte(data), &h)
+	}
+	return h
+}
@@ -29,6 +29,9 @@ type StatisticsManager interface {
 	// GetStatisticsSummary returns a summary of all statistics
 	GetStatisticsSummary() (map[string]interface{}, error)
 	
+	// TimeSeries returns the time-series rollup store, or nil when unavailable
+	TimeSeries() *TimeSeriesStore
+	
 	// Close closes any resources used by the statistics manager
 	Close() error
 	
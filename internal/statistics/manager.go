@@ -15,8 +15,9 @@ import (
 
 // Manager manages endpoint statistics persistence using SQLite
 type Manager struct {
-	db     *gorm.DB
-	dbPath string
+	db         *gorm.DB
+	dbPath     string
+	timeSeries *TimeSeriesStore
 }
 
 // NewManager creates a new statistics manager with independent statistics.db
@@ -84,9 +85,16 @@ func NewManager(dataDirectory string) (*Manager, error) {
 		return nil, fmt.Errorf("failed to create additional indexes: %v", err)
 	}
 
+	// Time-series rollups share statistics.db
+	timeSeries, err := NewTimeSeriesStore(db)
+	if err != nil {
+		return nil, err
+	}
+
 	manager := &Manager{
-		db:     db,
-		dbPath: dbPath,
+		db:         db,
+		dbPath:     dbPath,
+		timeSeries: timeSeries,
 	}
 
 	return manager, nil
@@ -295,8 +303,16 @@ func (m *Manager) GetStatisticsSummary() (map[string]interface{}, error) {
 	return summary, nil
 }
 
+// TimeSeries returns the time-series rollup store
+func (m *Manager) TimeSeries() *TimeSeriesStore {
+	return m.timeSeries
+}
+
 // Close closes the database connection
 func (m *Manager) Close() error {
+	if err := m.timeSeries.Close(); err != nil {
+		fmt.Printf("WARNING: %v\n", err)
+	}
 	if m.db != nil {
 		sqlDB, err := m.db.DB()
 		if err != nil {
@@ -189,6 +189,11 @@ func (m *MemoryManager) GetStatisticsSummary() (map[string]interface{}, error) {
 	return summary, nil
 }
 
+// TimeSeries returns nil: time-series rollups require persistent storage
+func (m *MemoryManager) TimeSeries() *TimeSeriesStore {
+	return nil
+}
+
 // Close is a no-op for memory manager
 func (m *MemoryManager) Close() error {
 	return nil
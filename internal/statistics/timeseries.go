@@ -0,0 +1,645 @@
+package statistics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Rollup resolutions
+const (
+	ResolutionMinute = "minute"
+	ResolutionHour   = "hour"
+	ResolutionDay    = "day"
+)
+
+var resolutionSteps = map[string]time.Duration{
+	ResolutionMinute: time.Minute,
+	ResolutionHour:   time.Hour,
+	ResolutionDay:    24 * time.Hour,
+}
+
+// Downsampling schedule: minute buckets older than 48 hours are merged into
+// hour buckets, hour buckets older than 30 days into day buckets (UTC days),
+// and day buckets are kept for 400 days
+const (
+	minuteRollupRetention    = 48 * time.Hour
+	hourRollupRetention      = 30 * 24 * time.Hour
+	dayRollupRetention       = 400 * 24 * time.Hour
+	rollupFlushInterval      = 10 * time.Second
+	rollupDownsampleInterval = 10 * time.Minute
+	maxTimeSeriesPoints      = 2000
+)
+
+var (
+	// ErrInvalidTimeSeriesQuery is returned for malformed time-series queries
+	ErrInvalidTimeSeriesQuery = errors.New("invalid time-series query")
+	// ErrTimeSeriesUnavailable is returned when statistics are kept in memory only
+	ErrTimeSeriesUnavailable = errors.New("time-series statistics are unavailable")
+)
+
+// TimeSeriesSample is the outcome of one upstream attempt
+type TimeSeriesSample struct {
+	Time          time.Time
+	Endpoint      string // endpoint name
+	Model         string
+	ClientType    string
+	Success       bool
+	ErrorCategory string // empty for successful attempts
+	Latency       time.Duration
+
+	InputTokens      int64
+	OutputTokens     int64
+	CacheReadTokens  int64
+	CacheWriteTokens int64
+}
+
+// StatsRollup is one aggregated time bucket for an endpoint, model and client type
+// This corresponds to the stats_rollups table in statistics.db
+type StatsRollup struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Resolution  string `gorm:"column:resolution;size:10;not null;uniqueIndex:idx_stats_rollup_key,priority:1"`
+	BucketStart int64  `gorm:"column:bucket_start;not null;uniqueIndex:idx_stats_rollup_key,priority:2"` // Unix seconds, UTC aligned
+	Endpoint    string `gorm:"column:endpoint;size:100;not null;uniqueIndex:idx_stats_rollup_key,priority:3"`
+	Model       string `gorm:"column:model;size:200;not null;uniqueIndex:idx_stats_rollup_key,priority:4"`
+	ClientType  string `gorm:"column:client_type;size:50;not null;uniqueIndex:idx_stats_rollup_key,priority:5"`
+
+	Requests        int64  `gorm:"column:requests;default:0;not null"`
+	Successes       int64  `gorm:"column:successes;default:0;not null"`
+	Errors          int64  `gorm:"column:errors;default:0;not null"`
+	ErrorCategories string `gorm:"column:error_categories;type:text"` // JSON object: category -> count
+
+	LatencySumMs     int64  `gorm:"column:latency_sum_ms;default:0;not null"`
+	LatencyMaxMs     int64  `gorm:"column:latency_max_ms;default:0;not null"`
+	LatencyHistogram string `gorm:"column:latency_histogram;type:text"` // JSON LatencyHistogram
+
+	InputTokens      int64 `gorm:"column:input_tokens;default:0;not null"`
+	OutputTokens     int64 `gorm:"column:output_tokens;default:0;not null"`
+	CacheReadTokens  int64 `gorm:"column:cache_read_tokens;default:0;not null"`
+	CacheWriteTokens int64 `gorm:"column:cache_write_tokens;default:0;not null"`
+
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// TableName specifies the table name for GORM
+func (StatsRollup) TableName() string {
+	return "stats_rollups"
+}
+
+// rollupKey identifies a bucket
+type rollupKey struct {
+	resolution string
+	bucket     int64
+	endpoint   string
+	model      string
+	clientType string
+}
+
+// rollupCounters holds the aggregated values of a bucket
+type rollupCounters struct {
+	requests        int64
+	successes       int64
+	errors          int64
+	errorCategories map[string]int64
+	latencySumMs    int64
+	latencyMaxMs    int64
+	histogram       LatencyHistogram
+
+	inputTokens      int64
+	outputTokens     int64
+	cacheReadTokens  int64
+	cacheWriteTokens int64
+}
+
+func newRollupCounters() *rollupCounters {
+	return &rollupCounters{errorCategories: make(map[string]int64), histogram: make(LatencyHistogram)}
+}
+
+func (r *rollupCounters) add(sample TimeSeriesSample) {
+	r.requests++
+	if sample.Success {
+		r.successes++
+	} else {
+		r.errors++
+		category := sample.ErrorCategory
+		if category == "" {
+			category = "unknown"
+		}
+		r.errorCategories[category]++
+	}
+
+	ms := sample.Latency.Milliseconds()
+	r.latencySumMs += ms
+	if ms > r.latencyMaxMs {
+		r.latencyMaxMs = ms
+	}
+	r.histogram.Observe(ms)
+
+	r.inputTokens += sample.InputTokens
+	r.outputTokens += sample.OutputTokens
+	r.cacheReadTokens += sample.CacheReadTokens
+	r.cacheWriteTokens += sample.CacheWriteTokens
+}
+
+func (r *rollupCounters) merge(other *rollupCounters) {
+	r.requests += other.requests
+	r.successes += other.successes
+	r.errors += other.errors
+	for category, count := range other.errorCategories {
+		r.errorCategories[category] += count
+	}
+	r.latencySumMs += other.latencySumMs
+	if other.latencyMaxMs > r.latencyMaxMs {
+		r.latencyMaxMs = other.latencyMaxMs
+	}
+	r.histogram.Merge(other.histogram)
+	r.inputTokens += other.inputTokens
+	r.outputTokens += other.outputTokens
+	r.cacheReadTokens += other.cacheReadTokens
+	r.cacheWriteTokens += other.cacheWriteTokens
+}
+
+func countersFromRow(row *StatsRollup) *rollupCounters {
+	counters := &rollupCounters{
+		requests:         row.Requests,
+		successes:        row.Successes,
+		errors:           row.Errors,
+		errorCategories:  make(map[string]int64),
+		latencySumMs:     row.LatencySumMs,
+		latencyMaxMs:     row.LatencyMaxMs,
+		histogram:        decodeHistogram(row.LatencyHistogram),
+		inputTokens:      row.InputTokens,
+		outputTokens:     row.OutputTokens,
+		cacheReadTokens:  row.CacheReadTokens,
+		cacheWriteTokens: row.CacheWriteTokens,
+	}
+	if row.ErrorCategories != "" {
+		json.Unmarshal([]byte(row.ErrorCategories), &counters.errorCategories)
+	}
+	return counters
+}
+
+func (r *rollupCounters) applyTo(row *StatsRollup) {
+	row.Requests = r.requests
+	row.Successes = r.successes
+	row.Errors = r.errors
+	row.ErrorCategories = ""
+	if len(r.errorCategories) > 0 {
+		data, _ := json.Marshal(r.errorCategories)
+		row.ErrorCategories = string(data)
+	}
+	row.LatencySumMs = r.latencySumMs
+	row.LatencyMaxMs = r.latencyMaxMs
+	row.LatencyHistogram = encodeHistogram(r.histogram)
+	row.InputTokens = r.inputTokens
+	row.OutputTokens = r.outputTokens
+	row.CacheReadTokens = r.cacheReadTokens
+	row.CacheWriteTokens = r.cacheWriteTokens
+}
+
+// TimeSeriesStore aggregates attempt outcomes into per-minute buckets in memory,
+// flushes them to statistics.db periodically and downsamples old buckets
+type TimeSeriesStore struct {
+	db        *gorm.DB
+	mutex     sync.Mutex
+	pending   map[rollupKey]*rollupCounters
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTimeSeriesStore creates the rollup table and starts the background flusher
+func NewTimeSeriesStore(db *gorm.DB) (*TimeSeriesStore, error) {
+	if err := db.AutoMigrate(&StatsRollup{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate stats rollup table: %v", err)
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_stats_rollup_bucket ON stats_rollups(bucket_start)").Error; err != nil {
+		return nil, fmt.Errorf("failed to create index: %v", err)
+	}
+
+	store := &TimeSeriesStore{
+		db:      db,
+		pending: make(map[rollupKey]*rollupCounters),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go store.run()
+	return store, nil
+}
+
+func (t *TimeSeriesStore) run() {
+	defer close(t.done)
+
+	flushTicker := time.NewTicker(rollupFlushInterval)
+	defer flushTicker.Stop()
+	downsampleTicker := time.NewTicker(rollupDownsampleInterval)
+	defer downsampleTicker.Stop()
+
+	t.runDownsample()
+	for {
+		select {
+		case <-flushTicker.C:
+			if err := t.Flush(); err != nil {
+				log.Printf("WARNING: %v", err)
+			}
+		case <-downsampleTicker.C:
+			t.runDownsample()
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+func (t *TimeSeriesStore) runDownsample() {
+	if err := t.Downsample(time.Now()); err != nil {
+		log.Printf("WARNING: Failed to downsample time-series statistics: %v", err)
+	}
+}
+
+// Record adds an attempt outcome to its minute bucket
+func (t *TimeSeriesStore) Record(sample TimeSeriesSample) {
+	if t == nil {
+		return
+	}
+	if sample.Time.IsZero() {
+		sample.Time = time.Now()
+	}
+
+	key := rollupKey{
+		resolution: ResolutionMinute,
+		bucket:     truncateBucket(sample.Time.Unix(), time.Minute),
+		endpoint:   sample.Endpoint,
+		model:      sample.Model,
+		clientType: sample.ClientType,
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	counters := t.pending[key]
+	if counters == nil {
+		counters = newRollupCounters()
+		t.pending[key] = counters
+	}
+	counters.add(sample)
+}
+
+// Flush writes pending buckets to the database, merging them into existing rows
+func (t *TimeSeriesStore) Flush() error {
+	if t == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	pending := t.pending
+	t.pending = make(map[rollupKey]*rollupCounters)
+	t.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		for key, counters := range pending {
+			if err := mergeRollup(tx, key, counters); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Keep the data for the next flush
+		t.mutex.Lock()
+		for key, counters := range pending {
+			if existing := t.pending[key]; existing != nil {
+				existing.merge(counters)
+			} else {
+				t.pending[key] = counters
+			}
+		}
+		t.mutex.Unlock()
+		return fmt.Errorf("failed to flush time-series statistics: %v", err)
+	}
+	return nil
+}
+
+// mergeRollup adds counters to the row identified by key, creating it if needed
+func mergeRollup(tx *gorm.DB, key rollupKey, counters *rollupCounters) error {
+	var rows []StatsRollup
+	err := tx.Where("resolution = ? AND bucket_start = ? AND endpoint = ? AND model = ? AND client_type = ?",
+		key.resolution, key.bucket, key.endpoint, key.model, key.clientType).Limit(1).Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		row := StatsRollup{
+			Resolution:  key.resolution,
+			BucketStart: key.bucket,
+			Endpoint:    key.endpoint,
+			Model:       key.model,
+			ClientType:  key.clientType,
+		}
+		counters.applyTo(&row)
+		return tx.Create(&row).Error
+	}
+
+	row := &rows[0]
+	merged := countersFromRow(row)
+	merged.merge(counters)
+	merged.applyTo(row)
+	return tx.Save(row).Error
+}
+
+// Downsample merges old minute buckets into hours and old hour buckets into days,
+// then deletes day buckets past retention
+func (t *TimeSeriesStore) Downsample(now time.Time) error {
+	if t == nil {
+		return nil
+	}
+
+	stages := []struct {
+		from string
+		to   string
+		age  time.Duration
+	}{
+		{ResolutionMinute, ResolutionHour, minuteRollupRetention},
+		{ResolutionHour, ResolutionDay, hourRollupRetention},
+	}
+	for _, stage := range stages {
+		// Align the cutoff to the target resolution so only complete target buckets are built
+		cutoff := truncateBucket(now.Add(-stage.age).Unix(), resolutionSteps[stage.to])
+		err := t.db.Transaction(func(tx *gorm.DB) error {
+			return downsampleRollups(tx, stage.from, stage.to, cutoff)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to downsample %s buckets: %v", stage.from, err)
+		}
+	}
+
+	err := t.db.Where("resolution = ? AND bucket_start < ?", ResolutionDay, now.Add(-dayRollupRetention).Unix()).
+		Delete(&StatsRollup{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete expired day buckets: %v", err)
+	}
+	return nil
+}
+
+func downsampleRollups(tx *gorm.DB, from, to string, cutoff int64) error {
+	var rows []StatsRollup
+	if err := tx.Where("resolution = ? AND bucket_start < ?", from, cutoff).Find(&rows).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	step := resolutionSteps[to]
+	merged := make(map[rollupKey]*rollupCounters)
+	for i := range rows {
+		row := &rows[i]
+		key := rollupKey{
+			resolution: to,
+			bucket:     truncateBucket(row.BucketStart, step),
+			endpoint:   row.Endpoint,
+			model:      row.Model,
+			clientType: row.ClientType,
+		}
+		if counters := merged[key]; counters != nil {
+			counters.merge(countersFromRow(row))
+		} else {
+			merged[key] = countersFromRow(row)
+		}
+	}
+
+	for key, counters := range merged {
+		if err := mergeRollup(tx, key, counters); err != nil {
+			return err
+		}
+	}
+	return tx.Where("resolution = ? AND bucket_start < ?", from, cutoff).Delete(&StatsRollup{}).Error
+}
+
+// truncateBucket aligns Unix seconds to the start of a UTC bucket
+func truncateBucket(unix int64, step time.Duration) int64 {
+	seconds := int64(step / time.Second)
+	return unix - ((unix%seconds)+seconds)%seconds
+}
+
+// TimeSeriesQuery selects buckets for the time-series API
+type TimeSeriesQuery struct {
+	From       time.Time // defaults to 24 hours before To
+	To         time.Time // defaults to now
+	Resolution string    // minute | hour | day; chosen from the time range when empty
+	Endpoint   string
+	Model      string
+	ClientType string
+	GroupBy    string // endpoint | model | client_type; empty for a single series
+}
+
+// TimeSeriesPoint is the aggregate of one bucket
+type TimeSeriesPoint struct {
+	Time            time.Time        `json:"time"`
+	Requests        int64            `json:"requests"`
+	Successes       int64            `json:"successes"`
+	Errors          int64            `json:"errors"`
+	ErrorRate       float64          `json:"error_rate"` // percentage
+	ErrorCategories map[string]int64 `json:"error_categories,omitempty"`
+
+	LatencyAvgMs float64 `json:"latency_avg_ms"`
+	LatencyP50Ms float64 `json:"latency_p50_ms"`
+	LatencyP90Ms float64 `json:"latency_p90_ms"`
+	LatencyP99Ms float64 `json:"latency_p99_ms"`
+	LatencyMaxMs int64   `json:"latency_max_ms"`
+
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	CacheReadTokens  int64 `json:"cache_read_tokens"`
+	CacheWriteTokens int64 `json:"cache_write_tokens"`
+}
+
+// TimeSeries is one series of points, keyed by the group-by value
+type TimeSeries struct {
+	Key    string            `json:"key"`
+	Points []TimeSeriesPoint `json:"points"`
+	Total  TimeSeriesPoint   `json:"total"`
+}
+
+// TimeSeriesResult is the response of a time-series query
+type TimeSeriesResult struct {
+	Resolution string       `json:"resolution"`
+	From       time.Time    `json:"from"`
+	To         time.Time    `json:"to"`
+	Series     []TimeSeries `json:"series"`
+}
+
+func (r *rollupCounters) point(at time.Time) TimeSeriesPoint {
+	point := TimeSeriesPoint{
+		Time:             at,
+		Requests:         r.requests,
+		Successes:        r.successes,
+		Errors:           r.errors,
+		LatencyMaxMs:     r.latencyMaxMs,
+		InputTokens:      r.inputTokens,
+		OutputTokens:     r.outputTokens,
+		CacheReadTokens:  r.cacheReadTokens,
+		CacheWriteTokens: r.cacheWriteTokens,
+	}
+	if len(r.errorCategories) > 0 {
+		point.ErrorCategories = r.errorCategories
+	}
+	if r.requests > 0 {
+		point.ErrorRate = float64(r.errors) / float64(r.requests) * 100.0
+		point.LatencyAvgMs = float64(r.latencySumMs) / float64(r.requests)
+		// Bucket upper bounds may exceed the largest observation
+		maxMs := float64(r.latencyMaxMs)
+		point.LatencyP50Ms = min(r.histogram.Quantile(0.50), maxMs)
+		point.LatencyP90Ms = min(r.histogram.Quantile(0.90), maxMs)
+		point.LatencyP99Ms = min(r.histogram.Quantile(0.99), maxMs)
+	}
+	return point
+}
+
+// autoResolution picks the finest resolution that keeps the point count readable
+func autoResolution(span time.Duration) string {
+	switch {
+	case span <= 6*time.Hour:
+		return ResolutionMinute
+	case span <= 7*24*time.Hour:
+		return ResolutionHour
+	default:
+		return ResolutionDay
+	}
+}
+
+// Query returns zero-filled series for the requested range. Buckets that were
+// already downsampled appear at the start of their coarser bucket.
+func (t *TimeSeriesStore) Query(query TimeSeriesQuery) (*TimeSeriesResult, error) {
+	if t == nil {
+		return nil, ErrTimeSeriesUnavailable
+	}
+
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-24 * time.Hour)
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidTimeSeriesQuery)
+	}
+	if query.Resolution == "" {
+		query.Resolution = autoResolution(query.To.Sub(query.From))
+	}
+	step, ok := resolutionSteps[query.Resolution]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown resolution '%s'", ErrInvalidTimeSeriesQuery, query.Resolution)
+	}
+	switch query.GroupBy {
+	case "", "endpoint", "model", "client_type":
+	default:
+		return nil, fmt.Errorf("%w: unknown group_by '%s'", ErrInvalidTimeSeriesQuery, query.GroupBy)
+	}
+
+	start := truncateBucket(query.From.Unix(), step)
+	end := query.To.Unix()
+	stepSeconds := int64(step / time.Second)
+	if (end-start)/stepSeconds+1 > maxTimeSeriesPoints {
+		return nil, fmt.Errorf("%w: time range too large for %s resolution", ErrInvalidTimeSeriesQuery, query.Resolution)
+	}
+
+	if err := t.Flush(); err != nil {
+		log.Printf("WARNING: %v", err)
+	}
+
+	db := t.db.Where("bucket_start >= ? AND bucket_start <= ?", start, end)
+	if query.Endpoint != "" {
+		db = db.Where("endpoint = ?", query.Endpoint)
+	}
+	if query.Model != "" {
+		db = db.Where("model = ?", query.Model)
+	}
+	if query.ClientType != "" {
+		db = db.Where("client_type = ?", query.ClientType)
+	}
+	var rows []StatsRollup
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query time-series statistics: %v", err)
+	}
+
+	buckets := make(map[string]map[int64]*rollupCounters)
+	if query.GroupBy == "" {
+		buckets[""] = make(map[int64]*rollupCounters)
+	}
+	for i := range rows {
+		row := &rows[i]
+		var key string
+		switch query.GroupBy {
+		case "endpoint":
+			key = row.Endpoint
+		case "model":
+			key = row.Model
+		case "client_type":
+			key = row.ClientType
+		}
+		series := buckets[key]
+		if series == nil {
+			series = make(map[int64]*rollupCounters)
+			buckets[key] = series
+		}
+		bucket := truncateBucket(row.BucketStart, step)
+		if counters := series[bucket]; counters != nil {
+			counters.merge(countersFromRow(row))
+		} else {
+			series[bucket] = countersFromRow(row)
+		}
+	}
+
+	result := &TimeSeriesResult{
+		Resolution: query.Resolution,
+		From:       time.Unix(start, 0).UTC(),
+		To:         query.To.UTC(),
+		Series:     make([]TimeSeries, 0, len(buckets)),
+	}
+	for key, series := range buckets {
+		total := newRollupCounters()
+		points := make([]TimeSeriesPoint, 0, (end-start)/stepSeconds+1)
+		for bucket := start; bucket <= end; bucket += stepSeconds {
+			counters := series[bucket]
+			if counters == nil {
+				counters = newRollupCounters()
+			}
+			total.merge(counters)
+			points = append(points, counters.point(time.Unix(bucket, 0).UTC()))
+		}
+		result.Series = append(result.Series, TimeSeries{
+			Key:    key,
+			Points: points,
+			Total:  total.point(result.From),
+		})
+	}
+	sort.Slice(result.Series, func(i, j int) bool {
+		if result.Series[i].Total.Requests != result.Series[j].Total.Requests {
+			return result.Series[i].Total.Requests > result.Series[j].Total.Requests
+		}
+		return result.Series[i].Key < result.Series[j].Key
+	})
+	return result, nil
+}
+
+// Close stops the background flusher and writes pending buckets
+func (t *TimeSeriesStore) Close() error {
+	if t == nil {
+		return nil
+	}
+	t.closeOnce.Do(func() {
+		close(t.stop)
+		<-t.done
+	})
+	return t.Flush()
+}
@@ -0,0 +1,164 @@
+package statistics
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func setupTimeSeries(t *testing.T) *TimeSeriesStore {
+	t.Helper()
+	manager, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	return manager.TimeSeries()
+}
+
+func TestLatencyHistogram_Quantile(t *testing.T) {
+	h := make(LatencyHistogram)
+	for ms := int64(1); ms <= 1000; ms++ {
+		h.Observe(ms)
+	}
+
+	for _, tc := range []struct {
+		q    float64
+		want float64
+	}{{0.5, 500}, {0.9, 900}, {0.99, 990}} {
+		got := h.Quantile(tc.q)
+		if math.Abs(got-tc.want)/tc.want > 0.05 {
+			t.Errorf("Quantile(%v) = %v, want within 5%% of %v", tc.q, got, tc.want)
+		}
+	}
+
+	restored := decodeHistogram(encodeHistogram(h))
+	if restored.Count() != 1000 || restored.Quantile(0.5) != h.Quantile(0.5) {
+		t.Errorf("Histogram did not survive encoding")
+	}
+}
+
+func TestTimeSeriesStore_RecordAndQuery(t *testing.T) {
+	store := setupTimeSeries(t)
+	base := time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC)
+
+	store.Record(TimeSeriesSample{Time: base.Add(10 * time.Second), Endpoint: "primary", Model: "claude", ClientType: "claude-code",
+		Success: true, Latency: 200 * time.Millisecond, InputTokens: 100, OutputTokens: 20})
+	store.Record(TimeSeriesSample{Time: base.Add(50 * time.Second), Endpoint: "primary", Model: "claude", ClientType: "claude-code",
+		Success: false, ErrorCategory: "server_error", Latency: 800 * time.Millisecond})
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	// 同一分钟的后续数据合并到已有的行
+	store.Record(TimeSeriesSample{Time: base.Add(2 * time.Minute), Endpoint: "backup", Model: "gpt", ClientType: "codex",
+		Success: true, Latency: 100 * time.Millisecond, OutputTokens: 5})
+	store.Record(TimeSeriesSample{Time: base.Add(30 * time.Second), Endpoint: "primary", Model: "claude", ClientType: "claude-code",
+		Success: false, ErrorCategory: "server_error", Latency: 400 * time.Millisecond})
+
+	result, err := store.Query(TimeSeriesQuery{From: base, To: base.Add(3 * time.Minute)})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.Resolution != ResolutionMinute || len(result.Series) != 1 || len(result.Series[0].Points) != 4 {
+		t.Fatalf("Unexpected result shape: %+v", result)
+	}
+
+	first := result.Series[0].Points[0]
+	if first.Requests != 3 || first.Errors != 2 || first.ErrorCategories["server_error"] != 2 {
+		t.Errorf("Unexpected first bucket: %+v", first)
+	}
+	if first.LatencyMaxMs != 800 || first.LatencyP50Ms < 380 || first.LatencyP50Ms > 420 {
+		t.Errorf("Unexpected latency: %+v", first)
+	}
+	if first.InputTokens != 100 || first.OutputTokens != 20 {
+		t.Errorf("Unexpected tokens: %+v", first)
+	}
+	if result.Series[0].Points[1].Requests != 0 || result.Series[0].Points[2].Requests != 1 {
+		t.Errorf("Expected zero-filled buckets, got %+v", result.Series[0].Points)
+	}
+	if total := result.Series[0].Total; total.Requests != 4 || total.OutputTokens != 25 {
+		t.Errorf("Unexpected total: %+v", total)
+	}
+
+	grouped, err := store.Query(TimeSeriesQuery{From: base, To: base.Add(3 * time.Minute), GroupBy: "endpoint"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(grouped.Series) != 2 || grouped.Series[0].Key != "primary" || grouped.Series[1].Key != "backup" {
+		t.Errorf("Unexpected grouped series: %+v", grouped.Series)
+	}
+
+	filtered, err := store.Query(TimeSeriesQuery{From: base, To: base.Add(3 * time.Minute), ClientType: "codex"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if filtered.Series[0].Total.Requests != 1 {
+		t.Errorf("Expected client type filter to apply, got %+v", filtered.Series[0].Total)
+	}
+}
+
+func TestTimeSeriesStore_Downsample(t *testing.T) {
+	store := setupTimeSeries(t)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-72 * time.Hour)
+
+	for i := 0; i < 3; i++ {
+		store.Record(TimeSeriesSample{Time: old.Add(time.Duration(i) * 7 * time.Minute), Endpoint: "primary",
+			Success: i != 2, Latency: time.Duration(100*(i+1)) * time.Millisecond, InputTokens: 10})
+	}
+	store.Record(TimeSeriesSample{Time: now.Add(-time.Hour), Endpoint: "primary", Success: true, Latency: time.Second})
+	store.Record(TimeSeriesSample{Time: now.Add(-500 * 24 * time.Hour), Endpoint: "primary", Success: true})
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if err := store.Downsample(now); err != nil {
+		t.Fatalf("Downsample failed: %v", err)
+	}
+
+	var rows []StatsRollup
+	store.db.Order("bucket_start").Find(&rows)
+	if len(rows) != 2 {
+		t.Fatalf("Expected one hour bucket and one minute bucket, got %+v", rows)
+	}
+	hour := rows[0]
+	if hour.Resolution != ResolutionHour || hour.BucketStart != old.Unix() || hour.Requests != 3 || hour.Errors != 1 ||
+		hour.InputTokens != 30 || hour.LatencyMaxMs != 300 {
+		t.Errorf("Unexpected hour bucket: %+v", hour)
+	}
+	if rows[1].Resolution != ResolutionMinute {
+		t.Errorf("Expected recent minute bucket to be kept, got %+v", rows[1])
+	}
+
+	// 降采样后的数据在分钟粒度查询中出现在所在小时的起点
+	result, err := store.Query(TimeSeriesQuery{From: old, To: old.Add(30 * time.Minute), Resolution: ResolutionMinute})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.Series[0].Points[0].Requests != 3 {
+		t.Errorf("Expected downsampled bucket at hour start, got %+v", result.Series[0].Points[0])
+	}
+}
+
+func TestTimeSeriesStore_InvalidQueries(t *testing.T) {
+	store := setupTimeSeries(t)
+	now := time.Now()
+
+	for _, query := range []TimeSeriesQuery{
+		{From: now, To: now.Add(-time.Hour)},
+		{From: now.Add(-time.Hour), To: now, Resolution: "second"},
+		{From: now.Add(-time.Hour), To: now, GroupBy: "tag"},
+		{From: now.Add(-30 * 24 * time.Hour), To: now, Resolution: ResolutionMinute},
+	} {
+		if _, err := store.Query(query); !errors.Is(err, ErrInvalidTimeSeriesQuery) {
+			t.Errorf("Expected ErrInvalidTimeSeriesQuery for %+v, got %v", query, err)
+		}
+	}
+
+	var unavailable *TimeSeriesStore
+	unavailable.Record(TimeSeriesSample{})
+	if _, err := unavailable.Query(TimeSeriesQuery{}); !errors.Is(err, ErrTimeSeriesUnavailable) {
+		t.Errorf("Expected ErrTimeSeriesUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"claude-code-codex-companion/internal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName 代理内所有 span 使用的 tracer 名称
+const instrumentationName = "claude-code-codex-companion/internal/proxy"
+
+// enabled 是否在导出 span（决定是否向上游注入 traceparent）
+var enabled atomic.Bool
+
+// propagator 解析与注入 W3C traceparent / baggage
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Init 根据配置初始化全局 TracerProvider，返回用于刷新并关闭导出器的函数
+// 未启用时不创建导出器，span 为非记录状态，但入站 traceparent 仍会被解析，trace ID 可写入请求日志
+func Init(cfg config.TracingConfig, serviceVersion string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+	if !cfg.Enabled {
+		enabled.Store(false)
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeOutput, err := newExporter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "claude-code-codex-companion"
+	}
+	res := resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("service.version", serviceVersion),
+	)
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	enabled.Store(true)
+
+	return func(ctx context.Context) error {
+		enabled.Store(false)
+		err := provider.Shutdown(ctx)
+		if closeOutput != nil {
+			if closeErr := closeOutput(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// newExporter 创建 span 导出器；file 导出器额外返回关闭输出文件的函数
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, func() error, error) {
+	switch cfg.Exporter {
+	case "", "otlp":
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = "http://localhost:4318/v1/traces"
+		}
+		options := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+		if len(cfg.Headers) > 0 {
+			options = append(options, otlptracehttp.WithHeaders(cfg.Headers))
+		}
+		// 导出在后台批量进行，创建时不连接 collector
+		exporter, err := otlptracehttp.New(context.Background(), options...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP trace exporter: %v", err)
+		}
+		return exporter, nil, nil
+
+	case "file":
+		filePath := cfg.FilePath
+		if filePath == "" {
+			filePath = "./logs/traces.jsonl"
+		}
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create trace file directory: %v", err)
+		}
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open trace file: %v", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, nil, fmt.Errorf("failed to create file trace exporter: %v", err)
+		}
+		return exporter, file.Close, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported tracing exporter: %s", cfg.Exporter)
+	}
+}
+
+// Enabled 是否正在导出 span
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Tracer 返回代理使用的 tracer（跟随全局 TracerProvider）
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Extract 从入站请求头解析 traceparent，返回携带远端 span 上下文的 context
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject 将当前 span 上下文写入上游请求头（覆盖客户端透传的 traceparent），仅在导出 span 时生效
+func Inject(ctx context.Context, header http.Header) {
+	if !Enabled() {
+		return
+	}
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// TraceID 返回 context 中 span 的 trace ID（十六进制），没有有效 span 上下文时为空
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
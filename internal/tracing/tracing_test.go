@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+const (
+	incomingTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	incomingTraceparent = "00-" + incomingTraceID + "-00f067aa0ba902b7-01"
+)
+
+func incomingHeader() http.Header {
+	header := http.Header{}
+	header.Set("traceparent", incomingTraceparent)
+	return header
+}
+
+func TestDisabled_PropagatesIncomingTraceID(t *testing.T) {
+	shutdown, err := Init(config.TracingConfig{}, "test")
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	if Enabled() {
+		t.Fatalf("Expected tracing to be disabled")
+	}
+
+	ctx := Extract(context.Background(), incomingHeader())
+	ctx, span := Tracer().Start(ctx, "cccc.request")
+	defer span.End()
+
+	if traceID := TraceID(ctx); traceID != incomingTraceID {
+		t.Errorf("Expected incoming trace ID %s, got %q", incomingTraceID, traceID)
+	}
+
+	// 未启用时不改写上游请求头
+	upstream := http.Header{}
+	Inject(ctx, upstream)
+	if upstream.Get("traceparent") != "" {
+		t.Errorf("Expected no traceparent injection when tracing is disabled")
+	}
+
+	if traceID := TraceID(context.Background()); traceID != "" {
+		t.Errorf("Expected empty trace ID without span context, got %q", traceID)
+	}
+}
+
+func TestFileExporter_WritesSpansAsJSONLines(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "nested", "traces.jsonl")
+	shutdown, err := Init(config.TracingConfig{Enabled: true, Exporter: "file", FilePath: filePath}, "test")
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	ctx := Extract(context.Background(), incomingHeader())
+	ctx, root := Tracer().Start(ctx, "cccc.request")
+	childCtx, child := Tracer().Start(ctx, "cccc.upstream_attempt")
+
+	upstream := http.Header{}
+	Inject(childCtx, upstream)
+	if !strings.Contains(upstream.Get("traceparent"), incomingTraceID) {
+		t.Errorf("Expected injected traceparent to keep trace ID, got %q", upstream.Get("traceparent"))
+	}
+	if upstream.Get("traceparent") == incomingTraceparent {
+		t.Errorf("Expected injected traceparent to carry the proxy span ID")
+	}
+
+	child.End()
+	root.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatalf("Failed to open trace file: %v", err)
+	}
+	defer file.Close()
+
+	spans := map[string]map[string]interface{}{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var span map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &span); err != nil {
+			t.Fatalf("Expected one JSON span per line, got %q: %v", scanner.Text(), err)
+		}
+		spans[span["Name"].(string)] = span
+	}
+
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	for name, span := range spans {
+		traceID := span["SpanContext"].(map[string]interface{})["TraceID"]
+		if traceID != incomingTraceID {
+			t.Errorf("Expected span %s to have trace ID %s, got %v", name, incomingTraceID, traceID)
+		}
+	}
+	rootSpanID := spans["cccc.request"]["SpanContext"].(map[string]interface{})["SpanID"]
+	childParentID := spans["cccc.upstream_attempt"]["Parent"].(map[string]interface{})["SpanID"]
+	if rootSpanID != childParentID {
+		t.Errorf("Expected attempt span to be a child of the request span")
+	}
+}
+
+func TestOTLPExporter_SendsToCollector(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer collector.Close()
+
+	shutdown, err := Init(config.TracingConfig{
+		Enabled:  true,
+		Exporter: "otlp",
+		Endpoint: collector.URL + "/v1/traces",
+		Headers:  map[string]string{"X-Collector-Token": "secret"},
+	}, "test")
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	_, span := Tracer().Start(context.Background(), "cccc.request")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) == 0 {
+		t.Fatalf("Expected spans to be exported to the collector")
+	}
+	r := requests[0]
+	if r.URL.Path != "/v1/traces" || r.Method != http.MethodPost {
+		t.Errorf("Expected POST /v1/traces, got %s %s", r.Method, r.URL.Path)
+	}
+	if r.Header.Get("X-Collector-Token") != "secret" {
+		t.Errorf("Expected configured headers to be sent")
+	}
+	if !strings.Contains(r.Header.Get("Content-Type"), "protobuf") {
+		t.Errorf("Expected protobuf payload, got %q", r.Header.Get("Content-Type"))
+	}
+}
+
+func TestInit_InvalidExporter(t *testing.T) {
+	if _, err := Init(config.TracingConfig{Enabled: true, Exporter: "zipkin"}, "test"); err == nil {
+		t.Errorf("Expected unsupported exporter to be rejected")
+	}
+}
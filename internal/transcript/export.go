@@ -0,0 +1,159 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// jsonlRecord JSONL 导出的一行；首行为会话概要，其后每轮一行
+type jsonlRecord struct {
+	Type    string      `json:"type"` // "session" | "turn"
+	Session *Transcript `json:"session,omitempty"`
+	Turn    *Turn       `json:"turn,omitempty"`
+}
+
+// WriteJSONL 以 JSONL 格式导出会话
+func (t *Transcript) WriteJSONL(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	summary := *t
+	summary.Turns = nil
+	if err := encoder.Encode(jsonlRecord{Type: "session", Session: &summary}); err != nil {
+		return err
+	}
+	for _, turn := range t.Turns {
+		if err := encoder.Encode(jsonlRecord{Type: "turn", Turn: turn}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Markdown 以 Markdown 格式导出会话，便于阅读和分享
+func (t *Transcript) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Session %s\n\n", t.SessionID)
+	if t.ClientType != "" {
+		fmt.Fprintf(&b, "- Client: %s\n", t.ClientType)
+	}
+	fmt.Fprintf(&b, "- Time: %s — %s\n", formatTime(t.Start), formatTime(t.End))
+	fmt.Fprintf(&b, "- Turns: %d\n", len(t.Turns))
+	fmt.Fprintf(&b, "- Tokens: %s\n", formatUsage(t.Usage))
+	fmt.Fprintf(&b, "- Cost: %s\n", formatCost(t.Cost))
+	if len(t.UnpricedModels) > 0 {
+		fmt.Fprintf(&b, "- Unpriced models: %s\n", strings.Join(t.UnpricedModels, ", "))
+	}
+
+	for _, turn := range t.Turns {
+		fmt.Fprintf(&b, "\n---\n\n## Turn %d · %s\n\n", turn.Index, formatTime(turn.Timestamp))
+		fmt.Fprintf(&b, "- Request ID: `%s`\n", turn.RequestID)
+		fmt.Fprintf(&b, "- Endpoint: %s\n", turn.Endpoint)
+		if turn.ModelRewritten {
+			fmt.Fprintf(&b, "- Model: %s → %s (rewritten)\n", turn.Model, turn.UpstreamModel)
+		} else if turn.Model != "" {
+			fmt.Fprintf(&b, "- Model: %s\n", turn.Model)
+		}
+		if turn.FormatConverted {
+			fmt.Fprintf(&b, "- Format: %s → %s (converted)\n", turn.RequestFormat, turn.TargetFormat)
+		}
+		fmt.Fprintf(&b, "- Status: %d", turn.StatusCode)
+		if len(turn.Attempts) > 1 {
+			fmt.Fprintf(&b, " after %d attempts", len(turn.Attempts))
+		}
+		b.WriteString("\n")
+		for _, attempt := range turn.Attempts[:len(turn.Attempts)-1] {
+			fmt.Fprintf(&b, "  - attempt %d: %s → %d %s\n", attempt.AttemptNumber, attempt.Endpoint, attempt.StatusCode, attempt.Error)
+		}
+		if turn.Error != "" {
+			fmt.Fprintf(&b, "- Error: %s\n", turn.Error)
+		}
+		fmt.Fprintf(&b, "- Tokens: %s (cumulative %s)\n", formatUsage(turn.Usage), formatUsage(turn.CumulativeUsage))
+		if turn.Cost != nil {
+			fmt.Fprintf(&b, "- Cost: %s (cumulative %s)\n", formatCost(*turn.Cost), formatCost(turn.CumulativeCost))
+		}
+		for _, warning := range turn.Warnings {
+			fmt.Fprintf(&b, "- ⚠ %s\n", warning)
+		}
+		b.WriteString("\n")
+
+		if turn.System != "" {
+			b.WriteString("### System\n\n")
+			writeFenced(&b, "", turn.System)
+		}
+		for _, message := range turn.Input {
+			writeMessage(&b, message)
+		}
+		if turn.Output != nil {
+			writeMessage(&b, *turn.Output)
+			if turn.StopReason != "" {
+				fmt.Fprintf(&b, "_stop reason: %s_\n", turn.StopReason)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func writeMessage(b *strings.Builder, message Message) {
+	role := message.Role
+	if role != "" {
+		role = strings.ToUpper(role[:1]) + role[1:]
+	}
+	fmt.Fprintf(b, "### %s\n\n", role)
+	for _, block := range message.Content {
+		switch block.Type {
+		case BlockText:
+			b.WriteString(block.Text)
+			b.WriteString("\n\n")
+		case BlockThinking:
+			b.WriteString("<details><summary>Thinking</summary>\n\n")
+			b.WriteString(block.Text)
+			b.WriteString("\n\n</details>\n\n")
+		case BlockToolUse:
+			fmt.Fprintf(b, "**Tool call** `%s` (%s)\n\n", block.Name, block.ID)
+			writeFenced(b, "json", block.Input)
+		case BlockToolResult:
+			label := "Tool result"
+			if block.IsError {
+				label = "Tool error"
+			}
+			fmt.Fprintf(b, "**%s** (%s)\n\n", label, block.ID)
+			writeFenced(b, "", block.Text)
+		case BlockImage:
+			b.WriteString("_[image]_\n\n")
+		default:
+			fmt.Fprintf(b, "_[%s]_ %s\n\n", block.Type, block.Text)
+		}
+	}
+}
+
+// writeFenced 写入代码块，围栏长度随内容中的反引号自动加长
+func writeFenced(b *strings.Builder, lang, content string) {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n\n", fence, lang, strings.TrimRight(content, "\n"), fence)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func formatUsage(u Usage) string {
+	s := fmt.Sprintf("in %d / out %d", u.InputTokens, u.OutputTokens)
+	if u.CacheReadTokens > 0 || u.CacheWriteTokens > 0 {
+		s += fmt.Sprintf(" / cache read %d / cache write %d", u.CacheReadTokens, u.CacheWriteTokens)
+	}
+	return s
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.4f", cost)
+}
@@ -0,0 +1,618 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 请求或响应无法还原时的原因
+var (
+	errEmptyBody     = errors.New("body not logged")
+	errUnknownFormat = errors.New("unrecognized body format")
+)
+
+// parsedRequest 统一格式后的请求
+type parsedRequest struct {
+	System   string
+	Messages []Message
+}
+
+// parsedResponse 统一格式后的响应
+type parsedResponse struct {
+	Message    *Message
+	StopReason string
+	Usage      Usage
+}
+
+// parseRequest 解析客户端请求，支持 Anthropic Messages、OpenAI Chat Completions 与 Responses API
+func parseRequest(body string) (*parsedRequest, error) {
+	if strings.TrimSpace(body) == "" {
+		return nil, errEmptyBody
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON (body may be truncated): %v", err)
+	}
+
+	if _, ok := raw["input"]; ok {
+		return parseResponsesRequest(raw)
+	}
+	if _, ok := raw["messages"]; !ok {
+		return nil, errUnknownFormat
+	}
+	// Anthropic 与 OpenAI Chat 都使用 messages，逐条消息按各自的格式兼容解析
+	return parseMessagesRequest(raw)
+}
+
+// wireMessage Anthropic / OpenAI Chat 消息
+type wireMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"`
+	ToolCalls  []wireToolCall  `json:"tool_calls"`   // OpenAI assistant
+	ToolCallID string          `json:"tool_call_id"` // OpenAI tool
+}
+
+type wireToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// wireBlock Anthropic 内容块 / OpenAI 与 Responses 内容片段
+type wireBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	Thinking  string          `json:"thinking"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+}
+
+func parseMessagesRequest(raw map[string]json.RawMessage) (*parsedRequest, error) {
+	var messages []wireMessage
+	if err := json.Unmarshal(raw["messages"], &messages); err != nil {
+		return nil, fmt.Errorf("invalid messages: %v", err)
+	}
+
+	request := &parsedRequest{}
+	if system, ok := raw["system"]; ok {
+		request.System = contentText(system)
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system", "developer":
+			// OpenAI Chat 的系统提示在 messages 中
+			if request.System == "" {
+				request.System = contentText(msg.Content)
+			}
+		case "tool":
+			request.Messages = append(request.Messages, Message{
+				Role:    "user",
+				Content: []Block{{Type: BlockToolResult, ID: msg.ToolCallID, Text: contentText(msg.Content)}},
+			})
+		default:
+			message := Message{Role: msg.Role, Content: contentBlocks(msg.Content)}
+			for _, call := range msg.ToolCalls {
+				message.Content = append(message.Content, Block{
+					Type: BlockToolUse, ID: call.ID, Name: call.Function.Name, Input: call.Function.Arguments,
+				})
+			}
+			request.Messages = append(request.Messages, message)
+		}
+	}
+
+	return request, nil
+}
+
+// responsesItem Responses API 的 input / output 条目
+type responsesItem struct {
+	Type      string          `json:"type"`
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	Name      string          `json:"name"`
+	Arguments string          `json:"arguments"`
+	CallID    string          `json:"call_id"`
+	Output    json.RawMessage `json:"output"`
+	Summary   []wireBlock     `json:"summary"`
+}
+
+func parseResponsesRequest(raw map[string]json.RawMessage) (*parsedRequest, error) {
+	request := &parsedRequest{}
+	if instructions, ok := raw["instructions"]; ok {
+		request.System = contentText(instructions)
+	}
+
+	var text string
+	if err := json.Unmarshal(raw["input"], &text); err == nil {
+		request.Messages = []Message{{Role: "user", Content: []Block{{Type: BlockText, Text: text}}}}
+		return request, nil
+	}
+
+	var items []responsesItem
+	if err := json.Unmarshal(raw["input"], &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %v", err)
+	}
+	for _, item := range items {
+		if item.Role == "system" || item.Role == "developer" {
+			if request.System == "" {
+				request.System = contentText(item.Content)
+			}
+			continue
+		}
+		if message := responsesItemMessage(item); message != nil {
+			appendMessage(&request.Messages, *message)
+		}
+	}
+
+	return request, nil
+}
+
+// responsesItemMessage 将 Responses 条目转为消息；无法展示的条目返回 nil
+func responsesItemMessage(item responsesItem) *Message {
+	switch item.Type {
+	case "", "message":
+		return &Message{Role: item.Role, Content: contentBlocks(item.Content)}
+	case "function_call", "custom_tool_call":
+		return &Message{Role: "assistant", Content: []Block{{Type: BlockToolUse, ID: item.CallID, Name: item.Name, Input: item.Arguments}}}
+	case "function_call_output", "custom_tool_call_output":
+		return &Message{Role: "user", Content: []Block{{Type: BlockToolResult, ID: item.CallID, Text: contentText(item.Output)}}}
+	case "reasoning":
+		var parts []string
+		for _, summary := range item.Summary {
+			parts = append(parts, summary.Text)
+		}
+		return &Message{Role: "assistant", Content: []Block{{Type: BlockThinking, Text: strings.Join(parts, "\n")}}}
+	}
+	return nil
+}
+
+// appendMessage 追加消息，与上一条角色相同时合并（Responses 中工具调用是独立条目）
+func appendMessage(messages *[]Message, message Message) {
+	if n := len(*messages); n > 0 && (*messages)[n-1].Role == message.Role {
+		(*messages)[n-1].Content = append((*messages)[n-1].Content, message.Content...)
+		return
+	}
+	*messages = append(*messages, message)
+}
+
+// contentBlocks 解析字符串或内容块数组
+func contentBlocks(content json.RawMessage) []Block {
+	if len(content) == 0 || string(content) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return []Block{{Type: BlockText, Text: text}}
+	}
+
+	var blocks []wireBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return []Block{{Type: BlockText, Text: string(content)}}
+	}
+
+	result := make([]Block, 0, len(blocks))
+	for _, block := range blocks {
+		result = append(result, block.toBlock())
+	}
+	return result
+}
+
+// toBlock 将各格式的内容块归一为 Block
+func (b wireBlock) toBlock() Block {
+	switch b.Type {
+	case "text", "input_text", "output_text":
+		return Block{Type: BlockText, Text: b.Text}
+	case "thinking", "redacted_thinking":
+		return Block{Type: BlockThinking, Text: b.Thinking}
+	case "tool_use", "server_tool_use":
+		return Block{Type: BlockToolUse, ID: b.ID, Name: b.Name, Input: string(b.Input)}
+	case "tool_result":
+		return Block{Type: BlockToolResult, ID: b.ToolUseID, Text: contentText(b.Content), IsError: b.IsError}
+	case "image", "image_url", "input_image":
+		return Block{Type: BlockImage}
+	default:
+		return Block{Type: b.Type, Text: b.Text}
+	}
+}
+
+// contentText 将内容拼接为纯文本
+func contentText(content json.RawMessage) string {
+	var parts []string
+	for _, block := range contentBlocks(content) {
+		if block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// parseResponse 解析返回给客户端的响应（JSON 或 SSE）
+func parseResponse(body string) (*parsedResponse, error) {
+	trimmed := strings.TrimSpace(body)
+	if trimmed == "" {
+		return nil, errEmptyBody
+	}
+	if strings.HasPrefix(trimmed, "{") {
+		return parseJSONResponse([]byte(trimmed))
+	}
+	return parseSSEResponse(trimmed)
+}
+
+// ParseUsage 从返回给客户端的响应（JSON 或 SSE）中提取 token 用量
+// 只解析 usage 字段：SSE 中只解码带 usage 的事件，不还原消息内容
+func ParseUsage(body []byte) (Usage, bool) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return Usage{}, false
+	}
+	if trimmed[0] == '{' {
+		var resp struct {
+			Usage *wireUsage `json:"usage"`
+		}
+		if err := json.Unmarshal(trimmed, &resp); err != nil {
+			return Usage{}, false
+		}
+		return resp.Usage.toUsage(), true
+	}
+	return parseSSEUsage(trimmed)
+}
+
+// usageEvent SSE 事件中与用量相关的字段
+type usageEvent struct {
+	Type    string `json:"type"`
+	Message *struct {
+		Usage *wireUsage `json:"usage"`
+	} `json:"message"` // Anthropic message_start
+	Usage    *wireUsage `json:"usage"` // Anthropic message_delta / OpenAI Chat 最后一个 chunk
+	Response *struct {
+		Usage *wireUsage `json:"usage"`
+	} `json:"response"` // Responses response.completed
+}
+
+// parseSSEUsage 按 accumulateAnthropicStream / accumulateChatStream 相同的口径合并流式响应中的用量
+func parseSSEUsage(body []byte) (Usage, bool) {
+	var (
+		found     bool
+		anthropic wireUsage
+		usage     Usage
+	)
+	usageKey := []byte(`"usage"`)
+	for len(body) > 0 {
+		line := body
+		if idx := bytes.IndexByte(body, '\n'); idx >= 0 {
+			line, body = body[:idx], body[idx+1:]
+		} else {
+			body = nil
+		}
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		data := bytes.TrimSpace(line[len("data:"):])
+		if len(data) == 0 || string(data) == "[DONE]" {
+			continue
+		}
+		found = true
+		if !bytes.Contains(data, usageKey) {
+			continue
+		}
+
+		var event usageEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			continue
+		}
+		switch {
+		case event.Type == "message_start" && event.Message != nil && event.Message.Usage != nil:
+			anthropic = *event.Message.Usage
+			usage = anthropic.toUsage()
+		case event.Type == "message_delta" && event.Usage != nil:
+			if event.Usage.OutputTokens > 0 {
+				anthropic.OutputTokens = event.Usage.OutputTokens
+			}
+			if event.Usage.InputTokens > 0 {
+				anthropic.InputTokens = event.Usage.InputTokens
+			}
+			if event.Usage.CacheReadInputTokens > 0 {
+				anthropic.CacheReadInputTokens = event.Usage.CacheReadInputTokens
+			}
+			if event.Usage.CacheCreationInputTokens > 0 {
+				anthropic.CacheCreationInputTokens = event.Usage.CacheCreationInputTokens
+			}
+			usage = anthropic.toUsage()
+		case event.Type == "response.completed" && event.Response != nil:
+			usage = event.Response.Usage.toUsage()
+		case event.Type == "" && event.Usage != nil:
+			usage = event.Usage.toUsage()
+		}
+	}
+	return usage, found
+}
+
+// wireUsage 兼容 Anthropic / OpenAI Chat / Responses 的 usage
+type wireUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	PromptTokens             int `json:"prompt_tokens"`
+	CompletionTokens         int `json:"completion_tokens"`
+	PromptTokensDetails      struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	InputTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"input_tokens_details"`
+}
+
+// toUsage 统一用量口径：OpenAI 的输入 token 包含缓存命中部分，需扣除
+func (w *wireUsage) toUsage() Usage {
+	if w == nil {
+		return Usage{}
+	}
+	if w.PromptTokens > 0 || w.CompletionTokens > 0 {
+		cached := w.PromptTokensDetails.CachedTokens
+		return Usage{InputTokens: w.PromptTokens - cached, OutputTokens: w.CompletionTokens, CacheReadTokens: cached}
+	}
+	if cached := w.InputTokensDetails.CachedTokens; cached > 0 {
+		return Usage{InputTokens: w.InputTokens - cached, OutputTokens: w.OutputTokens, CacheReadTokens: cached}
+	}
+	return Usage{
+		InputTokens:      w.InputTokens,
+		OutputTokens:     w.OutputTokens,
+		CacheReadTokens:  w.CacheReadInputTokens,
+		CacheWriteTokens: w.CacheCreationInputTokens,
+	}
+}
+
+// wireResponse 兼容三种格式的非流式响应
+type wireResponse struct {
+	Content    json.RawMessage `json:"content"`     // Anthropic
+	StopReason string          `json:"stop_reason"` // Anthropic
+	Choices    []struct {
+		Message      wireMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"` // OpenAI Chat
+	Output []responsesItem `json:"output"` // Responses
+	Status string          `json:"status"` // Responses
+	Usage  *wireUsage      `json:"usage"`
+}
+
+func parseJSONResponse(body []byte) (*parsedResponse, error) {
+	var resp wireResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid JSON (body may be truncated): %v", err)
+	}
+	return resp.toParsed()
+}
+
+func (r *wireResponse) toParsed() (*parsedResponse, error) {
+	parsed := &parsedResponse{Usage: r.Usage.toUsage()}
+	switch {
+	case len(r.Choices) > 0:
+		choice := r.Choices[0]
+		message := &Message{Role: "assistant", Content: contentBlocks(choice.Message.Content)}
+		for _, call := range choice.Message.ToolCalls {
+			message.Content = append(message.Content, Block{
+				Type: BlockToolUse, ID: call.ID, Name: call.Function.Name, Input: call.Function.Arguments,
+			})
+		}
+		parsed.Message = message
+		parsed.StopReason = choice.FinishReason
+	case r.Output != nil:
+		messages := []Message{}
+		for _, item := range r.Output {
+			if message := responsesItemMessage(item); message != nil {
+				message.Role = "assistant"
+				appendMessage(&messages, *message)
+			}
+		}
+		parsed.Message = &Message{Role: "assistant"}
+		if len(messages) > 0 {
+			parsed.Message = &messages[0]
+		}
+		parsed.StopReason = r.Status
+	case r.Content != nil:
+		parsed.Message = &Message{Role: "assistant", Content: contentBlocks(r.Content)}
+		parsed.StopReason = r.StopReason
+	default:
+		return nil, errUnknownFormat
+	}
+	return parsed, nil
+}
+
+// sseEvent 流式事件中用到的字段（三种格式共用）
+type sseEvent struct {
+	Type string `json:"type"`
+
+	// Anthropic
+	Message      *wireResponse `json:"message"`
+	Index        int           `json:"index"`
+	ContentBlock *wireBlock    `json:"content_block"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *wireUsage `json:"usage"`
+
+	// OpenAI Chat
+	Choices []struct {
+		Delta struct {
+			Content          string         `json:"content"`
+			ReasoningContent string         `json:"reasoning_content"`
+			ToolCalls        []wireToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+
+	// Responses
+	Response *wireResponse `json:"response"`
+}
+
+// parseSSEResponse 按事件累积流式响应
+func parseSSEResponse(body string) (*parsedResponse, error) {
+	var events []sseEvent
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		var event sseEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	if len(events) == 0 {
+		return nil, errUnknownFormat
+	}
+
+	// Responses：最终事件包含完整响应
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Type == "response.completed" && events[i].Response != nil {
+			return events[i].Response.toParsed()
+		}
+	}
+	// OpenAI Chat 的流式分片没有 type 字段
+	if events[0].Type == "" {
+		return accumulateChatStream(events), nil
+	}
+	return accumulateAnthropicStream(events), nil
+}
+
+func accumulateAnthropicStream(events []sseEvent) *parsedResponse {
+	parsed := &parsedResponse{}
+	blocks := make(map[int]*Block)
+	var usage wireUsage
+	for _, event := range events {
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil && event.Message.Usage != nil {
+				usage = *event.Message.Usage
+			}
+		case "content_block_start":
+			if event.ContentBlock != nil {
+				block := event.ContentBlock.toBlock()
+				if block.Type == BlockToolUse {
+					// 参数由 input_json_delta 增量给出，起始事件中为空对象
+					block.Input = ""
+				}
+				blocks[event.Index] = &block
+			}
+		case "content_block_delta":
+			block, ok := blocks[event.Index]
+			if !ok || event.Delta == nil {
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				block.Text += event.Delta.Text
+			case "thinking_delta":
+				block.Text += event.Delta.Thinking
+			case "input_json_delta":
+				block.Input += event.Delta.PartialJSON
+			}
+		case "message_delta":
+			if event.Delta != nil && event.Delta.StopReason != "" {
+				parsed.StopReason = event.Delta.StopReason
+			}
+			// message_delta 中的用量是累计值，非零字段覆盖 message_start 的值
+			if event.Usage != nil {
+				if event.Usage.OutputTokens > 0 {
+					usage.OutputTokens = event.Usage.OutputTokens
+				}
+				if event.Usage.InputTokens > 0 {
+					usage.InputTokens = event.Usage.InputTokens
+				}
+				if event.Usage.CacheReadInputTokens > 0 {
+					usage.CacheReadInputTokens = event.Usage.CacheReadInputTokens
+				}
+				if event.Usage.CacheCreationInputTokens > 0 {
+					usage.CacheCreationInputTokens = event.Usage.CacheCreationInputTokens
+				}
+			}
+		}
+	}
+
+	parsed.Message = &Message{Role: "assistant", Content: orderedBlocks(blocks)}
+	parsed.Usage = usage.toUsage()
+	return parsed
+}
+
+func accumulateChatStream(events []sseEvent) *parsedResponse {
+	parsed := &parsedResponse{}
+	var reasoning, text strings.Builder
+	calls := make(map[int]*Block)
+	for _, event := range events {
+		if event.Usage != nil {
+			parsed.Usage = event.Usage.toUsage()
+		}
+		for _, choice := range event.Choices {
+			reasoning.WriteString(choice.Delta.ReasoningContent)
+			text.WriteString(choice.Delta.Content)
+			for _, call := range choice.Delta.ToolCalls {
+				block, ok := calls[call.Index]
+				if !ok {
+					block = &Block{Type: BlockToolUse}
+					calls[call.Index] = block
+				}
+				if call.ID != "" {
+					block.ID = call.ID
+				}
+				if call.Function.Name != "" {
+					block.Name = call.Function.Name
+				}
+				block.Input += call.Function.Arguments
+			}
+			if choice.FinishReason != "" {
+				parsed.StopReason = choice.FinishReason
+			}
+		}
+	}
+
+	message := &Message{Role: "assistant"}
+	if reasoning.Len() > 0 {
+		message.Content = append(message.Content, Block{Type: BlockThinking, Text: reasoning.String()})
+	}
+	if text.Len() > 0 {
+		message.Content = append(message.Content, Block{Type: BlockText, Text: text.String()})
+	}
+	message.Content = append(message.Content, orderedBlocks(calls)...)
+	parsed.Message = message
+	return parsed
+}
+
+func orderedBlocks(blocks map[int]*Block) []Block {
+	indexes := make([]int, 0, len(blocks))
+	for index := range blocks {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	result := make([]Block, 0, len(indexes))
+	for _, index := range indexes {
+		result = append(result, *blocks[index])
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package transcript
+
+import (
+	"path/filepath"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+// Cost 按配置的价格（美元 / 百万 token）计算用量费用；没有匹配的价格时返回 false
+// 价格按配置顺序匹配第一条，未配置缓存价格时按输入价格计算
+func Cost(pricing []config.ModelPricingConfig, model string, usage Usage) (float64, bool) {
+	if model == "" {
+		return 0, false
+	}
+	for _, price := range pricing {
+		if matched, err := filepath.Match(price.Model, model); err != nil || !matched {
+			continue
+		}
+		cacheRead, cacheWrite := price.Input, price.Input
+		if price.CacheRead != nil {
+			cacheRead = *price.CacheRead
+		}
+		if price.CacheWrite != nil {
+			cacheWrite = *price.CacheWrite
+		}
+		cost := float64(usage.InputTokens)*price.Input +
+			float64(usage.OutputTokens)*price.Output +
+			float64(usage.CacheReadTokens)*cacheRead +
+			float64(usage.CacheWriteTokens)*cacheWrite
+		return cost / 1e6, true
+	}
+	return 0, false
+}
@@ -0,0 +1,247 @@
+// Package transcript 根据同一会话的请求日志重建对话记录
+package transcript
+
+import (
+	"sort"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/logger"
+)
+
+// 内容块类型
+const (
+	BlockText       = "text"
+	BlockThinking   = "thinking"
+	BlockToolUse    = "tool_use"
+	BlockToolResult = "tool_result"
+	BlockImage      = "image"
+)
+
+// Usage token 用量；InputTokens 不含缓存读写部分
+type Usage struct {
+	InputTokens      int `json:"input_tokens"`
+	OutputTokens     int `json:"output_tokens"`
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+}
+
+// Add 累加用量
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
+}
+
+// Total 全部 token 数
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheWriteTokens
+}
+
+// Block 统一后的消息内容块
+type Block struct {
+	Type    string `json:"type"`
+	Text    string `json:"text,omitempty"`     // text / thinking / tool_result 的文本
+	ID      string `json:"id,omitempty"`       // tool_use 的 ID，或 tool_result 对应的 tool_use ID
+	Name    string `json:"name,omitempty"`     // 工具名
+	Input   string `json:"input,omitempty"`    // 工具参数（JSON）
+	IsError bool   `json:"is_error,omitempty"` // tool_result 是否为错误
+}
+
+// Message 统一后的消息
+type Message struct {
+	Role    string  `json:"role"`
+	Content []Block `json:"content"`
+}
+
+// Attempt 一次上游尝试
+type Attempt struct {
+	AttemptNumber int       `json:"attempt_number"`
+	Timestamp     time.Time `json:"timestamp"`
+	Endpoint      string    `json:"endpoint"`
+	StatusCode    int       `json:"status_code"`
+	DurationMs    int64     `json:"duration_ms"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// Turn 一轮对话，对应一个 request_id；响应取自最后一次尝试
+type Turn struct {
+	Index           int       `json:"index"`
+	RequestID       string    `json:"request_id"`
+	Timestamp       time.Time `json:"timestamp"`
+	Endpoint        string    `json:"endpoint"`                 // 最终应答的端点
+	Model           string    `json:"model,omitempty"`          // 客户端请求的模型
+	UpstreamModel   string    `json:"upstream_model,omitempty"` // 重写后发送给上游的模型
+	ModelRewritten  bool      `json:"model_rewritten"`
+	RequestFormat   string    `json:"request_format,omitempty"`
+	TargetFormat    string    `json:"target_format,omitempty"`
+	FormatConverted bool      `json:"format_converted"`
+	StatusCode      int       `json:"status_code"`
+	Error           string    `json:"error,omitempty"`
+	Tags            []string  `json:"tags,omitempty"`
+	Attempts        []Attempt `json:"attempts"`
+
+	System     string    `json:"system,omitempty"` // 与上一轮不同时才记录
+	Input      []Message `json:"input"`            // 最后一条 assistant 消息之后的新输入
+	Output     *Message  `json:"output,omitempty"`
+	StopReason string    `json:"stop_reason,omitempty"`
+	Warnings   []string  `json:"warnings,omitempty"` // 请求或响应无法完整还原的原因
+
+	Usage           Usage    `json:"usage"`
+	Cost            *float64 `json:"cost,omitempty"` // 未配置价格时为空
+	CumulativeUsage Usage    `json:"cumulative_usage"`
+	CumulativeCost  float64  `json:"cumulative_cost"`
+}
+
+// Transcript 会话的完整对话记录
+type Transcript struct {
+	SessionID      string    `json:"session_id"`
+	ClientType     string    `json:"client_type,omitempty"`
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	Turns          []*Turn   `json:"turns"`
+	Usage          Usage     `json:"usage"`
+	Cost           float64   `json:"cost"`
+	UnpricedModels []string  `json:"unpriced_models,omitempty"` // 未配置价格的模型，其用量不计入费用
+}
+
+// Build 根据按时间排序的会话日志重建对话；同一 request_id 的多次尝试合并为一轮
+func Build(sessionID string, logs []*logger.RequestLog, pricing []config.ModelPricingConfig) *Transcript {
+	t := &Transcript{SessionID: sessionID, Turns: []*Turn{}}
+
+	groups := make(map[string][]*logger.RequestLog)
+	var order []string
+	for _, log := range logs {
+		if _, ok := groups[log.RequestID]; !ok {
+			order = append(order, log.RequestID)
+		}
+		groups[log.RequestID] = append(groups[log.RequestID], log)
+	}
+
+	unpriced := make(map[string]bool)
+	lastSystem := ""
+	for _, requestID := range order {
+		attempts := groups[requestID]
+		sort.SliceStable(attempts, func(i, j int) bool {
+			return attempts[i].AttemptNumber < attempts[j].AttemptNumber
+		})
+		turn := buildTurn(attempts)
+		turn.Index = len(t.Turns) + 1
+
+		if turn.System != "" {
+			if turn.System == lastSystem {
+				turn.System = ""
+			} else {
+				lastSystem = turn.System
+			}
+		}
+
+		pricedModel := turn.UpstreamModel
+		if pricedModel == "" {
+			pricedModel = turn.Model
+		}
+		if cost, ok := Cost(pricing, pricedModel, turn.Usage); ok {
+			turn.Cost = &cost
+			t.Cost += cost
+		} else if turn.Usage.Total() > 0 {
+			unpriced[pricedModel] = true
+		}
+		t.Usage.Add(turn.Usage)
+		turn.CumulativeUsage = t.Usage
+		turn.CumulativeCost = t.Cost
+
+		if t.ClientType == "" {
+			t.ClientType = attempts[0].ClientType
+		}
+		t.Turns = append(t.Turns, turn)
+	}
+
+	if len(logs) > 0 {
+		t.Start = logs[0].Timestamp
+		t.End = logs[len(logs)-1].Timestamp
+	}
+	for model := range unpriced {
+		t.UnpricedModels = append(t.UnpricedModels, model)
+	}
+	sort.Strings(t.UnpricedModels)
+
+	return t
+}
+
+// buildTurn 由同一请求的全部尝试构造一轮对话
+func buildTurn(attempts []*logger.RequestLog) *Turn {
+	first := attempts[0]
+	final := attempts[len(attempts)-1]
+
+	turn := &Turn{
+		RequestID:       final.RequestID,
+		Timestamp:       first.Timestamp,
+		Endpoint:        final.Endpoint,
+		Model:           final.Model,
+		ModelRewritten:  final.ModelRewriteApplied,
+		RequestFormat:   final.RequestFormat,
+		TargetFormat:    final.TargetFormat,
+		FormatConverted: final.FormatConverted,
+		StatusCode:      final.StatusCode,
+		Error:           final.Error,
+		Tags:            final.Tags,
+	}
+	if final.ModelRewriteApplied {
+		turn.UpstreamModel = final.RewrittenModel
+	}
+	for _, log := range attempts {
+		turn.Attempts = append(turn.Attempts, Attempt{
+			AttemptNumber: log.AttemptNumber,
+			Timestamp:     log.Timestamp,
+			Endpoint:      log.Endpoint,
+			StatusCode:    log.StatusCode,
+			DurationMs:    log.DurationMs,
+			Error:         log.Error,
+		})
+	}
+
+	// 请求取客户端发出的原始请求，响应取返回给客户端的最终响应
+	requestBody := firstNonEmpty(final.OriginalRequestBody, final.RequestBody)
+	request, err := parseRequest(requestBody)
+	if err != nil {
+		turn.Warnings = append(turn.Warnings, "request: "+err.Error())
+	} else {
+		turn.System = request.System
+		turn.Input = newInput(request.Messages)
+	}
+
+	if final.StatusCode > 0 && final.StatusCode < 400 {
+		responseBody := firstNonEmpty(final.FinalResponseBody, final.OriginalResponseBody, final.ResponseBody)
+		response, err := parseResponse(responseBody)
+		if err != nil {
+			turn.Warnings = append(turn.Warnings, "response: "+err.Error())
+		} else {
+			turn.Output = response.Message
+			turn.StopReason = response.StopReason
+			turn.Usage = response.Usage
+		}
+	}
+
+	return turn
+}
+
+// newInput 返回最后一条 assistant 消息之后的消息，即本轮新增的输入
+// 更早的历史已经在之前的轮次中出现过
+func newInput(messages []Message) []Message {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" {
+			return messages[i+1:]
+		}
+	}
+	return messages
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,324 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/logger"
+)
+
+const anthropicStream = `event: message_start
+data: {"type":"message_start","message":{"role":"assistant","content":[],"usage":{"input_tokens":100,"cache_read_input_tokens":1000,"cache_creation_input_tokens":200,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"look."}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"Read","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"file_path\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"main.go\"}"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":50}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+
+func sessionLogs(base time.Time) []*logger.RequestLog {
+	return []*logger.RequestLog{
+		{
+			Timestamp: base, RequestID: "req-1", AttemptNumber: 1, Endpoint: "https://primary.example.com",
+			Model: "claude-sonnet-4", StatusCode: 502, Error: "bad gateway", ClientType: "claude-code",
+			OriginalRequestBody: `{"model":"claude-sonnet-4","system":[{"type":"text","text":"You are helpful."}],"messages":[{"role":"user","content":"Read main.go"}]}`,
+		},
+		{
+			Timestamp: base.Add(time.Second), RequestID: "req-1", AttemptNumber: 2, Endpoint: "https://backup.example.com",
+			Model: "claude-sonnet-4", RewrittenModel: "gpt-4o", ModelRewriteApplied: true, StatusCode: 200,
+			ClientType: "claude-code", RequestFormat: "anthropic", TargetFormat: "openai", FormatConverted: true,
+			OriginalRequestBody: `{"model":"claude-sonnet-4","system":[{"type":"text","text":"You are helpful."}],"messages":[{"role":"user","content":"Read main.go"}]}`,
+			FinalResponseBody:   anthropicStream,
+		},
+		{
+			Timestamp: base.Add(time.Minute), RequestID: "req-2", AttemptNumber: 1, Endpoint: "https://primary.example.com",
+			Model: "claude-sonnet-4", StatusCode: 200, ClientType: "claude-code",
+			OriginalRequestBody: `{"model":"claude-sonnet-4","system":"You are helpful.","messages":[
+				{"role":"user","content":"Read main.go"},
+				{"role":"assistant","content":[{"type":"text","text":"Let me look."},{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"main.go"}}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"package main"}]}]}]}`,
+			FinalResponseBody: `{"role":"assistant","content":[{"type":"text","text":"It is a main package."}],"stop_reason":"end_turn","usage":{"input_tokens":300,"output_tokens":20}}`,
+		},
+		{
+			Timestamp: base.Add(2 * time.Minute), RequestID: "req-3", AttemptNumber: 1, Endpoint: "https://primary.example.com",
+			Model: "claude-haiku", StatusCode: 200, ClientType: "claude-code",
+			OriginalRequestBody: `{"model":"claude-haiku","messages":[{"role":"user","content":"trunc`,
+			FinalResponseBody:   `{"role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":10,"output_tokens":2}}`,
+		},
+	}
+}
+
+func float(v float64) *float64 { return &v }
+
+var testPricing = []config.ModelPricingConfig{
+	{Model: "gpt-4o", Input: 2.5, Output: 10},
+	{Model: "claude-sonnet-*", Input: 3, Output: 15, CacheRead: float(0.3), CacheWrite: float(3.75)},
+}
+
+func assertCost(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s: expected %.6f, got %.6f", name, want, got)
+	}
+}
+
+func TestBuild_AnthropicSession(t *testing.T) {
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	transcript := Build("sess-1", sessionLogs(base), testPricing)
+
+	if len(transcript.Turns) != 3 {
+		t.Fatalf("Expected 3 turns, got %d", len(transcript.Turns))
+	}
+	if transcript.ClientType != "claude-code" || !transcript.Start.Equal(base) || !transcript.End.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("Unexpected session summary: %+v", transcript)
+	}
+
+	// 第一轮：重试后由备用端点应答，模型重写 + 格式转换，流式响应
+	first := transcript.Turns[0]
+	if len(first.Attempts) != 2 || first.Endpoint != "https://backup.example.com" || first.StatusCode != 200 {
+		t.Errorf("Expected final attempt to answer the turn, got %+v", first)
+	}
+	if !first.ModelRewritten || first.UpstreamModel != "gpt-4o" || !first.FormatConverted {
+		t.Errorf("Expected rewrite and conversion info, got %+v", first)
+	}
+	if first.System != "You are helpful." || len(first.Input) != 1 || first.Input[0].Content[0].Text != "Read main.go" {
+		t.Errorf("Unexpected first turn input: system=%q input=%+v", first.System, first.Input)
+	}
+	if first.Output == nil || len(first.Output.Content) != 2 {
+		t.Fatalf("Expected text and tool_use output, got %+v", first.Output)
+	}
+	if first.Output.Content[0].Text != "Let me look." || first.Output.Content[1].Input != `{"file_path":"main.go"}` {
+		t.Errorf("Unexpected accumulated stream output: %+v", first.Output.Content)
+	}
+	if first.StopReason != "tool_use" {
+		t.Errorf("Expected stop reason tool_use, got %q", first.StopReason)
+	}
+	wantUsage := Usage{InputTokens: 100, OutputTokens: 50, CacheReadTokens: 1000, CacheWriteTokens: 200}
+	if first.Usage != wantUsage {
+		t.Errorf("Expected usage %+v, got %+v", wantUsage, first.Usage)
+	}
+	// 按重写后的上游模型计价，未配置缓存价格时按输入价格
+	if first.Cost == nil {
+		t.Fatalf("Expected first turn to be priced")
+	}
+	assertCost(t, "first turn cost", *first.Cost, (100*2.5+50*10+1200*2.5)/1e6)
+
+	// 第二轮：只包含最后一条 assistant 消息之后的新输入，相同的系统提示不重复
+	second := transcript.Turns[1]
+	if second.System != "" {
+		t.Errorf("Expected unchanged system prompt to be omitted, got %q", second.System)
+	}
+	if len(second.Input) != 1 || second.Input[0].Content[0].Type != BlockToolResult || second.Input[0].Content[0].Text != "package main" {
+		t.Errorf("Expected only the tool result as new input, got %+v", second.Input)
+	}
+	assertCost(t, "second turn cost", *second.Cost, (300*3+20*15)/1e6)
+	if second.CumulativeUsage.OutputTokens != 70 {
+		t.Errorf("Expected cumulative output tokens 70, got %d", second.CumulativeUsage.OutputTokens)
+	}
+	assertCost(t, "cumulative cost", second.CumulativeCost, *first.Cost+*second.Cost)
+
+	// 第三轮：请求体被截断，响应仍可解析；模型未配置价格
+	third := transcript.Turns[2]
+	if len(third.Warnings) != 1 || !strings.HasPrefix(third.Warnings[0], "request:") {
+		t.Errorf("Expected request warning, got %v", third.Warnings)
+	}
+	if third.Cost != nil || third.Output == nil || third.Output.Content[0].Text != "ok" {
+		t.Errorf("Expected unpriced turn with output, got %+v", third)
+	}
+	if strings.Join(transcript.UnpricedModels, ",") != "claude-haiku" {
+		t.Errorf("Expected claude-haiku to be unpriced, got %v", transcript.UnpricedModels)
+	}
+	if transcript.Usage.OutputTokens != 72 {
+		t.Errorf("Expected total output tokens 72, got %d", transcript.Usage.OutputTokens)
+	}
+	assertCost(t, "session cost", transcript.Cost, second.CumulativeCost)
+}
+
+func TestParse_OpenAIChatStream(t *testing.T) {
+	body := `data: {"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}
+
+data: {"choices":[{"delta":{"content":"lo","tool_calls":[{"index":0,"id":"call_1","function":{"name":"ls","arguments":"{\"pa"}}]}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\".\"}"}}]},"finish_reason":"tool_calls"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":120,"completion_tokens":30,"prompt_tokens_details":{"cached_tokens":100}}}
+
+data: [DONE]
+`
+	response, err := parseResponse(body)
+	if err != nil {
+		t.Fatalf("parseResponse failed: %v", err)
+	}
+	content := response.Message.Content
+	if len(content) != 2 || content[0].Text != "Hello" || content[1].Name != "ls" || content[1].Input != `{"path":"."}` {
+		t.Errorf("Unexpected chat stream output: %+v", content)
+	}
+	if response.StopReason != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %q", response.StopReason)
+	}
+	// OpenAI 的 prompt_tokens 包含缓存命中部分
+	if response.Usage != (Usage{InputTokens: 20, OutputTokens: 30, CacheReadTokens: 100}) {
+		t.Errorf("Unexpected normalized usage: %+v", response.Usage)
+	}
+
+	request, err := parseRequest(`{"messages":[
+		{"role":"system","content":"Be brief."},
+		{"role":"user","content":[{"type":"text","text":"list files"}]},
+		{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","function":{"name":"ls","arguments":"{}"}}]},
+		{"role":"tool","tool_call_id":"call_1","content":"a.go"}]}`)
+	if err != nil {
+		t.Fatalf("parseRequest failed: %v", err)
+	}
+	input := newInput(request.Messages)
+	if request.System != "Be brief." || len(input) != 1 || input[0].Content[0].Type != BlockToolResult || input[0].Content[0].ID != "call_1" {
+		t.Errorf("Unexpected chat request: system=%q input=%+v", request.System, input)
+	}
+}
+
+func TestParse_Responses(t *testing.T) {
+	request, err := parseRequest(`{"instructions":"Be brief.","input":[
+		{"type":"message","role":"user","content":[{"type":"input_text","text":"list files"}]},
+		{"type":"function_call","call_id":"call_1","name":"shell","arguments":"{\"cmd\":\"ls\"}"},
+		{"type":"function_call_output","call_id":"call_1","output":"a.go"}]}`)
+	if err != nil {
+		t.Fatalf("parseRequest failed: %v", err)
+	}
+	input := newInput(request.Messages)
+	if request.System != "Be brief." || len(input) != 1 || input[0].Content[0].Text != "a.go" {
+		t.Errorf("Unexpected responses request: system=%q input=%+v", request.System, input)
+	}
+
+	completed := `event: response.output_text.delta
+data: {"type":"response.output_text.delta","delta":"a.go"}
+
+event: response.completed
+data: {"type":"response.completed","response":{"status":"completed","output":[{"type":"reasoning","summary":[{"type":"summary_text","text":"listing"}]},{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Only a.go"}]}],"usage":{"input_tokens":50,"output_tokens":5,"input_tokens_details":{"cached_tokens":40}}}}
+`
+	response, err := parseResponse(completed)
+	if err != nil {
+		t.Fatalf("parseResponse failed: %v", err)
+	}
+	content := response.Message.Content
+	if len(content) != 2 || content[0].Type != BlockThinking || content[1].Text != "Only a.go" {
+		t.Errorf("Unexpected responses output: %+v", content)
+	}
+	if response.Usage != (Usage{InputTokens: 10, OutputTokens: 5, CacheReadTokens: 40}) || response.StopReason != "completed" {
+		t.Errorf("Unexpected responses usage or status: %+v %q", response.Usage, response.StopReason)
+	}
+}
+
+// ParseUsage 只解码用量字段，结果与完整解析一致
+func TestParseUsage(t *testing.T) {
+	bodies := map[string]string{
+		"anthropic stream": anthropicStream,
+		"anthropic json":   `{"content":[{"type":"text","text":"the usage is fine"}],"usage":{"input_tokens":7,"output_tokens":3,"cache_read_input_tokens":5}}`,
+		"chat stream": `data: {"choices":[{"delta":{"content":"\"usage\" in text"}}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":120,"completion_tokens":30,"prompt_tokens_details":{"cached_tokens":100}}}
+
+data: [DONE]
+`,
+		"responses stream": `event: response.completed
+data: {"type":"response.completed","response":{"status":"completed","output":[],"usage":{"input_tokens":50,"output_tokens":5,"input_tokens_details":{"cached_tokens":40}}}}
+`,
+	}
+	for name, body := range bodies {
+		response, err := parseResponse(body)
+		if err != nil {
+			t.Fatalf("%s: parseResponse failed: %v", name, err)
+		}
+		usage, ok := ParseUsage([]byte(body))
+		if !ok || usage != response.Usage {
+			t.Errorf("%s: expected usage %+v, got %+v (ok=%v)", name, response.Usage, usage, ok)
+		}
+		if usage == (Usage{}) {
+			t.Errorf("%s: expected non-zero usage", name)
+		}
+	}
+
+	if _, ok := ParseUsage([]byte("  ")); ok {
+		t.Errorf("Expected empty body to have no usage")
+	}
+	if _, ok := ParseUsage([]byte(`{"content":`)); ok {
+		t.Errorf("Expected truncated JSON to have no usage")
+	}
+}
+
+func TestExport(t *testing.T) {
+	base := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	transcript := Build("sess-1", sessionLogs(base), testPricing)
+
+	markdown := transcript.Markdown()
+	for _, want := range []string{
+		"# Session sess-1",
+		"## Turn 1",
+		"- Model: claude-sonnet-4 → gpt-4o (rewritten)",
+		"- Format: anthropic → openai (converted)",
+		"  - attempt 1: https://primary.example.com → 502 bad gateway",
+		"**Tool call** `Read` (toolu_1)",
+		"**Tool result** (toolu_1)",
+		"It is a main package.",
+		"- Unpriced models: claude-haiku",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected markdown to contain %q", want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := transcript.WriteJSONL(&buf); err != nil {
+		t.Fatalf("WriteJSONL failed: %v", err)
+	}
+	var types []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record jsonlRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid JSONL line: %v", err)
+		}
+		types = append(types, record.Type)
+		if record.Type == "session" && record.Session.Turns != nil {
+			t.Errorf("Expected session record without turns")
+		}
+	}
+	if strings.Join(types, ",") != "session,turn,turn,turn" {
+		t.Errorf("Unexpected JSONL records: %v", types)
+	}
+}
+
+func TestCost(t *testing.T) {
+	usage := Usage{InputTokens: 1000000, OutputTokens: 1000000}
+	if _, ok := Cost(testPricing, "unknown-model", usage); ok {
+		t.Errorf("Expected no price for unknown model")
+	}
+	cost, ok := Cost(testPricing, "claude-sonnet-4-5", usage)
+	if !ok {
+		t.Fatalf("Expected wildcard price to match")
+	}
+	assertCost(t, "wildcard price", cost, 18)
+}
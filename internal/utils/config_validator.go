@@ -13,6 +13,7 @@ type EndpointConfig interface {
 	GetURL() string
 	GetAuthType() string
 	GetAuthValue() string
+	GetEndpointType() string
 }
 
 // NewEndpointConfigValidator creates a new endpoint configuration validator
@@ -30,12 +31,14 @@ func (v *EndpointConfigValidator) ValidateEndpoint(endpoint EndpointConfig, inde
 		return fmt.Errorf("endpoint %d: url cannot be empty", index)
 	}
 	
-	if endpoint.GetAuthType() != "api_key" && endpoint.GetAuthType() != "auth_token" && endpoint.GetAuthType() != "oauth" {
-		return fmt.Errorf("endpoint %d: invalid auth_type '%s', must be 'api_key', 'auth_token', or 'oauth'", index, endpoint.GetAuthType())
+	switch endpoint.GetAuthType() {
+	case "api_key", "auth_token", "oauth", "aws_sigv4", "gcp_service_account", "entra_id":
+	default:
+		return fmt.Errorf("endpoint %d: invalid auth_type '%s', must be 'api_key', 'auth_token', 'oauth', 'aws_sigv4', 'gcp_service_account', or 'entra_id'", index, endpoint.GetAuthType())
 	}
 	
-	// OAuth 认证不需要 auth_value，其他认证类型需要
-	if endpoint.GetAuthType() != "oauth" && endpoint.GetAuthValue() == "" {
+	// OAuth、SigV4、服务账号与 Entra ID 认证不需要 auth_value，其他认证类型需要（本地 Ollama 通常不需要认证）
+	if endpoint.GetAuthType() != "oauth" && endpoint.GetAuthType() != "aws_sigv4" && endpoint.GetAuthType() != "gcp_service_account" && endpoint.GetAuthType() != "entra_id" && endpoint.GetEndpointType() != "ollama" && endpoint.GetAuthValue() == "" {
 		return fmt.Errorf("endpoint %d: auth_value cannot be empty for non-oauth authentication", index)
 	}
 	
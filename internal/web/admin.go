@@ -9,6 +9,7 @@ import (
 	"claude-code-codex-companion/internal/endpoint"
 	"claude-code-codex-companion/internal/i18n"
 	"claude-code-codex-companion/internal/logger"
+	"claude-code-codex-companion/internal/oauth"
 	"claude-code-codex-companion/internal/security"
 	"claude-code-codex-companion/internal/tagging"
 	"claude-code-codex-companion/internal/webres"
@@ -31,6 +32,7 @@ type AdminServer struct {
 	version           string
 	i18nManager       *i18n.Manager
 	csrfManager       *security.CSRFManager
+	oauthLogins       *oauth.LoginManager
 }
 
 func NewAdminServer(cfg *config.Config, endpointManager *endpoint.Manager, taggingManager *tagging.Manager, log *logger.Logger, configFilePath string, version string, i18nManager *i18n.Manager) *AdminServer {
@@ -43,6 +45,7 @@ func NewAdminServer(cfg *config.Config, endpointManager *endpoint.Manager, taggi
 		version:         version,
 		i18nManager:     i18nManager,
 		csrfManager:     security.NewCSRFManager(),
+		oauthLogins:     oauth.NewLoginManager(),
 	}
 }
 
@@ -211,6 +214,8 @@ func (s *AdminServer) RegisterRoutes(router *gin.Engine) {
 	router.GET("/admin/endpoints", s.handleEndpointsPage)
 	router.GET("/admin/taggers", s.handleTaggersPage)
 	router.GET("/admin/logs", s.handleLogsPage)
+	router.GET("/admin/sessions", s.handleSessionsPage)
+	router.GET("/admin/sessions/:session_id", s.handleSessionPage)
 	router.GET("/admin/settings", s.handleSettingsPage)
 
 	// 注册 API 路由，添加UTF-8字符集中间件和CSRF防护
@@ -227,11 +232,14 @@ func (s *AdminServer) RegisterRoutes(router *gin.Engine) {
 		api.PUT("/endpoints/:id", s.handleUpdateEndpoint)
 		api.PUT("/endpoints/:id/model-rewrite", s.handleUpdateEndpointModelRewrite)
 		api.POST("/endpoints/:id/test-model-rewrite", s.handleTestModelRewrite)
+		api.GET("/endpoints/:id/models", s.handleGetEndpointModels)
 		api.DELETE("/endpoints/:id", s.handleDeleteEndpoint)
 		api.POST("/endpoints/:id/copy", s.handleCopyEndpoint)
 		api.POST("/endpoints/:id/toggle", s.handleToggleEndpoint)
 		api.POST("/endpoints/:id/reset-status", s.handleResetEndpointStatus)
 		api.POST("/endpoints/reorder", s.handleReorderEndpoints)
+		api.POST("/endpoints/oauth/start", s.handleStartOAuthLogin)
+		api.POST("/endpoints/oauth/complete", s.handleCompleteOAuthLogin)
 		
 		// 端点向导路由
 		s.registerEndpointWizardRoutes(api)
@@ -245,7 +253,13 @@ func (s *AdminServer) RegisterRoutes(router *gin.Engine) {
 		api.GET("/logs", s.handleGetLogs)
 		api.POST("/logs/cleanup", s.handleCleanupLogs)
 		api.GET("/logs/stats", s.handleGetLogStats)
+		api.GET("/logs/stream", s.handleLogStream)
+		api.GET("/logs/export", s.handleExportLogs)
 		api.GET("/logs/:request_id/export", s.handleExportDebugInfo)
+		api.GET("/stats/timeseries", s.handleGetStatsTimeSeries)
+		api.GET("/sessions", s.handleGetSessions)
+		api.GET("/sessions/:session_id", s.handleGetSessionTranscript)
+		api.GET("/sessions/:session_id/export", s.handleExportSession)
 		api.PUT("/config", s.handleHotUpdateConfig)
 		api.GET("/config", s.handleGetConfig)
 		api.PUT("/settings", s.handleUpdateSettings)
@@ -83,6 +83,11 @@ func (s *AdminServer) validateConfigUpdate(newConfig *config.Config) error {
 		return err
 	}
 
+	// 配置会先写入文件，必须在保存前拒绝新增或修改的 command 告警 sink，否则重启后仍会被加载
+	if err := config.ValidateCommandSinksUnchanged(s.config.Alerting, newConfig.Alerting); err != nil {
+		return err
+	}
+
 	// 转换为接口类型进行统一验证
 	validator := utils.NewEndpointConfigValidator()
 	endpointInterfaces := make([]utils.EndpointConfig, len(newConfig.Endpoints))
@@ -56,7 +56,7 @@ func (s *AdminServer) handleCreateEndpoint(c *gin.Context) {
 	var request struct {
 		Name              string               `json:"name" binding:"required"`
 		URL               string               `json:"url" binding:"required"`
-		EndpointType      string               `json:"endpoint_type"` // "anthropic" | "openai"
+		EndpointType      string               `json:"endpoint_type"` // "anthropic" | "openai" | "gemini"
 		PathPrefix        string               `json:"path_prefix"`   // OpenAI 端点的路径前缀
 		AuthType          string               `json:"auth_type" binding:"required"`
 		AuthValue         string               `json:"auth_value"`    // OAuth时不需要
@@ -64,6 +64,12 @@ func (s *AdminServer) handleCreateEndpoint(c *gin.Context) {
 		Tags              []string             `json:"tags"`
 		Proxy             *config.ProxyConfig  `json:"proxy,omitempty"` // 新增：代理配置
 		OAuthConfig       *config.OAuthConfig  `json:"oauth_config,omitempty"` // 新增：OAuth配置
+		AWSConfig         *config.AWSConfig    `json:"aws_config,omitempty"`   // 新增：AWS SigV4签名配置
+		VertexConfig      *config.VertexConfig `json:"vertex_config,omitempty"` // 新增：Vertex AI配置
+		AzureConfig       *config.AzureConfig  `json:"azure_config,omitempty"`  // 新增：Azure OpenAI配置
+		OllamaConfig      *config.OllamaConfig `json:"ollama_config,omitempty"` // 新增：Ollama配置
+		TLS               *config.TLSConfig    `json:"tls,omitempty"`           // 新增：TLS配置
+		OpenAIAPI         string               `json:"openai_api,omitempty"`    // 新增：OpenAI端点上游协议
 		HeaderOverrides     map[string]string    `json:"header_overrides,omitempty"`   // 新增：HTTP Header覆盖配置
 		ParameterOverrides  map[string]string    `json:"parameter_overrides,omitempty"` // 新增：Request Parameter覆盖配置
 	}
@@ -104,8 +110,8 @@ func (s *AdminServer) handleCreateEndpoint(c *gin.Context) {
 	}
 
 	// 验证auth_type
-	if request.AuthType != "api_key" && request.AuthType != "auth_token" && request.AuthType != "oauth" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "auth_type must be 'api_key', 'auth_token', or 'oauth'"})
+	if request.AuthType != "api_key" && request.AuthType != "auth_token" && request.AuthType != "oauth" && request.AuthType != "aws_sigv4" && request.AuthType != "gcp_service_account" && request.AuthType != "entra_id" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "auth_type must be 'api_key', 'auth_token', 'oauth', 'aws_sigv4', 'gcp_service_account', or 'entra_id'"})
 		return
 	}
 	
@@ -120,9 +126,10 @@ func (s *AdminServer) handleCreateEndpoint(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid oauth config: " + err.Error()})
 			return
 		}
-	} else {
-		// 非 OAuth 认证需要 auth_value
-		if request.AuthValue == "" {
+	} else if request.AuthType != "aws_sigv4" && request.AuthType != "gcp_service_account" && request.AuthType != "entra_id" {
+		// 非 OAuth 认证需要 auth_value（SigV4 凭证来自 aws_config、环境变量或 profile，服务账号密钥来自 vertex_config，
+		// Entra ID 客户端凭证来自 azure_config；本地 Ollama 通常不需要认证）
+		if request.AuthValue == "" && request.EndpointType != "ollama" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "auth_value is required for non-oauth authentication"})
 			return
 		}
@@ -136,6 +143,14 @@ func (s *AdminServer) handleCreateEndpoint(c *gin.Context) {
 		}
 	}
 
+	// 验证TLS配置（如果提供）
+	if request.TLS != nil {
+		if err := config.ValidateTLSConfig(request.TLS, fmt.Sprintf("endpoint '%s'", request.Name)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tls config: " + err.Error()})
+			return
+		}
+	}
+
 	// 设置默认值 - 移除timeout相关逻辑
 
 	// 获取当前所有端点
@@ -154,6 +169,12 @@ func (s *AdminServer) handleCreateEndpoint(c *gin.Context) {
 		request.Name, request.URL, request.EndpointType, request.PathPrefix,
 		request.AuthType, request.AuthValue,
 		request.Enabled, maxPriority+1, request.Tags, request.Proxy, request.OAuthConfig, request.HeaderOverrides, request.ParameterOverrides)
+	newEndpoint.AWSConfig = request.AWSConfig
+	newEndpoint.VertexConfig = request.VertexConfig
+	newEndpoint.AzureConfig = request.AzureConfig
+	newEndpoint.OpenAIAPI = request.OpenAIAPI
+	newEndpoint.OllamaConfig = request.OllamaConfig
+	newEndpoint.TLS = request.TLS
 	currentEndpoints = append(currentEndpoints, newEndpoint)
 
 	// 使用热更新机制
@@ -190,6 +211,12 @@ func (s *AdminServer) handleUpdateEndpoint(c *gin.Context) {
 		Tags              []string             `json:"tags"`
 		Proxy             *config.ProxyConfig  `json:"proxy,omitempty"` // 新增：代理配置
 		OAuthConfig       *config.OAuthConfig  `json:"oauth_config,omitempty"` // 新增：OAuth配置
+		AWSConfig         *config.AWSConfig    `json:"aws_config,omitempty"`   // 新增：AWS SigV4签名配置
+		VertexConfig      *config.VertexConfig `json:"vertex_config,omitempty"` // 新增：Vertex AI配置
+		AzureConfig       *config.AzureConfig  `json:"azure_config,omitempty"`  // 新增：Azure OpenAI配置
+		OllamaConfig      *config.OllamaConfig `json:"ollama_config,omitempty"` // 新增：Ollama配置
+		TLS               *config.TLSConfig    `json:"tls,omitempty"`           // 新增：TLS配置
+		OpenAIAPI         *string              `json:"openai_api,omitempty"`    // 新增：OpenAI端点上游协议，未提交时保留原值
 		HeaderOverrides     map[string]string    `json:"header_overrides,omitempty"`   // 新增：HTTP Header覆盖配置
 		ParameterOverrides  map[string]string    `json:"parameter_overrides,omitempty"` // 新增：Request Parameter覆盖配置
 	}
@@ -243,6 +270,14 @@ func (s *AdminServer) handleUpdateEndpoint(c *gin.Context) {
 		}
 	}
 
+	// 验证TLS配置（如果提供）
+	if request.TLS != nil {
+		if err := config.ValidateTLSConfig(request.TLS, fmt.Sprintf("endpoint '%s'", endpointName)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tls config: " + err.Error()})
+			return
+		}
+	}
+
 	// 获取当前所有端点
 	currentEndpoints := s.config.Endpoints
 	found := false
@@ -261,9 +296,28 @@ func (s *AdminServer) handleUpdateEndpoint(c *gin.Context) {
 			}
 			// 处理 PathPrefix 字段，允许设置空值（对于 Anthropic 端点）
 			currentEndpoints[i].PathPrefix = request.PathPrefix
+			// Vertex AI 配置：未提交时保留原有配置
+			if request.VertexConfig != nil {
+				currentEndpoints[i].VertexConfig = request.VertexConfig
+			}
+			// Azure OpenAI 配置：未提交时保留原有配置
+			if request.AzureConfig != nil {
+				currentEndpoints[i].AzureConfig = request.AzureConfig
+			}
+			// Ollama 配置：未提交时保留原有配置
+			if request.OllamaConfig != nil {
+				currentEndpoints[i].OllamaConfig = request.OllamaConfig
+			}
+			// TLS 配置：未提交时保留原有配置
+			if request.TLS != nil {
+				currentEndpoints[i].TLS = request.TLS
+			}
+			if request.OpenAIAPI != nil {
+				currentEndpoints[i].OpenAIAPI = *request.OpenAIAPI
+			}
 			if request.AuthType != "" {
-				if request.AuthType != "api_key" && request.AuthType != "auth_token" && request.AuthType != "oauth" {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "auth_type must be 'api_key', 'auth_token', or 'oauth'"})
+				if request.AuthType != "api_key" && request.AuthType != "auth_token" && request.AuthType != "oauth" && request.AuthType != "aws_sigv4" && request.AuthType != "gcp_service_account" && request.AuthType != "entra_id" {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "auth_type must be 'api_key', 'auth_token', 'oauth', 'aws_sigv4', 'gcp_service_account', or 'entra_id'"})
 					return
 				}
 				
@@ -298,6 +352,17 @@ func (s *AdminServer) handleUpdateEndpoint(c *gin.Context) {
 					// 设置OAuth配置，清空auth_value
 					currentEndpoints[i].OAuthConfig = request.OAuthConfig
 					currentEndpoints[i].AuthValue = ""
+				} else if request.AuthType == "aws_sigv4" {
+					// SigV4 签名不使用 auth_value，未提交 aws_config 时保留原有配置
+					currentEndpoints[i].OAuthConfig = nil
+					currentEndpoints[i].AuthValue = ""
+					if request.AWSConfig != nil {
+						currentEndpoints[i].AWSConfig = request.AWSConfig
+					}
+				} else if request.AuthType == "gcp_service_account" || request.AuthType == "entra_id" {
+					// 服务账号与 Entra ID 令牌运行时换取，不使用 auth_value
+					currentEndpoints[i].OAuthConfig = nil
+					currentEndpoints[i].AuthValue = ""
 				} else {
 					// 非 OAuth 认证，清空OAuth配置
 					currentEndpoints[i].OAuthConfig = nil
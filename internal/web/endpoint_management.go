@@ -107,6 +107,7 @@ func (s *AdminServer) handleCopyEndpoint(c *gin.Context) {
 		URL:               sourceEndpoint.URL,
 		EndpointType:      sourceEndpoint.EndpointType,
 		PathPrefix:        sourceEndpoint.PathPrefix,
+		OpenAIAPI:         sourceEndpoint.OpenAIAPI,
 		AuthType:          sourceEndpoint.AuthType,
 		AuthValue:         sourceEndpoint.AuthValue,
 		Enabled:           sourceEndpoint.Enabled,
@@ -126,16 +127,49 @@ func (s *AdminServer) handleCopyEndpoint(c *gin.Context) {
 		copy(newEndpoint.ModelRewrite.Rules, sourceEndpoint.ModelRewrite.Rules)
 	}
 
-	// 深度复制Proxy配置
-	if sourceEndpoint.Proxy != nil {
-		newEndpoint.Proxy = &config.ProxyConfig{
-			Type:     sourceEndpoint.Proxy.Type,
-			Address:  sourceEndpoint.Proxy.Address,
-			Username: sourceEndpoint.Proxy.Username,
-			Password: sourceEndpoint.Proxy.Password,
+	// 深度复制AWS签名配置
+	if sourceEndpoint.AWSConfig != nil {
+		awsConfig := *sourceEndpoint.AWSConfig
+		newEndpoint.AWSConfig = &awsConfig
+	}
+
+	// 深度复制Vertex AI配置
+	if sourceEndpoint.VertexConfig != nil {
+		vertexConfig := *sourceEndpoint.VertexConfig
+		vertexConfig.Scopes = append([]string(nil), sourceEndpoint.VertexConfig.Scopes...)
+		newEndpoint.VertexConfig = &vertexConfig
+	}
+
+	// 深度复制Azure OpenAI配置
+	if sourceEndpoint.AzureConfig != nil {
+		azureConfig := *sourceEndpoint.AzureConfig
+		if sourceEndpoint.AzureConfig.Deployments != nil {
+			azureConfig.Deployments = make(map[string]string, len(sourceEndpoint.AzureConfig.Deployments))
+			for model, deployment := range sourceEndpoint.AzureConfig.Deployments {
+				azureConfig.Deployments[model] = deployment
+			}
 		}
+		newEndpoint.AzureConfig = &azureConfig
 	}
 
+	// 深度复制Ollama配置
+	if sourceEndpoint.OllamaConfig != nil {
+		ollamaConfig := *sourceEndpoint.OllamaConfig
+		if sourceEndpoint.OllamaConfig.Options != nil {
+			ollamaConfig.Options = make(map[string]interface{}, len(sourceEndpoint.OllamaConfig.Options))
+			for key, value := range sourceEndpoint.OllamaConfig.Options {
+				ollamaConfig.Options[key] = value
+			}
+		}
+		newEndpoint.OllamaConfig = &ollamaConfig
+	}
+
+	// 深度复制TLS配置
+	newEndpoint.TLS = copyTLSConfig(sourceEndpoint.TLS)
+
+	// 深度复制Proxy配置（包括代理链）
+	newEndpoint.Proxy = copyProxyConfig(sourceEndpoint.Proxy)
+
 	// 添加到端点列表
 	currentEndpoints = append(currentEndpoints, newEndpoint)
 
@@ -189,6 +223,35 @@ func (s *AdminServer) handleResetEndpointStatus(c *gin.Context) {
 	})
 }
 
+// handleGetEndpointModels 获取端点上可用的模型列表（目前支持 ollama 端点的 /api/tags），供模型重写规则选择目标模型
+func (s *AdminServer) handleGetEndpointModels(c *gin.Context) {
+	encodedEndpointName := c.Param("id") // 端点名称
+	endpointName, err := url.PathUnescape(encodedEndpointName)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endpoint name encoding"})
+		return
+	}
+
+	for _, ep := range s.endpointManager.GetAllEndpoints() {
+		if ep.Name != endpointName {
+			continue
+		}
+		if ep.EndpointType != "ollama" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Model discovery is only supported for ollama endpoints"})
+			return
+		}
+		models, err := ep.ListOllamaModels(s.config.Timeouts.ToHealthCheckTimeoutConfig())
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to list models: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"models": models})
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": "Endpoint not found"})
+}
+
 // handleReorderEndpoints 重新排序端点
 func (s *AdminServer) handleReorderEndpoints(c *gin.Context) {
 	var request struct {
@@ -233,4 +296,28 @@ func (s *AdminServer) handleReorderEndpoints(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Endpoints reordered successfully"})
-}
\ No newline at end of file
+}
+
+// copyProxyConfig 深度复制代理配置（包括 via 代理链与 TLS 配置）
+func copyProxyConfig(source *config.ProxyConfig) *config.ProxyConfig {
+	if source == nil {
+		return nil
+	}
+	proxyConfig := *source
+	proxyConfig.Via = copyProxyConfig(source.Via)
+	proxyConfig.TLS = copyTLSConfig(source.TLS)
+	return &proxyConfig
+}
+
+// copyTLSConfig 深度复制TLS配置
+func copyTLSConfig(source *config.TLSConfig) *config.TLSConfig {
+	if source == nil {
+		return nil
+	}
+	tlsConfig := *source
+	if source.PinnedSPKISHA256 != nil {
+		tlsConfig.PinnedSPKISHA256 = make([]string, len(source.PinnedSPKISHA256))
+		copy(tlsConfig.PinnedSPKISHA256, source.PinnedSPKISHA256)
+	}
+	return &tlsConfig
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/oauth"
+	"claude-code-codex-companion/internal/security"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleStartOAuthLogin 发起 PKCE 授权码登录，返回需要在浏览器中打开的授权地址
+func (s *AdminServer) handleStartOAuthLogin(c *gin.Context) {
+	var request oauth.LoginOptions
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+		return
+	}
+
+	for _, u := range []string{request.AuthorizeURL, request.TokenURL} {
+		if u == "" {
+			continue
+		}
+		if err := security.ValidateURL(u); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid OAuth URL: " + err.Error()})
+			return
+		}
+	}
+
+	session, err := s.oauthLogins.Start(request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start OAuth login: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"state":         session.State,
+		"authorize_url": session.AuthorizeURL,
+	})
+}
+
+// handleCompleteOAuthLogin 使用用户粘贴的授权码换取 token
+// 指定 endpoint_name 时直接将结果保存为该端点的 OAuth 配置，否则仅返回配置供表单填充
+func (s *AdminServer) handleCompleteOAuthLogin(c *gin.Context) {
+	var request struct {
+		State        string              `json:"state"`
+		Code         string              `json:"code" binding:"required"`
+		EndpointName string              `json:"endpoint_name,omitempty"`
+		Proxy        *config.ProxyConfig `json:"proxy,omitempty"` // 新建端点时使用表单中的代理配置
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+		return
+	}
+
+	// 已有端点使用其代理配置访问 token 端点
+	// 端点的 TLS 配置（CA、证书固定）只针对上游 API，不应用到 token 端点
+	proxyConfig := request.Proxy
+	if request.EndpointName != "" {
+		var existing *endpoint.Endpoint
+		for _, candidate := range s.endpointManager.GetAllEndpoints() {
+			if candidate.Name == request.EndpointName {
+				existing = candidate
+				break
+			}
+		}
+		if existing == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Endpoint not found"})
+			return
+		}
+		proxyConfig = existing.Proxy
+	}
+	ep := endpoint.NewEndpoint(config.EndpointConfig{Name: "oauth-login", Proxy: proxyConfig})
+	ep.SetGlobalProxy(&s.config.Proxy)
+
+	client, err := ep.CreateHealthClient(s.config.Timeouts.ToHealthCheckTimeoutConfig())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create http client: " + err.Error()})
+		return
+	}
+
+	oauthConfig, err := s.oauthLogins.Complete(request.State, request.Code, client)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "OAuth login failed: " + err.Error()})
+		return
+	}
+
+	if request.EndpointName != "" {
+		if err := s.saveEndpointOAuthConfig(request.EndpointName, oauthConfig); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save oauth config: " + err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"oauth_config": oauthConfig})
+}
+
+// saveEndpointOAuthConfig 将登录得到的 OAuth 配置保存到端点，并切换为 oauth 认证
+func (s *AdminServer) saveEndpointOAuthConfig(endpointName string, oauthConfig *config.OAuthConfig) error {
+	currentEndpoints := make([]config.EndpointConfig, len(s.config.Endpoints))
+	copy(currentEndpoints, s.config.Endpoints)
+
+	for i := range currentEndpoints {
+		if currentEndpoints[i].Name == endpointName {
+			currentEndpoints[i].AuthType = "oauth"
+			currentEndpoints[i].AuthValue = ""
+			currentEndpoints[i].OAuthConfig = oauthConfig
+			return s.hotUpdateEndpoints(currentEndpoints)
+		}
+	}
+
+	return fmt.Errorf("endpoint not found: %s", endpointName)
+}
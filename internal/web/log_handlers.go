@@ -3,14 +3,18 @@ package web
 import (
 	"archive/zip"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/logexport"
 	"claude-code-codex-companion/internal/logger"
 	"claude-code-codex-companion/internal/security"
 	"claude-code-codex-companion/internal/i18n"
@@ -18,75 +22,146 @@ import (
 )
 
 func (s *AdminServer) handleLogsPage(c *gin.Context) {
-	// 获取参数
-	pageStr := c.DefaultQuery("page", strconv.Itoa(config.Default.Pagination.DefaultPage))
-	failedOnlyStr := c.DefaultQuery("failed_only", "false")
-	
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < config.Default.Pagination.DefaultPage {
-		page = config.Default.Pagination.DefaultPage
+	filters := logFilterParams(c)
+	failedOnly, _ := strconv.ParseBool(c.DefaultQuery("failed_only", "false"))
+
+	// 页面使用统一默认的每页记录数
+	query, err := s.buildLogQuery(c)
+	query.Limit = config.Default.Pagination.DefaultLimit
+
+	var page *logger.LogPage
+	filterError := ""
+	if err != nil {
+		filterError = err.Error()
+	} else if page, err = s.logger.SearchLogs(query); err != nil {
+		filterError = err.Error()
 	}
-	
-	failedOnly, _ := strconv.ParseBool(failedOnlyStr)
-	
-	// 每页记录数使用统一默认值
-	limit := config.Default.Pagination.DefaultLimit
-	offset := (page - 1) * limit
-	
-	logs, total, _ := s.logger.GetLogs(limit, offset, failedOnly)
-	
-	// 计算分页信息
-	totalPages := (total + limit - 1) / limit
-	if totalPages == 0 {
-		totalPages = config.Default.Pagination.MaxPages
+	if page == nil {
+		page = &logger.LogPage{Logs: []*logger.RequestLog{}}
 	}
-	
-	// 生成分页数组
-	var pages []int
-	startPage := page - 5
-	if startPage < config.Default.Pagination.DefaultPage {
-		startPage = config.Default.Pagination.DefaultPage
-	}
-	endPage := startPage + 9
-	if endPage > totalPages {
-		endPage = totalPages
-		startPage = endPage - 9
-		if startPage < config.Default.Pagination.DefaultPage {
-			startPage = config.Default.Pagination.DefaultPage
+
+	// 翻页链接保留当前过滤条件
+	values := url.Values{}
+	hasFilters := false
+	for key, value := range filters {
+		if value != "" {
+			values.Set(key, value)
+			hasFilters = true
 		}
 	}
-	
-	for i := startPage; i <= endPage; i++ {
-		pages = append(pages, i)
+	if failedOnly {
+		values.Set("failed_only", "true")
 	}
-	
+	firstPageURL := "/admin/logs"
+	if encoded := values.Encode(); encoded != "" {
+		firstPageURL += "?" + encoded
+	}
+	// 批量导出使用相同的过滤条件
+	exportURLs := map[string]template.URL{}
+	for _, format := range []string{logexport.FormatHAR, logexport.FormatAnthropic, logexport.FormatOpenAI} {
+		exportValues := url.Values{}
+		for key, value := range values {
+			exportValues[key] = value
+		}
+		exportValues.Set("format", format)
+		exportURLs[format] = template.URL("/admin/api/logs/export?" + exportValues.Encode())
+	}
+	nextPageURL := ""
+	if page.NextCursor != "" {
+		values.Set("cursor", page.NextCursor)
+		nextPageURL = "/admin/logs?" + values.Encode()
+	}
+
+	var endpointNames []string
+	for _, ep := range s.config.Endpoints {
+		endpointNames = append(endpointNames, ep.Name)
+	}
+
 	data := s.mergeTemplateData(c, "logs", map[string]interface{}{
-		"Title":       "Request Logs",
-		"Logs":        logs,
-		"Total":       total,
-		"FailedOnly":  failedOnly,
-		"Page":        page,
-		"TotalPages":  totalPages,
-		"Pages":       pages,
-		"HasPrev":     page > 1,
-		"HasNext":     page < totalPages,
-		"PrevPage":    page - 1,
-		"NextPage":    page + 1,
-		"Limit":       limit,
+		"Title":        "Request Logs",
+		"Logs":         page.Logs,
+		"Total":        page.Total,
+		"FailedOnly":   failedOnly,
+		"Filters":      filters,
+		"HasFilters":   hasFilters,
+		"FilterError":  filterError,
+		"Endpoints":    endpointNames,
+		"IsFirstPage":  query.Cursor == "",
+		"FirstPageURL": template.URL(firstPageURL),
+		"NextPageURL":  template.URL(nextPageURL),
+		"ExportURLs":   exportURLs,
+		"Limit":        query.Limit,
 	})
 	s.renderHTML(c, "logs.html", data)
 }
 
+// logFilterParams 日志过滤条件的原始查询参数，用于回填过滤表单和生成翻页链接
+func logFilterParams(c *gin.Context) map[string]string {
+	filters := map[string]string{}
+	for _, key := range []string{"q", "endpoint", "model", "client_type", "session", "tag", "status", "from", "to", "format_converted"} {
+		filters[key] = strings.TrimSpace(c.Query(key))
+	}
+	return filters
+}
+
+// buildLogQuery 从查询参数构造日志检索条件
+func (s *AdminServer) buildLogQuery(c *gin.Context) (logger.LogQuery, error) {
+	filters := logFilterParams(c)
+	query := logger.LogQuery{
+		Endpoint:   s.resolveLogEndpoint(filters["endpoint"]),
+		Model:      filters["model"],
+		ClientType: filters["client_type"],
+		SessionID:  filters["session"],
+		Tag:        filters["tag"],
+		Text:       filters["q"],
+		Cursor:     c.Query("cursor"),
+	}
+	query.FailedOnly, _ = strconv.ParseBool(c.DefaultQuery("failed_only", "false"))
+
+	var err error
+	if query.Status, err = logger.ParseStatusRange(filters["status"]); err != nil {
+		return query, err
+	}
+	if filters["from"] != "" {
+		if query.From, err = logger.ParseLogTime(filters["from"]); err != nil {
+			return query, err
+		}
+	}
+	if filters["to"] != "" {
+		if query.To, err = logger.ParseLogTimeEnd(filters["to"]); err != nil {
+			return query, err
+		}
+	}
+	if filters["format_converted"] != "" {
+		converted, err := strconv.ParseBool(filters["format_converted"])
+		if err != nil {
+			return query, fmt.Errorf("invalid format_converted: %s", filters["format_converted"])
+		}
+		query.FormatConverted = &converted
+	}
+
+	query.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if query.Limit <= 0 {
+		query.Limit = 100
+	} else if query.Limit > 1000 {
+		query.Limit = 1000
+	}
+	return query, nil
+}
+
+// resolveLogEndpoint 日志中记录的是端点 URL，按名称过滤时转换为对应的 URL
+func (s *AdminServer) resolveLogEndpoint(value string) string {
+	for _, ep := range s.config.Endpoints {
+		if ep.Name == value {
+			return ep.URL
+		}
+	}
+	return value
+}
+
 func (s *AdminServer) handleGetLogs(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "100")
-	offsetStr := c.DefaultQuery("offset", "0")
-	failedOnlyStr := c.DefaultQuery("failed_only", "false")
 	requestIDStr := c.DefaultQuery("request_id", "")
 
-	limit, _ := strconv.Atoi(limitStr)
-	offset, _ := strconv.Atoi(offsetStr)
-	failedOnly, _ := strconv.ParseBool(failedOnlyStr)
-
 	if requestIDStr != "" {
 		// 如果指定了request_id，返回该请求的所有尝试记录
 		allLogs, _ := s.logger.GetAllLogsByRequestID(requestIDStr)
@@ -97,16 +172,41 @@ func (s *AdminServer) handleGetLogs(c *gin.Context) {
 		return
 	}
 
-	logs, total, err := s.logger.GetLogs(limit, offset, failedOnly)
+	// 兼容旧的 offset 分页方式
+	if offsetStr, ok := c.GetQuery("offset"); ok {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		offset, _ := strconv.Atoi(offsetStr)
+		failedOnly, _ := strconv.ParseBool(c.DefaultQuery("failed_only", "false"))
+
+		logs, total, err := s.logger.GetLogs(limit, offset, failedOnly)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve logs"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"logs":  logs,
+			"total": total,
+		})
+		return
+	}
+
+	query, err := s.buildLogQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := s.logger.SearchLogs(query)
 	if err != nil {
+		if errors.Is(err, logger.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve logs"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"logs":  logs,
-		"total": total,
-	})
+	c.JSON(http.StatusOK, page)
 }
 
 // handleCleanupLogs 清理日志
@@ -154,14 +254,28 @@ func (s *AdminServer) handleCleanupLogs(c *gin.Context) {
 	})
 }
 
+// retentionMaxAge 保留时长的展示文本，未配置时为默认的 720h
+func retentionMaxAge(maxAge string) string {
+	switch maxAge {
+	case "":
+		return "720h"
+	case "0":
+		return "unlimited"
+	default:
+		return maxAge
+	}
+}
+
 // handleGetLogStats 获取日志统计信息
 func (s *AdminServer) handleGetLogStats(c *gin.Context) {
 	// SQLite存储提供基本统计信息
+	retention := s.config.Logging.Retention
 	stats := map[string]interface{}{
 		"storage_type": "sqlite",
-		"message": "SQLite storage active with automatic cleanup (30 days retention)",
+		"message": fmt.Sprintf("SQLite storage active with automatic cleanup (success logs: %s, failed logs: %s)",
+			retentionMaxAge(retention.Success.MaxAge), retentionMaxAge(retention.Failed.MaxAge)),
 		"features": []string{
-			"Automatic cleanup of logs older than 30 days",
+			"Retention policy by age, row count and size for successful and failed requests",
 			"Indexed queries for better performance", 
 			"Memory efficient storage",
 			"ACID transactions",
@@ -173,6 +287,46 @@ func (s *AdminServer) handleGetLogStats(c *gin.Context) {
 	})
 }
 
+// handleExportLogs 按日志检索条件批量导出日志
+// format：har（默认）| anthropic | openai；limit：最多读取的日志条数，默认 10000
+func (s *AdminServer) handleExportLogs(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", logexport.FormatHAR))
+	if !logexport.ValidFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'har', 'anthropic' or 'openai'"})
+		return
+	}
+
+	query, err := s.buildLogQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := logexport.DefaultLimit
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > logexport.MaxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", logexport.MaxLimit)})
+			return
+		}
+	}
+
+	filename := logexport.FileName(format, time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", logexport.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Status(http.StatusOK)
+
+	// 导出前按脱敏规则处理，覆盖启用脱敏前记录的日志
+	_, err = logexport.Export(c.Writer, s.logger, logexport.Options{
+		Format:         format,
+		Query:          query,
+		Limit:          limit,
+		Redact:         s.logger.RedactLog,
+		CreatorVersion: s.version,
+	})
+	if err != nil {
+		c.Error(err)
+	}
+}
+
 // handleExportDebugInfo 导出指定请求的调试信息为ZIP文件
 func (s *AdminServer) handleExportDebugInfo(c *gin.Context) {
 	requestID := c.Param("request_id")
@@ -236,8 +390,9 @@ func (s *AdminServer) generateDebugInfoZip(requestID string, logs []*logger.Requ
 		return nil, err
 	}
 
-	// 为每次尝试创建目录和文件
+	// 为每次尝试创建目录和文件（导出前按脱敏规则处理，覆盖启用脱敏前记录的日志）
 	for i, log := range logs {
+		s.logger.RedactLog(log)
 		attemptDir := fmt.Sprintf("attempts/attempt_%d/", i+1)
 		if err := s.addLogFilesToZip(zipWriter, attemptDir, log); err != nil {
 			return nil, err
@@ -304,6 +459,7 @@ func (s *AdminServer) generateReadmeContent(requestID string, logs []*logger.Req
 	readme.WriteString("- final_*: Data sent by the proxy to upstream (after transformations)\n\n")
 	
 	readme.WriteString("NOTE: Authentication values in endpoint configurations have been sanitized.\n")
+	readme.WriteString("NOTE: Credentials in headers, bodies and URLs have been masked according to logging.redaction.\n")
 	
 	return readme.String()
 }
@@ -481,11 +637,36 @@ func (s *AdminServer) addEndpointConfigsToZip(zipWriter *zip.Writer, logs []*log
 			}
 
 			// 清空代理配置中的敏感信息
-			if sanitizedConfig.Proxy != nil {
-				sanitizedProxy := *sanitizedConfig.Proxy
-				sanitizedProxy.Username = "[REDACTED]"
-				sanitizedProxy.Password = "[REDACTED]"
-				sanitizedConfig.Proxy = &sanitizedProxy
+			sanitizedConfig.Proxy = sanitizeProxyConfig(sanitizedConfig.Proxy)
+
+			// 清空 AWS 静态凭证
+			if sanitizedConfig.AWSConfig != nil {
+				sanitizedAWS := *sanitizedConfig.AWSConfig
+				if sanitizedAWS.SecretAccessKey != "" {
+					sanitizedAWS.SecretAccessKey = "[REDACTED]"
+				}
+				if sanitizedAWS.SessionToken != "" {
+					sanitizedAWS.SessionToken = "[REDACTED]"
+				}
+				sanitizedConfig.AWSConfig = &sanitizedAWS
+			}
+
+			// 清空内联的服务账号密钥
+			if sanitizedConfig.VertexConfig != nil {
+				sanitizedVertex := *sanitizedConfig.VertexConfig
+				if sanitizedVertex.ServiceAccountJSON != "" {
+					sanitizedVertex.ServiceAccountJSON = "[REDACTED]"
+				}
+				sanitizedConfig.VertexConfig = &sanitizedVertex
+			}
+
+			// 清空 Entra ID 客户端密码
+			if sanitizedConfig.AzureConfig != nil {
+				sanitizedAzure := *sanitizedConfig.AzureConfig
+				if sanitizedAzure.ClientSecret != "" {
+					sanitizedAzure.ClientSecret = "[REDACTED]"
+				}
+				sanitizedConfig.AzureConfig = &sanitizedAzure
 			}
 
 			configJSON, err := json.MarshalIndent(sanitizedConfig, "", "  ")
@@ -503,6 +684,18 @@ func (s *AdminServer) addEndpointConfigsToZip(zipWriter *zip.Writer, logs []*log
 	return nil
 }
 
+// sanitizeProxyConfig 复制代理配置并清空认证信息，沿 via 代理链逐级处理
+func sanitizeProxyConfig(proxy *config.ProxyConfig) *config.ProxyConfig {
+	if proxy == nil {
+		return nil
+	}
+	sanitizedProxy := *proxy
+	sanitizedProxy.Username = "[REDACTED]"
+	sanitizedProxy.Password = "[REDACTED]"
+	sanitizedProxy.Via = sanitizeProxyConfig(proxy.Via)
+	return &sanitizedProxy
+}
+
 // addTaggerConfigsToZip 添加tagger配置到ZIP
 func (s *AdminServer) addTaggerConfigsToZip(zipWriter *zip.Writer, logs []*logger.RequestLog) error {
 	taggerNames := make(map[string]bool)
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestSanitizeProxyConfig_RedactsViaChain(t *testing.T) {
+	original := &config.ProxyConfig{
+		Type:     "socks5",
+		Address:  "10.0.0.1:1080",
+		Username: "outer-user",
+		Password: "outer-pass",
+		Via: &config.ProxyConfig{
+			Type:     "http",
+			Address:  "10.0.0.2:8080",
+			Username: "middle-user",
+			Password: "middle-pass",
+			Via: &config.ProxyConfig{
+				Type:     "https",
+				Address:  "10.0.0.3:443",
+				Username: "inner-user",
+				Password: "inner-pass",
+			},
+		},
+	}
+
+	sanitized := sanitizeProxyConfig(original)
+
+	depth := 0
+	for proxy := sanitized; proxy != nil; proxy = proxy.Via {
+		depth++
+		if proxy.Username != "[REDACTED]" || proxy.Password != "[REDACTED]" {
+			t.Errorf("level %d: credentials not redacted: %q / %q", depth, proxy.Username, proxy.Password)
+		}
+	}
+	if depth != 3 {
+		t.Fatalf("expected 3 proxy levels, got %d", depth)
+	}
+	if sanitized.Via.Via.Address != "10.0.0.3:443" {
+		t.Errorf("expected addresses to be kept, got %q", sanitized.Via.Via.Address)
+	}
+
+	// 原始配置不能被修改
+	if original.Password != "outer-pass" || original.Via.Password != "middle-pass" || original.Via.Via.Password != "inner-pass" {
+		t.Errorf("live proxy config was mutated: %+v", original)
+	}
+
+	if sanitizeProxyConfig(nil) != nil {
+		t.Errorf("expected nil proxy to stay nil")
+	}
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// logStreamHeartbeatInterval 心跳间隔，防止空闲连接被代理或浏览器断开
+const logStreamHeartbeatInterval = 15 * time.Second
+
+// handleLogStream 通过 SSE 实时推送新日志和端点状态变化，过滤参数与日志检索相同
+func (s *AdminServer) handleLogStream(c *gin.Context) {
+	query, err := s.buildLogQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hub := s.logger.Hub()
+	if hub == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Log stream not available"})
+		return
+	}
+
+	sub := hub.Subscribe(query)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	writeEvent := func(event string, data interface{}) bool {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return true
+		}
+		if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+
+	if !writeEvent("ready", gin.H{"time": time.Now()}) {
+		return
+	}
+
+	heartbeat := time.NewTicker(logStreamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			// 客户端消费过慢时缓冲区中的事件会被丢弃，先告知丢弃数量
+			if dropped := sub.TakeDropped(); dropped > 0 {
+				if !writeEvent("dropped", gin.H{"count": dropped}) {
+					return
+				}
+			}
+			if !writeEvent(event.Type, event) {
+				return
+			}
+		}
+	}
+}
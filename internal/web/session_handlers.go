@@ -0,0 +1,135 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/transcript"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleSessionsPage 会话列表页面
+func (s *AdminServer) handleSessionsPage(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", strconv.Itoa(config.Default.Pagination.DefaultPage)))
+	if err != nil || page < config.Default.Pagination.DefaultPage {
+		page = config.Default.Pagination.DefaultPage
+	}
+
+	limit := config.Default.Pagination.DefaultLimit
+	sessions, total, err := s.logger.ListSessions(limit, (page-1)*limit)
+	errorMessage := ""
+	if err != nil {
+		errorMessage = err.Error()
+	}
+
+	totalPages := (total + limit - 1) / limit
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	data := s.mergeTemplateData(c, "sessions", map[string]interface{}{
+		"Title":      "Sessions",
+		"Sessions":   sessions,
+		"Total":      total,
+		"Error":      errorMessage,
+		"Page":       page,
+		"TotalPages": totalPages,
+		"HasPrev":    page > 1,
+		"HasNext":    page < totalPages,
+		"PrevPage":   page - 1,
+		"NextPage":   page + 1,
+		"Limit":      limit,
+	})
+	s.renderHTML(c, "sessions.html", data)
+}
+
+// handleSessionPage 会话对话记录页面，内容由前端通过 API 加载
+func (s *AdminServer) handleSessionPage(c *gin.Context) {
+	data := s.mergeTemplateData(c, "sessions", map[string]interface{}{
+		"Title":     "Session " + c.Param("session_id"),
+		"SessionID": c.Param("session_id"),
+	})
+	s.renderHTML(c, "session.html", data)
+}
+
+// handleGetSessions 会话列表 API
+func (s *AdminServer) handleGetSessions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(config.Default.Pagination.DefaultLimit)))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 1000 {
+		limit = config.Default.Pagination.DefaultLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	sessions, total, err := s.logger.ListSessions(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"total":    total,
+	})
+}
+
+// buildSessionTranscript 根据会话日志重建对话记录；会话不存在时返回 nil
+func (s *AdminServer) buildSessionTranscript(c *gin.Context) *transcript.Transcript {
+	sessionID := c.Param("session_id")
+	logs, err := s.logger.GetLogsBySessionID(sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get session logs: " + err.Error()})
+		return nil
+	}
+	if len(logs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No logs found for the given session ID"})
+		return nil
+	}
+	return transcript.Build(sessionID, logs, s.config.Pricing)
+}
+
+// handleGetSessionTranscript 会话对话记录 API
+func (s *AdminServer) handleGetSessionTranscript(c *gin.Context) {
+	t := s.buildSessionTranscript(c)
+	if t == nil {
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// handleExportSession 导出会话对话记录，format 为 markdown（默认）或 jsonl
+func (s *AdminServer) handleExportSession(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", "markdown"))
+	if format != "markdown" && format != "md" && format != "jsonl" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'markdown' or 'jsonl'"})
+		return
+	}
+
+	t := s.buildSessionTranscript(c)
+	if t == nil {
+		return
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	filename := fmt.Sprintf("session_%s_%s", sanitizeForFilename(t.SessionID), timestamp)
+
+	if format == "jsonl" {
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.jsonl\"", filename))
+		c.Status(http.StatusOK)
+		if err := t.WriteJSONL(c.Writer); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.md\"", filename))
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(t.Markdown()))
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"claude-code-codex-companion/internal/logger"
+	"claude-code-codex-companion/internal/statistics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetStatsTimeSeries 按时间桶返回请求数、错误率、耗时分位数和 token 用量
+// 参数：from/to（默认最近 range，range 默认 24h）、resolution（minute/hour/day，默认按范围选择）、
+// endpoint（端点名称）、model、client_type、group_by（endpoint/model/client_type）
+func (s *AdminServer) handleGetStatsTimeSeries(c *gin.Context) {
+	query, err := buildTimeSeriesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := s.endpointManager.GetTimeSeries().Query(query)
+	if err != nil {
+		switch {
+		case errors.Is(err, statistics.ErrInvalidTimeSeriesQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, statistics.ErrTimeSeriesUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query statistics"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// buildTimeSeriesQuery 从查询参数构造时间序列查询条件
+func buildTimeSeriesQuery(c *gin.Context) (statistics.TimeSeriesQuery, error) {
+	query := statistics.TimeSeriesQuery{
+		Resolution: c.Query("resolution"),
+		Endpoint:   c.Query("endpoint"),
+		Model:      c.Query("model"),
+		ClientType: c.Query("client_type"),
+		GroupBy:    c.Query("group_by"),
+	}
+
+	var err error
+	if value := c.Query("to"); value != "" {
+		if query.To, err = logger.ParseLogTime(value); err != nil {
+			return query, err
+		}
+	} else {
+		query.To = time.Now()
+	}
+	if value := c.Query("from"); value != "" {
+		if query.From, err = logger.ParseLogTime(value); err != nil {
+			return query, err
+		}
+	} else {
+		span, err := time.ParseDuration(c.DefaultQuery("range", "24h"))
+		if err != nil || span <= 0 {
+			return query, fmt.Errorf("invalid range: %s", c.Query("range"))
+		}
+		query.From = query.To.Add(-span)
+	}
+	return query, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"html/template"
@@ -80,6 +81,14 @@ func (p *EmbeddedAssetProvider) ReadLocaleFile(filename string) ([]byte, error)
 }
 
 func main() {
+	// Subcommands take their own flags and exit without starting the server
+	if len(os.Args) > 1 && os.Args[1] == exportLogsCommand {
+		os.Exit(runExportLogs(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == compactLogsCommand {
+		os.Exit(runCompactLogs(os.Args[2:]))
+	}
+
 	flag.Parse()
 
 	if *version {
@@ -127,6 +136,20 @@ func main() {
 	<-quit
 	fmt.Println("\nShutting down servers...")
 	
+	// Flush pending trace spans before exit
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := proxyServer.ShutdownTracing(shutdownCtx); err != nil {
+		log.Printf("Error shutting down tracing: %v", err)
+	}
+	cancelShutdown()
+
+	proxyServer.StopAlerting()
+	proxyServer.StopOAuthRefresher()
+
+	if err := proxyServer.FlushStatistics(); err != nil {
+		log.Printf("Error flushing statistics: %v", err)
+	}
+
 	// Graceful shutdown: close logger and database connections
 	if logger := proxyServer.GetLogger(); logger != nil {
 		if err := logger.Close(); err != nil {
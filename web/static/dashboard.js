@@ -1,5 +1,10 @@
 // Dashboard Page JavaScript
 
+const STATS_COLORS = ['#0d6efd', '#dc3545', '#198754', '#fd7e14', '#6f42c1', '#20c997', '#6c757d', '#d63384'];
+const STATS_CHART_WIDTH = 600;
+const STATS_CHART_HEIGHT = 160;
+const STATS_CHART_PADDING = { top: 8, right: 8, bottom: 18, left: 44 };
+
 document.addEventListener('DOMContentLoaded', function() {
     initializeCommonFeatures();
     
@@ -11,9 +16,118 @@ document.addEventListener('DOMContentLoaded', function() {
             cell.innerHTML = `<code title="${escapeHtml(urlFormatted.title)}">${escapeHtml(urlFormatted.display)}</code>`;
         }
     });
+
+    initializeStatsCharts();
     
     // Auto-refresh every 30 seconds
     setInterval(function() {
         location.reload();
     }, 30000);
-});
\ No newline at end of file
+});
+
+// 趋势图的时间范围与分组保存在 localStorage，页面自动刷新后保持
+function initializeStatsCharts() {
+    const rangeSelect = document.getElementById('statsRange');
+    const groupSelect = document.getElementById('statsGroupBy');
+    if (!rangeSelect || !groupSelect) return;
+
+    rangeSelect.value = localStorage.getItem('statsRange') || rangeSelect.value;
+    groupSelect.value = localStorage.getItem('statsGroupBy') || '';
+
+    [rangeSelect, groupSelect].forEach(function(select) {
+        select.addEventListener('change', function() {
+            localStorage.setItem(select.id, select.value);
+            loadStatsCharts();
+        });
+    });
+
+    loadStatsCharts();
+}
+
+async function loadStatsCharts() {
+    const errorBox = document.getElementById('statsChartsError');
+    const params = new URLSearchParams({
+        range: document.getElementById('statsRange').value,
+        group_by: document.getElementById('statsGroupBy').value
+    });
+
+    try {
+        const response = await fetch(`/admin/api/stats/timeseries?${params}`);
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(data.error || response.statusText);
+        }
+        errorBox.classList.add('d-none');
+        renderStatsCharts(data);
+    } catch (error) {
+        errorBox.textContent = error.message;
+        errorBox.classList.remove('d-none');
+    }
+}
+
+function renderStatsCharts(data) {
+    const series = data.series.slice(0, STATS_COLORS.length).map(function(s, index) {
+        return { key: s.key || T('stats_series_all', '全部'), color: STATS_COLORS[index], points: s.points };
+    });
+    const times = series.length > 0 ? series[0].points.map(p => new Date(p.time)) : [];
+
+    const charts = [
+        ['statsChartRequests', p => p.requests],
+        ['statsChartErrorRate', p => p.error_rate],
+        ['statsChartLatency', p => p.latency_p90_ms],
+        ['statsChartTokens', p => p.input_tokens + p.output_tokens + p.cache_read_tokens + p.cache_write_tokens]
+    ];
+    charts.forEach(function([id, value]) {
+        const lines = series.map(s => ({ color: s.color, label: s.key, values: s.points.map(value) }));
+        document.getElementById(id).innerHTML = renderLineChart(lines, times, data.resolution);
+    });
+
+    const legend = document.getElementById('statsChartLegend');
+    legend.innerHTML = series.length > 1 ? series.map(s =>
+        `<span class="stats-legend-swatch" style="background-color: ${s.color}"></span>${escapeHtml(s.key)}`
+    ).join('') : '';
+}
+
+// renderLineChart 生成折线图 SVG，lines 为 [{color, label, values}]
+function renderLineChart(lines, times, resolution) {
+    const W = STATS_CHART_WIDTH, H = STATS_CHART_HEIGHT, pad = STATS_CHART_PADDING;
+    const plotW = W - pad.left - pad.right;
+    const plotH = H - pad.top - pad.bottom;
+    const maxValue = Math.max(1, ...lines.flatMap(line => line.values));
+    const count = times.length;
+
+    const x = i => pad.left + (count > 1 ? i / (count - 1) * plotW : plotW / 2);
+    const y = v => pad.top + plotH - v / maxValue * plotH;
+
+    let svg = `<svg viewBox="0 0 ${W} ${H}" xmlns="http://www.w3.org/2000/svg">`;
+    [0, 0.5, 1].forEach(function(ratio) {
+        const value = maxValue * ratio;
+        svg += `<line x1="${pad.left}" x2="${W - pad.right}" y1="${y(value)}" y2="${y(value)}" stroke="#dee2e6" stroke-width="1"/>`;
+        svg += `<text class="axis-label" x="${pad.left - 4}" y="${y(value) + 3}" text-anchor="end">${formatChartValue(value)}</text>`;
+    });
+    if (count > 0) {
+        [0, Math.floor((count - 1) / 2), count - 1].forEach(function(i, n) {
+            const anchor = ['start', 'middle', 'end'][n];
+            svg += `<text class="axis-label" x="${x(i)}" y="${H - 4}" text-anchor="${anchor}">${formatChartTime(times[i], resolution)}</text>`;
+        });
+    }
+    lines.forEach(function(line) {
+        const points = line.values.map((v, i) => `${x(i).toFixed(1)},${y(v).toFixed(1)}`).join(' ');
+        svg += `<polyline fill="none" stroke="${line.color}" stroke-width="1.5" points="${points}"><title>${escapeHtml(line.label)}</title></polyline>`;
+    });
+    return svg + '</svg>';
+}
+
+function formatChartValue(value) {
+    if (value >= 1000000) return (value / 1000000).toFixed(1) + 'M';
+    if (value >= 1000) return (value / 1000).toFixed(1) + 'k';
+    return Number.isInteger(value) ? String(value) : value.toFixed(1);
+}
+
+function formatChartTime(date, resolution) {
+    const pad2 = n => String(n).padStart(2, '0');
+    const day = `${pad2(date.getMonth() + 1)}-${pad2(date.getDate())}`;
+    if (resolution === 'day') return day;
+    const time = `${pad2(date.getHours())}:${pad2(date.getMinutes())}`;
+    return resolution === 'hour' ? `${day} ${time}` : time;
+}
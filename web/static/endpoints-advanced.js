@@ -55,7 +55,7 @@ function addRewriteRule(sourcePattern = '', targetModel = '') {
                    placeholder="${wildcardPatternText}" value="${escapeHtml(sourcePattern)}" readonly>
         </div>
         <div class="col-5">
-            <input type="text" class="form-control target-model-input" 
+            <input type="text" class="form-control target-model-input" list="endpoint-model-options"
                    placeholder="${targetModelPlaceholderText}" value="${escapeHtml(targetModel)}" 
                    oninput="onRewriteRuleTargetChange()">
         </div>
@@ -170,6 +170,11 @@ function collectProxyData() {
     const username = document.getElementById('proxy-username').value.trim();
     const password = document.getElementById('proxy-password').value.trim();
     
+    // Direct bypasses the global proxy and needs no address
+    if (type === 'direct') {
+        return { type: 'direct', address: '' };
+    }
+
     if (!address) {
         return null; // Don't save proxy config if address is empty
     }
@@ -179,6 +184,14 @@ function collectProxyData() {
         address: address
     };
 
+    // Keep proxy chain and https proxy TLS settings configured in config.yaml (not editable in the form)
+    if (loadedProxyExtras.via) {
+        proxyConfig.via = loadedProxyExtras.via;
+    }
+    if (type === 'https' && loadedProxyExtras.tls) {
+        proxyConfig.tls = loadedProxyExtras.tls;
+    }
+
     // Only add auth info if both username and password are not empty
     if (username && password) {
         proxyConfig.username = username;
@@ -188,10 +201,14 @@ function collectProxyData() {
     return proxyConfig;
 }
 
+// Proxy settings that are only configurable in config.yaml (via chain, https proxy tls)
+let loadedProxyExtras = {};
+
 // Load proxy configuration to form
 function loadProxyConfig(config) {
     const checkbox = document.getElementById('proxy-enabled');
     const configDiv = document.getElementById('proxy-config');
+    loadedProxyExtras = config ? { via: config.via, tls: config.tls } : {};
     
     if (config) {
         checkbox.checked = true;
@@ -236,6 +253,33 @@ function collectModelRewriteData() {
     return rules.length > 0 ? { enabled: true, rules: rules } : null;
 }
 
+// Load models available on the endpoint (Ollama /api/tags) as target model suggestions
+function loadEndpointModelOptions(endpoint) {
+    const datalist = document.getElementById('endpoint-model-options');
+    datalist.innerHTML = '';
+
+    if (!endpoint || endpoint.endpoint_type !== 'ollama') {
+        return;
+    }
+
+    apiRequest(`/admin/api/endpoints/${encodeURIComponent(endpoint.name)}/models`)
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            console.warn('Failed to discover endpoint models:', data.error);
+            return;
+        }
+        (data.models || []).forEach(model => {
+            const option = document.createElement('option');
+            option.value = model;
+            datalist.appendChild(option);
+        });
+    })
+    .catch(error => {
+        console.warn('Failed to discover endpoint models:', error);
+    });
+}
+
 // Load model rewrite configuration to form
 function loadModelRewriteConfig(config) {
     const checkbox = document.getElementById('model-rewrite-enabled');
@@ -50,6 +50,8 @@ function toggleOAuthVisibility(inputId, iconId) {
 }
 
 function loadOAuthConfig(oauthConfig) {
+    resetOAuthLogin();
+
     if (!oauthConfig) {
         // Clear OAuth fields
         document.getElementById('oauth-access-token').value = '';
@@ -78,6 +80,128 @@ function loadOAuthConfig(oauthConfig) {
     }
 }
 
+function loadOAuthRefreshHistory(endpoint) {
+    const group = document.getElementById('oauth-refresh-history-group');
+    const degradedAlert = document.getElementById('oauth-refresh-degraded');
+    const body = document.getElementById('oauth-refresh-history-body');
+    const status = endpoint ? endpoint.oauth_refresh : null;
+
+    body.innerHTML = '';
+    if (!status || !status.history || status.history.length === 0) {
+        StyleUtils.hide(group);
+        return;
+    }
+
+    if (status.degraded) {
+        degradedAlert.textContent = status.degraded_reason || '';
+        StyleUtils.show(degradedAlert);
+    } else {
+        StyleUtils.hide(degradedAlert);
+    }
+
+    status.history.forEach(record => {
+        const trigger = record.trigger === 'background'
+            ? T('oauth_refresh_trigger_background', '后台')
+            : T('oauth_refresh_trigger_request', '请求');
+        const result = record.success
+            ? `<span class="badge bg-success">${T('oauth_refresh_success', '成功')}</span>`
+            : `<span class="badge bg-danger">${T('oauth_refresh_failed', '失败')}</span>`;
+        const detail = record.success
+            ? (record.expires_at ? new Date(record.expires_at).toLocaleString() : '')
+            : record.error || '';
+        const row = document.createElement('tr');
+        row.innerHTML = `
+            <td class="text-nowrap">${escapeHtml(new Date(record.time).toLocaleString())}</td>
+            <td>${escapeHtml(trigger)}</td>
+            <td>${result}</td>
+            <td class="text-break"><small>${escapeHtml(detail)}</small></td>
+        `;
+        body.appendChild(row);
+    });
+    StyleUtils.show(group);
+}
+
+// ===== OAuth Login (PKCE) Functions =====
+
+let oauthLoginState = null;
+
+function resetOAuthLogin() {
+    oauthLoginState = null;
+    document.getElementById('oauth-login-code').value = '';
+    StyleUtils.hide(document.getElementById('oauth-login-step'));
+}
+
+function startOAuthLogin() {
+    const tokenUrl = document.getElementById('oauth-token-url').value.trim();
+    const clientId = document.getElementById('oauth-client-id').value.trim();
+    const scopes = document.getElementById('oauth-scopes').value
+        .split(/[,\s]+/)
+        .map(scope => scope.trim())
+        .filter(scope => scope);
+
+    apiRequest('/admin/api/endpoints/oauth/start', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ token_url: tokenUrl, client_id: clientId, scopes: scopes })
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            showAlert(data.error, 'danger');
+            return;
+        }
+        oauthLoginState = data.state;
+        const link = document.getElementById('oauth-login-url');
+        link.href = data.authorize_url;
+        link.textContent = data.authorize_url;
+        document.getElementById('oauth-login-code').value = '';
+        StyleUtils.show(document.getElementById('oauth-login-step'));
+        window.open(data.authorize_url, '_blank', 'noopener');
+    })
+    .catch(error => {
+        console.error('Failed to start OAuth login:', error);
+        showAlert(T('oauth_login_start_failed', '发起 OAuth 登录失败'), 'danger');
+    });
+}
+
+function completeOAuthLogin() {
+    const code = document.getElementById('oauth-login-code').value.trim();
+    if (!code) {
+        showAlert(T('oauth_login_code_required', '请粘贴授权码'), 'warning');
+        return;
+    }
+
+    // 编辑已有端点时直接保存到该端点；新建端点时仅填充表单
+    const data = { state: oauthLoginState, code: code };
+    if (editingEndpointName !== null) {
+        data.endpoint_name = editingEndpointName;
+    } else {
+        data.proxy = collectProxyData();
+    }
+
+    apiRequest('/admin/api/endpoints/oauth/complete', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(data)
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            showAlert(data.error, 'danger');
+            return;
+        }
+        loadOAuthConfig(data.oauth_config);
+        showAlert(T('oauth_login_success', 'OAuth 登录成功，令牌已获取'), 'success');
+        if (editingEndpointName !== null) {
+            loadEndpoints();
+        }
+    })
+    .catch(error => {
+        console.error('Failed to complete OAuth login:', error);
+        showAlert(T('oauth_login_complete_failed', '完成 OAuth 登录失败'), 'danger');
+    });
+}
+
 function resetAuthVisibility() {
     const eyeIcon = document.getElementById('auth-eye-icon');
     eyeIcon.className = 'fas fa-eye';
@@ -103,6 +227,11 @@ function togglePathPrefixField() {
         if (!pathPrefixInput.value) {
             pathPrefixInput.placeholder = '/v1/chat/completions (留空表示原生支持 /responses)';
         }
+    } else if (endpointType === 'gemini') {
+        StyleUtils.show(pathPrefixGroup);
+        // Gemini端点的path_prefix为API版本路径，留空默认 /v1beta
+        pathPrefixInput.required = false;
+        pathPrefixInput.placeholder = '/v1beta';
     } else {
         StyleUtils.hide(pathPrefixGroup);
         pathPrefixInput.required = false;
@@ -131,6 +260,57 @@ function toggleAuthTypeForEndpointType() {
         } else {
             authTypeSelect.value = 'auth_token'; // Default to auth_token
         }
+    } else if (endpointType === 'bedrock') {
+        // Bedrock endpoints are signed with AWS SigV4 (credentials from aws_config, env or profile)
+        authTypeSelect.innerHTML = `
+            <option value="aws_sigv4">AWS SigV4</option>
+        `;
+        authTypeSelect.value = 'aws_sigv4';
+    } else if (endpointType === 'azure-openai') {
+        // Azure OpenAI endpoints use the api-key header, or Entra ID bearer tokens (static, OAuth or client credentials)
+        authTypeSelect.innerHTML = `
+            <option value="api_key">API Key (api-key)</option>
+            <option value="auth_token">Auth Token (Authorization Bearer)</option>
+            <option value="oauth">OAuth 2.0</option>
+            <option value="entra_id">Entra ID (client credentials)</option>
+        `;
+
+        if (currentValue === 'api_key' || currentValue === 'auth_token' || currentValue === 'oauth' || currentValue === 'entra_id') {
+            authTypeSelect.value = currentValue;
+        } else {
+            authTypeSelect.value = 'api_key'; // Default to api_key
+        }
+    } else if (endpointType === 'ollama') {
+        // Local Ollama usually needs no auth (leave the value empty); remote instances behind a proxy use a bearer token
+        authTypeSelect.innerHTML = `
+            <option value="auth_token">Auth Token (Authorization Bearer)</option>
+        `;
+        authTypeSelect.value = 'auth_token';
+    } else if (endpointType === 'vertex-anthropic') {
+        // Vertex AI endpoints mint access tokens from a service account key (vertex_config), or use a static access token
+        authTypeSelect.innerHTML = `
+            <option value="gcp_service_account">GCP Service Account</option>
+            <option value="auth_token">Auth Token (Authorization Bearer)</option>
+        `;
+
+        if (currentValue === 'gcp_service_account' || currentValue === 'auth_token') {
+            authTypeSelect.value = currentValue;
+        } else {
+            authTypeSelect.value = 'gcp_service_account'; // Default to gcp_service_account
+        }
+    } else if (endpointType === 'gemini') {
+        // Gemini endpoints pass the API key in the x-goog-api-key header
+        authTypeSelect.innerHTML = `
+            <option value="api_key">API Key (x-goog-api-key)</option>
+            <option value="auth_token">Auth Token (Authorization Bearer)</option>
+            <option value="oauth">OAuth 2.0</option>
+        `;
+
+        if (currentValue === 'api_key' || currentValue === 'auth_token' || currentValue === 'oauth') {
+            authTypeSelect.value = currentValue;
+        } else {
+            authTypeSelect.value = 'api_key'; // Default to api_key
+        }
     } else {
         // Anthropic endpoints support all auth types
         authTypeSelect.innerHTML = `
@@ -170,11 +350,22 @@ function onAuthTypeChange() {
         document.getElementById('oauth-refresh-token').required = true;
         document.getElementById('oauth-expires-at').required = true;
         document.getElementById('oauth-token-url').required = true;
+    } else if (authType === 'aws_sigv4' || authType === 'gcp_service_account' || authType === 'entra_id') {
+        // SigV4、服务账号与 Entra ID 认证不需要认证值，凭证来自 aws_config / vertex_config / azure_config、环境变量或 profile
+        StyleUtils.hide(authValueGroup);
+        StyleUtils.hide(oauthConfigGroup);
+        authValueInput.required = false;
+
+        document.getElementById('oauth-access-token').required = false;
+        document.getElementById('oauth-refresh-token').required = false;
+        document.getElementById('oauth-expires-at').required = false;
+        document.getElementById('oauth-token-url').required = false;
     } else {
         // 显示认证值输入，隐藏 OAuth 配置
         StyleUtils.show(authValueGroup);
         StyleUtils.hide(oauthConfigGroup);
-        authValueInput.required = true;
+        // 本地 Ollama 通常不需要认证，认证值可以留空
+        authValueInput.required = document.getElementById('endpoint-type').value !== 'ollama';
         
         // OAuth 字段不再必填
         document.getElementById('oauth-access-token').required = false;
@@ -198,6 +389,12 @@ document.addEventListener('click', function(e) {
             const eyeId = button.dataset.eyeId;
             toggleOAuthVisibility(tokenId, eyeId);
             break;
+        case 'start-oauth-login':
+            startOAuthLogin();
+            break;
+        case 'complete-oauth-login':
+            completeOAuthLogin();
+            break;
         case 'add-rewrite-rule':
             addRewriteRule();
             break;
@@ -129,6 +129,7 @@ function updateEndpointRowStatus(row, endpoint) {
         // 其他状态（如检测中）
         statusBadge = '<span class="badge bg-warning"><i class="fas fa-clock"></i> 检测中</span>';
     }
+    statusBadge += buildOAuthDegradedBadge(endpoint);
     statusCell.innerHTML = statusBadge;
 }
 
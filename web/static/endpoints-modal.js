@@ -16,12 +16,15 @@ function showAddEndpointModal() {
     
     // Reset auth visibility
     resetAuthVisibility();
+    resetOAuthLogin();
+    loadOAuthRefreshHistory(null);
     
     // Clear proxy configuration
     loadProxyConfig(null);
     
     // Clear model rewrite configuration
     loadModelRewriteConfig(null);
+    loadEndpointModelOptions(null);
     
     // Clear default model
     document.getElementById('endpoint-default-model').value = '';
@@ -81,12 +84,13 @@ function showEditEndpointModal(endpointName) {
     // 移除客户端选择字段 - 现在自动检测
 
     // Set auth value or OAuth config based on auth type
+    loadOAuthRefreshHistory(endpoint);
     if (endpoint.auth_type === 'oauth' && endpoint.oauth_config) {
         // Load OAuth configuration
         loadOAuthConfig(endpoint.oauth_config);
     } else {
         // Set auth value to asterisks
-        document.getElementById('endpoint-auth-value').value = '*'.repeat(Math.min(endpoint.auth_value.length, 50));
+        document.getElementById('endpoint-auth-value').value = '*'.repeat(Math.min((endpoint.auth_value || '').length, 50));
         document.getElementById('endpoint-auth-value').type = 'password'; // Ensure it's password type
         document.getElementById('endpoint-auth-value').placeholder = '输入您的 API Key 或 Token';
         resetAuthVisibility();
@@ -100,6 +104,7 @@ function showEditEndpointModal(endpointName) {
     
     // Load model rewrite configuration
     loadModelRewriteConfig(endpoint.model_rewrite);
+    loadEndpointModelOptions(endpoint);
     
     // Load default model after loading model rewrite config
     loadDefaultModel(endpoint.model_rewrite);
@@ -186,6 +191,9 @@ function saveEndpoint() {
         // Remove empty optional fields
         if (!oauthConfig.client_id) delete oauthConfig.client_id;
         if (oauthConfig.scopes.length === 0) delete oauthConfig.scopes;
+    } else if (authType === 'aws_sigv4' || authType === 'gcp_service_account' || authType === 'entra_id') {
+        // SigV4 签名、服务账号与 Entra ID 认证不使用认证值
+        authValue = '';
     } else {
         // Get regular auth value
         authValue = document.getElementById('endpoint-auth-value').value;
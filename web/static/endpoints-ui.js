@@ -49,6 +49,7 @@ function rebuildTable(endpoints) {
             // 其他状态（如检测中）
             statusBadge = `<span class="badge bg-warning"><i class="fas fa-clock"></i> ${T('detecting', '检测中')}</span>`;
         }
+        statusBadge += buildOAuthDegradedBadge(endpoint);
         
         // Build enabled status badge
         const enabledBadge = endpoint.enabled 
@@ -56,9 +57,22 @@ function rebuildTable(endpoints) {
             : `<span class="badge bg-secondary"><i class="fas fa-toggle-off"></i> ${T('disabled', '已禁用')}</span>`;
         
         // Build endpoint type badge
-        const endpointTypeBadge = endpoint.endpoint_type === 'openai' 
-            ? '<span class="badge bg-warning">openai</span>'
-            : '<span class="badge bg-primary">anthropic</span>';
+        let endpointTypeBadge;
+        if (endpoint.endpoint_type === 'openai') {
+            endpointTypeBadge = '<span class="badge bg-warning">openai</span>';
+        } else if (endpoint.endpoint_type === 'gemini') {
+            endpointTypeBadge = '<span class="badge bg-info">gemini</span>';
+        } else if (endpoint.endpoint_type === 'bedrock') {
+            endpointTypeBadge = '<span class="badge bg-dark">bedrock</span>';
+        } else if (endpoint.endpoint_type === 'azure-openai') {
+            endpointTypeBadge = '<span class="badge bg-warning">azure-openai</span>';
+        } else if (endpoint.endpoint_type === 'vertex-anthropic') {
+            endpointTypeBadge = '<span class="badge bg-info">vertex-anthropic</span>';
+        } else if (endpoint.endpoint_type === 'ollama') {
+            endpointTypeBadge = '<span class="badge bg-secondary">ollama</span>';
+        } else {
+            endpointTypeBadge = '<span class="badge bg-primary">anthropic</span>';
+        }
         
         // Build URL display: only show domain, full URL in title, truncate domain if over 25 chars
         const urlFormatted = formatUrlDisplay(endpoint.url);
@@ -70,6 +84,17 @@ function rebuildTable(endpoints) {
             const fullPath = endpoint.path_prefix || '';
             const truncatedPath = truncatePath(fullPath, 10);
             pathDisplay = `<code class="path-display" title="${fullPath}">${truncatedPath}</code>`;
+        } else if (endpoint.endpoint_type === 'gemini') {
+            const fullPath = endpoint.path_prefix || '/v1beta';
+            pathDisplay = `<code class="path-display" title="${fullPath}">${truncatePath(fullPath, 10)}</code>`;
+        } else if (endpoint.endpoint_type === 'bedrock') {
+            pathDisplay = '<span class="text-muted">/model/…/invoke</span>';
+        } else if (endpoint.endpoint_type === 'azure-openai') {
+            pathDisplay = '<span class="text-muted">/openai/deployments/…</span>';
+        } else if (endpoint.endpoint_type === 'vertex-anthropic') {
+            pathDisplay = '<span class="text-muted">…:rawPredict</span>';
+        } else if (endpoint.endpoint_type === 'ollama') {
+            pathDisplay = '<span class="text-muted">/api/chat</span>';
         } else {
             pathDisplay = '<span class="text-muted">/v1/messages</span>';
         }
@@ -80,6 +105,12 @@ function rebuildTable(endpoints) {
             authTypeBadge = '<span class="badge bg-primary">api_key</span>';
         } else if (endpoint.auth_type === 'oauth') {
             authTypeBadge = '<span class="badge bg-success">oauth</span>';
+        } else if (endpoint.auth_type === 'aws_sigv4') {
+            authTypeBadge = '<span class="badge bg-dark">aws_sigv4</span>';
+        } else if (endpoint.auth_type === 'entra_id') {
+            authTypeBadge = '<span class="badge bg-info">entra_id</span>';
+        } else if (endpoint.auth_type === 'gcp_service_account') {
+            authTypeBadge = '<span class="badge bg-info">gcp_service_account</span>';
         } else {
             authTypeBadge = '<span class="badge bg-secondary">auth_token</span>';
         }
@@ -213,6 +244,15 @@ function updateEndpointEnabledBadge(endpointName, enabled) {
     }
 }
 
+// OAuth token 连续刷新失败时在状态后显示降级标记，悬停查看原因
+function buildOAuthDegradedBadge(endpoint) {
+    if (!endpoint.enabled || !endpoint.oauth_refresh || !endpoint.oauth_refresh.degraded) {
+        return '';
+    }
+    const reason = escapeHtml(endpoint.oauth_refresh.degraded_reason || '');
+    return ` <span class="badge bg-warning text-dark" title="${reason}"><i class="fas fa-exclamation-triangle"></i> ${T('oauth_refresh_degraded', '令牌刷新异常')}</span>`;
+}
+
 function updateEndpointStatusBadge(endpointName, enabled, status) {
     // Try to find in special endpoint list first
     let row = document.querySelector(`#special-endpoint-list tr[data-endpoint-name="${endpointName}"]`);
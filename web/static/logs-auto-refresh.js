@@ -56,6 +56,11 @@ function startAutoRefresh() {
         clearInterval(autoRefreshInterval);
     }
     
+    // 自动刷新与实时推送互斥
+    if (typeof liveModeEnabled !== 'undefined' && liveModeEnabled) {
+        toggleLiveMode();
+    }
+    
     autoRefreshInterval = setInterval(function() {
         // Check if any modal is currently open
         if (isAnyModalOpen()) {
@@ -63,13 +68,8 @@ function startAutoRefresh() {
             return;
         }
         
-        // Get current page parameters
-        const urlParams = new URLSearchParams(window.location.search);
-        const currentPage = urlParams.get('page') || '1';
-        const failedOnly = urlParams.get('failed_only') === 'true';
-        
-        // Refresh the page
-        refreshLogs(currentPage, failedOnly);
+        // Refresh the page with current filters
+        refreshLogs();
     }, autoRefreshTimer);
 }
 
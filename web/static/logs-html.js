@@ -109,6 +109,7 @@ function generateLogAttemptContentHtml(log, attemptNum) {
                     ) : ''
                 }
                 ${log.is_streaming ? '<span class="badge bg-info">SSE</span>' : ''}
+                ${log.trace_id ? `<span class="badge bg-light text-dark border" title="Trace ID: ${escapeHtml(log.trace_id)}"><i class="fas fa-project-diagram"></i> ${escapeHtml(log.trace_id.substring(0, 8))}</span>` : ''}
                 ${log.content_type_override ? `<span class="badge bg-warning text-dark" title="Content-Type覆盖: ${escapeHtml(log.content_type_override)}">${escapeHtml(log.content_type_override)}</span>` : ''}
                 ${requestChanges || responseChanges ? `<span class="badge bg-info">${T('has_modifications', '有修改')}</span>` : ''}
             </h6>
@@ -73,17 +73,20 @@ function addLogPageEventListeners() {
             case 'toggle-failed-only':
                 e.preventDefault();
                 const failedOnly = target.dataset.currentFailedOnly === 'true';
-                const currentPage = target.dataset.page || '1';
-                console.log('Calling toggleFailedOnly with:', failedOnly, currentPage); // Debug log
-                toggleFailedOnly(failedOnly, currentPage);
+                console.log('Calling toggleFailedOnly with:', failedOnly); // Debug log
+                toggleFailedOnly(failedOnly);
                 break;
                 
             case 'refresh-logs':
                 e.preventDefault();
-                const refreshPage = target.dataset.page || '1';
-                const refreshFailedOnly = target.dataset.failedOnly === 'true';
-                console.log('Calling refreshLogs with:', refreshPage, refreshFailedOnly); // Debug log
-                refreshLogs(refreshPage, refreshFailedOnly);
+                console.log('Calling refreshLogs'); // Debug log
+                refreshLogs();
+                break;
+                
+            case 'toggle-live-mode':
+                e.preventDefault();
+                console.log('Calling toggleLiveMode'); // Debug log
+                toggleLiveMode();
                 break;
                 
             case 'toggle-auto-refresh':
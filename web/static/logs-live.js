@@ -0,0 +1,252 @@
+// Live log tail over Server-Sent Events
+
+let liveModeEnabled = false;
+let liveEventSource = null;
+const LIVE_MAX_ROWS = 500; // 实时模式下表格最多保留的行数
+
+document.addEventListener('DOMContentLoaded', function() {
+    // 等待翻译加载后再恢复实时模式状态
+    function initLiveMode() {
+        if (typeof T === 'function' && window.I18n) {
+            if (localStorage.getItem('logsLiveMode') === 'true') {
+                // 实时模式只在最新一页生效
+                const params = new URLSearchParams(window.location.search);
+                if (!params.has('cursor')) {
+                    liveModeEnabled = true;
+                    startLiveMode();
+                }
+            }
+            updateLiveModeButton();
+            return;
+        }
+        setTimeout(initLiveMode, 100);
+    }
+
+    initLiveMode();
+});
+
+function toggleLiveMode() {
+    liveModeEnabled = !liveModeEnabled;
+    localStorage.setItem('logsLiveMode', liveModeEnabled.toString());
+
+    if (liveModeEnabled) {
+        const params = new URLSearchParams(window.location.search);
+        if (params.has('cursor')) {
+            // 回到最新一页后再开始实时推送
+            params.delete('cursor');
+            const query = params.toString();
+            window.location.href = query ? `/admin/logs?${query}` : '/admin/logs';
+            return;
+        }
+        startLiveMode();
+    } else {
+        stopLiveMode();
+    }
+
+    updateLiveModeButton();
+}
+
+function startLiveMode() {
+    stopLiveMode();
+
+    // 实时推送与自动刷新互斥
+    if (typeof autoRefreshEnabled !== 'undefined' && autoRefreshEnabled) {
+        toggleAutoRefresh();
+    }
+
+    // 使用与当前页面相同的过滤条件
+    const params = new URLSearchParams(window.location.search);
+    params.delete('cursor');
+    const query = params.toString();
+    liveEventSource = new EventSource(`/admin/api/logs/stream${query ? '?' + query : ''}`);
+
+    liveEventSource.addEventListener('log', function(e) {
+        const event = JSON.parse(e.data);
+        if (event.log) {
+            prependLiveRow(buildLiveLogRow(event.log));
+        }
+    });
+
+    liveEventSource.addEventListener('endpoint_status', function(e) {
+        const event = JSON.parse(e.data);
+        if (event.endpoint) {
+            prependLiveRow(buildEndpointStatusRow(event.endpoint, event.time));
+        }
+    });
+
+    liveEventSource.addEventListener('dropped', function(e) {
+        const data = JSON.parse(e.data);
+        showToast(T('live_events_dropped', '客户端处理过慢，已跳过 %d 条实时事件').replace('%d', data.count), 'warning');
+    });
+
+    liveEventSource.onerror = function() {
+        // EventSource 会自动重连，这里只更新按钮状态
+        updateLiveModeButton();
+    };
+    liveEventSource.onopen = function() {
+        updateLiveModeButton();
+    };
+}
+
+function stopLiveMode() {
+    if (liveEventSource) {
+        liveEventSource.close();
+        liveEventSource = null;
+    }
+}
+
+function updateLiveModeButton() {
+    const button = document.getElementById('liveModeToggle');
+    const text = document.getElementById('liveModeText');
+    if (!button || !text || typeof T !== 'function') {
+        return;
+    }
+
+    if (liveModeEnabled) {
+        const connected = liveEventSource && liveEventSource.readyState === EventSource.OPEN;
+        button.className = connected ? 'btn btn-sm btn-danger' : 'btn btn-sm btn-outline-danger';
+        text.textContent = connected ? T('live_mode_on', '实时中') : T('live_mode_connecting', '连接中...');
+    } else {
+        button.className = 'btn btn-sm btn-outline-danger';
+        text.textContent = T('live_mode', '实时');
+    }
+}
+
+function prependLiveRow(row) {
+    const tbody = document.getElementById('logsTableBody');
+    if (!tbody) return;
+
+    tbody.insertBefore(row, tbody.firstChild);
+    while (tbody.rows.length > LIVE_MAX_ROWS) {
+        tbody.deleteRow(tbody.rows.length - 1);
+    }
+}
+
+function formatLiveTime(timestamp) {
+    const date = new Date(timestamp);
+    return date.toTimeString().substring(0, 8);
+}
+
+// 与 logs.html 中的服务端渲染保持相同的列
+function buildLiveLogRow(log) {
+    const row = document.createElement('tr');
+    row.className = 'live-log-row';
+
+    let clientHtml = '<span class="text-muted">--</span>';
+    if (log.client_type) {
+        if (log.client_type === 'claude-code') {
+            clientHtml = `<span class="badge bg-primary" title="Claude Code (${escapeHtml(log.request_format || '')})"><i class="fas fa-robot"></i> Claude</span>`;
+        } else if (log.client_type === 'codex') {
+            clientHtml = `<span class="badge bg-success" title="Codex (${escapeHtml(log.request_format || '')})"><i class="fas fa-code"></i> Codex</span>`;
+        } else {
+            clientHtml = `<span class="badge bg-secondary" title="${escapeHtml(log.client_type)}"><i class="fas fa-question"></i> ${escapeHtml(log.client_type)}</span>`;
+        }
+        if (log.format_converted) {
+            clientHtml += ` <i class="fas fa-exchange-alt text-warning" title="${escapeHtml((log.request_format || '') + ' → ' + (log.target_format || ''))}"></i>`;
+        }
+    }
+
+    const sessionId = log.session_id || '';
+    const sessionText = sessionId ? getSessionIdDisplayText(sessionId) : '--';
+
+    let endpointHtml = `<div><small>${escapeHtml(log.endpoint || '')}</small></div>`;
+    if (log.endpoint && log.endpoint !== 'failed') {
+        const urlFormatted = formatUrlDisplay(log.endpoint);
+        endpointHtml = `<div><small><code title="${escapeHtml(urlFormatted.title)}">${escapeHtml(urlFormatted.display)}</code></small></div>`;
+    }
+    const errorText = log.endpoint_blacklist_reason || log.error || '';
+    if (errorText) {
+        const shortText = errorText.length > 20 ? errorText.substring(0, 20) + '...' : errorText;
+        endpointHtml += `<small class="text-danger"><span title="${escapeHtml(errorText)}">${escapeHtml(shortText)}</span></small>`;
+    }
+
+    let modelHtml = '-';
+    if (log.model) {
+        modelHtml = log.model_rewrite_applied
+            ? `<span class="model-rewritten" title="→ ${escapeHtml(log.rewritten_model || '')}">${escapeHtml(log.model)}</span>`
+            : `<span class="model-original">${escapeHtml(log.model)}</span>`;
+    }
+
+    const tagsHtml = (log.tags && log.tags.length > 0)
+        ? log.tags.map(tag => `<span class="badge bg-primary me-1">${escapeHtml(tag)}</span>`).join('')
+        : '<small class="text-muted">-</small>';
+
+    const thinkingHtml = log.thinking_enabled
+        ? `<span class="badge bg-warning thinking-badge" title="Thinking: ${log.thinking_budget_tokens} tokens">T</span>`
+        : '<small class="text-muted">-</small>';
+
+    const statusClass = log.status_code > 0 && log.status_code < 400 ? 'bg-success' : 'bg-danger';
+    const retryHtml = log.attempt_number > 1
+        ? `<span class="badge bg-warning text-dark">#${log.attempt_number > 6 ? '5+' : log.attempt_number - 1}</span>`
+        : '';
+    const streamingHtml = log.is_streaming
+        ? '<span class="badge bg-info">SSE</span>'
+        : '<span class="badge bg-secondary">JSON</span>';
+
+    const requestId = escapeHtml(log.request_id);
+    row.innerHTML = `
+        <td>${formatLiveTime(log.timestamp)}</td>
+        <td class="request-id-cell" data-request-id="${requestId}">
+            <small>${requestId}
+                <button class="copy-request-id-btn" data-action="copy-request-id" data-request-id="${requestId}" title="${escapeHtml(T('copy_request_id_tooltip', '复制请求ID'))}">
+                    <i class="fas fa-copy"></i>
+                </button>
+            </small>
+        </td>
+        <td class="client-type-cell">${clientHtml}</td>
+        <td class="session-id-cell">
+            ${sessionId ? `<a href="/admin/sessions/${encodeURIComponent(sessionId)}" class="session-link">` : ''}<span class="session-id-badge" data-session-id="${escapeHtml(sessionId)}" title="${escapeHtml(sessionId || '--')}" style="background-color: ${generateSessionIdColor(sessionId)}">${escapeHtml(sessionText)}</span>${sessionId ? '</a>' : ''}
+        </td>
+        <td class="endpoint-cell">${endpointHtml}</td>
+        <td><small>${modelHtml}</small></td>
+        <td>${tagsHtml}</td>
+        <td>${thinkingHtml}</td>
+        <td><span class="badge ${statusClass}">${log.status_code}</span></td>
+        <td>${retryHtml}</td>
+        <td>${formatDuration(log.duration_ms)}</td>
+        <td><small>${formatFileSize(log.request_body_size)}</small></td>
+        <td><small>${formatFileSize(log.response_body_size)}</small></td>
+        <td>${streamingHtml}</td>
+        <td>
+            <button class="btn btn-sm btn-outline-info" onclick="showLogDetails('${requestId}')">
+                <i class="fas fa-eye"></i>
+            </button>
+        </td>`;
+    return row;
+}
+
+function buildEndpointStatusRow(endpoint, time) {
+    const row = document.createElement('tr');
+    row.className = 'live-endpoint-row';
+
+    let variant = 'info';
+    let icon = 'fa-info-circle';
+    let message = '';
+    if (endpoint.change === 'blacklisted') {
+        variant = 'danger';
+        icon = 'fa-ban';
+        message = T('live_endpoint_blacklisted', '端点已被拉黑');
+    } else if (endpoint.change === 'recovered') {
+        variant = 'success';
+        icon = 'fa-check-circle';
+        message = T('live_endpoint_recovered', '端点已恢复');
+    } else if (endpoint.change === 'learned_param') {
+        variant = 'warning';
+        icon = 'fa-graduation-cap';
+        message = T('live_endpoint_learned_param', '端点学习到不支持的参数') + `: <code>${escapeHtml(endpoint.param)}</code>`;
+    }
+
+    const details = endpoint.reason ? ` — ${escapeHtml(endpoint.reason)}` : '';
+    row.innerHTML = `
+        <td>${formatLiveTime(time)}</td>
+        <td colspan="14" class="table-${variant}">
+            <i class="fas ${icon}"></i> <strong>${escapeHtml(endpoint.name)}</strong>
+            ${message}${details}
+            <small class="text-muted ms-2">(${escapeHtml(endpoint.status)})</small>
+        </td>`;
+    return row;
+}
+
+window.addEventListener('beforeunload', function() {
+    stopLiveMode();
+});
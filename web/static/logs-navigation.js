@@ -1,10 +1,20 @@
 // Logs Page Navigation and Refresh Functions
 
-function toggleFailedOnly(failedOnly, currentPage) {
+function toggleFailedOnly(failedOnly) {
     failedOnly = !failedOnly;
-    window.location.href = `/admin/logs?page=1&failed_only=${failedOnly}`;
+    // 保留当前过滤条件，切换后回到最新一页
+    const params = new URLSearchParams(window.location.search);
+    params.delete('cursor');
+    params.delete('page');
+    if (failedOnly) {
+        params.set('failed_only', 'true');
+    } else {
+        params.delete('failed_only');
+    }
+    const query = params.toString();
+    window.location.href = query ? `/admin/logs?${query}` : '/admin/logs';
 }
 
-function refreshLogs(currentPage, failedOnly) {
-    window.location.href = `/admin/logs?page=${currentPage}&failed_only=${failedOnly}`;
-}
\ No newline at end of file
+function refreshLogs() {
+    window.location.href = `/admin/logs${window.location.search}`;
+}
@@ -0,0 +1,183 @@
+// Session list and transcript view
+
+const SESSION_BLOCK_COLLAPSE_LENGTH = 2000; // 超过该长度的工具结果默认折叠
+
+document.addEventListener('DOMContentLoaded', function() {
+    initializeCommonFeatures();
+
+    // 会话徽标颜色与日志页面保持一致
+    document.querySelectorAll('.session-id-badge').forEach(function(badge) {
+        const sessionId = badge.getAttribute('data-session-id');
+        badge.style.backgroundColor = generateSessionIdColor(sessionId);
+        badge.textContent = getSessionIdDisplayText(sessionId);
+    });
+
+    const container = document.getElementById('sessionTranscript');
+    if (!container) return;
+
+    // 等待翻译加载后再渲染
+    function initTranscript() {
+        if (typeof T === 'function' && window.I18n) {
+            loadSessionTranscript(container, container.getAttribute('data-session-id'));
+            return;
+        }
+        setTimeout(initTranscript, 100);
+    }
+
+    initTranscript();
+});
+
+async function loadSessionTranscript(container, sessionId) {
+    try {
+        const response = await fetch(`/admin/api/sessions/${encodeURIComponent(sessionId)}`);
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(data.error || response.statusText);
+        }
+        container.innerHTML = renderSessionSummary(data) + data.turns.map(renderSessionTurn).join('');
+    } catch (error) {
+        container.innerHTML = `<div class="alert alert-danger"><i class="fas fa-exclamation-triangle"></i> ${escapeHtml(error.message)}</div>`;
+    }
+}
+
+function formatSessionTime(timestamp) {
+    const date = new Date(timestamp);
+    return date.toLocaleString();
+}
+
+function formatSessionUsage(usage) {
+    let text = `${T('session_tokens_in', '输入')} ${usage.input_tokens} / ${T('session_tokens_out', '输出')} ${usage.output_tokens}`;
+    if (usage.cache_read_tokens || usage.cache_write_tokens) {
+        text += ` / ${T('session_tokens_cache_read', '缓存读取')} ${usage.cache_read_tokens || 0} / ${T('session_tokens_cache_write', '缓存写入')} ${usage.cache_write_tokens || 0}`;
+    }
+    return text;
+}
+
+function formatSessionCost(cost) {
+    return '$' + (cost || 0).toFixed(4);
+}
+
+function renderSessionSummary(t) {
+    const unpriced = (t.unpriced_models && t.unpriced_models.length > 0)
+        ? `<div class="alert alert-warning py-2 mb-0 mt-2">
+               <i class="fas fa-info-circle"></i> ${escapeHtml(T('session_unpriced_models', '以下模型未配置价格，用量未计入费用'))}:
+               ${t.unpriced_models.map(model => `<code>${escapeHtml(model)}</code>`).join(', ')}
+           </div>`
+        : '';
+
+    return `
+        <div class="session-summary mb-4">
+            <div class="row g-3">
+                <div class="col-md-3"><div class="text-muted small">${escapeHtml(T('session_time_range', '时间'))}</div>${escapeHtml(formatSessionTime(t.start))} — ${escapeHtml(formatSessionTime(t.end))}</div>
+                <div class="col-md-2"><div class="text-muted small">${escapeHtml(T('session_turns', '轮次'))}</div>${t.turns.length}</div>
+                <div class="col-md-5"><div class="text-muted small">Tokens</div>${escapeHtml(formatSessionUsage(t.usage))}</div>
+                <div class="col-md-2"><div class="text-muted small">${escapeHtml(T('session_cost', '费用'))}</div><strong>${formatSessionCost(t.cost)}</strong></div>
+            </div>
+            ${unpriced}
+        </div>`;
+}
+
+function renderSessionTurn(turn) {
+    const statusClass = turn.status_code > 0 && turn.status_code < 400 ? 'bg-success' : 'bg-danger';
+    const endpoint = formatUrlDisplay(turn.endpoint || '');
+
+    let model = escapeHtml(turn.model || '-');
+    if (turn.model_rewritten) {
+        model = `<span class="model-rewritten">${escapeHtml(turn.model)}</span> → <strong>${escapeHtml(turn.upstream_model)}</strong>`;
+    }
+
+    const badges = [];
+    if (turn.format_converted) {
+        badges.push(`<span class="badge bg-warning text-dark" title="${escapeHtml(T('session_format_converted', '格式转换'))}"><i class="fas fa-exchange-alt"></i> ${escapeHtml(turn.request_format || '')} → ${escapeHtml(turn.target_format || '')}</span>`);
+    }
+    if (turn.attempts.length > 1) {
+        badges.push(`<span class="badge bg-secondary">${turn.attempts.length} ${escapeHtml(T('session_attempts', '次尝试'))}</span>`);
+    }
+    (turn.tags || []).forEach(tag => badges.push(`<span class="badge bg-primary">${escapeHtml(tag)}</span>`));
+
+    const attempts = turn.attempts.length > 1
+        ? `<ul class="session-attempts small mb-2">${turn.attempts.map(attempt => `
+               <li>#${attempt.attempt_number} <code>${escapeHtml(formatUrlDisplay(attempt.endpoint).display)}</code>
+                   <span class="badge ${attempt.status_code > 0 && attempt.status_code < 400 ? 'bg-success' : 'bg-danger'}">${attempt.status_code}</span>
+                   ${formatDuration(attempt.duration_ms)} ${attempt.error ? `<span class="text-danger">${escapeHtml(attempt.error)}</span>` : ''}</li>`).join('')}
+           </ul>`
+        : '';
+
+    const cost = turn.cost !== undefined && turn.cost !== null
+        ? `${formatSessionCost(turn.cost)} <span class="text-muted">(${escapeHtml(T('session_cumulative', '累计'))} ${formatSessionCost(turn.cumulative_cost)})</span>`
+        : `<span class="text-muted">${escapeHtml(T('session_cost_unpriced', '未计价'))}</span>`;
+
+    const warnings = (turn.warnings || []).map(warning =>
+        `<div class="alert alert-warning py-1 px-2 small mb-2"><i class="fas fa-exclamation-triangle"></i> ${escapeHtml(warning)}</div>`).join('');
+    const error = turn.error ? `<div class="alert alert-danger py-1 px-2 small mb-2">${escapeHtml(turn.error)}</div>` : '';
+
+    const system = turn.system
+        ? `<details class="session-system mb-2"><summary>${escapeHtml(T('session_system_prompt', '系统提示'))}</summary><div class="session-text">${escapeHtml(turn.system)}</div></details>`
+        : '';
+    const input = (turn.input || []).map(renderSessionMessage).join('');
+    const output = turn.output ? renderSessionMessage(turn.output) : '';
+    const stopReason = turn.stop_reason ? `<div class="text-muted small">stop_reason: <code>${escapeHtml(turn.stop_reason)}</code></div>` : '';
+
+    return `
+        <div class="card session-turn mb-3" id="turn-${turn.index}">
+            <div class="card-header">
+                <div class="d-flex flex-wrap justify-content-between align-items-center gap-2">
+                    <div>
+                        <a href="#turn-${turn.index}" class="text-decoration-none"><strong>#${turn.index}</strong></a>
+                        <small class="text-muted ms-2">${escapeHtml(formatSessionTime(turn.timestamp))}</small>
+                        <span class="badge ${statusClass} ms-2">${turn.status_code}</span>
+                        <small class="ms-2"><code title="${escapeHtml(endpoint.title)}">${escapeHtml(endpoint.display)}</code></small>
+                        <small class="ms-2">${model}</small>
+                        ${badges.join(' ')}
+                    </div>
+                    <div class="small text-end">
+                        <div>${escapeHtml(formatSessionUsage(turn.usage))} · ${cost}</div>
+                        <div class="text-muted">${escapeHtml(T('session_cumulative', '累计'))}: ${escapeHtml(formatSessionUsage(turn.cumulative_usage))}</div>
+                    </div>
+                </div>
+                <div class="small text-muted mt-1">
+                    <span>${escapeHtml(turn.request_id)}</span>
+                    <button class="copy-request-id-btn" onclick="copyRequestId('${escapeHtml(turn.request_id)}')" title="${escapeHtml(T('copy_request_id_tooltip', '复制请求ID'))}">
+                        <i class="fas fa-copy"></i>
+                    </button>
+                </div>
+            </div>
+            <div class="card-body">
+                ${attempts}${error}${warnings}${system}${input}${output}${stopReason}
+            </div>
+        </div>`;
+}
+
+function renderSessionMessage(message) {
+    const role = message.role || 'unknown';
+    const blocks = (message.content || []).map(renderSessionBlock).join('');
+    return `
+        <div class="session-message session-message-${escapeHtml(role)}">
+            <div class="session-role">${escapeHtml(role)}</div>
+            ${blocks}
+        </div>`;
+}
+
+function renderSessionBlock(block) {
+    switch (block.type) {
+        case 'text':
+            return `<div class="session-text">${escapeHtml(block.text)}</div>`;
+        case 'thinking':
+            return `<details class="session-thinking"><summary><i class="fas fa-brain"></i> ${escapeHtml(T('thinking', '思考'))}</summary><div class="session-text">${escapeHtml(block.text)}</div></details>`;
+        case 'tool_use':
+            return `<div class="session-tool-use"><i class="fas fa-wrench"></i> <strong>${escapeHtml(block.name || '')}</strong> <small class="text-muted">${escapeHtml(block.id || '')}</small>
+                        <pre class="session-code">${escapeHtml(formatJson(block.input || ''))}</pre></div>`;
+        case 'tool_result': {
+            const label = block.is_error ? T('session_tool_error', '工具错误') : T('session_tool_result', '工具结果');
+            const text = block.text || '';
+            const open = text.length <= SESSION_BLOCK_COLLAPSE_LENGTH ? ' open' : '';
+            return `<details class="session-tool-result${block.is_error ? ' text-danger' : ''}"${open}>
+                        <summary><i class="fas fa-reply"></i> ${escapeHtml(label)} <small class="text-muted">${escapeHtml(block.id || '')} · ${formatFileSize(text.length)}</small></summary>
+                        <pre class="session-code">${escapeHtml(text)}</pre></details>`;
+        }
+        case 'image':
+            return `<div><span class="badge bg-secondary"><i class="fas fa-image"></i> image</span></div>`;
+        default:
+            return `<div class="session-text text-muted">[${escapeHtml(block.type)}] ${escapeHtml(block.text || '')}</div>`;
+    }
+}
@@ -80,7 +80,8 @@ function collectFormData() {
             idle_connection: document.getElementById('idleConnection').value,
             health_check_timeout: document.getElementById('healthCheckTimeout').value,
             check_interval: document.getElementById('checkInterval').value,
-            recovery_threshold: parseInt(document.getElementById('recoveryThreshold').value)
+            recovery_threshold: parseInt(document.getElementById('recoveryThreshold').value),
+            oauth_refresh_margin: document.getElementById('oauthRefreshMargin').value
         }
     };
 }
@@ -166,6 +167,7 @@ function resetSettings() {
     document.getElementById('healthCheckTimeout').value = originalConfig.timeouts.health_check_timeout;
     document.getElementById('checkInterval').value = originalConfig.timeouts.check_interval;
     document.getElementById('recoveryThreshold').value = originalConfig.timeouts.recovery_threshold;
+    document.getElementById('oauthRefreshMargin').value = originalConfig.timeouts.oauth_refresh_margin;
     
     showAlert('配置已重置为初始值', 'info');
 }
\ No newline at end of file